@@ -0,0 +1,103 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// A peer which is low on disk space or busy with other adds can delegate
+// the adds it receives through the REST API to a better-suited peer. The
+// API asks AddDelegate which peer should ingest the content and, when one
+// is returned, proxies the request to that peer's REST API over libp2p,
+// streaming its progress back to the client.
+
+// AddDelegate returns the peer which should perform an add received by
+// this peer, or an empty ID when it should be performed locally. The
+// addsInProgress argument is the number of adds this peer is currently
+// performing. Adds are delegated when this peer has less free space than
+// AddDelegationMinFreeSpace or is running AddDelegationMaxAdds adds
+// already, to the peer preferred by the allocator according to the
+// allocation informer metrics.
+func (c *Cluster) AddDelegate(ctx context.Context, addsInProgress int) (peer.ID, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/AddDelegate")
+	defer span.End()
+
+	reason := c.addDelegationReason(ctx, addsInProgress)
+	if reason == "" {
+		return "", nil
+	}
+
+	delegate := c.chooseAddDelegate(ctx, c.monitor.LatestMetrics(ctx, c.informer.Name()))
+	if delegate == "" {
+		logger.Debugf("%s, but there are no peers to delegate adds to", reason)
+		return "", nil
+	}
+	logger.Infof("%s: delegating add to %s", reason, delegate.Pretty())
+	return delegate, nil
+}
+
+// addDelegationReason explains why an add should be delegated, or returns
+// an empty string when it should not.
+func (c *Cluster) addDelegationReason(ctx context.Context, addsInProgress int) string {
+	if max := c.config.AddDelegationMaxAdds; max > 0 && addsInProgress >= max {
+		return fmt.Sprintf("%d adds in progress", addsInProgress)
+	}
+
+	min := c.config.AddDelegationMinFreeSpace
+	if min == 0 {
+		return ""
+	}
+	stat, err := c.ipfs.RepoStat(ctx)
+	if err != nil {
+		logger.Warningf("cannot obtain free space to decide on add delegation: %s", err)
+		return ""
+	}
+	var free uint64
+	if stat.StorageMax > stat.RepoSize {
+		free = stat.StorageMax - stat.RepoSize
+	}
+	if free < min {
+		return fmt.Sprintf("%d bytes of free space left", free)
+	}
+	return ""
+}
+
+// chooseAddDelegate returns the peer preferred by the allocator among
+// those with the given metrics, leaving out this peer, peers which are not
+// trusted, peers which are not allocatable and those with less free space
+// than AddDelegationMinFreeSpace.
+func (c *Cluster) chooseAddDelegate(ctx context.Context, metrics []*api.Metric) peer.ID {
+	notAllocatable := c.notAllocatablePeers(ctx)
+	candidates := make(map[peer.ID]*api.Metric)
+	for _, m := range metrics {
+		if m.Peer == c.id ||
+			!c.consensus.IsTrustedPeer(ctx, m.Peer) ||
+			containsPeer(notAllocatable, m.Peer) ||
+			!c.hasSpaceFor(m, c.config.AddDelegationMinFreeSpace) {
+			continue
+		}
+		candidates[m.Peer] = m
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	peers, err := c.allocator.Allocate(
+		ctx,
+		cid.Undef,
+		map[peer.ID]*api.Metric{},
+		candidates,
+		map[peer.ID]*api.Metric{},
+	)
+	if err != nil || len(peers) == 0 {
+		return ""
+	}
+	return peers[0]
+}
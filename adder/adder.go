@@ -4,12 +4,15 @@ package adder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"strings"
+	"sync/atomic"
 
 	"github.com/ipfs/ipfs-cluster/adder/ipfsadd"
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/observations"
 
 	cid "github.com/ipfs/go-cid"
 	files "github.com/ipfs/go-ipfs-files"
@@ -17,10 +20,14 @@ import (
 	logging "github.com/ipfs/go-log"
 	merkledag "github.com/ipfs/go-merkledag"
 	multihash "github.com/multiformats/go-multihash"
+	"go.opencensus.io/stats"
 )
 
 var logger = logging.Logger("adder")
 
+// inFlight is the number of adds running in this process.
+var inFlight int64
+
 // ClusterDAGService is an implementation of ipld.DAGService plus a Finalize
 // method. ClusterDAGServices can be used to provide Adders with a different
 // add implementation.
@@ -49,7 +56,9 @@ type Adder struct {
 }
 
 // New returns a new Adder with the given ClusterDAGService, add options and a
-// channel to send updates during the adding process.
+// channel to send updates during the adding process. The Adder blocks when
+// the channel is full, so a slow consumer should be decoupled with
+// BufferOutput.
 //
 // An Adder may only be used once.
 func New(ds ClusterDAGService, p *api.AddParams, out chan *api.AddedOutput) *Adder {
@@ -104,7 +113,16 @@ func (a *Adder) FromFiles(ctx context.Context, f files.Directory) (cid.Cid, erro
 	defer a.cancel()
 	defer close(a.output)
 
-	ipfsAdder, err := ipfsadd.NewAdder(a.ctx, a.dgs)
+	if a.params.Manifest && a.params.Shard {
+		return cid.Undef, errors.New("manifests cannot be produced for sharded adds")
+	}
+
+	stats.Record(ctx, observations.AddsInFlight.M(atomic.AddInt64(&inFlight, 1)))
+	defer func() {
+		stats.Record(ctx, observations.AddsInFlight.M(atomic.AddInt64(&inFlight, -1)))
+	}()
+
+	ipfsAdder, err := ipfsadd.NewAdder(a.ctx, &meteredDAGService{a.dgs})
 	if err != nil {
 		logger.Error(err)
 		return cid.Undef, err
@@ -119,6 +137,14 @@ func (a *Adder) FromFiles(ctx context.Context, f files.Directory) (cid.Cid, erro
 	ipfsAdder.Progress = a.params.Progress
 	ipfsAdder.NoCopy = a.params.NoCopy
 
+	var manifest *manifestRecorder
+	if a.params.Manifest {
+		manifest = newManifestRecorder(a.output)
+		// runs before closing the output.
+		defer manifest.close()
+		ipfsAdder.Out = manifest.in
+	}
+
 	// Set up prefix
 	prefix, err := merkledag.PrefixForCidVersion(a.params.CidVersion)
 	if err != nil {
@@ -160,6 +186,39 @@ func (a *Adder) FromFiles(ctx context.Context, f files.Directory) (cid.Cid, erro
 		logger.Error("error finalizing adder:", err)
 		return cid.Undef, err
 	}
+	if manifest != nil {
+		err = a.addManifest(a.ctx, manifest, clusterRoot)
+		if err != nil {
+			logger.Error("error adding manifest:", err)
+			return cid.Undef, err
+		}
+	}
 	logger.Infof("%s successfully added to cluster", clusterRoot)
 	return clusterRoot, nil
 }
+
+// meteredDAGService records the size of the blocks added through a
+// ClusterDAGService.
+type meteredDAGService struct {
+	ClusterDAGService
+}
+
+func (dgs *meteredDAGService) Add(ctx context.Context, node ipld.Node) error {
+	err := dgs.ClusterDAGService.Add(ctx, node)
+	if err == nil {
+		stats.Record(ctx, observations.AddedBytes.M(int64(len(node.RawData()))))
+	}
+	return err
+}
+
+func (dgs *meteredDAGService) AddMany(ctx context.Context, nodes []ipld.Node) error {
+	err := dgs.ClusterDAGService.AddMany(ctx, nodes)
+	if err == nil {
+		var size int
+		for _, node := range nodes {
+			size += len(node.RawData())
+		}
+		stats.Record(ctx, observations.AddedBytes.M(int64(size)))
+	}
+	return err
+}
@@ -76,6 +76,58 @@ func TestAdder(t *testing.T) {
 	}
 }
 
+func TestAdder_Manifest(t *testing.T) {
+	sth := test.NewShardingTestHelper()
+	defer sth.Clean(t)
+
+	mr, closer := sth.GetTreeMultiReader(t)
+	defer closer.Close()
+	r := multipart.NewReader(mr, mr.Boundary())
+	p := api.DefaultAddParams()
+	p.Manifest = true
+
+	dags := &mockCDAGServ{
+		resultCids: make(map[string]struct{}),
+	}
+
+	output := make(chan *api.AddedOutput, 100)
+	adder := New(dags, p, BufferOutput(output, 100))
+	var outputs []*api.AddedOutput
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ao := range output {
+			outputs = append(outputs, ao)
+		}
+	}()
+
+	root, err := adder.FromMultipart(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	last := outputs[len(outputs)-1]
+	if !last.Cid.Equals(root) || last.Manifest == nil {
+		t.Fatalf("expected the last output to carry the manifest: %+v", last)
+	}
+	if _, ok := dags.resultCids[last.Manifest.String()]; !ok {
+		t.Error("the manifest should have been added")
+	}
+	if len(dags.resultCids) != len(test.ShardingDirCids)+1 {
+		t.Error("unexpected number of blocks imported")
+	}
+
+	p.Shard = true
+	f := sth.GetTreeSerialFile(t)
+	defer f.Close()
+	adder = New(dags, p, nil)
+	_, err = adder.FromFiles(context.Background(), f)
+	if err == nil {
+		t.Error("expected an error when asking for a manifest of a sharded add")
+	}
+}
+
 func TestAdder_DoubleStart(t *testing.T) {
 	sth := test.NewShardingTestHelper()
 	defer sth.Clean(t)
@@ -141,3 +193,77 @@ func TestAdder_ContextCancelled(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func TestAdder_SlowConsumer(t *testing.T) {
+	sth := test.NewShardingTestHelper()
+	defer sth.Clean(t)
+
+	mr, closer := sth.GetTreeMultiReader(t)
+	defer closer.Close()
+	r := multipart.NewReader(mr, mr.Boundary())
+	p := api.DefaultAddParams()
+	p.Progress = true
+
+	dags := &mockCDAGServ{
+		resultCids: make(map[string]struct{}),
+	}
+
+	// Nobody reads the output until the add has finished.
+	output := make(chan *api.AddedOutput)
+	adder := New(dags, p, BufferOutput(output, 1))
+
+	done := make(chan struct{})
+	var root cid.Cid
+	var err error
+	go func() {
+		defer close(done)
+		root, err = adder.FromMultipart(context.Background(), r)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the add should not wait for the consumer")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foundRoot := false
+	for ao := range output {
+		if ao.Cid.Equals(root) {
+			foundRoot = true
+		}
+	}
+	if !foundRoot {
+		t.Error("the output for the root should not be dropped")
+	}
+}
+
+func TestBufferOutput(t *testing.T) {
+	output := make(chan *api.AddedOutput)
+	in := BufferOutput(output, 2)
+
+	for i := 0; i < 10; i++ {
+		in <- &api.AddedOutput{Name: "a", Bytes: uint64(i)}
+	}
+	in <- &api.AddedOutput{Name: "a", Cid: test.Cid1}
+	in <- &api.AddedOutput{Name: "b", Cid: test.Cid2}
+	close(in)
+
+	var progress, final []*api.AddedOutput
+	for ao := range output {
+		if ao.Cid.Defined() {
+			final = append(final, ao)
+		} else {
+			progress = append(progress, ao)
+		}
+	}
+
+	if len(progress) == 0 || len(progress) > 3 {
+		t.Errorf("expected the buffer to keep between 1 and 3 progress updates: %d", len(progress))
+	}
+	if len(final) != 2 || !final[0].Cid.Equals(test.Cid1) || !final[1].Cid.Equals(test.Cid2) {
+		t.Error("expected all final outputs in order")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/ipfs/ipfs-cluster/adder/local"
 	"github.com/ipfs/ipfs-cluster/adder/sharding"
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/api/limits"
 
 	cid "github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log"
@@ -34,9 +35,12 @@ func AddMultipartHTTPHandler(
 ) (cid.Cid, error) {
 	var dags adder.ClusterDAGService
 	output := make(chan *api.AddedOutput, 200)
+	// The add pipeline writes to addOutput so that a slow client does
+	// not stall it.
+	addOutput := adder.BufferOutput(output, adder.DefaultOutputBufferSize)
 
 	if params.Shard {
-		dags = sharding.New(rpc, params.PinOptions, output)
+		dags = sharding.New(rpc, params.PinOptions, addOutput)
 	} else {
 		dags = local.New(rpc, params.PinOptions)
 	}
@@ -67,13 +71,17 @@ func AddMultipartHTTPHandler(
 		}()
 
 		enc := json.NewEncoder(w)
-		add := adder.New(dags, params, output)
+		add := adder.New(dags, params, addOutput)
 		root, err := add.FromMultipart(ctx, reader)
 		if err != nil { // Send an error
 			logger.Error(err)
-			w.WriteHeader(http.StatusInternalServerError)
+			status := http.StatusInternalServerError
+			if limitStatus, ok := limits.ErrorStatus(err); ok {
+				status = limitStatus
+			}
+			w.WriteHeader(status)
 			errorResp := api.Error{
-				Code:    http.StatusInternalServerError,
+				Code:    status,
 				Message: err.Error(),
 			}
 
@@ -101,7 +109,7 @@ func AddMultipartHTTPHandler(
 		defer wg.Done()
 		streamOutput(w, output, outputTransform)
 	}()
-	add := adder.New(dags, params, output)
+	add := adder.New(dags, params, addOutput)
 	root, err := add.FromMultipart(ctx, reader)
 	if err != nil {
 		logger.Error(err)
@@ -125,6 +133,9 @@ func streamOutput(w http.ResponseWriter, output chan *api.AddedOutput, transform
 			flusher.Flush()
 		}
 	}
+	// Drain the rest so that the adder can finish.
+	for range output {
+	}
 }
 
 func buildOutput(output chan *api.AddedOutput, transform func(*api.AddedOutput) interface{}) []interface{} {
@@ -30,6 +30,7 @@ type DAGService struct {
 
 	dests   []peer.ID
 	pinOpts api.PinOptions
+	addID   string
 }
 
 // New returns a new Adder with the given rpc Client. The client is used
@@ -39,13 +40,18 @@ func New(rpc *rpc.Client, opts api.PinOptions) *DAGService {
 		rpcClient: rpc,
 		dests:     nil,
 		pinOpts:   opts,
+		addID:     adder.NewAddID(),
 	}
 }
 
 // Add puts the given node in the destination peers.
 func (dgs *DAGService) Add(ctx context.Context, node ipld.Node) error {
 	if dgs.dests == nil {
-		dests, err := adder.BlockAllocate(ctx, dgs.rpcClient, dgs.pinOpts)
+		dests, err := adder.BlockAllocate(
+			ctx,
+			dgs.rpcClient,
+			api.PinWithOpts(cid.Undef, dgs.pinOpts),
+		)
 		if err != nil {
 			return err
 		}
@@ -60,6 +66,7 @@ func (dgs *DAGService) Add(ctx context.Context, node ipld.Node) error {
 		Cid:     node.Cid(),
 		Data:    node.RawData(),
 		CumSize: size,
+		AddID:   dgs.addID,
 	}
 
 	return adder.PutBlock(ctx, dgs.rpcClient, nodeSerial, dgs.dests)
@@ -68,12 +75,13 @@ func (dgs *DAGService) Add(ctx context.Context, node ipld.Node) error {
 // Finalize pins the last Cid added to this DAGService.
 func (dgs *DAGService) Finalize(ctx context.Context, root cid.Cid) (cid.Cid, error) {
 	// Cluster pin the result
+	dests := dgs.dests
 	rootPin := api.PinWithOpts(root, dgs.pinOpts)
-	rootPin.Allocations = dgs.dests
+	rootPin.Allocations = dests
 
 	dgs.dests = nil
 
-	return root, dgs.rpcClient.CallContext(
+	err := dgs.rpcClient.CallContext(
 		ctx,
 		"",
 		"Cluster",
@@ -81,6 +89,8 @@ func (dgs *DAGService) Finalize(ctx context.Context, root cid.Cid) (cid.Cid, err
 		rootPin,
 		&struct{}{},
 	)
+	adder.FinishAdd(ctx, dgs.rpcClient, dgs.addID, dests)
+	return root, err
 }
 
 // AddMany calls Add for every given node.
@@ -0,0 +1,107 @@
+package adder
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	cid "github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	ipld "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// A manifest is a dag-cbor object listing the path and CID of every file
+// and directory added in an add session, along with the content root:
+//
+//   {"root": <cid>, "files": {"<path>": <cid>, ...}}
+//
+// It lets applications map paths to CIDs without walking the DAG.
+
+// manifestRecorder sits between the ipfs adder and the Adder output,
+// forwarding every output while recording the added files and directories.
+type manifestRecorder struct {
+	in  chan *api.AddedOutput
+	out chan *api.AddedOutput
+
+	files map[string]cid.Cid
+	last  *api.AddedOutput
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newManifestRecorder(out chan *api.AddedOutput) *manifestRecorder {
+	mr := &manifestRecorder{
+		in:    make(chan *api.AddedOutput, cap(out)),
+		out:   out,
+		files: make(map[string]cid.Cid),
+		done:  make(chan struct{}),
+	}
+	go mr.run()
+	return mr
+}
+
+func (mr *manifestRecorder) run() {
+	defer close(mr.done)
+	for ao := range mr.in {
+		if ao.Cid.Defined() {
+			mr.last = ao
+			if ao.Name != "" {
+				mr.files[ao.Name] = ao.Cid
+			}
+		}
+		mr.out <- ao
+	}
+}
+
+// close stops recording and waits until all recorded outputs have been
+// forwarded. It can be called several times.
+func (mr *manifestRecorder) close() {
+	mr.closeOnce.Do(func() {
+		close(mr.in)
+	})
+	<-mr.done
+}
+
+// node builds the manifest for the given content root. It must be called
+// after close.
+func (mr *manifestRecorder) node(root cid.Cid) (ipld.Node, error) {
+	obj := map[string]interface{}{
+		"root":  root,
+		"files": mr.files,
+	}
+	return cbor.WrapObject(obj, mh.SHA2_256, mh.DefaultLengths[mh.SHA2_256])
+}
+
+// addManifest adds and pins the manifest of this add session and sends a
+// final output for the content root carrying the manifest CID.
+func (a *Adder) addManifest(ctx context.Context, mr *manifestRecorder, root cid.Cid) error {
+	mr.close()
+
+	node, err := mr.node(root)
+	if err != nil {
+		return err
+	}
+	err = a.dgs.Add(ctx, node)
+	if err != nil {
+		return err
+	}
+	manifest, err := a.dgs.Finalize(ctx, node.Cid())
+	if err != nil {
+		return err
+	}
+	logger.Infof("%s: added manifest %s", root, manifest)
+
+	final := &api.AddedOutput{
+		Cid:      root,
+		Manifest: &manifest,
+	}
+	if mr.last != nil {
+		final.Name = mr.last.Name
+		final.Size = mr.last.Size
+	}
+	a.output <- final
+	return nil
+}
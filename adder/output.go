@@ -0,0 +1,71 @@
+package adder
+
+import (
+	"context"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/observations"
+
+	"go.opencensus.io/stats"
+)
+
+// DefaultOutputBufferSize is the default number of progress updates that
+// BufferOutput keeps while waiting for a slow consumer.
+const DefaultOutputBufferSize = 200
+
+// BufferOutput decouples the add pipeline from the consumer of its output.
+// It returns a channel which can be given to New (and to the
+// ClusterDAGService) instead of out. Values sent on it never block on the
+// consumer: they are queued and forwarded to out in order.
+//
+// When more than size updates are pending, new progress updates (those
+// without a CID) are dropped. Outputs for added blocks, files and shards are
+// never dropped. out is closed once the returned channel has been closed and
+// all pending outputs have been delivered.
+func BufferOutput(out chan<- *api.AddedOutput, size int) chan *api.AddedOutput {
+	in := make(chan *api.AddedOutput, size)
+	go forwardOutput(in, out, size)
+	return in
+}
+
+func forwardOutput(in <-chan *api.AddedOutput, out chan<- *api.AddedOutput, size int) {
+	defer close(out)
+
+	var queue []*api.AddedOutput
+	dropped := int64(0)
+	for in != nil || len(queue) > 0 {
+		var sendCh chan<- *api.AddedOutput
+		var next *api.AddedOutput
+		if len(queue) > 0 {
+			sendCh = out
+			next = queue[0]
+		}
+
+		select {
+		case ao, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			if isProgress(ao) && len(queue) >= size {
+				dropped++
+				continue
+			}
+			queue = append(queue, ao)
+		case sendCh <- next:
+			queue[0] = nil
+			queue = queue[1:]
+		}
+	}
+
+	if dropped > 0 {
+		logger.Debugf("dropped %d progress updates for a slow consumer", dropped)
+		stats.Record(context.Background(), observations.AddDroppedProgress.M(dropped))
+	}
+}
+
+// isProgress returns true for outputs which only report the number of bytes
+// processed so far.
+func isProgress(ao *api.AddedOutput) bool {
+	return !ao.Cid.Defined()
+}
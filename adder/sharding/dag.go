@@ -123,13 +123,14 @@ func makeDAG(ctx context.Context, dagObj map[string]cid.Cid) ([]ipld.Node, error
 	return nodes, nil
 }
 
-func putDAG(ctx context.Context, rpcC *rpc.Client, nodes []ipld.Node, dests []peer.ID) error {
+func putDAG(ctx context.Context, rpcC *rpc.Client, nodes []ipld.Node, dests []peer.ID, addID string) error {
 	for _, n := range nodes {
 		//logger.Debugf("The dag cbor Node Links: %+v", n.Links())
 		b := &api.NodeWithMeta{
 			Cid:    n.Cid(), // Tests depend on this.
 			Data:   n.RawData(),
 			Format: "cbor",
+			AddID:  addID,
 		}
 		//logger.Debugf("Here is the serialized ipld: %x", b.Data)
 
@@ -146,7 +147,7 @@ func putDAG(ctx context.Context, rpcC *rpc.Client, nodes []ipld.Node, dests []pe
 			rpcutil.RPCDiscardReplies(len(dests)),
 		)
 
-		if err := rpcutil.CheckErrs(errs); err != nil {
+		if err := rpcutil.CheckPeerErrs(dests, errs); err != nil {
 			return err
 		}
 	}
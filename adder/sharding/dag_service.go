@@ -48,6 +48,10 @@ type DAGService struct {
 
 	startTime time.Time
 	totalSize uint64
+
+	addID string
+	// peers which received blocks
+	dests map[peer.ID]struct{}
 }
 
 // New returns a new ClusterDAGService, which uses the given rpc client to perform
@@ -60,6 +64,8 @@ func New(rpc *rpc.Client, opts api.PinOptions, out chan<- *api.AddedOutput) *DAG
 		addedSet:  cid.NewSet(),
 		shards:    make(map[string]cid.Cid),
 		startTime: time.Now(),
+		addID:     adder.NewAddID(),
+		dests:     make(map[peer.ID]struct{}),
 	}
 }
 
@@ -79,6 +85,7 @@ func (dgs *DAGService) Add(ctx context.Context, node ipld.Node) error {
 		Cid:     node.Cid(),
 		Data:    node.RawData(),
 		CumSize: size,
+		AddID:   dgs.addID,
 	}
 
 	return dgs.ingestBlock(ctx, nodeSerial)
@@ -87,6 +94,8 @@ func (dgs *DAGService) Add(ctx context.Context, node ipld.Node) error {
 // Finalize finishes sharding, creates the cluster DAG and pins it along
 // with the meta pin for the root node of the content.
 func (dgs *DAGService) Finalize(ctx context.Context, dataRoot cid.Cid) (cid.Cid, error) {
+	defer dgs.finishAdd(ctx)
+
 	lastCid, err := dgs.flushCurrentShard(ctx)
 	if err != nil {
 		return lastCid, err
@@ -102,7 +111,8 @@ func (dgs *DAGService) Finalize(ctx context.Context, dataRoot cid.Cid) (cid.Cid,
 	}
 
 	// PutDAG to ourselves
-	err = putDAG(ctx, dgs.rpcClient, clusterDAGNodes, []peer.ID{""})
+	dgs.dests[""] = struct{}{}
+	err = putDAG(ctx, dgs.rpcClient, clusterDAGNodes, []peer.ID{""}, dgs.addID)
 	if err != nil {
 		return dataRoot, err
 	}
@@ -171,11 +181,14 @@ func (dgs *DAGService) ingestBlock(ctx context.Context, n *api.NodeWithMeta) err
 	if shard == nil {
 		logger.Infof("new shard for '%s': #%d", dgs.pinOpts.Name, len(dgs.shards))
 		var err error
-		shard, err = newShard(ctx, dgs.rpcClient, dgs.pinOpts)
+		shard, err = newShard(ctx, dgs.rpcClient, dgs.pinOpts, dgs.addID)
 		if err != nil {
 			return err
 		}
 		dgs.currentShard = shard
+		for _, p := range shard.Allocations() {
+			dgs.dests[p] = struct{}{}
+		}
 	}
 
 	logger.Debugf("ingesting block %s in shard %d (%s)", n.Cid, len(dgs.shards), dgs.pinOpts.Name)
@@ -209,6 +222,15 @@ func (dgs *DAGService) ingestBlock(ctx context.Context, n *api.NodeWithMeta) err
 	return dgs.ingestBlock(ctx, n) // <-- retry ingest
 }
 
+// finishAdd tells the peers which received blocks that the add finished.
+func (dgs *DAGService) finishAdd(ctx context.Context) {
+	dests := make([]peer.ID, 0, len(dgs.dests))
+	for p := range dgs.dests {
+		dests = append(dests, p)
+	}
+	adder.FinishAdd(ctx, dgs.rpcClient, dgs.addID, dests)
+}
+
 func (dgs *DAGService) logStats(metaPin, clusterDAGPin cid.Cid) {
 	duration := time.Since(dgs.startTime)
 	seconds := uint64(duration) / uint64(time.Second)
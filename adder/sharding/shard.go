@@ -20,6 +20,7 @@ type shard struct {
 	rpc         *rpc.Client
 	allocations []peer.ID
 	pinOptions  api.PinOptions
+	addID       string
 	// dagNode represents a node with links and will be converted
 	// to Cbor.
 	dagNode     map[string]cid.Cid
@@ -27,8 +28,11 @@ type shard struct {
 	sizeLimit   uint64
 }
 
-func newShard(ctx context.Context, rpc *rpc.Client, opts api.PinOptions) (*shard, error) {
-	allocs, err := adder.BlockAllocate(ctx, rpc, opts)
+func newShard(ctx context.Context, rpc *rpc.Client, opts api.PinOptions, addID string) (*shard, error) {
+	pin := api.PinWithOpts(cid.Undef, opts)
+	pin.Type = api.ShardType
+	pin.MaxDepth = 1
+	allocs, err := adder.BlockAllocate(ctx, rpc, pin)
 	if err != nil {
 		return nil, err
 	}
@@ -49,6 +53,7 @@ func newShard(ctx context.Context, rpc *rpc.Client, opts api.PinOptions) (*shard
 		rpc:         rpc,
 		allocations: allocs,
 		pinOptions:  opts,
+		addID:       addID,
 		dagNode:     make(map[string]cid.Cid),
 		currentSize: 0,
 		sizeLimit:   opts.ShardSize,
@@ -81,7 +86,7 @@ func (sh *shard) Flush(ctx context.Context, shardN int, prev cid.Cid) (cid.Cid,
 		return cid.Undef, err
 	}
 
-	err = putDAG(ctx, sh.rpc, nodes, sh.allocations)
+	err = putDAG(ctx, sh.rpc, nodes, sh.allocations, sh.addID)
 	if err != nil {
 		return cid.Undef, err
 	}
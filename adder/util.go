@@ -8,6 +8,7 @@ import (
 	"github.com/ipfs/ipfs-cluster/api"
 	"github.com/ipfs/ipfs-cluster/rpcutil"
 
+	uuid "github.com/google/uuid"
 	cid "github.com/ipfs/go-cid"
 	ipld "github.com/ipfs/go-ipld-format"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
@@ -38,11 +39,40 @@ func PutBlock(ctx context.Context, rpc *rpc.Client, n *api.NodeWithMeta, dests [
 		n,
 		rpcutil.RPCDiscardReplies(len(dests)),
 	)
-	return rpcutil.CheckErrs(errs)
+	return rpcutil.CheckPeerErrs(dests, errs)
 }
 
-// BlockAllocate helps allocating blocks to peers.
-func BlockAllocate(ctx context.Context, rpc *rpc.Client, pinOpts api.PinOptions) ([]peer.ID, error) {
+// NewAddID returns a random identifier for an add, which is set in the
+// blocks it puts.
+func NewAddID() string {
+	return uuid.New().String()
+}
+
+// FinishAdd tells the given destinations that the add with the given ID
+// has finished putting blocks, and that its pins have been submitted.
+// Errors are only logged, as peers forget about adds which stop putting
+// blocks anyways.
+func FinishAdd(ctx context.Context, rpc *rpc.Client, addID string, dests []peer.ID) {
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, len(dests))
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := rpc.MultiCall(
+		ctxs,
+		dests,
+		"IPFSConnector",
+		"BlockPutDone",
+		addID,
+		rpcutil.RPCDiscardReplies(len(dests)),
+	)
+	if err := rpcutil.CheckPeerErrs(dests, errs); err != nil {
+		logger.Warningf("error finishing add %s: %s", addID, err)
+	}
+}
+
+// BlockAllocate helps allocating blocks to peers. The given pin, without
+// Cid, carries the options and the type of the pin the blocks will belong
+// to.
+func BlockAllocate(ctx context.Context, rpc *rpc.Client, pin *api.Pin) ([]peer.ID, error) {
 	// Find where to allocate this file
 	var allocsStr []peer.ID
 	err := rpc.CallContext(
@@ -50,7 +80,7 @@ func BlockAllocate(ctx context.Context, rpc *rpc.Client, pinOpts api.PinOptions)
 		"",
 		"Cluster",
 		"BlockAllocate",
-		api.PinWithOpts(cid.Undef, pinOpts),
+		pin,
 		&allocsStr,
 	)
 	return allocsStr, err
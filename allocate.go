@@ -2,10 +2,16 @@ package ipfscluster
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	namespace "github.com/ipfs/go-datastore/namespace"
+	query "github.com/ipfs/go-datastore/query"
 	peer "github.com/libp2p/go-libp2p-peer"
 
 	"go.opencensus.io/trace"
@@ -22,10 +28,13 @@ import (
 //
 // * Find which peers are pinning a CID
 // * Obtain the last values for the configured informer metrics from the
-//   monitor component
+//   monitor component, adjusted by any capacity reservations (see
+//   reservations.go)
 // * Divide the metrics between "current" (peers already pinning the CID)
 //   and "candidates" (peers that could pin the CID), as long as their metrics
-//   are valid.
+//   are valid. Peers which are not allocatable, or which do not have enough
+//   free space for the estimated size of the pin, are only kept when they
+//   are already pinning the CID.
 // * Given the candidates:
 //   * Check if we are overpinning an item
 //   * Check if there are not enough candidates for the "needed" replication
@@ -39,6 +48,13 @@ import (
 //       ReplicationFactorMax is reached. Error if there are less than
 //       ReplicationFactorMin.
 
+var errNoAllocationExplanation = errors.New("no allocation information available for this CID")
+
+// allocationsNamespace is the datastore namespace under which the
+// explanations of the allocation decisions made by this peer are stored,
+// keyed by Cid, so that they survive restarts.
+var allocationsNamespace = "/allocations"
+
 // allocate finds peers to allocate a hash using the informer and the monitor
 // it should only be used with valid replicationFactors (if rplMin and rplMax
 // are > 0, then rplMin <= rplMax).
@@ -47,10 +63,14 @@ import (
 // into account if the given CID was previously in a "pin everywhere" mode,
 // and will consider such Pins as currently unallocated ones, providing
 // new allocations as available.
-func (c *Cluster) allocate(ctx context.Context, hash cid.Cid, rplMin, rplMax int, blacklist []peer.ID, prioritylist []peer.ID) ([]peer.ID, error) {
+func (c *Cluster) allocate(ctx context.Context, pin *api.Pin, blacklist []peer.ID, prioritylist []peer.ID) ([]peer.ID, error) {
 	ctx, span := trace.StartSpan(ctx, "cluster/allocate")
 	defer span.End()
 
+	hash := pin.Cid
+	rplMin := pin.ReplicationFactorMin
+	rplMax := pin.ReplicationFactorMax
+
 	if (rplMin + rplMax) == 0 {
 		return nil, fmt.Errorf("bad replication factors: %d/%d", rplMin, rplMax)
 	}
@@ -65,7 +85,17 @@ func (c *Cluster) allocate(ctx context.Context, hash cid.Cid, rplMin, rplMax int
 	if err == nil {
 		currentAllocs = currentPin.Allocations
 	}
-	metrics := c.monitor.LatestMetrics(ctx, c.informer.Name())
+	metrics := c.reserveMetrics(ctx, c.monitor.LatestMetrics(ctx, c.informer.Name()))
+	notAllocatable := c.notAllocatablePeers(ctx)
+
+	expl := &api.AllocationExplanation{
+		Cid:       hash,
+		Peer:      c.id,
+		Allocator: c.allocator.Name(),
+		Informer:  c.informer.Name(),
+		Metrics:   metrics,
+		TS:        time.Now(),
+	}
 
 	currentMetrics := make(map[peer.ID]*api.Metric)
 	candidatesMetrics := make(map[peer.ID]*api.Metric)
@@ -78,9 +108,16 @@ func (c *Cluster) allocate(ctx context.Context, hash cid.Cid, rplMin, rplMax int
 		switch {
 		case containsPeer(blacklist, m.Peer):
 			// discard blacklisted peers
+			expl.Reject(m.Peer, "blacklisted")
 			continue
 		case containsPeer(currentAllocs, m.Peer):
 			currentMetrics[m.Peer] = m
+		case containsPeer(notAllocatable, m.Peer):
+			expl.Reject(m.Peer, "not allocatable")
+			continue
+		case !c.hasSpaceFor(m, pin.Size):
+			expl.Reject(m.Peer, "not enough free space")
+			continue
 		case containsPeer(prioritylist, m.Peer):
 			priorityMetrics[m.Peer] = m
 		default:
@@ -88,14 +125,14 @@ func (c *Cluster) allocate(ctx context.Context, hash cid.Cid, rplMin, rplMax int
 		}
 	}
 
-	newAllocs, err := c.obtainAllocations(
+	newAllocs, err := obtainAllocations(
 		ctx,
-		hash,
-		rplMin,
-		rplMax,
+		c.allocator,
+		pin,
 		currentMetrics,
 		candidatesMetrics,
 		priorityMetrics,
+		expl,
 	)
 	if err != nil {
 		return newAllocs, err
@@ -103,9 +140,132 @@ func (c *Cluster) allocate(ctx context.Context, hash cid.Cid, rplMin, rplMax int
 	if newAllocs == nil {
 		newAllocs = currentAllocs
 	}
+
+	// Reserve capacity in the newly allocated peers so that concurrent
+	// allocations take them into account.
+	var reserved []peer.ID
+	for _, p := range newAllocs {
+		if !containsPeer(currentAllocs, p) {
+			reserved = append(reserved, p)
+		}
+	}
+	c.reservations.reserve(reserved)
+
+	expl.Allocations = newAllocs
+	c.recordAllocation(expl)
 	return newAllocs, nil
 }
 
+// notAllocatablePeers returns the peers which have announced that they
+// do not accept new allocations.
+func (c *Cluster) notAllocatablePeers(ctx context.Context) []peer.ID {
+	var peers []peer.ID
+	for _, m := range c.monitor.LatestMetrics(ctx, allocatableMetricName) {
+		if allocatable, err := strconv.ParseBool(m.Value); err == nil && !allocatable {
+			peers = append(peers, m.Peer)
+		}
+	}
+	return peers
+}
+
+// hasSpaceFor returns false when the given metric reports less free space
+// than the given size. Peers are not ruled out when the size is unknown or
+// the allocation informer does not report free space.
+func (c *Cluster) hasSpaceFor(m *api.Metric, size uint64) bool {
+	spacer, ok := c.informer.(FreeSpacer)
+	if !ok || size == 0 {
+		return true
+	}
+	free, ok := spacer.FreeSpace(m)
+	return !ok || free >= size
+}
+
+func (c *Cluster) allocationStore() ds.Datastore {
+	return namespace.Wrap(c.datastore, ds.NewKey(allocationsNamespace))
+}
+
+func allocationKey(h cid.Cid) ds.Key {
+	return ds.NewKey(h.String())
+}
+
+// recordAllocation stores the explanation of the last allocation decision
+// made by this peer for a Cid.
+func (c *Cluster) recordAllocation(expl *api.AllocationExplanation) {
+	data, err := json.Marshal(expl)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	err = c.allocationStore().Put(allocationKey(expl.Cid), data)
+	if err != nil {
+		logger.Errorf("error storing allocation explanation: %s", err)
+	}
+}
+
+// loadAllocation returns the stored allocation explanation for the given
+// Cid.
+func (c *Cluster) loadAllocation(h cid.Cid) (*api.AllocationExplanation, error) {
+	data, err := c.allocationStore().Get(allocationKey(h))
+	if err == ds.ErrNotFound {
+		return nil, errNoAllocationExplanation
+	}
+	if err != nil {
+		return nil, err
+	}
+	expl := &api.AllocationExplanation{}
+	err = json.Unmarshal(data, expl)
+	if err != nil {
+		return nil, fmt.Errorf("error reading allocation explanation: %s", err)
+	}
+	return expl, nil
+}
+
+// forgetAllocation removes any allocation explanation for the given Cid.
+func (c *Cluster) forgetAllocation(h cid.Cid) {
+	err := c.allocationStore().Delete(allocationKey(h))
+	if err != nil && err != ds.ErrNotFound {
+		logger.Errorf("error removing allocation explanation: %s", err)
+	}
+}
+
+// pruneAllocations removes the allocation explanations made before the
+// given time for Cids which are not among the given pins. Pins can be
+// removed from the shared state by any peer, so this runs on StateSync.
+// Recent explanations are kept, as their pins may not have been committed
+// yet.
+func (c *Cluster) pruneAllocations(pins []*api.Pin, before time.Time) {
+	inState := make(map[string]struct{}, len(pins))
+	for _, pin := range pins {
+		inState[allocationKey(pin.Cid).String()] = struct{}{}
+	}
+
+	store := c.allocationStore()
+	results, err := store.Query(query.Query{})
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	for _, e := range entries {
+		if _, ok := inState[e.Key]; ok {
+			continue
+		}
+		expl := &api.AllocationExplanation{}
+		if err := json.Unmarshal(e.Value, expl); err == nil && !expl.TS.Before(before) {
+			continue
+		}
+		err := store.Delete(ds.NewKey(e.Key))
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+}
+
 // allocationError logs an allocation error
 func allocationError(hash cid.Cid, needed, wanted int, candidatesValid []peer.ID) error {
 	logger.Errorf("Not enough candidates to allocate %s:", hash)
@@ -123,17 +283,24 @@ func allocationError(hash cid.Cid, needed, wanted int, candidatesValid []peer.ID
 	return errors.New(errorMsg)
 }
 
-func (c *Cluster) obtainAllocations(
+// obtainAllocations decides the allocations of a pin with the given
+// allocator. It returns nil when the current allocations can be kept.
+func obtainAllocations(
 	ctx context.Context,
-	hash cid.Cid,
-	rplMin, rplMax int,
+	allocator PinAllocator,
+	pin *api.Pin,
 	currentValidMetrics map[peer.ID]*api.Metric,
 	candidatesMetrics map[peer.ID]*api.Metric,
 	priorityMetrics map[peer.ID]*api.Metric,
+	expl *api.AllocationExplanation,
 ) ([]peer.ID, error) {
 	ctx, span := trace.StartSpan(ctx, "cluster/obtainAllocations")
 	defer span.End()
 
+	hash := pin.Cid
+	rplMin := pin.ReplicationFactorMin
+	rplMax := pin.ReplicationFactorMax
+
 	// The list of peers in current
 	validAllocations := make([]peer.ID, 0, len(currentValidMetrics))
 	for k := range currentValidMetrics {
@@ -156,11 +323,21 @@ func (c *Cluster) obtainAllocations(
 		// This could be done more intelligently by dropping them
 		// according to the allocator order (i.e. free-ing peers
 		// with most used space first).
-		return validAllocations[0 : len(validAllocations)+wanted], nil
+		keep := len(validAllocations) + wanted
+		for _, p := range validAllocations[keep:] {
+			expl.Reject(p, "dropped: above replication factor max")
+		}
+		return validAllocations[0:keep], nil
 	}
 
 	if needed <= 0 { // allocations are above minimal threshold
 		// We don't provide any new allocations
+		for p := range priorityMetrics {
+			expl.Reject(p, "not needed: replication factor min satisfied")
+		}
+		for p := range candidatesMetrics {
+			expl.Reject(p, "not needed: replication factor min satisfied")
+		}
 		return nil, nil
 	}
 
@@ -179,19 +356,42 @@ func (c *Cluster) obtainAllocations(
 	// on the priority of candidates grab as many as "wanted"
 
 	// the allocator returns a list of peers ordered by priority
-	finalAllocs, err := c.allocator.Allocate(
-		ctx,
-		hash,
-		currentValidMetrics,
-		candidatesMetrics,
-		priorityMetrics,
-	)
+	var finalAllocs []peer.ID
+	var err error
+	if pinAware, ok := allocator.(PinAwareAllocator); ok {
+		finalAllocs, err = pinAware.AllocatePin(
+			ctx,
+			pin,
+			currentValidMetrics,
+			candidatesMetrics,
+			priorityMetrics,
+		)
+	} else {
+		finalAllocs, err = allocator.Allocate(
+			ctx,
+			hash,
+			currentValidMetrics,
+			candidatesMetrics,
+			priorityMetrics,
+		)
+	}
 	if err != nil {
 		return nil, logError(err.Error())
 	}
 
 	logger.Debugf("obtainAllocations: allocate(): %s", finalAllocs)
 
+	for p := range priorityMetrics {
+		if !containsPeer(finalAllocs, p) {
+			expl.Reject(p, "discarded by allocator")
+		}
+	}
+	for p := range candidatesMetrics {
+		if !containsPeer(finalAllocs, p) {
+			expl.Reject(p, "discarded by allocator")
+		}
+	}
+
 	// check that we have enough as the allocator may have returned
 	// less candidates than provided.
 	if got := len(finalAllocs); got < needed {
@@ -199,6 +399,9 @@ func (c *Cluster) obtainAllocations(
 	}
 
 	allocationsToUse := minInt(wanted, len(finalAllocs))
+	for _, p := range finalAllocs[allocationsToUse:] {
+		expl.Reject(p, "lower priority: replication factor max reached")
+	}
 
 	// the final result is the currently valid allocations
 	// along with the ones provided by the allocator
@@ -26,6 +26,9 @@ func NewAllocator() AscendAllocator {
 	return AscendAllocator{}
 }
 
+// Name returns "ascendalloc".
+func (alloc AscendAllocator) Name() string { return "ascendalloc" }
+
 // SetClient does nothing in this allocator
 func (alloc AscendAllocator) SetClient(c *rpc.Client) {}
 
@@ -26,6 +26,9 @@ func NewAllocator() DescendAllocator {
 	return DescendAllocator{}
 }
 
+// Name returns "descendalloc".
+func (alloc DescendAllocator) Name() string { return "descendalloc" }
+
 // SetClient does nothing in this allocator
 func (alloc DescendAllocator) SetClient(c *rpc.Client) {}
 
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"sort"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// FilterCandidates removes duplicates and peers which are neither among the
+// candidates nor the priority candidates from a list of allocations
+// obtained from an external source, preserving the order. The peers which
+// were not candidates are returned separately.
+func FilterCandidates(allocs []peer.ID, candidates, priority map[peer.ID]*api.Metric) (valid []peer.ID, ignored []peer.ID) {
+	seen := make(map[peer.ID]struct{}, len(allocs))
+	valid = make([]peer.ID, 0, len(allocs))
+	for _, p := range allocs {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+
+		_, isCandidate := candidates[p]
+		_, isPriority := priority[p]
+		if !isCandidate && !isPriority {
+			ignored = append(ignored, p)
+			continue
+		}
+		valid = append(valid, p)
+	}
+	return valid, ignored
+}
+
+// SortByPeer returns the given metrics in a slice sorted by peer, so that
+// they can be passed on in a deterministic order.
+func SortByPeer(metrics map[peer.ID]*api.Metric) []*api.Metric {
+	list := make([]*api.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Peer < list[j].Peer
+	})
+	return list
+}
@@ -0,0 +1,135 @@
+package webhookalloc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/kelseyhightower/envconfig"
+)
+
+const configKey = "webhookalloc"
+const envConfigKey = "cluster_webhookalloc"
+
+// These are the default values for a Config.
+const (
+	DefaultEndpoint = ""
+	DefaultTimeout  = 5 * time.Second
+)
+
+// Config allows to initialize a WebhookAllocator.
+type Config struct {
+	config.Saver
+
+	// Endpoint is the URL to which allocation requests are POSTed. It
+	// must be set in order to use this allocator.
+	Endpoint string
+	// Timeout limits how long an allocation request can take.
+	Timeout time.Duration
+	// Headers are added to every request (i.e. for authorization).
+	Headers map[string]string
+}
+
+type jsonConfig struct {
+	Endpoint string            `json:"endpoint"`
+	Timeout  string            `json:"timeout"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// ConfigKey returns a human-friendly identifier for this
+// Config's type.
+func (cfg *Config) ConfigKey() string {
+	return configKey
+}
+
+// Default initializes this Config with sensible values.
+func (cfg *Config) Default() error {
+	cfg.Endpoint = DefaultEndpoint
+	cfg.Timeout = DefaultTimeout
+	cfg.Headers = nil
+	return nil
+}
+
+// ApplyEnvVars fills in any Config fields found
+// as environment variables.
+func (cfg *Config) ApplyEnvVars() error {
+	jcfg := cfg.toJSONConfig()
+
+	err := envconfig.Process(envConfigKey, jcfg)
+	if err != nil {
+		return err
+	}
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+// Validate checks that the fields of this configuration have
+// sensible values. An empty endpoint is valid, as the configuration is
+// present even when the allocator is not in use.
+func (cfg *Config) Validate() error {
+	if cfg.Timeout <= 0 {
+		return errors.New("webhookalloc.timeout is invalid")
+	}
+
+	if cfg.Endpoint == "" {
+		return nil
+	}
+
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return fmt.Errorf("webhookalloc.endpoint is invalid: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("webhookalloc.endpoint must be an http or https URL")
+	}
+	return nil
+}
+
+// LoadJSON parses a raw JSON byte-slice as generated by ToJSON().
+func (cfg *Config) LoadJSON(raw []byte) error {
+	jcfg := &jsonConfig{}
+	err := json.Unmarshal(raw, jcfg)
+	if err != nil {
+		return err
+	}
+
+	cfg.Default()
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
+	config.SetIfNotDefault(jcfg.Endpoint, &cfg.Endpoint)
+
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.Timeout, Dst: &cfg.Timeout, Name: "timeout"},
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(jcfg.Headers) > 0 {
+		cfg.Headers = jcfg.Headers
+	}
+
+	return cfg.Validate()
+}
+
+// ToJSON generates a human-friendly JSON representation of this Config.
+func (cfg *Config) ToJSON() ([]byte, error) {
+	jcfg := cfg.toJSONConfig()
+
+	return config.DefaultJSONMarshal(jcfg)
+}
+
+func (cfg *Config) toJSONConfig() *jsonConfig {
+	return &jsonConfig{
+		Endpoint: cfg.Endpoint,
+		Timeout:  cfg.Timeout.String(),
+		Headers:  cfg.Headers,
+	}
+}
@@ -0,0 +1,92 @@
+package webhookalloc
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+var cfgJSON = []byte(`
+{
+      "endpoint": "http://127.0.0.1:9999/allocate",
+      "timeout": "2s",
+      "headers": {
+        "Authorization": "Bearer abc"
+      }
+}
+`)
+
+func TestLoadJSON(t *testing.T) {
+	cfg := &Config{}
+	err := cfg.LoadJSON(cfgJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Endpoint != "http://127.0.0.1:9999/allocate" ||
+		cfg.Timeout != 2*time.Second ||
+		cfg.Headers["Authorization"] != "Bearer abc" {
+		t.Error("config not loaded correctly")
+	}
+
+	j := &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.Endpoint = "ftp://example.com"
+	tst, _ := json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with non-http endpoint")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.Timeout = "abc"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error decoding timeout")
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	cfg := &Config{}
+	cfg.LoadJSON(cfgJSON)
+	newjson, err := cfg.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = &Config{}
+	err = cfg.LoadJSON(newjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Headers["Authorization"] != "Bearer abc" {
+		t.Error("headers not preserved")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+	if cfg.Validate() != nil {
+		t.Fatal("error validating")
+	}
+
+	cfg.Timeout = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+}
+
+func TestApplyEnvVars(t *testing.T) {
+	os.Setenv("CLUSTER_WEBHOOKALLOC_ENDPOINT", "https://example.com/alloc")
+	defer os.Unsetenv("CLUSTER_WEBHOOKALLOC_ENDPOINT")
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ApplyEnvVars()
+
+	if cfg.Endpoint != "https://example.com/alloc" {
+		t.Fatal("failed to override endpoint with env var")
+	}
+}
@@ -0,0 +1,156 @@
+// Package webhookalloc implements an ipfscluster.PinAllocator which
+// delegates allocation decisions to an external HTTP service. The candidate
+// peers, their metrics and the pin request are POSTed as JSON to the
+// configured endpoint, which answers with the peers to allocate in order of
+// preference. This allows encoding custom placement logic without
+// modifying ipfs-cluster.
+//
+// The request body has the form:
+//
+//	{
+//	  "pin": { ...pin object... },
+//	  "current": [ ...metrics of peers currently allocated... ],
+//	  "candidates": [ ...metrics of candidate peers... ],
+//	  "priority": [ ...metrics of priority candidate peers... ]
+//	}
+//
+// and the expected response is:
+//
+//	{ "allocations": [ "<peer ID>", ... ] }
+//
+// Returned peers which are not among the candidates are ignored. Candidates
+// left out of the response are not allocated.
+package webhookalloc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ipfs/ipfs-cluster/allocator/util"
+	"github.com/ipfs/ipfs-cluster/api"
+
+	cid "github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"go.opencensus.io/trace"
+)
+
+var logger = logging.Logger("webhookalloc")
+
+// maximum number of bytes of an error response included in errors.
+const maxErrorBody = 512
+
+// AllocationRequest is the body POSTed to the webhook endpoint.
+type AllocationRequest struct {
+	Pin        *api.Pin      `json:"pin"`
+	Current    []*api.Metric `json:"current"`
+	Candidates []*api.Metric `json:"candidates"`
+	Priority   []*api.Metric `json:"priority"`
+}
+
+// AllocationResponse is the body expected from the webhook endpoint.
+type AllocationResponse struct {
+	Allocations []peer.ID `json:"allocations"`
+}
+
+// WebhookAllocator is a PinAllocator which obtains allocations from an
+// HTTP endpoint.
+type WebhookAllocator struct {
+	config *Config
+	client *http.Client
+}
+
+// NewAllocator returns an initialized WebhookAllocator.
+func NewAllocator(cfg *Config) (*WebhookAllocator, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Endpoint == "" {
+		return nil, errors.New("webhookalloc.endpoint is not set")
+	}
+
+	return &WebhookAllocator{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Name returns "webhookalloc".
+func (alloc *WebhookAllocator) Name() string { return "webhookalloc" }
+
+// SetClient does nothing in this allocator
+func (alloc *WebhookAllocator) SetClient(c *rpc.Client) {}
+
+// Shutdown does nothing in this allocator
+func (alloc *WebhookAllocator) Shutdown(_ context.Context) error { return nil }
+
+// Allocate obtains the allocations for the given CID from the webhook.
+// Cluster uses AllocatePin instead, which sends the full pin request.
+func (alloc *WebhookAllocator) Allocate(ctx context.Context, c cid.Cid, current, candidates, priority map[peer.ID]*api.Metric) ([]peer.ID, error) {
+	return alloc.AllocatePin(ctx, api.PinCid(c), current, candidates, priority)
+}
+
+// AllocatePin POSTs the pin request along with the metrics of the current,
+// candidate and priority peers to the webhook and returns the peers in
+// the order provided by it.
+func (alloc *WebhookAllocator) AllocatePin(ctx context.Context, pin *api.Pin, current, candidates, priority map[peer.ID]*api.Metric) ([]peer.ID, error) {
+	ctx, span := trace.StartSpan(ctx, "allocator/webhookalloc/AllocatePin")
+	defer span.End()
+
+	body, err := json.Marshal(&AllocationRequest{
+		Pin:        pin,
+		Current:    util.SortByPeer(current),
+		Candidates: util.SortByPeer(candidates),
+		Priority:   util.SortByPeer(priority),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", alloc.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range alloc.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := alloc.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webhookalloc: request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf(
+			"webhookalloc: endpoint returned %s: %s",
+			resp.Status,
+			bytes.TrimSpace(msg),
+		)
+	}
+
+	var allocResp AllocationResponse
+	err = json.NewDecoder(resp.Body).Decode(&allocResp)
+	if err != nil {
+		return nil, fmt.Errorf("webhookalloc: error decoding response: %s", err)
+	}
+
+	allocs, ignored := util.FilterCandidates(allocResp.Allocations, candidates, priority)
+	for _, p := range ignored {
+		logger.Warningf("ignoring allocation to non-candidate peer %s", p.Pretty())
+	}
+	return allocs, nil
+}
@@ -0,0 +1,137 @@
+package webhookalloc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/test"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+var (
+	peer0   = test.PeerID1
+	peer1   = test.PeerID2
+	peer2   = test.PeerID3
+	peer3   = test.PeerID4
+	testCid = test.Cid1
+)
+
+var inAMinute = time.Now().Add(time.Minute).UnixNano()
+
+func testMetric(p peer.ID, v string) *api.Metric {
+	return &api.Metric{
+		Name:   "some-metric",
+		Peer:   p,
+		Value:  v,
+		Expire: inAMinute,
+		Valid:  true,
+	}
+}
+
+func testAllocator(t *testing.T, h http.HandlerFunc) (*WebhookAllocator, func()) {
+	srv := httptest.NewServer(h)
+	cfg := &Config{}
+	cfg.Default()
+	cfg.Endpoint = srv.URL
+	cfg.Headers = map[string]string{"Authorization": "Bearer abc"}
+	alloc, err := NewAllocator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alloc, srv.Close
+}
+
+func TestNewAllocator(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+	_, err := NewAllocator(cfg)
+	if err == nil {
+		t.Fatal("expected an error without endpoint")
+	}
+}
+
+func TestAllocatePin(t *testing.T) {
+	var got AllocationRequest
+	alloc, done := testAllocator(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Error("expected a POST request")
+		}
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			t.Error("expected configured headers")
+		}
+		err := json.NewDecoder(r.Body).Decode(&got)
+		if err != nil {
+			t.Error(err)
+		}
+		// return a non-candidate, a duplicate and leave peer1 out
+		json.NewEncoder(w).Encode(&AllocationResponse{
+			Allocations: []peer.ID{peer3, peer0, peer3, peer2},
+		})
+	})
+	defer done()
+
+	pin := api.PinCid(testCid)
+	pin.Name = "webhook"
+	pin.ReplicationFactorMin = 1
+	pin.ReplicationFactorMax = 2
+
+	current := map[peer.ID]*api.Metric{peer0: testMetric(peer0, "1")}
+	candidates := map[peer.ID]*api.Metric{
+		peer1: testMetric(peer1, "2"),
+		peer2: testMetric(peer2, "3"),
+	}
+	priority := map[peer.ID]*api.Metric{peer3: testMetric(peer3, "4")}
+
+	allocs, err := alloc.AllocatePin(context.Background(), pin, current, candidates, priority)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(allocs) != 2 || allocs[0] != peer3 || allocs[1] != peer2 {
+		t.Errorf("unexpected allocations: %s", allocs)
+	}
+
+	if !got.Pin.Cid.Equals(testCid) || got.Pin.Name != "webhook" ||
+		got.Pin.ReplicationFactorMax != 2 {
+		t.Error("pin request not sent correctly")
+	}
+	if len(got.Current) != 1 || len(got.Candidates) != 2 || len(got.Priority) != 1 {
+		t.Error("metrics not sent correctly")
+	}
+	if got.Candidates[0].Peer > got.Candidates[1].Peer {
+		t.Error("candidate metrics should be sorted by peer")
+	}
+}
+
+func TestAllocateError(t *testing.T) {
+	alloc, done := testAllocator(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no capacity", http.StatusServiceUnavailable)
+	})
+	defer done()
+
+	candidates := map[peer.ID]*api.Metric{peer1: testMetric(peer1, "2")}
+	_, err := alloc.Allocate(context.Background(), testCid, nil, candidates, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	t.Log(err)
+}
+
+func TestAllocateBadResponse(t *testing.T) {
+	alloc, done := testAllocator(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	})
+	defer done()
+
+	candidates := map[peer.ID]*api.Metric{peer1: testMetric(peer1, "2")}
+	_, err := alloc.Allocate(context.Background(), testCid, nil, candidates, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
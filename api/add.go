@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 
 	cid "github.com/ipfs/go-cid"
 )
@@ -19,6 +20,10 @@ type AddedOutput struct {
 	Cid   cid.Cid `json:"cid" codec:"c"`
 	Bytes uint64  `json:"bytes,omitempty" codec:"b,omitempty"`
 	Size  uint64  `json:"size,omitempty" codec:"s,omitempty"`
+	// Manifest is set in the last output of adds which requested a
+	// manifest. It is the CID of the pinned manifest object listing
+	// the CIDs of all the added files.
+	Manifest *cid.Cid `json:"manifest,omitempty" codec:"m,omitempty"`
 }
 
 // AddParams contains all of the configurable parameters needed to specify the
@@ -38,6 +43,73 @@ type AddParams struct {
 	HashFun        string
 	StreamChannels bool
 	NoCopy         bool
+	// CidBase is the multibase used to encode the CIDs in the add
+	// output. An empty value uses the default for each CID version.
+	CidBase string
+	// Manifest requests a manifest object listing the path and CID of
+	// every added file to be built and pinned after the content.
+	Manifest bool
+	// Profile names the add profile, as defined in the API
+	// configuration, whose options override the rest of these.
+	Profile string
+}
+
+// AddProfile is a named set of add options defined by the cluster
+// operator. When a profile is selected, its options take precedence over
+// those provided by the client, so that all content added with it is
+// chunked and replicated in the same way. Unset options are left as
+// given by the client.
+type AddProfile struct {
+	Chunker              string `json:"chunker,omitempty"`
+	Layout               string `json:"layout,omitempty"`
+	RawLeaves            *bool  `json:"raw_leaves,omitempty"`
+	ReplicationFactorMin int    `json:"replication_factor_min,omitempty"`
+	ReplicationFactorMax int    `json:"replication_factor_max,omitempty"`
+}
+
+// Validate checks that the options in the profile are valid.
+func (prof *AddProfile) Validate() error {
+	switch prof.Layout {
+	case "trickle", "balanced", "":
+	default:
+		return errors.New("layout is invalid")
+	}
+
+	if err := validateChunker(prof.Chunker); err != nil {
+		return err
+	}
+
+	rmin := prof.ReplicationFactorMin
+	rmax := prof.ReplicationFactorMax
+	switch {
+	case rmin < -1 || rmax < -1:
+		return errors.New("replication factors cannot be lower than -1")
+	case rmin > 0 && rmax > 0 && rmin > rmax:
+		return errors.New("replication_factor_min is greater than replication_factor_max")
+	case (rmin == -1) != (rmax == -1) && rmin != 0 && rmax != 0:
+		return errors.New("replication factors must both be -1 to pin everywhere")
+	}
+	return nil
+}
+
+// ApplyProfile overrides the options of p with those set in the given
+// profile.
+func (p *AddParams) ApplyProfile(prof *AddProfile) {
+	if prof.Chunker != "" {
+		p.Chunker = prof.Chunker
+	}
+	if prof.Layout != "" {
+		p.Layout = prof.Layout
+	}
+	if prof.RawLeaves != nil {
+		p.RawLeaves = *prof.RawLeaves
+	}
+	if prof.ReplicationFactorMin != 0 {
+		p.ReplicationFactorMin = prof.ReplicationFactorMin
+	}
+	if prof.ReplicationFactorMax != 0 {
+		p.ReplicationFactorMax = prof.ReplicationFactorMax
+	}
 }
 
 // DefaultAddParams returns a AddParams object with standard defaults
@@ -55,6 +127,8 @@ func DefaultAddParams() *AddParams {
 		HashFun:        "sha2-256",
 		StreamChannels: true,
 		NoCopy:         false,
+		Manifest:       false,
+		CidBase:        "",
 		PinOptions: PinOptions{
 			ReplicationFactorMin: 0,
 			ReplicationFactorMax: 0,
@@ -68,7 +142,7 @@ func parseBoolParam(q url.Values, name string, dest *bool) error {
 	if v := q.Get(name); v != "" {
 		b, err := strconv.ParseBool(v)
 		if err != nil {
-			return fmt.Errorf("parameter %s invalid", name)
+			return &ValidationError{Field: name, Message: fmt.Sprintf("%q is not a boolean", v)}
 		}
 		*dest = b
 	}
@@ -79,7 +153,7 @@ func parseIntParam(q url.Values, name string, dest *int) error {
 	if v := q.Get(name); v != "" {
 		i, err := strconv.Atoi(v)
 		if err != nil {
-			return fmt.Errorf("parameter %s invalid", name)
+			return &ValidationError{Field: name, Message: fmt.Sprintf("%q is not an integer", v)}
 		}
 		*dest = i
 	}
@@ -87,18 +161,12 @@ func parseIntParam(q url.Values, name string, dest *int) error {
 }
 
 // AddParamsFromQuery parses the AddParams object from
-// a URL.Query().
+// a URL.Query(). The resulting parameters are validated and
+// ValidationErrors are returned when they are not valid.
 func AddParamsFromQuery(query url.Values) (*AddParams, error) {
 	params := DefaultAddParams()
 
-	layout := query.Get("layout")
-	switch layout {
-	case "trickle", "balanced", "":
-		// nothing
-	default:
-		return nil, errors.New("layout parameter invalid")
-	}
-	params.Layout = layout
+	params.Layout = query.Get("layout")
 
 	chunker := query.Get("chunker")
 	params.Chunker = chunker
@@ -151,10 +219,19 @@ func AddParamsFromQuery(query url.Values) (*AddParams, error) {
 		return nil, err
 	}
 
+	if v := query.Get("cid-base"); v != "" {
+		if _, err := NewCidBaseEncoder(v); err != nil {
+			return nil, &ValidationError{Field: "cid-base", Message: err.Error()}
+		}
+		params.CidBase = v
+	}
+
+	params.Profile = query.Get("profile")
+
 	if v := query.Get("shard-size"); v != "" {
 		shardSize, err := strconv.ParseUint(v, 10, 64)
 		if err != nil {
-			return nil, errors.New("parameter shard_size is invalid")
+			return nil, &ValidationError{Field: "shard-size", Message: fmt.Sprintf("%q is not a valid size", v)}
 		}
 		params.ShardSize = shardSize
 	}
@@ -169,7 +246,22 @@ func AddParamsFromQuery(query url.Values) (*AddParams, error) {
 		return nil, err
 	}
 
-	return params, nil
+	err = parseBoolParam(query, "manifest", &params.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like IPFS, imply the options required by others when they are
+	// not explicitly given.
+	if params.NoCopy && query.Get("raw-leaves") == "" {
+		params.RawLeaves = true
+	}
+	if hashF != "" && query.Get("cid-version") == "" &&
+		strings.ToLower(hashF) != DefaultAddParams().HashFun {
+		params.CidVersion = 1
+	}
+
+	return params, params.Validate()
 }
 
 // ToQueryString returns a url query string (key=value&key2=value2&...)
@@ -191,6 +283,13 @@ func (p *AddParams) ToQueryString() string {
 	query.Set("hash", p.HashFun)
 	query.Set("stream-channels", fmt.Sprintf("%t", p.StreamChannels))
 	query.Set("nocopy", fmt.Sprintf("%t", p.NoCopy))
+	query.Set("manifest", fmt.Sprintf("%t", p.Manifest))
+	if p.CidBase != "" {
+		query.Set("cid-base", p.CidBase)
+	}
+	if p.Profile != "" {
+		query.Set("profile", p.Profile)
+	}
 	return query.Encode()
 }
 
@@ -210,5 +309,8 @@ func (p *AddParams) Equals(p2 *AddParams) bool {
 		p.CidVersion == p2.CidVersion &&
 		p.HashFun == p2.HashFun &&
 		p.StreamChannels == p2.StreamChannels &&
-		p.NoCopy == p2.NoCopy
+		p.NoCopy == p2.NoCopy &&
+		p.Manifest == p2.Manifest &&
+		p.CidBase == p2.CidBase &&
+		p.Profile == p2.Profile
 }
@@ -26,6 +26,19 @@ func TestAddParams_FromQuery(t *testing.T) {
 		p.ShardSize != 1 {
 		t.Fatal("did not parse the query correctly")
 	}
+
+	q.Set("manifest", "true")
+	_, err = AddParamsFromQuery(q)
+	if err == nil {
+		t.Error("expected an error with manifest and shard")
+	}
+	q.Del("manifest")
+
+	q.Set("cid-base", "abc")
+	_, err = AddParamsFromQuery(q)
+	if err == nil {
+		t.Error("expected an error with an invalid cid-base")
+	}
 }
 
 func TestAddParams_ToQueryString(t *testing.T) {
@@ -35,6 +48,9 @@ func TestAddParams_ToQueryString(t *testing.T) {
 	p.Name = "something"
 	p.RawLeaves = true
 	p.ShardSize = 1020
+	p.CidBase = "base32"
+	p.Profile = "archive"
+	p.Manifest = true
 	qstr := p.ToQueryString()
 
 	q, err := url.ParseQuery(qstr)
@@ -51,3 +67,40 @@ func TestAddParams_ToQueryString(t *testing.T) {
 		t.Error("generated and parsed params should be equal")
 	}
 }
+
+func TestAddParams_ApplyProfile(t *testing.T) {
+	rawLeaves := true
+	prof := &AddProfile{
+		Chunker:              "size-1048576",
+		RawLeaves:            &rawLeaves,
+		ReplicationFactorMin: 2,
+		ReplicationFactorMax: 3,
+	}
+	if err := prof.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	p := DefaultAddParams()
+	p.Layout = "trickle"
+	p.ReplicationFactorMin = 1
+	p.ReplicationFactorMax = 1
+	p.ApplyProfile(prof)
+	if p.Chunker != "size-1048576" || !p.RawLeaves ||
+		p.ReplicationFactorMin != 2 || p.ReplicationFactorMax != 3 {
+		t.Errorf("profile options were not applied: %+v", p)
+	}
+	if p.Layout != "trickle" {
+		t.Error("options not set in the profile should be kept")
+	}
+
+	prof.ReplicationFactorMin = 4
+	if err := prof.Validate(); err == nil {
+		t.Error("expected an error with rmin > rmax")
+	}
+
+	prof.ReplicationFactorMin = 2
+	prof.Layout = "abc"
+	if err := prof.Validate(); err == nil {
+		t.Error("expected an error with an invalid layout")
+	}
+}
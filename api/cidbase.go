@@ -0,0 +1,49 @@
+package api
+
+import (
+	"regexp"
+
+	cid "github.com/ipfs/go-cid"
+	mbase "github.com/multiformats/go-multibase"
+)
+
+// cidJSONRegexp matches CIDs as serialized by cid.Cid.MarshalJSON.
+var cidJSONRegexp = regexp.MustCompile(`\{"/":"([^"]+)"\}`)
+
+// CidBaseEncoder formats CIDs using a given multibase. CIDv0s can only be
+// represented in base58btc, so they are upgraded to CIDv1 for any other
+// base.
+type CidBaseEncoder struct {
+	base mbase.Encoder
+}
+
+// NewCidBaseEncoder returns a CidBaseEncoder for the multibase with the
+// given name (i.e. "base32") or prefix character.
+func NewCidBaseEncoder(name string) (*CidBaseEncoder, error) {
+	base, err := mbase.EncoderByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return &CidBaseEncoder{base: base}, nil
+}
+
+// Encode returns the string representation of c in the encoder's base.
+func (e *CidBaseEncoder) Encode(c cid.Cid) string {
+	if c.Version() == 0 && e.base.Encoding() != mbase.Base58BTC {
+		c = cid.NewCidV1(c.Type(), c.Hash())
+	}
+	return c.Encode(e.base)
+}
+
+// EncodeJSON re-encodes every CID serialized in the given JSON document
+// ({"/":"<cid>"}) using the encoder's base.
+func (e *CidBaseEncoder) EncodeJSON(doc []byte) []byte {
+	return cidJSONRegexp.ReplaceAllFunc(doc, func(m []byte) []byte {
+		sub := cidJSONRegexp.FindSubmatch(m)
+		c, err := cid.Decode(string(sub[1]))
+		if err != nil {
+			return m
+		}
+		return []byte(`{"/":"` + e.Encode(c) + `"}`)
+	})
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+func TestCidBaseEncoder(t *testing.T) {
+	v0, _ := cid.Decode("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmmq")
+	v1 := cid.NewCidV1(v0.Type(), v0.Hash())
+
+	if _, err := NewCidBaseEncoder("abc"); err == nil {
+		t.Error("expected an error with an unknown base")
+	}
+
+	enc, err := NewCidBaseEncoder("base58btc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Encode(v0) != v0.String() {
+		t.Error("CIDv0 should not be upgraded for base58btc")
+	}
+	if !strings.HasPrefix(enc.Encode(v1), "z") {
+		t.Error("expected a base58btc CIDv1")
+	}
+
+	enc, err = NewCidBaseEncoder("base32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Encode(v0) != v1.String() {
+		t.Error("CIDv0 should be upgraded to a base32 CIDv1")
+	}
+
+	doc := []byte(`{"cid":{"/":"` + v0.String() + `"},"name":"a","other":{"/":"abc"}}`)
+	expected := `{"cid":{"/":"` + v1.String() + `"},"name":"a","other":{"/":"abc"}}`
+	if got := string(enc.EncodeJSON(doc)); got != expected {
+		t.Errorf("unexpected JSON: %s", got)
+	}
+}
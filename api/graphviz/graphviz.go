@@ -1,10 +1,13 @@
-package main
+// Package graphviz writes IPFS Cluster connectivity graphs as graphviz
+// dot files.
+package graphviz
 
 import (
 	"errors"
 	"fmt"
 	"io"
 	"sort"
+	"time"
 
 	peer "github.com/libp2p/go-libp2p-peer"
 	dot "github.com/zenground0/go-dot"
@@ -14,12 +17,14 @@ import (
 
 /*
    These functions are used to write an IPFS Cluster connectivity graph to a
-   graphviz-style dot file.  Input an api.ConnectGraphSerial object, makeDot
+   graphviz-style dot file.  Input an api.ConnectGraph object, WriteDot
    does some preprocessing and then passes all 3 link maps to a
    cluster-dotWriter which handles iterating over the link maps and writing
    dot file node and edge statements to make a dot-file graph.  Nodes are
    labeled with the go-libp2p-peer shortened peer id.  IPFS nodes are rendered
-   with gold boundaries, Cluster nodes with blue.  Currently preprocessing
+   with gold boundaries, Cluster nodes with blue (red when their IPFS daemon
+   cannot be reached).  Connections among cluster peers are labeled with their
+   latency when known.  Currently preprocessing
    consists of moving IPFS swarm peers not connected to any cluster peer to
    the IPFSLinks map in the event that the function was invoked with the
    allIpfs flag.  This allows all IPFS peers connected to the cluster to be
@@ -39,7 +44,10 @@ var errUnfinishedWrite = errors.New("could not complete write of line to output"
 var errUnknownNodeType = errors.New("unsupported node type. Expected cluster or ipfs")
 var errCorruptOrdering = errors.New("expected pid to have an ordering within dot writer")
 
-func makeDot(cg *api.ConnectGraph, w io.Writer, allIpfs bool) error {
+// WriteDot writes the given connectivity graph to w in dot format. When
+// allIpfs is set, IPFS swarm peers which are not part of the cluster are
+// included too.
+func WriteDot(cg *api.ConnectGraph, w io.Writer, allIpfs bool) error {
 	ipfsEdges := make(map[string][]peer.ID)
 	for k, v := range cg.IPFSLinks {
 		ipfsEdges[k] = make([]peer.ID, 0)
@@ -65,6 +73,8 @@ func makeDot(cg *api.ConnectGraph, w io.Writer, allIpfs bool) error {
 		ipfsEdges:        ipfsEdges,
 		clusterEdges:     cg.ClusterLinks,
 		clusterIpfsEdges: cg.ClustertoIPFS,
+		latencies:        cg.ClusterLatencies,
+		ipfsErrors:       cg.IPFSErrors,
 		clusterNodes:     make(map[string]*dot.VertexDescription),
 		ipfsNodes:        make(map[string]*dot.VertexDescription),
 	}
@@ -81,6 +91,20 @@ type dotWriter struct {
 	ipfsEdges        map[string][]peer.ID
 	clusterEdges     map[string][]peer.ID
 	clusterIpfsEdges map[string]peer.ID
+	latencies        map[string]map[string]time.Duration
+	ipfsErrors       map[string]string
+}
+
+// labeledEdge is a dot edge with a label.
+type labeledEdge struct {
+	from  *dot.VertexDescription
+	to    *dot.VertexDescription
+	label string
+}
+
+func (e *labeledEdge) Write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s -> %s [label=\"%s\"]", e.from.ID, e.to.ID, e.label)
+	return err
 }
 
 // writes nodes to dot file output and creates and stores an ordering over nodes
@@ -92,6 +116,9 @@ func (dW *dotWriter) addNode(id string, nT nodeType) error {
 	case tCluster:
 		node.ID = fmt.Sprintf("C%d", len(dW.clusterNodes))
 		node.Color = "blue2"
+		if _, ok := dW.ipfsErrors[id]; ok {
+			node.Color = "red"
+		}
 		dW.clusterNodes[id] = &node
 	case tIpfs:
 		node.ID = fmt.Sprintf("I%d", len(dW.ipfsNodes))
@@ -134,6 +161,14 @@ func (dW *dotWriter) print() error {
 		for _, id := range v {
 			toNode := dW.clusterNodes[k]
 			fromNode := dW.clusterNodes[peer.IDB58Encode(id)]
+			if lat, ok := dW.latencies[k][peer.IDB58Encode(id)]; ok {
+				dW.dotGraph.Body = append(dW.dotGraph.Body, &labeledEdge{
+					from:  toNode,
+					to:    fromNode,
+					label: lat.Round(time.Microsecond).String(),
+				})
+				continue
+			}
 			dW.dotGraph.AddEdge(toNode, fromNode, true)
 		}
 	}
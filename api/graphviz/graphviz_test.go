@@ -1,4 +1,4 @@
-package main
+package graphviz
 
 import (
 	"bytes"
@@ -119,7 +119,7 @@ func TestSimpleIpfsGraphs(t *testing.T) {
 		},
 	}
 	buf := new(bytes.Buffer)
-	err := makeDot(&cg, buf, false)
+	err := WriteDot(&cg, buf, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -223,7 +223,7 @@ func TestIpfsAllGraphs(t *testing.T) {
 	}
 
 	buf := new(bytes.Buffer)
-	err := makeDot(&cg, buf, true)
+	err := WriteDot(&cg, buf, true)
 	if err != nil {
 		t.Fatal(err)
 	}
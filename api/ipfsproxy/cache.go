@@ -0,0 +1,245 @@
+package ipfsproxy
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// cachedPaths are the read-only IPFS API endpoints whose responses can be
+// cached by the proxy. Their responses only depend on the request, as long
+// as their arguments are immutable (see immutableArg).
+var cachedPaths = map[string]struct{}{
+	"/api/v0/cat":         {},
+	"/api/v0/object/stat": {},
+	"/api/v0/dag/stat":    {},
+	"/api/v0/block/stat":  {},
+	"/api/v0/resolve":     {},
+}
+
+// cacheEntry is a cached response from the IPFS daemon.
+type cacheEntry struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// responseCache is an LRU cache of IPFS daemon responses limited by the
+// total size of the cached bodies. Entries expire after a TTL.
+type responseCache struct {
+	maxSize       int
+	maxObjectSize int
+	ttl           time.Duration
+
+	mu      sync.Mutex
+	size    int
+	lru     *list.List // front is most recently used
+	entries map[string]*list.Element
+
+	hits   uint64
+	misses uint64
+}
+
+func newResponseCache(maxSize, maxObjectSize int, ttl time.Duration) *responseCache {
+	return &responseCache{
+		maxSize:       maxSize,
+		maxObjectSize: maxObjectSize,
+		ttl:           ttl,
+		lru:           list.New(),
+		entries:       make(map[string]*list.Element),
+	}
+}
+
+func (rc *responseCache) get(key string) (*cacheEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	elem, ok := rc.entries[key]
+	if !ok {
+		rc.misses++
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		rc.remove(elem)
+		rc.misses++
+		return nil, false
+	}
+	rc.lru.MoveToFront(elem)
+	rc.hits++
+	return entry, true
+}
+
+func (rc *responseCache) add(entry *cacheEntry) {
+	if len(entry.body) > rc.maxObjectSize || len(entry.body) > rc.maxSize {
+		return
+	}
+	entry.expires = time.Now().Add(rc.ttl)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if elem, ok := rc.entries[entry.key]; ok {
+		rc.remove(elem)
+	}
+	rc.entries[entry.key] = rc.lru.PushFront(entry)
+	rc.size += len(entry.body)
+
+	for rc.size > rc.maxSize {
+		rc.remove(rc.lru.Back())
+	}
+}
+
+// remove must be called with the lock held.
+func (rc *responseCache) remove(elem *list.Element) {
+	entry := rc.lru.Remove(elem).(*cacheEntry)
+	delete(rc.entries, entry.key)
+	rc.size -= len(entry.body)
+}
+
+// cacheKey returns the key identifying the response to a request, when
+// that response can be cached.
+func cacheKey(r *http.Request) (string, bool) {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		return "", false
+	}
+	if r.ContentLength > 0 {
+		return "", false
+	}
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if _, ok := cachedPaths[path]; !ok {
+		return "", false
+	}
+	query := r.URL.Query()
+	args := query["arg"]
+	if len(args) == 0 {
+		return "", false
+	}
+	for _, arg := range args {
+		if !immutableArg(arg) {
+			return "", false
+		}
+	}
+	// Encode() sorts the parameters by key.
+	return path + "?" + query.Encode(), true
+}
+
+// immutableArg returns true when the given argument is a CID or an /ipfs/
+// path. IPNS names and DNSLink domains resolve to different content over
+// time, so responses for them are never cached.
+func immutableArg(arg string) bool {
+	if strings.HasPrefix(arg, "/") {
+		if !strings.HasPrefix(arg, "/ipfs/") {
+			return false
+		}
+		arg = strings.TrimPrefix(arg, "/ipfs/")
+	}
+	root := strings.SplitN(arg, "/", 2)[0]
+	_, err := cid.Decode(root)
+	return err == nil
+}
+
+// cacheRecorder forwards a response to the client while keeping a copy of
+// it, until it grows larger than what can be cached.
+type cacheRecorder struct {
+	http.ResponseWriter
+
+	max      int
+	status   int
+	header   http.Header
+	body     []byte
+	overflow bool
+}
+
+func (rec *cacheRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = make(http.Header)
+		for k, v := range rec.ResponseWriter.Header() {
+			rec.header[k] = append([]string(nil), v...)
+		}
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *cacheRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.overflow {
+		if len(rec.body)+len(b) > rec.max {
+			rec.overflow = true
+			rec.body = nil
+		} else {
+			rec.body = append(rec.body, b...)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *cacheRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// entry returns a cache entry for the recorded response, unless it cannot
+// be cached.
+func (rec *cacheRecorder) entry(key string) (*cacheEntry, bool) {
+	if rec.status != http.StatusOK || rec.overflow {
+		return nil, false
+	}
+	// IPFS reports errors half-way through streamed responses
+	// in a trailer.
+	if rec.ResponseWriter.Header().Get("X-Stream-Error") != "" {
+		return nil, false
+	}
+	return &cacheEntry{
+		key:    key,
+		status: rec.status,
+		header: rec.header,
+		body:   rec.body,
+	}, true
+}
+
+// cacheHandler wraps the given handler so that cacheable responses are
+// served from the response cache when possible.
+func (proxy *Server) cacheHandler(h http.Handler) http.Handler {
+	if proxy.cache == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := cacheKey(r)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if entry, ok := proxy.cache.get(key); ok {
+			for k, v := range entry.header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(entry.status)
+			w.Write(entry.body)
+			return
+		}
+
+		rec := &cacheRecorder{
+			ResponseWriter: w,
+			max:            proxy.cache.maxObjectSize,
+		}
+		h.ServeHTTP(rec, r)
+		if entry, ok := rec.entry(key); ok {
+			proxy.cache.add(entry)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package ipfsproxy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func TestResponseCache(t *testing.T) {
+	rc := newResponseCache(10, 5, time.Minute)
+
+	rc.add(&cacheEntry{key: "a", body: []byte("aaaa")})
+	rc.add(&cacheEntry{key: "b", body: []byte("bbbb")})
+	rc.add(&cacheEntry{key: "big", body: []byte("bigbigbig")})
+	if _, ok := rc.get("big"); ok {
+		t.Error("objects larger than the maximum should not be cached")
+	}
+
+	// a is now the most recently used
+	if _, ok := rc.get("a"); !ok {
+		t.Fatal("a should be cached")
+	}
+	rc.add(&cacheEntry{key: "c", body: []byte("cccc")})
+	if _, ok := rc.get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, ok := rc.get("a"); !ok {
+		t.Error("a should still be cached")
+	}
+	if rc.size != 8 {
+		t.Error("unexpected cache size:", rc.size)
+	}
+
+	rc.ttl = -time.Second
+	rc.add(&cacheEntry{key: "d", body: []byte("d")})
+	if _, ok := rc.get("d"); ok {
+		t.Error("expired entries should not be returned")
+	}
+}
+
+func TestCacheKeyMutablePaths(t *testing.T) {
+	tcs := []struct {
+		path      string
+		cacheable bool
+	}{
+		{"/api/v0/cat?arg=" + test.Cid1.String(), true},
+		{"/api/v0/cat?arg=/ipfs/" + test.Cid1.String() + "/a/b", true},
+		{"/api/v0/resolve?arg=/ipfs/" + test.Cid1.String() + "/a", true},
+		{"/api/v0/cat?arg=/ipns/" + test.PeerID1.Pretty(), false},
+		{"/api/v0/cat?arg=/ipns/ipfs.io/index.html", false},
+		{"/api/v0/resolve?arg=/ipns/ipfs.io", false},
+		{"/api/v0/resolve?arg=ipfs.io", false},
+		{"/api/v0/dag/stat?arg=/ipfs/ipfs.io", false},
+		{"/api/v0/object/stat?arg=" + test.Cid1.String() + "&arg=/ipns/ipfs.io", false},
+		{"/api/v0/object/stat", false},
+	}
+
+	for _, tc := range tcs {
+		r, err := http.NewRequest("POST", "http://localhost"+tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cacheKey(r); ok != tc.cacheable {
+			t.Errorf("%s: expected cacheable=%t", tc.path, tc.cacheable)
+		}
+	}
+}
+
+func TestIPFSProxyCache(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.CacheSize = 1024 * 1024
+	proxy, mock := testIPFSProxyWithConfig(t, cfg)
+	defer mock.Close()
+	defer proxy.Shutdown(ctx)
+
+	get := func(path string) []byte {
+		res, err := http.Post(fmt.Sprintf("%s/%s", proxyURL(proxy), path), "", nil)
+		if err != nil {
+			t.Fatal("should forward requests to ipfs host: ", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatal("the request should have succeeded:", res.StatusCode)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		return body
+	}
+
+	stats := func() (entries int, hits, misses uint64) {
+		proxy.cache.mu.Lock()
+		defer proxy.cache.mu.Unlock()
+		return proxy.cache.lru.Len(), proxy.cache.hits, proxy.cache.misses
+	}
+
+	path := "object/stat?arg=" + test.Cid1.String()
+	first := get(path)
+	// The response is cached after it has been sent.
+	for i := 0; i < 10; i++ {
+		if n, _, _ := stats(); n > 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	second := get(path)
+	if string(first) != string(second) {
+		t.Error("cached response should match the original one")
+	}
+	if _, hits, misses := stats(); hits != 1 || misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss: %d, %d", hits, misses)
+	}
+
+	// Mutable paths are not cached
+	for _, path := range []string{
+		"cat?arg=/ipns/" + test.PeerID1.Pretty(),
+		"cat?arg=/ipns/" + test.PeerID1.Pretty(),
+		"resolve?arg=/ipns/ipfs.io",
+		"resolve?arg=/ipns/ipfs.io",
+		"object/stat?arg=ipfs.io",
+		"object/stat?arg=ipfs.io",
+	} {
+		res, err := http.Post(fmt.Sprintf("%s/%s", proxyURL(proxy), path), "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if n, hits, misses := stats(); n != 1 || hits != 1 || misses != 1 {
+		t.Error("mutable paths should not use the cache")
+	}
+
+	// Not a cached endpoint
+	get("version")
+	get("version")
+	if _, hits, misses := stats(); hits != 1 || misses != 1 {
+		t.Error("version requests should not use the cache")
+	}
+}
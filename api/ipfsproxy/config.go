@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -30,6 +32,13 @@ const (
 	DefaultExtractHeadersPath = "/api/v0/version"
 	DefaultExtractHeadersTTL  = 5 * time.Minute
 	DefaultMaxHeaderBytes     = minMaxHeaderBytes
+	DefaultCacheSize          = 0
+	DefaultCacheMaxObjectSize = 64 * 1024
+	DefaultCacheTTL           = time.Minute
+
+	DefaultMaxRequestBodySize     = 0
+	DefaultMaxAddBodySize         = 0
+	DefaultRequestBodyIdleTimeout = time.Minute
 )
 
 // Config allows to customize behaviour of IPFSProxy.
@@ -63,6 +72,19 @@ type Config struct {
 	// kept idle before being reused
 	IdleTimeout time.Duration
 
+	// MaxRequestBodySize is the maximum size in bytes of the body of
+	// requests, except for /add. Larger requests are answered with
+	// 413. 0 means no limit.
+	MaxRequestBodySize int64
+
+	// MaxAddBodySize is the maximum size in bytes of the content
+	// uploaded to /add. 0 means no limit.
+	MaxAddBodySize int64
+
+	// RequestBodyIdleTimeout is the maximum time to wait for more body
+	// data from a client before failing its request. 0 disables it.
+	RequestBodyIdleTimeout time.Duration
+
 	// A list of custom headers that should be extracted from
 	// IPFS daemon responses and re-used in responses from hijacked paths.
 	// This is only useful if the user has configured custom headers
@@ -81,6 +103,20 @@ type Config struct {
 	// refresh them with a new request. 0 means always.
 	ExtractHeadersTTL time.Duration
 
+	// CacheSize is the maximum number of bytes of IPFS daemon responses
+	// kept in memory to answer repeated read-only requests (cat,
+	// object/stat, dag/stat, block/stat and resolve) without hitting
+	// the daemon. 0 disables the cache.
+	CacheSize int
+
+	// CacheMaxObjectSize is the size of the largest response which
+	// will be cached.
+	CacheMaxObjectSize int
+
+	// CacheTTL is how long cached responses are used before they are
+	// requested again from the daemon.
+	CacheTTL time.Duration
+
 	// Tracing flag used to skip tracing specific paths when not enabled.
 	Tracing bool
 }
@@ -96,9 +132,17 @@ type jsonConfig struct {
 	IdleTimeout       string `json:"idle_timeout"`
 	MaxHeaderBytes    int    `json:"max_header_bytes"`
 
+	MaxRequestBodySize     int64  `json:"max_request_body_size"`
+	MaxAddBodySize         int64  `json:"max_add_body_size"`
+	RequestBodyIdleTimeout string `json:"request_body_idle_timeout"`
+
 	ExtractHeadersExtra []string `json:"extract_headers_extra,omitempty"`
 	ExtractHeadersPath  string   `json:"extract_headers_path,omitempty"`
 	ExtractHeadersTTL   string   `json:"extract_headers_ttl,omitempty"`
+
+	CacheSize          int    `json:"cache_size,omitempty"`
+	CacheMaxObjectSize int    `json:"cache_max_object_size,omitempty"`
+	CacheTTL           string `json:"cache_ttl,omitempty"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -126,6 +170,12 @@ func (cfg *Config) Default() error {
 	cfg.ExtractHeadersPath = DefaultExtractHeadersPath
 	cfg.ExtractHeadersTTL = DefaultExtractHeadersTTL
 	cfg.MaxHeaderBytes = DefaultMaxHeaderBytes
+	cfg.MaxRequestBodySize = DefaultMaxRequestBodySize
+	cfg.MaxAddBodySize = DefaultMaxAddBodySize
+	cfg.RequestBodyIdleTimeout = DefaultRequestBodyIdleTimeout
+	cfg.CacheSize = DefaultCacheSize
+	cfg.CacheMaxObjectSize = DefaultCacheMaxObjectSize
+	cfg.CacheTTL = DefaultCacheTTL
 
 	return nil
 }
@@ -185,6 +235,30 @@ func (cfg *Config) Validate() error {
 		err = fmt.Errorf("ipfsproxy.max_header_size must be greater or equal to %d", minMaxHeaderBytes)
 	}
 
+	if cfg.MaxRequestBodySize < 0 {
+		err = errors.New("ipfsproxy.max_request_body_size is invalid")
+	}
+
+	if cfg.MaxAddBodySize < 0 {
+		err = errors.New("ipfsproxy.max_add_body_size is invalid")
+	}
+
+	if cfg.RequestBodyIdleTimeout < 0 {
+		err = errors.New("ipfsproxy.request_body_idle_timeout is invalid")
+	}
+
+	if cfg.CacheSize < 0 {
+		err = errors.New("ipfsproxy.cache_size is invalid")
+	}
+
+	if cfg.CacheMaxObjectSize <= 0 {
+		err = errors.New("ipfsproxy.cache_max_object_size should be positive")
+	}
+
+	if cfg.CacheTTL <= 0 {
+		err = errors.New("ipfsproxy.cache_ttl should be positive")
+	}
+
 	return err
 }
 
@@ -226,6 +300,8 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 		&config.DurationOpt{Duration: jcfg.WriteTimeout, Dst: &cfg.WriteTimeout, Name: "write_timeout"},
 		&config.DurationOpt{Duration: jcfg.IdleTimeout, Dst: &cfg.IdleTimeout, Name: "idle_timeout"},
 		&config.DurationOpt{Duration: jcfg.ExtractHeadersTTL, Dst: &cfg.ExtractHeadersTTL, Name: "extract_header_ttl"},
+		&config.DurationOpt{Duration: jcfg.CacheTTL, Dst: &cfg.CacheTTL, Name: "cache_ttl"},
+		&config.DurationOpt{Duration: jcfg.RequestBodyIdleTimeout, Dst: &cfg.RequestBodyIdleTimeout, Name: "request_body_idle_timeout"},
 	)
 	if err != nil {
 		return err
@@ -236,11 +312,15 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	} else {
 		cfg.MaxHeaderBytes = jcfg.MaxHeaderBytes
 	}
+	cfg.MaxRequestBodySize = jcfg.MaxRequestBodySize
+	cfg.MaxAddBodySize = jcfg.MaxAddBodySize
 
 	if extra := jcfg.ExtractHeadersExtra; extra != nil && len(extra) > 0 {
 		cfg.ExtractHeadersExtra = extra
 	}
 	config.SetIfNotDefault(jcfg.ExtractHeadersPath, &cfg.ExtractHeadersPath)
+	config.SetIfNotDefault(jcfg.CacheSize, &cfg.CacheSize)
+	config.SetIfNotDefault(jcfg.CacheMaxObjectSize, &cfg.CacheMaxObjectSize)
 
 	return cfg.Validate()
 }
@@ -274,6 +354,9 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 	jcfg.WriteTimeout = cfg.WriteTimeout.String()
 	jcfg.IdleTimeout = cfg.IdleTimeout.String()
 	jcfg.MaxHeaderBytes = cfg.MaxHeaderBytes
+	jcfg.MaxRequestBodySize = cfg.MaxRequestBodySize
+	jcfg.MaxAddBodySize = cfg.MaxAddBodySize
+	jcfg.RequestBodyIdleTimeout = cfg.RequestBodyIdleTimeout.String()
 	jcfg.NodeHTTPS = cfg.NodeHTTPS
 
 	jcfg.ExtractHeadersExtra = cfg.ExtractHeadersExtra
@@ -284,5 +367,21 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 		jcfg.ExtractHeadersTTL = ttl.String()
 	}
 
+	jcfg.CacheSize = cfg.CacheSize
+	if cfg.CacheMaxObjectSize != DefaultCacheMaxObjectSize {
+		jcfg.CacheMaxObjectSize = cfg.CacheMaxObjectSize
+	}
+	if ttl := cfg.CacheTTL; ttl != DefaultCacheTTL {
+		jcfg.CacheTTL = ttl.String()
+	}
+
 	return
 }
+
+// maxBodySize returns the body size limit for the given request.
+func (cfg *Config) maxBodySize(r *http.Request) int64 {
+	if strings.TrimSuffix(r.URL.Path, "/") == "/api/v0/add" {
+		return cfg.MaxAddBodySize
+	}
+	return cfg.MaxRequestBodySize
+}
@@ -80,6 +80,47 @@ func TestLoadJSON(t *testing.T) {
 	if err == nil {
 		t.Error("expected error in extract_headers_ttl")
 	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.CacheSize = -1
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in cache_size")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.CacheSize = 1024 * 1024
+	j.CacheTTL = "30s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CacheSize != 1024*1024 || cfg.CacheTTL != 30*time.Second ||
+		cfg.CacheMaxObjectSize != DefaultCacheMaxObjectSize {
+		t.Error("cache options were not loaded")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.MaxAddBodySize = -1
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in max_add_body_size")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.RequestBodyIdleTimeout = "-1s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in request_body_idle_timeout")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ipfs/ipfs-cluster/adder/adderutils"
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/api/limits"
 	"github.com/ipfs/ipfs-cluster/rpcutil"
 
 	mux "github.com/gorilla/mux"
@@ -36,6 +37,20 @@ var DNSTimeout = 5 * time.Second
 
 var logger = logging.Logger("ipfsproxy")
 
+// accessBufferSize is the number of content accesses which can be queued
+// for recording. Accesses beyond it are dropped rather than slowing down
+// requests.
+const accessBufferSize = 1024
+
+// accessPaths are the IPFS API endpoints which retrieve content. Requests
+// to them are recorded as accesses to the root Cid of their argument.
+var accessPaths = map[string]struct{}{
+	"/api/v0/cat":       {},
+	"/api/v0/get":       {},
+	"/api/v0/dag/get":   {},
+	"/api/v0/block/get": {},
+}
+
 // Server offers an IPFS API, hijacking some interesting requests
 // and forwarding the rest to the ipfs daemon
 // it proxies HTTP requests to the configured IPFS
@@ -58,6 +73,11 @@ type Server struct {
 
 	ipfsHeadersStore sync.Map
 
+	accesses chan cid.Cid
+
+	// cache keeps responses to read-only requests. nil when disabled.
+	cache *responseCache
+
 	shutdownLock sync.Mutex
 	shutdown     bool
 	wg           sync.WaitGroup
@@ -148,6 +168,15 @@ func New(cfg *Config) (*Server, error) {
 		}
 	}
 
+	handler = limits.Handler(handler, limits.Options{
+		Name:            configKey,
+		MaxBodySize:     cfg.maxBodySize,
+		BodyIdleTimeout: cfg.RequestBodyIdleTimeout,
+		Reject: func(w http.ResponseWriter, status int, err error) {
+			ipfsErrorResponder(w, err.Error(), status)
+		},
+	})
+
 	s := &http.Server{
 		ReadTimeout:       cfg.ReadTimeout,
 		WriteTimeout:      cfg.WriteTimeout,
@@ -164,6 +193,14 @@ func New(cfg *Config) (*Server, error) {
 
 	reverseProxy := httputil.NewSingleHostReverseProxy(proxyURL)
 	reverseProxy.Transport = http.DefaultTransport
+	reverseProxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		status := http.StatusBadGateway
+		if limitStatus, ok := limits.ErrorStatus(err); ok {
+			status = limitStatus
+		}
+		logger.Errorf("error proxying %s: %s", r.URL.Path, err)
+		w.WriteHeader(status)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	proxy := &Server{
 		ctx:              ctx,
@@ -175,6 +212,11 @@ func New(cfg *Config) (*Server, error) {
 		listener:         l,
 		server:           s,
 		ipfsRoundTripper: reverseProxy.Transport,
+		accesses:         make(chan cid.Cid, accessBufferSize),
+	}
+
+	if cfg.CacheSize > 0 {
+		proxy.cache = newResponseCache(cfg.CacheSize, cfg.CacheMaxObjectSize, cfg.CacheTTL)
 	}
 
 	// Ideally, we should only intercept POST requests, but
@@ -226,7 +268,7 @@ func New(cfg *Config) (*Server, error) {
 		Name("RepoStat")
 
 	// Everything else goes to the IPFS daemon.
-	router.PathPrefix("/").Handler(reverseProxy)
+	router.PathPrefix("/").Handler(proxy.accessHandler(proxy.cacheHandler(reverseProxy)))
 
 	go proxy.run()
 	return proxy, nil
@@ -285,6 +327,75 @@ func (proxy *Server) run() {
 			logger.Error(err)
 		}
 	}()
+
+	proxy.wg.Add(1)
+	go proxy.recordAccesses()
+}
+
+// accessHandler wraps the given handler so that requests retrieving
+// content are recorded as accesses to it.
+func (proxy *Server) accessHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, ok := accessedCid(r); ok {
+			select {
+			case proxy.accesses <- c:
+			default:
+				logger.Debug("access queue full: dropping access")
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// accessedCid returns the root Cid of the content requested, when the
+// request retrieves content.
+func accessedCid(r *http.Request) (cid.Cid, bool) {
+	if _, ok := accessPaths[strings.TrimSuffix(r.URL.Path, "/")]; !ok {
+		return cid.Undef, false
+	}
+
+	arg := r.URL.Query().Get("arg")
+	if arg == "" {
+		return cid.Undef, false
+	}
+
+	p, err := path.ParsePath(arg)
+	if err != nil {
+		return cid.Undef, false
+	}
+
+	c, _, err := path.SplitAbsPath(p)
+	if err != nil {
+		return cid.Undef, false
+	}
+	return c, true
+}
+
+// recordAccesses lets the cluster peer know about accesses to content
+// until the proxy is shut down.
+func (proxy *Server) recordAccesses() {
+	defer proxy.wg.Done()
+	for {
+		select {
+		case <-proxy.ctx.Done():
+			return
+		case c := <-proxy.accesses:
+			if proxy.ctx.Err() != nil {
+				return
+			}
+			err := proxy.rpcClient.CallContext(
+				proxy.ctx,
+				"",
+				"Cluster",
+				"RecordAccess",
+				c,
+				&struct{}{},
+			)
+			if err != nil {
+				logger.Debugf("error recording access to %s: %s", c, err)
+			}
+		}
+	}
 }
 
 // ipfsErrorResponder writes an http error response just like IPFS would.
@@ -311,6 +422,11 @@ func (proxy *Server) pinOpHandler(op string, w http.ResponseWriter, r *http.Requ
 	}
 
 	pinPath := &api.PinPath{Path: p.String()}
+	pinPath.Provenance = &api.PinProvenance{Source: api.ProvenanceProxy}
+	if op == "PinPath" && r.URL.Query().Get("recursive") == "false" {
+		direct := 0
+		pinPath.Depth = &direct
+	}
 	var pin api.Pin
 	err = proxy.rpcClient.Call(
 		"",
@@ -319,6 +435,11 @@ func (proxy *Server) pinOpHandler(op string, w http.ResponseWriter, r *http.Requ
 		pinPath,
 		&pin,
 	)
+	if rlErr, ok := api.AsRateLimitError(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(rlErr.RetryAfterSeconds()))
+		ipfsErrorResponder(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
 	if err != nil {
 		ipfsErrorResponder(w, err.Error(), -1)
 		return
@@ -367,7 +488,7 @@ func (proxy *Server) pinLsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		pinLs.Keys[pin.Cid.String()] = ipfsPinType{
-			Type: "recursive",
+			Type: ipfsPinTypeOf(&pin),
 		}
 	} else {
 		pins := make([]*api.Pin, 0)
@@ -385,7 +506,7 @@ func (proxy *Server) pinLsHandler(w http.ResponseWriter, r *http.Request) {
 
 		for _, pin := range pins {
 			pinLs.Keys[pin.Cid.String()] = ipfsPinType{
-				Type: "recursive",
+				Type: ipfsPinTypeOf(pin),
 			}
 		}
 	}
@@ -395,6 +516,15 @@ func (proxy *Server) pinLsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(resBytes)
 }
 
+// ipfsPinTypeOf returns the pin type that ipfs would report for the
+// given pin.
+func ipfsPinTypeOf(pin *api.Pin) string {
+	if pin.MaxDepth == 0 {
+		return "direct"
+	}
+	return "recursive"
+}
+
 func (proxy *Server) pinUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, span := trace.StartSpan(r.Context(), "ipfsproxy/pinUpdateHandler")
 	defer span.End()
@@ -467,6 +597,7 @@ func (proxy *Server) pinUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		PinOptions: fromPin.PinOptions,
 	}
 	toPath.PinOptions.UserAllocations = fromPin.Allocations
+	toPath.PinOptions.Provenance = &api.PinProvenance{Source: api.ProvenanceProxy}
 
 	// Pin the TO pin.
 	var toPin api.Pin
@@ -536,15 +667,25 @@ func (proxy *Server) addHandler(w http.ResponseWriter, r *http.Request) {
 	if trickle == "true" {
 		params.Layout = "trickle"
 	}
+	params.Provenance = &api.PinProvenance{Source: api.ProvenanceProxy}
 
 	logger.Warningf("Proxy/add does not support all IPFS params. Current options: %+v", params)
 
+	var cidEnc *api.CidBaseEncoder
+	if params.CidBase != "" {
+		// Already validated by AddParamsFromQuery.
+		cidEnc, _ = api.NewCidBaseEncoder(params.CidBase)
+	}
+
 	outputTransform := func(in *api.AddedOutput) interface{} {
 		r := &ipfsAddResp{
 			Name:  in.Name,
 			Hash:  in.Cid.String(),
 			Bytes: int64(in.Bytes),
 		}
+		if cidEnc != nil && in.Cid.Defined() {
+			r.Hash = cidEnc.Encode(in.Cid)
+		}
 		if in.Size != 0 {
 			r.Size = strconv.FormatUint(in.Size, 10)
 		}
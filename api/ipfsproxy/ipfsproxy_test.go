@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
@@ -543,11 +544,18 @@ func TestProxyAdd(t *testing.T) {
 		expectedCid string
 	}
 
+	root, _ := cid.Decode(test.ShardingDirBalancedRootCID)
+	rootV1 := cid.NewCidV1(root.Type(), root.Hash())
+
 	testcases := []testcase{
 		testcase{
 			query:       "",
 			expectedCid: test.ShardingDirBalancedRootCID,
 		},
+		testcase{
+			query:       "cid-base=base32",
+			expectedCid: rootV1.String(),
+		},
 		testcase{
 			query:       "progress=true",
 			expectedCid: test.ShardingDirBalancedRootCID,
@@ -636,6 +644,33 @@ func TestProxyError(t *testing.T) {
 	}
 }
 
+func TestAccessedCid(t *testing.T) {
+	testcases := []struct {
+		url      string
+		expected cid.Cid
+	}{
+		{"/api/v0/cat?arg=" + test.Cid1.String(), test.Cid1},
+		{"/api/v0/get?arg=/ipfs/" + test.Cid1.String() + "/a/b", test.Cid1},
+		{"/api/v0/dag/get?arg=" + test.Cid4.String(), test.Cid4},
+		{"/api/v0/block/get/?arg=" + test.Cid2.String(), test.Cid2},
+		{"/api/v0/cat?arg=/ipns/example.com", cid.Undef},
+		{"/api/v0/cat", cid.Undef},
+		{"/api/v0/version?arg=" + test.Cid1.String(), cid.Undef},
+	}
+
+	for _, tc := range testcases {
+		r := httptest.NewRequest(http.MethodPost, tc.url, nil)
+		c, ok := accessedCid(r)
+		if ok != tc.expected.Defined() {
+			t.Errorf("%s: unexpected result %t", tc.url, ok)
+			continue
+		}
+		if ok && !c.Equals(tc.expected) {
+			t.Errorf("%s: expected %s, got %s", tc.url, tc.expected, c)
+		}
+	}
+}
+
 func proxyURL(c *Server) string {
 	addr := c.listener.Addr()
 	return fmt.Sprintf("http://%s/api/v0", addr.String())
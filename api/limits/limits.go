@@ -0,0 +1,278 @@
+// Package limits provides an HTTP middleware protecting the API servers
+// from clients sending oversized requests or sending them too slowly.
+package limits
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/observations"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+var logger = logging.Logger("limits")
+
+// Reasons for rejecting a request, used to tag observations.RejectedRequests.
+const (
+	ReasonBodyTooLarge = "body_too_large"
+	ReasonBodyTimeout  = "body_timeout"
+)
+
+// ErrBodyTooLarge is returned when reading a request body beyond the
+// configured limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// bodyTimeoutError is returned when the client stops sending the body of a
+// request for longer than the idle timeout.
+type bodyTimeoutError struct{}
+
+func (bodyTimeoutError) Error() string   { return "timed out waiting for the request body" }
+func (bodyTimeoutError) Timeout() bool   { return true }
+func (bodyTimeoutError) Temporary() bool { return true }
+
+// Options configures the limits enforced by Handler.
+type Options struct {
+	// Name identifies the API in metrics and logs.
+	Name string
+
+	// MaxBodySize returns the maximum number of bytes that can be read
+	// from the body of a request. 0 means no limit. It may be nil.
+	MaxBodySize func(r *http.Request) int64
+
+	// BodyIdleTimeout is the maximum time to wait for more body data
+	// from the client before failing the request. 0 disables it.
+	BodyIdleTimeout time.Duration
+
+	// Reject writes an error response for requests refused before
+	// reaching the wrapped handler.
+	Reject func(w http.ResponseWriter, status int, err error)
+}
+
+// Handler wraps h so that request bodies larger than the configured limit
+// are refused with 413 (Request Entity Too Large) and clients which stop
+// sending body data for longer than the idle timeout are disconnected.
+//
+// Requests which announce a too large Content-Length are refused right
+// away. Otherwise, body reads fail once the limit or the idle timeout are
+// reached, and the error can be turned into a response status with
+// ErrorStatus.
+func Handler(h http.Handler, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var max int64
+		if opts.MaxBodySize != nil {
+			max = opts.MaxBodySize(r)
+		}
+
+		if max > 0 && r.ContentLength > max {
+			record(r.Context(), opts.Name, ReasonBodyTooLarge)
+			opts.Reject(
+				w,
+				http.StatusRequestEntityTooLarge,
+				fmt.Errorf("request body is larger than %d bytes", max),
+			)
+			return
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			var body io.ReadCloser = r.Body
+			if opts.BodyIdleTimeout > 0 {
+				body = newIdleBody(r.Context(), w, body, opts.BodyIdleTimeout)
+			}
+			if max > 0 {
+				body = &maxBody{ReadCloser: body, w: w, remaining: max}
+			}
+			r.Body = &watchedBody{
+				ReadCloser: body,
+				ctx:        r.Context(),
+				name:       opts.Name,
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ErrorStatus returns the response status corresponding to errors caused
+// by the limits enforced by Handler when reading request bodies.
+func ErrorStatus(err error) (int, bool) {
+	if err == nil {
+		return 0, false
+	}
+	err = errors.Cause(err)
+	if err == ErrBodyTooLarge {
+		return http.StatusRequestEntityTooLarge, true
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return http.StatusRequestTimeout, true
+	}
+	return 0, false
+}
+
+func record(ctx context.Context, name, reason string) {
+	logger.Debugf("%s: rejecting request: %s", name, reason)
+	stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{
+			tag.Upsert(observations.APIKey, name),
+			tag.Upsert(observations.ReasonKey, reason),
+		},
+		observations.RejectedRequests.M(1),
+	)
+}
+
+// watchedBody records the first read error caused by the limits.
+type watchedBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	name     string
+	recorded bool
+}
+
+func (wb *watchedBody) Read(p []byte) (int, error) {
+	n, err := wb.ReadCloser.Read(p)
+	if err != nil && !wb.recorded {
+		if status, ok := ErrorStatus(err); ok {
+			wb.recorded = true
+			reason := ReasonBodyTooLarge
+			if status == http.StatusRequestTimeout {
+				reason = ReasonBodyTimeout
+			}
+			record(wb.ctx, wb.name, reason)
+		}
+	}
+	return n, err
+}
+
+// maxBody fails reads once more than the given number of bytes have been
+// read, like http.MaxBytesReader, but with an error that can be recognized
+// by ErrorStatus.
+type maxBody struct {
+	io.ReadCloser
+	w         http.ResponseWriter
+	remaining int64
+	err       error
+}
+
+func (mb *maxBody) Read(p []byte) (int, error) {
+	if mb.err != nil {
+		return 0, mb.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// Read one byte more than allowed to find out whether the body
+	// goes over the limit.
+	if int64(len(p)) > mb.remaining+1 {
+		p = p[:mb.remaining+1]
+	}
+	n, err := mb.ReadCloser.Read(p)
+	if int64(n) <= mb.remaining {
+		mb.remaining -= int64(n)
+		mb.err = err
+		return n, err
+	}
+
+	n = int(mb.remaining)
+	mb.remaining = 0
+	mb.err = ErrBodyTooLarge
+	// The rest of the body is not read, so the connection cannot be
+	// reused.
+	mb.w.Header().Set("Connection", "close")
+	return n, mb.err
+}
+
+// idleBody fails reads when the client sends no body data for longer than
+// the idle timeout. The body is read in the background, so that a stalled
+// read can be given up on. The server read timeout still applies to the
+// background reads.
+type idleBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	w       http.ResponseWriter
+	idle    time.Duration
+	chunks  chan bodyChunk
+	done    chan struct{}
+	start   sync.Once
+	stop    sync.Once
+	pending []byte
+	err     error
+}
+
+type bodyChunk struct {
+	data []byte
+	err  error
+}
+
+func newIdleBody(ctx context.Context, w http.ResponseWriter, body io.ReadCloser, idle time.Duration) *idleBody {
+	return &idleBody{
+		ReadCloser: body,
+		ctx:        ctx,
+		w:          w,
+		idle:       idle,
+		chunks:     make(chan bodyChunk),
+		done:       make(chan struct{}),
+	}
+}
+
+// readBody reads the body until it fails or until the request is done.
+func (ib *idleBody) readBody() {
+	for {
+		buf := make([]byte, 32*1024)
+		n, err := ib.ReadCloser.Read(buf)
+		select {
+		case ib.chunks <- bodyChunk{data: buf[:n], err: err}:
+		case <-ib.done:
+			return
+		case <-ib.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (ib *idleBody) Read(p []byte) (int, error) {
+	if len(ib.pending) == 0 {
+		if ib.err != nil {
+			return 0, ib.err
+		}
+		ib.start.Do(func() { go ib.readBody() })
+
+		timer := time.NewTimer(ib.idle)
+		select {
+		case chunk := <-ib.chunks:
+			timer.Stop()
+			ib.pending = chunk.data
+			ib.err = chunk.err
+		case <-timer.C:
+			ib.err = bodyTimeoutError{}
+			ib.stop.Do(func() { close(ib.done) })
+			// The client is stuck, so the connection cannot be
+			// reused.
+			ib.w.Header().Set("Connection", "close")
+			return 0, ib.err
+		}
+	}
+
+	n := copy(p, ib.pending)
+	ib.pending = ib.pending[n:]
+	if len(ib.pending) == 0 && ib.err != nil {
+		return n, ib.err
+	}
+	return n, nil
+}
+
+func (ib *idleBody) Close() error {
+	ib.stop.Do(func() { close(ib.done) })
+	return ib.ReadCloser.Close()
+}
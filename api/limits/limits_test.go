@@ -0,0 +1,114 @@
+package limits
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer(t *testing.T, opts Options) *httptest.Server {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := ioutil.ReadAll(r.Body)
+		if status, ok := ErrorStatus(err); ok {
+			w.WriteHeader(status)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	opts.Name = "test"
+	opts.Reject = func(w http.ResponseWriter, status int, err error) {
+		http.Error(w, err.Error(), status)
+	}
+	return httptest.NewServer(Handler(h, opts))
+}
+
+func TestMaxBodySize(t *testing.T) {
+	srv := testServer(t, Options{
+		MaxBodySize: func(r *http.Request) int64 {
+			if r.URL.Path == "/unlimited" {
+				return 0
+			}
+			return 10
+		},
+	})
+	defer srv.Close()
+
+	post := func(path string, body io.Reader) int {
+		res, err := http.Post(srv.URL+path, "text/plain", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if st := post("/", strings.NewReader("small")); st != http.StatusOK {
+		t.Error("small bodies should be accepted:", st)
+	}
+
+	// Known Content-Length
+	if st := post("/", bytes.NewReader(make([]byte, 100))); st != http.StatusRequestEntityTooLarge {
+		t.Error("large bodies should be rejected:", st)
+	}
+
+	// Chunked body
+	if st := post("/", ioutil.NopCloser(bytes.NewReader(make([]byte, 100)))); st != http.StatusRequestEntityTooLarge {
+		t.Error("large chunked bodies should be rejected:", st)
+	}
+
+	if st := post("/unlimited", bytes.NewReader(make([]byte, 100))); st != http.StatusOK {
+		t.Error("unlimited paths should accept large bodies:", st)
+	}
+}
+
+func TestBodyIdleTimeout(t *testing.T) {
+	srv := testServer(t, Options{
+		BodyIdleTimeout: 200 * time.Millisecond,
+	})
+	defer srv.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("some data"))
+		// Stall for longer than the idle timeout
+		time.Sleep(time.Second)
+		pw.Close()
+	}()
+
+	res, err := http.Post(srv.URL, "text/plain", pr)
+	if err != nil {
+		// The server may close the connection before the client
+		// reads the response.
+		t.Log(err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusRequestTimeout {
+		t.Error("slow bodies should time out:", res.StatusCode)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	if _, ok := ErrorStatus(nil); ok {
+		t.Error("nil errors have no status")
+	}
+	if _, ok := ErrorStatus(io.ErrUnexpectedEOF); ok {
+		t.Error("other errors have no status")
+	}
+	if st, ok := ErrorStatus(ErrBodyTooLarge); !ok || st != http.StatusRequestEntityTooLarge {
+		t.Error("expected 413 for ErrBodyTooLarge")
+	}
+	if st, ok := ErrorStatus(bodyTimeoutError{}); !ok || st != http.StatusRequestTimeout {
+		t.Error("expected 408 for body timeouts")
+	}
+}
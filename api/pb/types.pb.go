@@ -61,6 +61,14 @@ type Pin struct {
 	MaxDepth             int32       `protobuf:"zigzag32,4,opt,name=MaxDepth,proto3" json:"MaxDepth,omitempty"`
 	Reference            []byte      `protobuf:"bytes,5,opt,name=Reference,proto3" json:"Reference,omitempty"`
 	Options              *PinOptions `protobuf:"bytes,6,opt,name=Options,proto3" json:"Options,omitempty"`
+	RemoveAt             int64       `protobuf:"zigzag64,7,opt,name=RemoveAt,proto3" json:"RemoveAt,omitempty"`
+	CreatedAt            int64       `protobuf:"zigzag64,8,opt,name=CreatedAt,proto3" json:"CreatedAt,omitempty"`
+	UpdatedAt            int64       `protobuf:"zigzag64,9,opt,name=UpdatedAt,proto3" json:"UpdatedAt,omitempty"`
+	Size                 uint64      `protobuf:"varint,10,opt,name=Size,proto3" json:"Size,omitempty"`
+	CreatedBySource      string      `protobuf:"bytes,11,opt,name=CreatedBySource,proto3" json:"CreatedBySource,omitempty"`
+	CreatedByUser        string      `protobuf:"bytes,12,opt,name=CreatedByUser,proto3" json:"CreatedByUser,omitempty"`
+	CreatedByPeer        []byte      `protobuf:"bytes,13,opt,name=CreatedByPeer,proto3" json:"CreatedByPeer,omitempty"`
+	LastRun              int64       `protobuf:"zigzag64,14,opt,name=LastRun,proto3" json:"LastRun,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
 	XXX_unrecognized     []byte      `json:"-"`
 	XXX_sizecache        int32       `json:"-"`
@@ -133,12 +141,79 @@ func (m *Pin) GetOptions() *PinOptions {
 	return nil
 }
 
+func (m *Pin) GetRemoveAt() int64 {
+	if m != nil {
+		return m.RemoveAt
+	}
+	return 0
+}
+
+func (m *Pin) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Pin) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *Pin) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Pin) GetCreatedBySource() string {
+	if m != nil {
+		return m.CreatedBySource
+	}
+	return ""
+}
+
+func (m *Pin) GetCreatedByUser() string {
+	if m != nil {
+		return m.CreatedByUser
+	}
+	return ""
+}
+
+func (m *Pin) GetCreatedByPeer() []byte {
+	if m != nil {
+		return m.CreatedByPeer
+	}
+	return nil
+}
+
+func (m *Pin) GetLastRun() int64 {
+	if m != nil {
+		return m.LastRun
+	}
+	return 0
+}
+
 type PinOptions struct {
 	ReplicationFactorMin int32             `protobuf:"zigzag32,1,opt,name=ReplicationFactorMin,proto3" json:"ReplicationFactorMin,omitempty"`
 	ReplicationFactorMax int32             `protobuf:"zigzag32,2,opt,name=ReplicationFactorMax,proto3" json:"ReplicationFactorMax,omitempty"`
 	Name                 string            `protobuf:"bytes,3,opt,name=Name,proto3" json:"Name,omitempty"`
 	ShardSize            uint64            `protobuf:"varint,4,opt,name=ShardSize,proto3" json:"ShardSize,omitempty"`
 	Metadata             map[string]string `protobuf:"bytes,6,rep,name=Metadata,proto3" json:"Metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	SignatureKey         []byte            `protobuf:"bytes,7,opt,name=SignatureKey,proto3" json:"SignatureKey,omitempty"`
+	Signature            []byte            `protobuf:"bytes,8,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	ProvenanceSource     string            `protobuf:"bytes,9,opt,name=ProvenanceSource,proto3" json:"ProvenanceSource,omitempty"`
+	ProvenanceUser       string            `protobuf:"bytes,10,opt,name=ProvenanceUser,proto3" json:"ProvenanceUser,omitempty"`
+	ProvenancePeer       []byte            `protobuf:"bytes,11,opt,name=ProvenancePeer,proto3" json:"ProvenancePeer,omitempty"`
+	ScheduleAt           int64             `protobuf:"zigzag64,12,opt,name=ScheduleAt,proto3" json:"ScheduleAt,omitempty"`
+	Recurrence           string            `protobuf:"bytes,13,opt,name=Recurrence,proto3" json:"Recurrence,omitempty"`
+	RecurrencePath       string            `protobuf:"bytes,14,opt,name=RecurrencePath,proto3" json:"RecurrencePath,omitempty"`
+	Priority             int32             `protobuf:"zigzag32,15,opt,name=Priority,proto3" json:"Priority,omitempty"`
+	PinTimeout           int64             `protobuf:"zigzag64,16,opt,name=PinTimeout,proto3" json:"PinTimeout,omitempty"`
+	SignatureTime        int64             `protobuf:"zigzag64,17,opt,name=SignatureTime,proto3" json:"SignatureTime,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -204,6 +279,83 @@ func (m *PinOptions) GetMetadata() map[string]string {
 	return nil
 }
 
+func (m *PinOptions) GetSignatureKey() []byte {
+	if m != nil {
+		return m.SignatureKey
+	}
+	return nil
+}
+
+func (m *PinOptions) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *PinOptions) GetProvenanceSource() string {
+	if m != nil {
+		return m.ProvenanceSource
+	}
+	return ""
+}
+
+func (m *PinOptions) GetProvenanceUser() string {
+	if m != nil {
+		return m.ProvenanceUser
+	}
+	return ""
+}
+
+func (m *PinOptions) GetProvenancePeer() []byte {
+	if m != nil {
+		return m.ProvenancePeer
+	}
+	return nil
+}
+
+func (m *PinOptions) GetScheduleAt() int64 {
+	if m != nil {
+		return m.ScheduleAt
+	}
+	return 0
+}
+
+func (m *PinOptions) GetRecurrence() string {
+	if m != nil {
+		return m.Recurrence
+	}
+	return ""
+}
+
+func (m *PinOptions) GetRecurrencePath() string {
+	if m != nil {
+		return m.RecurrencePath
+	}
+	return ""
+}
+
+func (m *PinOptions) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *PinOptions) GetPinTimeout() int64 {
+	if m != nil {
+		return m.PinTimeout
+	}
+	return 0
+}
+
+func (m *PinOptions) GetSignatureTime() int64 {
+	if m != nil {
+		return m.SignatureTime
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("api.pb.Pin_PinType", Pin_PinType_name, Pin_PinType_value)
 	proto.RegisterType((*Pin)(nil), "api.pb.Pin")
@@ -214,29 +366,44 @@ func init() {
 func init() { proto.RegisterFile("types.proto", fileDescriptor_d938547f84707355) }
 
 var fileDescriptor_d938547f84707355 = []byte{
-	// 377 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x52, 0x4d, 0x6f, 0xda, 0x40,
-	0x10, 0xed, 0xda, 0xc6, 0xe0, 0x31, 0x20, 0x98, 0x72, 0xb0, 0x50, 0x0f, 0x2b, 0x2e, 0xf5, 0xa1,
-	0xf2, 0xc1, 0xbd, 0x54, 0x6d, 0x2f, 0x14, 0xda, 0x4a, 0x95, 0x68, 0xd1, 0xd2, 0xfc, 0x80, 0x05,
-	0x36, 0x62, 0x15, 0xc7, 0x5e, 0x99, 0x25, 0xc2, 0xf9, 0x37, 0x39, 0xe5, 0x6f, 0x46, 0xbb, 0xe6,
-	0x2b, 0x0a, 0x07, 0x4b, 0xf3, 0xde, 0x9b, 0xe7, 0x9d, 0x79, 0x1a, 0x08, 0x75, 0xa5, 0xc4, 0x36,
-	0x51, 0x65, 0xa1, 0x0b, 0xf4, 0xb9, 0x92, 0x89, 0x5a, 0x8e, 0x9e, 0x1c, 0x70, 0xe7, 0x32, 0xc7,
-	0x1e, 0xb8, 0x13, 0xb9, 0x8e, 0x08, 0x25, 0x71, 0x9b, 0x99, 0x12, 0x3f, 0x82, 0xf7, 0xbf, 0x52,
-	0x22, 0x72, 0x28, 0x89, 0xbb, 0xe9, 0xfb, 0xa4, 0x36, 0x24, 0x73, 0x99, 0x9b, 0xcf, 0x48, 0xcc,
-	0x36, 0x20, 0x85, 0x70, 0x9c, 0x65, 0xc5, 0x8a, 0x6b, 0x59, 0xe4, 0xdb, 0xc8, 0xa5, 0x6e, 0xdc,
-	0x66, 0x97, 0x14, 0x0e, 0xa1, 0x35, 0xe3, 0xfb, 0xa9, 0x50, 0x7a, 0x13, 0x79, 0x94, 0xc4, 0x7d,
-	0x76, 0xc2, 0xf8, 0x01, 0x02, 0x26, 0x6e, 0x45, 0x29, 0xf2, 0x95, 0x88, 0x1a, 0xf6, 0xf9, 0x33,
-	0x81, 0x9f, 0xa0, 0xf9, 0x4f, 0xd5, 0xff, 0xf5, 0x29, 0x89, 0xc3, 0x14, 0x2f, 0xe6, 0x38, 0x28,
-	0xec, 0xd8, 0x32, 0xba, 0x81, 0xe6, 0x61, 0x34, 0x0c, 0xa1, 0xf9, 0x83, 0xaf, 0x4d, 0xd9, 0x7b,
-	0x87, 0x6d, 0x68, 0x4d, 0xb9, 0xe6, 0x16, 0x11, 0x83, 0x66, 0xe2, 0x80, 0x1c, 0x44, 0xe8, 0x4e,
-	0xb2, 0xdd, 0x56, 0x8b, 0x72, 0x3a, 0xfe, 0x6d, 0x39, 0x17, 0x3b, 0x10, 0x2c, 0x36, 0xbc, 0xac,
-	0xed, 0xde, 0xe8, 0xd9, 0x01, 0x38, 0x3f, 0x87, 0x29, 0x0c, 0x98, 0x50, 0x99, 0xac, 0xb7, 0xfb,
-	0xc5, 0x57, 0xba, 0x28, 0x67, 0x32, 0xb7, 0xd9, 0xf5, 0xd9, 0x55, 0xed, 0xba, 0x87, 0xef, 0x6d,
-	0xb8, 0x57, 0x3d, 0x7c, 0x8f, 0x08, 0xde, 0x5f, 0x7e, 0x2f, 0x22, 0x97, 0x92, 0x38, 0x60, 0xb6,
-	0x36, 0x69, 0xd9, 0xc9, 0x16, 0xf2, 0x51, 0xd8, 0x28, 0x3d, 0x76, 0x26, 0xf0, 0x7b, 0xbd, 0xd9,
-	0x9a, 0x6b, 0x1e, 0xf9, 0xd4, 0x8d, 0xc3, 0x94, 0xbe, 0x8d, 0x2b, 0x39, 0xb6, 0xfc, 0xcc, 0x75,
-	0x59, 0xb1, 0x93, 0x63, 0xf8, 0x0d, 0x3a, 0xaf, 0x24, 0x73, 0x13, 0x77, 0xa2, 0xb2, 0x7b, 0x05,
-	0xcc, 0x94, 0x38, 0x80, 0xc6, 0x03, 0xcf, 0x76, 0xf5, 0x51, 0x04, 0xac, 0x06, 0x5f, 0x9d, 0x2f,
-	0xe4, 0x8f, 0xd7, 0x6a, 0xf4, 0xfc, 0xa5, 0x6f, 0x8f, 0xeb, 0xf3, 0x4b, 0x00, 0x00, 0x00, 0xff,
-	0xff, 0xaf, 0xb8, 0xbd, 0x88, 0x6b, 0x02, 0x00, 0x00,
+	// 614 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x94, 0xcf, 0x6e, 0xd3, 0x40,
+	0x10, 0xc6, 0x71, 0xec, 0xfc, 0xf1, 0xd8, 0x49, 0xd3, 0xa5, 0x87, 0x55, 0x55, 0x21, 0x2b, 0x42,
+	0x60, 0x21, 0x94, 0x43, 0xb8, 0x20, 0xe0, 0x92, 0xb6, 0x80, 0x04, 0x14, 0xa2, 0x0d, 0x7d, 0x80,
+	0xad, 0x33, 0x10, 0x8b, 0xd4, 0xb6, 0x36, 0xeb, 0x2a, 0xe6, 0x5d, 0x78, 0x06, 0x5e, 0x11, 0xed,
+	0xd8, 0x89, 0xe3, 0xb4, 0x1c, 0x22, 0xed, 0xf7, 0x9b, 0x99, 0xcc, 0x7a, 0xe7, 0xdb, 0x05, 0x4f,
+	0x17, 0x19, 0xae, 0xc7, 0x99, 0x4a, 0x75, 0xca, 0x3a, 0x32, 0x8b, 0xc7, 0xd9, 0xcd, 0xe8, 0x8f,
+	0x03, 0xf6, 0x2c, 0x4e, 0xd8, 0x10, 0xec, 0x8b, 0x78, 0xc1, 0xad, 0xc0, 0x0a, 0x7d, 0x61, 0x96,
+	0xec, 0x39, 0x38, 0xdf, 0x8b, 0x0c, 0x79, 0x2b, 0xb0, 0xc2, 0xc1, 0xe4, 0xf1, 0xb8, 0x2c, 0x18,
+	0xcf, 0xe2, 0xc4, 0xfc, 0x4c, 0x48, 0x50, 0x02, 0x0b, 0xc0, 0x9b, 0xae, 0x56, 0x69, 0x24, 0x75,
+	0x9c, 0x26, 0x6b, 0x6e, 0x07, 0x76, 0xe8, 0x8b, 0x7d, 0xc4, 0x4e, 0xa1, 0x77, 0x25, 0x37, 0x97,
+	0x98, 0xe9, 0x25, 0x77, 0x02, 0x2b, 0x3c, 0x16, 0x3b, 0xcd, 0xce, 0xc0, 0x15, 0xf8, 0x03, 0x15,
+	0x26, 0x11, 0xf2, 0x36, 0xb5, 0xaf, 0x01, 0x7b, 0x09, 0xdd, 0x6f, 0x59, 0xf9, 0xbf, 0x9d, 0xc0,
+	0x0a, 0xbd, 0x09, 0xdb, 0xdb, 0x47, 0x15, 0x11, 0xdb, 0x14, 0xd3, 0x47, 0xe0, 0x6d, 0x7a, 0x87,
+	0x53, 0xcd, 0xbb, 0x81, 0x15, 0x32, 0xb1, 0xd3, 0xa6, 0xcf, 0x85, 0x42, 0xa9, 0x71, 0x31, 0xd5,
+	0xbc, 0x47, 0xc1, 0x1a, 0x98, 0xe8, 0x75, 0xb6, 0xa8, 0xa2, 0x6e, 0x19, 0xdd, 0x01, 0xc6, 0xc0,
+	0x99, 0xc7, 0xbf, 0x91, 0x43, 0x60, 0x85, 0x8e, 0xa0, 0x35, 0x0b, 0xe1, 0xa8, 0x2a, 0x3f, 0x2f,
+	0xe6, 0x69, 0xae, 0x22, 0xe4, 0x5e, 0x60, 0x85, 0xae, 0x38, 0xc4, 0xec, 0x29, 0xf4, 0x77, 0xe8,
+	0x7a, 0x8d, 0x8a, 0xfb, 0x94, 0xd7, 0x84, 0x8d, 0xac, 0x19, 0xa2, 0xe2, 0x7d, 0x3a, 0x8b, 0x26,
+	0x64, 0x1c, 0xba, 0x5f, 0xe4, 0x5a, 0x8b, 0x3c, 0xe1, 0x03, 0xda, 0xe5, 0x56, 0x8e, 0xae, 0xa1,
+	0x5b, 0x8d, 0x85, 0x79, 0xd0, 0x3d, 0x97, 0x0b, 0xb3, 0x1c, 0x3e, 0x62, 0x3e, 0xf4, 0x2e, 0xa5,
+	0x96, 0xa4, 0x2c, 0xa3, 0xae, 0xb0, 0x52, 0x2d, 0xc6, 0x60, 0x70, 0xb1, 0xca, 0xd7, 0x1a, 0xd5,
+	0xe5, 0xf4, 0x23, 0x31, 0x9b, 0xf5, 0xc1, 0x9d, 0x2f, 0xa5, 0x2a, 0xcb, 0x9d, 0xd1, 0xdf, 0x36,
+	0x40, 0x7d, 0xd4, 0x6c, 0x02, 0x27, 0x02, 0xb3, 0x55, 0x5c, 0x4e, 0xf6, 0x83, 0x8c, 0x74, 0xaa,
+	0xae, 0xe2, 0x84, 0x7c, 0x73, 0x2c, 0x1e, 0x8c, 0x3d, 0x5c, 0x23, 0x37, 0xbc, 0xf5, 0xbf, 0x1a,
+	0xb9, 0x31, 0x27, 0xfe, 0x55, 0xde, 0x22, 0xb7, 0xe9, 0xa8, 0x68, 0xcd, 0xce, 0xaa, 0x9d, 0xd1,
+	0x28, 0x1c, 0x1a, 0x45, 0x0d, 0xd8, 0xbb, 0xf2, 0xcb, 0x16, 0x52, 0x4b, 0xde, 0x09, 0xec, 0xd0,
+	0x9b, 0x04, 0xf7, 0xad, 0x32, 0xde, 0xa6, 0xbc, 0x4f, 0xb4, 0x2a, 0xc4, 0xae, 0x82, 0x8d, 0xc0,
+	0x9f, 0xc7, 0x3f, 0x13, 0xa9, 0x73, 0x85, 0x9f, 0xb1, 0x20, 0xf7, 0xf8, 0xa2, 0xc1, 0xa8, 0xff,
+	0x56, 0x93, 0x83, 0x7c, 0x51, 0x03, 0xf6, 0x02, 0x86, 0x33, 0x95, 0xde, 0x61, 0x22, 0x93, 0x08,
+	0x2b, 0x43, 0xb8, 0xb4, 0xfb, 0x7b, 0x9c, 0x3d, 0x83, 0x41, 0xcd, 0xc8, 0x12, 0x40, 0x99, 0x07,
+	0xb4, 0x99, 0x47, 0xa6, 0xf0, 0xa8, 0xed, 0x01, 0x65, 0x4f, 0x00, 0xe6, 0xd1, 0x12, 0x17, 0xf9,
+	0xca, 0x38, 0xdf, 0x27, 0x63, 0xec, 0x11, 0x13, 0x17, 0x18, 0xe5, 0xaa, 0xbc, 0x64, 0x7d, 0xea,
+	0xb5, 0x47, 0x4c, 0x9f, 0x5a, 0xcd, 0xa4, 0x5e, 0x92, 0xb9, 0x5c, 0x71, 0x40, 0xcd, 0xfd, 0x9a,
+	0xa9, 0x38, 0x55, 0xb1, 0x2e, 0xf8, 0x51, 0x79, 0x8f, 0xb7, 0xda, 0xf4, 0x30, 0xfe, 0x8b, 0x6f,
+	0x31, 0xcd, 0x35, 0x1f, 0x96, 0x7b, 0xa8, 0x89, 0xf1, 0xf7, 0xee, 0xb0, 0x0c, 0xe3, 0xc7, 0x94,
+	0xd2, 0x84, 0xa7, 0x6f, 0xa1, 0xdf, 0x18, 0x91, 0x79, 0x97, 0x7e, 0x61, 0x41, 0xfe, 0x72, 0x85,
+	0x59, 0xb2, 0x13, 0x68, 0xdf, 0xc9, 0x55, 0x5e, 0x3e, 0x4c, 0xae, 0x28, 0xc5, 0x9b, 0xd6, 0x6b,
+	0xeb, 0x93, 0xd3, 0x6b, 0x0f, 0x3b, 0x37, 0x1d, 0x7a, 0xe0, 0x5e, 0xfd, 0x1b, 0x00, 0x5a, 0xa7,
+	0x8b, 0x6d, 0xef, 0x04, 0x00, 0x00,
 }
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	namespace "github.com/ipfs/go-datastore/namespace"
+	manet "github.com/multiformats/go-multiaddr-net"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACMENamespace is the datastore namespace under which the ACME account
+// key and the certificates obtained for the REST API are stored.
+const ACMENamespace = "/restapi/acme"
+
+// ErrACMENeedsDatastore is returned when the API is configured with
+// acme_domains but no datastore is provided to store certificates.
+var ErrACMENeedsDatastore = errors.New("restapi: acme_domains is set but there is no datastore to store certificates")
+
+// datastoreCertCache implements autocert.Cache on top of a datastore.
+type datastoreCertCache struct {
+	store ds.Datastore
+}
+
+func newDatastoreCertCache(store ds.Datastore) *datastoreCertCache {
+	return &datastoreCertCache{
+		store: namespace.Wrap(store, ds.NewKey(ACMENamespace)),
+	}
+}
+
+// Get returns the data stored under the given name, or
+// autocert.ErrCacheMiss.
+func (c *datastoreCertCache) Get(ctx context.Context, name string) ([]byte, error) {
+	data, err := c.store.Get(ds.NewKey(name))
+	if err == ds.ErrNotFound {
+		return nil, autocert.ErrCacheMiss
+	}
+	return data, err
+}
+
+// Put stores data under the given name.
+func (c *datastoreCertCache) Put(ctx context.Context, name string, data []byte) error {
+	return c.store.Put(ds.NewKey(name), data)
+}
+
+// Delete removes the data stored under the given name, if any.
+func (c *datastoreCertCache) Delete(ctx context.Context, name string) error {
+	err := c.store.Delete(ds.NewKey(name))
+	if err == ds.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// setupACME creates the autocert manager when acme_domains are configured.
+// autocert answers TLS-ALPN-01 challenges on the HTTP listener and renews
+// certificates before they expire.
+func (api *API) setupACME(store ds.Datastore) error {
+	if len(api.config.ACMEDomains) == 0 {
+		return nil
+	}
+	if store == nil {
+		return ErrACMENeedsDatastore
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(api.config.ACMEDomains...),
+		Cache:      newDatastoreCertCache(store),
+		Email:      api.config.ACMEEmail,
+	}
+	if api.config.ACMEDirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: api.config.ACMEDirectoryURL}
+	}
+	api.acmeManager = m
+
+	if api.config.ACMEHTTPListenAddr == nil {
+		return nil
+	}
+
+	n, addr, err := manet.DialArgs(api.config.ACMEHTTPListenAddr)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen(n, addr)
+	if err != nil {
+		return err
+	}
+	api.acmeListener = l
+	api.acmeServer = &http.Server{
+		ReadHeaderTimeout: api.config.ReadHeaderTimeout,
+		IdleTimeout:       api.config.IdleTimeout,
+		MaxHeaderBytes:    api.config.MaxHeaderBytes,
+		// Answers HTTP-01 challenges and redirects
+		// anything else to https.
+		Handler: m.HTTPHandler(nil),
+	}
+	return nil
+}
+
+// runs in goroutine from run()
+func (api *API) runACMEServer(ctx context.Context) {
+	defer api.wg.Done()
+
+	logger.Infof("REST API (ACME HTTP-01 challenges): %s", api.config.ACMEHTTPListenAddr)
+	err := api.acmeServer.Serve(api.acmeListener)
+	if err != nil && !strings.Contains(err.Error(), "closed network connection") {
+		logger.Error(err)
+	}
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestDatastoreCertCache(t *testing.T) {
+	ctx := context.Background()
+	cache := newDatastoreCertCache(ds.NewMapDatastore())
+
+	_, err := cache.Get(ctx, "example.com")
+	if err != autocert.ErrCacheMiss {
+		t.Fatal("expected a cache miss")
+	}
+
+	err = cache.Put(ctx, "example.com", []byte("cert"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := cache.Get(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte("cert")) {
+		t.Error("unexpected cached data")
+	}
+
+	err = cache.Delete(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cache.Get(ctx, "example.com")
+	if err != autocert.ErrCacheMiss {
+		t.Fatal("expected a cache miss after deleting")
+	}
+
+	err = cache.Delete(ctx, "example.com")
+	if err != nil {
+		t.Error("deleting missing entries should not fail")
+	}
+}
+
+func TestACMENeedsDatastore(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ACMEDomains = []string{"example.com"}
+
+	_, err := NewAPI(ctx, cfg)
+	if err != ErrACMENeedsDatastore {
+		t.Fatal("expected an error creating the API without a datastore")
+	}
+}
@@ -0,0 +1,171 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	gostream "github.com/hsanjuan/go-libp2p-gostream"
+)
+
+// Authentication backends which can be enabled for each listener with the
+// http_auth_backends and libp2p_auth_backends options.
+const (
+	// AuthBasic checks Basic Authentication credentials against
+	// basic_auth_credentials.
+	AuthBasic = "basic"
+	// AuthLDAP checks Basic Authentication credentials by binding to
+	// an LDAP server.
+	AuthLDAP = "ldap"
+	// AuthOIDC checks bearer tokens issued by an OpenID Connect
+	// provider.
+	AuthOIDC = "oidc"
+)
+
+// errNoCredentials is returned by Authenticators when the request does not
+// carry the kind of credentials they check.
+var errNoCredentials = errors.New("no credentials")
+
+// Authenticator verifies the credentials of API requests.
+type Authenticator interface {
+	// Authenticate returns the name of the user making the request,
+	// or an error when it cannot be authenticated.
+	Authenticate(r *http.Request) (string, error)
+}
+
+type userCtxKey struct{}
+
+// authenticatedUser returns the name of the user authenticated for the
+// given request, if any.
+func authenticatedUser(r *http.Request) string {
+	user, _ := r.Context().Value(userCtxKey{}).(string)
+	return user
+}
+
+// basicAuthenticator checks Basic Authentication credentials against a
+// fixed set.
+type basicAuthenticator struct {
+	credentials map[string]string
+}
+
+func (ba *basicAuthenticator) Authenticate(r *http.Request) (string, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", errNoCredentials
+	}
+	for u, p := range ba.credentials {
+		if u == username && p == password {
+			return username, nil
+		}
+	}
+	return "", errors.New("wrong username or password")
+}
+
+// newAuthenticator returns the Authenticator for the given backend.
+func newAuthenticator(cfg *Config, backend string) (Authenticator, error) {
+	switch backend {
+	case AuthBasic:
+		return &basicAuthenticator{credentials: cfg.BasicAuthCreds}, nil
+	case AuthLDAP:
+		return newLDAPAuthenticator(cfg)
+	case AuthOIDC:
+		return newOIDCAuthenticator(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown authentication backend: %s", backend)
+	}
+}
+
+// listenerAuth holds the Authenticators used by each kind of listener.
+type listenerAuth struct {
+	http   []Authenticator
+	libp2p []Authenticator
+	// Basic Authentication credentials are accepted by some backend.
+	httpBasic   bool
+	libp2pBasic bool
+}
+
+func newListenerAuth(cfg *Config) (*listenerAuth, error) {
+	// Backends shared by both listeners are only created once, so
+	// that they share their caches.
+	created := make(map[string]Authenticator)
+	build := func(backends []string) ([]Authenticator, bool, error) {
+		var auths []Authenticator
+		basic := false
+		for _, b := range backends {
+			a, ok := created[b]
+			if !ok {
+				var err error
+				a, err = newAuthenticator(cfg, b)
+				if err != nil {
+					return nil, false, err
+				}
+				created[b] = a
+			}
+			auths = append(auths, a)
+			basic = basic || b == AuthBasic || b == AuthLDAP
+		}
+		return auths, basic, nil
+	}
+
+	la := &listenerAuth{}
+	var err error
+	la.http, la.httpBasic, err = build(cfg.authBackends(cfg.HTTPAuthBackends))
+	if err != nil {
+		return nil, err
+	}
+	la.libp2p, la.libp2pBasic, err = build(cfg.authBackends(cfg.Libp2pAuthBackends))
+	if err != nil {
+		return nil, err
+	}
+	return la, nil
+}
+
+// isLibp2pRequest returns true when the request arrived on the libp2p
+// listener.
+func isLibp2pRequest(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == gostream.Network
+}
+
+// authHandler wraps a given handler so that requests are authenticated
+// with the backends enabled for the listener they arrived on, in order.
+// The authenticated user is recorded in the request context.
+func authHandler(la *listenerAuth, h http.Handler) http.Handler {
+	wrap := func(w http.ResponseWriter, r *http.Request) {
+		auths, basic := la.http, la.httpBasic
+		if isLibp2pRequest(r) {
+			auths, basic = la.libp2p, la.libp2pBasic
+		}
+		if len(auths) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		for _, a := range auths {
+			user, err := a.Authenticate(r)
+			if err == nil {
+				ctx := context.WithValue(r.Context(), userCtxKey{}, user)
+				h.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+			if err != errNoCredentials {
+				logger.Debugf("authentication failed: %s", err)
+			}
+		}
+
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		resp, err := unauthorizedResp()
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+		http.Error(w, resp, 401)
+	}
+	return http.HandlerFunc(wrap)
+}
@@ -0,0 +1,285 @@
+package rest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func TestBasicAuthenticator(t *testing.T) {
+	ba := &basicAuthenticator{credentials: map[string]string{"user": "pass"}}
+
+	r := httptest.NewRequest("GET", "/id", nil)
+	if _, err := ba.Authenticate(r); err != errNoCredentials {
+		t.Error("expected no credentials")
+	}
+	r.SetBasicAuth("user", "wrong")
+	if _, err := ba.Authenticate(r); err == nil {
+		t.Error("expected an error with a wrong password")
+	}
+	r.SetBasicAuth("user", "pass")
+	if user, err := ba.Authenticate(r); err != nil || user != "user" {
+		t.Error("expected user to be authenticated")
+	}
+}
+
+// fakeLDAPServer accepts binds with the password "secret" over TLS and
+// returns its listener and the number of binds received.
+func fakeLDAPServer(t *testing.T) (net.Listener, *int64) {
+	tlsCfg, err := newTLSConfig(SSLCertFile, SSLKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", tlsCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binds := new(int64)
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		for {
+			packet, err := ber.ReadPacket(conn)
+			if err != nil || len(packet.Children) < 2 {
+				return
+			}
+			req := packet.Children[1]
+			if req.Tag != ldap.ApplicationBindRequest || len(req.Children) < 3 {
+				continue
+			}
+			atomic.AddInt64(binds, 1)
+			code := int64(ldap.LDAPResultInvalidCredentials)
+			if string(req.Children[2].Data.Bytes()) == "secret" {
+				code = int64(ldap.LDAPResultSuccess)
+			}
+
+			resp := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			resp.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, packet.Children[0].Value, "MessageID"))
+			bindResp := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationBindResponse, nil, "Bind Response")
+			bindResp.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+			bindResp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+			bindResp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "test", "diagnosticMessage"))
+			resp.AppendChild(bindResp)
+			conn.Write(resp.Bytes())
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return l, binds
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	l, binds := fakeLDAPServer(t)
+	defer l.Close()
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.LDAPURL = "ldaps://" + l.Addr().String()
+	cfg.LDAPBindDN = "uid=%s,ou=people,dc=example,dc=org"
+	la, err := newLDAPAuthenticator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The test certificate is self-signed.
+	la.tlsConfig.InsecureSkipVerify = true
+
+	r := httptest.NewRequest("GET", "/id", nil)
+	r.SetBasicAuth("user", "wrong")
+	if _, err := la.Authenticate(r); err == nil {
+		t.Error("expected an error with a wrong password")
+	}
+	r.SetBasicAuth("user", "")
+	if _, err := la.Authenticate(r); err == nil {
+		t.Error("empty passwords should be refused")
+	}
+
+	r.SetBasicAuth("user", "secret")
+	for i := 0; i < 2; i++ {
+		user, err := la.Authenticate(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user != "ldap:user" {
+			t.Error("unexpected user:", user)
+		}
+	}
+	if n := atomic.LoadInt64(binds); n != 2 {
+		t.Errorf("expected successful binds to be cached: %d binds", n)
+	}
+}
+
+func TestLDAPAuthenticatorStartTLS(t *testing.T) {
+	// A server which does not support StartTLS.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.LDAPURL = "ldap://" + l.Addr().String()
+	cfg.LDAPBindDN = "uid=%s,ou=people,dc=example,dc=org"
+	la, err := newLDAPAuthenticator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/id", nil)
+	r.SetBasicAuth("user", "secret")
+	if _, err := la.Authenticate(r); err == nil {
+		t.Error("credentials should not be sent without StartTLS")
+	}
+}
+
+func TestEscapeDN(t *testing.T) {
+	if s := escapeDN(" a,b=c "); s != `\ a\,b\=c\ ` {
+		t.Errorf("unexpected escaped DN: %s", s)
+	}
+}
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	enc := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := enc(map[string]string{"alg": "RS256", "kid": kid}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer": "%s", "jwks_uri": "%s/keys"}`, srv.URL, srv.URL)
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{{
+					"kty": "RSA",
+					"kid": "k1",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.OIDCIssuer = srv.URL
+	cfg.OIDCAudience = "cluster"
+	cfg.OIDCUserClaim = "email"
+	oa := newOIDCAuthenticator(cfg)
+
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   srv.URL,
+			"aud":   []string{"other", "cluster"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"email": "user@example.org",
+		}
+	}
+	authenticate := func(token string) (string, error) {
+		r := httptest.NewRequest("GET", "/id", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return oa.Authenticate(r)
+	}
+
+	user, err := authenticate(signJWT(t, key, "k1", claims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != "oidc:user@example.org" {
+		t.Error("unexpected user:", user)
+	}
+
+	c := claims()
+	c["aud"] = "other"
+	if _, err := authenticate(signJWT(t, key, "k1", c)); err == nil {
+		t.Error("expected an error with the wrong audience")
+	}
+	c = claims()
+	c["exp"] = time.Now().Add(-time.Hour).Unix()
+	if _, err := authenticate(signJWT(t, key, "k1", c)); err == nil {
+		t.Error("expected an error with an expired token")
+	}
+	c = claims()
+	c["iss"] = "https://example.org"
+	if _, err := authenticate(signJWT(t, key, "k1", c)); err == nil {
+		t.Error("expected an error with the wrong issuer")
+	}
+	if _, err := authenticate(signJWT(t, key, "k2", claims())); err == nil {
+		t.Error("expected an error with an unknown key")
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticate(signJWT(t, otherKey, "k1", claims())); err == nil {
+		t.Error("expected an error with a bad signature")
+	}
+
+	parts := strings.Split(signJWT(t, key, "k1", claims()), ".")
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"k1"}`))
+	if _, err := authenticate(none + "." + parts[1] + "."); err == nil {
+		t.Error("expected unsigned tokens to be refused")
+	}
+
+	r := httptest.NewRequest("GET", "/id", nil)
+	r.SetBasicAuth("user", "pass")
+	if _, err := oa.Authenticate(r); err != errNoCredentials {
+		t.Error("expected no credentials without a bearer token")
+	}
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	types "github.com/ipfs/ipfs-cluster/api"
+)
+
+// cidBaseHandler re-encodes the CIDs in the responses of h with the
+// multibase given in the cid-base query parameter, when present.
+func cidBaseHandler(h http.Handler) http.Handler {
+	wrap := func(w http.ResponseWriter, r *http.Request) {
+		base := r.URL.Query().Get("cid-base")
+		if base == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		enc, err := types.NewCidBaseEncoder(base)
+		if err != nil {
+			resp, err := json.Marshal(&types.Error{
+				Code:    http.StatusBadRequest,
+				Message: "parameter cid-base is invalid",
+			})
+			if err != nil {
+				logger.Error(err)
+				return
+			}
+			http.Error(w, string(resp), http.StatusBadRequest)
+			return
+		}
+		h.ServeHTTP(&cidBaseResponseWriter{ResponseWriter: w, enc: enc}, r)
+	}
+	return http.HandlerFunc(wrap)
+}
+
+// cidBaseResponseWriter re-encodes the CIDs in the JSON written to it.
+// Responses are written with json.Encoder, which writes every value at
+// once, so CIDs are never split across writes.
+type cidBaseResponseWriter struct {
+	http.ResponseWriter
+	enc *types.CidBaseEncoder
+}
+
+func (w *cidBaseResponseWriter) Write(b []byte) (int, error) {
+	_, err := w.ResponseWriter.Write(w.enc.EncodeJSON(b))
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher so that streamed responses keep working.
+func (w *cidBaseResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -48,6 +48,8 @@ var logger = logging.Logger(loggingFacility)
 type Client interface {
 	// ID returns information about the cluster Peer.
 	ID(context.Context) (*api.ID, error)
+	// ClusterInfo returns aggregate facts about the whole cluster.
+	ClusterInfo(context.Context) (*api.ClusterInfo, error)
 
 	// Peers requests ID information for all cluster peers.
 	Peers(context.Context) ([]*api.ID, error)
@@ -55,6 +57,42 @@ type Client interface {
 	PeerAdd(ctx context.Context, pid peer.ID) (*api.ID, error)
 	// PeerRm removes a current peer from the cluster
 	PeerRm(ctx context.Context, pid peer.ID) error
+	// PeerMigrate re-allocates the pins of a peer to other peers and
+	// returns them with their new allocations.
+	PeerMigrate(ctx context.Context, pid peer.ID) (*api.PeerMigration, error)
+	// Peerstore lists the peers known to the cluster peer, along with
+	// their addresses and when they were last seen.
+	Peerstore(ctx context.Context) ([]*api.KnownPeer, error)
+	// PeerstoreAdd seeds the peerstore of the cluster peer with the given
+	// addresses, which must include the /p2p/ part.
+	PeerstoreAdd(ctx context.Context, addrs []api.Multiaddr) ([]*api.KnownPeer, error)
+	// SetAllocatable sets whether a peer is a candidate for new
+	// allocations.
+	SetAllocatable(ctx context.Context, pid peer.ID, allocatable bool) error
+	// SetTrackerPaused pauses or resumes the pin tracker of a peer. A
+	// paused tracker does not start queued pin and unpin operations.
+	SetTrackerPaused(ctx context.Context, pid peer.ID, paused bool) error
+	// JoinToken creates a token, valid for the given time, which new
+	// peers can use to bootstrap to the cluster.
+	JoinToken(ctx context.Context, ttl time.Duration) (*api.JoinToken, error)
+	// ConnectionDenyList returns the peers and IP ranges which cannot
+	// connect to the cluster peer.
+	ConnectionDenyList(ctx context.Context) (*api.ConnectionFilter, error)
+	// ConnectionDeny adds peers and IP ranges to the connection deny list
+	// of the cluster peer.
+	ConnectionDeny(ctx context.Context, deny *api.ConnectionFilter) error
+	// ConnectionUndeny removes peers and IP ranges from the connection
+	// deny list of the cluster peer.
+	ConnectionUndeny(ctx context.Context, undeny *api.ConnectionFilter) error
+	// Faults returns the fault injection rules of the cluster peer.
+	Faults(ctx context.Context) ([]*api.FaultRule, error)
+	// SetFaults replaces the fault injection rules of the cluster peer.
+	// An empty list stops injecting faults.
+	SetFaults(ctx context.Context, rules []*api.FaultRule) error
+
+	// Logs returns the recent log entries captured by the cluster peer,
+	// optionally only those of a component and with a minimum level.
+	Logs(ctx context.Context, component, level string) ([]*api.LogEntry, error)
 
 	// Add imports files to the cluster from the given paths.
 	Add(ctx context.Context, paths []string, params *api.AddParams, out chan<- *api.AddedOutput) error
@@ -66,6 +104,23 @@ type Client interface {
 	Pin(ctx context.Context, ci cid.Cid, opts api.PinOptions) error
 	// Unpin untracks a Cid from cluster.
 	Unpin(ctx context.Context, ci cid.Cid) error
+	// RestorePin cancels the removal of a Cid which was unpinned during
+	// the unpin retention period.
+	RestorePin(ctx context.Context, ci cid.Cid) (*api.Pin, error)
+
+	// Snapshots lists the pinset snapshots stored by the cluster peer.
+	Snapshots(ctx context.Context) ([]*api.Snapshot, error)
+	// SnapshotCreate takes a snapshot of the pinset with the given name.
+	SnapshotCreate(ctx context.Context, name string) (*api.Snapshot, error)
+	// SnapshotDelete removes a pinset snapshot.
+	SnapshotDelete(ctx context.Context, name string) error
+	// SnapshotDiff compares two pinset snapshots. Either can be
+	// api.SnapshotCurrent.
+	SnapshotDiff(ctx context.Context, from, to string) (*api.SnapshotDiff, error)
+	// SnapshotRestore brings the pinset back to the given snapshot.
+	// Restores which would unpin many items must be forced, and maybe
+	// confirmed, with the given options.
+	SnapshotRestore(ctx context.Context, name string, opts api.BulkUnpinOptions) (*api.SnapshotRestore, error)
 
 	// PinPath resolves given path into a cid and performs the pin operation.
 	PinPath(ctx context.Context, path string, opts api.PinOptions) (*api.Pin, error)
@@ -76,6 +131,9 @@ type Client interface {
 	// Allocations returns the consensus state listing all tracked items
 	// and the peers that should be pinning them.
 	Allocations(ctx context.Context, filter api.PinType) ([]*api.Pin, error)
+	// AllocationsSince returns the tracked items added to the consensus
+	// state at or after the given time, sorted by their creation time.
+	AllocationsSince(ctx context.Context, filter api.PinType, since time.Time) ([]*api.Pin, error)
 	// Allocation returns the current allocations for a given Cid.
 	Allocation(ctx context.Context, ci cid.Cid) (*api.Pin, error)
 
@@ -83,8 +141,21 @@ type Client interface {
 	// the information affects only the current peer, otherwise the information
 	// is fetched from all cluster peers.
 	Status(ctx context.Context, ci cid.Cid, local bool) (*api.GlobalPinInfo, error)
+	// StatusExplain works like Status but also includes information about
+	// how the allocations for the Cid were decided.
+	StatusExplain(ctx context.Context, ci cid.Cid, local bool) (*api.GlobalPinInfo, error)
+	// PinDetails returns the Pin for a Cid from the shared state along
+	// with its current status in every cluster peer.
+	PinDetails(ctx context.Context, ci cid.Cid) (*api.PinDetails, error)
+	// PinGateways returns the URLs to retrieve a Cid from the IPFS
+	// gateways of the peers which have it pinned.
+	PinGateways(ctx context.Context, ci cid.Cid) ([]string, error)
 	// StatusAll gathers Status() for all tracked items.
 	StatusAll(ctx context.Context, filter api.TrackerStatus, local bool) ([]*api.GlobalPinInfo, error)
+	// StatusSummary returns the number of items in each status for
+	// every peer and their totals. If local is true, only the current
+	// peer is counted.
+	StatusSummary(ctx context.Context, local bool) (*api.StatusSummary, error)
 
 	// Sync makes sure the state of a Cid corresponds to the state reported
 	// by the ipfs daemon, and returns it. If local is true, this operation
@@ -101,10 +172,39 @@ type Client interface {
 	// state.  If local is true, the operation is limited to the current
 	// peer, otherwise it happens on every cluster peer.
 	Recover(ctx context.Context, ci cid.Cid, local bool) (*api.GlobalPinInfo, error)
-	// RecoverAll triggers Recover() operations on all tracked items. If
-	// local is true, the operation is limited to the current peer.
-	// Otherwise, it happens everywhere.
+	// RecoverAll triggers Recover() operations on all tracked items of
+	// the current peer. Only local is supported: use RecoveryStart to
+	// recover the items of every peer.
 	RecoverAll(ctx context.Context, local bool) ([]*api.GlobalPinInfo, error)
+	// Provide makes the IPFS daemons of the peers allocated to a pin
+	// announce its root CID to the network.
+	Provide(ctx context.Context, ci cid.Cid) ([]*api.ProvideResult, error)
+	// StateSync triggers a sync of the shared state to the pin tracker
+	// and returns the results. If local is true, the operation is
+	// limited to the current peer. Otherwise, it happens on every peer.
+	StateSync(ctx context.Context, local bool) ([]*api.StateSync, error)
+	// RepoGC runs the garbage collector of the IPFS daemons of every
+	// peer, or only of the current one if local is true.
+	RepoGC(ctx context.Context, local bool) ([]*api.RepoGC, error)
+	// StateSyncPeer triggers a state sync on the given peer only.
+	StateSyncPeer(ctx context.Context, pid peer.ID) ([]*api.StateSync, error)
+	// LastStateSync returns the results of the last state sync. If local
+	// is true, only the current peer's are returned.
+	LastStateSync(ctx context.Context, local bool) ([]*api.StateSync, error)
+
+	// AuditLog returns the audit log of the peer, which records
+	// destructive operations like bulk unpins.
+	AuditLog(ctx context.Context) ([]*api.AuditRecord, error)
+
+	// RecoverPeer triggers Recover() operations on all items tracked by
+	// the given peer, that is, on the items allocated to it.
+	RecoverPeer(ctx context.Context, pid peer.ID) ([]*api.GlobalPinInfo, error)
+	// RecoveryStart starts a background job which recovers every item in
+	// error state in the cluster, and returns it.
+	RecoveryStart(ctx context.Context) (*api.RecoveryJob, error)
+	// RecoveryStatus returns the progress of the last recovery job
+	// started by the current peer.
+	RecoveryStatus(ctx context.Context) (*api.RecoveryJob, error)
 
 	// Version returns the ipfs-cluster peer's version.
 	Version(context.Context) (*api.Version, error)
@@ -116,6 +216,31 @@ type Client interface {
 	// GetConnectGraph returns an ipfs-cluster connection graph.
 	GetConnectGraph(context.Context) (*api.ConnectGraph, error)
 
+	// Ping measures the RPC round-trip time and clock skew from the
+	// current peer to the given one, or to every cluster peer when pid
+	// is empty.
+	Ping(ctx context.Context, pid peer.ID) ([]*api.PingResult, error)
+
+	// Versions returns the versions run by every cluster peer and whether
+	// they are compatible with those of the current peer.
+	Versions(context.Context) ([]*api.PeerVersion, error)
+
+	// RuntimeStats returns the Go runtime stats of every cluster peer,
+	// or of the current peer only when local is true.
+	RuntimeStats(ctx context.Context, local bool) ([]*api.RuntimeStats, error)
+
+	// IPFSDrift compares the IPFS daemons of every cluster peer and
+	// reports differences in their versions and settings.
+	IPFSDrift(ctx context.Context) (*api.IPFSDrift, error)
+
+	// StorageUsage returns the deduplicated storage used by the cluster
+	// pinset, estimated from sample pins when sample is greater than 0.
+	StorageUsage(ctx context.Context, sample int) (*api.StorageUsage, error)
+
+	// ReconnectStatus reports whether the cluster peer is in contact with
+	// other peers and how its attempts to reconnect to them are going.
+	ReconnectStatus(ctx context.Context) (*api.ReconnectStatus, error)
+
 	// Metrics returns a map with the latest metrics of matching name
 	// for the current cluster peers.
 	Metrics(ctx context.Context, name string) ([]*api.Metric, error)
@@ -133,6 +258,10 @@ type Config struct {
 	Username string
 	Password string
 
+	// Bearer token (i.e. an OpenID Connect ID token) for token
+	// authentication. Takes precedence over Username and Password.
+	Token string
+
 	// The ipfs-cluster REST API endpoint in multiaddress form
 	// (takes precedence over host:port). It this address contains
 	// an /ipfs/, /p2p/ or /dnsaddr, the API will be contacted
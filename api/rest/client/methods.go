@@ -72,6 +72,171 @@ func (c *defaultClient) PeerRm(ctx context.Context, id peer.ID) error {
 	return c.do(ctx, "DELETE", fmt.Sprintf("/peers/%s", id.Pretty()), nil, nil, nil)
 }
 
+// PeerMigrate re-allocates the pins of a peer to other peers, so that it
+// can be removed safely. The returned pins carry their new allocations.
+func (c *defaultClient) PeerMigrate(ctx context.Context, id peer.ID) (*api.PeerMigration, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PeerMigrate")
+	defer span.End()
+
+	var mig api.PeerMigration
+	err := c.do(ctx, "POST", fmt.Sprintf("/peers/%s/migrate", id.Pretty()), nil, nil, &mig)
+	return &mig, err
+}
+
+// Peerstore lists the peers known to the cluster peer, along with their
+// addresses and when they were last seen.
+func (c *defaultClient) Peerstore(ctx context.Context) ([]*api.KnownPeer, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Peerstore")
+	defer span.End()
+
+	var known []*api.KnownPeer
+	err := c.do(ctx, "GET", "/peerstore", nil, nil, &known)
+	return known, err
+}
+
+type peerstoreAddBody struct {
+	Addresses []api.Multiaddr `json:"addresses"`
+}
+
+// PeerstoreAdd seeds the peerstore of the cluster peer with the given
+// addresses, which must include the /p2p/ part.
+func (c *defaultClient) PeerstoreAdd(ctx context.Context, addrs []api.Multiaddr) ([]*api.KnownPeer, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PeerstoreAdd")
+	defer span.End()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.Encode(peerstoreAddBody{addrs})
+
+	var known []*api.KnownPeer
+	err := c.do(ctx, "POST", "/peerstore", nil, &buf, &known)
+	return known, err
+}
+
+// SetAllocatable sets whether a peer is a candidate for new
+// allocations.
+func (c *defaultClient) SetAllocatable(ctx context.Context, id peer.ID, allocatable bool) error {
+	ctx, span := trace.StartSpan(ctx, "client/SetAllocatable")
+	defer span.End()
+
+	method := "POST"
+	if !allocatable {
+		method = "DELETE"
+	}
+	return c.do(ctx, method, fmt.Sprintf("/peers/%s/allocatable", id.Pretty()), nil, nil, nil)
+}
+
+// SetTrackerPaused pauses or resumes the pin tracker of a peer. A paused
+// tracker does not start queued pin and unpin operations.
+func (c *defaultClient) SetTrackerPaused(ctx context.Context, id peer.ID, paused bool) error {
+	ctx, span := trace.StartSpan(ctx, "client/SetTrackerPaused")
+	defer span.End()
+
+	method := "POST"
+	if !paused {
+		method = "DELETE"
+	}
+	return c.do(ctx, method, fmt.Sprintf("/peers/%s/paused", id.Pretty()), nil, nil, nil)
+}
+
+// JoinToken creates a token, valid for the given time, which new peers can
+// use to bootstrap to the cluster.
+func (c *defaultClient) JoinToken(ctx context.Context, ttl time.Duration) (*api.JoinToken, error) {
+	ctx, span := trace.StartSpan(ctx, "client/JoinToken")
+	defer span.End()
+
+	var token api.JoinToken
+	err := c.do(ctx, "POST", fmt.Sprintf("/peers/token?ttl=%s", ttl), nil, nil, &token)
+	return &token, err
+}
+
+// ConnectionDenyList returns the peers and IP ranges which cannot connect to
+// the cluster peer.
+func (c *defaultClient) ConnectionDenyList(ctx context.Context) (*api.ConnectionFilter, error) {
+	ctx, span := trace.StartSpan(ctx, "client/ConnectionDenyList")
+	defer span.End()
+
+	var deny api.ConnectionFilter
+	err := c.do(ctx, "GET", "/connections/deny", nil, nil, &deny)
+	return &deny, err
+}
+
+// ConnectionDeny adds peers and IP ranges to the connection deny list of the
+// cluster peer.
+func (c *defaultClient) ConnectionDeny(ctx context.Context, deny *api.ConnectionFilter) error {
+	ctx, span := trace.StartSpan(ctx, "client/ConnectionDeny")
+	defer span.End()
+
+	return c.do(ctx, "POST", "/connections/deny?"+connectionFilterQuery(deny), nil, nil, nil)
+}
+
+// ConnectionUndeny removes peers and IP ranges from the connection deny list
+// of the cluster peer.
+func (c *defaultClient) ConnectionUndeny(ctx context.Context, undeny *api.ConnectionFilter) error {
+	ctx, span := trace.StartSpan(ctx, "client/ConnectionUndeny")
+	defer span.End()
+
+	return c.do(ctx, "DELETE", "/connections/deny?"+connectionFilterQuery(undeny), nil, nil, nil)
+}
+
+// Faults returns the fault injection rules of the cluster peer.
+func (c *defaultClient) Faults(ctx context.Context) ([]*api.FaultRule, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Faults")
+	defer span.End()
+
+	var rules []*api.FaultRule
+	err := c.do(ctx, "GET", "/faults", nil, nil, &rules)
+	return rules, err
+}
+
+// SetFaults replaces the fault injection rules of the cluster peer. An
+// empty list stops injecting faults.
+func (c *defaultClient) SetFaults(ctx context.Context, rules []*api.FaultRule) error {
+	ctx, span := trace.StartSpan(ctx, "client/SetFaults")
+	defer span.End()
+
+	if rules == nil {
+		rules = []*api.FaultRule{}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.Encode(rules)
+
+	return c.do(ctx, "POST", "/faults", nil, &buf, nil)
+}
+
+// Logs returns the recent log entries captured by the cluster peer, oldest
+// first. When set, component and level (i.e. "warn") select only the
+// entries of that component and with that level or a more severe one.
+func (c *defaultClient) Logs(ctx context.Context, component, level string) ([]*api.LogEntry, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Logs")
+	defer span.End()
+
+	q := url.Values{}
+	if component != "" {
+		q.Set("component", component)
+	}
+	if level != "" {
+		q.Set("level", level)
+	}
+
+	var entries []*api.LogEntry
+	err := c.do(ctx, "GET", "/debug/logs?"+q.Encode(), nil, nil, &entries)
+	return entries, err
+}
+
+func connectionFilterQuery(f *api.ConnectionFilter) string {
+	q := url.Values{}
+	for _, p := range f.Peers {
+		q.Add("peer", peer.IDB58Encode(p))
+	}
+	for _, c := range f.CIDRs {
+		q.Add("cidr", c)
+	}
+	return q.Encode()
+}
+
 // Pin tracks a Cid with the given replication factor and a name for
 // human-friendliness.
 func (c *defaultClient) Pin(ctx context.Context, ci cid.Cid, opts api.PinOptions) error {
@@ -127,6 +292,88 @@ func (c *defaultClient) PinPath(ctx context.Context, path string, opts api.PinOp
 	return &pin, err
 }
 
+// RestorePin cancels the removal of a Cid which was unpinned during the
+// unpin retention period. It returns the restored api.Pin.
+func (c *defaultClient) RestorePin(ctx context.Context, ci cid.Cid) (*api.Pin, error) {
+	ctx, span := trace.StartSpan(ctx, "client/RestorePin")
+	defer span.End()
+
+	var pin api.Pin
+	err := c.do(ctx, "POST", fmt.Sprintf("/pins/%s/restore", ci.String()), nil, nil, &pin)
+	return &pin, err
+}
+
+// Snapshots lists the pinset snapshots stored by the cluster peer.
+func (c *defaultClient) Snapshots(ctx context.Context) ([]*api.Snapshot, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Snapshots")
+	defer span.End()
+
+	var snaps []*api.Snapshot
+	err := c.do(ctx, "GET", "/snapshots", nil, nil, &snaps)
+	return snaps, err
+}
+
+// SnapshotCreate takes a snapshot of the pinset with the given name.
+func (c *defaultClient) SnapshotCreate(ctx context.Context, name string) (*api.Snapshot, error) {
+	ctx, span := trace.StartSpan(ctx, "client/SnapshotCreate")
+	defer span.End()
+
+	var snap api.Snapshot
+	err := c.do(ctx, "POST", "/snapshots?name="+url.QueryEscape(name), nil, nil, &snap)
+	return &snap, err
+}
+
+// SnapshotDelete removes a pinset snapshot.
+func (c *defaultClient) SnapshotDelete(ctx context.Context, name string) error {
+	ctx, span := trace.StartSpan(ctx, "client/SnapshotDelete")
+	defer span.End()
+
+	return c.do(ctx, "DELETE", "/snapshots/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// SnapshotDiff compares two pinset snapshots. Either can be
+// api.SnapshotCurrent.
+func (c *defaultClient) SnapshotDiff(ctx context.Context, from, to string) (*api.SnapshotDiff, error) {
+	ctx, span := trace.StartSpan(ctx, "client/SnapshotDiff")
+	defer span.End()
+
+	var diff api.SnapshotDiff
+	err := c.do(
+		ctx,
+		"GET",
+		fmt.Sprintf("/snapshots/%s/diff/%s", url.PathEscape(from), url.PathEscape(to)),
+		nil,
+		nil,
+		&diff,
+	)
+	return &diff, err
+}
+
+// SnapshotRestore brings the pinset back to the given snapshot. Restores
+// which would unpin many items must be forced, and maybe confirmed, with
+// the given options.
+func (c *defaultClient) SnapshotRestore(ctx context.Context, name string, opts api.BulkUnpinOptions) (*api.SnapshotRestore, error) {
+	ctx, span := trace.StartSpan(ctx, "client/SnapshotRestore")
+	defer span.End()
+
+	q := url.Values{}
+	q.Set("force", fmt.Sprintf("%t", opts.Force))
+	if opts.Confirm != "" {
+		q.Set("confirm", opts.Confirm)
+	}
+
+	var res api.SnapshotRestore
+	err := c.do(
+		ctx,
+		"POST",
+		fmt.Sprintf("/snapshots/%s/restore?%s", url.PathEscape(name), q.Encode()),
+		nil,
+		nil,
+		&res,
+	)
+	return &res, err
+}
+
 // UnpinPath allows to unpin an item by providing its IPFS path.
 // It returns the unpinned api.Pin information of the resolved Cid.
 func (c *defaultClient) UnpinPath(ctx context.Context, p string) (*api.Pin, error) {
@@ -150,6 +397,38 @@ func (c *defaultClient) Allocations(ctx context.Context, filter api.PinType) ([]
 	defer span.End()
 
 	var pins []*api.Pin
+	f := url.QueryEscape(pinTypeFilter(filter))
+	err := c.do(ctx, "GET", fmt.Sprintf("/allocations?filter=%s", f), nil, nil, &pins)
+	return pins, err
+}
+
+// AllocationsSince works like Allocations but only returns the items
+// which were added to the consensus state at or after the given time,
+// sorted by their creation time.
+func (c *defaultClient) AllocationsSince(ctx context.Context, filter api.PinType, since time.Time) ([]*api.Pin, error) {
+	ctx, span := trace.StartSpan(ctx, "client/AllocationsSince")
+	defer span.End()
+
+	var pins []*api.Pin
+	f := url.QueryEscape(pinTypeFilter(filter))
+	s := url.QueryEscape(since.Format(time.RFC3339Nano))
+	err := c.do(
+		ctx,
+		"GET",
+		fmt.Sprintf("/allocations?filter=%s&since=%s&sort=created_at", f, s),
+		nil,
+		nil,
+		&pins,
+	)
+	return pins, err
+}
+
+// pinTypeFilter returns the value of the filter query parameter for
+// the given pin types.
+func pinTypeFilter(filter api.PinType) string {
+	if filter == api.AllType {
+		return "all"
+	}
 
 	types := []api.PinType{
 		api.DataType,
@@ -159,20 +438,12 @@ func (c *defaultClient) Allocations(ctx context.Context, filter api.PinType) ([]
 	}
 
 	var strFilter []string
-
-	if filter == api.AllType {
-		strFilter = []string{"all"}
-	} else {
-		for _, t := range types {
-			if t&filter > 0 { // the filter includes this type
-				strFilter = append(strFilter, t.String())
-			}
+	for _, t := range types {
+		if t&filter > 0 { // the filter includes this type
+			strFilter = append(strFilter, t.String())
 		}
 	}
-
-	f := url.QueryEscape(strings.Join(strFilter, ","))
-	err := c.do(ctx, "GET", fmt.Sprintf("/allocations?filter=%s", f), nil, nil, &pins)
-	return pins, err
+	return strings.Join(strFilter, ",")
 }
 
 // Allocation returns the current allocations for a given Cid.
@@ -204,6 +475,60 @@ func (c *defaultClient) Status(ctx context.Context, ci cid.Cid, local bool) (*ap
 	return &gpi, err
 }
 
+// PinDetails returns the Pin for a Cid from the shared state along with its
+// current status in every cluster peer.
+func (c *defaultClient) PinDetails(ctx context.Context, ci cid.Cid) (*api.PinDetails, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PinDetails")
+	defer span.End()
+
+	var details api.PinDetails
+	err := c.do(ctx, "GET", fmt.Sprintf("/pins/%s/details", ci.String()), nil, nil, &details)
+	return &details, err
+}
+
+// PinGateways returns the URLs to retrieve a Cid from the IPFS gateways of
+// the peers which have it pinned. Only peers with a configured gateway URL
+// are included.
+func (c *defaultClient) PinGateways(ctx context.Context, ci cid.Cid) ([]string, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PinGateways")
+	defer span.End()
+
+	var gateways []string
+	err := c.do(ctx, "GET", fmt.Sprintf("/pins/%s/gateways", ci.String()), nil, nil, &gateways)
+	return gateways, err
+}
+
+// StatusExplain works like Status but also includes information about how
+// the allocations for the Cid were decided (allocator, metrics and rejected
+// peers), when available.
+func (c *defaultClient) StatusExplain(ctx context.Context, ci cid.Cid, local bool) (*api.GlobalPinInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "client/StatusExplain")
+	defer span.End()
+
+	var gpi api.GlobalPinInfo
+	err := c.do(
+		ctx,
+		"GET",
+		fmt.Sprintf("/pins/%s?local=%t&explain=true", ci.String(), local),
+		nil,
+		nil,
+		&gpi,
+	)
+	return &gpi, err
+}
+
+// StatusSummary returns the number of items in each status for every peer
+// and their totals, without fetching the status of every item. If local is
+// true, only the current peer is counted.
+func (c *defaultClient) StatusSummary(ctx context.Context, local bool) (*api.StatusSummary, error) {
+	ctx, span := trace.StartSpan(ctx, "client/StatusSummary")
+	defer span.End()
+
+	var summary api.StatusSummary
+	err := c.do(ctx, "GET", fmt.Sprintf("/pins/summary?local=%t", local), nil, nil, &summary)
+	return &summary, err
+}
+
 // StatusAll gathers Status() for all tracked items. If a filter is
 // provided, only entries matching the given filter statuses
 // will be returned. A filter can be built by merging TrackerStatuses with
@@ -278,9 +603,20 @@ func (c *defaultClient) Recover(ctx context.Context, ci cid.Cid, local bool) (*a
 	return &gpi, err
 }
 
-// RecoverAll triggers Recover() operations on all tracked items. If local is
-// true, the operation is limited to the current peer. Otherwise, it happens
-// everywhere.
+// Provide makes the IPFS daemons of the peers allocated to a pin announce
+// its root CID to the network. It returns the result for every peer.
+func (c *defaultClient) Provide(ctx context.Context, ci cid.Cid) ([]*api.ProvideResult, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Provide")
+	defer span.End()
+
+	var results []*api.ProvideResult
+	err := c.do(ctx, "POST", fmt.Sprintf("/pins/%s/provide", ci.String()), nil, nil, &results)
+	return results, err
+}
+
+// RecoverAll triggers Recover() operations on all tracked items of the
+// current peer. Only local is supported: use RecoveryStart to recover the
+// items of every peer.
 func (c *defaultClient) RecoverAll(ctx context.Context, local bool) ([]*api.GlobalPinInfo, error) {
 	ctx, span := trace.StartSpan(ctx, "client/RecoverAll")
 	defer span.End()
@@ -290,6 +626,108 @@ func (c *defaultClient) RecoverAll(ctx context.Context, local bool) ([]*api.Glob
 	return gpis, err
 }
 
+// RecoverPeer triggers Recover() operations on all items tracked by the
+// given peer, that is, on the items allocated to it. Other peers are not
+// affected.
+func (c *defaultClient) RecoverPeer(ctx context.Context, pid peer.ID) ([]*api.GlobalPinInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "client/RecoverPeer")
+	defer span.End()
+
+	var gpis []*api.GlobalPinInfo
+	err := c.do(ctx, "POST", fmt.Sprintf("/pins/recover?peer=%s", pid.Pretty()), nil, nil, &gpis)
+	return gpis, err
+}
+
+// RecoveryStart starts a background job which recovers every item in error
+// state in the cluster, and returns it.
+func (c *defaultClient) RecoveryStart(ctx context.Context) (*api.RecoveryJob, error) {
+	ctx, span := trace.StartSpan(ctx, "client/RecoveryStart")
+	defer span.End()
+
+	var job api.RecoveryJob
+	err := c.do(ctx, "POST", "/pins/recover?local=false", nil, nil, &job)
+	return &job, err
+}
+
+// RecoveryStatus returns the progress of the last recovery job started by
+// the current peer.
+func (c *defaultClient) RecoveryStatus(ctx context.Context) (*api.RecoveryJob, error) {
+	ctx, span := trace.StartSpan(ctx, "client/RecoveryStatus")
+	defer span.End()
+
+	var job api.RecoveryJob
+	err := c.do(ctx, "GET", "/pins/recover", nil, nil, &job)
+	return &job, err
+}
+
+// StateSync triggers a sync of the shared state to the pin tracker and
+// returns the results. If local is true, the operation is limited to the
+// current peer. Otherwise, it happens on every cluster peer.
+func (c *defaultClient) StateSync(ctx context.Context, local bool) ([]*api.StateSync, error) {
+	ctx, span := trace.StartSpan(ctx, "client/StateSync")
+	defer span.End()
+
+	var results []*api.StateSync
+	err := c.do(ctx, "POST", fmt.Sprintf("/sync?local=%t", local), nil, nil, &results)
+	return results, err
+}
+
+// RepoGC runs the garbage collector of the IPFS daemons of every cluster
+// peer and returns the results. If local is true, only the IPFS daemon of
+// the current peer is garbage collected.
+func (c *defaultClient) RepoGC(ctx context.Context, local bool) ([]*api.RepoGC, error) {
+	ctx, span := trace.StartSpan(ctx, "client/RepoGC")
+	defer span.End()
+
+	var results []*api.RepoGC
+	err := c.do(ctx, "POST", fmt.Sprintf("/ipfs/gc?local=%t", local), nil, nil, &results)
+	return results, err
+}
+
+// StateSyncPeer triggers a sync of the shared state to the pin tracker on
+// the given peer only.
+func (c *defaultClient) StateSyncPeer(ctx context.Context, pid peer.ID) ([]*api.StateSync, error) {
+	ctx, span := trace.StartSpan(ctx, "client/StateSyncPeer")
+	defer span.End()
+
+	var results []*api.StateSync
+	err := c.do(ctx, "POST", fmt.Sprintf("/sync?peer=%s", pid.Pretty()), nil, nil, &results)
+	return results, err
+}
+
+// LastStateSync returns the results of the last state sync run. If local
+// is true, only the results for the current peer are returned. Otherwise,
+// they are fetched from every cluster peer.
+func (c *defaultClient) LastStateSync(ctx context.Context, local bool) ([]*api.StateSync, error) {
+	ctx, span := trace.StartSpan(ctx, "client/LastStateSync")
+	defer span.End()
+
+	var results []*api.StateSync
+	err := c.do(ctx, "GET", fmt.Sprintf("/sync/last?local=%t", local), nil, nil, &results)
+	return results, err
+}
+
+// AuditLog returns the audit log of the peer, oldest records first.
+func (c *defaultClient) AuditLog(ctx context.Context) ([]*api.AuditRecord, error) {
+	ctx, span := trace.StartSpan(ctx, "client/AuditLog")
+	defer span.End()
+
+	var recs []*api.AuditRecord
+	err := c.do(ctx, "GET", "/audit", nil, nil, &recs)
+	return recs, err
+}
+
+// ClusterInfo returns aggregate facts about the cluster (number of peers,
+// pins and errors, free space, consensus and oldest peer version).
+func (c *defaultClient) ClusterInfo(ctx context.Context) (*api.ClusterInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "client/ClusterInfo")
+	defer span.End()
+
+	var info api.ClusterInfo
+	err := c.do(ctx, "GET", "/cluster/info", nil, nil, &info)
+	return &info, err
+}
+
 // Version returns the ipfs-cluster peer's version.
 func (c *defaultClient) Version(ctx context.Context) (*api.Version, error) {
 	ctx, span := trace.StartSpan(ctx, "client/Version")
@@ -311,6 +749,80 @@ func (c *defaultClient) GetConnectGraph(ctx context.Context) (*api.ConnectGraph,
 	return &graph, err
 }
 
+// Ping measures the RPC round-trip time and clock skew from the current
+// peer to the given one. If pid is empty, every cluster peer is pinged.
+func (c *defaultClient) Ping(ctx context.Context, pid peer.ID) ([]*api.PingResult, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Ping")
+	defer span.End()
+
+	path := "/health/ping"
+	if pid != "" {
+		path = fmt.Sprintf("/health/ping?peer=%s", pid.Pretty())
+	}
+	var results []*api.PingResult
+	err := c.do(ctx, "GET", path, nil, nil, &results)
+	return results, err
+}
+
+// Versions returns the versions run by every cluster peer and whether they
+// are compatible with those of the current peer.
+func (c *defaultClient) Versions(ctx context.Context) ([]*api.PeerVersion, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Versions")
+	defer span.End()
+
+	var versions []*api.PeerVersion
+	err := c.do(ctx, "GET", "/health/versions", nil, nil, &versions)
+	return versions, err
+}
+
+// RuntimeStats returns the Go runtime stats (goroutines, heap, GC pauses and
+// open file descriptors) of every cluster peer. If local is true, only
+// those of the current peer are returned.
+func (c *defaultClient) RuntimeStats(ctx context.Context, local bool) ([]*api.RuntimeStats, error) {
+	ctx, span := trace.StartSpan(ctx, "client/RuntimeStats")
+	defer span.End()
+
+	var results []*api.RuntimeStats
+	err := c.do(ctx, "GET", fmt.Sprintf("/health/runtime?local=%t", local), nil, nil, &results)
+	return results, err
+}
+
+// IPFSDrift returns the versions and settings of the IPFS daemons of every
+// cluster peer, along with warnings about the differences between them.
+func (c *defaultClient) IPFSDrift(ctx context.Context) (*api.IPFSDrift, error) {
+	ctx, span := trace.StartSpan(ctx, "client/IPFSDrift")
+	defer span.End()
+
+	var drift api.IPFSDrift
+	err := c.do(ctx, "GET", "/health/ipfs", nil, nil, &drift)
+	return &drift, err
+}
+
+// StorageUsage returns the number of unique blocks and bytes referenced by
+// the cluster pinset, along with the bytes taken by all the replicas. When
+// sample is greater than 0, only that many random pins are inspected and the
+// totals are extrapolated from them.
+func (c *defaultClient) StorageUsage(ctx context.Context, sample int) (*api.StorageUsage, error) {
+	ctx, span := trace.StartSpan(ctx, "client/StorageUsage")
+	defer span.End()
+
+	var usage api.StorageUsage
+	err := c.do(ctx, "GET", fmt.Sprintf("/health/storage?sample=%d", sample), nil, nil, &usage)
+	return &usage, err
+}
+
+// ReconnectStatus reports whether the cluster peer is in contact with other
+// cluster peers and, when it is not, how its attempts to reconnect to them
+// are going.
+func (c *defaultClient) ReconnectStatus(ctx context.Context) (*api.ReconnectStatus, error) {
+	ctx, span := trace.StartSpan(ctx, "client/ReconnectStatus")
+	defer span.End()
+
+	var status api.ReconnectStatus
+	err := c.do(ctx, "GET", "/health/reconnect", nil, nil, &status)
+	return &status, err
+}
+
 // Metrics returns a map with the latest valid metrics of the given name
 // for the current cluster peers.
 func (c *defaultClient) Metrics(ctx context.Context, name string) ([]*api.Metric, error) {
@@ -122,6 +122,33 @@ func TestPeerAdd(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestPeerstore(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		known, err := c.Peerstore(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(known) != 1 || known[0].ID != test.PeerID1 {
+			t.Error("unexpected peerstore:", known)
+		}
+
+		addr, _ := types.NewMultiaddr("/ip4/1.2.3.4/tcp/9096/ipfs/" + test.PeerID2.Pretty())
+		added, err := c.PeerstoreAdd(ctx, []types.Multiaddr{addr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(added) != 1 || added[0].ID != test.PeerID2 {
+			t.Error("unexpected peerstore add response:", added)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestPeerRm(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -137,6 +164,171 @@ func TestPeerRm(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestPeerMigrate(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		mig, err := c.PeerMigrate(ctx, test.PeerID1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mig.Pins) != 1 || !mig.Pins[0].Cid.Equals(test.Cid1) {
+			t.Error("unexpected migrated pins")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestJoinToken(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		token, err := c.JoinToken(ctx, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Token == "" || token.Expires.IsZero() {
+			t.Error("expected a token with an expiration date")
+		}
+
+		_, err = c.JoinToken(ctx, 0)
+		if err == nil {
+			t.Error("expected an error with a zero ttl")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestConnectionDeny(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		deny, err := c.ConnectionDenyList(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deny.Peers) != 1 || len(deny.CIDRs) != 1 {
+			t.Error("expected a denied peer and cidr")
+		}
+
+		f := &types.ConnectionFilter{
+			Peers: []peer.ID{test.PeerID1},
+			CIDRs: []string{"10.0.0.0/8"},
+		}
+		err = c.ConnectionDeny(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.ConnectionUndeny(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestLogs(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		entries, err := c.Logs(ctx, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Errorf("expected 2 log entries, got %d", len(entries))
+		}
+
+		entries, err = c.Logs(ctx, "cluster", "warn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("expected no entries: %+v", entries)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		rules, err := c.Faults(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rules) != 1 || rules[0].Method != "Pin" {
+			t.Errorf("unexpected rules: %+v", rules)
+		}
+
+		err = c.SetFaults(ctx, []*types.FaultRule{
+			{Target: "rpc", Percent: 10, Delay: time.Second},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.SetFaults(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestSetAllocatable(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		err := c.SetAllocatable(ctx, test.PeerID1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.SetAllocatable(ctx, test.PeerID1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestSetTrackerPaused(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		err := c.SetTrackerPaused(ctx, test.PeerID1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.SetTrackerPaused(ctx, test.PeerID1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestPin(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -172,6 +364,76 @@ func TestUnpin(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestRestorePin(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		pin, err := c.RestorePin(ctx, test.Cid1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pin.Cid.Equals(test.Cid1) {
+			t.Error("expected the same cid")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		snap, err := c.SnapshotCreate(ctx, "snap1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.Name != "snap1" {
+			t.Error("unexpected snapshot name")
+		}
+
+		snaps, err := c.Snapshots(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snaps) != 1 {
+			t.Error("expected one snapshot")
+		}
+
+		diff, err := c.SnapshotDiff(ctx, "snap1", types.SnapshotCurrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diff.Pin) != 1 || !diff.Pin[0].Cid.Equals(test.Cid1) {
+			t.Error("expected Cid1 to be pinned")
+		}
+
+		_, err = c.SnapshotRestore(ctx, "snap1", types.BulkUnpinOptions{})
+		if _, ok := types.AsBulkUnpinError(err); !ok {
+			t.Errorf("expected a bulk unpin error: %v", err)
+		}
+
+		res, err := c.SnapshotRestore(ctx, "snap1", types.BulkUnpinOptions{Force: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Unpinned) != 1 || !res.Unpinned[0].Equals(test.Cid2) {
+			t.Error("expected Cid2 to be unpinned")
+		}
+
+		err = c.SnapshotDelete(ctx, "snap1")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 type pathCase struct {
 	path        string
 	wantErr     bool
@@ -291,6 +553,26 @@ func TestAllocations(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestAllocationsSince(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		since := time.Now().Add(-72 * time.Hour)
+		pins, err := c.AllocationsSince(ctx, types.AllType, since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pins) != 2 ||
+			!pins[0].Cid.Equals(test.Cid1) || !pins[1].Cid.Equals(test.Cid2) {
+			t.Error("unexpected pin list: ", pins)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestAllocation(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -327,6 +609,77 @@ func TestStatus(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestStatusExplain(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		pin, err := c.StatusExplain(ctx, test.Cid1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pin.Cid.Equals(test.Cid1) {
+			t.Error("should be same pin")
+		}
+		if pin.Allocation == nil {
+			t.Fatal("expected allocation information")
+		}
+		if len(pin.Allocation.Allocations) == 0 {
+			t.Error("expected some allocations")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestPinDetails(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		details, err := c.PinDetails(ctx, test.Cid1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !details.Pin.Cid.Equals(test.Cid1) || !details.Status.Cid.Equals(test.Cid1) {
+			t.Error("should be same pin")
+		}
+		if len(details.Status.PeerMap) == 0 {
+			t.Error("expected status information")
+		}
+
+		_, err = c.PinDetails(ctx, test.ErrorCid)
+		if err == nil {
+			t.Error("expected an error")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestPinGateways(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		gateways, err := c.PinGateways(ctx, test.Cid1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(gateways) != 1 {
+			t.Fatal("expected one gateway")
+		}
+		if gateways[0] != "http://127.0.0.1:8080/ipfs/"+test.Cid1.String() {
+			t.Error("unexpected gateway url:", gateways[0])
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestStatusAll(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -432,6 +785,30 @@ func TestRecover(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestProvide(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		results, err := c.Provide(ctx, test.Cid1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 {
+			t.Fatal("expected 2 results")
+		}
+		if results[0].Peer != test.PeerID1 || results[0].Error != "" {
+			t.Error("unexpected result for the first peer")
+		}
+		if results[1].Error == "" {
+			t.Error("expected an error for the second peer")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestRecoverAll(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -447,6 +824,98 @@ func TestRecoverAll(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestRecovery(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		job, err := c.RecoveryStart(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Peer != test.PeerID1 {
+			t.Error("expected a job started by the contacted peer")
+		}
+		job, err = c.RecoveryStatus(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Total != 3 {
+			t.Error("unexpected recovery job total")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestStateSync(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		results, err := c.StateSync(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 {
+			t.Error("expected 1 result")
+		}
+
+		results, err = c.StateSyncPeer(ctx, test.PeerID2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Peer != test.PeerID2 {
+			t.Error("expected result from the given peer")
+		}
+
+		results, err = c.LastStateSync(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Start.IsZero() {
+			t.Error("expected a last state sync result")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		recs, err := c.AuditLog(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) != 1 || recs[0].Peer != test.PeerID1 {
+			t.Error("expected an audit record")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestRecoverPeer(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		_, err := c.RecoverPeer(ctx, test.PeerID1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestGetConnectGraph(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -466,6 +935,176 @@ func TestGetConnectGraph(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestPing(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		results, err := c.Ping(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 {
+			t.Error("expected a result for every peer")
+		}
+
+		results, err = c.Ping(ctx, test.PeerID2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Peer != test.PeerID2 {
+			t.Error("expected result from the given peer")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestVersions(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		versions, err := c.Versions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 2 || versions[1].Error == "" {
+			t.Error("unexpected versions")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestClusterInfo(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		info, err := c.ClusterInfo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Peers != 1 || info.Consensus != "raft" {
+			t.Errorf("unexpected cluster info: %+v", info)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestIPFSDrift(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		drift, err := c.IPFSDrift(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(drift.Daemons) != 2 || !drift.Dangerous {
+			t.Errorf("unexpected ipfs drift: %+v", drift)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestRuntimeStats(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		results, err := c.RuntimeStats(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Goroutines == 0 {
+			t.Error("unexpected runtime stats")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestRepoGC(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		results, err := c.RepoGC(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Removed != 2 {
+			t.Error("unexpected repo gc results")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestStorageUsage(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		usage, err := c.StorageUsage(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage.Pins != 4 || usage.SampledPins != 2 || usage.UniqueBytes == 0 {
+			t.Errorf("unexpected storage usage: %+v", usage)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestReconnectStatus(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		status, err := c.ReconnectStatus(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.State != types.ReconnectStateConnected || status.KnownPeers != 2 {
+			t.Errorf("unexpected reconnect status: %+v", status)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestStatusSummary(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		summary, err := c.StatusSummary(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(summary.Peers) != 2 || summary.Totals.Pinned != 1 {
+			t.Errorf("unexpected summary: %+v", summary)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestMetrics(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"go.opencensus.io/trace"
 
@@ -69,7 +70,9 @@ func (c *defaultClient) doRequest(
 		r.Close = true
 	}
 
-	if c.config.Username != "" {
+	if c.config.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.config.Token)
+	} else if c.config.Username != "" {
 		r.SetBasicAuth(c.config.Username, c.config.Password)
 	}
 
@@ -79,6 +82,18 @@ func (c *defaultClient) doRequest(
 		}
 	}
 
+	// Let the server know how long we will wait so that it does
+	// not keep working for us afterwards.
+	timeout := c.config.Timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if d := time.Until(deadline); timeout == 0 || d < timeout {
+			timeout = d
+		}
+	}
+	if timeout > 0 {
+		r.Header.Set(api.RequestTimeoutHeader, timeout.String())
+	}
+
 	if body != nil {
 		r.ContentLength = -1 // this lets go use "chunked".
 	}
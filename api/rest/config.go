@@ -7,9 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"strings"
 	"time"
 
+	types "github.com/ipfs/ipfs-cluster/api"
 	"github.com/ipfs/ipfs-cluster/config"
 	"github.com/rs/cors"
 
@@ -33,6 +36,15 @@ const (
 	DefaultWriteTimeout      = 0
 	DefaultIdleTimeout       = 120 * time.Second
 	DefaultMaxHeaderBytes    = minMaxHeaderBytes
+
+	DefaultMaxRequestBodySize     = 0
+	DefaultMaxAddBodySize         = 0
+	DefaultRequestBodyIdleTimeout = time.Minute
+
+	DefaultLDAPCacheTTL  = 5 * time.Minute
+	DefaultOIDCUserClaim = "sub"
+
+	DefaultPublicStatusRateLimit = 1.0
 )
 
 // These are the default values for Config.
@@ -68,6 +80,12 @@ type Config struct {
 	// Listen address for the HTTP REST API endpoint.
 	HTTPListenAddr ma.Multiaddr
 
+	// HTTPExtraListenAddrs are additional listen addresses for the HTTP
+	// REST API endpoint, with the same TLS and authentication settings.
+	// They can be unix sockets or local ports to which an onion service
+	// or a pluggable transport bridge forwards connections.
+	HTTPExtraListenAddrs []ma.Multiaddr
+
 	// TLS configuration for the HTTP listener
 	TLS *tls.Config
 
@@ -79,6 +97,24 @@ type Config struct {
 	// SSLKeyFile. We track it so we can write it in the JSON.
 	pathSSLKeyFile string
 
+	// ACMEDomains enables automatic TLS for the HTTP listener using
+	// certificates obtained through ACME (i.e. Let's Encrypt) for
+	// these domains. Certificates are kept in the datastore and
+	// renewed automatically.
+	ACMEDomains []string
+
+	// ACMEEmail is the contact email for the ACME account (optional).
+	ACMEEmail string
+
+	// ACMEDirectoryURL is the ACME directory endpoint. Let's Encrypt
+	// is used when empty.
+	ACMEDirectoryURL string
+
+	// ACMEHTTPListenAddr enables an HTTP listener answering
+	// HTTP-01 challenges (it must be reachable on port 80). Otherwise,
+	// only TLS-ALPN-01 challenges on the HTTP listener are supported.
+	ACMEHTTPListenAddr ma.Multiaddr
+
 	// Maximum duration before timing out reading a full request
 	ReadTimeout time.Duration
 
@@ -96,9 +132,43 @@ type Config struct {
 	// accepted by the server
 	MaxHeaderBytes int
 
-	// Listen address for the Libp2p REST API endpoint.
+	// MaxRequestBodySize is the maximum size in bytes of the body of
+	// requests, except for the add endpoint. Larger requests are
+	// answered with 413. 0 means no limit.
+	MaxRequestBodySize int64
+
+	// MaxAddBodySize is the maximum size in bytes of the content
+	// uploaded to the add endpoint. 0 means no limit.
+	MaxAddBodySize int64
+
+	// RequestBodyIdleTimeout is the maximum time to wait for more body
+	// data from a client before failing its request with 408. It
+	// protects against clients which trickle uploads to keep
+	// connections open. 0 disables it.
+	RequestBodyIdleTimeout time.Duration
+
+	// RouteTimeouts sets the maximum duration of requests to the given
+	// routes, by route name (i.e. "StatusAll"). Work done on behalf of
+	// a request, including calls to other peers and to IPFS, is
+	// cancelled when it expires. Clients can ask for shorter deadlines
+	// with the X-Request-Timeout header.
+	RouteTimeouts map[string]time.Duration
+
+	// Listen address for the Libp2p REST API endpoint. When set, the API
+	// runs its own libp2p host, separate from the cluster peer's, so
+	// that cluster traffic and API traffic can use different interfaces.
 	Libp2pListenAddr ma.Multiaddr
 
+	// Libp2pExtraListenAddrs are additional listen addresses for the
+	// libp2p host of the API. They require Libp2pListenAddr.
+	Libp2pExtraListenAddrs []ma.Multiaddr
+
+	// Libp2pAnnounceAddrs, when set, are the only addresses advertised
+	// by the libp2p host of the API, instead of the ones it listens on
+	// (i.e. a public address forwarded to a private one). They require
+	// Libp2pListenAddr.
+	Libp2pAnnounceAddrs []ma.Multiaddr
+
 	// ID and PrivateKey are used to create a libp2p host if we
 	// want the API component to do it (not by default).
 	ID         peer.ID
@@ -108,10 +178,59 @@ type Config struct {
 	// which are authorized to use Basic Authentication
 	BasicAuthCreds map[string]string
 
+	// BasicAuthRoles maps the names of authenticated users to the role
+	// they are granted (viewer, pinner, operator or admin). Users
+	// authenticated by the LDAP and OIDC backends are named
+	// "ldap:<username>" and "oidc:<claim value>". Users without an
+	// assigned role get the DefaultRole.
+	BasicAuthRoles map[string]string
+
+	// DefaultRole is the role of users without one in BasicAuthRoles.
+	// When empty, they cannot use any endpoint. It cannot be admin.
+	// Roles are always checked when the LDAP or OIDC backends are
+	// used, so one of BasicAuthRoles or DefaultRole is needed then.
+	DefaultRole string
+
+	// HTTPAuthBackends and Libp2pAuthBackends are the authentication
+	// backends (AuthBasic, AuthLDAP, AuthOIDC) enabled on the HTTP and
+	// libp2p listeners, tried in order. When empty, AuthBasic is used
+	// if BasicAuthCreds are set, and requests are not authenticated
+	// otherwise.
+	HTTPAuthBackends   []string
+	Libp2pAuthBackends []string
+
+	// LDAPURL is the LDAP server (ldap:// or ldaps://) used to check
+	// Basic Authentication credentials. Connections to ldap:// URLs
+	// must be upgraded with StartTLS. Users are authenticated by
+	// binding as LDAPBindDN, in which "%s" is replaced by their
+	// username. Successful binds are cached for LDAPCacheTTL.
+	LDAPURL      string
+	LDAPBindDN   string
+	LDAPCacheTTL time.Duration
+
+	// OIDCIssuer is the OpenID Connect provider whose ID tokens are
+	// accepted as bearer tokens, when issued for OIDCAudience (the
+	// client ID). Signing keys are obtained from OIDCJWKSURL, or
+	// discovered from the issuer when empty. The username is taken
+	// from the OIDCUserClaim claim of the token.
+	OIDCIssuer    string
+	OIDCAudience  string
+	OIDCJWKSURL   string
+	OIDCUserClaim string
+
 	// Headers provides customization for the headers returned
 	// by the API on existing routes.
 	Headers map[string][]string
 
+	// AddProfiles are named sets of add options which clients can
+	// select with the "profile" parameter of the add endpoint. The
+	// options in a profile override those sent by the client.
+	AddProfiles map[string]*types.AddProfile
+
+	// DefaultAddProfile is the profile applied to add requests which
+	// do not select one. No profile is applied when empty.
+	DefaultAddProfile string
+
 	// CORS header management
 	CORSAllowedOrigins   []string
 	CORSAllowedMethods   []string
@@ -120,6 +239,16 @@ type Config struct {
 	CORSAllowCredentials bool
 	CORSMaxAge           time.Duration
 
+	// PublicStatus enables the /public/status endpoint, which serves a
+	// summary of the cluster health (peers, pins and errors) without
+	// authentication, i.e. for a public status page.
+	PublicStatus bool
+
+	// PublicStatusRateLimit is the number of requests per second
+	// served by the /public/status endpoint, to all clients together.
+	// Requests over it are answered with 429.
+	PublicStatusRateLimit float64
+
 	// Tracing flag used to skip tracing specific paths when not enabled.
 	Tracing bool
 }
@@ -134,19 +263,52 @@ type jsonConfig struct {
 	IdleTimeout            string `json:"idle_timeout"`
 	MaxHeaderBytes         int    `json:"max_header_bytes"`
 
-	Libp2pListenMultiaddress string `json:"libp2p_listen_multiaddress,omitempty"`
-	ID                       string `json:"id,omitempty"`
-	PrivateKey               string `json:"private_key,omitempty"`
+	MaxRequestBodySize     int64  `json:"max_request_body_size"`
+	MaxAddBodySize         int64  `json:"max_add_body_size"`
+	RequestBodyIdleTimeout string `json:"request_body_idle_timeout"`
+
+	RouteTimeouts map[string]string `json:"route_timeouts,omitempty"`
+
+	HTTPExtraListenMultiaddresses []string `json:"http_extra_listen_multiaddresses,omitempty"`
+
+	ACMEDomains                []string `json:"acme_domains,omitempty"`
+	ACMEEmail                  string   `json:"acme_email,omitempty"`
+	ACMEDirectoryURL           string   `json:"acme_directory_url,omitempty"`
+	ACMEHTTPListenMultiaddress string   `json:"acme_http_listen_multiaddress,omitempty"`
+
+	Libp2pListenMultiaddress        string   `json:"libp2p_listen_multiaddress,omitempty"`
+	Libp2pExtraListenMultiaddresses []string `json:"libp2p_extra_listen_multiaddresses,omitempty"`
+	Libp2pAnnounceMultiaddresses    []string `json:"libp2p_announce_multiaddresses,omitempty"`
+	ID                              string   `json:"id,omitempty"`
+	PrivateKey                      string   `json:"private_key,omitempty"`
 
 	BasicAuthCreds map[string]string   `json:"basic_auth_credentials"`
+	BasicAuthRoles map[string]string   `json:"basic_auth_roles,omitempty"`
+	DefaultRole    string              `json:"default_role,omitempty"`
 	Headers        map[string][]string `json:"headers"`
 
+	HTTPAuthBackends   []string `json:"http_auth_backends,omitempty"`
+	Libp2pAuthBackends []string `json:"libp2p_auth_backends,omitempty"`
+	LDAPURL            string   `json:"ldap_url,omitempty"`
+	LDAPBindDN         string   `json:"ldap_bind_dn,omitempty"`
+	LDAPCacheTTL       string   `json:"ldap_cache_ttl,omitempty"`
+	OIDCIssuer         string   `json:"oidc_issuer,omitempty"`
+	OIDCAudience       string   `json:"oidc_audience,omitempty"`
+	OIDCJWKSURL        string   `json:"oidc_jwks_url,omitempty"`
+	OIDCUserClaim      string   `json:"oidc_user_claim,omitempty"`
+
+	AddProfiles       map[string]*types.AddProfile `json:"add_profiles,omitempty"`
+	DefaultAddProfile string                       `json:"default_add_profile,omitempty"`
+
 	CORSAllowedOrigins   []string `json:"cors_allowed_origins"`
 	CORSAllowedMethods   []string `json:"cors_allowed_methods"`
 	CORSAllowedHeaders   []string `json:"cors_allowed_headers"`
 	CORSExposedHeaders   []string `json:"cors_exposed_headers"`
 	CORSAllowCredentials bool     `json:"cors_allow_credentials"`
 	CORSMaxAge           string   `json:"cors_max_age"`
+
+	PublicStatus          bool    `json:"public_status,omitempty"`
+	PublicStatusRateLimit float64 `json:"public_status_rate_limit,omitempty"`
 }
 
 // ConfigKey returns a human-friendly identifier for this type of
@@ -160,6 +322,8 @@ func (cfg *Config) Default() error {
 	// http
 	httpListen, _ := ma.NewMultiaddr(DefaultHTTPListenAddr)
 	cfg.HTTPListenAddr = httpListen
+	cfg.HTTPExtraListenAddrs = nil
+	cfg.TLS = nil
 	cfg.pathSSLCertFile = ""
 	cfg.pathSSLKeyFile = ""
 	cfg.ReadTimeout = DefaultReadTimeout
@@ -167,18 +331,45 @@ func (cfg *Config) Default() error {
 	cfg.WriteTimeout = DefaultWriteTimeout
 	cfg.IdleTimeout = DefaultIdleTimeout
 	cfg.MaxHeaderBytes = DefaultMaxHeaderBytes
+	cfg.MaxRequestBodySize = DefaultMaxRequestBodySize
+	cfg.MaxAddBodySize = DefaultMaxAddBodySize
+	cfg.RequestBodyIdleTimeout = DefaultRequestBodyIdleTimeout
+	cfg.RouteTimeouts = nil
+
+	// acme
+	cfg.ACMEDomains = nil
+	cfg.ACMEEmail = ""
+	cfg.ACMEDirectoryURL = ""
+	cfg.ACMEHTTPListenAddr = nil
 
 	// libp2p
 	cfg.ID = ""
 	cfg.PrivateKey = nil
 	cfg.Libp2pListenAddr = nil
+	cfg.Libp2pExtraListenAddrs = nil
+	cfg.Libp2pAnnounceAddrs = nil
 
 	// Auth
 	cfg.BasicAuthCreds = nil
+	cfg.BasicAuthRoles = nil
+	cfg.DefaultRole = ""
+	cfg.HTTPAuthBackends = nil
+	cfg.Libp2pAuthBackends = nil
+	cfg.LDAPURL = ""
+	cfg.LDAPBindDN = ""
+	cfg.LDAPCacheTTL = DefaultLDAPCacheTTL
+	cfg.OIDCIssuer = ""
+	cfg.OIDCAudience = ""
+	cfg.OIDCJWKSURL = ""
+	cfg.OIDCUserClaim = DefaultOIDCUserClaim
 
 	// Headers
 	cfg.Headers = DefaultHeaders
 
+	// Add profiles
+	cfg.AddProfiles = nil
+	cfg.DefaultAddProfile = ""
+
 	cfg.CORSAllowedOrigins = DefaultCORSAllowedOrigins
 	cfg.CORSAllowedMethods = DefaultCORSAllowedMethods
 	cfg.CORSAllowedHeaders = DefaultCORSAllowedHeaders
@@ -186,6 +377,10 @@ func (cfg *Config) Default() error {
 	cfg.CORSAllowCredentials = DefaultCORSAllowCredentials
 	cfg.CORSMaxAge = DefaultCORSMaxAge
 
+	// Public status
+	cfg.PublicStatus = false
+	cfg.PublicStatusRateLimit = DefaultPublicStatusRateLimit
+
 	return nil
 }
 
@@ -219,17 +414,159 @@ func (cfg *Config) Validate() error {
 		return errors.New("restapi.idle_timeout invalid")
 	case cfg.MaxHeaderBytes < minMaxHeaderBytes:
 		return fmt.Errorf("restapi.max_header_bytes must be not less then %d", minMaxHeaderBytes)
+	case cfg.MaxRequestBodySize < 0:
+		return errors.New("restapi.max_request_body_size is invalid")
+	case cfg.MaxAddBodySize < 0:
+		return errors.New("restapi.max_add_body_size is invalid")
+	case cfg.RequestBodyIdleTimeout < 0:
+		return errors.New("restapi.request_body_idle_timeout is invalid")
 	case cfg.BasicAuthCreds != nil && len(cfg.BasicAuthCreds) == 0:
 		return errors.New("restapi.basic_auth_creds should be null or have at least one entry")
+	case cfg.BasicAuthRoles != nil && cfg.BasicAuthCreds == nil && !cfg.usesExternalAuth():
+		return errors.New("restapi.basic_auth_roles needs basic_auth_credentials")
+	case cfg.DefaultRole == RoleAdmin:
+		return errors.New("restapi.default_role cannot be admin")
+	case cfg.usesExternalAuth() && len(cfg.BasicAuthRoles) == 0 && cfg.DefaultRole == "":
+		return errors.New("restapi: the ldap and oidc authentication backends need basic_auth_roles or default_role")
 	case (cfg.pathSSLCertFile != "" || cfg.pathSSLKeyFile != "") && cfg.TLS == nil:
 		return errors.New("restapi: missing TLS configuration")
 	case (cfg.CORSMaxAge < 0):
 		return errors.New("restapi.cors_max_age is invalid")
+	case len(cfg.ACMEDomains) > 0 && cfg.TLS != nil:
+		return errors.New("restapi: acme_domains cannot be used along ssl_cert_file and ssl_key_file")
+	case len(cfg.ACMEDomains) > 0 && cfg.HTTPListenAddr == nil:
+		return errors.New("restapi: acme_domains needs http_listen_multiaddress")
+	case len(cfg.ACMEDomains) == 0 && cfg.ACMEHTTPListenAddr != nil:
+		return errors.New("restapi.acme_http_listen_multiaddress needs acme_domains")
+	case cfg.PublicStatusRateLimit < 0:
+		return errors.New("restapi.public_status_rate_limit is invalid")
+	case cfg.PublicStatus && cfg.PublicStatusRateLimit == 0:
+		return errors.New("restapi.public_status needs a public_status_rate_limit")
+	}
+
+	for _, addr := range cfg.HTTPExtraListenAddrs {
+		if isOnionAddr(addr) {
+			return fmt.Errorf("restapi.http_extra_listen_multiaddresses: cannot listen on onion address %s", addr)
+		}
+	}
+
+	for name, t := range cfg.RouteTimeouts {
+		if t < 0 {
+			return fmt.Errorf("restapi.route_timeouts: timeout for %s is invalid", name)
+		}
+	}
+
+	for name, prof := range cfg.AddProfiles {
+		if prof == nil {
+			return fmt.Errorf("restapi.add_profiles: profile %s is empty", name)
+		}
+		if err := prof.Validate(); err != nil {
+			return fmt.Errorf("restapi.add_profiles: profile %s: %s", name, err)
+		}
+	}
+	if p := cfg.DefaultAddProfile; p != "" && cfg.AddProfiles[p] == nil {
+		return fmt.Errorf("restapi.default_add_profile: unknown profile %s", p)
+	}
+
+	if _, ok := roleLevels[cfg.DefaultRole]; cfg.DefaultRole != "" && !ok {
+		return fmt.Errorf("restapi.default_role: unknown role %s", cfg.DefaultRole)
+	}
+
+	for user, role := range cfg.BasicAuthRoles {
+		// Users of other backends are not known in advance, but
+		// the backend must be enabled.
+		switch {
+		case strings.HasPrefix(user, AuthLDAP+":"):
+			if !cfg.usesAuthBackend(AuthLDAP) {
+				return fmt.Errorf("restapi.basic_auth_roles: user %s needs the ldap authentication backend", user)
+			}
+		case strings.HasPrefix(user, AuthOIDC+":"):
+			if !cfg.usesAuthBackend(AuthOIDC) {
+				return fmt.Errorf("restapi.basic_auth_roles: user %s needs the oidc authentication backend", user)
+			}
+		default:
+			if _, ok := cfg.BasicAuthCreds[user]; !ok {
+				return fmt.Errorf("restapi.basic_auth_roles: unknown user %s", user)
+			}
+		}
+		if _, ok := roleLevels[role]; !ok {
+			return fmt.Errorf("restapi.basic_auth_roles: unknown role %s for user %s", role, user)
+		}
+	}
+
+	err := cfg.validateAuth()
+	if err != nil {
+		return err
 	}
 
 	return cfg.validateLibp2p()
 }
 
+// authBackends returns the authentication backends used by a listener
+// configured with the given ones.
+func (cfg *Config) authBackends(backends []string) []string {
+	if len(backends) == 0 && cfg.BasicAuthCreds != nil {
+		return []string{AuthBasic}
+	}
+	return backends
+}
+
+// usesAuthBackend returns true when the given authentication backend is
+// enabled on any listener.
+func (cfg *Config) usesAuthBackend(backend string) bool {
+	for _, b := range append(cfg.HTTPAuthBackends, cfg.Libp2pAuthBackends...) {
+		if b == backend {
+			return true
+		}
+	}
+	return false
+}
+
+// usesExternalAuth returns true when LDAP or OIDC authentication is
+// enabled on any listener.
+func (cfg *Config) usesExternalAuth() bool {
+	return cfg.usesAuthBackend(AuthLDAP) || cfg.usesAuthBackend(AuthOIDC)
+}
+
+// usesRoles returns true when the roles of users must be checked: when
+// they are configured, and always with the LDAP and OIDC backends, which
+// authenticate users that are not configured here.
+func (cfg *Config) usesRoles() bool {
+	return len(cfg.BasicAuthRoles) > 0 || cfg.DefaultRole != "" || cfg.usesExternalAuth()
+}
+
+func (cfg *Config) validateAuth() error {
+	for _, b := range append(cfg.HTTPAuthBackends, cfg.Libp2pAuthBackends...) {
+		switch b {
+		case AuthBasic:
+			if cfg.BasicAuthCreds == nil {
+				return errors.New("restapi: the basic authentication backend needs basic_auth_credentials")
+			}
+		case AuthLDAP:
+			u, err := url.Parse(cfg.LDAPURL)
+			if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+				return errors.New("restapi: the ldap authentication backend needs a valid ldap_url")
+			}
+			if strings.Count(cfg.LDAPBindDN, "%s") != 1 {
+				return errors.New("restapi.ldap_bind_dn should contain %s once, to be replaced by the username")
+			}
+			if cfg.LDAPCacheTTL < 0 {
+				return errors.New("restapi.ldap_cache_ttl is invalid")
+			}
+		case AuthOIDC:
+			if cfg.OIDCIssuer == "" || cfg.OIDCAudience == "" {
+				return errors.New("restapi: the oidc authentication backend needs oidc_issuer and oidc_audience")
+			}
+			if cfg.OIDCUserClaim == "" {
+				return errors.New("restapi.oidc_user_claim cannot be empty")
+			}
+		default:
+			return fmt.Errorf("restapi: unknown authentication backend %q", b)
+		}
+	}
+	return nil
+}
+
 func (cfg *Config) validateLibp2p() error {
 	if cfg.ID != "" || cfg.PrivateKey != nil || cfg.Libp2pListenAddr != nil {
 		// if one is set, all should be
@@ -241,6 +578,17 @@ func (cfg *Config) validateLibp2p() error {
 		}
 	}
 
+	if cfg.Libp2pListenAddr == nil &&
+		(len(cfg.Libp2pExtraListenAddrs) > 0 || len(cfg.Libp2pAnnounceAddrs) > 0) {
+		return errors.New("restapi: libp2p_extra_listen_multiaddresses and libp2p_announce_multiaddresses need libp2p_listen_multiaddress")
+	}
+
+	for _, addr := range append([]ma.Multiaddr{cfg.Libp2pListenAddr}, cfg.Libp2pExtraListenAddrs...) {
+		if addr != nil && isOnionAddr(addr) {
+			return fmt.Errorf("restapi: cannot listen on onion address %s", addr)
+		}
+	}
+
 	return nil
 }
 
@@ -269,9 +617,22 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 		return err
 	}
 
+	err = cfg.loadAuthOptions(jcfg)
+	if err != nil {
+		return err
+	}
+
 	// Other options
 	cfg.BasicAuthCreds = jcfg.BasicAuthCreds
+	cfg.BasicAuthRoles = jcfg.BasicAuthRoles
+	cfg.DefaultRole = jcfg.DefaultRole
 	cfg.Headers = jcfg.Headers
+	cfg.AddProfiles = jcfg.AddProfiles
+	cfg.DefaultAddProfile = jcfg.DefaultAddProfile
+	cfg.PublicStatus = jcfg.PublicStatus
+	if jcfg.PublicStatusRateLimit != 0 {
+		cfg.PublicStatusRateLimit = jcfg.PublicStatusRateLimit
+	}
 
 	return cfg.Validate()
 }
@@ -286,16 +647,44 @@ func (cfg *Config) loadHTTPOptions(jcfg *jsonConfig) error {
 		cfg.HTTPListenAddr = httpAddr
 	}
 
+	cfg.HTTPExtraListenAddrs = nil
+	for _, extraListen := range jcfg.HTTPExtraListenMultiaddresses {
+		extraAddr, err := ma.NewMultiaddr(extraListen)
+		if err != nil {
+			err = fmt.Errorf("error parsing restapi.http_extra_listen_multiaddresses: %s", err)
+			return err
+		}
+		cfg.HTTPExtraListenAddrs = append(cfg.HTTPExtraListenAddrs, extraAddr)
+	}
+
 	err := cfg.tlsOptions(jcfg)
 	if err != nil {
 		return err
 	}
 
+	err = cfg.acmeOptions(jcfg)
+	if err != nil {
+		return err
+	}
+
 	if jcfg.MaxHeaderBytes == 0 {
 		cfg.MaxHeaderBytes = DefaultMaxHeaderBytes
 	} else {
 		cfg.MaxHeaderBytes = jcfg.MaxHeaderBytes
 	}
+	cfg.MaxRequestBodySize = jcfg.MaxRequestBodySize
+	cfg.MaxAddBodySize = jcfg.MaxAddBodySize
+
+	if jcfg.RouteTimeouts != nil {
+		cfg.RouteTimeouts = make(map[string]time.Duration, len(jcfg.RouteTimeouts))
+		for name, t := range jcfg.RouteTimeouts {
+			d, err := time.ParseDuration(t)
+			if err != nil {
+				return fmt.Errorf("error parsing restapi.route_timeouts for %s: %s", name, err)
+			}
+			cfg.RouteTimeouts[name] = d
+		}
+	}
 
 	// CORS
 	cfg.CORSAllowedOrigins = jcfg.CORSAllowedOrigins
@@ -314,6 +703,7 @@ func (cfg *Config) loadHTTPOptions(jcfg *jsonConfig) error {
 		&config.DurationOpt{Duration: jcfg.WriteTimeout, Dst: &cfg.WriteTimeout, Name: "write_timeout"},
 		&config.DurationOpt{Duration: jcfg.IdleTimeout, Dst: &cfg.IdleTimeout, Name: "idle_timeout"},
 		&config.DurationOpt{Duration: jcfg.CORSMaxAge, Dst: &cfg.CORSMaxAge, Name: "cors_max_age"},
+		&config.DurationOpt{Duration: jcfg.RequestBodyIdleTimeout, Dst: &cfg.RequestBodyIdleTimeout, Name: "request_body_idle_timeout"},
 	)
 }
 
@@ -347,6 +737,37 @@ func (cfg *Config) tlsOptions(jcfg *jsonConfig) error {
 	return nil
 }
 
+func (cfg *Config) acmeOptions(jcfg *jsonConfig) error {
+	cfg.ACMEDomains = jcfg.ACMEDomains
+	cfg.ACMEEmail = jcfg.ACMEEmail
+	cfg.ACMEDirectoryURL = jcfg.ACMEDirectoryURL
+
+	if acmeListen := jcfg.ACMEHTTPListenMultiaddress; acmeListen != "" {
+		acmeAddr, err := ma.NewMultiaddr(acmeListen)
+		if err != nil {
+			return fmt.Errorf("error parsing restapi.acme_http_listen_multiaddress: %s", err)
+		}
+		cfg.ACMEHTTPListenAddr = acmeAddr
+	}
+	return nil
+}
+
+func (cfg *Config) loadAuthOptions(jcfg *jsonConfig) error {
+	cfg.HTTPAuthBackends = jcfg.HTTPAuthBackends
+	cfg.Libp2pAuthBackends = jcfg.Libp2pAuthBackends
+	cfg.LDAPURL = jcfg.LDAPURL
+	cfg.LDAPBindDN = jcfg.LDAPBindDN
+	cfg.OIDCIssuer = jcfg.OIDCIssuer
+	cfg.OIDCAudience = jcfg.OIDCAudience
+	cfg.OIDCJWKSURL = jcfg.OIDCJWKSURL
+	config.SetIfNotDefault(jcfg.OIDCUserClaim, &cfg.OIDCUserClaim)
+
+	return config.ParseDurations(
+		"restapi",
+		&config.DurationOpt{Duration: jcfg.LDAPCacheTTL, Dst: &cfg.LDAPCacheTTL, Name: "ldap_cache_ttl"},
+	)
+}
+
 func (cfg *Config) loadLibp2pOptions(jcfg *jsonConfig) error {
 	if libp2pListen := jcfg.Libp2pListenMultiaddress; libp2pListen != "" {
 		libp2pAddr, err := ma.NewMultiaddr(libp2pListen)
@@ -357,6 +778,24 @@ func (cfg *Config) loadLibp2pOptions(jcfg *jsonConfig) error {
 		cfg.Libp2pListenAddr = libp2pAddr
 	}
 
+	cfg.Libp2pExtraListenAddrs = nil
+	for _, s := range jcfg.Libp2pExtraListenMultiaddresses {
+		addr, err := ma.NewMultiaddr(s)
+		if err != nil {
+			return fmt.Errorf("error parsing restapi.libp2p_extra_listen_multiaddresses: %s", err)
+		}
+		cfg.Libp2pExtraListenAddrs = append(cfg.Libp2pExtraListenAddrs, addr)
+	}
+
+	cfg.Libp2pAnnounceAddrs = nil
+	for _, s := range jcfg.Libp2pAnnounceMultiaddresses {
+		addr, err := ma.NewMultiaddr(s)
+		if err != nil {
+			return fmt.Errorf("error parsing restapi.libp2p_announce_multiaddresses: %s", err)
+		}
+		cfg.Libp2pAnnounceAddrs = append(cfg.Libp2pAnnounceAddrs, addr)
+	}
+
 	if jcfg.PrivateKey != "" {
 		pkb, err := base64.StdEncoding.DecodeString(jcfg.PrivateKey)
 		if err != nil {
@@ -408,14 +847,34 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 		WriteTimeout:           cfg.WriteTimeout.String(),
 		IdleTimeout:            cfg.IdleTimeout.String(),
 		MaxHeaderBytes:         cfg.MaxHeaderBytes,
+		MaxRequestBodySize:     cfg.MaxRequestBodySize,
+		MaxAddBodySize:         cfg.MaxAddBodySize,
+		RequestBodyIdleTimeout: cfg.RequestBodyIdleTimeout.String(),
+		ACMEDomains:            cfg.ACMEDomains,
+		ACMEEmail:              cfg.ACMEEmail,
+		ACMEDirectoryURL:       cfg.ACMEDirectoryURL,
 		BasicAuthCreds:         cfg.BasicAuthCreds,
+		BasicAuthRoles:         cfg.BasicAuthRoles,
+		DefaultRole:            cfg.DefaultRole,
+		HTTPAuthBackends:       cfg.HTTPAuthBackends,
+		Libp2pAuthBackends:     cfg.Libp2pAuthBackends,
 		Headers:                cfg.Headers,
+		AddProfiles:            cfg.AddProfiles,
+		DefaultAddProfile:      cfg.DefaultAddProfile,
 		CORSAllowedOrigins:     cfg.CORSAllowedOrigins,
 		CORSAllowedMethods:     cfg.CORSAllowedMethods,
 		CORSAllowedHeaders:     cfg.CORSAllowedHeaders,
 		CORSExposedHeaders:     cfg.CORSExposedHeaders,
 		CORSAllowCredentials:   cfg.CORSAllowCredentials,
 		CORSMaxAge:             cfg.CORSMaxAge.String(),
+		PublicStatus:           cfg.PublicStatus,
+	}
+
+	if cfg.RouteTimeouts != nil {
+		jcfg.RouteTimeouts = make(map[string]string, len(cfg.RouteTimeouts))
+		for name, t := range cfg.RouteTimeouts {
+			jcfg.RouteTimeouts[name] = t.String()
+		}
 	}
 
 	if cfg.ID != "" {
@@ -431,10 +890,55 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 	if cfg.Libp2pListenAddr != nil {
 		jcfg.Libp2pListenMultiaddress = cfg.Libp2pListenAddr.String()
 	}
+	for _, addr := range cfg.Libp2pExtraListenAddrs {
+		jcfg.Libp2pExtraListenMultiaddresses = append(jcfg.Libp2pExtraListenMultiaddresses, addr.String())
+	}
+	for _, addr := range cfg.Libp2pAnnounceAddrs {
+		jcfg.Libp2pAnnounceMultiaddresses = append(jcfg.Libp2pAnnounceMultiaddresses, addr.String())
+	}
+	if cfg.ACMEHTTPListenAddr != nil {
+		jcfg.ACMEHTTPListenMultiaddress = cfg.ACMEHTTPListenAddr.String()
+	}
+	if cfg.LDAPURL != "" {
+		jcfg.LDAPURL = cfg.LDAPURL
+		jcfg.LDAPBindDN = cfg.LDAPBindDN
+		jcfg.LDAPCacheTTL = cfg.LDAPCacheTTL.String()
+	}
+	if cfg.OIDCIssuer != "" {
+		jcfg.OIDCIssuer = cfg.OIDCIssuer
+		jcfg.OIDCAudience = cfg.OIDCAudience
+		jcfg.OIDCJWKSURL = cfg.OIDCJWKSURL
+		jcfg.OIDCUserClaim = cfg.OIDCUserClaim
+	}
+	for _, addr := range cfg.HTTPExtraListenAddrs {
+		jcfg.HTTPExtraListenMultiaddresses = append(jcfg.HTTPExtraListenMultiaddresses, addr.String())
+	}
+	if cfg.PublicStatus {
+		jcfg.PublicStatusRateLimit = cfg.PublicStatusRateLimit
+	}
 
 	return
 }
 
+// isOnionAddr returns true for Tor onion service addresses, which can be
+// announced but not listened on directly.
+func isOnionAddr(addr ma.Multiaddr) bool {
+	for _, p := range addr.Protocols() {
+		if p.Code == ma.P_ONION || p.Code == ma.P_ONION3 {
+			return true
+		}
+	}
+	return false
+}
+
+// maxBodySize returns the body size limit for the given request.
+func (cfg *Config) maxBodySize(r *http.Request) int64 {
+	if r.URL.Path == "/add" {
+		return cfg.MaxAddBodySize
+	}
+	return cfg.MaxRequestBodySize
+}
+
 func (cfg *Config) corsOptions() *cors.Options {
 	maxAgeSeconds := int(cfg.CORSMaxAge / time.Second)
 
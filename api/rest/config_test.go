@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ipfs/ipfs-cluster/api"
+
 	crypto "github.com/libp2p/go-libp2p-crypto"
 	peer "github.com/libp2p/go-libp2p-peer"
 	ma "github.com/multiformats/go-multiaddr"
@@ -82,6 +84,208 @@ func TestLoadJSON(t *testing.T) {
 		t.Error("expected error with empty basic auth map")
 	}
 
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.BasicAuthRoles = map[string]string{"user": RoleViewer}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with basic auth roles and no credentials")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.AddProfiles = map[string]*api.AddProfile{"bad": {Layout: "abc"}}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with an invalid add profile")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.DefaultAddProfile = "missing"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with an unknown default add profile")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.AddProfiles = map[string]*api.AddProfile{"archive": {Chunker: "size-1048576"}}
+	j.DefaultAddProfile = "archive"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Error("did not expect an error with a valid default add profile:", err)
+	}
+	if cfg.AddProfiles["archive"].Chunker != "size-1048576" {
+		t.Error("add profiles were not loaded")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.MaxRequestBodySize = -1
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in max_request_body_size")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.MaxRequestBodySize = 1024
+	j.MaxAddBodySize = 1024 * 1024
+	j.RequestBodyIdleTimeout = "10s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxRequestBodySize != 1024 || cfg.MaxAddBodySize != 1024*1024 ||
+		cfg.RequestBodyIdleTimeout != 10*time.Second {
+		t.Error("request body limits were not loaded")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.RouteTimeouts = map[string]string{"StatusAll": "abc"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error parsing route_timeouts")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.RouteTimeouts = map[string]string{"StatusAll": "-1s"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with a negative route timeout")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.RouteTimeouts = map[string]string{"SyncAll": "2m"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RouteTimeouts["SyncAll"] != 2*time.Minute {
+		t.Error("route_timeouts were not loaded")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.BasicAuthCreds = map[string]string{"user": "pass"}
+	j.BasicAuthRoles = map[string]string{"user": "superuser"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with unknown basic auth role")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.BasicAuthCreds = map[string]string{"user": "pass"}
+	j.BasicAuthRoles = map[string]string{"other": RoleViewer}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with basic auth role for unknown user")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.BasicAuthCreds = map[string]string{"user": "pass"}
+	j.BasicAuthRoles = map[string]string{"user": RolePinner}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BasicAuthRoles["user"] != RolePinner {
+		t.Error("expected basic auth role to be loaded")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.ACMEHTTPListenMultiaddress = "/ip4/0.0.0.0/tcp/80"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with acme_http_listen_multiaddress and no acme_domains")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.SSLCertFile = ""
+	j.SSLKeyFile = ""
+	j.ACMEDomains = []string{"example.com"}
+	j.ACMEHTTPListenMultiaddress = "abc"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error decoding acme_http_listen_multiaddress")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.SSLCertFile = ""
+	j.SSLKeyFile = ""
+	j.ACMEDomains = []string{"example.com"}
+	j.ACMEHTTPListenMultiaddress = "/ip4/0.0.0.0/tcp/80"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.ACMEDomains) != 1 || cfg.ACMEHTTPListenAddr == nil {
+		t.Error("expected acme options to be loaded")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.ACMEDomains = []string{"example.com"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with acme_domains and ssl_cert_file")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.HTTPExtraListenMultiaddresses = []string{"/unix/tmp/restapi.sock", "/ip4/127.0.0.1/tcp/9095"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.HTTPExtraListenAddrs) != 2 {
+		t.Error("expected http_extra_listen_multiaddresses to be loaded")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.HTTPExtraListenMultiaddresses = []string{"abc"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error decoding http_extra_listen_multiaddresses")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.HTTPExtraListenMultiaddresses = []string{"/onion3/vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd:9094"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error listening on an onion address")
+	}
+
 	j = &jsonConfig{}
 	json.Unmarshal(cfgJSON, j)
 	j.SSLCertFile = "abc"
@@ -118,6 +322,97 @@ func TestLoadJSON(t *testing.T) {
 		t.Error("expected error with private key")
 	}
 
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.HTTPAuthBackends = []string{"oidc", "ldap"}
+	j.OIDCIssuer = "https://accounts.example.org"
+	j.OIDCAudience = "cluster"
+	j.LDAPURL = "ldaps://ldap.example.org"
+	j.LDAPBindDN = "uid=%s,dc=example,dc=org"
+	j.LDAPCacheTTL = "1m"
+	j.BasicAuthRoles = map[string]string{"oidc:user@example.org": "viewer", "ldap:user": "pinner"}
+	j.DefaultRole = RoleViewer
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LDAPCacheTTL != time.Minute || cfg.OIDCUserClaim != DefaultOIDCUserClaim || cfg.DefaultRole != RoleViewer {
+		t.Error("expected authentication options to be loaded")
+	}
+
+	j.BasicAuthRoles = map[string]string{"user@example.org": "viewer"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with a role for an unknown user")
+	}
+
+	j.BasicAuthRoles = nil
+	j.DefaultRole = ""
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with external authentication and no roles")
+	}
+
+	j.DefaultRole = RoleAdmin
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with admin as default role")
+	}
+
+	j.DefaultRole = RoleViewer
+	j.HTTPAuthBackends = []string{"oidc"}
+	j.BasicAuthRoles = map[string]string{"ldap:user": "pinner"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with a role for a user of a disabled backend")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.HTTPAuthBackends = []string{"ldap"}
+	j.LDAPURL = "ldaps://ldap.example.org"
+	j.LDAPBindDN = "dc=example,dc=org"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with an ldap_bind_dn without %s")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.Libp2pAuthBackends = []string{"kerberos"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with an unknown authentication backend")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.PublicStatus = true
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.PublicStatus || cfg.PublicStatusRateLimit != DefaultPublicStatusRateLimit {
+		t.Error("expected the public status to be enabled with the default rate limit")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.PublicStatusRateLimit = -1
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with a negative public_status_rate_limit")
+	}
+
 	j = &jsonConfig{}
 	json.Unmarshal(cfgJSON, j)
 	j.MaxHeaderBytes = minMaxHeaderBytes - 1
@@ -201,6 +496,50 @@ func TestLibp2pConfig(t *testing.T) {
 	}
 	defer rest.Shutdown(ctx)
 
+	extra, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/0")
+	announce, _ := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/9097")
+	cfg.Libp2pExtraListenAddrs = []ma.Multiaddr{extra}
+	cfg.Libp2pAnnounceAddrs = []ma.Multiaddr{announce}
+
+	cfgJSON, err = cfg.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.LoadJSON(cfgJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Libp2pExtraListenAddrs) != 1 || len(cfg.Libp2pAnnounceAddrs) != 1 {
+		t.Fatal("expected extra listen and announce addresses to be loaded")
+	}
+
+	rest2, err := NewAPI(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rest2.Shutdown(ctx)
+
+	if n := len(rest2.Host().Network().ListenAddresses()); n != 2 {
+		t.Errorf("expected the API host to listen on 2 addresses, got %d", n)
+	}
+	addrs := rest2.Host().Addrs()
+	if len(addrs) != 1 || !addrs[0].Equal(announce) {
+		t.Errorf("expected the API host to only announce %s, got %s", announce, addrs)
+	}
+
+	cfg.Libp2pListenAddr = nil
+	cfg.ID = ""
+	cfg.PrivateKey = nil
+	err = cfg.Validate()
+	if err == nil {
+		t.Error("expected error with announce addresses and no libp2p listen address")
+	}
+	cfg.Libp2pListenAddr = addr
+	cfg.ID = pid
+	cfg.PrivateKey = priv
+	cfg.Libp2pExtraListenAddrs = nil
+	cfg.Libp2pAnnounceAddrs = nil
+
 	badPid, _ := peer.IDB58Decode("QmTQ6oKHDwFjzr4ihirVCLJe8CxanxD3ZjGRYzubFuNDjE")
 	cfg.ID = badPid
 	err = cfg.Validate()
@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"sync/atomic"
+
+	types "github.com/ipfs/ipfs-cluster/api"
+
+	p2phttp "github.com/hsanjuan/go-libp2p-http"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// Adds received by a peer which is low on disk space or busy can be
+// delegated to a better-suited peer (see Cluster.AddDelegate). The request
+// is then proxied, as it arrives, to the REST API of that peer over the
+// cluster's libp2p host, and its output streamed back to the client. This
+// requires that the delegate serves its REST API on the cluster host and
+// accepts the credentials of the original request.
+
+// DelegatedAddHeader is set on add requests delegated to another peer,
+// with the ID of the delegating peer. Delegated adds are never delegated
+// again. The header is only honored on requests received over libp2p from
+// that same peer.
+const DelegatedAddHeader = "X-Delegated-Add-From"
+
+// AddDelegateHeader is set on the responses to delegated adds, with the ID
+// of the peer which performed the add.
+const AddDelegateHeader = "X-Add-Delegate"
+
+// addDelegate asks the cluster which peer should perform the given add. An
+// empty ID means that it should be performed by this peer.
+func (api *API) addDelegate(r *http.Request, params *types.AddParams) peer.ID {
+	delegated := delegatedAddFrom(r) != ""
+
+	// Adds can only be proxied through the cluster host. Adds with
+	// nocopy refer to files on the IPFS daemon of this peer.
+	if api.host == nil || api.config.Libp2pListenAddr != nil ||
+		delegated || params.NoCopy {
+		return ""
+	}
+
+	var pid peer.ID
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"AddDelegate",
+		int(atomic.LoadInt64(&api.addsInProgress)),
+		&pid,
+	)
+	if err != nil {
+		logger.Warningf("error choosing a peer to delegate the add to: %s", err)
+		return ""
+	}
+	return pid
+}
+
+// delegatedAddFrom returns the peer which delegated the given add request,
+// if any. The DelegatedAddHeader is removed from the request unless it was
+// received over libp2p from the peer in the header, so that clients cannot
+// use it to skip delegation.
+func delegatedAddFrom(r *http.Request) peer.ID {
+	from := r.Header.Get(DelegatedAddHeader)
+	if from == "" {
+		return ""
+	}
+
+	// The remote address of requests served on the cluster host is
+	// the ID of the remote peer.
+	remote, err := peer.IDB58Decode(r.RemoteAddr)
+	if err != nil || peer.IDB58Encode(remote) != from {
+		r.Header.Del(DelegatedAddHeader)
+		return ""
+	}
+	return remote
+}
+
+// delegateAdd proxies an add request to the REST API of the given peer.
+func (api *API) delegateAdd(w http.ResponseWriter, r *http.Request, pid peer.ID) {
+	logger.Infof("delegating add to %s", pid.Pretty())
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "libp2p"
+			req.URL.Host = peer.IDB58Encode(pid)
+			req.Host = req.URL.Host
+			// CORS is handled by this API.
+			req.Header.Del("Origin")
+			req.Header.Set(DelegatedAddHeader, peer.IDB58Encode(api.host.ID()))
+		},
+		Transport: p2phttp.NewTransport(api.host),
+		// Stream the add output as it comes.
+		FlushInterval: -1,
+		ModifyResponse: func(res *http.Response) error {
+			res.Header.Set(AddDelegateHeader, peer.IDB58Encode(pid))
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			api.sendResponse(
+				w,
+				http.StatusBadGateway,
+				fmt.Errorf("error delegating add to %s: %s", pid.Pretty(), err),
+				nil,
+			)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+// The LDAP backend authenticates Basic Authentication credentials with a
+// simple bind to the configured server. Connections are always encrypted:
+// ldaps:// URLs use TLS from the start and ldap:// ones must upgrade with
+// StartTLS, so that passwords are never sent in the clear.
+
+var ldapTimeout = 10 * time.Second
+
+// ldapAuthenticator checks credentials by binding to an LDAP server as
+// the DN of the user. Successful binds are cached.
+type ldapAuthenticator struct {
+	url       *url.URL
+	bindDN    string
+	cacheTTL  time.Duration
+	tlsConfig *tls.Config
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]time.Time
+}
+
+func newLDAPAuthenticator(cfg *Config) (*ldapAuthenticator, error) {
+	u, err := url.Parse(cfg.LDAPURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Port() == "" {
+		port := "389"
+		if u.Scheme == "ldaps" {
+			port = "636"
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return &ldapAuthenticator{
+		url:      u,
+		bindDN:   cfg.LDAPBindDN,
+		cacheTTL: cfg.LDAPCacheTTL,
+		tlsConfig: &tls.Config{
+			ServerName: u.Hostname(),
+			MinVersion: tls.VersionTLS12,
+		},
+		cache: make(map[[sha256.Size]byte]time.Time),
+	}, nil
+}
+
+func (la *ldapAuthenticator) Authenticate(r *http.Request) (string, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", errNoCredentials
+	}
+	// Servers treat binds without a password as anonymous binds,
+	// which succeed.
+	if username == "" || password == "" {
+		return "", errors.New("ldap: empty username or password")
+	}
+	user := AuthLDAP + ":" + username
+
+	key := sha256.Sum256([]byte(username + "\x00" + password))
+	now := time.Now()
+	la.mu.Lock()
+	expiry, cached := la.cache[key]
+	la.mu.Unlock()
+	if cached && now.Before(expiry) {
+		return user, nil
+	}
+
+	dn := strings.Replace(la.bindDN, "%s", escapeDN(username), 1)
+	err := la.bind(dn, password)
+	if err != nil {
+		return "", err
+	}
+
+	if la.cacheTTL > 0 {
+		la.mu.Lock()
+		for k, exp := range la.cache {
+			if now.After(exp) {
+				delete(la.cache, k)
+			}
+		}
+		la.cache[key] = now.Add(la.cacheTTL)
+		la.mu.Unlock()
+	}
+	return user, nil
+}
+
+// dial opens an encrypted connection to the server.
+func (la *ldapAuthenticator) dial() (*ldap.Conn, error) {
+	if la.url.Scheme == "ldaps" {
+		return ldap.DialTLS("tcp", la.url.Host, la.tlsConfig)
+	}
+
+	conn, err := ldap.Dial("tcp", la.url.Host)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapTimeout)
+	err = conn.StartTLS(la.tlsConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("starttls: %s", err)
+	}
+	return conn, nil
+}
+
+// bind performs a simple bind with the given credentials.
+func (la *ldapAuthenticator) bind(dn, password string) error {
+	conn, err := la.dial()
+	if err != nil {
+		return fmt.Errorf("ldap: %s", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(ldapTimeout)
+
+	err = conn.Bind(dn, password)
+	if err != nil {
+		return fmt.Errorf("ldap: %s", err)
+	}
+	return nil
+}
+
+// escapeDN escapes the special characters of a DN attribute value
+// (RFC 4514).
+func escapeDN(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.IndexByte(`,+"\<>;=`, c) >= 0,
+			(c == ' ' || c == '#') && i == 0,
+			c == ' ' && i == len(s)-1:
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == 0:
+			sb.WriteString(`\00`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
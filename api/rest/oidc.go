@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	oidc "github.com/coreos/go-oidc"
+)
+
+// The OIDC backend accepts ID tokens issued by an OpenID Connect provider
+// as bearer tokens. Tokens are verified with go-oidc against the keys
+// published by the provider (its JWKS), which are discovered from the
+// issuer unless configured.
+
+var (
+	oidcFetchTimeout = 10 * time.Second
+	// oidcDiscoveryRetryInterval limits how often the discovery of
+	// the provider is retried after failing.
+	oidcDiscoveryRetryInterval = time.Minute
+)
+
+// oidcSigningAlgs are the accepted signing algorithms. Only asymmetric
+// algorithms are accepted.
+var oidcSigningAlgs = []string{
+	oidc.RS256, oidc.RS384, oidc.RS512,
+	oidc.ES256, oidc.ES384, oidc.ES512,
+}
+
+// oidcAuthenticator checks bearer tokens issued by an OpenID Connect
+// provider.
+type oidcAuthenticator struct {
+	issuer    string
+	userClaim string
+	// ctx carries the HTTP client used to fetch the provider
+	// configuration and keys.
+	ctx       context.Context
+	verifyCfg *oidc.Config
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+	// discovering is closed when the running discovery finishes.
+	discovering   chan struct{}
+	lastDiscovery time.Time
+}
+
+func newOIDCAuthenticator(cfg *Config) *oidcAuthenticator {
+	oa := &oidcAuthenticator{
+		issuer:    cfg.OIDCIssuer,
+		userClaim: cfg.OIDCUserClaim,
+		ctx: oidc.ClientContext(
+			context.Background(),
+			&http.Client{Timeout: oidcFetchTimeout},
+		),
+		verifyCfg: &oidc.Config{
+			ClientID:             cfg.OIDCAudience,
+			SupportedSigningAlgs: oidcSigningAlgs,
+		},
+	}
+	// Without discovery, keys are only fetched when verifying tokens.
+	if cfg.OIDCJWKSURL != "" {
+		keySet := oidc.NewRemoteKeySet(oa.ctx, cfg.OIDCJWKSURL)
+		oa.verifier = oidc.NewVerifier(oa.issuer, keySet, oa.verifyCfg)
+	}
+	return oa
+}
+
+func (oa *oidcAuthenticator) Authenticate(r *http.Request) (string, error) {
+	authz := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(authz) < len(prefix) || !strings.EqualFold(authz[:len(prefix)], prefix) {
+		return "", errNoCredentials
+	}
+
+	verifier, err := oa.getVerifier(r.Context())
+	if err != nil {
+		return "", fmt.Errorf("oidc: %s", err)
+	}
+	token, err := verifier.Verify(r.Context(), strings.TrimSpace(authz[len(prefix):]))
+	if err != nil {
+		return "", fmt.Errorf("oidc: %s", err)
+	}
+
+	var claims map[string]interface{}
+	if err := token.Claims(&claims); err != nil {
+		return "", fmt.Errorf("oidc: %s", err)
+	}
+	user, ok := claims[oa.userClaim].(string)
+	if !ok || user == "" {
+		return "", fmt.Errorf("oidc: token has no %s claim", oa.userClaim)
+	}
+	return AuthOIDC + ":" + user, nil
+}
+
+// getVerifier returns the token verifier, discovering the provider
+// configuration when needed. Concurrent requests wait for a single
+// discovery, which runs without holding the lock.
+func (oa *oidcAuthenticator) getVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	oa.mu.Lock()
+	for oa.discovering != nil {
+		wait := oa.discovering
+		oa.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wait:
+		}
+		oa.mu.Lock()
+	}
+	if oa.verifier != nil {
+		v := oa.verifier
+		oa.mu.Unlock()
+		return v, nil
+	}
+	if time.Since(oa.lastDiscovery) < oidcDiscoveryRetryInterval {
+		oa.mu.Unlock()
+		return nil, errors.New("the provider could not be discovered")
+	}
+	oa.lastDiscovery = time.Now()
+	done := make(chan struct{})
+	oa.discovering = done
+	oa.mu.Unlock()
+
+	var v *oidc.IDTokenVerifier
+	provider, err := oidc.NewProvider(oa.ctx, oa.issuer)
+	if err == nil {
+		v = provider.Verifier(oa.verifyCfg)
+	}
+
+	oa.mu.Lock()
+	oa.verifier = v
+	oa.discovering = nil
+	close(done)
+	oa.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
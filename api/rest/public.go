@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	types "github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/ratelimit"
+)
+
+// The public status endpoint, when enabled, lets anyone see whether the
+// cluster is healthy, i.e. for the status page of a collaborative cluster,
+// without giving access to the rest of the API. It is served before
+// authentication, CORS and request limits, and only shows the
+// types.PublicStatus subset of the cluster information. Requests are
+// rate-limited for all clients together, and the information itself is
+// cached by the cluster peer, so that the endpoint cannot be used to load
+// the cluster.
+
+const publicStatusPath = "/public/status"
+
+var (
+	errMethodNotAllowed        = errors.New("method not allowed")
+	errPublicStatusUnavailable = errors.New("the cluster status is not available")
+)
+
+// publicHandler serves the public status endpoint and passes any other
+// request to the given handler.
+func (api *API) publicHandler(h http.Handler) http.Handler {
+	limiter := ratelimit.NewBucket(api.config.PublicStatusRateLimit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != publicStatusPath {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			api.sendResponse(w, http.StatusMethodNotAllowed, errMethodNotAllowed, nil)
+			return
+		}
+
+		if wait, ok := limiter.Reserve(time.Now(), 0); !ok {
+			api.sendResponse(w, autoStatus, &types.RateLimitError{RetryAfter: wait}, nil)
+			return
+		}
+
+		api.publicStatusHandler(w, r)
+	})
+}
+
+func (api *API) publicStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var info types.ClusterInfo
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"ClusterInfo",
+		struct{}{},
+		&info,
+	)
+	if err != nil {
+		// Do not give away the details of the error.
+		logger.Errorf("error obtaining the public status: %s", err)
+		api.sendResponse(w, http.StatusServiceUnavailable, errPublicStatusUnavailable, nil)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	api.sendResponse(w, autoStatus, nil, info.PublicStatus())
+}
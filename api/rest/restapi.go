@@ -8,6 +8,7 @@
 package rest
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -16,17 +17,24 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ipfs/ipfs-cluster/adder/adderutils"
 	types "github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/api/graphviz"
+	"github.com/ipfs/ipfs-cluster/api/limits"
+	"github.com/ipfs/ipfs-cluster/rpcutil"
 
 	mux "github.com/gorilla/mux"
 	gostream "github.com/hsanjuan/go-libp2p-gostream"
 	p2phttp "github.com/hsanjuan/go-libp2p-http"
 	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log"
 	gopath "github.com/ipfs/go-path"
 	libp2p "github.com/libp2p/go-libp2p"
@@ -39,6 +47,7 @@ import (
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
 	"go.opencensus.io/trace"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 func init() {
@@ -80,12 +89,20 @@ type API struct {
 	server *http.Server
 	host   host.Host
 
-	httpListener   net.Listener
+	httpListeners  []net.Listener
 	libp2pListener net.Listener
 
+	acmeManager  *autocert.Manager
+	acmeServer   *http.Server
+	acmeListener net.Listener
+
 	shutdownLock sync.Mutex
 	shutdown     bool
 	wg           sync.WaitGroup
+
+	// number of adds being performed by this peer, used to decide on
+	// add delegation. Accessed atomically.
+	addsInProgress int64
 }
 
 type route struct {
@@ -99,6 +116,10 @@ type peerAddBody struct {
 	PeerID string `json:"peer_id"`
 }
 
+type peerstoreAddBody struct {
+	Addresses []types.Multiaddr `json:"addresses"`
+}
+
 // NewAPI creates a new REST API component with the given configuration.
 func NewAPI(ctx context.Context, cfg *Config) (*API, error) {
 	return NewAPIWithHost(ctx, cfg, nil)
@@ -107,18 +128,36 @@ func NewAPI(ctx context.Context, cfg *Config) (*API, error) {
 // NewAPIWithHost creates a new REST API component and enables
 // the libp2p-http endpoint using the given Host, if not nil.
 func NewAPIWithHost(ctx context.Context, cfg *Config, h host.Host) (*API, error) {
+	return NewAPIWithHostAndDatastore(ctx, cfg, h, nil)
+}
+
+// NewAPIWithHostAndDatastore works like NewAPIWithHost and additionally
+// uses the given datastore to store the certificates obtained via ACME. It
+// must not be nil when the configuration sets acme_domains.
+func NewAPIWithHostAndDatastore(ctx context.Context, cfg *Config, h host.Host, store ds.Datastore) (*API, error) {
 	err := cfg.Validate()
 	if err != nil {
 		return nil, err
 	}
 
 	// Our handler is a gorilla router,
+	// wrapped with the request limits handler,
 	// wrapped with the cors handler,
-	// wrapped with the basic auth handler.
+	// wrapped with the auth handler.
+	auth, err := newListenerAuth(cfg)
+	if err != nil {
+		return nil, err
+	}
 	router := mux.NewRouter().StrictSlash(true)
-	handler := basicAuthHandler(
-		cfg.BasicAuthCreds,
-		cors.New(*cfg.corsOptions()).Handler(router),
+	limitsHandler := limits.Handler(router, limits.Options{
+		Name:            configKey,
+		MaxBodySize:     cfg.maxBodySize,
+		BodyIdleTimeout: cfg.RequestBodyIdleTimeout,
+		Reject:          rejectRequest(cfg.Headers),
+	})
+	handler := authHandler(
+		auth,
+		cors.New(*cfg.corsOptions()).Handler(limitsHandler),
 	)
 	if cfg.Tracing {
 		handler = &ochttp.Handler{
@@ -155,8 +194,17 @@ func NewAPIWithHost(ctx context.Context, cfg *Config, h host.Host) (*API, error)
 		rpcReady: make(chan struct{}, 2),
 	}
 	api.addRoutes(router)
+	if cfg.PublicStatus {
+		s.Handler = api.publicHandler(s.Handler)
+	}
+
+	// Set up api.acmeManager if enabled
+	err = api.setupACME(store)
+	if err != nil {
+		return nil, err
+	}
 
-	// Set up api.httpListener if enabled
+	// Set up api.httpListeners if enabled
 	err = api.setupHTTP(ctx)
 	if err != nil {
 		return nil, err
@@ -168,7 +216,7 @@ func NewAPIWithHost(ctx context.Context, cfg *Config, h host.Host) (*API, error)
 		return nil, err
 	}
 
-	if api.httpListener == nil && api.libp2pListener == nil {
+	if len(api.httpListeners) == 0 && api.libp2pListener == nil {
 		return nil, ErrNoEndpointsEnabled
 	}
 
@@ -177,37 +225,55 @@ func NewAPIWithHost(ctx context.Context, cfg *Config, h host.Host) (*API, error)
 }
 
 func (api *API) setupHTTP(ctx context.Context) error {
-	if api.config.HTTPListenAddr == nil {
-		return nil
+	var addrs []ma.Multiaddr
+	if api.config.HTTPListenAddr != nil {
+		addrs = append(addrs, api.config.HTTPListenAddr)
+	}
+	addrs = append(addrs, api.config.HTTPExtraListenAddrs...)
+
+	for _, listenAddr := range addrs {
+		l, err := api.listenHTTP(listenAddr)
+		if err != nil {
+			for _, l := range api.httpListeners {
+				l.Close()
+			}
+			api.httpListeners = nil
+			return err
+		}
+		api.httpListeners = append(api.httpListeners, l)
 	}
+	return nil
+}
 
-	n, addr, err := manet.DialArgs(api.config.HTTPListenAddr)
+func (api *API) listenHTTP(listenAddr ma.Multiaddr) (net.Listener, error) {
+	n, addr, err := manet.DialArgs(listenAddr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var l net.Listener
-	if api.config.TLS != nil {
-		l, err = tls.Listen(n, addr, api.config.TLS)
-	} else {
-		l, err = net.Listen(n, addr)
+	if api.acmeManager != nil {
+		return tls.Listen(n, addr, api.acmeManager.TLSConfig())
 	}
-	if err != nil {
-		return err
+	if api.config.TLS != nil {
+		return tls.Listen(n, addr, api.config.TLS)
 	}
-	api.httpListener = l
-	return nil
+	return net.Listen(n, addr)
 }
 
 func (api *API) setupLibp2p(ctx context.Context) error {
 	// Make new host. Override any provided existing one
 	// if we have config for a custom one.
 	if api.config.Libp2pListenAddr != nil {
-		h, err := libp2p.New(
-			ctx,
+		opts := []libp2p.Option{
 			libp2p.Identity(api.config.PrivateKey),
-			libp2p.ListenAddrs([]ma.Multiaddr{api.config.Libp2pListenAddr}...),
-		)
+			libp2p.ListenAddrs(append([]ma.Multiaddr{api.config.Libp2pListenAddr}, api.config.Libp2pExtraListenAddrs...)...),
+		}
+		if announce := api.config.Libp2pAnnounceAddrs; len(announce) > 0 {
+			opts = append(opts, libp2p.AddrsFactory(func([]ma.Multiaddr) []ma.Multiaddr {
+				return announce
+			}))
+		}
+		h, err := libp2p.New(ctx, opts...)
 		if err != nil {
 			return err
 		}
@@ -231,10 +297,10 @@ func (api *API) setupLibp2p(ctx context.Context) error {
 // on a random port (0). Returns error when the HTTP endpoint
 // is not enabled.
 func (api *API) HTTPAddress() (string, error) {
-	if api.httpListener == nil {
+	if len(api.httpListeners) == 0 {
 		return "", ErrHTTPEndpointNotEnabled
 	}
-	return api.httpListener.Addr().String(), nil
+	return api.httpListeners[0].Addr().String(), nil
 }
 
 // Host returns the libp2p Host used by the API, if any.
@@ -253,7 +319,14 @@ func (api *API) addRoutes(router *mux.Router) {
 			Name(route.Name).
 			Handler(
 				ochttp.WithRouteTag(
-					http.HandlerFunc(route.HandlerFunc),
+					roleHandler(
+						api.config,
+						route.Name,
+						api.deadlineHandler(
+							route.Name,
+							cidBaseHandler(http.HandlerFunc(route.HandlerFunc)),
+						),
+					),
 					"/"+route.Name,
 				),
 			)
@@ -261,43 +334,56 @@ func (api *API) addRoutes(router *mux.Router) {
 	api.router = router
 }
 
-// basicAuth wraps a given handler with basic authentication
-func basicAuthHandler(credentials map[string]string, h http.Handler) http.Handler {
-	if credentials == nil {
-		return h
-	}
-
-	wrap := func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-		username, password, ok := r.BasicAuth()
-		if !ok {
-			resp, err := unauthorizedResp()
-			if err != nil {
-				logger.Error(err)
+// deadlineHandler sets a deadline on the context of requests to the given
+// route. It is the shortest of the configured route timeout and the timeout
+// requested by the client, if any.
+func (api *API) deadlineHandler(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := api.config.RouteTimeouts[name]
+		if v := r.Header.Get(types.RequestTimeoutHeader); v != "" {
+			clientTimeout, err := time.ParseDuration(v)
+			if err != nil || clientTimeout <= 0 {
+				api.sendResponse(
+					w,
+					http.StatusBadRequest,
+					fmt.Errorf("invalid %s header: %q", types.RequestTimeoutHeader, v),
+					nil,
+				)
 				return
 			}
-			http.Error(w, resp, 401)
-			return
+			if timeout == 0 || clientTimeout < timeout {
+				timeout = clientTimeout
+			}
 		}
 
-		authorized := false
-		for u, p := range credentials {
-			if u == username && p == password {
-				authorized = true
-			}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
 		}
-		if !authorized {
-			resp, err := unauthorizedResp()
-			if err != nil {
-				logger.Error(err)
-				return
+		h.ServeHTTP(w, r)
+	})
+}
+
+// rejectRequest returns a function writing error responses for requests
+// refused before reaching the router.
+func rejectRequest(headers map[string][]string) func(http.ResponseWriter, int, error) {
+	return func(w http.ResponseWriter, status int, err error) {
+		for header, values := range headers {
+			for _, val := range values {
+				w.Header().Add(header, val)
 			}
-			http.Error(w, resp, 401)
-			return
 		}
-		h.ServeHTTP(w, r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		errorResp := types.Error{
+			Code:    status,
+			Message: err.Error(),
+		}
+		if err := json.NewEncoder(w).Encode(errorResp); err != nil {
+			logger.Error(err)
+		}
 	}
-	return http.HandlerFunc(wrap)
 }
 
 func unauthorizedResp() (string, error) {
@@ -325,6 +411,13 @@ func (api *API) routes() []route {
 			api.versionHandler,
 		},
 
+		{
+			"ClusterInfo",
+			"GET",
+			"/cluster/info",
+			api.clusterInfoHandler,
+		},
+
 		{
 			"Peers",
 			"GET",
@@ -337,12 +430,96 @@ func (api *API) routes() []route {
 			"/peers",
 			api.peerAddHandler,
 		},
+		{
+			"JoinToken",
+			"POST",
+			"/peers/token",
+			api.joinTokenHandler,
+		},
 		{
 			"PeerRemove",
 			"DELETE",
 			"/peers/{peer}",
 			api.peerRemoveHandler,
 		},
+		{
+			"PeerMigrate",
+			"POST",
+			"/peers/{peer}/migrate",
+			api.peerMigrateHandler,
+		},
+		{
+			"PeerAllocatable",
+			"POST",
+			"/peers/{peer}/allocatable",
+			api.peerAllocatableHandler,
+		},
+		{
+			"PeerNotAllocatable",
+			"DELETE",
+			"/peers/{peer}/allocatable",
+			api.peerAllocatableHandler,
+		},
+		{
+			"PeerTrackerPause",
+			"POST",
+			"/peers/{peer}/paused",
+			api.peerPausedHandler,
+		},
+		{
+			"PeerTrackerResume",
+			"DELETE",
+			"/peers/{peer}/paused",
+			api.peerPausedHandler,
+		},
+		{
+			"Peerstore",
+			"GET",
+			"/peerstore",
+			api.peerstoreHandler,
+		},
+		{
+			"PeerstoreAdd",
+			"POST",
+			"/peerstore",
+			api.peerstoreAddHandler,
+		},
+		{
+			"ConnectionDenyList",
+			"GET",
+			"/connections/deny",
+			api.connectionDenyListHandler,
+		},
+		{
+			"ConnectionDeny",
+			"POST",
+			"/connections/deny",
+			api.connectionDenyHandler,
+		},
+		{
+			"ConnectionUndeny",
+			"DELETE",
+			"/connections/deny",
+			api.connectionDenyHandler,
+		},
+		{
+			"Faults",
+			"GET",
+			"/faults",
+			api.faultsHandler,
+		},
+		{
+			"SetFaults",
+			"POST",
+			"/faults",
+			api.setFaultsHandler,
+		},
+		{
+			"Logs",
+			"GET",
+			"/debug/logs",
+			api.logsHandler,
+		},
 		{
 			"Add",
 			"POST",
@@ -367,6 +544,12 @@ func (api *API) routes() []route {
 			"/pins",
 			api.statusAllHandler,
 		},
+		{
+			"StatusSummary",
+			"GET",
+			"/pins/summary",
+			api.statusSummaryHandler,
+		},
 		{
 			"Sync",
 			"POST",
@@ -391,12 +574,96 @@ func (api *API) routes() []route {
 			"/pins/recover",
 			api.recoverAllHandler,
 		},
+		{
+			"RecoveryStatus",
+			"GET",
+			"/pins/recover",
+			api.recoveryStatusHandler,
+		},
+		{
+			"RestorePin",
+			"POST",
+			"/pins/{hash}/restore",
+			api.restorePinHandler,
+		},
+		{
+			"Snapshots",
+			"GET",
+			"/snapshots",
+			api.snapshotsHandler,
+		},
+		{
+			"SnapshotCreate",
+			"POST",
+			"/snapshots",
+			api.snapshotCreateHandler,
+		},
+		{
+			"SnapshotDelete",
+			"DELETE",
+			"/snapshots/{name}",
+			api.snapshotDeleteHandler,
+		},
+		{
+			"SnapshotDiff",
+			"GET",
+			"/snapshots/{from}/diff/{to}",
+			api.snapshotDiffHandler,
+		},
+		{
+			"SnapshotRestore",
+			"POST",
+			"/snapshots/{name}/restore",
+			api.snapshotRestoreHandler,
+		},
+		{
+			"Provide",
+			"POST",
+			"/pins/{hash}/provide",
+			api.provideHandler,
+		},
 		{
 			"Status",
 			"GET",
 			"/pins/{hash}",
 			api.statusHandler,
 		},
+		{
+			"PinDetails",
+			"GET",
+			"/pins/{hash}/details",
+			api.pinDetailsHandler,
+		},
+		{
+			"PinGateways",
+			"GET",
+			"/pins/{hash}/gateways",
+			api.pinGatewaysHandler,
+		},
+		{
+			"StateSync",
+			"POST",
+			"/sync",
+			api.stateSyncHandler,
+		},
+		{
+			"LastStateSync",
+			"GET",
+			"/sync/last",
+			api.lastStateSyncHandler,
+		},
+		{
+			"AuditLog",
+			"GET",
+			"/audit",
+			api.auditLogHandler,
+		},
+		{
+			"RepoGC",
+			"POST",
+			"/ipfs/gc",
+			api.repoGCHandler,
+		},
 		{
 			"Pin",
 			"POST",
@@ -427,6 +694,42 @@ func (api *API) routes() []route {
 			"/health/graph",
 			api.graphHandler,
 		},
+		{
+			"Ping",
+			"GET",
+			"/health/ping",
+			api.pingHandler,
+		},
+		{
+			"Versions",
+			"GET",
+			"/health/versions",
+			api.versionsHandler,
+		},
+		{
+			"RuntimeStats",
+			"GET",
+			"/health/runtime",
+			api.runtimeStatsHandler,
+		},
+		{
+			"IPFSDrift",
+			"GET",
+			"/health/ipfs",
+			api.ipfsDriftHandler,
+		},
+		{
+			"StorageUsage",
+			"GET",
+			"/health/storage",
+			api.storageUsageHandler,
+		},
+		{
+			"ReconnectStatus",
+			"GET",
+			"/health/reconnect",
+			api.reconnectStatusHandler,
+		},
 		{
 			"Metrics",
 			"GET",
@@ -437,7 +740,7 @@ func (api *API) routes() []route {
 }
 
 func (api *API) run(ctx context.Context) {
-	if api.httpListener != nil {
+	if len(api.httpListeners) > 0 {
 		api.wg.Add(1)
 		go api.runHTTPServer(ctx)
 	}
@@ -446,6 +749,11 @@ func (api *API) run(ctx context.Context) {
 		api.wg.Add(1)
 		go api.runLibp2pServer(ctx)
 	}
+
+	if api.acmeListener != nil {
+		api.wg.Add(1)
+		go api.runACMEServer(ctx)
+	}
 }
 
 // runs in goroutine from run()
@@ -453,8 +761,21 @@ func (api *API) runHTTPServer(ctx context.Context) {
 	defer api.wg.Done()
 	<-api.rpcReady
 
-	logger.Infof("REST API (HTTP): %s", api.config.HTTPListenAddr)
-	err := api.server.Serve(api.httpListener)
+	// All HTTP listeners share the same server. The first one is
+	// served from this goroutine.
+	for _, l := range api.httpListeners[1:] {
+		api.wg.Add(1)
+		go func(l net.Listener) {
+			defer api.wg.Done()
+			api.serveHTTP(l)
+		}(l)
+	}
+	api.serveHTTP(api.httpListeners[0])
+}
+
+func (api *API) serveHTTP(l net.Listener) {
+	logger.Infof("REST API (HTTP): %s", l.Addr())
+	err := api.server.Serve(l)
 	if err != nil && !strings.Contains(err.Error(), "closed network connection") {
 		logger.Error(err)
 	}
@@ -498,12 +819,15 @@ func (api *API) Shutdown(ctx context.Context) error {
 	// Cancel any outstanding ops
 	api.server.SetKeepAlivesEnabled(false)
 
-	if api.httpListener != nil {
-		api.httpListener.Close()
+	for _, l := range api.httpListeners {
+		l.Close()
 	}
 	if api.libp2pListener != nil {
 		api.libp2pListener.Close()
 	}
+	if api.acmeListener != nil {
+		api.acmeListener.Close()
+	}
 
 	// This means we created the host
 	if api.config.Libp2pListenAddr != nil {
@@ -539,6 +863,19 @@ func (api *API) idHandler(w http.ResponseWriter, r *http.Request) {
 	api.sendResponse(w, autoStatus, err, &id)
 }
 
+func (api *API) clusterInfoHandler(w http.ResponseWriter, r *http.Request) {
+	var info types.ClusterInfo
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"ClusterInfo",
+		struct{}{},
+		&info,
+	)
+	api.sendResponse(w, autoStatus, err, &info)
+}
+
 func (api *API) versionHandler(w http.ResponseWriter, r *http.Request) {
 	var v types.Version
 	err := api.rpcClient.CallContext(
@@ -563,70 +900,245 @@ func (api *API) graphHandler(w http.ResponseWriter, r *http.Request) {
 		struct{}{},
 		&graph,
 	)
-	api.sendResponse(w, autoStatus, err, graph)
+
+	queryValues := r.URL.Query()
+	switch format := queryValues.Get("format"); format {
+	case "", "json":
+		api.sendResponse(w, autoStatus, err, graph)
+	case "dot":
+		if err != nil {
+			api.sendResponse(w, autoStatus, err, nil)
+			return
+		}
+		allIpfs := queryValues.Get("all-ipfs-peers") == "true"
+		var buf bytes.Buffer
+		err = graphviz.WriteDot(&graph, &buf, allIpfs)
+		if err != nil {
+			api.sendResponse(w, autoStatus, err, nil)
+			return
+		}
+		api.setHeaders(w)
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	default:
+		api.sendResponse(w, http.StatusBadRequest, errors.New("unknown graph format: "+format), nil)
+	}
 }
 
-func (api *API) metricsHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	name := vars["name"]
+func (api *API) pingHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
 
-	var metrics []*types.Metric
+	if pidStr := queryValues.Get("peer"); pidStr != "" {
+		pid, err := peer.IDB58Decode(pidStr)
+		if err != nil {
+			api.sendResponse(w, http.StatusBadRequest, errors.New("error decoding peer: "+err.Error()), nil)
+			return
+		}
+		var result types.PingResult
+		err = api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"PingPeer",
+			pid,
+			&result,
+		)
+		api.sendResponse(w, autoStatus, err, []*types.PingResult{&result})
+		return
+	}
+
+	var results []*types.PingResult
 	err := api.rpcClient.CallContext(
 		r.Context(),
 		"",
-		"PeerMonitor",
-		"LatestMetrics",
-		name,
-		&metrics,
+		"Cluster",
+		"PingAll",
+		struct{}{},
+		&results,
 	)
-	api.sendResponse(w, autoStatus, err, metrics)
+	api.sendResponse(w, autoStatus, err, results)
 }
 
-func (api *API) addHandler(w http.ResponseWriter, r *http.Request) {
-	reader, err := r.MultipartReader()
-	if err != nil {
-		api.sendResponse(w, http.StatusBadRequest, err, nil)
-		return
-	}
-
-	params, err := types.AddParamsFromQuery(r.URL.Query())
-	if err != nil {
-		api.sendResponse(w, http.StatusBadRequest, err, nil)
-		return
-	}
-
-	api.setHeaders(w)
-
-	// any errors sent as trailer
-	adderutils.AddMultipartHTTPHandler(
+func (api *API) versionsHandler(w http.ResponseWriter, r *http.Request) {
+	var versions []*types.PeerVersion
+	err := api.rpcClient.CallContext(
 		r.Context(),
-		api.rpcClient,
-		params,
-		reader,
-		w,
-		nil,
+		"",
+		"Cluster",
+		"Versions",
+		struct{}{},
+		&versions,
 	)
-
-	return
+	api.sendResponse(w, autoStatus, err, versions)
 }
 
-func (api *API) peerListHandler(w http.ResponseWriter, r *http.Request) {
-	var peers []*types.ID
+func (api *API) ipfsDriftHandler(w http.ResponseWriter, r *http.Request) {
+	var drift types.IPFSDrift
 	err := api.rpcClient.CallContext(
 		r.Context(),
 		"",
 		"Cluster",
-		"Peers",
+		"IPFSDrift",
 		struct{}{},
-		&peers,
+		&drift,
 	)
-
-	api.sendResponse(w, autoStatus, err, peers)
+	api.sendResponse(w, autoStatus, err, &drift)
 }
 
-func (api *API) peerAddHandler(w http.ResponseWriter, r *http.Request) {
-	dec := json.NewDecoder(r.Body)
-	defer r.Body.Close()
+// reconnectStatusHandler reports whether this peer is in contact with the
+// rest of the cluster and how its reconnection attempts are going.
+func (api *API) reconnectStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var status types.ReconnectStatus
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"ReconnectStatus",
+		struct{}{},
+		&status,
+	)
+	api.sendResponse(w, autoStatus, err, &status)
+}
+
+// runtimeStatsHandler returns the Go runtime stats of every peer, or of
+// this peer only (local=true).
+func (api *API) runtimeStatsHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	if local == "true" {
+		var result types.RuntimeStats
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"RuntimeStatsLocal",
+			struct{}{},
+			&result,
+		)
+		api.sendResponse(w, autoStatus, err, []*types.RuntimeStats{&result})
+	} else {
+		var results []*types.RuntimeStats
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"RuntimeStatsAll",
+			struct{}{},
+			&results,
+		)
+		api.sendResponse(w, autoStatus, err, results)
+	}
+}
+
+// storageUsageHandler reports the unique and replicated storage used by
+// the pinset. The optional sample parameter limits how many pins are
+// examined.
+func (api *API) storageUsageHandler(w http.ResponseWriter, r *http.Request) {
+	sample := 0
+	if s := r.URL.Query().Get("sample"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			api.sendResponse(w, http.StatusBadRequest, errors.New("invalid sample size: "+s), nil)
+			return
+		}
+		sample = n
+	}
+
+	var usage types.StorageUsage
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"StorageUsage",
+		sample,
+		&usage,
+	)
+	api.sendResponse(w, autoStatus, err, &usage)
+}
+
+func (api *API) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	var metrics []*types.Metric
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"PeerMonitor",
+		"LatestMetrics",
+		name,
+		&metrics,
+	)
+	api.sendResponse(w, autoStatus, err, metrics)
+}
+
+func (api *API) addHandler(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		api.sendResponse(w, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	params, err := types.AddParamsFromQuery(r.URL.Query())
+	if err != nil {
+		api.sendResponse(w, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if params.Profile == "" {
+		params.Profile = api.config.DefaultAddProfile
+	}
+	if params.Profile != "" {
+		prof, ok := api.config.AddProfiles[params.Profile]
+		if !ok {
+			api.sendResponse(w, http.StatusBadRequest, errors.New("unknown add profile: "+params.Profile), nil)
+			return
+		}
+		params.ApplyProfile(prof)
+	}
+	params.Provenance = provenance(r, types.ProvenanceAdder)
+
+	if delegate := api.addDelegate(r, params); delegate != "" {
+		api.delegateAdd(w, r, delegate)
+		return
+	}
+	atomic.AddInt64(&api.addsInProgress, 1)
+	defer atomic.AddInt64(&api.addsInProgress, -1)
+
+	api.setHeaders(w)
+
+	// any errors sent as trailer
+	adderutils.AddMultipartHTTPHandler(
+		r.Context(),
+		api.rpcClient,
+		params,
+		reader,
+		w,
+		nil,
+	)
+
+	return
+}
+
+func (api *API) peerListHandler(w http.ResponseWriter, r *http.Request) {
+	var peers []*types.ID
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Peers",
+		struct{}{},
+		&peers,
+	)
+
+	api.sendResponse(w, autoStatus, err, peers)
+}
+
+func (api *API) peerAddHandler(w http.ResponseWriter, r *http.Request) {
+	dec := json.NewDecoder(r.Body)
+	defer r.Body.Close()
 
 	var addInfo peerAddBody
 	err := dec.Decode(&addInfo)
@@ -653,6 +1165,52 @@ func (api *API) peerAddHandler(w http.ResponseWriter, r *http.Request) {
 	api.sendResponse(w, autoStatus, err, &id)
 }
 
+func (api *API) peerstoreHandler(w http.ResponseWriter, r *http.Request) {
+	var known []*types.KnownPeer
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Peerstore",
+		struct{}{},
+		&known,
+	)
+	api.sendResponse(w, autoStatus, err, known)
+}
+
+func (api *API) peerstoreAddHandler(w http.ResponseWriter, r *http.Request) {
+	dec := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+
+	var body peerstoreAddBody
+	err := dec.Decode(&body)
+	if err != nil {
+		api.sendResponse(w, http.StatusBadRequest, errors.New("error decoding request body"), nil)
+		return
+	}
+	if len(body.Addresses) == 0 {
+		api.sendResponse(w, http.StatusBadRequest, errors.New("no addresses given"), nil)
+		return
+	}
+	for _, addr := range body.Addresses {
+		if _, _, err := types.Libp2pMultiaddrSplit(addr.Value()); err != nil {
+			api.sendResponse(w, http.StatusBadRequest, err, nil)
+			return
+		}
+	}
+
+	var known []*types.KnownPeer
+	err = api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"PeerstoreAdd",
+		body.Addresses,
+		&known,
+	)
+	api.sendResponse(w, autoStatus, err, known)
+}
+
 func (api *API) peerRemoveHandler(w http.ResponseWriter, r *http.Request) {
 	if p := api.parsePidOrError(w, r); p != "" {
 		err := api.rpcClient.CallContext(
@@ -667,44 +1225,343 @@ func (api *API) peerRemoveHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// peerMigrateHandler re-allocates the pins of a peer to other peers.
+func (api *API) peerMigrateHandler(w http.ResponseWriter, r *http.Request) {
+	if p := api.parsePidOrError(w, r); p != "" {
+		var mig types.PeerMigration
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"PeerMigrate",
+			p,
+			&mig,
+		)
+		api.sendResponse(w, autoStatus, err, mig)
+	}
+}
+
+// peerPausedHandler pauses (POST) or resumes (DELETE) the pin tracker of a
+// peer.
+func (api *API) peerPausedHandler(w http.ResponseWriter, r *http.Request) {
+	if p := api.parsePidOrError(w, r); p != "" {
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"SetTrackerPaused",
+			&types.TrackerPause{
+				Peer:   p,
+				Paused: r.Method == "POST",
+			},
+			&struct{}{},
+		)
+		api.sendResponse(w, autoStatus, err, nil)
+	}
+}
+
+// peerAllocatableHandler enables (POST) or disables (DELETE) new
+// allocations to a peer.
+func (api *API) peerAllocatableHandler(w http.ResponseWriter, r *http.Request) {
+	if p := api.parsePidOrError(w, r); p != "" {
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"SetAllocatable",
+			&types.PeerAllocatable{
+				Peer:        p,
+				Allocatable: r.Method == "POST",
+			},
+			&struct{}{},
+		)
+		api.sendResponse(w, autoStatus, err, nil)
+	}
+}
+
+func (api *API) connectionDenyListHandler(w http.ResponseWriter, r *http.Request) {
+	var deny types.ConnectionFilter
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"ConnectionDenyList",
+		struct{}{},
+		&deny,
+	)
+	api.sendResponse(w, autoStatus, err, deny)
+}
+
+// connectionDenyHandler adds (POST) or removes (DELETE) the peers and IP
+// ranges given in the "peer" and "cidr" query parameters to the connection
+// deny list.
+func (api *API) connectionDenyHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	deny := &types.ConnectionFilter{
+		CIDRs: queryValues["cidr"],
+	}
+	for _, pStr := range queryValues["peer"] {
+		p, err := peer.IDB58Decode(pStr)
+		if err != nil {
+			api.sendResponse(w, http.StatusBadRequest, errors.New("error decoding Peer ID: "+err.Error()), nil)
+			return
+		}
+		deny.Peers = append(deny.Peers, p)
+	}
+	if len(deny.Peers) == 0 && len(deny.CIDRs) == 0 {
+		api.sendResponse(w, http.StatusBadRequest, errors.New("no peers or cidrs given"), nil)
+		return
+	}
+
+	method := "ConnectionDeny"
+	if r.Method == "DELETE" {
+		method = "ConnectionUndeny"
+	}
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		method,
+		deny,
+		&struct{}{},
+	)
+	api.sendResponse(w, autoStatus, err, nil)
+}
+
+func (api *API) faultsHandler(w http.ResponseWriter, r *http.Request) {
+	var rules []*types.FaultRule
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Faults",
+		struct{}{},
+		&rules,
+	)
+	api.sendResponse(w, autoStatus, err, rules)
+}
+
+// logsHandler returns the recent log entries captured by the peer. The
+// "component" and "level" query parameters select the entries of a single
+// component and with a minimum severity.
+func (api *API) logsHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	q := types.LogQuery{
+		Component: queryValues.Get("component"),
+		Level:     queryValues.Get("level"),
+	}
+	if _, err := types.LogLevelSeverity(q.Level); err != nil {
+		api.sendResponse(w, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	var entries []*types.LogEntry
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Logs",
+		q,
+		&entries,
+	)
+	api.sendResponse(w, autoStatus, err, entries)
+}
+
+// setFaultsHandler replaces the fault injection rules of the peer with
+// the list given in the request body.
+func (api *API) setFaultsHandler(w http.ResponseWriter, r *http.Request) {
+	dec := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+
+	var rules []*types.FaultRule
+	err := dec.Decode(&rules)
+	if err != nil {
+		api.sendResponse(w, http.StatusBadRequest, errors.New("error decoding request body"), nil)
+		return
+	}
+
+	err = api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"SetFaults",
+		rules,
+		&struct{}{},
+	)
+	api.sendResponse(w, autoStatus, err, nil)
+}
+
+// joinTokenHandler creates a join token. The "ttl" query parameter sets
+// for how long it is valid.
+func (api *API) joinTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ttl, err := time.ParseDuration(r.URL.Query().Get("ttl"))
+	if err != nil {
+		api.sendResponse(w, http.StatusBadRequest, errors.New("error parsing ttl: "+err.Error()), nil)
+		return
+	}
+
+	var token types.JoinToken
+	err = api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"JoinToken",
+		ttl,
+		&token,
+	)
+	api.sendResponse(w, autoStatus, err, token)
+}
+
 func (api *API) pinHandler(w http.ResponseWriter, r *http.Request) {
 	if pin := api.parseCidOrError(w, r); pin != nil {
 		logger.Debugf("rest api pinHandler: %s", pin.Cid)
+		pin.Provenance = provenance(r, types.ProvenanceRESTAPI)
 		// span.AddAttributes(trace.StringAttribute("cid", pin.Cid))
+		var pinResp types.PinResponse
 		err := api.rpcClient.CallContext(
 			r.Context(),
 			"",
 			"Cluster",
-			"Pin",
+			"PinWithResponse",
+			pin,
+			&pinResp,
+		)
+		if err == nil && pinResp.Duplicate {
+			// Nothing new was committed: return the existing pin.
+			api.sendResponse(w, http.StatusOK, nil, pinResp)
+		} else {
+			api.sendResponse(w, http.StatusAccepted, err, pinResp)
+		}
+		logger.Debug("rest api pinHandler done")
+	}
+}
+
+func (api *API) unpinHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.parseCidOrError(w, r); pin != nil {
+		logger.Debugf("rest api unpinHandler: %s", pin.Cid)
+		// span.AddAttributes(trace.StringAttribute("cid", pin.Cid))
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"Unpin",
 			pin,
 			&struct{}{},
 		)
 		api.sendResponse(w, http.StatusAccepted, err, nil)
-		logger.Debug("rest api pinHandler done")
+		logger.Debug("rest api unpinHandler done")
+	}
+}
+
+func (api *API) restorePinHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.parseCidOrError(w, r); pin != nil {
+		logger.Debugf("rest api restorePinHandler: %s", pin.Cid)
+		var restored types.Pin
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"RestorePin",
+			pin,
+			&restored,
+		)
+		api.sendResponse(w, autoStatus, err, restored)
+		logger.Debug("rest api restorePinHandler done")
 	}
 }
 
-func (api *API) unpinHandler(w http.ResponseWriter, r *http.Request) {
-	if pin := api.parseCidOrError(w, r); pin != nil {
-		logger.Debugf("rest api unpinHandler: %s", pin.Cid)
-		// span.AddAttributes(trace.StringAttribute("cid", pin.Cid))
-		err := api.rpcClient.CallContext(
-			r.Context(),
-			"",
-			"Cluster",
-			"Unpin",
-			pin,
-			&struct{}{},
-		)
-		api.sendResponse(w, http.StatusAccepted, err, nil)
-		logger.Debug("rest api unpinHandler done")
+func (api *API) snapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	var snaps []*types.Snapshot
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Snapshots",
+		struct{}{},
+		&snaps,
+	)
+	api.sendResponse(w, autoStatus, err, snaps)
+}
+
+// snapshotCreateHandler takes a snapshot of the pinset with the name given
+// in the "name" query parameter.
+func (api *API) snapshotCreateHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		api.sendResponse(w, http.StatusBadRequest, errors.New("a snapshot name is needed"), nil)
+		return
+	}
+
+	var snap types.Snapshot
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"SnapshotCreate",
+		name,
+		&snap,
+	)
+	api.sendResponse(w, autoStatus, err, snap)
+}
+
+func (api *API) snapshotDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"SnapshotDelete",
+		mux.Vars(r)["name"],
+		&struct{}{},
+	)
+	api.sendResponse(w, autoStatus, err, nil)
+}
+
+// snapshotDiffHandler compares two snapshots. Either can be "current", for
+// the current pinset.
+func (api *API) snapshotDiffHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var diff types.SnapshotDiff
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"SnapshotDiff",
+		types.SnapshotDiffQuery{From: vars["from"], To: vars["to"]},
+		&diff,
+	)
+	api.sendResponse(w, autoStatus, err, diff)
+}
+
+// snapshotRestoreHandler restores the pinset to a snapshot. Restores which
+// would unpin many items need "force=true" and, when confirmations are
+// enabled, the token returned by a first attempt in "confirm".
+func (api *API) snapshotRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	q := types.SnapshotRestoreQuery{
+		Name: mux.Vars(r)["name"],
+		Options: types.BulkUnpinOptions{
+			Force:   queryValues.Get("force") == "true",
+			Confirm: queryValues.Get("confirm"),
+		},
 	}
+
+	var res types.SnapshotRestore
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"SnapshotRestore",
+		q,
+		&res,
+	)
+	api.sendResponse(w, autoStatus, err, res)
 }
 
 func (api *API) pinPathHandler(w http.ResponseWriter, r *http.Request) {
 	var pin types.Pin
 	if pinpath := api.parsePinPathOrError(w, r); pinpath != nil {
 		logger.Debugf("rest api pinPathHandler: %s", pinpath.Path)
+		pinpath.Provenance = provenance(r, types.ProvenanceRESTAPI)
 		err := api.rpcClient.CallContext(
 			r.Context(),
 			"",
@@ -749,6 +1606,24 @@ func (api *API) allocationsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var since time.Time
+	if sinceStr := queryValues.Get("since"); sinceStr != "" {
+		var err error
+		since, err = parseSince(sinceStr)
+		if err != nil {
+			api.sendResponse(w, http.StatusBadRequest, err, nil)
+			return
+		}
+	}
+
+	sortBy := queryValues.Get("sort")
+	switch sortBy {
+	case "", "created_at", "updated_at":
+	default:
+		api.sendResponse(w, http.StatusBadRequest, errors.New("invalid sort value"), nil)
+		return
+	}
+
 	var pins []*types.Pin
 	err := api.rpcClient.CallContext(
 		r.Context(),
@@ -760,14 +1635,43 @@ func (api *API) allocationsHandler(w http.ResponseWriter, r *http.Request) {
 	)
 	outPins := make([]*types.Pin, 0)
 	for _, pin := range pins {
-		if filter&pin.Type > 0 {
-			// add this pin to output
-			outPins = append(outPins, pin)
+		if filter&pin.Type == 0 {
+			continue
 		}
+		if !since.IsZero() && pin.CreatedAt.Before(since) {
+			continue
+		}
+		// add this pin to output
+		outPins = append(outPins, pin)
+	}
+
+	switch sortBy {
+	case "created_at":
+		sort.SliceStable(outPins, func(i, j int) bool {
+			return outPins[i].CreatedAt.Before(outPins[j].CreatedAt)
+		})
+	case "updated_at":
+		sort.SliceStable(outPins, func(i, j int) bool {
+			return outPins[i].UpdatedAt.Before(outPins[j].UpdatedAt)
+		})
 	}
 	api.sendResponse(w, autoStatus, err, outPins)
 }
 
+// parseSince parses the value of the "since" query parameter, which is
+// either an RFC3339 timestamp or a duration (i.e. "168h") counting back
+// from now.
+func parseSince(str string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil || d < 0 {
+		return time.Time{}, errors.New("invalid since value: use an RFC3339 timestamp or a duration")
+	}
+	return time.Now().Add(-d), nil
+}
+
 func (api *API) allocationHandler(w http.ResponseWriter, r *http.Request) {
 	if pin := api.parseCidOrError(w, r); pin != nil {
 		var pinResp types.Pin
@@ -811,28 +1715,62 @@ func filterGlobalPinInfos(globalPinInfos []*types.GlobalPinInfo, filter types.Tr
 	return filteredGlobalPinInfos
 }
 
+// statusSummaryHandler returns the number of items in each status for
+// every peer, or for this peer only (local=true).
+func (api *API) statusSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	if local == "true" {
+		var peerSummary types.PeerStatusSummary
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"StatusSummaryLocal",
+			struct{}{},
+			&peerSummary,
+		)
+		summary := &types.StatusSummary{
+			Totals: peerSummary.Counts,
+			Peers:  []*types.PeerStatusSummary{&peerSummary},
+		}
+		api.sendResponse(w, autoStatus, err, summary)
+	} else {
+		var summary types.StatusSummary
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"StatusSummary",
+			struct{}{},
+			&summary,
+		)
+		api.sendResponse(w, autoStatus, err, &summary)
+	}
+}
+
 func (api *API) statusAllHandler(w http.ResponseWriter, r *http.Request) {
 	queryValues := r.URL.Query()
 	local := queryValues.Get("local")
 
 	var globalPinInfos []*types.GlobalPinInfo
 
-	filterStr := queryValues.Get("filter")
-	filter := types.TrackerStatusFromString(filterStr)
-	if filter == types.TrackerStatusUndefined && filterStr != "" {
-		api.sendResponse(w, http.StatusBadRequest, errors.New("invalid filter value"), nil)
+	filter, err := types.TrackerStatusFilterFromString(queryValues.Get("filter"))
+	if err != nil {
+		api.sendResponse(w, http.StatusBadRequest, err, nil)
 		return
 	}
 
 	if local == "true" {
 		var pinInfos []*types.PinInfo
 
-		err := api.rpcClient.CallContext(
+		err = api.rpcClient.CallContext(
 			r.Context(),
 			"",
 			"Cluster",
 			"StatusAllLocal",
-			struct{}{},
+			filter,
 			&pinInfos,
 		)
 		if err != nil {
@@ -841,12 +1779,12 @@ func (api *API) statusAllHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		globalPinInfos = pinInfosToGlobal(pinInfos)
 	} else {
-		err := api.rpcClient.CallContext(
+		err = api.rpcClient.CallContext(
 			r.Context(),
 			"",
 			"Cluster",
 			"StatusAll",
-			struct{}{},
+			filter,
 			&globalPinInfos,
 		)
 		if err != nil {
@@ -863,6 +1801,7 @@ func (api *API) statusAllHandler(w http.ResponseWriter, r *http.Request) {
 func (api *API) statusHandler(w http.ResponseWriter, r *http.Request) {
 	queryValues := r.URL.Query()
 	local := queryValues.Get("local")
+	explain := queryValues.Get("explain")
 
 	if pin := api.parseCidOrError(w, r); pin != nil {
 		if local == "true" {
@@ -875,7 +1814,11 @@ func (api *API) statusHandler(w http.ResponseWriter, r *http.Request) {
 				pin.Cid,
 				&pinInfo,
 			)
-			api.sendResponse(w, autoStatus, err, pinInfoToGlobal(&pinInfo))
+			gpi := pinInfoToGlobal(&pinInfo)
+			if err == nil && explain == "true" {
+				gpi.Allocation = api.allocationExplanation(r, pin.Cid, "AllocationExplanationLocal")
+			}
+			api.sendResponse(w, autoStatus, err, gpi)
 		} else {
 			var pinInfo types.GlobalPinInfo
 			err := api.rpcClient.CallContext(
@@ -886,11 +1829,115 @@ func (api *API) statusHandler(w http.ResponseWriter, r *http.Request) {
 				pin.Cid,
 				&pinInfo,
 			)
+			if err == nil && explain == "true" {
+				pinInfo.Allocation = api.allocationExplanation(r, pin.Cid, "AllocationExplanation")
+			}
 			api.sendResponse(w, autoStatus, err, pinInfo)
 		}
 	}
 }
 
+func (api *API) pinDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.parseCidOrError(w, r); pin != nil {
+		var pinResp types.Pin
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"PinGet",
+			pin.Cid,
+			&pinResp,
+		)
+		if err != nil { // errors here are 404s
+			api.sendResponse(w, http.StatusNotFound, err, nil)
+			return
+		}
+
+		var pinInfo types.GlobalPinInfo
+		err = api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"Status",
+			pin.Cid,
+			&pinInfo,
+		)
+		details := &types.PinDetails{
+			Pin:    &pinResp,
+			Status: &pinInfo,
+		}
+		api.sendResponse(w, autoStatus, err, details)
+	}
+}
+
+// pinGatewaysHandler returns the URLs to retrieve a Cid from the HTTP
+// gateways of the peers which have it pinned.
+func (api *API) pinGatewaysHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.parseCidOrError(w, r); pin != nil {
+		var pinInfo types.GlobalPinInfo
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"Status",
+			pin.Cid,
+			&pinInfo,
+		)
+		if err != nil {
+			api.sendResponse(w, autoStatus, err, nil)
+			return
+		}
+
+		var ids []*types.ID
+		err = api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"Peers",
+			struct{}{},
+			&ids,
+		)
+		if err != nil {
+			api.sendResponse(w, autoStatus, err, nil)
+			return
+		}
+
+		gateways := make([]string, 0)
+		for _, id := range ids {
+			if id.IPFS == nil || id.IPFS.GatewayURL == "" {
+				continue
+			}
+			pInfo, ok := pinInfo.PeerMap[peer.IDB58Encode(id.ID)]
+			if !ok || pInfo.Status != types.TrackerStatusPinned {
+				continue
+			}
+			gwURL := strings.TrimSuffix(id.IPFS.GatewayURL, "/")
+			gateways = append(gateways, gwURL+"/ipfs/"+pin.Cid.String())
+		}
+		sort.Strings(gateways)
+		api.sendResponse(w, autoStatus, nil, gateways)
+	}
+}
+
+// allocationExplanation fetches how the allocations for a Cid were decided.
+// When this information is not available, it returns nil.
+func (api *API) allocationExplanation(r *http.Request, c cid.Cid, method string) *types.AllocationExplanation {
+	var expl types.AllocationExplanation
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		method,
+		c,
+		&expl,
+	)
+	if err != nil {
+		logger.Debugf("no allocation explanation for %s: %s", c, err)
+		return nil
+	}
+	return &expl
+}
+
 func (api *API) syncAllHandler(w http.ResponseWriter, r *http.Request) {
 	queryValues := r.URL.Query()
 	local := queryValues.Get("local")
@@ -920,6 +1967,130 @@ func (api *API) syncAllHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// stateSyncHandler triggers a state sync on every peer, on the given peer
+// (peer=<peerID>) or on this peer only (local=true).
+func (api *API) stateSyncHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	switch pidStr := queryValues.Get("peer"); {
+	case pidStr != "":
+		pid, err := peer.IDB58Decode(pidStr)
+		if err != nil {
+			api.sendResponse(w, http.StatusBadRequest, errors.New("error decoding peer: "+err.Error()), nil)
+			return
+		}
+		var result types.StateSync
+		err = api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"StateSyncPeer",
+			pid,
+			&result,
+		)
+		api.sendResponse(w, autoStatus, err, []*types.StateSync{&result})
+	case local == "true":
+		var result types.StateSync
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"StateSyncLocal",
+			struct{}{},
+			&result,
+		)
+		api.sendResponse(w, autoStatus, err, []*types.StateSync{&result})
+	default:
+		var results []*types.StateSync
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"StateSyncAll",
+			struct{}{},
+			&results,
+		)
+		api.sendResponse(w, autoStatus, err, results)
+	}
+}
+
+// repoGCHandler runs the garbage collector of the IPFS daemons of every
+// peer, or of this peer only (local=true).
+func (api *API) repoGCHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	if local == "true" {
+		var result types.RepoGC
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"RepoGCLocal",
+			struct{}{},
+			&result,
+		)
+		api.sendResponse(w, autoStatus, err, []*types.RepoGC{&result})
+	} else {
+		var results []*types.RepoGC
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"RepoGC",
+			struct{}{},
+			&results,
+		)
+		api.sendResponse(w, autoStatus, err, results)
+	}
+}
+
+// lastStateSyncHandler returns the results of the last state sync on every
+// peer, or on this peer only (local=true).
+func (api *API) lastStateSyncHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	if local == "true" {
+		var result types.StateSync
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"LastStateSyncLocal",
+			struct{}{},
+			&result,
+		)
+		api.sendResponse(w, autoStatus, err, []*types.StateSync{&result})
+	} else {
+		var results []*types.StateSync
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"LastStateSyncAll",
+			struct{}{},
+			&results,
+		)
+		api.sendResponse(w, autoStatus, err, results)
+	}
+}
+
+// auditLogHandler returns the audit log of this peer.
+func (api *API) auditLogHandler(w http.ResponseWriter, r *http.Request) {
+	var recs []*types.AuditRecord
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"AuditLog",
+		struct{}{},
+		&recs,
+	)
+	api.sendResponse(w, autoStatus, err, recs)
+}
+
 func (api *API) syncHandler(w http.ResponseWriter, r *http.Request) {
 	queryValues := r.URL.Query()
 	local := queryValues.Get("local")
@@ -954,7 +2125,23 @@ func (api *API) syncHandler(w http.ResponseWriter, r *http.Request) {
 func (api *API) recoverAllHandler(w http.ResponseWriter, r *http.Request) {
 	queryValues := r.URL.Query()
 	local := queryValues.Get("local")
-	if local == "true" {
+	if pidStr := queryValues.Get("peer"); pidStr != "" {
+		pid, err := peer.IDB58Decode(pidStr)
+		if err != nil {
+			api.sendResponse(w, http.StatusBadRequest, errors.New("error decoding peer: "+err.Error()), nil)
+			return
+		}
+		var pinInfos []*types.PinInfo
+		err = api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"RecoverPeer",
+			pid,
+			&pinInfos,
+		)
+		api.sendResponse(w, autoStatus, err, pinInfosToGlobal(pinInfos))
+	} else if local == "true" {
 		var pinInfos []*types.PinInfo
 		err := api.rpcClient.CallContext(
 			r.Context(),
@@ -966,10 +2153,32 @@ func (api *API) recoverAllHandler(w http.ResponseWriter, r *http.Request) {
 		)
 		api.sendResponse(w, autoStatus, err, pinInfosToGlobal(pinInfos))
 	} else {
-		api.sendResponse(w, http.StatusBadRequest, errors.New("only requests with parameter local=true are supported"), nil)
+		var job types.RecoveryJob
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"RecoveryStart",
+			struct{}{},
+			&job,
+		)
+		api.sendResponse(w, autoStatus, err, job)
 	}
 }
 
+func (api *API) recoveryStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var job types.RecoveryJob
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"RecoveryStatus",
+		struct{}{},
+		&job,
+	)
+	api.sendResponse(w, autoStatus, err, job)
+}
+
 func (api *API) recoverHandler(w http.ResponseWriter, r *http.Request) {
 	queryValues := r.URL.Query()
 	local := queryValues.Get("local")
@@ -1001,6 +2210,21 @@ func (api *API) recoverHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (api *API) provideHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.parseCidOrError(w, r); pin != nil {
+		var results []*types.ProvideResult
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"Provide",
+			pin.Cid,
+			&results,
+		)
+		api.sendResponse(w, autoStatus, err, results)
+	}
+}
+
 func (api *API) parsePinPathOrError(w http.ResponseWriter, r *http.Request) *types.PinPath {
 	vars := mux.Vars(r)
 	urlpath := "/" + vars["keyType"] + "/" + strings.TrimSuffix(vars["path"], "/")
@@ -1013,6 +2237,10 @@ func (api *API) parsePinPathOrError(w http.ResponseWriter, r *http.Request) *typ
 
 	pinPath := &types.PinPath{Path: path.String()}
 	pinPath.PinOptions.FromQuery(r.URL.Query())
+	if err := pinPath.PinOptions.Validate(); err != nil {
+		api.sendResponse(w, http.StatusBadRequest, err, nil)
+		return nil
+	}
 	return pinPath
 }
 
@@ -1028,9 +2256,20 @@ func (api *API) parseCidOrError(w http.ResponseWriter, r *http.Request) *types.P
 
 	opts := types.PinOptions{}
 	opts.FromQuery(r.URL.Query())
-	pin := types.PinWithOpts(c, opts)
-	pin.MaxDepth = -1 // For now, all pins are recursive
-	return pin
+	if err := opts.Validate(); err != nil {
+		api.sendResponse(w, http.StatusBadRequest, err, nil)
+		return nil
+	}
+	return types.PinWithOpts(c, opts)
+}
+
+// provenance describes the submitter of a request received by the given
+// source.
+func provenance(r *http.Request, source string) *types.PinProvenance {
+	return &types.PinProvenance{
+		Source: source,
+		User:   authenticatedUser(r),
+	}
 }
 
 func (api *API) parsePidOrError(w http.ResponseWriter, r *http.Request) peer.ID {
@@ -1078,15 +2317,34 @@ func (api *API) sendResponse(
 
 	// Send an error
 	if err != nil {
+		if status == autoStatus && rpcutil.Outcome(err) == types.RPCTimeout {
+			status = http.StatusGatewayTimeout
+		}
 		if status == autoStatus || status < 400 { // set a default error status
 			status = http.StatusInternalServerError
 		}
+		if _, ok := types.AsBulkUnpinError(err); ok {
+			status = http.StatusConflict
+		}
+		if limitStatus, ok := limits.ErrorStatus(err); ok {
+			status = limitStatus
+		}
+		if rlErr, ok := types.AsRateLimitError(err); ok {
+			status = http.StatusTooManyRequests
+			w.Header().Set("Retry-After", strconv.Itoa(rlErr.RetryAfterSeconds()))
+		}
 		w.WriteHeader(status)
 
 		errorResp := types.Error{
 			Code:    status,
 			Message: err.Error(),
 		}
+		switch verr := err.(type) {
+		case types.ValidationErrors:
+			errorResp.Details = verr
+		case *types.ValidationError:
+			errorResp.Details = types.ValidationErrors{verr}
+		}
 		logger.Errorf("sending error response: %d: %s", status, err.Error())
 
 		if err := enc.Encode(errorResp); err != nil {
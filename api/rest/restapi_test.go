@@ -9,8 +9,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/http/httputil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -307,6 +311,46 @@ func TestAPIShutdown(t *testing.T) {
 
 }
 
+func TestAPIExtraListenAddrs(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "restapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "api.sock")
+	socketAddr, _ := ma.NewMultiaddr("/unix/" + socket)
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.HTTPExtraListenAddrs = []ma.Multiaddr{socketAddr}
+	rest := testAPIwithConfig(t, cfg, "extra listeners")
+	defer rest.Shutdown(ctx)
+
+	if len(rest.httpListeners) != 2 {
+		t.Fatal("expected two HTTP listeners")
+	}
+
+	c := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	httpResp, err := c.Get("http://unix/id")
+	id := api.ID{}
+	processResp(t, httpResp, err, &id)
+	if id.ID != test.PeerID1 {
+		t.Error("expected correct id over the unix socket")
+	}
+
+	// The main listener keeps working.
+	makeGet(t, rest, httpURL(rest)+"/id", &id)
+}
+
 func TestRestAPIIDEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -403,6 +447,42 @@ func TestAPIPeerAddEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIPeerstoreEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var known []*api.KnownPeer
+		makeGet(t, rest, url(rest)+"/peerstore", &known)
+		if len(known) != 1 || known[0].ID != test.PeerID1 || !known[0].Connected {
+			t.Error("unexpected peerstore response:", known)
+		}
+
+		addr := "/ip4/1.2.3.4/tcp/9096/ipfs/" + test.PeerID2.Pretty()
+		body := fmt.Sprintf("{\"addresses\":[\"%s\"]}", addr)
+		var added []*api.KnownPeer
+		makePost(t, rest, url(rest)+"/peerstore", []byte(body), &added)
+		if len(added) != 1 || added[0].ID != test.PeerID2 {
+			t.Error("unexpected peerstore add response:", added)
+		}
+
+		errResp := api.Error{}
+		makePost(t, rest, url(rest)+"/peerstore", []byte("{\"addresses\":[]}"), &errResp)
+		if errResp.Code != 400 {
+			t.Error("expected error with no addresses")
+		}
+
+		errResp = api.Error{}
+		makePost(t, rest, url(rest)+"/peerstore", []byte("{\"addresses\":[\"/ip4/1.2.3.4/tcp/9096\"]}"), &errResp)
+		if errResp.Code != 400 {
+			t.Error("expected error with an address without peer ID")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestAPIAddFileEndpointBadContentType(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -456,6 +536,209 @@ func TestAPIAddFileEndpointLocal(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIDelegatedAdd(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+	delegate := testAPI(t)
+	defer delegate.Shutdown(ctx)
+
+	rest.Host().Peerstore().AddAddrs(
+		delegate.Host().ID(),
+		delegate.Host().Addrs(),
+		peerstore.PermanentAddrTTL,
+	)
+
+	sth := test.NewShardingTestHelper()
+	defer sth.Clean(t)
+	body, closer := sth.GetTreeMultiReader(t)
+	defer closer.Close()
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/add?shard=false&repl_min=-1&repl_max=-1&stream-channels=true",
+		body,
+	)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+body.Boundary())
+	rec := httptest.NewRecorder()
+	rest.delegateAdd(rec, req, delegate.Host().ID())
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if d := rec.Header().Get(AddDelegateHeader); d != peer.IDB58Encode(delegate.Host().ID()) {
+		t.Error("expected the delegate in the response headers, got:", d)
+	}
+
+	// The output of the delegate is streamed back. The last object has
+	// the root.
+	var resp api.AddedOutput
+	dec := json.NewDecoder(rec.Body)
+	for dec.More() {
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if resp.Cid.String() != test.ShardingDirBalancedRootCID {
+		t.Error("Bad Cid after adding: ", resp.Cid)
+	}
+}
+
+func TestDelegatedAddFrom(t *testing.T) {
+	from := peer.IDB58Encode(test.PeerID1)
+
+	// Clients cannot skip delegation by setting the header.
+	req := httptest.NewRequest(http.MethodPost, "/add", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set(DelegatedAddHeader, from)
+	if p := delegatedAddFrom(req); p != "" {
+		t.Error("requests from clients should not be delegated adds")
+	}
+	if req.Header.Get(DelegatedAddHeader) != "" {
+		t.Error("the header should have been removed")
+	}
+
+	// Nor can other peers on behalf of someone else.
+	req.RemoteAddr = peer.IDB58Encode(test.PeerID2)
+	req.Header.Set(DelegatedAddHeader, from)
+	if p := delegatedAddFrom(req); p != "" {
+		t.Error("the header should match the remote peer")
+	}
+
+	req.RemoteAddr = from
+	req.Header.Set(DelegatedAddHeader, from)
+	if p := delegatedAddFrom(req); p != test.PeerID1 {
+		t.Error("expected an add delegated by", from)
+	}
+}
+
+func TestAPIAddFileEndpointProfile(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.AddProfiles = map[string]*api.AddProfile{
+		"trickle": {Layout: "trickle"},
+	}
+	rest := testAPIwithConfig(t, cfg, "profiles")
+	defer rest.Shutdown(ctx)
+
+	sth := test.NewShardingTestHelper()
+	defer sth.Clean(t)
+	_, closer := sth.GetTreeMultiReader(t)
+	closer.Close()
+
+	tf := func(t *testing.T, url urlF) {
+		// The profile overrides the layout given in the query.
+		localURL := url(rest) + "/add?layout=balanced&profile=trickle&stream-channels=true"
+		body, closer := sth.GetTreeMultiReader(t)
+		defer closer.Close()
+		resp := api.AddedOutput{}
+		mpContentType := "multipart/form-data; boundary=" + body.Boundary()
+		makeStreamingPost(t, rest, localURL, body, mpContentType, &resp)
+		if resp.Cid.String() != test.ShardingDirTrickleRootCID {
+			t.Error("Bad Cid after adding with a profile: ", resp.Cid)
+		}
+
+		errResp := api.Error{}
+		makePostWithContentType(
+			t,
+			rest,
+			url(rest)+"/add?profile=unknown",
+			[]byte("test"),
+			"multipart/form-data; boundary=abc",
+			&errResp,
+		)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("expected an error with an unknown profile")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIMaxRequestBodySize(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.MaxRequestBodySize = 10
+	rest := testAPIwithConfig(t, cfg, "body_limits")
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		errResp := api.Error{}
+		makePostWithContentType(
+			t,
+			rest,
+			url(rest)+"/pins/"+test.Cid1.String(),
+			make([]byte, 100),
+			"application/octet-stream",
+			&errResp,
+		)
+		if errResp.Code != http.StatusRequestEntityTooLarge {
+			t.Error("expected 413 with a body larger than the limit, got:", errResp.Code)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPICidBase(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	sth := test.NewShardingTestHelper()
+	defer sth.Clean(t)
+	_, closer := sth.GetTreeMultiReader(t)
+	closer.Close()
+
+	cidString := func(t *testing.T, obj map[string]interface{}) string {
+		link, ok := obj["cid"].(map[string]interface{})
+		if !ok {
+			t.Fatal("expected a cid object:", obj)
+		}
+		return link["/"].(string)
+	}
+
+	tf := func(t *testing.T, url urlF) {
+		cid1v1 := cid.NewCidV1(test.Cid1.Type(), test.Cid1.Hash())
+
+		var statusAll []map[string]interface{}
+		makeGet(t, rest, url(rest)+"/pins?cid-base=base32", &statusAll)
+		if len(statusAll) == 0 || cidString(t, statusAll[0]) != cid1v1.String() {
+			t.Error("expected base32 CIDs in the status output")
+		}
+
+		var pin map[string]interface{}
+		makeGet(t, rest, url(rest)+"/allocations/"+test.Cid1.String()+"?cid-base=base32", &pin)
+		if cidString(t, pin) != cid1v1.String() {
+			t.Error("expected base32 CIDs in the allocation output")
+		}
+
+		body, closer := sth.GetTreeMultiReader(t)
+		defer closer.Close()
+		var added map[string]interface{}
+		mpContentType := "multipart/form-data; boundary=" + body.Boundary()
+		makeStreamingPost(t, rest, url(rest)+"/add?cid-base=base32", body, mpContentType, &added)
+		root, _ := cid.Decode(test.ShardingDirBalancedRootCID)
+		rootv1 := cid.NewCidV1(root.Type(), root.Hash())
+		if cidString(t, added) != rootv1.String() {
+			t.Error("expected base32 CIDs in the add output")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+
+	httpResp, err := http.Get(httpURL(rest) + "/pins?cid-base=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusBadRequest {
+		t.Error("expected a bad request with an invalid cid-base")
+	}
+}
+
 func TestAPIAddFileEndpointShard(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -533,6 +816,224 @@ func TestAPIPeerRemoveEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIPeerMigrateEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var mig api.PeerMigration
+		makePost(t, rest, url(rest)+"/peers/"+test.PeerID1.Pretty()+"/migrate", []byte{}, &mig)
+		if mig.Peer != test.PeerID1 || len(mig.Pins) != 1 {
+			t.Errorf("unexpected migration: %+v", mig)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIJoinTokenEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var token api.JoinToken
+		makePost(t, rest, url(rest)+"/peers/token?ttl=1h", []byte{}, &token)
+		if token.Token == "" || token.Expires.IsZero() {
+			t.Error("expected a token with an expiration date")
+		}
+
+		errResp := api.Error{}
+		makePost(t, rest, url(rest)+"/peers/token?ttl=abc", []byte{}, &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail with bad ttl")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPISnapshotEndpoints(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var snap api.Snapshot
+		makePost(t, rest, url(rest)+"/snapshots?name=snap1", []byte{}, &snap)
+		if snap.Name != "snap1" || snap.Pins != 2 {
+			t.Error("unexpected snapshot")
+		}
+
+		errResp := api.Error{}
+		makePost(t, rest, url(rest)+"/snapshots", []byte{}, &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail without a snapshot name")
+		}
+
+		var snaps []*api.Snapshot
+		makeGet(t, rest, url(rest)+"/snapshots", &snaps)
+		if len(snaps) != 1 || snaps[0].Name != "snap1" {
+			t.Error("expected one snapshot")
+		}
+
+		var diff api.SnapshotDiff
+		makeGet(t, rest, url(rest)+"/snapshots/snap1/diff/current", &diff)
+		if diff.From != "snap1" || diff.To != "current" {
+			t.Error("unexpected diff")
+		}
+		if len(diff.Pin) != 1 || len(diff.Unpin) != 1 {
+			t.Error("expected one pin and one unpin")
+		}
+
+		errResp = api.Error{}
+		makePost(t, rest, url(rest)+"/snapshots/snap1/restore", []byte{}, &errResp)
+		if errResp.Code != 409 {
+			t.Error("restores unpinning many items should need to be forced")
+		}
+
+		var res api.SnapshotRestore
+		makePost(t, rest, url(rest)+"/snapshots/snap1/restore?force=true", []byte{}, &res)
+		if len(res.Pinned) != 1 || !res.Pinned[0].Equals(test.Cid1) {
+			t.Error("expected Cid1 to be pinned")
+		}
+
+		makeDelete(t, rest, url(rest)+"/snapshots/snap1", &struct{}{})
+		errResp = api.Error{}
+		makeDelete(t, rest, url(rest)+"/snapshots/snap2", &errResp)
+		if errResp.Code != 500 {
+			t.Error("should fail deleting a missing snapshot")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIConnectionDenyEndpoints(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var deny api.ConnectionFilter
+		makeGet(t, rest, url(rest)+"/connections/deny", &deny)
+		if len(deny.Peers) != 1 || deny.Peers[0] != test.PeerID2 {
+			t.Error("expected a denied peer")
+		}
+		if len(deny.CIDRs) != 1 || deny.CIDRs[0] != "10.0.0.0/8" {
+			t.Error("expected a denied cidr")
+		}
+
+		makePost(t, rest, url(rest)+"/connections/deny?peer="+test.PeerID1.Pretty()+"&cidr=10.0.0.0/8", []byte{}, &struct{}{})
+		makeDelete(t, rest, url(rest)+"/connections/deny?peer="+test.PeerID1.Pretty(), &struct{}{})
+
+		errResp := api.Error{}
+		makePost(t, rest, url(rest)+"/connections/deny?peer=abcd", []byte{}, &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail with bad peer ID")
+		}
+
+		errResp = api.Error{}
+		makeDelete(t, rest, url(rest)+"/connections/deny", &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail without peers or cidrs")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIFaultsEndpoints(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var rules []*api.FaultRule
+		makeGet(t, rest, url(rest)+"/faults", &rules)
+		if len(rules) != 1 || rules[0].Target != "ipfs" || rules[0].Percent != 50 {
+			t.Errorf("unexpected rules: %+v", rules)
+		}
+
+		makePost(t, rest, url(rest)+"/faults", []byte(`[{"target":"rpc","percent":10,"delay":1000000}]`), &struct{}{})
+
+		errResp := api.Error{}
+		makePost(t, rest, url(rest)+"/faults", []byte(`{"target":`), &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail with bad body")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPILogsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var entries []*api.LogEntry
+		makeGet(t, rest, url(rest)+"/debug/logs", &entries)
+		if len(entries) != 2 {
+			t.Errorf("expected 2 log entries, got %d", len(entries))
+		}
+
+		entries = nil
+		makeGet(t, rest, url(rest)+"/debug/logs?component=pintracker&level=warn", &entries)
+		if len(entries) != 1 || entries[0].Component != "pintracker" {
+			t.Errorf("unexpected log entries: %+v", entries)
+		}
+
+		errResp := api.Error{}
+		makeGet(t, rest, url(rest)+"/debug/logs?level=loud", &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("an unknown level should be rejected")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIPeerAllocatableEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		makePost(t, rest, url(rest)+"/peers/"+test.PeerID1.Pretty()+"/allocatable", []byte{}, &struct{}{})
+		makeDelete(t, rest, url(rest)+"/peers/"+test.PeerID1.Pretty()+"/allocatable", &struct{}{})
+
+		errResp := api.Error{}
+		makeDelete(t, rest, url(rest)+"/peers/abcd/allocatable", &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail with bad peer ID")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIPeerPausedEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		makePost(t, rest, url(rest)+"/peers/"+test.PeerID1.Pretty()+"/paused", []byte{}, &struct{}{})
+		makeDelete(t, rest, url(rest)+"/peers/"+test.PeerID1.Pretty()+"/paused", &struct{}{})
+
+		errResp := api.Error{}
+		makePost(t, rest, url(rest)+"/peers/abcd/paused", []byte{}, &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail with bad peer ID")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestConnectGraphEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -553,38 +1054,332 @@ func TestConnectGraphEndpoint(t *testing.T) {
 		if len(cg.ClustertoIPFS) != 3 {
 			t.Error("unexpected number of cluster to ipfs links")
 		}
-		// test a few link values
-		pid1 := test.PeerID1
-		pid4 := test.PeerID4
-		if _, ok := cg.ClustertoIPFS[peer.IDB58Encode(pid1)]; !ok {
-			t.Fatal("missing cluster peer 1 from cluster to peer links map")
+		// test a few link values
+		pid1 := test.PeerID1
+		pid4 := test.PeerID4
+		if _, ok := cg.ClustertoIPFS[peer.IDB58Encode(pid1)]; !ok {
+			t.Fatal("missing cluster peer 1 from cluster to peer links map")
+		}
+		if cg.ClustertoIPFS[peer.IDB58Encode(pid1)] != pid4 {
+			t.Error("unexpected ipfs peer mapped to cluster peer 1 in graph")
+		}
+		if cg.ClusterLatencies[peer.IDB58Encode(pid1)][peer.IDB58Encode(test.PeerID2)] != 10*time.Millisecond {
+			t.Error("unexpected latency between cluster peers 1 and 2")
+		}
+
+		h := makeHost(t, rest)
+		defer h.Close()
+		c := httpClient(t, h, isHTTPS(url(rest)))
+		httpResp, err := c.Get(url(rest) + "/health/graph?format=dot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer httpResp.Body.Close()
+		if ct := httpResp.Header.Get("Content-Type"); ct != "text/vnd.graphviz" {
+			t.Error("unexpected content type for dot format:", ct)
+		}
+		dot, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(dot), "digraph cluster {") ||
+			!strings.Contains(string(dot), `label="10ms"`) {
+			t.Error("unexpected dot output:", string(dot))
+		}
+
+		errResp := api.Error{}
+		makeGet(t, rest, url(rest)+"/health/graph?format=png", &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail with unknown format")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIPingEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []*api.PingResult
+		makeGet(t, rest, url(rest)+"/health/ping", &resp)
+		if len(resp) != 2 || resp[0].RTT != 10*time.Millisecond {
+			t.Errorf("unexpected ping resp:\n %+v", resp)
+		}
+
+		var resp2 []*api.PingResult
+		makeGet(t, rest, url(rest)+"/health/ping?peer="+test.PeerID3.Pretty(), &resp2)
+		if len(resp2) != 1 || resp2[0].Peer != test.PeerID3 ||
+			resp2[0].ClockSkew != -2*time.Millisecond {
+			t.Errorf("unexpected ping+peer resp:\n %+v", resp2)
+		}
+
+		var errResp api.Error
+		makeGet(t, rest, url(rest)+"/health/ping?peer=abc", &errResp)
+		if errResp.Code != 400 {
+			t.Error("expected a different error")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIVersionsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []*api.PeerVersion
+		makeGet(t, rest, url(rest)+"/health/versions", &resp)
+		if len(resp) != 2 || !resp[0].Compatible || resp[1].Compatible {
+			t.Errorf("unexpected versions resp:\n %+v", resp)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIClusterInfoEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp api.ClusterInfo
+		makeGet(t, rest, url(rest)+"/cluster/info", &resp)
+		if resp.Peers != 1 || resp.Pins != 3 || resp.Leader != test.PeerID1 {
+			t.Errorf("unexpected cluster info resp:\n %+v", resp)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIPublicStatusEndpoint(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.BasicAuthCreds = map[string]string{
+		validUserName: validUserPassword,
+	}
+	cfg.PublicStatus = true
+	cfg.PublicStatusRateLimit = 1
+	rest := testAPIwithConfig(t, cfg, "public status")
+	defer rest.Shutdown(ctx)
+
+	c := httpClient(t, nil, false)
+	httpResp, err := c.Get(httpURL(rest) + "/public/status")
+	var resp api.PublicStatus
+	processResp(t, httpResp, err, &resp)
+	if httpResp.StatusCode != http.StatusOK {
+		t.Fatal("the public status should not need authentication, got:", httpResp.StatusCode)
+	}
+	if resp.Peers != 1 || resp.Pins != 3 || resp.PinErrors != 1 || resp.Healthy {
+		t.Errorf("unexpected public status resp:\n %+v", resp)
+	}
+	if o := httpResp.Header.Get("Access-Control-Allow-Origin"); o != "*" {
+		t.Error("the public status should be readable from any origin, got:", o)
+	}
+
+	httpResp, err = c.Get(httpURL(rest) + "/public/status")
+	errResp := api.Error{}
+	processResp(t, httpResp, err, &errResp)
+	if errResp.Code != http.StatusTooManyRequests {
+		t.Error("expected 429 when the public status rate limit is exceeded, got:", errResp.Code)
+	}
+	if ra := httpResp.Header.Get("Retry-After"); ra == "" {
+		t.Error("expected a Retry-After header")
+	}
+
+	httpResp, err = c.Get(httpURL(rest) + "/cluster/info")
+	processResp(t, httpResp, err, &errResp)
+	if httpResp.StatusCode != http.StatusUnauthorized {
+		t.Error("the rest of the API should still need authentication, got:", httpResp.StatusCode)
+	}
+}
+
+func TestAPIIPFSDriftEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp api.IPFSDrift
+		makeGet(t, rest, url(rest)+"/health/ipfs", &resp)
+		if len(resp.Daemons) != 2 || len(resp.Warnings) != 1 || !resp.Dangerous {
+			t.Errorf("unexpected ipfs drift resp:\n %+v", resp)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIRuntimeStatsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []*api.RuntimeStats
+		makeGet(t, rest, url(rest)+"/health/runtime", &resp)
+		if len(resp) != 1 || resp[0].Goroutines != 100 || resp[0].OpenFDs != 30 {
+			t.Errorf("unexpected runtime stats resp:\n %+v", resp)
+		}
+
+		var resp2 []*api.RuntimeStats
+		makeGet(t, rest, url(rest)+"/health/runtime?local=true", &resp2)
+		if len(resp2) != 1 || resp2[0].Peer != test.PeerID1 {
+			t.Errorf("unexpected local runtime stats resp:\n %+v", resp2)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIRepoGCEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []*api.RepoGC
+		makePost(t, rest, url(rest)+"/ipfs/gc", []byte{}, &resp)
+		if len(resp) != 1 || resp[0].Removed != 2 {
+			t.Errorf("unexpected repo gc resp:\n %+v", resp)
+		}
+
+		var resp2 []*api.RepoGC
+		makePost(t, rest, url(rest)+"/ipfs/gc?local=true", []byte{}, &resp2)
+		if len(resp2) != 1 || resp2[0].Peer != test.PeerID1 {
+			t.Errorf("unexpected local repo gc resp:\n %+v", resp2)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIReconnectStatusEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp api.ReconnectStatus
+		makeGet(t, rest, url(rest)+"/health/reconnect", &resp)
+		if resp.Peer != test.PeerID1 || resp.State != api.ReconnectStateConnected {
+			t.Errorf("unexpected reconnect status:\n %+v", resp)
+		}
+		if resp.ConnectedPeers != 2 || resp.NextAttempt.IsZero() {
+			t.Errorf("unexpected reconnect status:\n %+v", resp)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIStorageUsageEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp api.StorageUsage
+		makeGet(t, rest, url(rest)+"/health/storage", &resp)
+		if resp.Pins != 4 || resp.SampledPins != 4 || resp.UniqueBlocks != 5 {
+			t.Errorf("unexpected storage usage resp:\n %+v", resp)
+		}
+
+		var resp2 api.StorageUsage
+		makeGet(t, rest, url(rest)+"/health/storage?sample=2", &resp2)
+		if resp2.SampledPins != 2 {
+			t.Errorf("expected 2 sampled pins:\n %+v", resp2)
+		}
+
+		errResp := api.Error{}
+		makeGet(t, rest, url(rest)+"/health/storage?sample=-1", &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("a negative sample should be rejected")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIPinEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		// test regular post
+		var pinResp api.PinResponse
+		makePost(t, rest, url(rest)+"/pins/"+test.Cid1.String(), []byte{}, &pinResp)
+		if pinResp.Duplicate {
+			t.Error("a new pin should not be a duplicate")
+		}
+		if !pinResp.Cid.Equals(test.Cid1) {
+			t.Error("expected the pin to be returned")
+		}
+		if pinResp.MaxDepth != -1 {
+			t.Error("expected a recursive pin")
+		}
+
+		// test max-depth
+		pinResp = api.PinResponse{}
+		makePost(t, rest, url(rest)+"/pins/"+test.Cid3.String()+"?max-depth=0", []byte{}, &pinResp)
+		if pinResp.MaxDepth != 0 {
+			t.Error("expected a direct pin")
+		}
+
+		errResp := api.Error{}
+		makePost(t, rest, url(rest)+"/pins/"+test.ErrorCid.String(), []byte{}, &errResp)
+		if errResp.Message != test.ErrBadCid.Error() {
+			t.Error("expected different error: ", errResp.Message)
+		}
+
+		makePost(t, rest, url(rest)+"/pins/abcd", []byte{}, &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail with bad Cid")
+		}
+
+		errResp = api.Error{}
+		makePost(t, rest, url(rest)+"/pins/"+test.Cid1.String()+"?replication-min=3&replication-max=2", []byte{}, &errResp)
+		if errResp.Code != 400 {
+			t.Error("should fail with replication-min > replication-max")
 		}
-		if cg.ClustertoIPFS[peer.IDB58Encode(pid1)] != pid4 {
-			t.Error("unexpected ipfs peer mapped to cluster peer 1 in graph")
+		if len(errResp.Details) != 1 || errResp.Details[0].Field != "replication-min" {
+			t.Errorf("expected details about replication-min: %+v", errResp.Details)
 		}
 	}
 
 	testBothEndpoints(t, tf)
 }
 
-func TestAPIPinEndpoint(t *testing.T) {
+func TestAPIPinRateLimited(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
 	defer rest.Shutdown(ctx)
 
 	tf := func(t *testing.T, url urlF) {
-		// test regular post
-		makePost(t, rest, url(rest)+"/pins/"+test.Cid1.String(), []byte{}, &struct{}{})
-
+		h := makeHost(t, rest)
+		defer h.Close()
+		c := httpClient(t, h, isHTTPS(url(rest)))
+		req, _ := http.NewRequest(http.MethodPost, url(rest)+"/pins/"+test.RateLimitedCid.String(), nil)
+		req.Header.Set("Origin", clientOrigin)
+		httpResp, err := c.Do(req)
 		errResp := api.Error{}
-		makePost(t, rest, url(rest)+"/pins/"+test.ErrorCid.String(), []byte{}, &errResp)
-		if errResp.Message != test.ErrBadCid.Error() {
-			t.Error("expected different error: ", errResp.Message)
+		processResp(t, httpResp, err, &errResp)
+		if errResp.Code != http.StatusTooManyRequests {
+			t.Error("expected 429 when the pin rate limit is exceeded, got:", errResp.Code)
 		}
-
-		makePost(t, rest, url(rest)+"/pins/abcd", []byte{}, &errResp)
-		if errResp.Code != 400 {
-			t.Error("should fail with bad Cid")
+		if ra := httpResp.Header.Get("Retry-After"); ra != "2" {
+			t.Error("expected a Retry-After header of 2 seconds, got:", ra)
+		}
+		if _, ok := api.AsRateLimitError(&errResp); !ok {
+			t.Error("clients should be able to tell rate limit errors")
 		}
 	}
 
@@ -699,6 +1494,28 @@ func TestAPIUnpinEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIRestorePinEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var pin api.Pin
+		makePost(t, rest, url(rest)+"/pins/"+test.Cid1.String()+"/restore", []byte{}, &pin)
+		if !pin.Cid.Equals(test.Cid1) {
+			t.Error("expected the same cid")
+		}
+
+		errResp := api.Error{}
+		makePost(t, rest, url(rest)+"/pins/"+test.ErrorCid.String()+"/restore", []byte{}, &errResp)
+		if errResp.Message != test.ErrBadCid.Error() {
+			t.Error("expected different error: ", errResp.Message)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestAPIUnpinEndpointWithPath(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -766,6 +1583,55 @@ func TestAPIAllocationsEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIAllocationsSinceAndSort(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []*api.Pin
+		makeGet(t, rest, url(rest)+"/allocations?since=72h", &resp)
+		if len(resp) != 2 ||
+			!resp[0].Cid.Equals(test.Cid1) || !resp[1].Cid.Equals(test.Cid2) {
+			t.Error("unexpected pin list: ", resp)
+		}
+
+		since := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+		makeGet(t, rest, url(rest)+"/allocations?since="+since, &resp)
+		if len(resp) != 1 || !resp[0].Cid.Equals(test.Cid2) {
+			t.Error("unexpected pin list: ", resp)
+		}
+
+		makeGet(t, rest, url(rest)+"/allocations?sort=created_at", &resp)
+		if len(resp) != 3 ||
+			!resp[0].Cid.Equals(test.Cid3) || !resp[1].Cid.Equals(test.Cid1) ||
+			!resp[2].Cid.Equals(test.Cid2) {
+			t.Error("unexpected pin list: ", resp)
+		}
+
+		makeGet(t, rest, url(rest)+"/allocations?sort=updated_at", &resp)
+		if len(resp) != 3 ||
+			!resp[0].Cid.Equals(test.Cid3) || !resp[1].Cid.Equals(test.Cid2) ||
+			!resp[2].Cid.Equals(test.Cid1) {
+			t.Error("unexpected pin list: ", resp)
+		}
+
+		errResp := api.Error{}
+		makeGet(t, rest, url(rest)+"/allocations?since=yesterday", &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("an invalid since value should 400")
+		}
+
+		errResp = api.Error{}
+		makeGet(t, rest, url(rest)+"/allocations?sort=name", &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("an invalid sort value should 400")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestAPIAllocationEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -788,6 +1654,59 @@ func TestAPIAllocationEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIPinDetailsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp api.PinDetails
+		makeGet(t, rest, url(rest)+"/pins/"+test.Cid1.String()+"/details", &resp)
+		if resp.Pin == nil || !resp.Pin.Cid.Equals(test.Cid1) {
+			t.Fatal("expected the pin for the cid")
+		}
+		if resp.Pin.ReplicationFactorMin != -1 {
+			t.Error("expected the pin options")
+		}
+		if resp.Status == nil || !resp.Status.Cid.Equals(test.Cid1) {
+			t.Fatal("expected the status for the cid")
+		}
+		if _, ok := resp.Status.PeerMap[peer.IDB58Encode(test.PeerID1)]; !ok {
+			t.Error("expected status info for test.PeerID1")
+		}
+
+		errResp := api.Error{}
+		makeGet(t, rest, url(rest)+"/pins/"+test.ErrorCid.String()+"/details", &errResp)
+		if errResp.Code != 404 {
+			t.Error("a non-pinned cid should 404")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIPinGatewaysEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []string
+		makeGet(t, rest, url(rest)+"/pins/"+test.Cid1.String()+"/gateways", &resp)
+		if len(resp) != 1 || resp[0] != "http://127.0.0.1:8080/ipfs/"+test.Cid1.String() {
+			t.Error("unexpected gateways: ", resp)
+		}
+
+		errResp := api.Error{}
+		makeGet(t, rest, url(rest)+"/pins/"+test.ErrorCid.String()+"/gateways", &errResp)
+		if errResp.Code != 500 {
+			t.Error("expected an error")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestAPIMetricsEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -812,6 +1731,46 @@ func TestAPIMetricsEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIRequestTimeout(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.RouteTimeouts = map[string]time.Duration{
+		"StatusAll": time.Minute,
+	}
+	rest := testAPIwithConfig(t, cfg, "timeouts")
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		h := makeHost(t, rest)
+		defer h.Close()
+		c := httpClient(t, h, isHTTPS(url(rest)))
+
+		get := func(timeout string) *http.Response {
+			req, _ := http.NewRequest(http.MethodGet, url(rest)+"/pins", nil)
+			req.Header.Set(api.RequestTimeoutHeader, timeout)
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			return resp
+		}
+
+		if resp := get("abc"); resp.StatusCode != http.StatusBadRequest {
+			t.Error("expected 400 with an invalid timeout, got:", resp.StatusCode)
+		}
+		if resp := get("-1s"); resp.StatusCode != http.StatusBadRequest {
+			t.Error("expected 400 with a negative timeout, got:", resp.StatusCode)
+		}
+		if resp := get("30s"); resp.StatusCode != http.StatusOK {
+			t.Error("expected 200 with a valid timeout, got:", resp.StatusCode)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestAPIStatusAllEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -870,6 +1829,38 @@ func TestAPIStatusAllEndpoint(t *testing.T) {
 		if errorResp.Code != http.StatusBadRequest {
 			t.Error("an invalid filter value should 400")
 		}
+
+		var errorResp2 api.Error
+		makeGet(t, rest, url(rest)+"/pins?filter=pinned,invalid", &errorResp2)
+		if errorResp2.Code != http.StatusBadRequest {
+			t.Error("a filter with an invalid value should 400")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIStatusSummaryEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp api.StatusSummary
+		makeGet(t, rest, url(rest)+"/pins/summary", &resp)
+		if len(resp.Peers) != 2 || resp.Peers[1].Error == "" {
+			t.Errorf("unexpected summary peers:\n %+v", resp.Peers)
+		}
+		if resp.Totals.Pinned != 1 || resp.Totals.Error != 1 {
+			t.Errorf("unexpected summary totals:\n %+v", resp.Totals)
+		}
+
+		var resp2 api.StatusSummary
+		makeGet(t, rest, url(rest)+"/pins/summary?local=true", &resp2)
+		if len(resp2.Peers) != 1 || resp2.Peers[0].Peer != test.PeerID1 ||
+			resp2.Totals != resp2.Peers[0].Counts {
+			t.Errorf("unexpected local summary:\n %+v", resp2)
+		}
 	}
 
 	testBothEndpoints(t, tf)
@@ -909,6 +1900,27 @@ func TestAPIStatusEndpoint(t *testing.T) {
 		if info.Status.String() != "pinned" {
 			t.Error("expected different status")
 		}
+		if resp2.Allocation != nil {
+			t.Error("allocation explanation should only be included when requested")
+		}
+
+		// Test explain=true
+		var resp3 api.GlobalPinInfo
+		makeGet(t, rest, url(rest)+"/pins/"+test.Cid1.String()+"?explain=true", &resp3)
+
+		expl := resp3.Allocation
+		if expl == nil {
+			t.Fatal("expected an allocation explanation")
+		}
+		if !expl.Cid.Equals(test.Cid1) {
+			t.Error("expected the same cid")
+		}
+		if expl.Allocator == "" {
+			t.Error("expected an allocator name")
+		}
+		if len(expl.Rejected) != 1 || expl.Rejected[0].Peer != test.PeerID2 {
+			t.Error("expected test.PeerID2 to be rejected")
+		}
 	}
 
 	testBothEndpoints(t, tf)
@@ -941,6 +1953,56 @@ func TestAPISyncAllEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIStateSyncEndpoints(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []*api.StateSync
+		makePost(t, rest, url(rest)+"/sync", []byte{}, &resp)
+		if len(resp) != 1 || resp[0].Changed != 1 {
+			t.Errorf("unexpected state sync resp:\n %+v", resp)
+		}
+
+		var resp2 []*api.StateSync
+		makePost(t, rest, url(rest)+"/sync?peer="+test.PeerID2.Pretty(), []byte{}, &resp2)
+		if len(resp2) != 1 || resp2[0].Peer != test.PeerID2 {
+			t.Errorf("unexpected state sync+peer resp:\n %+v", resp2)
+		}
+
+		var errResp api.Error
+		makePost(t, rest, url(rest)+"/sync?peer=abc", []byte{}, &errResp)
+		if errResp.Code != 400 {
+			t.Error("expected a different error")
+		}
+
+		var resp3 []*api.StateSync
+		makeGet(t, rest, url(rest)+"/sync/last?local=true", &resp3)
+		if len(resp3) != 1 || resp3[0].Peer != test.PeerID1 {
+			t.Errorf("unexpected last state sync resp:\n %+v", resp3)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
+func TestAPIAuditLogEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []*api.AuditRecord
+		makeGet(t, rest, url(rest)+"/audit", &resp)
+		if len(resp) != 1 || resp[0].Outcome != api.AuditRefused {
+			t.Errorf("unexpected audit log resp:\n %+v", resp)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestAPISyncEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -1004,6 +2066,31 @@ func TestAPIRecoverEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIProvideEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var resp []*api.ProvideResult
+		makePost(t, rest, url(rest)+"/pins/"+test.Cid1.String()+"/provide", []byte{}, &resp)
+		if len(resp) != 2 {
+			t.Fatal("expected 2 results")
+		}
+		if resp[0].Peer != test.PeerID1 {
+			t.Error("expected a result for test.PeerID1")
+		}
+
+		var errResp api.Error
+		makePost(t, rest, url(rest)+"/pins/"+test.ErrorCid.String()+"/provide", []byte{}, &errResp)
+		if errResp.Code != 500 {
+			t.Error("expected an error providing ErrorCid")
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestAPIRecoverAllEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -1017,9 +2104,21 @@ func TestAPIRecoverAllEndpoint(t *testing.T) {
 			t.Fatal("bad response length")
 		}
 
-		var errResp api.Error
-		makePost(t, rest, url(rest)+"/pins/recover", []byte{}, &errResp)
-		if errResp.Code != 400 {
+		var job api.RecoveryJob
+		makePost(t, rest, url(rest)+"/pins/recover", []byte{}, &job)
+		if job.State != api.RecoveryJobRunning || job.Peer != test.PeerID1 {
+			t.Error("expected a running recovery job")
+		}
+
+		var peerResp []*api.GlobalPinInfo
+		makePost(t, rest, url(rest)+"/pins/recover?peer="+test.PeerID1.Pretty(), []byte{}, &peerResp)
+		if len(peerResp) != 0 {
+			t.Fatal("bad response length")
+		}
+
+		var errResp2 api.Error
+		makePost(t, rest, url(rest)+"/pins/recover?peer=abc", []byte{}, &errResp2)
+		if errResp2.Code != 400 {
 			t.Error("expected a different error")
 		}
 	}
@@ -1027,6 +2126,22 @@ func TestAPIRecoverAllEndpoint(t *testing.T) {
 	testBothEndpoints(t, tf)
 }
 
+func TestAPIRecoveryStatusEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url urlF) {
+		var job api.RecoveryJob
+		makeGet(t, rest, url(rest)+"/pins/recover", &job)
+		if job.Total != 3 || job.Recovered != 1 {
+			t.Errorf("unexpected recovery job: %+v", job)
+		}
+	}
+
+	testBothEndpoints(t, tf)
+}
+
 func TestCORS(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -1103,6 +2218,10 @@ func assertHTTPStatusIsUnauthoriazed(resp *http.Response) error {
 	return httpStatusCodeChecker(resp, http.StatusUnauthorized)
 }
 
+func assertHTTPStatusIsForbidden(resp *http.Response) error {
+	return httpStatusCodeChecker(resp, http.StatusForbidden)
+}
+
 func assertHTTPStatusIsTooLarge(resp *http.Response) error {
 	return httpStatusCodeChecker(resp, http.StatusRequestHeaderFieldsTooLarge)
 }
@@ -1294,6 +2413,79 @@ func TestBasicAuth(t *testing.T) {
 	}
 }
 
+func TestBasicAuthRoles(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.BasicAuthCreds = map[string]string{
+		validUserName: validUserPassword,
+		adminUserName: adminUserPassword,
+		"norole":      "norole",
+	}
+	cfg.BasicAuthRoles = map[string]string{
+		validUserName: RoleViewer,
+		adminUserName: RoleAdmin,
+	}
+	rest := testAPIwithConfig(t, cfg, "Basic Authentication with roles")
+	defer rest.Shutdown(ctx)
+
+	for _, tc := range []httpTestcase{
+		httpTestcase{
+			method:  "GET",
+			path:    "/pins",
+			shaper:  makeBasicAuthRequestShaper(validUserName, validUserPassword),
+			checker: makeHTTPStatusNegatedAssert(assertHTTPStatusIsForbidden),
+		},
+		httpTestcase{
+			method:  "DELETE",
+			path:    "/pins/" + test.Cid1.String(),
+			shaper:  makeBasicAuthRequestShaper(validUserName, validUserPassword),
+			checker: assertHTTPStatusIsForbidden,
+		},
+		httpTestcase{
+			method:  "POST",
+			path:    "/pins/" + test.Cid1.String() + "/sync",
+			shaper:  makeBasicAuthRequestShaper(validUserName, validUserPassword),
+			checker: assertHTTPStatusIsForbidden,
+		},
+		httpTestcase{
+			method:  "DELETE",
+			path:    "/pins/" + test.Cid1.String(),
+			shaper:  makeBasicAuthRequestShaper(adminUserName, adminUserPassword),
+			checker: makeHTTPStatusNegatedAssert(assertHTTPStatusIsForbidden),
+		},
+		httpTestcase{
+			method:  "GET",
+			path:    "/id",
+			shaper:  makeBasicAuthRequestShaper("norole", "norole"),
+			checker: assertHTTPStatusIsForbidden,
+		},
+	} {
+		testBothEndpoints(t, tc.getTestFunction(rest))
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	if !roleAllows(RolePinner, routeRole("Unpin")) {
+		t.Error("pinner should be able to unpin")
+	}
+	if roleAllows(RoleViewer, routeRole("Unpin")) {
+		t.Error("viewer should not be able to unpin")
+	}
+	if roleAllows(RoleOperator, routeRole("PeerRemove")) {
+		t.Error("operator should not be able to remove peers")
+	}
+	if roleAllows(userRole(nil, "", validUserName), routeRole("ID")) {
+		t.Error("users without a role should not be allowed anything")
+	}
+	if role := userRole(nil, RoleViewer, validUserName); !roleAllows(role, routeRole("ID")) || roleAllows(role, routeRole("Pin")) {
+		t.Error("users without a role should get the default role")
+	}
+	if roleAllows("superuser", routeRole("ID")) {
+		t.Error("unknown roles should not be allowed anything")
+	}
+}
+
 func TestLimitMaxHeaderSize(t *testing.T) {
 	const maxHeaderBytes = 4 * DefaultMaxHeaderBytes
 	cfg := &Config{}
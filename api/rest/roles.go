@@ -0,0 +1,147 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	types "github.com/ipfs/ipfs-cluster/api"
+)
+
+// Roles which can be assigned to authenticated users in the
+// basic_auth_roles configuration option. Each role is allowed everything
+// that the previous ones are.
+const (
+	// RoleViewer can only use read-only endpoints.
+	RoleViewer = "viewer"
+	// RolePinner can additionally add, pin, unpin and restore content,
+	// and take pinset snapshots.
+	RolePinner = "pinner"
+	// RoleOperator can additionally sync, recover, change peer
+	// allocatability and restore or delete pinset snapshots.
+	RoleOperator = "operator"
+	// RoleAdmin can use every endpoint.
+	RoleAdmin = "admin"
+)
+
+var roleLevels = map[string]int{
+	RoleViewer:   0,
+	RolePinner:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// routeRoles sets the minimum role needed for every named route. Routes
+// not listed here need RoleAdmin.
+var routeRoles = map[string]string{
+	"ID":              RoleViewer,
+	"Version":         RoleViewer,
+	"ClusterInfo":     RoleViewer,
+	"Peers":           RoleViewer,
+	"Peerstore":       RoleViewer,
+	"Allocations":     RoleViewer,
+	"Allocation":      RoleViewer,
+	"StatusAll":       RoleViewer,
+	"StatusSummary":   RoleViewer,
+	"Status":          RoleViewer,
+	"PinDetails":      RoleViewer,
+	"PinGateways":     RoleViewer,
+	"LastStateSync":   RoleViewer,
+	"ConnectionGraph": RoleViewer,
+	"Ping":            RoleViewer,
+	"Versions":        RoleViewer,
+	"RuntimeStats":    RoleViewer,
+	"IPFSDrift":       RoleViewer,
+	"RecoveryStatus":  RoleViewer,
+	"ReconnectStatus": RoleViewer,
+	"Metrics":         RoleViewer,
+	"Snapshots":       RoleViewer,
+	"SnapshotDiff":    RoleViewer,
+
+	"Add":        RolePinner,
+	"Pin":        RolePinner,
+	"PinPath":    RolePinner,
+	"Unpin":      RolePinner,
+	"UnpinPath":  RolePinner,
+	"RestorePin": RolePinner,
+
+	"SnapshotCreate": RolePinner,
+
+	"Sync":               RoleOperator,
+	"SyncAll":            RoleOperator,
+	"Recover":            RoleOperator,
+	"RecoverAll":         RoleOperator,
+	"Provide":            RoleOperator,
+	"StateSync":          RoleOperator,
+	"PeerAllocatable":    RoleOperator,
+	"PeerMigrate":        RoleOperator,
+	"PeerNotAllocatable": RoleOperator,
+	"StorageUsage":       RoleOperator,
+	"RepoGC":             RoleOperator,
+	"SnapshotRestore":    RoleOperator,
+	"SnapshotDelete":     RoleOperator,
+}
+
+// routeRole returns the role needed to use the given route.
+func routeRole(name string) string {
+	role, ok := routeRoles[name]
+	if !ok {
+		return RoleAdmin
+	}
+	return role
+}
+
+// userRole returns the role of an authenticated user. Users without an
+// assigned role get the default one, which may be none.
+func userRole(roles map[string]string, defaultRole, username string) string {
+	role, ok := roles[username]
+	if !ok {
+		return defaultRole
+	}
+	return role
+}
+
+// roleAllows returns true when the given role includes the required one.
+// No role, or an unknown one, allows nothing.
+func roleAllows(role, required string) bool {
+	level, ok := roleLevels[role]
+	if !ok {
+		return false
+	}
+	return level >= roleLevels[required]
+}
+
+// roleHandler wraps a route handler so that it can only be used by
+// authenticated users whose role allows it. It must run after authHandler
+// has authenticated the request. Roles are not checked when none are
+// configured and only Basic Authentication is used, as every configured
+// user is then trusted.
+func roleHandler(cfg *Config, routeName string, h http.Handler) http.Handler {
+	if !cfg.usesRoles() {
+		return h
+	}
+
+	required := routeRole(routeName)
+	wrap := func(w http.ResponseWriter, r *http.Request) {
+		role := userRole(cfg.BasicAuthRoles, cfg.DefaultRole, authenticatedUser(r))
+		if !roleAllows(role, required) {
+			resp, err := forbiddenResp()
+			if err != nil {
+				logger.Error(err)
+				return
+			}
+			http.Error(w, resp, http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(wrap)
+}
+
+func forbiddenResp() (string, error) {
+	apiError := &types.Error{
+		Code:    http.StatusForbidden,
+		Message: "Forbidden",
+	}
+	resp, err := json.Marshal(apiError)
+	return string(resp), err
+}
@@ -9,7 +9,9 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -23,6 +25,7 @@ import (
 	proto "github.com/gogo/protobuf/proto"
 	cid "github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log"
+	crypto "github.com/libp2p/go-libp2p-crypto"
 	peer "github.com/libp2p/go-libp2p-peer"
 	protocol "github.com/libp2p/go-libp2p-protocol"
 	multiaddr "github.com/multiformats/go-multiaddr"
@@ -162,6 +165,25 @@ func TrackerStatusFromString(str string) TrackerStatus {
 	return status
 }
 
+// TrackerStatusFilterFromString parses a comma-separated list of status
+// names into a TrackerStatus filter. Unlike TrackerStatusFromString, it
+// returns an error when any of the names is unknown. An empty string
+// results in TrackerStatusUndefined, which matches everything.
+func TrackerStatusFilterFromString(str string) (TrackerStatus, error) {
+	var filter TrackerStatus
+	if strings.TrimSpace(str) == "" {
+		return filter, nil
+	}
+	for _, v := range strings.Split(strings.Replace(str, " ", "", -1), ",") {
+		st, ok := stringTrackerStatus[v]
+		if !ok {
+			return TrackerStatusUndefined, fmt.Errorf("invalid filter value: %q", v)
+		}
+		filter |= st
+	}
+	return filter, nil
+}
+
 // TrackerStatusAll all known TrackerStatus values.
 func TrackerStatusAll() []TrackerStatus {
 	var list []TrackerStatus
@@ -246,6 +268,14 @@ type GlobalPinInfo struct {
 	// Peer IDs are of string Kind(). We can't use peer IDs here
 	// as Go ignores TextMarshaler.
 	PeerMap map[string]*PinInfo `json:"peer_map" codec:"pm,omitempty"`
+	// Allocation is only set when the allocation decision for the Cid
+	// has been explicitly requested.
+	Allocation *AllocationExplanation `json:"allocation,omitempty" codec:"al,omitempty"`
+	// Results holds the outcome of the request made to every peer to
+	// obtain its PinInfo.
+	Results []*PeerResult `json:"results,omitempty" codec:"r,omitempty"`
+	// Shards summarizes the status of the shards of sharded pins.
+	Shards *ShardsStatus `json:"shards,omitempty" codec:"s,omitempty"`
 }
 
 // String returns the string representation of a GlobalPinInfo.
@@ -258,6 +288,30 @@ func (gpi *GlobalPinInfo) String() string {
 	return str
 }
 
+// ShardStatus describes where a shard of a sharded pin is allocated and
+// which peers have it pinned.
+type ShardStatus struct {
+	Cid         cid.Cid   `json:"cid" codec:"c"`
+	Allocations []peer.ID `json:"allocations" codec:"a,omitempty"`
+	Pinned      []peer.ID `json:"pinned" codec:"p,omitempty"`
+}
+
+// ShardsStatus summarizes the status of the shards of a sharded pin. Shards
+// which no peer has pinned are missing.
+type ShardsStatus struct {
+	Total   int            `json:"total" codec:"t,omitempty"`
+	Pinned  int            `json:"pinned" codec:"p,omitempty"`
+	Missing []cid.Cid      `json:"missing" codec:"m,omitempty"`
+	Shards  []*ShardStatus `json:"shards" codec:"s,omitempty"`
+}
+
+// PinDetails combines a Pin from the shared state with its current status
+// in every cluster peer.
+type PinDetails struct {
+	Pin    *Pin           `json:"pin" codec:"p"`
+	Status *GlobalPinInfo `json:"status" codec:"s"`
+}
+
 // PinInfo holds information about local pins.
 type PinInfo struct {
 	Cid      cid.Cid       `json:"cid" codec:"c"`
@@ -266,6 +320,207 @@ type PinInfo struct {
 	Status   TrackerStatus `json:"status" codec:"st,omitempty"`
 	TS       time.Time     `json:"timestamp" codec:"ts,omitempty"`
 	Error    string        `json:"error" codec:"e,omitempty"`
+	// Bitswap is only set for pins which are being pinned and helps
+	// following the progress of the operation.
+	Bitswap *IPFSBitswapStat `json:"bitswap,omitempty" codec:"b,omitempty"`
+	// Paused is set for queued operations which wait because the pin
+	// tracker of the peer is paused.
+	Paused bool `json:"paused,omitempty" codec:"pa,omitempty"`
+	// Progress is only set for pins which are being pinned and tells
+	// how much of the DAG has been fetched so far.
+	Progress *PinProgress `json:"progress,omitempty" codec:"pg,omitempty"`
+}
+
+// AllocationExplanation records how the allocations for a Cid were
+// decided: the allocator and the metrics it was given and the peers which
+// were not chosen, along with the reason.
+type AllocationExplanation struct {
+	Cid cid.Cid `json:"cid" codec:"c"`
+	// The peer which made the allocation decision.
+	Peer        peer.ID         `json:"peer" codec:"p,omitempty"`
+	Allocator   string          `json:"allocator" codec:"a,omitempty"`
+	Informer    string          `json:"informer" codec:"i,omitempty"`
+	Metrics     []*Metric       `json:"metrics" codec:"m,omitempty"`
+	Allocations []peer.ID       `json:"allocations" codec:"al,omitempty"`
+	Rejected    []*RejectedPeer `json:"rejected" codec:"r,omitempty"`
+	TS          time.Time       `json:"timestamp" codec:"ts,omitempty"`
+}
+
+// RejectedPeer is a peer which was not allocated a Cid and the reason why.
+type RejectedPeer struct {
+	Peer   peer.ID `json:"peer" codec:"p,omitempty"`
+	Reason string  `json:"reason" codec:"r,omitempty"`
+}
+
+// Reject adds a peer to the list of rejected peers with the given reason.
+func (ae *AllocationExplanation) Reject(p peer.ID, reason string) {
+	ae.Rejected = append(ae.Rejected, &RejectedPeer{
+		Peer:   p,
+		Reason: reason,
+	})
+}
+
+// StateSync describes a run of the state sync process on a peer, which
+// makes sure that its pin tracker follows the shared state.
+type StateSync struct {
+	Peer     peer.ID   `json:"peer" codec:"p,omitempty"`
+	PeerName string    `json:"peername" codec:"pn,omitempty"`
+	Start    time.Time `json:"start" codec:"s,omitempty"`
+	End      time.Time `json:"end" codec:"e,omitempty"`
+	// Changed is the number of items which were tracked, untracked or
+	// re-tracked because of the sync.
+	Changed int    `json:"changed" codec:"c,omitempty"`
+	Error   string `json:"error" codec:"er,omitempty"`
+}
+
+// Outcomes of the operations in the audit log.
+const (
+	AuditRefused   = "refused"
+	AuditPending   = "pending confirmation"
+	AuditForced    = "forced"
+	AuditConfirmed = "confirmed"
+	AuditCompleted = "completed"
+	AuditFailed    = "failed"
+)
+
+// AuditRecord is an entry of the audit log, which records destructive
+// operations run by a peer, like bulk unpins, and their outcome.
+type AuditRecord struct {
+	Time      time.Time `json:"time" codec:"t,omitempty"`
+	Peer      peer.ID   `json:"peer" codec:"p,omitempty"`
+	Operation string    `json:"operation" codec:"o,omitempty"`
+	Outcome   string    `json:"outcome" codec:"r,omitempty"`
+	Details   string    `json:"details,omitempty" codec:"d,omitempty"`
+}
+
+// BulkUnpinOptions are set by requests which may unpin many items at once.
+// Force lets them unpin more than the configured threshold. When
+// confirmations are required, Confirm carries the token returned by a first
+// forced attempt (see BulkUnpinError).
+type BulkUnpinOptions struct {
+	Force   bool   `json:"force,omitempty" codec:"f,omitempty"`
+	Confirm string `json:"confirm,omitempty" codec:"c,omitempty"`
+}
+
+// BulkUnpinError is returned when an operation would unpin more items than
+// allowed in one go without being forced. When Token is set, the operation
+// was forced but must be confirmed by repeating it with the token.
+type BulkUnpinError struct {
+	Operation string
+	Unpins    int
+	Total     int
+	Limit     int
+	Token     string
+}
+
+// Error implements the error interface.
+func (e *BulkUnpinError) Error() string {
+	msg := fmt.Sprintf(
+		"%s would unpin %d of %d pins, over the limit of %d",
+		e.Operation,
+		e.Unpins,
+		e.Total,
+		e.Limit,
+	)
+	if e.Token != "" {
+		return msg + ": confirm with token " + e.Token
+	}
+	return msg + ": it must be forced"
+}
+
+var bulkUnpinErrorRegexp = regexp.MustCompile(`(.+) would unpin ([0-9]+) of ([0-9]+) pins, over the limit of ([0-9]+): (?:it must be forced|confirm with token ([0-9a-f]+))`)
+
+// AsBulkUnpinError returns the BulkUnpinError carried by err. Errors
+// received over RPC only keep their message, so it is parsed when needed.
+func AsBulkUnpinError(err error) (*BulkUnpinError, bool) {
+	if err == nil {
+		return nil, false
+	}
+	if buErr, ok := err.(*BulkUnpinError); ok {
+		return buErr, true
+	}
+	m := bulkUnpinErrorRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil, false
+	}
+	unpins, _ := strconv.Atoi(m[2])
+	total, _ := strconv.Atoi(m[3])
+	limit, _ := strconv.Atoi(m[4])
+	return &BulkUnpinError{
+		Operation: m[1],
+		Unpins:    unpins,
+		Total:     total,
+		Limit:     limit,
+		Token:     m[5],
+	}, true
+}
+
+// StatusCounts counts tracked items by their status. Items in any error
+// state are counted as Error, and items waiting to be pinned or unpinned as
+// Queued.
+type StatusCounts struct {
+	Pinned    int `json:"pinned" codec:"p,omitempty"`
+	Pinning   int `json:"pinning" codec:"pg,omitempty"`
+	Unpinning int `json:"unpinning" codec:"ug,omitempty"`
+	Queued    int `json:"queued" codec:"q,omitempty"`
+	Error     int `json:"error" codec:"e,omitempty"`
+	Remote    int `json:"remote" codec:"r,omitempty"`
+}
+
+// Count adds an item with the given status to the counts. Statuses not
+// covered by StatusCounts are ignored.
+func (sc *StatusCounts) Count(st TrackerStatus) {
+	switch {
+	case st == TrackerStatusPinned:
+		sc.Pinned++
+	case st == TrackerStatusPinning:
+		sc.Pinning++
+	case st == TrackerStatusUnpinning:
+		sc.Unpinning++
+	case st.Match(TrackerStatusQueued):
+		sc.Queued++
+	case st.Match(TrackerStatusError):
+		sc.Error++
+	case st == TrackerStatusRemote:
+		sc.Remote++
+	}
+}
+
+// Add adds the given counts to these ones.
+func (sc *StatusCounts) Add(sc2 StatusCounts) {
+	sc.Pinned += sc2.Pinned
+	sc.Pinning += sc2.Pinning
+	sc.Unpinning += sc2.Unpinning
+	sc.Queued += sc2.Queued
+	sc.Error += sc2.Error
+	sc.Remote += sc2.Remote
+}
+
+// PeerStatusSummary holds the status counts of the items tracked by a
+// cluster peer. Error is set when the peer could not be contacted.
+type PeerStatusSummary struct {
+	Peer     peer.ID      `json:"peer" codec:"p,omitempty"`
+	Peername string       `json:"peername" codec:"pn,omitempty"`
+	Counts   StatusCounts `json:"counts" codec:"c,omitempty"`
+	Error    string       `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// StatusSummary holds the status counts of every cluster peer and their
+// totals. Each item is counted once per peer tracking it.
+type StatusSummary struct {
+	Totals StatusCounts         `json:"totals" codec:"t,omitempty"`
+	Peers  []*PeerStatusSummary `json:"peers" codec:"p,omitempty"`
+}
+
+// PingResult describes the result of pinging a cluster peer over RPC.
+type PingResult struct {
+	Peer peer.ID `json:"peer" codec:"p,omitempty"`
+	// RTT is the round-trip time of the RPC request.
+	RTT time.Duration `json:"rtt" codec:"r,omitempty"`
+	// ClockSkew is the estimated offset of the peer's clock from the
+	// local one. Positive values mean that the peer's clock is ahead.
+	ClockSkew time.Duration `json:"clock_skew" codec:"cs,omitempty"`
+	Error     string        `json:"error" codec:"er,omitempty"`
 }
 
 // Version holds version information
@@ -293,6 +548,11 @@ type ConnectGraph struct {
 	ClusterLinks map[string][]peer.ID `json:"cluster_links" codec:"cl,omitempty"`
 	// cluster to ipfs links
 	ClustertoIPFS map[string]peer.ID `json:"cluster_to_ipfs" codec:"ci,omitempty"`
+	// latencies of the cluster to cluster links, as measured by
+	// each cluster peer
+	ClusterLatencies map[string]map[string]time.Duration `json:"cluster_latencies,omitempty" codec:"lt,omitempty"`
+	// errors reaching the ipfs daemon of cluster peers
+	IPFSErrors map[string]string `json:"ipfs_errors,omitempty" codec:"ie,omitempty"`
 }
 
 // Multiaddr is a concrete type to wrap a Multiaddress so that it knows how to
@@ -344,7 +604,13 @@ func (maddr Multiaddr) Value() multiaddr.Multiaddr {
 	return maddr.Multiaddr
 }
 
-// ID holds information about the Cluster peer
+// ID holds information about the Cluster peer. FreeSpace, PinQueue and
+// PinErrors describe the health of the peer: the space left in its IPFS
+// repository, the number of operations waiting in its pin tracker and the
+// number of items in error state. TrackerPaused is set while the pin
+// tracker of the peer is paused and does not start queued operations.
+// LastHeartbeat is when the peer reporting the ID last received a heartbeat
+// from this one, and it is only set by Peers().
 type ID struct {
 	ID                    peer.ID     `json:"id" codec:"i,omitempty"`
 	Addresses             []Multiaddr `json:"addresses" codec:"a,omitempty"`
@@ -353,17 +619,382 @@ type ID struct {
 	Version               string      `json:"version" codec:"v,omitempty"`
 	Commit                string      `json:"commit" codec:"c,omitempty"`
 	RPCProtocolVersion    protocol.ID `json:"rpc_protocol_version" codec:"rv,omitempty"`
+	StateVersion          int         `json:"state_version" codec:"sv,omitempty"`
 	Error                 string      `json:"error" codec:"e,omitempty"`
 	IPFS                  *IPFSID     `json:"ipfs,omitempty" codec:"ip,omitempty"`
 	Peername              string      `json:"peername" codec:"pn,omitempty"`
+	Allocatable           bool        `json:"allocatable" codec:"al,omitempty"`
+	FreeSpace             uint64      `json:"free_space" codec:"fs,omitempty"`
+	PinQueue              int         `json:"pin_queue" codec:"pq,omitempty"`
+	PinErrors             int         `json:"pin_errors" codec:"pe,omitempty"`
+	TrackerPaused         bool        `json:"tracker_paused" codec:"tp,omitempty"`
+	LastHeartbeat         time.Time   `json:"last_heartbeat" codec:"lh,omitempty"`
 	//PublicKey          crypto.PubKey
 }
 
+// KnownPeer describes a peer in the peerstore of a cluster peer: the
+// addresses known for it, whether there is a connection to it and the last
+// time it was seen connected.
+type KnownPeer struct {
+	ID        peer.ID     `json:"id" codec:"i,omitempty"`
+	Addresses []Multiaddr `json:"addresses" codec:"a,omitempty"`
+	Connected bool        `json:"connected" codec:"c,omitempty"`
+	LastSeen  time.Time   `json:"last_seen" codec:"l,omitempty"`
+}
+
+// States of the reconnection manager reported in ReconnectStatus.
+const (
+	// ReconnectStateConnected means that some other cluster peer is
+	// connected.
+	ReconnectStateConnected = "connected"
+	// ReconnectStateReconnecting means that no other cluster peer is
+	// connected and reconnection attempts are being made.
+	ReconnectStateReconnecting = "reconnecting"
+	// ReconnectStateAlone means that no other cluster peers or bootstrap
+	// addresses are known, so there is nobody to reconnect to.
+	ReconnectStateAlone = "alone"
+	// ReconnectStateDisabled means that reconnecting is disabled in the
+	// configuration.
+	ReconnectStateDisabled = "disabled"
+)
+
+// ReconnectStatus reports how a cluster peer is doing at keeping in contact
+// with the rest of the cluster. Attempts counts the consecutive failed
+// reconnection attempts since the last time another peer was connected.
+type ReconnectStatus struct {
+	Peer           peer.ID   `json:"peer" codec:"p,omitempty"`
+	State          string    `json:"state" codec:"s,omitempty"`
+	ConnectedPeers int       `json:"connected_peers" codec:"c,omitempty"`
+	KnownPeers     int       `json:"known_peers" codec:"k,omitempty"`
+	Attempts       int       `json:"attempts" codec:"a,omitempty"`
+	LastConnected  time.Time `json:"last_connected" codec:"lc,omitempty"`
+	LastAttempt    time.Time `json:"last_attempt" codec:"la,omitempty"`
+	NextAttempt    time.Time `json:"next_attempt" codec:"na,omitempty"`
+	LastError      string    `json:"last_error,omitempty" codec:"e,omitempty"`
+}
+
+// LogEntry is a log message captured by a cluster peer. Component is the
+// logging facility that emitted it (i.e. "pintracker").
+type LogEntry struct {
+	Time      time.Time `json:"time" codec:"t,omitempty"`
+	Component string    `json:"component" codec:"c,omitempty"`
+	Level     string    `json:"level" codec:"l,omitempty"`
+	Message   string    `json:"message" codec:"m,omitempty"`
+}
+
+// LogQuery selects the captured log entries of a component (all of them
+// when empty) with the given level or a more severe one (all levels when
+// empty).
+type LogQuery struct {
+	Component string `json:"component" codec:"c,omitempty"`
+	Level     string `json:"level" codec:"l,omitempty"`
+}
+
+// logLevels maps log level names to their severity (lower is more
+// severe), as used by the logging library.
+var logLevels = map[string]int{
+	"critical": 0,
+	"error":    1,
+	"warning":  2,
+	"warn":     2,
+	"notice":   3,
+	"info":     4,
+	"debug":    5,
+}
+
+// LogLevelSeverity returns the severity of the given log level name, where
+// lower numbers are more severe. Names are case-insensitive and "warn" can
+// be used for "warning". An empty level is the least severe (debug).
+func LogLevelSeverity(level string) (int, error) {
+	if level == "" {
+		return logLevels["debug"], nil
+	}
+	sev, ok := logLevels[strings.ToLower(level)]
+	if !ok {
+		return 0, fmt.Errorf("unknown log level: %s", level)
+	}
+	return sev, nil
+}
+
+// PeerVersion describes the versions run by a cluster peer and whether they
+// are compatible with those of the peer reporting them. Error is set when
+// they are not, or when the peer could not be contacted.
+type PeerVersion struct {
+	Peer               peer.ID     `json:"peer" codec:"p,omitempty"`
+	Peername           string      `json:"peername" codec:"pn,omitempty"`
+	Version            string      `json:"version" codec:"v,omitempty"`
+	RPCProtocolVersion protocol.ID `json:"rpc_protocol_version" codec:"rv,omitempty"`
+	StateVersion       int         `json:"state_version" codec:"sv,omitempty"`
+	Compatible         bool        `json:"compatible" codec:"c,omitempty"`
+	Error              string      `json:"error" codec:"e,omitempty"`
+}
+
+// ClusterInfo holds aggregate facts about the whole cluster, meant for
+// monitoring. PinErrors adds up the items in error state in every peer and
+// FreeSpace the space left in the IPFS repositories of every peer which
+// could be contacted (Peers minus UnreachablePeers). Leader is empty for
+// consensus components without a leader. UpdatedAt is when the facts were
+// gathered, as they are cached for a short time.
+type ClusterInfo struct {
+	Peers            int       `json:"peers" codec:"p,omitempty"`
+	UnreachablePeers int       `json:"unreachable_peers" codec:"up,omitempty"`
+	Pins             int       `json:"pins" codec:"pi,omitempty"`
+	PinErrors        int       `json:"pin_errors" codec:"pe,omitempty"`
+	FreeSpace        uint64    `json:"free_space" codec:"fs,omitempty"`
+	Consensus        string    `json:"consensus" codec:"c,omitempty"`
+	Leader           peer.ID   `json:"leader,omitempty" codec:"l,omitempty"`
+	OldestVersion    string    `json:"oldest_version" codec:"ov,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at" codec:"u,omitempty"`
+}
+
+// PublicStatus is the subset of ClusterInfo which can be shown to anyone,
+// i.e. in the public status page of a collaborative cluster. Healthy is
+// true when every peer could be contacted and no item is in error state.
+type PublicStatus struct {
+	Peers            int       `json:"peers" codec:"p,omitempty"`
+	UnreachablePeers int       `json:"unreachable_peers" codec:"up,omitempty"`
+	Pins             int       `json:"pins" codec:"pi,omitempty"`
+	PinErrors        int       `json:"pin_errors" codec:"pe,omitempty"`
+	Healthy          bool      `json:"healthy" codec:"h,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at" codec:"u,omitempty"`
+}
+
+// PublicStatus returns the public subset of the ClusterInfo.
+func (ci *ClusterInfo) PublicStatus() *PublicStatus {
+	return &PublicStatus{
+		Peers:            ci.Peers,
+		UnreachablePeers: ci.UnreachablePeers,
+		Pins:             ci.Pins,
+		PinErrors:        ci.PinErrors,
+		Healthy:          ci.UnreachablePeers == 0 && ci.PinErrors == 0,
+		UpdatedAt:        ci.UpdatedAt,
+	}
+}
+
+// RuntimeStats describes the Go runtime of a cluster peer: its goroutines,
+// heap and garbage collector usage and open file descriptors. OpenFDs is
+// -1 when the number of open file descriptors cannot be obtained.
+type RuntimeStats struct {
+	Peer        peer.ID       `json:"peer" codec:"p,omitempty"`
+	Peername    string        `json:"peername" codec:"pn,omitempty"`
+	Goroutines  int           `json:"goroutines" codec:"g,omitempty"`
+	HeapAlloc   uint64        `json:"heap_alloc" codec:"ha,omitempty"`
+	HeapSys     uint64        `json:"heap_sys" codec:"hs,omitempty"`
+	NumGC       uint32        `json:"num_gc" codec:"ng,omitempty"`
+	LastGCPause time.Duration `json:"last_gc_pause" codec:"gp,omitempty"`
+	OpenFDs     int           `json:"open_fds" codec:"fd,omitempty"`
+	Error       string        `json:"error" codec:"e,omitempty"`
+}
+
+// OperationStats summarizes the pintracker operations of a peer which are
+// in the given status: how many there are and how long the oldest of them
+// has been in it. Pin and unpin errors are reported together, with the
+// TrackerStatusError status.
+type OperationStats struct {
+	Status    TrackerStatus `json:"status" codec:"st,omitempty"`
+	Count     int           `json:"count" codec:"c,omitempty"`
+	OldestAge time.Duration `json:"oldest_age" codec:"a,omitempty"`
+}
+
+// RPCOutcome describes how an RPC request made to a peer ended.
+type RPCOutcome string
+
+// RPCOutcome values.
+const (
+	RPCSuccess RPCOutcome = "success"
+	RPCError   RPCOutcome = "error"
+	// The peer did not answer in time.
+	RPCTimeout RPCOutcome = "timeout"
+)
+
+// PeerResult describes the outcome of the request made to one of the
+// peers involved in an operation which contacts several of them.
+type PeerResult struct {
+	Peer    peer.ID    `json:"peer" codec:"p,omitempty"`
+	Outcome RPCOutcome `json:"outcome" codec:"o,omitempty"`
+	Error   string     `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// ProvideResult describes the outcome of asking the IPFS daemon of a
+// cluster peer to announce that it provides some content.
+type ProvideResult struct {
+	Peer  peer.ID `json:"peer" codec:"p,omitempty"`
+	Error string  `json:"error" codec:"e,omitempty"`
+}
+
+// RepoGC describes the outcome of a garbage collection run on the IPFS
+// daemon of a cluster peer. Removed is the number of blocks removed.
+type RepoGC struct {
+	Peer     peer.ID `json:"peer" codec:"p,omitempty"`
+	Peername string  `json:"peername" codec:"pn,omitempty"`
+	Removed  int     `json:"removed" codec:"r,omitempty"`
+	Error    string  `json:"error" codec:"e,omitempty"`
+}
+
+// States of a RecoveryJob.
+const (
+	RecoveryJobRunning = "running"
+	RecoveryJobDone    = "done"
+	RecoveryJobFailed  = "failed"
+)
+
+// RecoveryJob describes the recovery of the items in error state across
+// the cluster, run in the background by a cluster peer. Items are recovered
+// a few at a time, those pinned by fewer peers first. Recovered and Failed
+// count the finished items out of Total, and Errors holds the first
+// failures. Error is set when the job failed as a whole.
+type RecoveryJob struct {
+	Peer      peer.ID   `json:"peer" codec:"p,omitempty"`
+	State     string    `json:"state" codec:"s,omitempty"`
+	Total     int       `json:"total" codec:"t,omitempty"`
+	Recovered int       `json:"recovered" codec:"r,omitempty"`
+	Failed    int       `json:"failed" codec:"f,omitempty"`
+	Errors    []string  `json:"errors,omitempty" codec:"es,omitempty"`
+	Started   time.Time `json:"started" codec:"st,omitempty"`
+	Finished  time.Time `json:"finished" codec:"fi,omitempty"`
+	Error     string    `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// IPFSDaemonInfo describes the IPFS daemon of a cluster peer: its version
+// and the configuration values which should match across the cluster
+// (Routing.Type and Datastore.StorageMax). Error is set when they could not
+// be obtained.
+type IPFSDaemonInfo struct {
+	Peer       peer.ID `json:"peer" codec:"p,omitempty"`
+	Peername   string  `json:"peername" codec:"pn,omitempty"`
+	Version    string  `json:"version" codec:"v,omitempty"`
+	Routing    string  `json:"routing" codec:"r,omitempty"`
+	StorageMax string  `json:"storage_max" codec:"s,omitempty"`
+	Error      string  `json:"error" codec:"e,omitempty"`
+}
+
+// IPFSDrift reports the IPFS daemons of the cluster peers and the
+// differences between them. Dangerous is set when some of those
+// differences are likely to cause problems (IPFS versions with a different
+// major or minor number, or different routing types).
+type IPFSDrift struct {
+	Daemons   []*IPFSDaemonInfo `json:"daemons" codec:"d,omitempty"`
+	Warnings  []string          `json:"warnings" codec:"w,omitempty"`
+	Dangerous bool              `json:"dangerous" codec:"da,omitempty"`
+}
+
+// PeerMigration describes the re-allocation of the pins of a peer before
+// it is removed: the pins moved to other peers, with their new
+// allocations, and the errors for those which could not be moved.
+type PeerMigration struct {
+	Peer   peer.ID  `json:"peer" codec:"p,omitempty"`
+	Pins   []*Pin   `json:"pins" codec:"pi,omitempty"`
+	Errors []string `json:"errors,omitempty" codec:"e,omitempty"`
+}
+
+// BlockFetch asks a peer to fetch the blocks of a pin directly from other
+// cluster peers, trying the given sources in order.
+type BlockFetch struct {
+	Cid     cid.Cid   `json:"cid" codec:"c"`
+	Sources []peer.ID `json:"sources,omitempty" codec:"s,omitempty"`
+}
+
+// BlockTransfer describes the blocks of a pin fetched from another
+// cluster peer.
+type BlockTransfer struct {
+	Cid    cid.Cid `json:"cid" codec:"c"`
+	Source peer.ID `json:"source" codec:"p,omitempty"`
+	Blocks int     `json:"blocks" codec:"n,omitempty"`
+	Bytes  uint64  `json:"bytes" codec:"b,omitempty"`
+}
+
+// PeerAllocatable is used to set whether a peer is a candidate for new
+// allocations.
+type PeerAllocatable struct {
+	Peer        peer.ID `json:"peer" codec:"p,omitempty"`
+	Allocatable bool    `json:"allocatable" codec:"a,omitempty"`
+}
+
+// TrackerPause is used to pause or resume the pin tracker of a peer.
+type TrackerPause struct {
+	Peer   peer.ID `json:"peer" codec:"p,omitempty"`
+	Paused bool    `json:"paused" codec:"a,omitempty"`
+}
+
+// ConnectionFilter lists peers and IP ranges (in CIDR notation) used to
+// allow or deny libp2p connections to a cluster peer.
+type ConnectionFilter struct {
+	Peers []peer.ID `json:"peers" codec:"p,omitempty"`
+	CIDRs []string  `json:"cidrs" codec:"c,omitempty"`
+}
+
+// FaultRule makes a cluster peer with fault injection enabled delay or fail
+// a percentage of the IPFS connector calls ("ipfs" target) or of the RPC
+// requests it receives from other peers ("rpc" target). Method restricts
+// the rule to a single IPFS connector method (i.e. "Pin") or RPC endpoint
+// (i.e. "PinTracker.Track"). When empty, the rule applies to all of them.
+type FaultRule struct {
+	Target  string        `json:"target" codec:"t,omitempty"`
+	Method  string        `json:"method,omitempty" codec:"m,omitempty"`
+	Percent float64       `json:"percent" codec:"p,omitempty"`
+	Delay   time.Duration `json:"delay,omitempty" codec:"d,omitempty"`
+	Error   string        `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// JoinToken is a signed, short-lived token which lets a new peer bootstrap
+// to a cluster. It carries the addresses of the peer that created it and
+// the cluster secret.
+type JoinToken struct {
+	Token   string    `json:"token" codec:"t,omitempty"`
+	Expires time.Time `json:"expires" codec:"e,omitempty"`
+}
+
+// Snapshot describes a named copy of the pinset, stored by the peer which
+// took it, to which the pinset can be restored.
+type Snapshot struct {
+	Name      string    `json:"name" codec:"n,omitempty"`
+	CreatedAt time.Time `json:"created_at" codec:"c,omitempty"`
+	Pins      int       `json:"pins" codec:"p,omitempty"`
+}
+
+// SnapshotCurrent names the current pinset when diffing snapshots.
+const SnapshotCurrent = "current"
+
+// SnapshotDiffQuery selects the pinsets compared by a snapshot diff. Either
+// can be SnapshotCurrent.
+type SnapshotDiffQuery struct {
+	From string `json:"from" codec:"f,omitempty"`
+	To   string `json:"to" codec:"t,omitempty"`
+}
+
+// SnapshotDiff lists the operations which turn the From pinset into the
+// To one: the pins of To which are missing from From or have different
+// options there, and the pins of From which are not in To. Allocations
+// are not compared.
+type SnapshotDiff struct {
+	From  string `json:"from" codec:"f,omitempty"`
+	To    string `json:"to" codec:"t,omitempty"`
+	Pin   []*Pin `json:"pin" codec:"p,omitempty"`
+	Unpin []*Pin `json:"unpin" codec:"u,omitempty"`
+}
+
+// SnapshotRestoreQuery selects the snapshot to restore the pinset to.
+// Restores which would unpin many items need the bulk unpin options.
+type SnapshotRestoreQuery struct {
+	Name    string           `json:"name" codec:"n,omitempty"`
+	Options BulkUnpinOptions `json:"options" codec:"o,omitempty"`
+}
+
+// SnapshotRestore is the result of restoring the pinset to a snapshot.
+type SnapshotRestore struct {
+	Name     string    `json:"name" codec:"n,omitempty"`
+	Pinned   []cid.Cid `json:"pinned" codec:"p,omitempty"`
+	Unpinned []cid.Cid `json:"unpinned" codec:"u,omitempty"`
+	// Errors describes the operations which failed.
+	Errors []string `json:"errors,omitempty" codec:"e,omitempty"`
+}
+
 // IPFSID is used to store information about the underlying IPFS daemon
 type IPFSID struct {
-	ID        peer.ID     `json:"id,omitempty" codec:"i,omitempty"`
-	Addresses []Multiaddr `json:"addresses" codec:"a,omitempty"`
-	Error     string      `json:"error" codec:"e,omitempty"`
+	ID         peer.ID     `json:"id,omitempty" codec:"i,omitempty"`
+	Addresses  []Multiaddr `json:"addresses" codec:"a,omitempty"`
+	GatewayURL string      `json:"gateway_url,omitempty" codec:"gw,omitempty"`
+	Error      string      `json:"error" codec:"e,omitempty"`
 }
 
 // PinType specifies which sort of Pin object we are dealing with.
@@ -373,17 +1004,19 @@ type IPFSID struct {
 // A sharded Pin would look like:
 //
 // [ Meta ] (not pinned on IPFS, only present in cluster state)
-//   |
-//   v
+//
+//	|
+//	v
+//
 // [ Cluster DAG ] (pinned everywhere in "direct")
-//   |      ..  |
-//   v          v
+//
+//	|      ..  |
+//	v          v
+//
 // [Shard1] .. [ShardN] (allocated to peers and pinned with max-depth=1
 // | | .. |    | | .. |
 // v v .. v    v v .. v
 // [][]..[]    [][]..[] Blocks (indirectly pinned on ipfs, not tracked in cluster)
-//
-//
 type PinType uint64
 
 // PinType values. See PinType documentation for further explanation.
@@ -462,6 +1095,84 @@ type PinOptions struct {
 	ShardSize            uint64            `json:"shard_size" codec:"s,omitempty"`
 	UserAllocations      []peer.ID         `json:"user_allocations" codec:"ua,omitempty"`
 	Metadata             map[string]string `json:"metadata" codec:"m,omitempty"`
+
+	// Depth requests a non-recursive pin. 0 pins the root block
+	// only and a positive value limits how deep the DAG is pinned.
+	// It is applied to the Pin's MaxDepth by PinWithOpts. nil or
+	// negative values pin recursively.
+	Depth *int `json:"depth,omitempty" codec:"dp,omitempty"`
+
+	// Signature authorizes the request on peers which require signed
+	// pins. It is stored along with the pin.
+	Signature *PinSignature `json:"signature,omitempty" codec:"sg,omitempty"`
+
+	// Provenance describes the last submission of the pin. It is set
+	// by the peers receiving requests and never parsed from queries.
+	Provenance *PinProvenance `json:"provenance,omitempty" codec:"pv,omitempty"`
+
+	// ScheduleAt defers the pin until the given time: it is kept in the
+	// shared state, but it is not allocated nor pinned before.
+	ScheduleAt time.Time `json:"schedule_at" codec:"sca,omitempty"`
+
+	// Recurrence is a cron expression (see the cron package) on which
+	// pins of a mutable path (RecurrencePath) are refreshed: the path is
+	// resolved again and its new CID replaces the pinned one.
+	// RecurrencePath is set by the peers receiving the path pin
+	// requests and never parsed from queries.
+	Recurrence     string `json:"recurrence,omitempty" codec:"rc,omitempty"`
+	RecurrencePath string `json:"recurrence_path,omitempty" codec:"rcp,omitempty"`
+
+	// Priority sets the order in which queued pin operations are
+	// processed by the stateless pintracker: operations with a higher
+	// priority go first. 0 is the default.
+	Priority int `json:"priority,omitempty" codec:"pr,omitempty"`
+
+	// PinTimeout limits how long the pin and unpin operations of this
+	// pin may take on each peer, before they are cancelled and set in
+	// error. 0 leaves it to the pintracker configuration.
+	PinTimeout time.Duration `json:"pin_timeout,omitempty" codec:"pto,omitempty"`
+
+	// badScheduleAt holds a schedule-at query value which could not be
+	// parsed, to be reported by Validate.
+	badScheduleAt string
+}
+
+// Sources of pin submissions (see PinProvenance).
+const (
+	ProvenanceRESTAPI      = "restapi"
+	ProvenanceProxy        = "ipfsproxy"
+	ProvenanceAdder        = "adder"
+	ProvenanceReallocation = "reallocation"
+	ProvenanceSnapshot     = "snapshot"
+	ProvenanceScheduler    = "scheduler"
+)
+
+// PinProvenance records who or what submitted a pin, so that the origin of
+// the entries in the shared state can be audited.
+type PinProvenance struct {
+	// Source is the component which received the request (i.e.
+	// ProvenanceRESTAPI). It is empty when unknown, like for pins
+	// submitted with the Go API.
+	Source string `json:"source" codec:"s,omitempty"`
+	// User is the API credential used, if any.
+	User string `json:"user,omitempty" codec:"u,omitempty"`
+	// Peer is the cluster peer which committed the pin.
+	Peer peer.ID `json:"peer" codec:"p,omitempty"`
+}
+
+// String returns a human-readable description of the provenance.
+func (pp *PinProvenance) String() string {
+	source := pp.Source
+	if source == "" {
+		source = "unknown"
+	}
+	if pp.User != "" {
+		source = fmt.Sprintf("%s (user %s)", source, pp.User)
+	}
+	if pp.Peer != "" {
+		source = fmt.Sprintf("%s on %s", source, pp.Peer.Pretty())
+	}
+	return source
 }
 
 // Equals returns true if two PinOption objects are equivalent. po and po2 may
@@ -487,6 +1198,15 @@ func (po *PinOptions) Equals(po2 *PinOptions) bool {
 		return false
 	}
 
+	if po.Depth != nil && po2.Depth == nil ||
+		po.Depth == nil && po2.Depth != nil {
+		return false
+	}
+
+	if po.Depth != nil && po2.Depth != nil && *po.Depth != *po2.Depth {
+		return false
+	}
+
 	lenAllocs1 := len(po.UserAllocations)
 	lenAllocs2 := len(po2.UserAllocations)
 	if lenAllocs1 != lenAllocs2 {
@@ -508,7 +1228,20 @@ func (po *PinOptions) Equals(po2 *PinOptions) bool {
 			return false
 		}
 	}
-	return true
+
+	if !po.ScheduleAt.Equal(po2.ScheduleAt) {
+		return false
+	}
+
+	if po.Priority != po2.Priority {
+		return false
+	}
+
+	if po.PinTimeout != po2.PinTimeout {
+		return false
+	}
+
+	return po.Recurrence == po2.Recurrence && po.RecurrencePath == po2.RecurrencePath
 }
 
 // ToQuery returns the PinOption as query arguments.
@@ -519,12 +1252,32 @@ func (po *PinOptions) ToQuery() string {
 	q.Set("name", po.Name)
 	q.Set("shard-size", fmt.Sprintf("%d", po.ShardSize))
 	q.Set("user-allocations", strings.Join(PeersToStrings(po.UserAllocations), ","))
+	if po.Depth != nil {
+		q.Set("max-depth", fmt.Sprintf("%d", *po.Depth))
+	}
 	for k, v := range po.Metadata {
 		if k == "" {
 			continue
 		}
 		q.Set(fmt.Sprintf("%s%s", pinOptionsMetaPrefix, k), v)
 	}
+	if sig := po.Signature; sig != nil {
+		q.Set("signature", base64.StdEncoding.EncodeToString(sig.Signature))
+		q.Set("signature-key", base64.StdEncoding.EncodeToString(sig.PublicKey))
+		q.Set("signature-time", sig.Timestamp.Format(time.RFC3339Nano))
+	}
+	if !po.ScheduleAt.IsZero() {
+		q.Set("schedule-at", po.ScheduleAt.Format(time.RFC3339Nano))
+	}
+	if po.Recurrence != "" {
+		q.Set("recurrence", po.Recurrence)
+	}
+	if po.Priority != 0 {
+		q.Set("priority", fmt.Sprintf("%d", po.Priority))
+	}
+	if po.PinTimeout > 0 {
+		q.Set("pin-timeout", po.PinTimeout.String())
+	}
 	return q.Encode()
 }
 
@@ -553,6 +1306,13 @@ func (po *PinOptions) FromQuery(q url.Values) {
 		po.UserAllocations = StringsToPeers(strings.Split(allocs, ","))
 	}
 
+	if depth, err := strconv.Atoi(q.Get("max-depth")); err == nil {
+		if depth < 0 {
+			depth = -1
+		}
+		po.Depth = &depth
+	}
+
 	po.Metadata = make(map[string]string)
 	for k := range q {
 		if !strings.HasPrefix(k, pinOptionsMetaPrefix) {
@@ -564,6 +1324,142 @@ func (po *PinOptions) FromQuery(q url.Values) {
 		}
 		po.Metadata[metaKey] = q.Get(k)
 	}
+
+	if q.Get("signature") != "" || q.Get("signature-key") != "" {
+		// Undecodable values are left empty and caught by Validate.
+		sig, _ := base64.StdEncoding.DecodeString(q.Get("signature"))
+		key, _ := base64.StdEncoding.DecodeString(q.Get("signature-key"))
+		ts, _ := time.Parse(time.RFC3339Nano, q.Get("signature-time"))
+		po.Signature = &PinSignature{
+			PublicKey: key,
+			Signature: sig,
+			Timestamp: ts,
+		}
+	}
+
+	if at := q.Get("schedule-at"); at != "" {
+		t, err := time.Parse(time.RFC3339Nano, at)
+		if err != nil {
+			po.badScheduleAt = at
+		}
+		po.ScheduleAt = t
+	}
+	po.Recurrence = q.Get("recurrence")
+
+	if prio, err := strconv.Atoi(q.Get("priority")); err == nil {
+		po.Priority = prio
+	}
+
+	if timeout, err := time.ParseDuration(q.Get("pin-timeout")); err == nil {
+		po.PinTimeout = timeout
+	}
+}
+
+// Operations which can be signed (see PinSignature).
+const (
+	SignedPinOp     = "pin"
+	SignedUnpinOp   = "unpin"
+	SignedRestoreOp = "restore"
+)
+
+// PinSignature is the signature of a pin, unpin or restore request, made
+// with the private key of a user, along with the public key to verify it
+// and the time of the signature. Peers with RequireSignedPins only accept
+// requests signed by one of their authorized keys, recently and only once.
+type PinSignature struct {
+	PublicKey []byte    `json:"public_key" codec:"pk,omitempty"`
+	Signature []byte    `json:"signature" codec:"s,omitempty"`
+	Timestamp time.Time `json:"timestamp" codec:"t,omitempty"`
+}
+
+// pinSignaturePayload returns the bytes signed for the given operation on
+// a pin at the given time. It is a canonical encoding, one "key=value" line
+// each, of the operation, the Cid and the time (in Unix nanoseconds). Pin
+// requests also encode every option which is kept in the shared state, so
+// that none of them can be altered without breaking the signature. Unpin
+// and restore requests carry no options.
+func pinSignaturePayload(op string, pin *Pin, ts time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ipfs-cluster-signed-request/v2\n")
+	fmt.Fprintf(&b, "op=%s\n", op)
+	fmt.Fprintf(&b, "cid=%s\n", pin.Cid)
+	fmt.Fprintf(&b, "timestamp=%d\n", ts.UnixNano())
+	if op != SignedPinOp {
+		return []byte(b.String())
+	}
+
+	ref := ""
+	if pin.Reference != nil {
+		ref = pin.Reference.String()
+	}
+	allocs := PeersToStrings(pin.UserAllocations)
+	sort.Strings(allocs)
+	scheduleAt := int64(0)
+	if pin.IsScheduled() {
+		scheduleAt = pin.ScheduleAt.UnixNano()
+	}
+
+	fmt.Fprintf(&b, "type=%d\n", pin.Type)
+	fmt.Fprintf(&b, "max-depth=%d\n", pin.MaxDepth)
+	fmt.Fprintf(&b, "reference=%s\n", ref)
+	fmt.Fprintf(&b, "name=%s\n", strconv.Quote(pin.Name))
+	fmt.Fprintf(&b, "replication-min=%d\n", pin.ReplicationFactorMin)
+	fmt.Fprintf(&b, "replication-max=%d\n", pin.ReplicationFactorMax)
+	fmt.Fprintf(&b, "shard-size=%d\n", pin.ShardSize)
+	fmt.Fprintf(&b, "user-allocations=%s\n", strings.Join(allocs, ","))
+	keys := make([]string, 0, len(pin.Metadata))
+	for k := range pin.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "metadata%s=%s\n", strconv.Quote(k), strconv.Quote(pin.Metadata[k]))
+	}
+	fmt.Fprintf(&b, "schedule-at=%d\n", scheduleAt)
+	fmt.Fprintf(&b, "recurrence=%s\n", strconv.Quote(pin.Recurrence))
+	fmt.Fprintf(&b, "priority=%d\n", pin.Priority)
+	fmt.Fprintf(&b, "pin-timeout=%d\n", int64(pin.PinTimeout))
+	return []byte(b.String())
+}
+
+// SignPin signs the given operation (SignedPinOp, SignedUnpinOp or
+// SignedRestoreOp) on a pin with the given key, at the current time. Pin
+// requests must be signed with all their options set.
+func SignPin(op string, pin *Pin, key crypto.PrivKey) (*PinSignature, error) {
+	if key == nil {
+		return nil, errors.New("a private key is needed to sign")
+	}
+	ts := time.Now()
+	sig, err := key.Sign(pinSignaturePayload(op, pin, ts))
+	if err != nil {
+		return nil, err
+	}
+	pubBytes, err := crypto.MarshalPublicKey(key.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+	return &PinSignature{
+		PublicKey: pubBytes,
+		Signature: sig,
+		Timestamp: ts,
+	}, nil
+}
+
+// Verify checks that this is a valid signature of the given operation on a
+// pin and returns the peer ID corresponding to the key that made it.
+func (ps *PinSignature) Verify(op string, pin *Pin) (peer.ID, error) {
+	pub, err := crypto.UnmarshalPublicKey(ps.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("error unmarshaling public key: %s", err)
+	}
+	ok, err := pub.Verify(pinSignaturePayload(op, pin, ps.Timestamp), ps.Signature)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("invalid signature")
+	}
+	return peer.IDFromPublicKey(pub)
 }
 
 // Pin carries all the information associated to a CID that is pinned
@@ -589,6 +1485,28 @@ type Pin struct {
 	// it is the previous shard CID.
 	// When not needed the pointer is nil
 	Reference *cid.Cid `json:"reference" codec:"r,omitempty"`
+
+	// RemoveAt is set when the pin has been unpinned but it is
+	// retained until the given time before being actually removed.
+	RemoveAt time.Time `json:"remove_at" codec:"ra,omitempty"`
+
+	// CreatedAt is the time when the Cid was first added to the shared
+	// state. UpdatedAt is the time of the last modification of the pin
+	// (i.e. new allocations or options).
+	CreatedAt time.Time `json:"created_at" codec:"cat,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" codec:"uat,omitempty"`
+
+	// CreatedBy is the Provenance of the submission which first added
+	// the Cid to the shared state.
+	CreatedBy *PinProvenance `json:"created_by,omitempty" codec:"cby,omitempty"`
+
+	// Size is the estimated cumulative size of the DAG in bytes, as
+	// determined before allocating it. 0 means unknown.
+	Size uint64 `json:"size,omitempty" codec:"sz,omitempty"`
+
+	// LastRun is the last time that the scheduler ran the pin (see
+	// ScheduleAt and Recurrence).
+	LastRun time.Time `json:"last_run" codec:"lr,omitempty"`
 }
 
 // String is a string representation of a Pin.
@@ -601,9 +1519,51 @@ func (pin *Pin) String() string {
 	if pin.Reference != nil {
 		fmt.Fprintf(&b, "reference: %s\n", pin.Reference)
 	}
+	if pin.IsScheduledForRemoval() {
+		fmt.Fprintf(&b, "remove at: %s\n", pin.RemoveAt)
+	}
+	if pin.IsScheduled() {
+		fmt.Fprintf(&b, "scheduled at: %s\n", pin.ScheduleAt)
+	}
+	if pin.Recurrence != "" {
+		fmt.Fprintf(&b, "recurrence: %s (%s)\n", pin.Recurrence, pin.RecurrencePath)
+	}
+	if pin.Priority != 0 {
+		fmt.Fprintf(&b, "priority: %d\n", pin.Priority)
+	}
+	if pin.PinTimeout > 0 {
+		fmt.Fprintf(&b, "pin timeout: %s\n", pin.PinTimeout)
+	}
+	if !pin.CreatedAt.IsZero() {
+		fmt.Fprintf(&b, "created at: %s\n", pin.CreatedAt)
+	}
+	if !pin.UpdatedAt.IsZero() {
+		fmt.Fprintf(&b, "updated at: %s\n", pin.UpdatedAt)
+	}
+	if pin.CreatedBy != nil {
+		fmt.Fprintf(&b, "created by: %s\n", pin.CreatedBy)
+	}
+	if pin.Provenance != nil {
+		fmt.Fprintf(&b, "submitted by: %s\n", pin.Provenance)
+	}
+	if pin.Size > 0 {
+		fmt.Fprintf(&b, "size: %d\n", pin.Size)
+	}
 	return b.String()
 }
 
+// IsScheduledForRemoval returns true when the pin has been unpinned and is
+// only retained until RemoveAt.
+func (pin *Pin) IsScheduledForRemoval() bool {
+	return !pin.RemoveAt.IsZero()
+}
+
+// IsScheduled returns true when the pin is deferred until ScheduleAt.
+// Scheduled pins are not allocated to any peer.
+func (pin *Pin) IsScheduled() bool {
+	return !pin.ScheduleAt.IsZero()
+}
+
 // PinPath is a wrapper for holding pin options and path of the content.
 type PinPath struct {
 	PinOptions
@@ -622,13 +1582,26 @@ func PinCid(c cid.Cid) *Pin {
 }
 
 // PinWithOpts creates a new Pin calling PinCid(c) and then sets
-// its PinOptions fields with the given options.
+// its PinOptions fields with the given options. When opts.Depth is set,
+// it is used as the Pin's MaxDepth.
 func PinWithOpts(c cid.Cid, opts PinOptions) *Pin {
 	p := PinCid(c)
 	p.PinOptions = opts
+	if opts.Depth != nil {
+		p.MaxDepth = *opts.Depth
+		p.Depth = nil
+	}
 	return p
 }
 
+// PinResponse is the result of submitting a Pin to the cluster. Duplicate
+// is true when an identical Pin was already present or being submitted, and
+// therefore the request did not result in a new entry in the shared state.
+type PinResponse struct {
+	Pin
+	Duplicate bool `json:"duplicate" codec:"dup,omitempty"`
+}
+
 func convertPinType(t PinType) pb.Pin_PinType {
 	var i pb.Pin_PinType
 	for t != 1 {
@@ -660,6 +1633,23 @@ func (pin *Pin) ProtoMarshal() ([]byte, error) {
 		// UserAllocations:      pin.UserAllocations,
 		Metadata: pin.Metadata,
 	}
+	if sig := pin.Signature; sig != nil {
+		opts.SignatureKey = sig.PublicKey
+		opts.Signature = sig.Signature
+		opts.SignatureTime = sig.Timestamp.UnixNano()
+	}
+	if prov := pin.Provenance; prov != nil {
+		opts.ProvenanceSource = prov.Source
+		opts.ProvenanceUser = prov.User
+		opts.ProvenancePeer = []byte(prov.Peer)
+	}
+	if pin.IsScheduled() {
+		opts.ScheduleAt = pin.ScheduleAt.UnixNano()
+	}
+	opts.Recurrence = pin.Recurrence
+	opts.RecurrencePath = pin.RecurrencePath
+	opts.Priority = int32(pin.Priority)
+	opts.PinTimeout = int64(pin.PinTimeout)
 
 	pbPin := &pb.Pin{
 		Cid:         pin.Cid.Bytes(),
@@ -667,10 +1657,28 @@ func (pin *Pin) ProtoMarshal() ([]byte, error) {
 		Allocations: allocs,
 		MaxDepth:    int32(pin.MaxDepth),
 		Options:     opts,
+		Size:        pin.Size,
 	}
 	if ref := pin.Reference; ref != nil {
 		pbPin.Reference = ref.Bytes()
 	}
+	if pin.IsScheduledForRemoval() {
+		pbPin.RemoveAt = pin.RemoveAt.UnixNano()
+	}
+	if !pin.CreatedAt.IsZero() {
+		pbPin.CreatedAt = pin.CreatedAt.UnixNano()
+	}
+	if !pin.UpdatedAt.IsZero() {
+		pbPin.UpdatedAt = pin.UpdatedAt.UnixNano()
+	}
+	if prov := pin.CreatedBy; prov != nil {
+		pbPin.CreatedBySource = prov.Source
+		pbPin.CreatedByUser = prov.User
+		pbPin.CreatedByPeer = []byte(prov.Peer)
+	}
+	if !pin.LastRun.IsZero() {
+		pbPin.LastRun = pin.LastRun.UnixNano()
+	}
 	return proto.Marshal(pbPin)
 }
 
@@ -711,6 +1719,22 @@ func (pin *Pin) ProtoUnmarshal(data []byte) error {
 		pin.Reference = &ref
 	}
 
+	if removeAt := pbPin.GetRemoveAt(); removeAt != 0 {
+		pin.RemoveAt = time.Unix(0, removeAt)
+	} else {
+		pin.RemoveAt = time.Time{}
+	}
+
+	pin.CreatedAt = unixNanoToTime(pbPin.GetCreatedAt())
+	pin.UpdatedAt = unixNanoToTime(pbPin.GetUpdatedAt())
+	pin.Size = pbPin.GetSize()
+	pin.LastRun = unixNanoToTime(pbPin.GetLastRun())
+	pin.CreatedBy = protoToProvenance(
+		pbPin.GetCreatedBySource(),
+		pbPin.GetCreatedByUser(),
+		pbPin.GetCreatedByPeer(),
+	)
+
 	opts := pbPin.GetOptions()
 	pin.ReplicationFactorMin = int(opts.GetReplicationFactorMin())
 	pin.ReplicationFactorMax = int(opts.GetReplicationFactorMax())
@@ -718,12 +1742,51 @@ func (pin *Pin) ProtoUnmarshal(data []byte) error {
 	pin.ShardSize = opts.GetShardSize()
 	// pin.UserAllocations = opts.GetUserAllocations()
 	pin.Metadata = opts.GetMetadata()
+	if sig := opts.GetSignature(); len(sig) > 0 {
+		pin.Signature = &PinSignature{
+			PublicKey: opts.GetSignatureKey(),
+			Signature: sig,
+			Timestamp: unixNanoToTime(opts.GetSignatureTime()),
+		}
+	}
+	pin.Provenance = protoToProvenance(
+		opts.GetProvenanceSource(),
+		opts.GetProvenanceUser(),
+		opts.GetProvenancePeer(),
+	)
+	pin.ScheduleAt = unixNanoToTime(opts.GetScheduleAt())
+	pin.Recurrence = opts.GetRecurrence()
+	pin.RecurrencePath = opts.GetRecurrencePath()
+	pin.Priority = int(opts.GetPriority())
+	pin.PinTimeout = time.Duration(opts.GetPinTimeout())
 	return nil
 }
 
+// protoToProvenance builds a PinProvenance from its protobuf fields. It
+// returns nil when they are all unset.
+func protoToProvenance(source, user string, pid []byte) *PinProvenance {
+	if source == "" && user == "" && len(pid) == 0 {
+		return nil
+	}
+	return &PinProvenance{
+		Source: source,
+		User:   user,
+		Peer:   peer.ID(pid),
+	}
+}
+
+// unixNanoToTime converts a protobuf timestamp to a time.Time, where 0
+// means unset.
+func unixNanoToTime(t int64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t)
+}
+
 // Equals checks if two pins are the same (with the same allocations).
 // If allocations are the same but in different order, they are still
-// considered equivalent.
+// considered equivalent. CreatedAt, UpdatedAt and Size are not compared.
 // pin or pin2 may be nil. If both are nil, Equals returns false.
 func (pin *Pin) Equals(pin2 *Pin) bool {
 	if pin == nil && pin2 != nil || pin2 == nil && pin != nil {
@@ -763,6 +1826,10 @@ func (pin *Pin) Equals(pin2 *Pin) bool {
 		return false
 	}
 
+	if !pin.RemoveAt.Equal(pin2.RemoveAt) {
+		return false
+	}
+
 	allocs1 := PeersToStrings(pin.Allocations)
 	sort.Strings(allocs1)
 	allocs2 := PeersToStrings(pin2.Allocations)
@@ -776,8 +1843,12 @@ func (pin *Pin) Equals(pin2 *Pin) bool {
 }
 
 // IsRemotePin determines whether a Pin's ReplicationFactor has
-// been met, so as to either pin or unpin it from the peer.
+// been met, so as to either pin or unpin it from the peer. Scheduled pins
+// are remote for every peer.
 func (pin *Pin) IsRemotePin(pid peer.ID) bool {
+	if pin.IsScheduled() {
+		return true
+	}
 	if pin.ReplicationFactorMax < 0 || pin.ReplicationFactorMin < 0 {
 		return false
 	}
@@ -797,6 +1868,10 @@ type NodeWithMeta struct {
 	Cid     cid.Cid `codec:"c, omitempty"`
 	CumSize uint64  `codec:"s,omitempty"` // Cumulative size
 	Format  string  `codec:"f,omitempty"`
+	// AddID identifies the add the block belongs to, if any, so that
+	// peers know that blocks are about to be pinned until the add
+	// finishes (see adder.FinishAdd).
+	AddID string `codec:"a,omitempty"`
 }
 
 // Size returns how big is the block. It is different from CumSize, which
@@ -809,14 +1884,16 @@ func (n *NodeWithMeta) Size() uint64 {
 // pin allocations by a PinAllocator. IPFS cluster is agnostic to
 // the Value, which should be interpreted by the PinAllocator.
 // The ReceivedAt value is a timestamp representing when a peer has received
-// the metric value.
+// the metric value. The SentAt value is set by the peer publishing it and
+// allows receivers to detect clock skew.
 type Metric struct {
 	Name       string  `json:"name" codec:"n,omitempty"`
 	Peer       peer.ID `json:"peer" codec:"p,omitempty"`
 	Value      string  `json:"value" codec:"v,omitempty"`
 	Expire     int64   `json:"expire" codec:"e,omitempty"`
 	Valid      bool    `json:"valid" codec:"d,omitempty"`
-	ReceivedAt int64   `json:"received_at" codec:"t,omitempty"` // ReceivedAt contains a UnixNano timestamp
+	ReceivedAt int64   `json:"received_at" codec:"t,omitempty"`       // ReceivedAt contains a UnixNano timestamp
+	SentAt     int64   `json:"sent_at,omitempty" codec:"s,omitempty"` // SentAt contains a UnixNano timestamp set when publishing
 }
 
 // SetTTL sets Metric to expire after the given time.Duration
@@ -848,10 +1925,18 @@ type Alert struct {
 	MetricName string
 }
 
+// RequestTimeoutHeader is the HTTP header with which API clients tell the
+// server how long they are willing to wait for a response, as a duration
+// string (i.e. "30s"). The server stops working on the request afterwards.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
 // Error can be used by APIs to return errors.
 type Error struct {
 	Code    int    `json:"code" codec:"o,omitempty"`
 	Message string `json:"message" codec:"m,omitempty"`
+	// Details lists the invalid options when the error is caused by
+	// a request which did not validate.
+	Details ValidationErrors `json:"details,omitempty" codec:"d,omitempty"`
 }
 
 // Error implements the error interface and returns the error's message.
@@ -859,8 +1944,126 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
 }
 
+// RateLimitError is returned when an operation is refused because the
+// configured rate limit was exceeded. RetryAfter estimates when it will be
+// accepted.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+// RetryAfterSeconds returns RetryAfter rounded up to whole seconds, as
+// used in Retry-After HTTP headers.
+func (e *RateLimitError) RetryAfterSeconds() int {
+	secs := int((e.RetryAfter + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
+
+var rateLimitErrorRegexp = regexp.MustCompile(`rate limit exceeded, retry after ([0-9][0-9.a-zµ]*)`)
+
+// AsRateLimitError returns the RateLimitError carried by err. Errors
+// received over RPC only keep their message, so it is parsed when needed.
+func AsRateLimitError(err error) (*RateLimitError, bool) {
+	if err == nil {
+		return nil, false
+	}
+	if rlErr, ok := err.(*RateLimitError); ok {
+		return rlErr, true
+	}
+	m := rateLimitErrorRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil, false
+	}
+	d, perr := time.ParseDuration(m[1])
+	if perr != nil {
+		return nil, false
+	}
+	return &RateLimitError{RetryAfter: d}, true
+}
+
 // IPFSRepoStat wraps information about the IPFS repository.
 type IPFSRepoStat struct {
 	RepoSize   uint64 `codec:"r,omitempty"`
 	StorageMax uint64 `codec:"s, omitempty"`
 }
+
+// PinnedBytes is an estimate of the amount of data pinned by a peer,
+// obtained from the sizes of the pins allocated to it. Bytes is the sum of
+// the known sizes. When the size of some pins is unknown, it is raised to
+// the size of the IPFS repository, if larger, attributing the data which
+// is not accounted for to them.
+type PinnedBytes struct {
+	Bytes       uint64 `json:"bytes" codec:"b,omitempty"`
+	Pins        int    `json:"pins" codec:"p,omitempty"`
+	UnknownSize int    `json:"unknown_size" codec:"u,omitempty"`
+	RepoSize    uint64 `json:"repo_size" codec:"r,omitempty"`
+}
+
+// StorageUsage compares the amount of data referenced by the pinset, once
+// deduplicated across pins (unique), with the raw amount of data stored by
+// all the peers holding replicas of it (replicated). When only some of the
+// pins were examined (SampledPins minus FailedPins), the figures are
+// extrapolated to the whole pinset.
+type StorageUsage struct {
+	Pins            int    `json:"pins" codec:"p,omitempty"`
+	SampledPins     int    `json:"sampled_pins" codec:"sp,omitempty"`
+	FailedPins      int    `json:"failed_pins" codec:"fp,omitempty"`
+	UniqueBlocks    uint64 `json:"unique_blocks" codec:"ub,omitempty"`
+	UniqueBytes     uint64 `json:"unique_bytes" codec:"u,omitempty"`
+	ReplicatedBytes uint64 `json:"replicated_bytes" codec:"r,omitempty"`
+}
+
+// IPFSBlockStat wraps the size of an IPFS block.
+type IPFSBlockStat struct {
+	Cid  cid.Cid `json:"cid" codec:"c"`
+	Size uint64  `json:"size" codec:"s,omitempty"`
+}
+
+// IPFSBitswapStat wraps the bitswap statistics of an IPFS daemon.
+type IPFSBitswapStat struct {
+	// Number of blocks the daemon is looking for.
+	WantlistSize int `json:"wantlist_size" codec:"w,omitempty"`
+	// Number of peers the daemon exchanges blocks with.
+	Peers          int    `json:"peers" codec:"p,omitempty"`
+	BlocksReceived uint64 `json:"blocks_received" codec:"b,omitempty"`
+	DataReceived   uint64 `json:"data_received" codec:"d,omitempty"`
+	// Bytes per second received since the previous time the stats
+	// were obtained.
+	DataReceivedRate float64 `json:"data_received_rate" codec:"r,omitempty"`
+}
+
+// PinProgress tells how much of a DAG has been fetched by an ongoing pin
+// operation. Blocks are those walked by IPFS so far. Bytes are estimated
+// from the data received by IPFS, which is shared between the pins fetched
+// at the same time in proportion to their blocks. TotalBytes is the
+// estimated size of the DAG (see Pin.Size) and Percent the estimated
+// completion, when it is known.
+type PinProgress struct {
+	Cid        cid.Cid `json:"cid" codec:"c"`
+	Blocks     uint64  `json:"blocks" codec:"b,omitempty"`
+	Bytes      uint64  `json:"bytes" codec:"by,omitempty"`
+	TotalBytes uint64  `json:"total_bytes,omitempty" codec:"t,omitempty"`
+	Percent    float64 `json:"percent,omitempty" codec:"pc,omitempty"`
+}
+
+// SetTotalBytes sets the estimated size of the DAG and the completion
+// percentage. The percentage stays below 100 until the pin is done, as
+// the size is an estimation.
+func (pp *PinProgress) SetTotalBytes(total uint64) {
+	pp.TotalBytes = total
+	pp.Percent = 0
+	if total == 0 {
+		return
+	}
+	pp.Percent = 100 * float64(pp.Bytes) / float64(total)
+	if pp.Percent > 99 {
+		pp.Percent = 99
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"errors"
 	"net/url"
 	"reflect"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	cid "github.com/ipfs/go-cid"
+	crypto "github.com/libp2p/go-libp2p-crypto"
 	peer "github.com/libp2p/go-libp2p-peer"
 	ma "github.com/multiformats/go-multiaddr"
 
@@ -44,6 +46,52 @@ func TestTrackerFromString(t *testing.T) {
 	}
 }
 
+func TestTrackerStatusFilterFromString(t *testing.T) {
+	filter, err := TrackerStatusFilterFromString("pinning, pin_error,queued")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter != TrackerStatusPinning|TrackerStatusPinError|TrackerStatusQueued {
+		t.Errorf("unexpected filter: %s", filter)
+	}
+
+	filter, err = TrackerStatusFilterFromString("")
+	if err != nil || filter != TrackerStatusUndefined {
+		t.Error("expected an undefined filter for an empty string")
+	}
+
+	_, err = TrackerStatusFilterFromString("pinning,xyz")
+	if err == nil {
+		t.Error("expected an error for unknown status names")
+	}
+}
+
+func TestStatusCounts(t *testing.T) {
+	var sc StatusCounts
+	for _, st := range []TrackerStatus{
+		TrackerStatusPinned,
+		TrackerStatusPinned,
+		TrackerStatusPinning,
+		TrackerStatusPinQueued,
+		TrackerStatusUnpinQueued,
+		TrackerStatusPinError,
+		TrackerStatusClusterError,
+		TrackerStatusRemote,
+		TrackerStatusSharded,
+	} {
+		sc.Count(st)
+	}
+	want := StatusCounts{Pinned: 2, Pinning: 1, Queued: 2, Error: 2, Remote: 1}
+	if sc != want {
+		t.Errorf("unexpected counts: %+v", sc)
+	}
+
+	sc.Add(want)
+	if sc.Pinned != 4 || sc.Error != 4 {
+		t.Errorf("unexpected counts after Add: %+v", sc)
+	}
+}
+
 func TestIPFSPinStatusFromString(t *testing.T) {
 	testcases := []string{"direct", "recursive", "indirect"}
 	for i, tc := range testcases {
@@ -201,6 +249,27 @@ func TestPinOptionsQuery(t *testing.T) {
 				"": "bye",
 			},
 		},
+		&PinOptions{
+			ReplicationFactorMax: 1,
+			ReplicationFactorMin: 1,
+			Depth:                new(int),
+		},
+		&PinOptions{
+			ReplicationFactorMax: 1,
+			ReplicationFactorMin: 1,
+			ScheduleAt:           time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+			Recurrence:           "@daily",
+		},
+		&PinOptions{
+			ReplicationFactorMax: 1,
+			ReplicationFactorMin: 1,
+			Priority:             5,
+		},
+		&PinOptions{
+			ReplicationFactorMax: 1,
+			ReplicationFactorMin: 1,
+			PinTimeout:           10 * time.Minute,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -220,6 +289,125 @@ func TestPinOptionsQuery(t *testing.T) {
 	}
 }
 
+func TestPinSignature(t *testing.T) {
+	priv, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := PinOptions{
+		ReplicationFactorMin: 1,
+		ReplicationFactorMax: 2,
+		Name:                 "signed",
+		Metadata:             map[string]string{"a": "b", "c": "d"},
+		Priority:             3,
+	}
+	pin := PinWithOpts(testCid1, opts)
+	sig, err := SignPin(SignedPinOp, pin, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(sig.Timestamp) > time.Minute {
+		t.Error("expected the signature time to be set")
+	}
+	signer, err := sig.Verify(SignedPinOp, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != pid {
+		t.Error("expected the signer to be the key's peer ID")
+	}
+
+	if _, err := sig.Verify(SignedUnpinOp, pin); err == nil {
+		t.Error("a pin signature should not be valid to unpin")
+	}
+	if _, err := sig.Verify(SignedPinOp, PinWithOpts(testCid2, opts)); err == nil {
+		t.Error("a signature should not be valid for other cids")
+	}
+
+	// Every option is signed.
+	for _, modify := range []func(o *PinOptions){
+		func(o *PinOptions) { o.ReplicationFactorMax = 3 },
+		func(o *PinOptions) { o.Name = "other" },
+		func(o *PinOptions) { o.Metadata = map[string]string{"a": "b"} },
+		func(o *PinOptions) { o.UserAllocations = []peer.ID{pid} },
+		func(o *PinOptions) { o.Priority = 0 },
+		func(o *PinOptions) { o.PinTimeout = time.Minute },
+	} {
+		o := opts
+		modify(&o)
+		if _, err := sig.Verify(SignedPinOp, PinWithOpts(testCid1, o)); err == nil {
+			t.Errorf("a signature should not be valid for other options: %+v", o)
+		}
+	}
+
+	// The signature time is signed.
+	sig2 := *sig
+	sig2.Timestamp = sig.Timestamp.Add(time.Second)
+	if _, err := sig2.Verify(SignedPinOp, pin); err == nil {
+		t.Error("a signature should not be valid for another time")
+	}
+
+	// Unpin signatures only cover the Cid.
+	unpinSig, err := SignPin(SignedUnpinOp, PinCid(testCid1), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unpinSig.Verify(SignedUnpinOp, pin); err != nil {
+		t.Error("unpin signatures should not depend on the options:", err)
+	}
+
+	// Signatures travel in the query and in the shared state.
+	po := opts
+	po.Signature = sig
+	q, err := url.ParseQuery(po.ToQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	po2 := &PinOptions{}
+	po2.FromQuery(q)
+	if err := po2.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := po2.Signature.Verify(SignedPinOp, PinWithOpts(testCid1, *po2)); err != nil {
+		t.Error("expected a valid signature after parsing the query:", err)
+	}
+
+	pin = PinWithOpts(testCid1, po)
+	data, err := pin.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin2 := &Pin{}
+	if err := pin2.ProtoUnmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if pin2.Signature == nil {
+		t.Fatal("expected the signature to be kept")
+	}
+	if _, err := pin2.Signature.Verify(SignedPinOp, pin2); err != nil {
+		t.Error("expected a valid signature after unmarshaling:", err)
+	}
+
+	q.Del("signature-time")
+	po3 := &PinOptions{}
+	po3.FromQuery(q)
+	if err := po3.Validate(); err == nil {
+		t.Error("expected an error without the signature time")
+	}
+
+	q.Set("signature", "not base64!")
+	po3 = &PinOptions{}
+	po3.FromQuery(q)
+	if err := po3.Validate(); err == nil {
+		t.Error("expected an error with an undecodable signature")
+	}
+}
+
 func TestIDCodec(t *testing.T) {
 	TestPeerID1, _ := peer.IDB58Decode("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
 	TestPeerID2, _ := peer.IDB58Decode("QmUZ13osndQ5uL4tPWHXe3iBgBgq9gfewcBMSCAuMBsDJ6")
@@ -281,3 +469,213 @@ func TestPinCodec(t *testing.T) {
 	}
 
 }
+
+func TestPinProtoRemoveAt(t *testing.T) {
+	ci, _ := cid.Decode("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+	pin := PinCid(ci)
+	pin.RemoveAt = time.Now().Add(time.Hour)
+
+	data, err := pin.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pin2 Pin
+	err = pin2.ProtoUnmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pin2.IsScheduledForRemoval() || !pin2.RemoveAt.Equal(pin.RemoveAt) {
+		t.Error("remove_at was not preserved")
+	}
+	if !pin.Equals(&pin2) {
+		t.Error("pins should be equal")
+	}
+
+	pin.RemoveAt = time.Time{}
+	if pin.Equals(&pin2) {
+		t.Error("pins should not be equal")
+	}
+	data, _ = pin.ProtoMarshal()
+	pin2.ProtoUnmarshal(data)
+	if pin2.IsScheduledForRemoval() {
+		t.Error("pin should not be scheduled for removal")
+	}
+}
+
+func TestAsBulkUnpinError(t *testing.T) {
+	errs := []*BulkUnpinError{
+		{Operation: "restore of snapshot a", Unpins: 30, Total: 40, Limit: 20},
+		{Operation: "restore of snapshot b", Unpins: 3, Total: 4, Limit: 2, Token: "0a1b2c"},
+	}
+	for _, e := range errs {
+		// Errors received over RPC only keep their message.
+		buErr, ok := AsBulkUnpinError(errors.New("rpc: " + e.Error()))
+		if !ok {
+			t.Fatal("expected a bulk unpin error")
+		}
+		if buErr.Unpins != e.Unpins || buErr.Total != e.Total || buErr.Limit != e.Limit || buErr.Token != e.Token {
+			t.Errorf("unexpected bulk unpin error: %+v", buErr)
+		}
+	}
+
+	if _, ok := AsBulkUnpinError(errors.New("some error")); ok {
+		t.Error("expected no bulk unpin error")
+	}
+}
+
+func TestPinProtoTimestamps(t *testing.T) {
+	ci, _ := cid.Decode("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+	pin := PinCid(ci)
+	pin.CreatedAt = time.Now().Add(-time.Hour)
+	pin.UpdatedAt = time.Now()
+
+	data, err := pin.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pin2 Pin
+	err = pin2.ProtoUnmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pin2.CreatedAt.Equal(pin.CreatedAt) {
+		t.Error("created_at was not preserved")
+	}
+	if !pin2.UpdatedAt.Equal(pin.UpdatedAt) {
+		t.Error("updated_at was not preserved")
+	}
+
+	pin2.UpdatedAt = time.Now().Add(time.Hour)
+	if !pin.Equals(&pin2) {
+		t.Error("timestamps should not affect equality")
+	}
+
+	data, _ = PinCid(ci).ProtoMarshal()
+	pin2.ProtoUnmarshal(data)
+	if !pin2.CreatedAt.IsZero() || !pin2.UpdatedAt.IsZero() {
+		t.Error("timestamps should be unset")
+	}
+}
+
+func TestPinProtoProvenance(t *testing.T) {
+	ci, _ := cid.Decode("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+	pid, _ := peer.IDB58Decode("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+	pin := PinCid(ci)
+	pin.CreatedBy = &PinProvenance{Source: ProvenanceRESTAPI, User: "alice", Peer: pid}
+	pin.Provenance = &PinProvenance{Source: ProvenanceReallocation, Peer: pid}
+
+	data, err := pin.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pin2 Pin
+	err = pin2.ProtoUnmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin2.CreatedBy == nil || *pin2.CreatedBy != *pin.CreatedBy {
+		t.Error("created_by was not preserved")
+	}
+	if pin2.Provenance == nil || *pin2.Provenance != *pin.Provenance {
+		t.Error("provenance was not preserved")
+	}
+
+	data, _ = PinCid(ci).ProtoMarshal()
+	pin2.ProtoUnmarshal(data)
+	if pin2.CreatedBy != nil || pin2.Provenance != nil {
+		t.Error("provenance should be unset")
+	}
+}
+
+func TestPinProtoSize(t *testing.T) {
+	ci, _ := cid.Decode("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+	pin := PinCid(ci)
+	pin.Size = 1024
+
+	data, err := pin.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pin2 Pin
+	err = pin2.ProtoUnmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin2.Size != pin.Size {
+		t.Error("size was not preserved")
+	}
+
+	pin2.Size = 0
+	if !pin.Equals(&pin2) {
+		t.Error("size should not affect equality")
+	}
+}
+
+func TestPinWithOptsDepth(t *testing.T) {
+	ci, _ := cid.Decode("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+
+	pin := PinWithOpts(ci, PinOptions{})
+	if pin.MaxDepth != -1 {
+		t.Error("pins should be recursive by default")
+	}
+
+	depth := 2
+	pin = PinWithOpts(ci, PinOptions{Depth: &depth})
+	if pin.MaxDepth != 2 {
+		t.Error("max depth should be 2")
+	}
+	if pin.Depth != nil {
+		t.Error("the depth option should have been applied to MaxDepth")
+	}
+
+	po := &PinOptions{}
+	po.FromQuery(url.Values{"max-depth": []string{"-5"}})
+	if po.Depth == nil || *po.Depth != -1 {
+		t.Error("negative depths should mean recursive")
+	}
+}
+
+func TestAsRateLimitError(t *testing.T) {
+	if _, ok := AsRateLimitError(nil); ok {
+		t.Error("nil is not a rate limit error")
+	}
+
+	rlErr := &RateLimitError{RetryAfter: 1500 * time.Millisecond}
+	got, ok := AsRateLimitError(rlErr)
+	if !ok || got != rlErr {
+		t.Error("expected the same rate limit error")
+	}
+
+	// Errors crossing RPC only keep their message.
+	got, ok = AsRateLimitError(&Error{Code: 500, Message: rlErr.Error()})
+	if !ok || got.RetryAfter != rlErr.RetryAfter {
+		t.Error("expected a rate limit error parsed from the message")
+	}
+
+	if _, ok := AsRateLimitError(&Error{Code: 500, Message: "other"}); ok {
+		t.Error("other errors are not rate limit errors")
+	}
+}
+
+func TestPinProgressSetTotalBytes(t *testing.T) {
+	pp := &PinProgress{Bytes: 500}
+	pp.SetTotalBytes(1000)
+	if pp.Percent != 50 {
+		t.Error("expected 50% completion, got:", pp.Percent)
+	}
+
+	// sizes are estimations
+	pp.SetTotalBytes(400)
+	if pp.Percent != 99 {
+		t.Error("the completion should stay below 100% while pinning, got:", pp.Percent)
+	}
+
+	pp.SetTotalBytes(0)
+	if pp.Percent != 0 {
+		t.Error("the completion is unknown without a size")
+	}
+}
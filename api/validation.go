@@ -0,0 +1,214 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/ipfs-cluster/cron"
+
+	multihash "github.com/multiformats/go-multihash"
+)
+
+// maxChunkSize is the largest chunk size accepted for adding. Larger blocks
+// cannot be exchanged by IPFS daemons.
+const maxChunkSize = 1024 * 1024
+
+// ValidationError reports an invalid option given to the API. Field is the
+// name of the option as given by the user (i.e. the query parameter).
+type ValidationError struct {
+	Field   string `json:"field" codec:"f,omitempty"`
+	Message string `json:"message" codec:"m,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors collects all the problems found when validating a set
+// of options, so that they can be reported at once.
+type ValidationErrors []*ValidationError
+
+// Error implements the error interface.
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (errs *ValidationErrors) add(field, format string, a ...interface{}) {
+	*errs = append(*errs, &ValidationError{
+		Field:   field,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// err returns nil when no errors were collected. This avoids returning
+// non-nil error interfaces holding an empty list.
+func (errs ValidationErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Validate checks that the PinOptions are consistent. Unset (0) replication
+// factors are valid, as they are replaced by the cluster defaults. It
+// returns ValidationErrors, naming fields by their query parameter.
+func (po *PinOptions) Validate() error {
+	var errs ValidationErrors
+	po.validate(&errs)
+	return errs.err()
+}
+
+func (po *PinOptions) validate(errs *ValidationErrors) {
+	rmin := po.ReplicationFactorMin
+	rmax := po.ReplicationFactorMax
+	if rmin < -1 {
+		errs.add("replication-min", "must be -1 (everywhere) or larger, got %d", rmin)
+	}
+	if rmax < -1 {
+		errs.add("replication-max", "must be -1 (everywhere) or larger, got %d", rmax)
+	}
+	if rmin > 0 && rmax > 0 && rmin > rmax {
+		errs.add("replication-min", "%d is larger than replication-max (%d)", rmin, rmax)
+	}
+	if (rmin == -1 && rmax > 0) || (rmax == -1 && rmin > 0) {
+		errs.add("replication-min", "replication-min and replication-max must both be -1 to pin everywhere")
+	}
+
+	for k := range po.Metadata {
+		if k == "" {
+			errs.add(pinOptionsMetaPrefix, "metadata keys cannot be empty")
+			break
+		}
+	}
+
+	if sig := po.Signature; sig != nil {
+		if len(sig.Signature) == 0 || len(sig.PublicKey) == 0 {
+			errs.add("signature", "signature and signature-key must be given together, base64-encoded")
+		}
+		if sig.Timestamp.IsZero() {
+			errs.add("signature-time", "must be given with the signature, in RFC3339 format")
+		}
+	}
+
+	if po.badScheduleAt != "" {
+		errs.add("schedule-at", "%q is not an RFC3339 timestamp", po.badScheduleAt)
+	}
+	if po.Recurrence != "" {
+		if _, err := cron.Parse(po.Recurrence); err != nil {
+			errs.add("recurrence", "%s", err)
+		}
+	}
+}
+
+// Validate checks that the AddParams are valid and consistent, so that
+// adding does not fail half-way. It returns ValidationErrors, naming fields
+// by their query parameter.
+func (p *AddParams) Validate() error {
+	var errs ValidationErrors
+	p.PinOptions.validate(&errs)
+
+	// Added content is pinned as it is added.
+	if !p.ScheduleAt.IsZero() || p.badScheduleAt != "" {
+		errs.add("schedule-at", "cannot be used when adding")
+	}
+	if p.Recurrence != "" {
+		errs.add("recurrence", "cannot be used when adding")
+	}
+
+	switch p.Layout {
+	case "trickle", "balanced", "":
+	default:
+		errs.add("layout", "must be balanced or trickle, got %q", p.Layout)
+	}
+
+	if err := validateChunker(p.Chunker); err != nil {
+		errs.add("chunker", "%s", err)
+	}
+
+	if _, ok := multihash.Names[strings.ToLower(p.HashFun)]; !ok {
+		errs.add("hash", "unknown hash function %q", p.HashFun)
+	}
+
+	switch p.CidVersion {
+	case 0:
+		if p.HashFun != "" && strings.ToLower(p.HashFun) != "sha2-256" {
+			errs.add("hash", "%s requires cid-version=1", p.HashFun)
+		}
+	case 1:
+	default:
+		errs.add("cid-version", "must be 0 or 1, got %d", p.CidVersion)
+	}
+
+	if p.NoCopy && !p.RawLeaves {
+		errs.add("nocopy", "requires raw-leaves")
+	}
+
+	if p.Shard {
+		if p.ShardSize == 0 {
+			errs.add("shard-size", "must be set when sharding")
+		}
+		if p.Manifest {
+			errs.add("manifest", "cannot be used along shard")
+		}
+		if p.NoCopy {
+			errs.add("nocopy", "cannot be used along shard")
+		}
+	}
+
+	return errs.err()
+}
+
+// validateChunker checks chunker strings as understood by the ipfs adder:
+// "size-<bytes>" or "rabin[-<min>-<avg>-<max>|-<avg>]".
+func validateChunker(chunker string) error {
+	parts := strings.Split(chunker, "-")
+	switch parts[0] {
+	case "", "default":
+		if len(parts) > 1 {
+			return fmt.Errorf("unrecognized chunker %q", chunker)
+		}
+		return nil
+	case "size":
+		if len(parts) != 2 {
+			return fmt.Errorf("%q should be size-<bytes>", chunker)
+		}
+		size, err := strconv.Atoi(parts[1])
+		if err != nil || size <= 0 {
+			return fmt.Errorf("%q does not have a valid size", chunker)
+		}
+		if size > maxChunkSize {
+			return fmt.Errorf("chunks cannot be larger than %d bytes", maxChunkSize)
+		}
+		return nil
+	case "rabin":
+		var sizes []int
+		for _, s := range parts[1:] {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("%q has an invalid size: %s", chunker, s)
+			}
+			sizes = append(sizes, n)
+		}
+		switch len(sizes) {
+		case 0, 1:
+		case 3:
+			if sizes[0] > sizes[1] || sizes[1] > sizes[2] {
+				return fmt.Errorf("%q should be rabin-<min>-<avg>-<max> with min <= avg <= max", chunker)
+			}
+			if sizes[2] > maxChunkSize {
+				return fmt.Errorf("chunks cannot be larger than %d bytes", maxChunkSize)
+			}
+		default:
+			return fmt.Errorf("%q should be rabin-<min>-<avg>-<max> or rabin-<avg>", chunker)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unrecognized chunker %q", chunker)
+	}
+}
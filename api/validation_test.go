@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPinOptionsValidate(t *testing.T) {
+	po := &PinOptions{
+		ReplicationFactorMin: 2,
+		ReplicationFactorMax: 3,
+	}
+	if err := po.Validate(); err != nil {
+		t.Error("expected valid options:", err)
+	}
+
+	po.ReplicationFactorMin = 4
+	err := po.Validate()
+	verrs, ok := err.(ValidationErrors)
+	if !ok || len(verrs) != 1 || verrs[0].Field != "replication-min" {
+		t.Errorf("expected a replication-min error: %v", err)
+	}
+
+	po.ReplicationFactorMin = -1
+	if err := po.Validate(); err == nil {
+		t.Error("expected an error when only replication-min is -1")
+	}
+
+	po.ReplicationFactorMin = -2
+	po.ReplicationFactorMax = -3
+	verrs, _ = po.Validate().(ValidationErrors)
+	if len(verrs) != 2 {
+		t.Errorf("expected errors for both replication factors: %v", verrs)
+	}
+}
+
+func TestAddParamsValidate(t *testing.T) {
+	p := DefaultAddParams()
+	if err := p.Validate(); err != nil {
+		t.Fatal("default params should be valid:", err)
+	}
+
+	testcases := []struct {
+		field string
+		set   func(p *AddParams)
+	}{
+		{"chunker", func(p *AddParams) { p.Chunker = "size-abc" }},
+		{"chunker", func(p *AddParams) { p.Chunker = "size-0" }},
+		{"chunker", func(p *AddParams) { p.Chunker = "size-2097152" }},
+		{"chunker", func(p *AddParams) { p.Chunker = "rabin-300-200-400" }},
+		{"chunker", func(p *AddParams) { p.Chunker = "rabin-1-2" }},
+		{"chunker", func(p *AddParams) { p.Chunker = "fastcdc" }},
+		{"layout", func(p *AddParams) { p.Layout = "sideways" }},
+		{"hash", func(p *AddParams) { p.HashFun = "md5-but-faster" }},
+		{"hash", func(p *AddParams) { p.HashFun = "blake2b-256" }},
+		{"cid-version", func(p *AddParams) { p.CidVersion = 2 }},
+		{"nocopy", func(p *AddParams) { p.NoCopy = true }},
+		{"replication-min", func(p *AddParams) {
+			p.ReplicationFactorMin = 3
+			p.ReplicationFactorMax = 2
+		}},
+		{"shard-size", func(p *AddParams) {
+			p.Shard = true
+			p.ShardSize = 0
+		}},
+		{"manifest", func(p *AddParams) {
+			p.Shard = true
+			p.Manifest = true
+		}},
+	}
+
+	for _, tc := range testcases {
+		p := DefaultAddParams()
+		tc.set(p)
+		verrs, ok := p.Validate().(ValidationErrors)
+		if !ok || len(verrs) != 1 || verrs[0].Field != tc.field {
+			t.Errorf("%+v: expected a single %s error, got %v", p, tc.field, verrs)
+		}
+	}
+
+	for _, chunker := range []string{"", "default", "size-1024", "rabin", "rabin-1024", "rabin-512-1024-2048"} {
+		p := DefaultAddParams()
+		p.Chunker = chunker
+		if err := p.Validate(); err != nil {
+			t.Errorf("chunker %s should be valid: %s", chunker, err)
+		}
+	}
+}
+
+func TestAddParamsFromQueryImplied(t *testing.T) {
+	q, _ := url.ParseQuery("nocopy=true&hash=blake2b-256")
+	p, err := AddParamsFromQuery(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.RawLeaves || p.CidVersion != 1 {
+		t.Error("nocopy and hash should imply raw-leaves and cid-version=1")
+	}
+
+	q, _ = url.ParseQuery("nocopy=true&raw-leaves=false")
+	_, err = AddParamsFromQuery(q)
+	if err == nil {
+		t.Error("expected an error with nocopy and raw-leaves=false")
+	}
+
+	q, _ = url.ParseQuery("hidden=maybe")
+	_, err = AddParamsFromQuery(q)
+	verr, ok := err.(*ValidationError)
+	if !ok || verr.Field != "hidden" {
+		t.Errorf("expected a hidden error: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	ds "github.com/ipfs/go-datastore"
+	namespace "github.com/ipfs/go-datastore/namespace"
+	query "github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log"
+
+	"go.opencensus.io/trace"
+)
+
+// The audit log records the destructive operations run by this peer, like
+// bulk unpins, along with their outcome. Records are logged by the "audit"
+// facility and kept in the datastore of the peer, which retains the last
+// auditLogMaxRecords of them. They can be retrieved with Cluster.AuditLog().
+
+var auditLogger = logging.Logger("audit")
+
+// auditNamespace is the datastore namespace under which audit records are
+// stored. Keys sort in the order the records were written.
+var auditNamespace = "/audit"
+
+// auditLogMaxRecords is the number of records kept in the datastore.
+var auditLogMaxRecords = 10000
+
+func (c *Cluster) auditStore() ds.Datastore {
+	return namespace.Wrap(c.datastore, ds.NewKey(auditNamespace))
+}
+
+// audit records an operation in the audit log.
+func (c *Cluster) audit(ctx context.Context, op, outcome, details string) {
+	rec := &api.AuditRecord{
+		Time:      time.Now(),
+		Peer:      c.id,
+		Operation: op,
+		Outcome:   outcome,
+		Details:   details,
+	}
+
+	msg := fmt.Sprintf("%s: %s", op, outcome)
+	if details != "" {
+		msg += ": " + details
+	}
+	switch outcome {
+	case api.AuditFailed:
+		auditLogger.Error(msg)
+	case api.AuditCompleted:
+		auditLogger.Info(msg)
+	default:
+		auditLogger.Warning(msg)
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	c.auditMux.Lock()
+	defer c.auditMux.Unlock()
+
+	c.auditSeq++
+	key := ds.NewKey(fmt.Sprintf("%020d-%06d", rec.Time.UnixNano(), c.auditSeq%1000000))
+	store := c.auditStore()
+	err = store.Put(key, data)
+	if err != nil {
+		logger.Errorf("error storing audit record: %s", err)
+		return
+	}
+	c.pruneAuditLog(store)
+}
+
+// pruneAuditLog removes the oldest records over auditLogMaxRecords.
+func (c *Cluster) pruneAuditLog(store ds.Datastore) {
+	results, err := store.Query(query.Query{KeysOnly: true})
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	if len(entries) <= auditLogMaxRecords {
+		return
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	sort.Strings(keys)
+	for _, k := range keys[:len(keys)-auditLogMaxRecords] {
+		err := store.Delete(ds.NewKey(k))
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+}
+
+// AuditLog returns the records in the audit log of this peer, oldest first.
+func (c *Cluster) AuditLog(ctx context.Context) ([]*api.AuditRecord, error) {
+	_, span := trace.StartSpan(ctx, "cluster/AuditLog")
+	defer span.End()
+
+	results, err := c.auditStore().Query(query.Query{})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	type keyedRecord struct {
+		key string
+		rec *api.AuditRecord
+	}
+	var keyed []keyedRecord
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		rec := &api.AuditRecord{}
+		if err := json.Unmarshal(r.Value, rec); err != nil {
+			logger.Warningf("skipping unreadable audit record %s: %s", r.Key, err)
+			continue
+		}
+		keyed = append(keyed, keyedRecord{r.Key, rec})
+	}
+	sort.Slice(keyed, func(i, j int) bool {
+		return keyed[i].key < keyed[j].key
+	})
+
+	recs := make([]*api.AuditRecord, 0, len(keyed))
+	for _, kr := range keyed {
+		recs = append(recs, kr.rec)
+	}
+	return recs, nil
+}
@@ -0,0 +1,356 @@
+package ipfscluster
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/observations"
+)
+
+// Cluster peers can send each other the blocks of pinned content directly
+// over the block transfer protocol. When a pin is re-allocated (which
+// includes shard repair), its new allocations are asked to fetch its
+// blocks from its previous allocations, so that they do not depend on
+// bitswap finding providers. This is best-effort: content which cannot be
+// transferred is fetched by IPFS as usual. Peers only send the blocks of
+// pins in the shared state, only to trusted peers, and every block is
+// checked against its CID before it is put. Both directions are limited
+// by BlockTransferBandwidthLimit.
+//
+// A request is the JSON-encoded api.BlockFetch of the pin (its sources are
+// ignored). The response is a sequence of blocks, each sent as the
+// uvarint-prefixed bytes of its CID followed by its uvarint-prefixed data.
+// The stream is closed once all blocks are sent, or reset on errors.
+
+// BlockTransferProtocol is the libp2p protocol used to transfer blocks
+// between cluster peers.
+const BlockTransferProtocol = protocol.ID("/ipfscluster/blocks/1.0.0")
+
+// blockTransferTimeout limits how long a transfer may take.
+var blockTransferTimeout = 30 * time.Minute
+
+const (
+	maxTransferCidSize   = 256
+	maxTransferBlockSize = 4 << 20
+)
+
+// bandwidthLimiter spaces out blocks so that, on average, no more than
+// rate bytes per second go through it. A nil bandwidthLimiter does not
+// limit anything.
+type bandwidthLimiter struct {
+	mu   sync.Mutex
+	rate float64   // bytes per second
+	next time.Time // when the next block may go through
+}
+
+func newBandwidthLimiter(rate uint64) *bandwidthLimiter {
+	if rate == 0 {
+		return nil
+	}
+	return &bandwidthLimiter{rate: float64(rate)}
+}
+
+// wait blocks until a block of n bytes may go through.
+func (bl *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	if bl == nil {
+		return nil
+	}
+
+	bl.mu.Lock()
+	now := time.Now()
+	if bl.next.Before(now) {
+		bl.next = now
+	}
+	wait := bl.next.Sub(now)
+	bl.next = bl.next.Add(time.Duration(float64(n) / bl.rate * float64(time.Second)))
+	bl.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeBlockFrame(w io.Writer, c cid.Cid, data []byte) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, field := range [][]byte{c.Bytes(), data} {
+		n := binary.PutUvarint(buf, uint64(len(field)))
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+		if _, err := w.Write(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBlockFrame reads a block written by writeBlockFrame. It returns
+// io.EOF only when there are no more blocks.
+func readBlockFrame(r *bufio.Reader) (cid.Cid, []byte, error) {
+	cidBytes, err := readFrameField(r, maxTransferCidSize)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	c, err := cid.Cast(cidBytes)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	data, err := readFrameField(r, maxTransferBlockSize)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return c, data, nil
+}
+
+func readFrameField(r *bufio.Reader, max uint64) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > max {
+		return nil, fmt.Errorf("block frame too large: %d bytes", size)
+	}
+	field := make([]byte, size)
+	_, err = io.ReadFull(r, field)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return field, err
+}
+
+// blockFormat returns the format used to put the given block in IPFS.
+func blockFormat(c cid.Cid) string {
+	if c.Prefix().Version == 0 {
+		return "v0"
+	}
+	return cid.CodecToStr[c.Type()]
+}
+
+func recordBlockTransfer(ctx context.Context, direction string, remote peer.ID, tr *api.BlockTransfer, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	var bytes uint64
+	if tr != nil {
+		bytes = tr.Bytes
+	}
+	stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{
+			tag.Upsert(observations.DirectionKey, direction),
+			tag.Upsert(observations.RemotePeerKey, remote.Pretty()),
+			tag.Upsert(observations.ResultKey, result),
+		},
+		observations.BlockTransfers.M(1),
+		observations.BlockTransferBytes.M(int64(bytes)),
+	)
+}
+
+// handleBlockTransfer serves a block transfer request.
+func (c *Cluster) handleBlockTransfer(s inet.Stream) {
+	remote := s.Conn().RemotePeer()
+	ctx, cancel := context.WithTimeout(c.ctx, blockTransferTimeout)
+	defer cancel()
+	ctx, span := trace.StartSpan(ctx, "cluster/handleBlockTransfer")
+	defer span.End()
+
+	s.SetDeadline(time.Now().Add(blockTransferTimeout))
+	tr, err := c.sendBlocks(ctx, s, remote)
+	recordBlockTransfer(ctx, "sent", remote, tr, err)
+	if err != nil {
+		logger.Warningf("error sending blocks to %s: %s", remote.Pretty(), err)
+		s.Reset()
+		return
+	}
+	s.Close()
+	logger.Debugf("sent %d blocks (%d bytes) of %s to %s", tr.Blocks, tr.Bytes, tr.Cid, remote.Pretty())
+}
+
+func (c *Cluster) sendBlocks(ctx context.Context, s inet.Stream, remote peer.ID) (*api.BlockTransfer, error) {
+	if !c.consensus.IsTrustedPeer(ctx, remote) {
+		return nil, errors.New("the peer is not trusted")
+	}
+
+	var req api.BlockFetch
+	err := json.NewDecoder(s).Decode(&req)
+	if err != nil {
+		return nil, err
+	}
+	pin, err := c.PinGet(ctx, req.Cid)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in the shared state", req.Cid)
+	}
+	blocks, err := c.ipfs.DAGBlocks(ctx, pin.Cid, pin.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &api.BlockTransfer{
+		Cid:    pin.Cid,
+		Source: c.id,
+	}
+	w := bufio.NewWriter(s)
+	for _, b := range blocks {
+		data, err := c.ipfs.BlockGet(ctx, b.Cid)
+		if err != nil {
+			return tr, err
+		}
+		err = c.blockSendLimit.wait(ctx, len(data))
+		if err != nil {
+			return tr, err
+		}
+		err = writeBlockFrame(w, b.Cid, data)
+		if err != nil {
+			return tr, err
+		}
+		tr.Blocks++
+		tr.Bytes += uint64(len(data))
+	}
+	return tr, w.Flush()
+}
+
+// BlockFetch fetches the blocks of a pin from the first of the given
+// sources which sends them all, and puts them in the IPFS daemon of this
+// peer.
+func (c *Cluster) BlockFetch(ctx context.Context, fetch *api.BlockFetch) (*api.BlockTransfer, error) {
+	_, span := trace.StartSpan(ctx, "cluster/BlockFetch")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	if c.config.DisableBlockTransfer {
+		return nil, errors.New("block transfers are disabled on this peer")
+	}
+
+	err := errors.New("no peers to fetch the blocks from")
+	for _, src := range fetch.Sources {
+		if src == c.id {
+			continue
+		}
+		var tr *api.BlockTransfer
+		tr, err = c.fetchBlocksFrom(ctx, src, fetch.Cid)
+		recordBlockTransfer(ctx, "received", src, tr, err)
+		if err == nil {
+			return tr, nil
+		}
+		logger.Debugf("error fetching the blocks of %s from %s: %s", fetch.Cid, src.Pretty(), err)
+	}
+	return nil, err
+}
+
+func (c *Cluster) fetchBlocksFrom(ctx context.Context, src peer.ID, h cid.Cid) (*api.BlockTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, blockTransferTimeout)
+	defer cancel()
+
+	s, err := c.host.NewStream(ctx, src, BlockTransferProtocol)
+	if err != nil {
+		return nil, err
+	}
+	s.SetDeadline(time.Now().Add(blockTransferTimeout))
+
+	tr := &api.BlockTransfer{
+		Cid:    h,
+		Source: src,
+	}
+	err = c.receiveBlocks(ctx, s, tr)
+	if err != nil {
+		s.Reset()
+		return tr, err
+	}
+	s.Close()
+	return tr, nil
+}
+
+func (c *Cluster) receiveBlocks(ctx context.Context, s inet.Stream, tr *api.BlockTransfer) error {
+	err := json.NewEncoder(s).Encode(&api.BlockFetch{Cid: tr.Cid})
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(s)
+	for {
+		bc, data, err := readBlockFrame(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sum, err := bc.Prefix().Sum(data)
+		if err != nil || !sum.Equals(bc) {
+			return fmt.Errorf("block %s does not match its CID", bc)
+		}
+		err = c.blockRecvLimit.wait(ctx, len(data))
+		if err != nil {
+			return err
+		}
+		err = c.ipfs.BlockPut(ctx, &api.NodeWithMeta{
+			Cid:    bc,
+			Data:   data,
+			Format: blockFormat(bc),
+		})
+		if err != nil {
+			return err
+		}
+		tr.Blocks++
+		tr.Bytes += uint64(len(data))
+	}
+}
+
+// requestBlockTransfers asks the new allocations of a re-allocated pin to
+// fetch its blocks from the given peers, which had it allocated.
+func (c *Cluster) requestBlockTransfers(pin *api.Pin, sources []peer.ID) {
+	if c.config.DisableBlockTransfer || len(sources) == 0 {
+		return
+	}
+
+	fetch := &api.BlockFetch{
+		Cid:     pin.Cid,
+		Sources: sources,
+	}
+	for _, p := range pin.Allocations {
+		if containsPeer(sources, p) {
+			continue
+		}
+		go func(p peer.ID) {
+			ctx, cancel := context.WithTimeout(c.ctx, blockTransferTimeout)
+			defer cancel()
+
+			var tr api.BlockTransfer
+			err := c.rpcClient.CallContext(ctx, p, "Cluster", "BlockFetch", fetch, &tr)
+			if err != nil {
+				logger.Infof("%s could not fetch %s from other cluster peers, leaving it to IPFS: %s", p.Pretty(), pin.Cid, err)
+				return
+			}
+			logger.Infof("%s fetched %d blocks (%d bytes) of %s from %s", p.Pretty(), tr.Blocks, tr.Bytes, pin.Cid, tr.Source.Pretty())
+		}(p)
+	}
+}
@@ -0,0 +1,131 @@
+package ipfscluster
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/test"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+func TestBlockFrames(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeBlockFrame(&buf, test.Cid1, []byte("block1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writeBlockFrame(&buf, test.Cid2, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := buf.Bytes()
+
+	r := bufio.NewReader(bytes.NewReader(frames))
+	c, data, err := readBlockFrame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Equals(test.Cid1) || string(data) != "block1" {
+		t.Errorf("unexpected first block: %s %q", c, data)
+	}
+	c, data, err = readBlockFrame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Equals(test.Cid2) || len(data) != 0 {
+		t.Errorf("unexpected second block: %s %q", c, data)
+	}
+	_, _, err = readBlockFrame(r)
+	if err != io.EOF {
+		t.Errorf("expected io.EOF after the last block, got %v", err)
+	}
+
+	r = bufio.NewReader(bytes.NewReader(frames[:len(frames)-3]))
+	readBlockFrame(r)
+	_, _, err = readBlockFrame(r)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF for a truncated block, got %v", err)
+	}
+}
+
+func TestBandwidthLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	unlimited := newBandwidthLimiter(0)
+	if err := unlimited.wait(ctx, 1<<30); err != nil {
+		t.Fatal(err)
+	}
+
+	bl := newBandwidthLimiter(1000)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := bl.wait(ctx, 250); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The third block waits for the first two: 500 bytes at 1000B/s.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("blocks went through too fast: %s", elapsed)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := bl.wait(cctx, 250); err == nil {
+		t.Error("expected an error waiting with a cancelled context")
+	}
+}
+
+func TestClustersBlockFetch(t *testing.T) {
+	ctx := context.Background()
+	if nClusters < 2 {
+		t.Skip("Need at least 2 peers")
+	}
+
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+
+	data := []byte("block transfer test")
+	h, err := cid.V0Builder{}.Sum(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = clusters[0].ipfs.BlockPut(ctx, &api.NodeWithMeta{Cid: h, Data: data, Format: "v0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := &api.BlockFetch{
+		Cid:     h,
+		Sources: []peer.ID{clusters[0].id},
+	}
+	_, err = clusters[1].BlockFetch(ctx, fetch)
+	if err == nil {
+		t.Fatal("content which is not in the shared state should not be sent")
+	}
+
+	pin := api.PinCid(h)
+	pin.MaxDepth = 0
+	err = clusters[0].Pin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+
+	tr, err := clusters[1].BlockFetch(ctx, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Blocks != 1 || tr.Bytes != uint64(len(data)) || tr.Source != clusters[0].id {
+		t.Errorf("unexpected transfer: %+v", tr)
+	}
+	if got := mock[1].BlockStore[h.String()]; !bytes.Equal(got, data) {
+		t.Error("the block should have been put in the IPFS daemon of the peer")
+	}
+}
@@ -0,0 +1,146 @@
+package ipfscluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// Operations which unpin many items at once are refused when they would
+// unpin more than the allowed share of the pinset (see
+// Config.BulkUnpinThreshold) unless they are forced. When
+// Config.BulkUnpinConfirmation is set, a forced request only returns a
+// confirmation token, and the operation proceeds when the request is
+// repeated with it. A mistaken request cannot then wipe the pinset in one
+// go. Refused, forced and confirmed operations are recorded in the audit
+// log.
+
+// bulkUnpinToken is a confirmation token handed out for a forced bulk
+// unpin. It is only valid for the same operation on the same pinset.
+type bulkUnpinToken struct {
+	op      string
+	unpins  int
+	total   int
+	expires time.Time
+}
+
+// bulkUnpinLimit returns the number of items which may be unpinned from a
+// pinset of the given size, and whether unpins exceeds it.
+func (c *Cluster) bulkUnpinLimit(unpins, total int) (int, bool) {
+	limit := -1
+	if t := c.config.BulkUnpinThreshold; t >= 0 {
+		limit = t
+	}
+	if p := c.config.BulkUnpinThresholdPercent; p >= 0 {
+		l := total * p / 100
+		if limit < 0 || l < limit {
+			limit = l
+		}
+	}
+	return limit, limit >= 0 && unpins > limit
+}
+
+// checkBulkUnpin returns an error when the given operation would unpin
+// more than the allowed share of a pinset of the given size and is not
+// forced. It is used by operations which cluster runs on its own, and
+// which therefore cannot be confirmed.
+func (c *Cluster) checkBulkUnpin(ctx context.Context, op string, unpins, total int, force bool) error {
+	limit, over := c.bulkUnpinLimit(unpins, total)
+	if !over {
+		return nil
+	}
+	details := fmt.Sprintf("unpins %d of %d pins (limit %d)", unpins, total, limit)
+	if force {
+		c.audit(ctx, op, api.AuditForced, details)
+		return nil
+	}
+	c.audit(ctx, op, api.AuditRefused, details)
+	return &api.BulkUnpinError{
+		Operation: op,
+		Unpins:    unpins,
+		Total:     total,
+		Limit:     limit,
+	}
+}
+
+// checkBulkUnpinRequest is like checkBulkUnpin for operations requested
+// through the APIs. When confirmations are enabled, forcing them returns
+// an error with a token, and they only proceed when repeated with it.
+func (c *Cluster) checkBulkUnpinRequest(ctx context.Context, op string, unpins, total int, opts api.BulkUnpinOptions) error {
+	if !opts.Force || !c.config.BulkUnpinConfirmation {
+		return c.checkBulkUnpin(ctx, op, unpins, total, opts.Force)
+	}
+
+	limit, over := c.bulkUnpinLimit(unpins, total)
+	if !over {
+		return nil
+	}
+	details := fmt.Sprintf("unpins %d of %d pins (limit %d)", unpins, total, limit)
+	if opts.Confirm != "" {
+		if c.useBulkUnpinToken(opts.Confirm, op, unpins, total) {
+			c.audit(ctx, op, api.AuditConfirmed, details)
+			return nil
+		}
+		c.audit(ctx, op, api.AuditRefused, details+": invalid or expired confirmation token")
+	}
+
+	token, err := c.newBulkUnpinToken(op, unpins, total)
+	if err != nil {
+		return err
+	}
+	c.audit(ctx, op, api.AuditPending, details)
+	return &api.BulkUnpinError{
+		Operation: op,
+		Unpins:    unpins,
+		Total:     total,
+		Limit:     limit,
+		Token:     token,
+	}
+}
+
+// newBulkUnpinToken returns a new confirmation token for the given
+// operation. Expired tokens are dropped.
+func (c *Cluster) newBulkUnpinToken(op string, unpins, total int) (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	now := time.Now()
+	c.bulkUnpinTokensMux.Lock()
+	defer c.bulkUnpinTokensMux.Unlock()
+	for t, bt := range c.bulkUnpinTokens {
+		if now.After(bt.expires) {
+			delete(c.bulkUnpinTokens, t)
+		}
+	}
+	c.bulkUnpinTokens[token] = &bulkUnpinToken{
+		op:      op,
+		unpins:  unpins,
+		total:   total,
+		expires: now.Add(c.config.BulkUnpinConfirmTimeout),
+	}
+	return token, nil
+}
+
+// useBulkUnpinToken consumes the given token and returns whether it was
+// valid for the given operation.
+func (c *Cluster) useBulkUnpinToken(token, op string, unpins, total int) bool {
+	c.bulkUnpinTokensMux.Lock()
+	defer c.bulkUnpinTokensMux.Unlock()
+	bt, ok := c.bulkUnpinTokens[token]
+	if !ok {
+		return false
+	}
+	delete(c.bulkUnpinTokens, token)
+	return bt.op == op &&
+		bt.unpins == unpins &&
+		bt.total == total &&
+		time.Now().Before(bt.expires)
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"mime/multipart"
+	"net"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,7 +15,11 @@ import (
 	"github.com/ipfs/ipfs-cluster/adder/local"
 	"github.com/ipfs/ipfs-cluster/adder/sharding"
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/faults"
+	"github.com/ipfs/ipfs-cluster/observations"
+	"github.com/ipfs/ipfs-cluster/policy"
 	"github.com/ipfs/ipfs-cluster/pstoremgr"
+	"github.com/ipfs/ipfs-cluster/ratelimit"
 	"github.com/ipfs/ipfs-cluster/rpcutil"
 	"github.com/ipfs/ipfs-cluster/state"
 	"github.com/ipfs/ipfs-cluster/version"
@@ -27,6 +33,8 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 
 	ocgorpc "github.com/lanzafame/go-libp2p-ocgorpc"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	trace "go.opencensus.io/trace"
 )
 
@@ -38,6 +46,15 @@ var ReadyTimeout = 30 * time.Second
 
 var pingMetricName = "ping"
 
+// allocatableMetricName is the name of the metric used to let other peers
+// know whether this peer accepts new allocations. It is pushed along with
+// the ping metric.
+var allocatableMetricName = "allocatable"
+
+// operationStatsInterval is how often the stats of the pintracker
+// operations are recorded as metrics.
+var operationStatsInterval = 10 * time.Second
+
 // Cluster is the main IPFS cluster component. It provides
 // the go-API for it and orchestrates the components that make up the system.
 type Cluster struct {
@@ -53,6 +70,7 @@ type Cluster struct {
 	rpcServer   *rpc.Server
 	rpcClient   *rpc.Client
 	peerManager *pstoremgr.Manager
+	connGater   *connGater
 
 	consensus Consensus
 	apis      []API
@@ -60,7 +78,8 @@ type Cluster struct {
 	tracker   PinTracker
 	monitor   PeerMonitor
 	allocator PinAllocator
-	informer  Informer
+	informers []Informer
+	informer  Informer // the one used for allocations (informers[0])
 	tracer    Tracer
 
 	doneCh  chan struct{}
@@ -71,6 +90,58 @@ type Cluster struct {
 	// peerAdd
 	paMux sync.Mutex
 
+	// last run of StateSync
+	lastStateSync    *api.StateSync
+	lastStateSyncMux sync.RWMutex
+
+	// capacity reserved by allocations made by this peer
+	reservations *reservations
+
+	// Pin submissions in progress, used to collapse identical requests
+	pinInflight    map[string]*inflightPin
+	pinInflightMux sync.Mutex
+
+	// confirmation tokens handed out for forced bulk unpins
+	bulkUnpinTokens    map[string]*bulkUnpinToken
+	bulkUnpinTokensMux sync.Mutex
+
+	auditMux sync.Mutex
+	auditSeq uint64
+
+	// fault injection, only set when enabled in the configuration
+	faults *faults.Injector
+
+	// peers and status of the reconnection manager
+	reconnect *reconnectState
+
+	// throttles the pins and unpins committed by this peer
+	pinRate *ratelimit.Bucket
+
+	// denied content
+	policy *policy.Filter
+
+	// checks the signatures of pin requests
+	pinVerifier *pinVerifier
+
+	// serializes changes to the stored pinset snapshots
+	snapshotsMux sync.Mutex
+
+	gcGuard *repoGCGuard
+
+	// pins being transferred away from a peer
+	transfers *pinTransfers
+
+	// limit the bandwidth used by the block transfer protocol
+	blockSendLimit *bandwidthLimiter
+	blockRecvLimit *bandwidthLimiter
+
+	// last recovery job started by this peer
+	recovery *recoveryState
+
+	// last aggregate cluster facts, reused for clusterInfoCacheTTL
+	clusterInfo    *api.ClusterInfo
+	clusterInfoMux sync.Mutex
+
 	// shutdown function and related variables
 	shutdownLock sync.Mutex
 	shutdownB    bool
@@ -83,6 +154,9 @@ type Cluster struct {
 // The new cluster peer may still be performing initialization tasks when
 // this call returns (consensus may still be bootstrapping). Use Cluster.Ready()
 // if you need to wait until the peer is fully up.
+//
+// The metrics of all the given informers are published, but only the
+// first informer is used to allocate pins.
 func NewCluster(
 	ctx context.Context,
 	host host.Host,
@@ -95,7 +169,7 @@ func NewCluster(
 	tracker PinTracker,
 	monitor PeerMonitor,
 	allocator PinAllocator,
-	informer Informer,
+	informers []Informer,
 	tracer Tracer,
 ) (*Cluster, error) {
 	err := cfg.Validate()
@@ -103,6 +177,10 @@ func NewCluster(
 		return nil, err
 	}
 
+	if len(informers) == 0 {
+		return nil, errors.New("no informers provided")
+	}
+
 	if host == nil {
 		return nil, errors.New("cluster host is nil")
 	}
@@ -116,9 +194,21 @@ func NewCluster(
 
 	logger.Infof("IPFS Cluster v%s listening on:\n%s\n", version.Version, listenAddrs)
 
+	var injector *faults.Injector
+	if cfg.EnableFaultInjection {
+		logger.Warning("fault injection is enabled. Do not use this in production")
+		injector = faults.New()
+		ipfs = &faultyIPFSConnector{IPFSConnector: ipfs, faults: injector}
+	}
+
 	// Note, we already loaded peers from peerstore into the host
 	// in daemon.go.
 	peerManager := pstoremgr.New(host, cfg.GetPeerstorePath())
+	peerManager.SetDatastore(datastore, cfg.PeerstoreTTL)
+	err = peerManager.ImportPersistedPeers(false)
+	if err != nil {
+		logger.Errorf("error importing persisted peer addresses: %s", err)
+	}
 
 	c := &Cluster{
 		ctx:         ctx,
@@ -134,7 +224,8 @@ func NewCluster(
 		tracker:     tracker,
 		monitor:     monitor,
 		allocator:   allocator,
-		informer:    informer,
+		informers:   informers,
+		informer:    informers[0],
 		tracer:      tracer,
 		peerManager: peerManager,
 		shutdownB:   false,
@@ -142,14 +233,35 @@ func NewCluster(
 		doneCh:      make(chan struct{}),
 		readyCh:     make(chan struct{}),
 		readyB:      false,
+		pinInflight: make(map[string]*inflightPin),
+		faults:      injector,
+
+		reservations:    newReservations(),
+		bulkUnpinTokens: make(map[string]*bulkUnpinToken),
+		reconnect:       newReconnectState(cfg.PeerAddresses),
+		pinRate:         ratelimit.NewBucket(cfg.PinRateLimit),
+		policy:          policy.New(cfg.AllowedCIDs, cfg.DeniedCIDs),
+		pinVerifier:     newPinVerifier(cfg),
+		gcGuard:         newRepoGCGuard(),
+		transfers:       newPinTransfers(),
+		recovery:        &recoveryState{},
+
+		blockSendLimit: newBandwidthLimiter(cfg.BlockTransferBandwidthLimit),
+		blockRecvLimit: newBandwidthLimiter(cfg.BlockTransferBandwidthLimit),
 	}
 
+	c.connGater = newConnGater(host, cfg)
+	c.connGater.start()
+
 	err = c.setupRPC()
 	if err != nil {
 		c.Shutdown(ctx)
 		return nil, err
 	}
 	c.setupRPCClients()
+	if !cfg.DisableBlockTransfer {
+		host.SetStreamHandler(BlockTransferProtocol, c.handleBlockTransfer)
+	}
 	go func() {
 		c.ready(ReadyTimeout)
 		c.run()
@@ -159,7 +271,13 @@ func NewCluster(
 }
 
 func (c *Cluster) setupRPC() error {
-	rpcServer, err := newRPCServer(c)
+	h := newRPCHost(
+		c.host,
+		version.RPCProtocol,
+		c.config.RPCCompression,
+		newRPCScheduler(c.ctx, c.config.RPCClassBudgets),
+	)
+	rpcServer, err := newRPCServer(c, h)
 	if err != nil {
 		return err
 	}
@@ -169,13 +287,13 @@ func (c *Cluster) setupRPC() error {
 	if c.config.Tracing {
 		csh := &ocgorpc.ClientHandler{}
 		rpcClient = rpc.NewClientWithServer(
-			c.host,
+			h,
 			version.RPCProtocol,
 			rpcServer,
 			rpc.WithClientStatsHandler(csh),
 		)
 	} else {
-		rpcClient = rpc.NewClientWithServer(c.host, version.RPCProtocol, rpcServer)
+		rpcClient = rpc.NewClientWithServer(h, version.RPCProtocol, rpcServer)
 	}
 	c.rpcClient = rpcClient
 	return nil
@@ -190,7 +308,9 @@ func (c *Cluster) setupRPCClients() {
 	c.consensus.SetClient(c.rpcClient)
 	c.monitor.SetClient(c.rpcClient)
 	c.allocator.SetClient(c.rpcClient)
-	c.informer.SetClient(c.rpcClient)
+	for _, informer := range c.informers {
+		informer.SetClient(c.rpcClient)
+	}
 }
 
 // syncWatcher loops and triggers StateSync and SyncAllLocal from time to time
@@ -216,19 +336,54 @@ func (c *Cluster) syncWatcher() {
 	}
 }
 
-func (c *Cluster) sendInformerMetric(ctx context.Context) (*api.Metric, error) {
+// recordOperationStats records the stats of the pintracker operations as
+// metrics, every operationStatsInterval.
+func (c *Cluster) recordOperationStats(reporter OperationStatsReporter) {
+	ticker := time.NewTicker(operationStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, s := range reporter.OperationStats(c.ctx) {
+				stats.RecordWithTags(
+					c.ctx,
+					[]tag.Mutator{tag.Upsert(observations.StatusKey, s.Status.String())},
+					observations.TrackerOperations.M(int64(s.Count)),
+					observations.TrackerOperationAge.M(float64(s.OldestAge)/float64(time.Millisecond)),
+				)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cluster) sendInformerMetric(ctx context.Context, informer Informer) (*api.Metric, error) {
 	ctx, span := trace.StartSpan(ctx, "cluster/sendInformerMetric")
 	defer span.End()
 
-	metric := c.informer.GetMetric(ctx)
+	metric := informer.GetMetric(ctx)
 	metric.Peer = c.id
 	return metric, c.monitor.PublishMetric(ctx, metric)
 }
 
+// sendInformersMetrics publishes the metrics of all informers.
+func (c *Cluster) sendInformersMetrics(ctx context.Context) error {
+	var err error
+	for _, informer := range c.informers {
+		_, ierr := c.sendInformerMetric(ctx, informer)
+		if ierr != nil {
+			err = ierr
+		}
+	}
+	return err
+}
+
 // pushInformerMetrics loops and publishes informers metrics using the
-// cluster monitor. Metrics are pushed normally at a TTL/2 rate. If an error
-// occurs, they are pushed at a TTL/4 rate.
-func (c *Cluster) pushInformerMetrics(ctx context.Context) {
+// cluster monitor. Metrics are pushed at the rate decided by the informer
+// (TTL/2 by default). If an error occurs, they are pushed at a TTL/4 rate.
+func (c *Cluster) pushInformerMetrics(ctx context.Context, informer Informer) {
 	ctx, span := trace.StartSpan(ctx, "cluster/pushInformerMetrics")
 	defer span.End()
 
@@ -250,7 +405,7 @@ func (c *Cluster) pushInformerMetrics(ctx context.Context) {
 			// wait
 		}
 
-		metric, err := c.sendInformerMetric(ctx)
+		metric, err := c.sendInformerMetric(ctx, informer)
 
 		if err != nil {
 			if (retries % retryWarnMod) == 0 {
@@ -263,8 +418,7 @@ func (c *Cluster) pushInformerMetrics(ctx context.Context) {
 		}
 
 		retries = 0
-		// send metric again in TTL/2
-		timer.Reset(metric.GetTTL() / 2)
+		timer.Reset(informer.PushInterval(metric))
 	}
 }
 
@@ -281,6 +435,20 @@ func (c *Cluster) sendPingMetric(ctx context.Context) (*api.Metric, error) {
 	return metric, c.monitor.PublishMetric(ctx, metric)
 }
 
+func (c *Cluster) sendAllocatableMetric(ctx context.Context) (*api.Metric, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/sendAllocatableMetric")
+	defer span.End()
+
+	metric := &api.Metric{
+		Name:  allocatableMetricName,
+		Peer:  c.id,
+		Value: strconv.FormatBool(c.config.IsAllocatable()),
+		Valid: true,
+	}
+	metric.SetTTL(c.config.MonitorPingInterval * 2)
+	return metric, c.monitor.PublishMetric(ctx, metric)
+}
+
 func (c *Cluster) pushPingMetrics(ctx context.Context) {
 	ctx, span := trace.StartSpan(ctx, "cluster/pushPingMetrics")
 	defer span.End()
@@ -288,6 +456,8 @@ func (c *Cluster) pushPingMetrics(ctx context.Context) {
 	ticker := time.NewTicker(c.config.MonitorPingInterval)
 	for {
 		c.sendPingMetric(ctx)
+		c.sendAllocatableMetric(ctx)
+		c.sendReservationsMetric(ctx)
 
 		select {
 		case <-ctx.Done():
@@ -321,6 +491,9 @@ func (c *Cluster) alertsHandler() {
 			}
 			for _, pin := range list {
 				if len(pin.Allocations) == 1 && containsPeer(pin.Allocations, alrt.Peer) {
+					if pin.Type == api.ShardType {
+						continue // see repairShardsFromPeer
+					}
 					logger.Warning("a pin with only one allocation cannot be repinned")
 					logger.Warning("to make repinning possible, pin with a replication factor of 2+")
 					continue
@@ -329,6 +502,7 @@ func (c *Cluster) alertsHandler() {
 					c.repinFromPeer(c.ctx, alrt.Peer)
 				}
 			}
+			c.repairShardsFromPeer(c.ctx, alrt.Peer, list)
 		}
 	}
 }
@@ -398,33 +572,93 @@ func (c *Cluster) repinFromPeer(ctx context.Context, p peer.ID) {
 		return
 	}
 
-	cState, err := c.consensus.State(ctx)
+	_, err := c.migratePins(ctx, p)
 	if err != nil {
 		logger.Warning(err)
-		return
+	}
+}
+
+// migratePins re-allocates the pins allocated to the given peer to other
+// peers.
+func (c *Cluster) migratePins(ctx context.Context, p peer.ID) (*api.PeerMigration, error) {
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		return nil, err
 	}
 	list, err := cState.List(ctx)
 	if err != nil {
-		logger.Warning(err)
-		return
+		return nil, err
+	}
+
+	mig := &api.PeerMigration{
+		Peer: p,
+		Pins: []*api.Pin{},
 	}
 	for _, pin := range list {
-		if containsPeer(pin.Allocations, p) {
-			_, ok, err := c.pin(ctx, pin, []peer.ID{p}, []peer.ID{}) // pin blacklisting this peer
-			if ok && err == nil {
-				logger.Infof("repinned %s out of %s", pin.Cid, p.Pretty())
-			}
+		if !containsPeer(pin.Allocations, p) {
+			continue
+		}
+		pin.Provenance = &api.PinProvenance{Source: api.ProvenanceReallocation}
+		_, ok, err := c.reallocate(ctx, pin, p)
+		if err != nil {
+			logger.Errorf("error repinning %s out of %s: %s", pin.Cid, p.Pretty(), err)
+			mig.Errors = append(mig.Errors, fmt.Sprintf("%s: %s", pin.Cid, err))
+			continue
+		}
+		if ok {
+			logger.Infof("repinned %s out of %s", pin.Cid, p.Pretty())
+			mig.Pins = append(mig.Pins, pin)
 		}
 	}
+	return mig, nil
+}
+
+// PeerMigrate re-allocates the pins allocated to the given peer to other
+// peers, so that it can be removed without losing replicas. It is done
+// regardless of DisableRepinning. The returned pins carry their new
+// allocations, which can be watched until they are pinned.
+func (c *Cluster) PeerMigrate(ctx context.Context, pid peer.ID) (*api.PeerMigration, error) {
+	_, span := trace.StartSpan(ctx, "cluster/PeerMigrate")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	logger.Infof("migrating the pins allocated to %s", pid)
+	return c.migratePins(ctx, pid)
 }
 
 // run launches some go-routines which live throughout the cluster's life
 func (c *Cluster) run() {
 	go c.syncWatcher()
 	go c.pushPingMetrics(c.ctx)
-	go c.pushInformerMetrics(c.ctx)
+	for _, informer := range c.informers {
+		go c.pushInformerMetrics(c.ctx, informer)
+	}
+	go c.pushReservationsMetrics(c.ctx)
+	if c.config.ReplicationScalingInterval > 0 {
+		go c.replicationScalingWatcher()
+	}
+	if c.config.DHTProvideInterval > 0 {
+		go c.dhtProvideWatcher()
+	}
 	go c.watchPeers()
+	go c.peerstoreWatcher()
+	if c.config.ReconnectInterval > 0 {
+		go c.reconnectWatcher()
+	}
+	if c.config.PinRateLimit > 0 {
+		go c.pinRateWatcher()
+	}
+	if len(c.config.DenylistURLs) > 0 {
+		go c.denylistWatcher()
+	}
+	go c.pinScheduler()
+	if c.config.IPFSDriftCheckInterval > 0 {
+		go c.ipfsDriftWatcher()
+	}
 	go c.alertsHandler()
+	if reporter, ok := c.tracker.(OperationStatsReporter); ok {
+		go c.recordOperationStats(reporter)
+	}
 }
 
 func (c *Cluster) ready(timeout time.Duration) {
@@ -515,10 +749,13 @@ func (c *Cluster) Shutdown(ctx context.Context) error {
 
 	logger.Info("shutting down Cluster")
 
+	c.host.RemoveStreamHandler(BlockTransferProtocol)
+
 	// Try to store peerset file for all known peers whatsoever
 	// if we got ready (otherwise, don't overwrite anything)
 	if c.readyB {
 		c.peerManager.SavePeerstoreForPeers(c.host.Peerstore().Peers())
+		c.persistPeerstore()
 	}
 
 	// Only attempt to leave if:
@@ -576,6 +813,13 @@ func (c *Cluster) Shutdown(ctx context.Context) error {
 		return err
 	}
 
+	for _, informer := range c.informers {
+		if err := informer.Shutdown(ctx); err != nil {
+			logger.Errorf("error stopping informer: %s", err)
+			return err
+		}
+	}
+
 	if err := c.tracer.Shutdown(ctx); err != nil {
 		logger.Errorf("error stopping Tracer: %s", err)
 		return err
@@ -633,7 +877,7 @@ func (c *Cluster) ID(ctx context.Context) *api.ID {
 		peers, _ = c.consensus.Peers(ctx)
 	}
 
-	return &api.ID{
+	id := &api.ID{
 		ID: c.id,
 		//PublicKey:          c.host.Peerstore().PubKey(c.id),
 		Addresses:             addrs,
@@ -641,9 +885,229 @@ func (c *Cluster) ID(ctx context.Context) *api.ID {
 		ClusterPeersAddresses: c.peerManager.PeersAddresses(peers),
 		Version:               version.Version.String(),
 		RPCProtocolVersion:    version.RPCProtocol,
+		StateVersion:          version.StateVersion,
 		IPFS:                  ipfsID,
 		Peername:              c.config.Peername,
+		Allocatable:           c.config.IsAllocatable(),
+	}
+	c.addHealthToID(ctx, id)
+	return id
+}
+
+// addHealthToID fills in the health fields of the ID of this peer. They
+// are left empty when the information is not available.
+func (c *Cluster) addHealthToID(ctx context.Context, id *api.ID) {
+	if id.IPFS.Error == "" {
+		repoStat, err := c.ipfs.RepoStat(ctx)
+		if err == nil && repoStat.StorageMax > repoStat.RepoSize {
+			id.FreeSpace = repoStat.StorageMax - repoStat.RepoSize
+		}
+	}
+
+	if counter, ok := c.tracker.(OperationCounter); ok {
+		id.PinQueue, id.PinErrors = counter.OperationCounts(ctx)
+	}
+
+	if pauser, ok := c.tracker.(Pauser); ok {
+		id.TrackerPaused = pauser.Paused()
+	}
+}
+
+// SetAllocatable sets whether the given peer is a candidate for new
+// allocations. Peers which are not allocatable keep tracking the content
+// already allocated to them. The setting is persisted in the
+// configuration of the affected peer.
+func (c *Cluster) SetAllocatable(ctx context.Context, pid peer.ID, allocatable bool) error {
+	_, span := trace.StartSpan(ctx, "cluster/SetAllocatable")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	if pid == c.id {
+		return c.setAllocatableLocal(ctx, allocatable)
+	}
+
+	return c.rpcClient.CallContext(
+		ctx,
+		pid,
+		"Cluster",
+		"SetAllocatableLocal",
+		&api.PeerAllocatable{Peer: pid, Allocatable: allocatable},
+		&struct{}{},
+	)
+}
+
+func (c *Cluster) setAllocatableLocal(ctx context.Context, allocatable bool) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/setAllocatableLocal")
+	defer span.End()
+
+	c.config.SetAllocatable(allocatable)
+	logger.Infof("peer allocatable set to %t", allocatable)
+
+	// Let everyone know straight away.
+	_, err := c.sendAllocatableMetric(ctx)
+	return err
+}
+
+// SetTrackerPaused pauses or resumes the pin tracker of the given peer.
+// A paused tracker does not start the queued pin and unpin operations until
+// it is resumed. Pausing is not persisted: trackers are not paused after a
+// restart.
+func (c *Cluster) SetTrackerPaused(ctx context.Context, pid peer.ID, paused bool) error {
+	_, span := trace.StartSpan(ctx, "cluster/SetTrackerPaused")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	if pid == c.id {
+		return c.setTrackerPausedLocal(ctx, paused)
+	}
+
+	return c.rpcClient.CallContext(
+		ctx,
+		pid,
+		"Cluster",
+		"SetTrackerPausedLocal",
+		&api.TrackerPause{Peer: pid, Paused: paused},
+		&struct{}{},
+	)
+}
+
+func (c *Cluster) setTrackerPausedLocal(ctx context.Context, paused bool) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/setTrackerPausedLocal")
+	defer span.End()
+
+	pauser, ok := c.tracker.(Pauser)
+	if !ok {
+		return errors.New("the pin tracker does not support pausing")
+	}
+	if paused {
+		return pauser.Pause(ctx)
+	}
+	return pauser.Resume(ctx)
+}
+
+// RecordAccess lets informers which keep track of content usage know that
+// the given Cid has been accessed (i.e. via the IPFS proxy).
+func (c *Cluster) RecordAccess(ctx context.Context, h cid.Cid) {
+	_, span := trace.StartSpan(ctx, "cluster/RecordAccess")
+	defer span.End()
+
+	for _, informer := range c.informers {
+		if recorder, ok := informer.(AccessRecorder); ok {
+			recorder.RecordAccess(h)
+		}
+	}
+}
+
+// ConnectionDenyList returns the peers and IP ranges which are not allowed
+// to connect to this peer.
+func (c *Cluster) ConnectionDenyList(ctx context.Context) *api.ConnectionFilter {
+	_, span := trace.StartSpan(ctx, "cluster/ConnectionDenyList")
+	defer span.End()
+
+	peers, cidrs := c.config.GetConnectionDenyList()
+	deny := &api.ConnectionFilter{
+		Peers: peers,
+		CIDRs: make([]string, 0, len(cidrs)),
+	}
+	for _, n := range cidrs {
+		deny.CIDRs = append(deny.CIDRs, n.String())
+	}
+	return deny
+}
+
+// ConnectionDeny adds the given peers and IP ranges to the deny list of this
+// peer and closes any existing connections to them. The deny list is
+// persisted in the configuration.
+func (c *Cluster) ConnectionDeny(ctx context.Context, deny *api.ConnectionFilter) error {
+	_, span := trace.StartSpan(ctx, "cluster/ConnectionDeny")
+	defer span.End()
+
+	cidrs, err := ParseCIDRs("deny list", deny.CIDRs)
+	if err != nil {
+		return err
+	}
+
+	denyPeers, denyCIDRs := c.config.GetConnectionDenyList()
+	for _, p := range deny.Peers {
+		if p == c.id {
+			return errors.New("cannot deny connections to this peer itself")
+		}
+		if !containsPeer(denyPeers, p) {
+			denyPeers = append(denyPeers, p)
+		}
+	}
+	for _, n := range cidrs {
+		if !containsCIDR(denyCIDRs, n) {
+			denyCIDRs = append(denyCIDRs, n)
+		}
+	}
+
+	c.config.SetConnectionDenyList(denyPeers, denyCIDRs)
+	c.connGater.update()
+	logger.Infof("connections denied to peers %s and ranges %s", deny.Peers, deny.CIDRs)
+	return nil
+}
+
+// ConnectionUndeny removes the given peers and IP ranges from the deny list
+// of this peer. The deny list is persisted in the configuration.
+func (c *Cluster) ConnectionUndeny(ctx context.Context, undeny *api.ConnectionFilter) error {
+	_, span := trace.StartSpan(ctx, "cluster/ConnectionUndeny")
+	defer span.End()
+
+	cidrs, err := ParseCIDRs("deny list", undeny.CIDRs)
+	if err != nil {
+		return err
+	}
+
+	var denyPeers []peer.ID
+	var denyCIDRs []*net.IPNet
+	currentPeers, currentCIDRs := c.config.GetConnectionDenyList()
+	for _, p := range currentPeers {
+		if !containsPeer(undeny.Peers, p) {
+			denyPeers = append(denyPeers, p)
+		}
+	}
+	for _, n := range currentCIDRs {
+		if !containsCIDR(cidrs, n) {
+			denyCIDRs = append(denyCIDRs, n)
+		}
+	}
+
+	c.config.SetConnectionDenyList(denyPeers, denyCIDRs)
+	c.connGater.update()
+	logger.Infof("connections allowed again to peers %s and ranges %s", undeny.Peers, undeny.CIDRs)
+	return nil
+}
+
+// Faults returns the fault injection rules currently used by this peer. It
+// fails when fault injection is not enabled in the configuration.
+func (c *Cluster) Faults(ctx context.Context) ([]*api.FaultRule, error) {
+	_, span := trace.StartSpan(ctx, "cluster/Faults")
+	defer span.End()
+
+	if c.faults == nil {
+		return nil, errFaultInjectionDisabled
+	}
+	return c.faults.Rules(), nil
+}
+
+// SetFaults replaces the fault injection rules of this peer. An empty list
+// stops injecting faults. Rules for the "rpc" target only affect the RPC
+// requests received from other peers. It fails when fault injection is not
+// enabled in the configuration.
+func (c *Cluster) SetFaults(ctx context.Context, rules []*api.FaultRule) error {
+	_, span := trace.StartSpan(ctx, "cluster/SetFaults")
+	defer span.End()
+
+	if c.faults == nil {
+		return errFaultInjectionDisabled
+	}
+	err := c.faults.SetRules(rules)
+	if err != nil {
+		return err
 	}
+	logger.Warningf("fault injection rules set: %d rules active", len(rules))
+	return nil
 }
 
 // PeerAdd adds a new peer to this Cluster.
@@ -668,8 +1132,13 @@ func (c *Cluster) PeerAdd(ctx context.Context, pid peer.ID) (*api.ID, error) {
 	defer c.paMux.Unlock()
 	logger.Debugf("peerAdd called with %s", pid.Pretty())
 
+	err := c.checkPeerVersion(ctx, pid)
+	if err != nil {
+		return &api.ID{ID: pid, Error: err.Error()}, err
+	}
+
 	// Let the consensus layer be aware of this peer
-	err := c.consensus.AddPeer(ctx, pid)
+	err = c.consensus.AddPeer(ctx, pid)
 	if err != nil {
 		logger.Error(err)
 		id := &api.ID{ID: pid, Error: err.Error()}
@@ -732,9 +1201,17 @@ func (c *Cluster) Join(ctx context.Context, addr ma.Multiaddr) error {
 		return nil
 	}
 
+	// Remember it in case we need to reconnect later.
+	c.reconnect.addBootstrap(addr)
+
 	// Add peer to peerstore so we can talk to it (and connect)
 	c.peerManager.ImportPeer(addr, true)
 
+	err = c.checkPeerVersion(ctx, pid)
+	if err != nil {
+		return err
+	}
+
 	// Note that PeerAdd() on the remote peer will
 	// figure out what our real address is (obviously not
 	// ListenAddr).
@@ -767,7 +1244,7 @@ func (c *Cluster) Join(ctx context.Context, addr ma.Multiaddr) error {
 	}
 
 	// Broadcast our metrics to the world
-	_, err = c.sendInformerMetric(ctx)
+	err = c.sendInformersMetrics(ctx)
 	if err != nil {
 		logger.Warning(err)
 	}
@@ -807,24 +1284,60 @@ func (c *Cluster) Join(ctx context.Context, addr ma.Multiaddr) error {
 
 // StateSync syncs the consensus state to the Pin Tracker, ensuring
 // that every Cid in the shared state is tracked and that the Pin Tracker
-// is not tracking more Cids than it should.
+// is not tracking more Cids than it should. The results of the last run
+// can be obtained with LastStateSyncLocal().
 func (c *Cluster) StateSync(ctx context.Context) error {
 	_, span := trace.StartSpan(ctx, "cluster/StateSync")
 	defer span.End()
 	ctx = trace.NewContext(c.ctx, span)
 
+	result := &api.StateSync{
+		Peer:     c.id,
+		PeerName: c.config.Peername,
+		Start:    time.Now(),
+	}
+	changed, err := c.stateSync(ctx)
+	result.End = time.Now()
+	result.Changed = changed
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	c.lastStateSyncMux.Lock()
+	c.lastStateSync = result
+	c.lastStateSyncMux.Unlock()
+	return err
+}
+
+// stateSync performs StateSync and returns the number of items whose
+// tracking was modified.
+func (c *Cluster) stateSync(ctx context.Context) (int, error) {
 	cState, err := c.consensus.State(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	logger.Debug("syncing state to tracker")
 	clusterPins, err := cState.List(ctx)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	c.removeExpiredPins(ctx, clusterPins)
+	c.enforceContentPolicy(ctx, clusterPins)
+
+	// Explanations older than the previous StateSync have had time to
+	// see their pins committed.
+	c.lastStateSyncMux.RLock()
+	lastSync := c.lastStateSync
+	c.lastStateSyncMux.RUnlock()
+	if lastSync != nil {
+		c.pruneAllocations(clusterPins, lastSync.Start)
 	}
 
-	trackedPins := c.tracker.StatusAll(ctx)
+	changed := 0
+
+	trackedPins := c.tracker.StatusAll(ctx, api.TrackerStatusUndefined)
 	trackedPinsMap := make(map[string]int)
 	for i, tpin := range trackedPins {
 		trackedPinsMap[tpin.Cid.String()] = i
@@ -834,8 +1347,13 @@ func (c *Cluster) StateSync(ctx context.Context) error {
 	for _, pin := range clusterPins {
 		_, tracked := trackedPinsMap[pin.Cid.String()]
 		if !tracked {
+			if err := c.pinVerifier.verify(api.SignedPinOp, pin); err != nil {
+				logger.Debugf("StateSync: not tracking %s: %s", pin.Cid, err)
+				continue
+			}
 			logger.Debugf("StateSync: tracking %s, part of the shared state", pin.Cid)
 			c.tracker.Track(ctx, pin)
+			changed++
 		}
 	}
 
@@ -846,83 +1364,542 @@ func (c *Cluster) StateSync(ctx context.Context) error {
 		pCid := p.Cid
 		currentPin, err := cState.Get(ctx, pCid)
 		if err != nil && err != state.ErrNotFound {
-			return err
+			return changed, err
 		}
 
 		if err == state.ErrNotFound {
 			logger.Debugf("StateSync: untracking %s: not part of shared state", pCid)
 			c.tracker.Untrack(ctx, pCid)
+			changed++
+			continue
+		}
+
+		if err := c.pinVerifier.verify(api.SignedPinOp, currentPin); err != nil {
+			logger.Debugf("StateSync: untracking %s: %s", pCid, err)
+			c.tracker.Untrack(ctx, pCid)
+			changed++
 			continue
 		}
 
-		allocatedHere := containsPeer(currentPin.Allocations, c.id) || currentPin.ReplicationFactorMin == -1
+		allocatedHere := !currentPin.IsScheduled() &&
+			(containsPeer(currentPin.Allocations, c.id) || currentPin.ReplicationFactorMin == -1)
 
 		switch {
 		case p.Status == api.TrackerStatusRemote && allocatedHere:
 			logger.Debugf("StateSync: Tracking %s locally (currently remote)", pCid)
 			c.tracker.Track(ctx, currentPin)
+			changed++
 		case p.Status == api.TrackerStatusPinned && !allocatedHere:
 			logger.Debugf("StateSync: Tracking %s as remote (currently local)", pCid)
 			c.tracker.Track(ctx, currentPin)
+			changed++
 		}
 	}
 
-	return nil
+	return changed, nil
 }
 
-// StatusAll returns the GlobalPinInfo for all tracked Cids in all peers.
-// If an error happens, the slice will contain as much information as
-// could be fetched from other peers.
-func (c *Cluster) StatusAll(ctx context.Context) ([]*api.GlobalPinInfo, error) {
-	_, span := trace.StartSpan(ctx, "cluster/StatusAll")
+// StateSyncAll triggers StateSyncLocal() in all cluster peers and returns
+// the results. Peers which could not be contacted are included with an
+// error.
+func (c *Cluster) StateSyncAll(ctx context.Context) ([]*api.StateSync, error) {
+	_, span := trace.StartSpan(ctx, "cluster/StateSyncAll")
 	defer span.End()
 	ctx = trace.NewContext(c.ctx, span)
 
-	return c.globalPinInfoSlice(ctx, "PinTracker", "StatusAll")
+	return c.stateSyncBroadcast(ctx, "StateSyncLocal")
 }
 
-// StatusAllLocal returns the PinInfo for all the tracked Cids in this peer.
-func (c *Cluster) StatusAllLocal(ctx context.Context) []*api.PinInfo {
-	_, span := trace.StartSpan(ctx, "cluster/StatusAllLocal")
+// StateSyncPeer triggers StateSyncLocal() in the given peer and returns the
+// result.
+func (c *Cluster) StateSyncPeer(ctx context.Context, pid peer.ID) (*api.StateSync, error) {
+	_, span := trace.StartSpan(ctx, "cluster/StateSyncPeer")
 	defer span.End()
 	ctx = trace.NewContext(c.ctx, span)
 
-	return c.tracker.StatusAll(ctx)
+	if pid == c.id {
+		return c.StateSyncLocal(ctx)
+	}
+
+	var result api.StateSync
+	err := c.rpcClient.CallContext(
+		ctx,
+		pid,
+		"Cluster",
+		"StateSyncLocal",
+		struct{}{},
+		&result,
+	)
+	return &result, err
 }
 
-// Status returns the GlobalPinInfo for a given Cid as fetched from all
-// current peers. If an error happens, the GlobalPinInfo should contain
-// as much information as could be fetched from the other peers.
-func (c *Cluster) Status(ctx context.Context, h cid.Cid) (*api.GlobalPinInfo, error) {
-	_, span := trace.StartSpan(ctx, "cluster/Status")
+// StateSyncLocal runs StateSync() and returns its result. Sync errors are
+// part of the result.
+func (c *Cluster) StateSyncLocal(ctx context.Context) (*api.StateSync, error) {
+	_, span := trace.StartSpan(ctx, "cluster/StateSyncLocal")
 	defer span.End()
 	ctx = trace.NewContext(c.ctx, span)
 
-	return c.globalPinInfoCid(ctx, "PinTracker", "Status", h)
+	err := c.StateSync(ctx)
+	if err != nil {
+		logger.Error("StateSync() returned with error: ", err)
+	}
+	return c.LastStateSyncLocal(ctx)
 }
 
-// StatusLocal returns this peer's PinInfo for a given Cid.
-func (c *Cluster) StatusLocal(ctx context.Context, h cid.Cid) *api.PinInfo {
-	_, span := trace.StartSpan(ctx, "cluster/StatusLocal")
+// LastStateSyncAll returns the result of the last StateSync() run in every
+// cluster peer.
+func (c *Cluster) LastStateSyncAll(ctx context.Context) ([]*api.StateSync, error) {
+	_, span := trace.StartSpan(ctx, "cluster/LastStateSyncAll")
 	defer span.End()
 	ctx = trace.NewContext(c.ctx, span)
 
-	return c.tracker.Status(ctx, h)
+	return c.stateSyncBroadcast(ctx, "LastStateSyncLocal")
 }
 
-// SyncAll triggers SyncAllLocal() operations in all cluster peers, making sure
-// that the state of tracked items matches the state reported by the IPFS daemon
-// and returning the results as GlobalPinInfo. If an error happens, the slice
-// will contain as much information as could be fetched from the peers.
-func (c *Cluster) SyncAll(ctx context.Context) ([]*api.GlobalPinInfo, error) {
-	_, span := trace.StartSpan(ctx, "cluster/SyncAll")
+// LastStateSyncLocal returns the result of the last StateSync() run in this
+// peer. If it has not run yet, the result has no start time.
+func (c *Cluster) LastStateSyncLocal(ctx context.Context) (*api.StateSync, error) {
+	_, span := trace.StartSpan(ctx, "cluster/LastStateSyncLocal")
 	defer span.End()
-	ctx = trace.NewContext(c.ctx, span)
 
-	return c.globalPinInfoSlice(ctx, "Cluster", "SyncAllLocal")
+	c.lastStateSyncMux.RLock()
+	defer c.lastStateSyncMux.RUnlock()
+	if c.lastStateSync == nil {
+		return &api.StateSync{
+			Peer:     c.id,
+			PeerName: c.config.Peername,
+		}, nil
+	}
+	result := *c.lastStateSync
+	return &result, nil
 }
 
-// SyncAllLocal makes sure that the current state for all tracked items
+func (c *Cluster) stateSyncBroadcast(ctx context.Context, method string) ([]*api.StateSync, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/stateSyncBroadcast")
+	defer span.End()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	lenMembers := len(members)
+
+	replies := make([]*api.StateSync, lenMembers, lenMembers)
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, lenMembers)
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		members,
+		"Cluster",
+		method,
+		struct{}{},
+		rpcutil.CopyStateSyncsToIfaces(replies),
+	)
+
+	for i, err := range errs {
+		if err != nil {
+			logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, members[i], err)
+			replies[i] = &api.StateSync{
+				Peer:  members[i],
+				Error: err.Error(),
+			}
+		}
+	}
+	return replies, nil
+}
+
+// Versions returns the versions run by every cluster peer and whether they
+// are compatible with the version of this peer.
+func (c *Cluster) Versions(ctx context.Context) []*api.PeerVersion {
+	_, span := trace.StartSpan(ctx, "cluster/Versions")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	ids := c.Peers(ctx)
+	versions := make([]*api.PeerVersion, 0, len(ids))
+	for _, id := range ids {
+		v := &api.PeerVersion{
+			Peer:               id.ID,
+			Peername:           id.Peername,
+			Version:            id.Version,
+			RPCProtocolVersion: id.RPCProtocolVersion,
+			StateVersion:       id.StateVersion,
+			Error:              id.Error,
+		}
+		if v.Error == "" {
+			err := version.Compatible(id.Version, id.RPCProtocolVersion, id.StateVersion)
+			if err != nil {
+				v.Error = err.Error()
+			} else {
+				v.Compatible = true
+			}
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// RuntimeStatsAll returns the Go runtime stats of every cluster peer.
+func (c *Cluster) RuntimeStatsAll(ctx context.Context) ([]*api.RuntimeStats, error) {
+	_, span := trace.StartSpan(ctx, "cluster/RuntimeStatsAll")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	lenMembers := len(members)
+
+	replies := make([]*api.RuntimeStats, lenMembers, lenMembers)
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, lenMembers)
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		members,
+		"Cluster",
+		"RuntimeStatsLocal",
+		struct{}{},
+		rpcutil.CopyRuntimeStatsToIfaces(replies),
+	)
+
+	for i, err := range errs {
+		if err != nil {
+			logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, members[i], err)
+			replies[i] = &api.RuntimeStats{
+				Peer:  members[i],
+				Error: err.Error(),
+			}
+		}
+	}
+	return replies, nil
+}
+
+// RuntimeStatsLocal returns the Go runtime stats of this peer.
+func (c *Cluster) RuntimeStatsLocal(ctx context.Context) *api.RuntimeStats {
+	_, span := trace.StartSpan(ctx, "cluster/RuntimeStatsLocal")
+	defer span.End()
+
+	rs := observations.RuntimeStats()
+	rs.Peer = c.id
+	rs.Peername = c.config.Peername
+	return rs
+}
+
+// Ping returns the current time of this peer. It is the remote end of
+// PingPeer().
+func (c *Cluster) Ping(ctx context.Context) time.Time {
+	_, span := trace.StartSpan(ctx, "cluster/Ping")
+	defer span.End()
+
+	return time.Now()
+}
+
+// PingPeer measures the round-trip time of an RPC request to the given
+// peer and estimates the skew of its clock. RPC errors are part of the
+// result.
+func (c *Cluster) PingPeer(ctx context.Context, pid peer.ID) *api.PingResult {
+	_, span := trace.StartSpan(ctx, "cluster/PingPeer")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	result := &api.PingResult{Peer: pid}
+
+	var remoteTime time.Time
+	start := time.Now()
+	err := c.rpcClient.CallContext(
+		ctx,
+		pid,
+		"Cluster",
+		"Ping",
+		struct{}{},
+		&remoteTime,
+	)
+	result.RTT = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// Assume the remote time was taken halfway through the request.
+	result.ClockSkew = remoteTime.Sub(start.Add(result.RTT / 2))
+	return result
+}
+
+// PingAll runs PingPeer() on every cluster peer concurrently.
+func (c *Cluster) PingAll(ctx context.Context) ([]*api.PingResult, error) {
+	_, span := trace.StartSpan(ctx, "cluster/PingAll")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	results := make([]*api.PingResult, len(members), len(members))
+	var wg sync.WaitGroup
+	for i, p := range members {
+		wg.Add(1)
+		go func(i int, p peer.ID) {
+			defer wg.Done()
+			results[i] = c.PingPeer(ctx, p)
+		}(i, p)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// Provide makes the IPFS daemons of the peers allocated to the given pin
+// announce its root CID to the network, regardless of their reprovider
+// strategy. The result for every peer is returned.
+func (c *Cluster) Provide(ctx context.Context, h cid.Cid) ([]*api.ProvideResult, error) {
+	_, span := trace.StartSpan(ctx, "cluster/Provide")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	pin, err := c.PinGet(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := pin.Allocations
+	if len(peers) == 0 { // pinned everywhere
+		peers, err = c.consensus.Peers(ctx)
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+	}
+
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, len(peers))
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		peers,
+		"IPFSConnector",
+		"Provide",
+		h,
+		rpcutil.RPCDiscardReplies(len(peers)),
+	)
+
+	results := make([]*api.ProvideResult, len(peers), len(peers))
+	for i, p := range peers {
+		results[i] = &api.ProvideResult{Peer: p}
+		if errs[i] != nil {
+			logger.Errorf("error providing %s on %s: %s", h, p, errs[i])
+			results[i].Error = errs[i].Error()
+		}
+	}
+	return results, nil
+}
+
+// StatusAll returns the GlobalPinInfo for all tracked Cids in all peers.
+// If an error happens, the slice will contain as much information as
+// could be fetched from other peers.
+//
+// The filter is applied by every peer, so that only the PinInfos matching
+// it are sent back. For example, excluding TrackerStatusRemote from the
+// filter avoids transmitting an entry per peer for every pin which is not
+// allocated to it. A TrackerStatusUndefined filter matches everything.
+func (c *Cluster) StatusAll(ctx context.Context, filter api.TrackerStatus) ([]*api.GlobalPinInfo, error) {
+	_, span := trace.StartSpan(ctx, "cluster/StatusAll")
+	defer span.End()
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
+
+	return c.globalPinInfoSlice(
+		ctx,
+		"PinTracker",
+		"StatusAll",
+		filter,
+		c.config.StatusAllConcurrency,
+		c.config.StatusAllPeerTimeout,
+	)
+}
+
+// StatusAllLocal returns the PinInfo for all the tracked Cids in this peer
+// which match the given filter.
+func (c *Cluster) StatusAllLocal(ctx context.Context, filter api.TrackerStatus) []*api.PinInfo {
+	_, span := trace.StartSpan(ctx, "cluster/StatusAllLocal")
+	defer span.End()
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
+
+	return c.tracker.StatusAll(ctx, filter)
+}
+
+// StatusSummary returns the number of items in each status for every
+// cluster peer, along with the totals. Each peer counts its own items, so
+// only the counts travel over the network.
+func (c *Cluster) StatusSummary(ctx context.Context) (*api.StatusSummary, error) {
+	_, span := trace.StartSpan(ctx, "cluster/StatusSummary")
+	defer span.End()
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	lenMembers := len(members)
+
+	replies := make([]*api.PeerStatusSummary, lenMembers, lenMembers)
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, lenMembers)
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := rpcutil.LimitedMultiCall(
+		ctxs,
+		c.rpcClient,
+		c.config.StatusAllConcurrency,
+		c.config.StatusAllPeerTimeout,
+		members,
+		"Cluster",
+		"StatusSummaryLocal",
+		struct{}{},
+		rpcutil.CopyPeerStatusSummariesToIfaces(replies),
+	)
+
+	summary := &api.StatusSummary{
+		Peers: make([]*api.PeerStatusSummary, 0, lenMembers),
+	}
+	for i, err := range errs {
+		if err != nil {
+			if rpc.IsAuthorizationError(err) {
+				continue
+			}
+			logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, members[i], err)
+			replies[i] = &api.PeerStatusSummary{
+				Peer:  members[i],
+				Error: err.Error(),
+			}
+		}
+		summary.Totals.Add(replies[i].Counts)
+		summary.Peers = append(summary.Peers, replies[i])
+	}
+	return summary, nil
+}
+
+// StatusSummaryLocal returns the number of items in each status tracked
+// by this peer.
+func (c *Cluster) StatusSummaryLocal(ctx context.Context) *api.PeerStatusSummary {
+	_, span := trace.StartSpan(ctx, "cluster/StatusSummaryLocal")
+	defer span.End()
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
+
+	summary := &api.PeerStatusSummary{
+		Peer:     c.id,
+		Peername: c.config.Peername,
+	}
+	for _, pinfo := range c.tracker.StatusAll(ctx, api.TrackerStatusUndefined) {
+		summary.Counts.Count(pinfo.Status)
+	}
+	return summary
+}
+
+// Status returns the GlobalPinInfo for a given Cid as fetched from all
+// current peers. If an error happens, the GlobalPinInfo should contain
+// as much information as could be fetched from the other peers.
+func (c *Cluster) Status(ctx context.Context, h cid.Cid) (*api.GlobalPinInfo, error) {
+	_, span := trace.StartSpan(ctx, "cluster/Status")
+	defer span.End()
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
+
+	gpi, err := c.globalPinInfoCid(ctx, "PinTracker", "Status", h)
+	if err != nil {
+		return nil, err
+	}
+	c.attachShardsStatus(ctx, gpi)
+	return gpi, nil
+}
+
+// StatusLocal returns this peer's PinInfo for a given Cid.
+func (c *Cluster) StatusLocal(ctx context.Context, h cid.Cid) *api.PinInfo {
+	_, span := trace.StartSpan(ctx, "cluster/StatusLocal")
+	defer span.End()
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
+
+	return c.tracker.Status(ctx, h)
+}
+
+// AllocationExplanation returns the explanation for the latest allocation
+// decision made for a Cid by any of the cluster peers. It returns an error
+// when no peer holds information about how the Cid was allocated.
+func (c *Cluster) AllocationExplanation(ctx context.Context, h cid.Cid) (*api.AllocationExplanation, error) {
+	_, span := trace.StartSpan(ctx, "cluster/AllocationExplanation")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	lenMembers := len(members)
+
+	replies := make([]*api.AllocationExplanation, lenMembers, lenMembers)
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, lenMembers)
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		members,
+		"Cluster",
+		"AllocationExplanationLocal",
+		h,
+		rpcutil.CopyAllocationExplanationsToIfaces(replies),
+	)
+
+	var latest *api.AllocationExplanation
+	for i, r := range replies {
+		if errs[i] != nil {
+			logger.Debugf("%s: no allocation explanation from %s: %s", c.id, members[i], errs[i])
+			continue
+		}
+		if latest == nil || r.TS.After(latest.TS) {
+			latest = r
+		}
+	}
+
+	if latest == nil {
+		return nil, errNoAllocationExplanation
+	}
+	return latest, nil
+}
+
+// AllocationExplanationLocal returns the explanation for the last allocation
+// decision this peer made for a Cid.
+func (c *Cluster) AllocationExplanationLocal(ctx context.Context, h cid.Cid) (*api.AllocationExplanation, error) {
+	_, span := trace.StartSpan(ctx, "cluster/AllocationExplanationLocal")
+	defer span.End()
+
+	return c.loadAllocation(h)
+}
+
+// SyncAll triggers SyncAllLocal() operations in all cluster peers, making sure
+// that the state of tracked items matches the state reported by the IPFS daemon
+// and returning the results as GlobalPinInfo. If an error happens, the slice
+// will contain as much information as could be fetched from the peers.
+func (c *Cluster) SyncAll(ctx context.Context) ([]*api.GlobalPinInfo, error) {
+	_, span := trace.StartSpan(ctx, "cluster/SyncAll")
+	defer span.End()
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
+
+	return c.globalPinInfoSlice(ctx, "Cluster", "SyncAllLocal", struct{}{}, 0, 0)
+}
+
+// SyncAllLocal makes sure that the current state for all tracked items
 // in this peer matches the state reported by the IPFS daemon.
 //
 // SyncAllLocal returns the list of PinInfo that where updated because of
@@ -930,7 +1907,8 @@ func (c *Cluster) SyncAll(ctx context.Context) ([]*api.GlobalPinInfo, error) {
 func (c *Cluster) SyncAllLocal(ctx context.Context) ([]*api.PinInfo, error) {
 	_, span := trace.StartSpan(ctx, "cluster/SyncAllLocal")
 	defer span.End()
-	ctx = trace.NewContext(c.ctx, span)
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
 
 	syncedItems, err := c.tracker.SyncAll(ctx)
 	// Despite errors, tracker provides synced items that we can provide.
@@ -947,7 +1925,8 @@ func (c *Cluster) SyncAllLocal(ctx context.Context) ([]*api.PinInfo, error) {
 func (c *Cluster) Sync(ctx context.Context, h cid.Cid) (*api.GlobalPinInfo, error) {
 	_, span := trace.StartSpan(ctx, "cluster/Sync")
 	defer span.End()
-	ctx = trace.NewContext(c.ctx, span)
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
 
 	return c.globalPinInfoCid(ctx, "Cluster", "SyncLocal", h)
 }
@@ -985,7 +1964,8 @@ func (c *Cluster) localPinInfoOp(
 func (c *Cluster) SyncLocal(ctx context.Context, h cid.Cid) (pInfo *api.PinInfo, err error) {
 	_, span := trace.StartSpan(ctx, "cluster/SyncLocal")
 	defer span.End()
-	ctx = trace.NewContext(c.ctx, span)
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
 
 	return c.localPinInfoOp(ctx, h, c.tracker.Sync)
 }
@@ -995,17 +1975,79 @@ func (c *Cluster) SyncLocal(ctx context.Context, h cid.Cid) (pInfo *api.PinInfo,
 func (c *Cluster) RecoverAllLocal(ctx context.Context) ([]*api.PinInfo, error) {
 	_, span := trace.StartSpan(ctx, "cluster/RecoverAllLocal")
 	defer span.End()
-	ctx = trace.NewContext(c.ctx, span)
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
 
 	return c.tracker.RecoverAll(ctx)
 }
 
+// RecoverPeer triggers a RecoverLocal operation for all Cids tracked by
+// the given peer, that is, for the items allocated to it. It is useful to
+// recover a single peer (for example, after its IPFS repository has been
+// lost) without touching the rest of the cluster. Items which cannot be
+// recovered are reported with a ClusterError status and do not stop the
+// operation.
+func (c *Cluster) RecoverPeer(ctx context.Context, pid peer.ID) ([]*api.PinInfo, error) {
+	_, span := trace.StartSpan(ctx, "cluster/RecoverPeer")
+	defer span.End()
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
+
+	if pid == c.id {
+		return c.RecoverAllLocal(ctx)
+	}
+
+	// PinTracker.RecoverAll is not open to other peers, so the items
+	// allocated to the peer are recovered one by one.
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := cState.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pinfos []*api.PinInfo
+	for _, pin := range pins {
+		if pin.Type == api.MetaType || pin.IsRemotePin(pid) {
+			continue
+		}
+		var pinfo api.PinInfo
+		err := c.rpcClient.CallContext(
+			ctx,
+			pid,
+			"PinTracker",
+			"Recover",
+			pin.Cid,
+			&pinfo,
+		)
+		if ctx.Err() != nil {
+			return pinfos, ctx.Err()
+		}
+		if err != nil {
+			logger.Errorf("%s: error recovering %s in %s: %s", c.id, pin.Cid, pid, err)
+			pinfo = api.PinInfo{
+				Cid:      pin.Cid,
+				Peer:     pid,
+				PeerName: pid.String(),
+				Status:   api.TrackerStatusClusterError,
+				TS:       time.Now(),
+				Error:    err.Error(),
+			}
+		}
+		pinfos = append(pinfos, &pinfo)
+	}
+	return pinfos, nil
+}
+
 // Recover triggers a recover operation for a given Cid in all
 // cluster peers.
 func (c *Cluster) Recover(ctx context.Context, h cid.Cid) (*api.GlobalPinInfo, error) {
 	_, span := trace.StartSpan(ctx, "cluster/Recover")
 	defer span.End()
-	ctx = trace.NewContext(c.ctx, span)
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
 
 	return c.globalPinInfoCid(ctx, "PinTracker", "Recover", h)
 }
@@ -1015,7 +2057,8 @@ func (c *Cluster) Recover(ctx context.Context, h cid.Cid) (*api.GlobalPinInfo, e
 func (c *Cluster) RecoverLocal(ctx context.Context, h cid.Cid) (pInfo *api.PinInfo, err error) {
 	_, span := trace.StartSpan(ctx, "cluster/RecoverLocal")
 	defer span.End()
-	ctx = trace.NewContext(c.ctx, span)
+	ctx, cancel := c.requestContext(ctx, span)
+	defer cancel()
 
 	return c.localPinInfoOp(ctx, h, c.tracker.Recover)
 }
@@ -1037,6 +2080,44 @@ func (c *Cluster) Pins(ctx context.Context) ([]*api.Pin, error) {
 	return cState.List(ctx)
 }
 
+// PinnedBytes returns an estimate of the amount of data pinned by this
+// peer, based on the sizes of the pins allocated to it and on the size of
+// the IPFS repository (see api.PinnedBytes).
+func (c *Cluster) PinnedBytes(ctx context.Context) (*api.PinnedBytes, error) {
+	_, span := trace.StartSpan(ctx, "cluster/PinnedBytes")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	pins, err := c.Pins(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pb := &api.PinnedBytes{}
+	for _, pin := range pins {
+		if pin.Type == api.MetaType || pin.IsRemotePin(c.id) {
+			continue
+		}
+		pb.Pins++
+		if pin.Size == 0 {
+			pb.UnknownSize++
+			continue
+		}
+		pb.Bytes += pin.Size
+	}
+
+	repoStat, err := c.ipfs.RepoStat(ctx)
+	if err != nil {
+		logger.Debugf("error obtaining repo stat: %s", err)
+		return pb, nil
+	}
+	pb.RepoSize = repoStat.RepoSize
+	if pb.UnknownSize > 0 && pb.RepoSize > pb.Bytes {
+		pb.Bytes = pb.RepoSize
+	}
+	return pb, nil
+}
+
 // PinGet returns information for a single Cid managed by Cluster.
 // The information is obtained from the current global state. The
 // returned api.Pin provides information about the allocations
@@ -1076,11 +2157,84 @@ func (c *Cluster) PinGet(ctx context.Context, h cid.Cid) (*api.Pin, error) {
 // the cluster.  Priority allocations are best effort.  If any priority peers
 // are unavailable then Pin will simply allocate from the rest of the cluster.
 func (c *Cluster) Pin(ctx context.Context, pin *api.Pin) error {
+	_, _, err := c.pinDedup(ctx, pin)
+	return err
+}
+
+// inflightPin tracks a Pin submission which has not finished yet
+// so that identical submissions can wait for its result.
+type inflightPin struct {
+	done chan struct{}
+	pin  *api.Pin
+	err  error
+}
+
+// pinKey identifies a pin submission by its Cid and options.
+func pinKey(pin *api.Pin) string {
+	ref := ""
+	if pin.Reference != nil {
+		ref = pin.Reference.String()
+	}
+	return fmt.Sprintf(
+		"%s|%s|%d|%s|%s",
+		pin.Cid,
+		pin.Type,
+		pin.MaxDepth,
+		ref,
+		pin.PinOptions.ToQuery(),
+	)
+}
+
+// pinDedup works like pin() but collapses repeated submissions of the same
+// Cid with identical options: if an identical Pin is being submitted
+// already, it waits for it and returns its result. The returned boolean
+// is true when the request was a duplicate, either of a submission in
+// progress or of a pin already present in the shared state, and thus
+// nothing was committed to the consensus layer.
+func (c *Cluster) pinDedup(ctx context.Context, pin *api.Pin) (*api.Pin, bool, error) {
 	_, span := trace.StartSpan(ctx, "cluster/Pin")
 	defer span.End()
 	ctx = trace.NewContext(c.ctx, span)
-	_, _, err := c.pin(ctx, pin, []peer.ID{}, pin.UserAllocations)
-	return err
+
+	if pin.Cid == cid.Undef {
+		return pin, false, errors.New("bad pin object")
+	}
+
+	key := pinKey(pin)
+	c.pinInflightMux.Lock()
+	inflight, ok := c.pinInflight[key]
+	if ok {
+		c.pinInflightMux.Unlock()
+		logger.Debugf("pinning %s: waiting for identical submission", pin.Cid)
+		select {
+		case <-ctx.Done():
+			return pin, false, ctx.Err()
+		case <-inflight.done:
+		}
+		return inflight.pin, inflight.err == nil, inflight.err
+	}
+
+	inflight = &inflightPin{done: make(chan struct{})}
+	c.pinInflight[key] = inflight
+	c.pinInflightMux.Unlock()
+
+	defer func() {
+		c.pinInflightMux.Lock()
+		delete(c.pinInflight, key)
+		c.pinInflightMux.Unlock()
+		close(inflight.done)
+	}()
+
+	// Peers already given in the allocations (i.e. those which received
+	// the blocks of an add) are preferred, like user allocations.
+	priority := append(append([]peer.ID{}, pin.UserAllocations...), pin.Allocations...)
+	result, submitted, err := c.pin(ctx, pin, []peer.ID{}, priority)
+	inflight.pin = result
+	inflight.err = err
+	if err != nil {
+		return result, false, err
+	}
+	return result, !submitted, nil
 }
 
 // sets the default replication factor in a pin when it's set to 0
@@ -1140,7 +2294,8 @@ func checkPinType(pin *api.Pin) error {
 
 // setupPin ensures that the Pin object is fit for pinning. We check
 // and set the replication factors and ensure that the pinType matches the
-// metadata consistently.
+// metadata consistently. It also sets the pin timestamps, keeping the
+// creation time (and the size, when unknown) of the existing pin, if any.
 func (c *Cluster) setupPin(ctx context.Context, pin *api.Pin) error {
 	ctx, span := trace.StartSpan(ctx, "cluster/setupPin")
 	defer span.End()
@@ -1162,9 +2317,66 @@ func (c *Cluster) setupPin(ctx context.Context, pin *api.Pin) error {
 		return fmt.Errorf(msg, pin.Type, existing.Type)
 	}
 
+	now := time.Now()
+	if existing != nil && !existing.CreatedAt.IsZero() {
+		pin.CreatedAt = existing.CreatedAt
+	} else {
+		pin.CreatedAt = now
+	}
+	pin.UpdatedAt = now
+
+	// Record that this peer committed the submission, without
+	// modifying the caller's provenance object.
+	prov := api.PinProvenance{}
+	if pin.Provenance != nil {
+		prov = *pin.Provenance
+	}
+	prov.Peer = c.id
+	pin.Provenance = &prov
+	if existing != nil {
+		pin.CreatedBy = existing.CreatedBy
+	} else {
+		pin.CreatedBy = pin.Provenance
+	}
+
+	if existing != nil && pin.Size == 0 {
+		pin.Size = existing.Size
+	}
+	if existing != nil && pin.LastRun.IsZero() {
+		pin.LastRun = existing.LastRun
+	}
+
+	err = c.setupSchedule(pin)
+	if err != nil {
+		return err
+	}
+
 	return checkPinType(pin)
 }
 
+// estimatePinSize sets the Size of the given pin, when unknown, to the
+// cumulative size of its DAG as reported by the IPFS daemon. For pins
+// which are not recursive, this is an upper bound. Errors are only logged,
+// as pins of unknown size can be allocated anyways.
+func (c *Cluster) estimatePinSize(ctx context.Context, pin *api.Pin) {
+	ctx, span := trace.StartSpan(ctx, "cluster/estimatePinSize")
+	defer span.End()
+
+	timeout := c.config.PinSizeEstimationTimeout
+	if pin.Size > 0 || timeout == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	size, err := c.ipfs.DAGSize(ctx, pin.Cid)
+	if err != nil {
+		logger.Warningf("could not estimate the size of %s: %s", pin.Cid, err)
+		return
+	}
+	pin.Size = size
+}
+
 // pin performs the actual pinning and supports a blacklist to be
 // able to evacuate a node and returns the pin object that it tried to pin, whether the pin was submitted
 // to the consensus layer or skipped (due to error or to the fact
@@ -1173,29 +2385,60 @@ func (c *Cluster) pin(ctx context.Context, pin *api.Pin, blacklist []peer.ID, pr
 	ctx, span := trace.StartSpan(ctx, "cluster/pin")
 	defer span.End()
 
+	submit, err := c.preparePin(ctx, pin, blacklist, prioritylist)
+	if err != nil || !submit {
+		return pin, false, err
+	}
+	return pin, true, c.logPin(ctx, pin)
+}
+
+// preparePin sets up the given pin and its allocations, and returns
+// whether it needs to be submitted to the consensus layer (false when it is
+// already correctly allocated).
+func (c *Cluster) preparePin(ctx context.Context, pin *api.Pin, blacklist []peer.ID, prioritylist []peer.ID) (bool, error) {
 	if pin.Cid == cid.Undef {
-		return pin, false, errors.New("bad pin object")
+		return false, errors.New("bad pin object")
+	}
+
+	err := c.checkContentPolicy(ctx, pin)
+	if err != nil {
+		return false, err
 	}
 
 	// setup pin might produce some side-effects to our pin
-	err := c.setupPin(ctx, pin)
+	err = c.setupPin(ctx, pin)
 	if err != nil {
-		return pin, false, err
+		return false, err
 	}
 	if pin.Type == api.MetaType {
-		return pin, true, c.consensus.LogPin(ctx, pin)
+		return true, nil
+	}
+
+	// Deferred pins are allocated when they run.
+	if pin.IsScheduled() {
+		pin.Allocations = nil
+		logger.Infof("pinning %s scheduled at %s", pin.Cid, pin.ScheduleAt)
+		return true, nil
+	}
+
+	if pin.Type == api.DataType {
+		c.estimatePinSize(ctx, pin)
+		if max := c.config.MaxPinSize; max > 0 && pin.Size > max {
+			return false, fmt.Errorf(
+				"the size of %s (%d bytes) exceeds the maximum pin size (%d bytes)",
+				pin.Cid, pin.Size, max,
+			)
+		}
 	}
 
 	allocs, err := c.allocate(
 		ctx,
-		pin.Cid,
-		pin.ReplicationFactorMin,
-		pin.ReplicationFactorMax,
+		pin,
 		blacklist,
 		prioritylist,
 	)
 	if err != nil {
-		return pin, false, err
+		return false, err
 	}
 	pin.Allocations = allocs
 
@@ -1203,7 +2446,7 @@ func (c *Cluster) pin(ctx context.Context, pin *api.Pin, blacklist []peer.ID, pr
 	if curr, _ := c.PinGet(ctx, pin.Cid); curr.Equals(pin) {
 		// skip pinning
 		logger.Debugf("pinning %s skipped: already correctly allocated", pin.Cid)
-		return pin, false, nil
+		return false, nil
 	}
 
 	if len(pin.Allocations) == 0 {
@@ -1212,7 +2455,7 @@ func (c *Cluster) pin(ctx context.Context, pin *api.Pin, blacklist []peer.ID, pr
 		logger.Infof("pinning %s on %s:", pin.Cid, pin.Allocations)
 	}
 
-	return pin, true, c.consensus.LogPin(ctx, pin)
+	return true, nil
 }
 
 func (c *Cluster) unpin(ctx context.Context, h cid.Cid) (*api.Pin, error) {
@@ -1226,24 +2469,115 @@ func (c *Cluster) unpin(ctx context.Context, h cid.Cid) (*api.Pin, error) {
 		return nil, err
 	}
 
+	retention := c.config.UnpinRetention
+	if retention > 0 && !pin.IsScheduledForRemoval() &&
+		(pin.Type == api.DataType || pin.Type == api.MetaType) {
+		pin.UpdatedAt = time.Now()
+		pin.RemoveAt = pin.UpdatedAt.Add(retention)
+		logger.Infof("%s scheduled for removal at %s", h, pin.RemoveAt)
+		return pin, c.logPin(ctx, pin)
+	}
+
+	return pin, c.removePin(ctx, pin)
+}
+
+// removePin removes a pin from the shared state.
+func (c *Cluster) removePin(ctx context.Context, pin *api.Pin) error {
 	switch pin.Type {
 	case api.DataType:
-		return pin, c.consensus.LogUnpin(ctx, pin)
+		c.forgetAllocation(pin.Cid)
+		return c.logUnpin(ctx, pin)
 	case api.ShardType:
 		err := "cannot unpin a shard direclty. Unpin content root CID instead."
-		return pin, errors.New(err)
+		return errors.New(err)
 	case api.MetaType:
+		// Throttle before touching the shards, so that they are
+		// not left unpinned when the limit is hit.
+		err := c.waitPinRate(ctx)
+		if err != nil {
+			return err
+		}
 		// Unpin cluster dag and referenced shards
-		err := c.unpinClusterDag(pin)
+		err = c.unpinClusterDag(pin)
 		if err != nil {
-			return pin, err
+			return err
 		}
-		return pin, c.consensus.LogUnpin(ctx, pin)
+		c.forgetAllocation(pin.Cid)
+		return c.consensus.LogUnpin(ctx, pin)
 	case api.ClusterDAGType:
 		err := "cannot unpin a Cluster DAG directly. Unpin content root CID instead."
-		return pin, errors.New(err)
+		return errors.New(err)
 	default:
-		return pin, errors.New("unrecognized pin type")
+		return errors.New("unrecognized pin type")
+	}
+}
+
+// RestorePin cancels the removal of a pin which has been unpinned while
+// an unpin retention period is configured. It returns the restored pin.
+func (c *Cluster) RestorePin(ctx context.Context, h cid.Cid) (*api.Pin, error) {
+	_, span := trace.StartSpan(ctx, "cluster/RestorePin")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	pin, err := c.PinGet(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pin.IsScheduledForRemoval() {
+		return nil, fmt.Errorf("%s is not scheduled for removal", h)
+	}
+
+	logger.Infof("restoring %s (was scheduled for removal at %s)", h, pin.RemoveAt)
+	pin.RemoveAt = time.Time{}
+	pin.UpdatedAt = time.Now()
+	return pin, c.logPin(ctx, pin)
+}
+
+// isCoordinator returns whether this peer should run the tasks which only
+// one peer in the cluster must run at a time. That is the leader, with
+// consensus components which have one. Otherwise, it is the trusted peer
+// with the lowest ID among the current cluster peers. Peers which do not
+// trust themselves (followers) never coordinate.
+func (c *Cluster) isCoordinator(ctx context.Context) bool {
+	if leader, err := c.consensus.Leader(ctx); err == nil {
+		return leader == c.id
+	}
+
+	if !c.consensus.IsTrustedPeer(ctx, c.id) {
+		return false
+	}
+
+	peers, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return false
+	}
+	for _, p := range peers {
+		if p < c.id && c.consensus.IsTrustedPeer(ctx, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// removeExpiredPins removes the given pins which were scheduled for removal
+// and whose retention period has expired. Only the coordinator does it.
+func (c *Cluster) removeExpiredPins(ctx context.Context, pins []*api.Pin) {
+	if !c.isCoordinator(ctx) {
+		return
+	}
+
+	now := time.Now()
+	for _, pin := range pins {
+		if !pin.IsScheduledForRemoval() || pin.RemoveAt.After(now) {
+			continue
+		}
+		logger.Infof("removing %s: retention period expired", pin.Cid)
+		err := c.removePin(ctx, pin)
+		if err != nil {
+			logger.Errorf("error removing %s: %s", pin.Cid, err)
+		}
 	}
 }
 
@@ -1253,6 +2587,10 @@ func (c *Cluster) unpin(ctx context.Context, h cid.Cid) (*api.Pin, error) {
 // Unpin returns an error if the operation could not be persisted
 // to the global state. Unpin does not reflect the success or failure
 // of underlying IPFS daemon unpinning operations.
+//
+// When an UnpinRetention period is configured, the pin is only scheduled
+// for removal and can be restored with RestorePin() until the period
+// expires. Unpinning it again removes it right away.
 func (c *Cluster) Unpin(ctx context.Context, h cid.Cid) error {
 	_, span := trace.StartSpan(ctx, "cluster/Unpin")
 	defer span.End()
@@ -1297,8 +2635,10 @@ func (c *Cluster) PinPath(ctx context.Context, path *api.PinPath) (*api.Pin, err
 		return nil, err
 	}
 
-	p := api.PinCid(ci)
-	p.PinOptions = path.PinOptions
+	p := api.PinWithOpts(ci, path.PinOptions)
+	if p.Recurrence != "" {
+		p.RecurrencePath = path.Path
+	}
 	p, _, err = c.pin(ctx, p, []peer.ID{}, p.UserAllocations)
 	return p, err
 }
@@ -1384,9 +2724,34 @@ func (c *Cluster) Peers(ctx context.Context) []*api.ID {
 		peers[i].Error = err.Error()
 	}
 
+	// Add the time of the last heartbeat received from each peer.
+	heartbeats := make(map[peer.ID]time.Time)
+	for _, m := range c.monitor.LatestMetrics(ctx, pingMetricName) {
+		heartbeats[m.Peer] = time.Unix(0, m.ReceivedAt)
+	}
+	for _, p := range peers {
+		p.LastHeartbeat = heartbeats[p.ID]
+	}
+
 	return peers
 }
 
+// requestContext returns a context carrying the given span for operations
+// serving a request. Unlike the cluster context, it is cancelled when the
+// request context is done, i.e. because the API client went away or its
+// deadline expired. It is also cancelled when the cluster shuts down.
+func (c *Cluster) requestContext(ctx context.Context, span *trace.Span) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(trace.NewContext(ctx, span))
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 func (c *Cluster) globalPinInfoCid(ctx context.Context, comp, method string, h cid.Cid) (*api.GlobalPinInfo, error) {
 	ctx, span := trace.StartSpan(ctx, "cluster/globalPinInfoCid")
 	defer span.End()
@@ -1416,6 +2781,12 @@ func (c *Cluster) globalPinInfoCid(ctx context.Context, comp, method string, h c
 		rpcutil.CopyPinInfoToIfaces(replies),
 	)
 
+	for i, res := range rpcutil.PeerResults(members, errs) {
+		if !rpc.IsAuthorizationError(errs[i]) {
+			pin.Results = append(pin.Results, res)
+		}
+	}
+
 	for i, r := range replies {
 		e := errs[i]
 
@@ -1445,7 +2816,10 @@ func (c *Cluster) globalPinInfoCid(ctx context.Context, comp, method string, h c
 	return pin, nil
 }
 
-func (c *Cluster) globalPinInfoSlice(ctx context.Context, comp, method string) ([]*api.GlobalPinInfo, error) {
+// globalPinInfoSlice asks all peers for a list of PinInfos and merges
+// them. At most "concurrency" peers are contacted at the same time and
+// every peer is given "timeout" to answer (0 means no limits).
+func (c *Cluster) globalPinInfoSlice(ctx context.Context, comp, method string, arg interface{}, concurrency int, timeout time.Duration) ([]*api.GlobalPinInfo, error) {
 	ctx, span := trace.StartSpan(ctx, "cluster/globalPinInfoSlice")
 	defer span.End()
 
@@ -1464,12 +2838,15 @@ func (c *Cluster) globalPinInfoSlice(ctx context.Context, comp, method string) (
 	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, lenMembers)
 	defer rpcutil.MultiCancel(cancels)
 
-	errs := c.rpcClient.MultiCall(
+	errs := rpcutil.LimitedMultiCall(
 		ctxs,
+		c.rpcClient,
+		concurrency,
+		timeout,
 		members,
 		comp,
 		method,
-		struct{}{},
+		arg,
 		rpcutil.CopyPinInfoSliceToIfaces(replies),
 	)
 
@@ -1500,6 +2877,10 @@ func (c *Cluster) globalPinInfoSlice(ctx context.Context, comp, method string) (
 				continue
 			}
 			logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, members[i], e)
+			if rpcutil.Outcome(e) == api.RPCTimeout && ctx.Err() == nil {
+				erroredPeers[members[i]] = fmt.Sprintf("peer did not respond in %s", timeout)
+				continue
+			}
 			erroredPeers[members[i]] = e.Error()
 		} else {
 			mergePins(r)
@@ -1547,6 +2928,28 @@ func (c *Cluster) getIDForPeer(ctx context.Context, pid peer.ID) (*api.ID, error
 	return &id, err
 }
 
+// checkPeerVersion verifies that the version of the given peer is
+// compatible with the version of this one. Incompatible peers, or peers
+// whose version cannot be obtained, only cause an error when
+// RefuseIncompatiblePeers is set. Otherwise, a warning is logged.
+func (c *Cluster) checkPeerVersion(ctx context.Context, pid peer.ID) error {
+	id, err := c.getIDForPeer(ctx, pid)
+	if err == nil {
+		err = version.Compatible(id.Version, id.RPCProtocolVersion, id.StateVersion)
+	}
+	if err == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("cannot verify the compatibility of peer %s: %s", pid, err)
+	if c.config.RefuseIncompatiblePeers {
+		logger.Error(err)
+		return err
+	}
+	logger.Warningf("%s. Upgrade all peers to the same version to avoid problems", err)
+	return nil
+}
+
 // cidsFromMetaPin expands a meta-pin and returns a list of Cids that
 // Cluster handles for it: the ShardPins, the ClusterDAG and the MetaPin, in
 // that order (the MetaPin is the last element).
@@ -1579,25 +2982,40 @@ func (c *Cluster) cidsFromMetaPin(ctx context.Context, h cid.Cid) ([]cid.Cid, er
 		return nil, errors.New("metaPin.Reference is unset")
 	}
 	list = append([]cid.Cid{*pin.Reference}, list...)
+	shards, err := c.shardsFromMetaPin(ctx, pin)
+	if err != nil {
+		return list, err
+	}
+	for _, s := range shards {
+		list = append([]cid.Cid{s}, list...)
+	}
+
+	return list, nil
+}
+
+// shardsFromMetaPin returns the shards linked from the cluster DAG of the
+// given meta pin, in order.
+func (c *Cluster) shardsFromMetaPin(ctx context.Context, pin *api.Pin) ([]cid.Cid, error) {
 	clusterDagPin, err := c.PinGet(ctx, *pin.Reference)
 	if err != nil {
-		return list, fmt.Errorf("could not get clusterDAG pin from state. Malformed pin?: %s", err)
+		return nil, fmt.Errorf("could not get clusterDAG pin from state. Malformed pin?: %s", err)
 	}
 
 	clusterDagBlock, err := c.ipfs.BlockGet(ctx, clusterDagPin.Cid)
 	if err != nil {
-		return list, fmt.Errorf("error reading clusterDAG block from ipfs: %s", err)
+		return nil, fmt.Errorf("error reading clusterDAG block from ipfs: %s", err)
 	}
 
 	clusterDagNode, err := sharding.CborDataToNode(clusterDagBlock, "cbor")
 	if err != nil {
-		return list, fmt.Errorf("error parsing clusterDAG block: %s", err)
+		return nil, fmt.Errorf("error parsing clusterDAG block: %s", err)
 	}
-	for _, l := range clusterDagNode.Links() {
-		list = append([]cid.Cid{l.Cid}, list...)
+	links := clusterDagNode.Links()
+	shards := make([]cid.Cid, 0, len(links))
+	for _, l := range links {
+		shards = append(shards, l.Cid)
 	}
-
-	return list, nil
+	return shards, nil
 }
 
 // diffPeers returns the peerIDs added and removed from peers2 in relation to
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,12 +18,39 @@ import (
 
 	"github.com/ipfs/ipfs-cluster/config"
 
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
 	pnet "github.com/libp2p/go-libp2p-pnet"
 	ma "github.com/multiformats/go-multiaddr"
 )
 
 const configKey = "cluster"
 
+// Strategies to choose the peers which receive the blocks of an add.
+const (
+	// BlockPutFanout puts the blocks directly in the peers which will be
+	// allocated the pin.
+	BlockPutFanout = "fanout"
+	// BlockPutLocal puts the blocks in the peer performing the add only.
+	// The pin is then allocated to it first, and the rest of the
+	// allocations fetch the content from it.
+	BlockPutLocal = "local"
+)
+
+// Policies to verify the transfer of a pin re-allocated away from a peer
+// before that peer is unallocated (and unpins it).
+const (
+	// ReallocationVerifyNone unallocates the peer right away.
+	ReallocationVerifyNone = "none"
+	// ReallocationVerifyMin keeps the peer allocated until as many of the
+	// new allocations as the minimum replication factor of the pin
+	// report it pinned.
+	ReallocationVerifyMin = "min"
+	// ReallocationVerifyAll keeps the peer allocated until all the new
+	// allocations report the pin pinned.
+	ReallocationVerifyAll = "all"
+)
+
 // Configuration defaults
 const (
 	DefaultListenAddr          = "/ip4/0.0.0.0/tcp/9096"
@@ -31,7 +61,59 @@ const (
 	DefaultReplicationFactor   = -1
 	DefaultLeaveOnShutdown     = false
 	DefaultDisableRepinning    = false
+	DefaultAllocatable         = true
+	DefaultUnpinRetention      = 0
 	DefaultPeerstoreFile       = "peerstore"
+	DefaultPeerstoreTTL        = 7 * 24 * time.Hour
+
+	DefaultBulkUnpinThreshold        = 100
+	DefaultBulkUnpinThresholdPercent = 50
+	DefaultBulkUnpinConfirmation     = true
+	DefaultBulkUnpinConfirmTimeout   = 5 * time.Minute
+
+	DefaultReconnectInterval    = 10 * time.Second
+	DefaultReconnectMaxInterval = 10 * time.Minute
+
+	DefaultReplicationScalingInterval      = 0 // disabled
+	DefaultReplicationScalingUpThreshold   = 100
+	DefaultReplicationScalingDownThreshold = 10
+
+	DefaultDHTProvideInterval = 0 // disabled
+
+	DefaultStatusAllConcurrency = 10
+	DefaultRecoveryConcurrency  = 10
+	DefaultStatusAllPeerTimeout = time.Minute
+
+	DefaultPinSizeEstimationTimeout = 10 * time.Second
+	DefaultMaxPinSize               = 0 // no limit
+
+	DefaultPinRateLimit   = 0 // disabled
+	DefaultPinRateMaxWait = 10 * time.Second
+
+	DefaultAddDelegationMinFreeSpace = 0 // disabled
+	DefaultAddDelegationMaxAdds      = 0 // disabled
+
+	DefaultBlockPutStrategy = BlockPutFanout
+
+	DefaultRepoGCGracePeriod = 10 * time.Minute
+	DefaultRepoGCMaxWait     = 10 * time.Minute
+
+	DefaultDenylistUpdateInterval = time.Hour
+
+	DefaultIPFSDriftCheckInterval = time.Hour
+
+	DefaultSignedPinMaxAge = 5 * time.Minute
+
+	DefaultReallocationVerification        = ReallocationVerifyNone
+	DefaultReallocationVerificationTimeout = 30 * time.Minute
+
+	DefaultDisableBlockTransfer        = false
+	DefaultBlockTransferBandwidthLimit = 0 // no limit
+
+	DefaultRPCCompression = RPCCompressionNone
+
+	DefaultPubsubMessageSigning              = true
+	DefaultPubsubStrictSignatureVerification = true
 )
 
 // Config is the configuration object containing customizable variables to
@@ -62,6 +144,26 @@ type Config struct {
 	// the RPC and Consensus components.
 	ListenAddr ma.Multiaddr
 
+	// ExtraListenAddrs are additional listen addresses for the Cluster
+	// libp2p Host. They can use any transport supported by the host
+	// (i.e. websockets), or be local addresses to which an onion service
+	// or a pluggable transport bridge forwards connections.
+	ExtraListenAddrs []ma.Multiaddr
+
+	// AnnounceAddrs, when set, are the only addresses that the Cluster
+	// libp2p Host advertises to other peers, instead of the addresses it
+	// listens on.
+	AnnounceAddrs []ma.Multiaddr
+
+	// NoAnnounceAddrs and NoAnnounceCIDRs are never advertised by the
+	// Cluster libp2p Host, even when they are listen or announce
+	// addresses. They allow leaving out automatically detected addresses
+	// which are not reachable by other peers (i.e. behind NAT). In the
+	// configuration, both are given in no_announce_multiaddresses, the
+	// IP ranges as "/ip4/10.0.0.0/ipcidr/8" masks.
+	NoAnnounceAddrs []ma.Multiaddr
+	NoAnnounceCIDRs []*net.IPNet
+
 	// Time between syncs of the consensus state to the
 	// tracker state. Normally states are synced anyway, but this helps
 	// when new nodes are joining the cluster. Reduce for faster
@@ -111,10 +213,277 @@ type Config struct {
 	// when not wanting to rely on the monitoring system which needs a revamp.
 	DisableRepinning bool
 
+	// UnpinRetention, when set, makes unpinned items stay pinned for
+	// the given time before they are actually removed. During this
+	// time, they can be restored with Cluster.RestorePin().
+	UnpinRetention time.Duration
+
+	// Allocatable controls whether this peer is a candidate for new
+	// allocations. Peers which are not allocatable stay in the cluster
+	// and keep the content already allocated to them. It can be changed
+	// at runtime with Cluster.SetAllocatable().
+	Allocatable bool
+
+	// BulkUnpinThreshold is the number of items that a single operation,
+	// like restoring a snapshot, may unpin without being forced.
+	// BulkUnpinThresholdPercent is the same, as a percentage of the
+	// pinset. The lowest of both applies. A negative value disables
+	// the threshold.
+	BulkUnpinThreshold        int
+	BulkUnpinThresholdPercent int
+
+	// BulkUnpinConfirmation makes forced bulk unpins only proceed when
+	// they are repeated with the token returned by the first attempt.
+	// Tokens can be used once and expire after BulkUnpinConfirmTimeout.
+	BulkUnpinConfirmation   bool
+	BulkUnpinConfirmTimeout time.Duration
+
 	// Peerstore file specifies the file on which we persist the
 	// libp2p host peerstore addresses. This file is regularly saved.
 	PeerstoreFile string
 
+	// PeerstoreTTL is how long the addresses of a peer are remembered
+	// after it was last seen. Peer addresses are persisted in the
+	// datastore and restored on start, so that peers with changing
+	// addresses can be found again after a restart. 0 means addresses
+	// are never forgotten.
+	PeerstoreTTL time.Duration
+
+	// PeerAddresses stores additional addresses for peers that may or may
+	// not be in the peerstore file. These are considered high priority
+	// when bootstrapping the initial cluster connections.
+	PeerAddresses []ma.Multiaddr
+
+	// ReconnectInterval is how often this peer checks that it is
+	// connected to some other cluster peer. When it is not (i.e. after a
+	// network partition), it tries to get back in contact by dialing the
+	// known cluster peers and bootstrap addresses and by running DHT
+	// discovery, waiting twice as long after every failed attempt, up to
+	// ReconnectMaxInterval. 0 disables reconnecting.
+	ReconnectInterval    time.Duration
+	ReconnectMaxInterval time.Duration
+
+	// ConnectionAllowPeers, when set, only lets the given peers keep
+	// connections to this peer. It should include all the cluster peers.
+	ConnectionAllowPeers []peer.ID
+
+	// ConnectionAllowCIDRs, when set, only lets connections from and to
+	// addresses in the given IP ranges through.
+	ConnectionAllowCIDRs []*net.IPNet
+
+	// ConnectionDenyPeers and ConnectionDenyCIDRs block connections from
+	// and to the given peers and IP ranges. They take precedence over the
+	// allow lists and can be modified at runtime with
+	// Cluster.ConnectionDeny() and Cluster.ConnectionUndeny().
+	ConnectionDenyPeers []peer.ID
+	ConnectionDenyCIDRs []*net.IPNet
+
+	// ReplicationScalingInterval sets how often the replication factors
+	// of pins are adjusted to the demand for their content, as reported
+	// by the popularity informer. 0 disables replication scaling.
+	ReplicationScalingInterval time.Duration
+
+	// ReplicationScalingUpThreshold is the cluster-wide popularity score
+	// above which the replication factors of a pin are raised by one.
+	ReplicationScalingUpThreshold float64
+
+	// ReplicationScalingDownThreshold is the cluster-wide popularity
+	// score below which raised replication factors are lowered by one,
+	// until they return to their original values.
+	ReplicationScalingDownThreshold float64
+
+	// ReplicationScalingMax is the highest replication factor that
+	// replication scaling can set.
+	ReplicationScalingMax int
+
+	// DHTProvideInterval sets how often this peer announces itself on
+	// the DHT as a provider of the root CIDs of the pins it holds, using
+	// its own libp2p host. This improves the discoverability of the
+	// content when the IPFS daemons do not provide it themselves. The
+	// announcements only reach the public IPFS DHT when this peer is
+	// connected to it (no cluster secret). 0 disables it.
+	DHTProvideInterval time.Duration
+
+	// StatusAllConcurrency limits how many peers are asked at the same
+	// time for the status of their pins when obtaining the status of
+	// all pins in the cluster.
+	StatusAllConcurrency int
+
+	// RecoveryConcurrency limits how many items in error state are
+	// recovered at the same time by a recovery job.
+	RecoveryConcurrency int
+
+	// StatusAllPeerTimeout limits how long to wait for every peer to
+	// report the status of its pins. Peers which do not answer in time
+	// are reported with an error for every pin, instead of failing the
+	// whole request. 0 means no limit.
+	StatusAllPeerTimeout time.Duration
+
+	// PinSizeEstimationTimeout limits how long to wait for the IPFS
+	// daemon to report the size of a DAG before allocating it. The size
+	// is used to skip peers without enough free space and to enforce
+	// MaxPinSize. Pins whose size cannot be obtained in time are
+	// allocated as usual. 0 disables size estimation.
+	PinSizeEstimationTimeout time.Duration
+
+	// MaxPinSize, when set, makes this peer reject pins whose estimated
+	// size in bytes is larger than it.
+	MaxPinSize uint64
+
+	// PinRateLimit is the maximum number of pin and unpin operations per
+	// second committed to the shared state by the whole cluster. Every
+	// peer takes an equal share of it for the operations submitted
+	// through it. Operations over the limit wait for their turn, for up
+	// to PinRateMaxWait, and are refused otherwise, so that bulk imports
+	// cannot overwhelm the consensus layer. 0 disables the limit.
+	PinRateLimit float64
+
+	// PinRateMaxWait is how long an operation may wait for its turn
+	// before being refused with a rate limit error.
+	PinRateMaxWait time.Duration
+
+	// AddDelegationMinFreeSpace makes this peer delegate the adds it
+	// receives to a better-suited peer when the free space of its IPFS
+	// repository, in bytes, falls below it. Peers with less free space
+	// are not chosen as delegates either. 0 disables it.
+	AddDelegationMinFreeSpace uint64
+
+	// AddDelegationMaxAdds makes this peer delegate the adds it receives
+	// to a better-suited peer when it is performing this many adds
+	// already. 0 disables it.
+	AddDelegationMaxAdds int
+
+	// BlockPutStrategy selects which peers receive the blocks of the
+	// non-sharded adds performed by this peer: BlockPutFanout (those
+	// which will be allocated the pin) or BlockPutLocal (this peer,
+	// which replicates the content once pinned). The blocks of sharded
+	// adds are always fanned out.
+	BlockPutStrategy string
+
+	// RepoGCGracePeriod enables the GC-safe mode for the garbage
+	// collections of the IPFS repository triggered through the cluster.
+	// They are postponed while adds which put blocks in the IPFS daemon
+	// have not finished, unless they put no blocks within this period,
+	// while other blocks have been added within this period, and while
+	// pins allocated to this peer are not pinned yet (including those not
+	// queued yet). Blocks cannot be added while they run. 0 disables the
+	// GC-safe mode.
+	RepoGCGracePeriod time.Duration
+
+	// RepoGCMaxWait is how long a garbage collection may be postponed by
+	// the GC-safe mode before it is refused. With 0, it is refused right
+	// away.
+	RepoGCMaxWait time.Duration
+
+	// IPFSDriftCheckInterval sets how often the IPFS daemons of all
+	// peers are compared, logging a warning when they run different
+	// versions or settings. 0 disables it.
+	IPFSDriftCheckInterval time.Duration
+
+	// ReallocationVerification sets how pins re-allocated away from a
+	// peer (i.e. when it is removed or stops sending heartbeats) are
+	// transferred: ReallocationVerifyNone unallocates the peer at
+	// once, while ReallocationVerifyMin and ReallocationVerifyAll keep
+	// it allocated until enough of the new allocations report the pin
+	// pinned, so that the number of replicas does not drop in between.
+	ReallocationVerification string
+
+	// ReallocationVerificationTimeout is how long to wait for the new
+	// allocations of a re-allocated pin to pin it. Afterwards, the old
+	// peer is left allocated and an error is logged.
+	ReallocationVerificationTimeout time.Duration
+
+	// DisableBlockTransfer stops this peer from exchanging the blocks of
+	// re-allocated pins directly with other cluster peers over the
+	// block transfer protocol. They are then only fetched by IPFS.
+	DisableBlockTransfer bool
+
+	// BlockTransferBandwidthLimit is the maximum number of bytes per
+	// second that this peer sends, and receives, over the block
+	// transfer protocol. 0 means no limit.
+	BlockTransferBandwidthLimit uint64
+
+	// RPCCompression sets the algorithm used to compress the RPC
+	// requests this peer sends to other peers, and their responses,
+	// when they support it: RPCCompressionNone or RPCCompressionSnappy.
+	// Compressed requests from other peers are always accepted.
+	RPCCompression string
+
+	// RPCClassBudgets limits the RPC traffic with other peers by
+	// class (see DefaultRPCClasses), so that lower priority traffic
+	// leaves room for the control traffic on saturated links. Classes
+	// which are not listed are not limited.
+	RPCClassBudgets map[RPCClass]RPCClassBudget
+
+	// AllocationInformer and Allocator select by name the Informer used
+	// for allocations and the PinAllocator. Besides the built-in ones,
+	// components compiled in through the registry package can be used.
+	// When empty, the allocation strategy given to the daemon applies.
+	AllocationInformer string
+	Allocator          string
+
+	// RefuseIncompatiblePeers makes this peer refuse to join, or to add,
+	// peers whose version is incompatible with its own (see
+	// version.Compatible). Otherwise, a warning is logged.
+	RefuseIncompatiblePeers bool
+
+	// PubsubMessageSigning makes this peer sign the messages it publishes
+	// on pubsub topics (metrics and CRDT updates).
+	PubsubMessageSigning bool
+
+	// PubsubStrictSignatureVerification makes this peer discard unsigned
+	// pubsub messages. It requires PubsubMessageSigning, as otherwise the
+	// messages from this peer would be discarded by the others.
+	PubsubStrictSignatureVerification bool
+
+	// PubsubValidateThrottle limits the number of pubsub messages being
+	// validated at the same time. Messages beyond the limit are dropped.
+	// 0 uses the pubsub default.
+	PubsubValidateThrottle int
+
+	// EnableFaultInjection lets administrators make this peer delay or
+	// fail a share of its IPFS connector calls and of the RPC requests it
+	// receives, in order to rehearse failure handling. Faults are only
+	// injected after being configured with Cluster.SetFaults().
+	EnableFaultInjection bool
+
+	// DeniedCIDs are never pinned by this peer. Pins of them already in
+	// the shared state are removed. Every CID version is denied.
+	DeniedCIDs []cid.Cid
+
+	// DenylistURLs are fetched every DenylistUpdateInterval and the
+	// CIDs they list treated like DeniedCIDs. Lists hold one CID per
+	// line or double-hashed entries as in the "bad bits" list (see the
+	// policy package).
+	DenylistURLs           []string
+	DenylistUpdateInterval time.Duration
+
+	// AllowedCIDs are never denied, even when listed in DeniedCIDs or in
+	// a denylist.
+	AllowedCIDs []cid.Cid
+
+	// DenylistForceBulkUnpin lets the denylists remove more pins at once
+	// than allowed by BulkUnpinThreshold and BulkUnpinThresholdPercent.
+	// Otherwise, such removals are refused and recorded in the audit log.
+	DenylistForceBulkUnpin bool
+
+	// RequireSignedPins makes this peer only accept pin and unpin
+	// requests carrying a signature by one of the AuthorizedKeys (see
+	// api.PinSignature), and only track pins of the shared state which
+	// carry one. Requests using IPFS paths and adds, whose CIDs are not
+	// known in advance, are refused, as well as scheduled pins and
+	// replication scaling, which modify pins after they are signed.
+	RequireSignedPins bool
+
+	// AuthorizedKeys are the peer IDs of the keys allowed to sign pin
+	// requests.
+	AuthorizedKeys []peer.ID
+
+	// SignedPinMaxAge is how long signed requests are accepted after
+	// (or before, to tolerate clock skew) the time of their signature.
+	// Requests are only accepted once within that window.
+	SignedPinMaxAge time.Duration
+
 	// Tracing flag used to skip tracing specific paths when not enabled.
 	Tracing bool
 }
@@ -123,20 +492,97 @@ type Config struct {
 // saved using JSON. Most configuration keys are converted into simple types
 // like strings, and key names aim to be self-explanatory for the user.
 type configJSON struct {
-	ID                   string `json:"id,omitempty"`
-	Peername             string `json:"peername"`
-	PrivateKey           string `json:"private_key,omitempty"`
-	Secret               string `json:"secret"`
-	LeaveOnShutdown      bool   `json:"leave_on_shutdown"`
-	ListenMultiaddress   string `json:"listen_multiaddress"`
-	StateSyncInterval    string `json:"state_sync_interval"`
-	IPFSSyncInterval     string `json:"ipfs_sync_interval"`
-	ReplicationFactorMin int    `json:"replication_factor_min"`
-	ReplicationFactorMax int    `json:"replication_factor_max"`
-	MonitorPingInterval  string `json:"monitor_ping_interval"`
-	PeerWatchInterval    string `json:"peer_watch_interval"`
-	DisableRepinning     bool   `json:"disable_repinning"`
-	PeerstoreFile        string `json:"peerstore_file,omitempty"`
+	ID                   string   `json:"id,omitempty"`
+	Peername             string   `json:"peername"`
+	PrivateKey           string   `json:"private_key,omitempty"`
+	Secret               string   `json:"secret"`
+	LeaveOnShutdown      bool     `json:"leave_on_shutdown"`
+	ListenMultiaddress   string   `json:"listen_multiaddress"`
+	ExtraListenAddrs     []string `json:"extra_listen_multiaddresses,omitempty"`
+	AnnounceAddrs        []string `json:"announce_multiaddresses,omitempty"`
+	NoAnnounceAddrs      []string `json:"no_announce_multiaddresses,omitempty"`
+	StateSyncInterval    string   `json:"state_sync_interval"`
+	IPFSSyncInterval     string   `json:"ipfs_sync_interval"`
+	ReplicationFactorMin int      `json:"replication_factor_min"`
+	ReplicationFactorMax int      `json:"replication_factor_max"`
+	MonitorPingInterval  string   `json:"monitor_ping_interval"`
+	PeerWatchInterval    string   `json:"peer_watch_interval"`
+	DisableRepinning     bool     `json:"disable_repinning"`
+	UnpinRetention       string   `json:"unpin_retention"`
+	Allocatable          *bool    `json:"allocatable,omitempty"`
+	PeerstoreFile        string   `json:"peerstore_file,omitempty"`
+	PeerstoreTTL         string   `json:"peerstore_ttl,omitempty"`
+	PeerAddresses        []string `json:"peer_addresses,omitempty"`
+	ReconnectInterval    string   `json:"reconnect_interval,omitempty"`
+	ReconnectMaxInterval string   `json:"reconnect_max_interval,omitempty"`
+	ConnectionAllowPeers []string `json:"connection_allow_peers,omitempty"`
+	ConnectionAllowCIDRs []string `json:"connection_allow_cidrs,omitempty"`
+	ConnectionDenyPeers  []string `json:"connection_deny_peers,omitempty"`
+	ConnectionDenyCIDRs  []string `json:"connection_deny_cidrs,omitempty"`
+
+	BulkUnpinThreshold        int    `json:"bulk_unpin_threshold,omitempty"`
+	BulkUnpinThresholdPercent int    `json:"bulk_unpin_threshold_percent,omitempty"`
+	BulkUnpinConfirmation     *bool  `json:"bulk_unpin_confirmation,omitempty"`
+	BulkUnpinConfirmTimeout   string `json:"bulk_unpin_confirm_timeout,omitempty"`
+
+	ReplicationScalingInterval      string  `json:"replication_scaling_interval,omitempty"`
+	ReplicationScalingUpThreshold   float64 `json:"replication_scaling_up_threshold,omitempty"`
+	ReplicationScalingDownThreshold float64 `json:"replication_scaling_down_threshold,omitempty"`
+	ReplicationScalingMax           int     `json:"replication_scaling_max,omitempty"`
+
+	DHTProvideInterval string `json:"dht_provide_interval,omitempty"`
+
+	StatusAllConcurrency int    `json:"status_all_concurrency,omitempty"`
+	StatusAllPeerTimeout string `json:"status_all_peer_timeout,omitempty"`
+
+	RecoveryConcurrency int `json:"recovery_concurrency,omitempty"`
+
+	PinSizeEstimationTimeout string `json:"pin_size_estimation_timeout,omitempty"`
+	MaxPinSize               uint64 `json:"max_pin_size,omitempty"`
+
+	PinRateLimit   float64 `json:"pin_rate_limit,omitempty"`
+	PinRateMaxWait string  `json:"pin_rate_max_wait,omitempty"`
+
+	AddDelegationMinFreeSpace uint64 `json:"add_delegation_min_free_space,omitempty"`
+	AddDelegationMaxAdds      int    `json:"add_delegation_max_adds,omitempty"`
+
+	BlockPutStrategy string `json:"block_put_strategy,omitempty"`
+
+	RepoGCGracePeriod string `json:"repo_gc_grace_period,omitempty"`
+	RepoGCMaxWait     string `json:"repo_gc_max_wait,omitempty"`
+
+	IPFSDriftCheckInterval string `json:"ipfs_drift_check_interval,omitempty"`
+
+	ReallocationVerification        string `json:"reallocation_verification,omitempty"`
+	ReallocationVerificationTimeout string `json:"reallocation_verification_timeout,omitempty"`
+
+	DisableBlockTransfer        bool   `json:"disable_block_transfer,omitempty"`
+	BlockTransferBandwidthLimit uint64 `json:"block_transfer_bandwidth_limit,omitempty"`
+
+	RPCCompression string `json:"rpc_compression,omitempty"`
+
+	RPCClassBudgets map[RPCClass]RPCClassBudget `json:"rpc_class_budgets,omitempty"`
+
+	AllocationInformer string `json:"allocation_informer,omitempty"`
+	Allocator          string `json:"allocator,omitempty"`
+
+	RefuseIncompatiblePeers bool `json:"refuse_incompatible_peers,omitempty"`
+
+	PubsubMessageSigning              *bool `json:"pubsub_message_signing,omitempty"`
+	PubsubStrictSignatureVerification *bool `json:"pubsub_strict_signature_verification,omitempty"`
+	PubsubValidateThrottle            int   `json:"pubsub_validate_throttle,omitempty"`
+
+	EnableFaultInjection bool `json:"enable_fault_injection,omitempty"`
+
+	DeniedCIDs             []string `json:"denied_cids,omitempty"`
+	DenylistURLs           []string `json:"denylist_urls,omitempty"`
+	DenylistUpdateInterval string   `json:"denylist_update_interval,omitempty"`
+	AllowedCIDs            []string `json:"allowed_cids,omitempty"`
+	DenylistForceBulkUnpin bool     `json:"denylist_force_bulk_unpin,omitempty"`
+
+	RequireSignedPins bool     `json:"require_signed_pins,omitempty"`
+	AuthorizedKeys    []string `json:"authorized_keys,omitempty"`
+	SignedPinMaxAge   string   `json:"signed_pin_max_age,omitempty"`
 }
 
 // ConfigKey returns a human-readable string to identify
@@ -185,6 +631,12 @@ func (cfg *Config) Validate() error {
 		return errors.New("cluster.listen_multiaddress is undefined")
 	}
 
+	for _, addr := range append([]ma.Multiaddr{cfg.ListenAddr}, cfg.ExtraListenAddrs...) {
+		if isOnionAddr(addr) {
+			return fmt.Errorf("cluster: cannot listen on onion address %s", addr)
+		}
+	}
+
 	if cfg.StateSyncInterval <= 0 {
 		return errors.New("cluster.state_sync_interval is invalid")
 	}
@@ -201,6 +653,38 @@ func (cfg *Config) Validate() error {
 		return errors.New("cluster.peer_watch_interval is invalid")
 	}
 
+	if cfg.UnpinRetention < 0 {
+		return errors.New("cluster.unpin_retention is invalid")
+	}
+
+	if cfg.BulkUnpinThresholdPercent > 100 {
+		return errors.New("cluster.bulk_unpin_threshold_percent is invalid")
+	}
+
+	if cfg.BulkUnpinConfirmTimeout <= 0 {
+		return errors.New("cluster.bulk_unpin_confirm_timeout is invalid")
+	}
+
+	if cfg.PeerstoreTTL < 0 {
+		return errors.New("cluster.peerstore_ttl is invalid")
+	}
+
+	if cfg.ReconnectInterval < 0 {
+		return errors.New("cluster.reconnect_interval is invalid")
+	}
+
+	if cfg.ReconnectInterval > 0 && cfg.ReconnectMaxInterval < cfg.ReconnectInterval {
+		return errors.New("cluster.reconnect_max_interval should be larger than reconnect_interval")
+	}
+
+	if cfg.PubsubStrictSignatureVerification && !cfg.PubsubMessageSigning {
+		return errors.New("cluster.pubsub_strict_signature_verification needs pubsub_message_signing")
+	}
+
+	if cfg.PubsubValidateThrottle < 0 {
+		return errors.New("cluster.pubsub_validate_throttle is invalid")
+	}
+
 	rfMax := cfg.ReplicationFactorMax
 	rfMin := cfg.ReplicationFactorMin
 
@@ -208,9 +692,131 @@ func (cfg *Config) Validate() error {
 		return err
 	}
 
+	if err := cfg.isReplicationScalingValid(); err != nil {
+		return err
+	}
+
+	if cfg.DHTProvideInterval < 0 {
+		return errors.New("cluster.dht_provide_interval is invalid")
+	}
+
+	if cfg.StatusAllConcurrency <= 0 {
+		return errors.New("cluster.status_all_concurrency is invalid")
+	}
+
+	if cfg.RecoveryConcurrency <= 0 {
+		return errors.New("cluster.recovery_concurrency is invalid")
+	}
+
+	if cfg.StatusAllPeerTimeout < 0 {
+		return errors.New("cluster.status_all_peer_timeout is invalid")
+	}
+
+	if cfg.PinSizeEstimationTimeout < 0 {
+		return errors.New("cluster.pin_size_estimation_timeout is invalid")
+	}
+
+	if cfg.PinRateLimit < 0 {
+		return errors.New("cluster.pin_rate_limit is invalid")
+	}
+
+	if cfg.PinRateMaxWait < 0 {
+		return errors.New("cluster.pin_rate_max_wait is invalid")
+	}
+
+	if cfg.AddDelegationMaxAdds < 0 {
+		return errors.New("cluster.add_delegation_max_adds is invalid")
+	}
+
+	if cfg.RepoGCGracePeriod < 0 {
+		return errors.New("cluster.repo_gc_grace_period is invalid")
+	}
+
+	if cfg.RepoGCMaxWait < 0 {
+		return errors.New("cluster.repo_gc_max_wait is invalid")
+	}
+
+	if cfg.IPFSDriftCheckInterval < 0 {
+		return errors.New("cluster.ipfs_drift_check_interval is invalid")
+	}
+
+	switch cfg.ReallocationVerification {
+	case ReallocationVerifyNone, ReallocationVerifyMin, ReallocationVerifyAll:
+	default:
+		return errors.New("cluster.reallocation_verification is invalid")
+	}
+
+	if cfg.ReallocationVerificationTimeout <= 0 {
+		return errors.New("cluster.reallocation_verification_timeout is invalid")
+	}
+
+	switch cfg.RPCCompression {
+	case RPCCompressionNone, RPCCompressionSnappy:
+	default:
+		return errors.New("cluster.rpc_compression is invalid")
+	}
+
+	for class, b := range cfg.RPCClassBudgets {
+		if !isRPCClassValid(class) {
+			return fmt.Errorf("cluster.rpc_class_budgets: unknown class %q", class)
+		}
+		if b.MaxConcurrent < 0 {
+			return fmt.Errorf("cluster.rpc_class_budgets: max_concurrent for %q is invalid", class)
+		}
+	}
+
+	if cfg.DenylistUpdateInterval <= 0 {
+		return errors.New("cluster.denylist_update_interval is invalid")
+	}
+
+	for _, u := range cfg.DenylistURLs {
+		pu, err := url.Parse(u)
+		if err != nil || (pu.Scheme != "http" && pu.Scheme != "https") {
+			return fmt.Errorf("cluster.denylist_urls: invalid URL %q", u)
+		}
+	}
+
+	switch cfg.BlockPutStrategy {
+	case BlockPutFanout, BlockPutLocal:
+	default:
+		return errors.New("cluster.block_put_strategy is invalid")
+	}
+
+	if cfg.RequireSignedPins && len(cfg.AuthorizedKeys) == 0 {
+		return errors.New("cluster.require_signed_pins needs some cluster.authorized_keys")
+	}
+
+	if cfg.SignedPinMaxAge <= 0 {
+		return errors.New("cluster.signed_pin_max_age is invalid")
+	}
+
 	return isRPCPolicyValid(cfg.RPCPolicy)
 }
 
+func (cfg *Config) isReplicationScalingValid() error {
+	if cfg.ReplicationScalingInterval < 0 {
+		return errors.New("cluster.replication_scaling_interval is invalid")
+	}
+
+	if cfg.ReplicationScalingInterval == 0 {
+		return nil
+	}
+
+	if cfg.ReplicationScalingUpThreshold <= 0 {
+		return errors.New("cluster.replication_scaling_up_threshold is invalid")
+	}
+
+	if cfg.ReplicationScalingDownThreshold < 0 ||
+		cfg.ReplicationScalingDownThreshold >= cfg.ReplicationScalingUpThreshold {
+		return errors.New("cluster.replication_scaling_down_threshold should be lower than replication_scaling_up_threshold")
+	}
+
+	if cfg.ReplicationScalingMax <= 0 {
+		return errors.New("cluster.replication_scaling_max must be set when replication scaling is enabled")
+	}
+	return nil
+}
+
 func isReplicationFactorValid(rplMin, rplMax int) error {
 	// check Max and Min are correct
 	if rplMin == 0 || rplMax == 0 {
@@ -273,6 +879,10 @@ func (cfg *Config) setDefaults() {
 
 	addr, _ := ma.NewMultiaddr(DefaultListenAddr)
 	cfg.ListenAddr = addr
+	cfg.ExtraListenAddrs = nil
+	cfg.AnnounceAddrs = nil
+	cfg.NoAnnounceAddrs = nil
+	cfg.NoAnnounceCIDRs = nil
 	cfg.LeaveOnShutdown = DefaultLeaveOnShutdown
 	cfg.StateSyncInterval = DefaultStateSyncInterval
 	cfg.IPFSSyncInterval = DefaultIPFSSyncInterval
@@ -281,7 +891,59 @@ func (cfg *Config) setDefaults() {
 	cfg.MonitorPingInterval = DefaultMonitorPingInterval
 	cfg.PeerWatchInterval = DefaultPeerWatchInterval
 	cfg.DisableRepinning = DefaultDisableRepinning
+	cfg.UnpinRetention = DefaultUnpinRetention
+	cfg.Allocatable = DefaultAllocatable
+	cfg.BulkUnpinThreshold = DefaultBulkUnpinThreshold
+	cfg.BulkUnpinThresholdPercent = DefaultBulkUnpinThresholdPercent
+	cfg.BulkUnpinConfirmation = DefaultBulkUnpinConfirmation
+	cfg.BulkUnpinConfirmTimeout = DefaultBulkUnpinConfirmTimeout
 	cfg.PeerstoreFile = "" // empty so it gets ommited.
+	cfg.PeerstoreTTL = DefaultPeerstoreTTL
+	cfg.ReconnectInterval = DefaultReconnectInterval
+	cfg.ReconnectMaxInterval = DefaultReconnectMaxInterval
+	cfg.ConnectionAllowPeers = nil
+	cfg.ConnectionAllowCIDRs = nil
+	cfg.ConnectionDenyPeers = nil
+	cfg.ConnectionDenyCIDRs = nil
+	cfg.AllocationInformer = ""
+	cfg.Allocator = ""
+	cfg.RefuseIncompatiblePeers = false
+	cfg.EnableFaultInjection = false
+	cfg.DeniedCIDs = nil
+	cfg.DenylistURLs = nil
+	cfg.DenylistUpdateInterval = DefaultDenylistUpdateInterval
+	cfg.AllowedCIDs = nil
+	cfg.DenylistForceBulkUnpin = false
+	cfg.RequireSignedPins = false
+	cfg.AuthorizedKeys = nil
+	cfg.SignedPinMaxAge = DefaultSignedPinMaxAge
+	cfg.PubsubMessageSigning = DefaultPubsubMessageSigning
+	cfg.PubsubStrictSignatureVerification = DefaultPubsubStrictSignatureVerification
+	cfg.PubsubValidateThrottle = 0
+	cfg.ReplicationScalingInterval = DefaultReplicationScalingInterval
+	cfg.ReplicationScalingUpThreshold = DefaultReplicationScalingUpThreshold
+	cfg.ReplicationScalingDownThreshold = DefaultReplicationScalingDownThreshold
+	cfg.ReplicationScalingMax = 0
+	cfg.DHTProvideInterval = DefaultDHTProvideInterval
+	cfg.StatusAllConcurrency = DefaultStatusAllConcurrency
+	cfg.RecoveryConcurrency = DefaultRecoveryConcurrency
+	cfg.StatusAllPeerTimeout = DefaultStatusAllPeerTimeout
+	cfg.PinSizeEstimationTimeout = DefaultPinSizeEstimationTimeout
+	cfg.MaxPinSize = DefaultMaxPinSize
+	cfg.PinRateLimit = DefaultPinRateLimit
+	cfg.PinRateMaxWait = DefaultPinRateMaxWait
+	cfg.AddDelegationMinFreeSpace = DefaultAddDelegationMinFreeSpace
+	cfg.AddDelegationMaxAdds = DefaultAddDelegationMaxAdds
+	cfg.BlockPutStrategy = DefaultBlockPutStrategy
+	cfg.RepoGCGracePeriod = DefaultRepoGCGracePeriod
+	cfg.RepoGCMaxWait = DefaultRepoGCMaxWait
+	cfg.IPFSDriftCheckInterval = DefaultIPFSDriftCheckInterval
+	cfg.ReallocationVerification = DefaultReallocationVerification
+	cfg.ReallocationVerificationTimeout = DefaultReallocationVerificationTimeout
+	cfg.DisableBlockTransfer = DefaultDisableBlockTransfer
+	cfg.BlockTransferBandwidthLimit = DefaultBlockTransferBandwidthLimit
+	cfg.RPCCompression = DefaultRPCCompression
+	cfg.RPCClassBudgets = nil
 	cfg.RPCPolicy = DefaultRPCPolicy
 }
 
@@ -320,6 +982,54 @@ func (cfg *Config) applyConfigJSON(jcfg *configJSON) error {
 	}
 	cfg.ListenAddr = clusterAddr
 
+	cfg.ExtraListenAddrs, err = parseMultiaddrs("extra_listen_multiaddresses", jcfg.ExtraListenAddrs)
+	if err != nil {
+		return err
+	}
+	cfg.AnnounceAddrs, err = parseMultiaddrs("announce_multiaddresses", jcfg.AnnounceAddrs)
+	if err != nil {
+		return err
+	}
+	cfg.NoAnnounceAddrs, cfg.NoAnnounceCIDRs, err = parseNoAnnounce(jcfg.NoAnnounceAddrs)
+	if err != nil {
+		return err
+	}
+	cfg.PeerAddresses, err = parseMultiaddrs("peer_addresses", jcfg.PeerAddresses)
+	if err != nil {
+		return err
+	}
+
+	cfg.DeniedCIDs, err = parseCids("denied_cids", jcfg.DeniedCIDs)
+	if err != nil {
+		return err
+	}
+	cfg.AllowedCIDs, err = parseCids("allowed_cids", jcfg.AllowedCIDs)
+	if err != nil {
+		return err
+	}
+
+	cfg.ConnectionAllowPeers, err = parsePeerIDs("connection_allow_peers", jcfg.ConnectionAllowPeers)
+	if err != nil {
+		return err
+	}
+	cfg.ConnectionAllowCIDRs, err = ParseCIDRs("connection_allow_cidrs", jcfg.ConnectionAllowCIDRs)
+	if err != nil {
+		return err
+	}
+	cfg.ConnectionDenyPeers, err = parsePeerIDs("connection_deny_peers", jcfg.ConnectionDenyPeers)
+	if err != nil {
+		return err
+	}
+	cfg.AuthorizedKeys, err = parsePeerIDs("authorized_keys", jcfg.AuthorizedKeys)
+	if err != nil {
+		return err
+	}
+	cfg.RequireSignedPins = jcfg.RequireSignedPins
+	cfg.ConnectionDenyCIDRs, err = ParseCIDRs("connection_deny_cidrs", jcfg.ConnectionDenyCIDRs)
+	if err != nil {
+		return err
+	}
+
 	rplMin := jcfg.ReplicationFactorMin
 	rplMax := jcfg.ReplicationFactorMax
 	config.SetIfNotDefault(rplMin, &cfg.ReplicationFactorMin)
@@ -330,6 +1040,22 @@ func (cfg *Config) applyConfigJSON(jcfg *configJSON) error {
 		&config.DurationOpt{Duration: jcfg.IPFSSyncInterval, Dst: &cfg.IPFSSyncInterval, Name: "ipfs_sync_interval"},
 		&config.DurationOpt{Duration: jcfg.MonitorPingInterval, Dst: &cfg.MonitorPingInterval, Name: "monitor_ping_interval"},
 		&config.DurationOpt{Duration: jcfg.PeerWatchInterval, Dst: &cfg.PeerWatchInterval, Name: "peer_watch_interval"},
+		&config.DurationOpt{Duration: jcfg.UnpinRetention, Dst: &cfg.UnpinRetention, Name: "unpin_retention"},
+		&config.DurationOpt{Duration: jcfg.BulkUnpinConfirmTimeout, Dst: &cfg.BulkUnpinConfirmTimeout, Name: "bulk_unpin_confirm_timeout"},
+		&config.DurationOpt{Duration: jcfg.PeerstoreTTL, Dst: &cfg.PeerstoreTTL, Name: "peerstore_ttl"},
+		&config.DurationOpt{Duration: jcfg.ReconnectInterval, Dst: &cfg.ReconnectInterval, Name: "reconnect_interval"},
+		&config.DurationOpt{Duration: jcfg.ReconnectMaxInterval, Dst: &cfg.ReconnectMaxInterval, Name: "reconnect_max_interval"},
+		&config.DurationOpt{Duration: jcfg.ReplicationScalingInterval, Dst: &cfg.ReplicationScalingInterval, Name: "replication_scaling_interval"},
+		&config.DurationOpt{Duration: jcfg.DHTProvideInterval, Dst: &cfg.DHTProvideInterval, Name: "dht_provide_interval"},
+		&config.DurationOpt{Duration: jcfg.StatusAllPeerTimeout, Dst: &cfg.StatusAllPeerTimeout, Name: "status_all_peer_timeout"},
+		&config.DurationOpt{Duration: jcfg.PinSizeEstimationTimeout, Dst: &cfg.PinSizeEstimationTimeout, Name: "pin_size_estimation_timeout"},
+		&config.DurationOpt{Duration: jcfg.PinRateMaxWait, Dst: &cfg.PinRateMaxWait, Name: "pin_rate_max_wait"},
+		&config.DurationOpt{Duration: jcfg.DenylistUpdateInterval, Dst: &cfg.DenylistUpdateInterval, Name: "denylist_update_interval"},
+		&config.DurationOpt{Duration: jcfg.RepoGCGracePeriod, Dst: &cfg.RepoGCGracePeriod, Name: "repo_gc_grace_period"},
+		&config.DurationOpt{Duration: jcfg.RepoGCMaxWait, Dst: &cfg.RepoGCMaxWait, Name: "repo_gc_max_wait"},
+		&config.DurationOpt{Duration: jcfg.IPFSDriftCheckInterval, Dst: &cfg.IPFSDriftCheckInterval, Name: "ipfs_drift_check_interval"},
+		&config.DurationOpt{Duration: jcfg.ReallocationVerificationTimeout, Dst: &cfg.ReallocationVerificationTimeout, Name: "reallocation_verification_timeout"},
+		&config.DurationOpt{Duration: jcfg.SignedPinMaxAge, Dst: &cfg.SignedPinMaxAge, Name: "signed_pin_max_age"},
 	)
 	if err != nil {
 		return err
@@ -337,6 +1063,51 @@ func (cfg *Config) applyConfigJSON(jcfg *configJSON) error {
 
 	cfg.LeaveOnShutdown = jcfg.LeaveOnShutdown
 	cfg.DisableRepinning = jcfg.DisableRepinning
+	cfg.AllocationInformer = jcfg.AllocationInformer
+	cfg.Allocator = jcfg.Allocator
+	cfg.RefuseIncompatiblePeers = jcfg.RefuseIncompatiblePeers
+	cfg.EnableFaultInjection = jcfg.EnableFaultInjection
+	cfg.DenylistURLs = jcfg.DenylistURLs
+	cfg.DenylistForceBulkUnpin = jcfg.DenylistForceBulkUnpin
+	if jcfg.Allocatable != nil {
+		cfg.Allocatable = *jcfg.Allocatable
+	}
+	config.SetIfNotDefault(jcfg.BulkUnpinThreshold, &cfg.BulkUnpinThreshold)
+	config.SetIfNotDefault(jcfg.BulkUnpinThresholdPercent, &cfg.BulkUnpinThresholdPercent)
+	if jcfg.BulkUnpinConfirmation != nil {
+		cfg.BulkUnpinConfirmation = *jcfg.BulkUnpinConfirmation
+	}
+	if jcfg.PubsubMessageSigning != nil {
+		cfg.PubsubMessageSigning = *jcfg.PubsubMessageSigning
+	}
+	if jcfg.PubsubStrictSignatureVerification != nil {
+		cfg.PubsubStrictSignatureVerification = *jcfg.PubsubStrictSignatureVerification
+	}
+	cfg.PubsubValidateThrottle = jcfg.PubsubValidateThrottle
+	config.SetIfNotDefault(jcfg.StatusAllConcurrency, &cfg.StatusAllConcurrency)
+	config.SetIfNotDefault(jcfg.RecoveryConcurrency, &cfg.RecoveryConcurrency)
+	config.SetIfNotDefault(jcfg.MaxPinSize, &cfg.MaxPinSize)
+	if jcfg.PinRateLimit != 0 {
+		cfg.PinRateLimit = jcfg.PinRateLimit
+	}
+	config.SetIfNotDefault(jcfg.AddDelegationMinFreeSpace, &cfg.AddDelegationMinFreeSpace)
+	config.SetIfNotDefault(jcfg.AddDelegationMaxAdds, &cfg.AddDelegationMaxAdds)
+	config.SetIfNotDefault(jcfg.BlockPutStrategy, &cfg.BlockPutStrategy)
+	config.SetIfNotDefault(jcfg.ReallocationVerification, &cfg.ReallocationVerification)
+	cfg.DisableBlockTransfer = jcfg.DisableBlockTransfer
+	config.SetIfNotDefault(jcfg.BlockTransferBandwidthLimit, &cfg.BlockTransferBandwidthLimit)
+	config.SetIfNotDefault(jcfg.RPCCompression, &cfg.RPCCompression)
+	if len(jcfg.RPCClassBudgets) > 0 {
+		cfg.RPCClassBudgets = jcfg.RPCClassBudgets
+	}
+
+	if jcfg.ReplicationScalingUpThreshold != 0 {
+		cfg.ReplicationScalingUpThreshold = jcfg.ReplicationScalingUpThreshold
+	}
+	if jcfg.ReplicationScalingDownThreshold != 0 {
+		cfg.ReplicationScalingDownThreshold = jcfg.ReplicationScalingDownThreshold
+	}
+	config.SetIfNotDefault(jcfg.ReplicationScalingMax, &cfg.ReplicationScalingMax)
 
 	return cfg.Validate()
 }
@@ -374,11 +1145,136 @@ func (cfg *Config) toConfigJSON() (jcfg *configJSON, err error) {
 	jcfg.MonitorPingInterval = cfg.MonitorPingInterval.String()
 	jcfg.PeerWatchInterval = cfg.PeerWatchInterval.String()
 	jcfg.DisableRepinning = cfg.DisableRepinning
+	jcfg.UnpinRetention = cfg.UnpinRetention.String()
+	allocatable := cfg.IsAllocatable()
+	jcfg.Allocatable = &allocatable
+	jcfg.BulkUnpinThreshold = cfg.BulkUnpinThreshold
+	jcfg.BulkUnpinThresholdPercent = cfg.BulkUnpinThresholdPercent
+	bulkUnpinConfirmation := cfg.BulkUnpinConfirmation
+	jcfg.BulkUnpinConfirmation = &bulkUnpinConfirmation
+	jcfg.BulkUnpinConfirmTimeout = cfg.BulkUnpinConfirmTimeout.String()
 	jcfg.PeerstoreFile = cfg.PeerstoreFile
+	jcfg.PeerstoreTTL = cfg.PeerstoreTTL.String()
+	jcfg.ReconnectInterval = cfg.ReconnectInterval.String()
+	jcfg.ReconnectMaxInterval = cfg.ReconnectMaxInterval.String()
+	jcfg.AllocationInformer = cfg.AllocationInformer
+	jcfg.Allocator = cfg.Allocator
+	jcfg.RefuseIncompatiblePeers = cfg.RefuseIncompatiblePeers
+	jcfg.EnableFaultInjection = cfg.EnableFaultInjection
+	jcfg.DeniedCIDs = cidsToStrings(cfg.DeniedCIDs)
+	jcfg.DenylistURLs = cfg.DenylistURLs
+	jcfg.DenylistUpdateInterval = cfg.DenylistUpdateInterval.String()
+	jcfg.AllowedCIDs = cidsToStrings(cfg.AllowedCIDs)
+	jcfg.DenylistForceBulkUnpin = cfg.DenylistForceBulkUnpin
+	jcfg.RequireSignedPins = cfg.RequireSignedPins
+	jcfg.SignedPinMaxAge = cfg.SignedPinMaxAge.String()
+	jcfg.PubsubMessageSigning = &cfg.PubsubMessageSigning
+	jcfg.PubsubStrictSignatureVerification = &cfg.PubsubStrictSignatureVerification
+	jcfg.PubsubValidateThrottle = cfg.PubsubValidateThrottle
+	jcfg.StatusAllConcurrency = cfg.StatusAllConcurrency
+	jcfg.RecoveryConcurrency = cfg.RecoveryConcurrency
+	jcfg.StatusAllPeerTimeout = cfg.StatusAllPeerTimeout.String()
+	jcfg.PinSizeEstimationTimeout = cfg.PinSizeEstimationTimeout.String()
+	jcfg.MaxPinSize = cfg.MaxPinSize
+	jcfg.PinRateLimit = cfg.PinRateLimit
+	jcfg.PinRateMaxWait = cfg.PinRateMaxWait.String()
+	jcfg.AddDelegationMinFreeSpace = cfg.AddDelegationMinFreeSpace
+	jcfg.AddDelegationMaxAdds = cfg.AddDelegationMaxAdds
+	jcfg.BlockPutStrategy = cfg.BlockPutStrategy
+	jcfg.RepoGCGracePeriod = cfg.RepoGCGracePeriod.String()
+	jcfg.RepoGCMaxWait = cfg.RepoGCMaxWait.String()
+	jcfg.IPFSDriftCheckInterval = cfg.IPFSDriftCheckInterval.String()
+	jcfg.ReallocationVerification = cfg.ReallocationVerification
+	jcfg.ReallocationVerificationTimeout = cfg.ReallocationVerificationTimeout.String()
+	jcfg.DisableBlockTransfer = cfg.DisableBlockTransfer
+	jcfg.BlockTransferBandwidthLimit = cfg.BlockTransferBandwidthLimit
+	jcfg.RPCCompression = cfg.RPCCompression
+	jcfg.RPCClassBudgets = cfg.RPCClassBudgets
+	if cfg.ReplicationScalingInterval > 0 {
+		jcfg.ReplicationScalingInterval = cfg.ReplicationScalingInterval.String()
+		jcfg.ReplicationScalingUpThreshold = cfg.ReplicationScalingUpThreshold
+		jcfg.ReplicationScalingDownThreshold = cfg.ReplicationScalingDownThreshold
+		jcfg.ReplicationScalingMax = cfg.ReplicationScalingMax
+	}
+	if cfg.DHTProvideInterval > 0 {
+		jcfg.DHTProvideInterval = cfg.DHTProvideInterval.String()
+	}
+	for _, addr := range cfg.ExtraListenAddrs {
+		jcfg.ExtraListenAddrs = append(jcfg.ExtraListenAddrs, addr.String())
+	}
+	for _, addr := range cfg.AnnounceAddrs {
+		jcfg.AnnounceAddrs = append(jcfg.AnnounceAddrs, addr.String())
+	}
+	for _, addr := range cfg.NoAnnounceAddrs {
+		jcfg.NoAnnounceAddrs = append(jcfg.NoAnnounceAddrs, addr.String())
+	}
+	for _, n := range cfg.NoAnnounceCIDRs {
+		jcfg.NoAnnounceAddrs = append(jcfg.NoAnnounceAddrs, ipcidrMask(n))
+	}
+	for _, addr := range cfg.PeerAddresses {
+		jcfg.PeerAddresses = append(jcfg.PeerAddresses, addr.String())
+	}
+	for _, p := range cfg.ConnectionAllowPeers {
+		jcfg.ConnectionAllowPeers = append(jcfg.ConnectionAllowPeers, peer.IDB58Encode(p))
+	}
+	for _, n := range cfg.ConnectionAllowCIDRs {
+		jcfg.ConnectionAllowCIDRs = append(jcfg.ConnectionAllowCIDRs, n.String())
+	}
+	denyPeers, denyCIDRs := cfg.GetConnectionDenyList()
+	for _, p := range denyPeers {
+		jcfg.ConnectionDenyPeers = append(jcfg.ConnectionDenyPeers, peer.IDB58Encode(p))
+	}
+	for _, n := range denyCIDRs {
+		jcfg.ConnectionDenyCIDRs = append(jcfg.ConnectionDenyCIDRs, n.String())
+	}
+	for _, p := range cfg.AuthorizedKeys {
+		jcfg.AuthorizedKeys = append(jcfg.AuthorizedKeys, peer.IDB58Encode(p))
+	}
 
 	return
 }
 
+// IsAllocatable returns the current value of Allocatable. It is safe
+// to call while the value is being modified with SetAllocatable.
+func (cfg *Config) IsAllocatable() bool {
+	cfg.lock.Lock()
+	defer cfg.lock.Unlock()
+	return cfg.Allocatable
+}
+
+// SetAllocatable modifies Allocatable and triggers a configuration
+// save so that the new value persists across restarts.
+func (cfg *Config) SetAllocatable(allocatable bool) {
+	cfg.lock.Lock()
+	cfg.Allocatable = allocatable
+	cfg.lock.Unlock()
+	cfg.NotifySave()
+}
+
+// GetConnectionDenyList returns copies of ConnectionDenyPeers and
+// ConnectionDenyCIDRs. It is safe to call while they are being modified
+// with SetConnectionDenyList.
+func (cfg *Config) GetConnectionDenyList() ([]peer.ID, []*net.IPNet) {
+	cfg.lock.Lock()
+	defer cfg.lock.Unlock()
+	peers := make([]peer.ID, len(cfg.ConnectionDenyPeers))
+	copy(peers, cfg.ConnectionDenyPeers)
+	cidrs := make([]*net.IPNet, len(cfg.ConnectionDenyCIDRs))
+	copy(cidrs, cfg.ConnectionDenyCIDRs)
+	return peers, cidrs
+}
+
+// SetConnectionDenyList replaces ConnectionDenyPeers and
+// ConnectionDenyCIDRs and triggers a configuration save so that the new
+// values persist across restarts.
+func (cfg *Config) SetConnectionDenyList(peers []peer.ID, cidrs []*net.IPNet) {
+	cfg.lock.Lock()
+	cfg.ConnectionDenyPeers = peers
+	cfg.ConnectionDenyCIDRs = cidrs
+	cfg.lock.Unlock()
+	cfg.NotifySave()
+}
+
 // GetPeerstorePath returns the full path of the
 // PeerstoreFile, obtained by concatenating that value
 // with BaseDir of the configuration, if set.
@@ -413,3 +1309,107 @@ func DecodeClusterSecret(hexSecret string) ([]byte, error) {
 		return nil, fmt.Errorf("input secret is %d bytes, cluster secret should be 32", secretLen)
 	}
 }
+
+// ParseCIDRs parses a list of IP ranges in CIDR notation. The given name is
+// used in error messages.
+func ParseCIDRs(name string, cidrs []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %s", name, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func parsePeerIDs(name string, ids []string) ([]peer.ID, error) {
+	var peers []peer.ID
+	for _, id := range ids {
+		p, err := peer.IDB58Decode(id)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %s", name, err)
+		}
+		peers = append(peers, p)
+	}
+	return peers, nil
+}
+
+func parseMultiaddrs(name string, addrs []string) ([]ma.Multiaddr, error) {
+	var maddrs []ma.Multiaddr
+	for _, addr := range addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %s", name, err)
+		}
+		maddrs = append(maddrs, maddr)
+	}
+	return maddrs, nil
+}
+
+func parseCids(name string, strs []string) ([]cid.Cid, error) {
+	var cids []cid.Cid
+	for _, s := range strs {
+		c, err := cid.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %s", name, err)
+		}
+		cids = append(cids, c)
+	}
+	return cids, nil
+}
+
+func cidsToStrings(cids []cid.Cid) []string {
+	var strs []string
+	for _, c := range cids {
+		strs = append(strs, c.String())
+	}
+	return strs
+}
+
+// parseNoAnnounce parses no_announce_multiaddresses, which can hold
+// multiaddresses and "/ip4/<ip>/ipcidr/<bits>" masks (as in go-ipfs).
+func parseNoAnnounce(addrs []string) ([]ma.Multiaddr, []*net.IPNet, error) {
+	var maddrs []ma.Multiaddr
+	var nets []*net.IPNet
+	for _, addr := range addrs {
+		parts := strings.Split(addr, "/")
+		if len(parts) == 5 && parts[0] == "" && parts[3] == "ipcidr" &&
+			(parts[1] == "ip4" || parts[1] == "ip6") {
+			_, n, err := net.ParseCIDR(parts[2] + "/" + parts[4])
+			if err != nil {
+				return nil, nil, fmt.Errorf("error parsing no_announce_multiaddresses: %s", err)
+			}
+			nets = append(nets, n)
+			continue
+		}
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing no_announce_multiaddresses: %s", err)
+		}
+		maddrs = append(maddrs, maddr)
+	}
+	return maddrs, nets, nil
+}
+
+// ipcidrMask returns the "/ip4/<ip>/ipcidr/<bits>" form of an IP range.
+func ipcidrMask(n *net.IPNet) string {
+	proto := "ip6"
+	if n.IP.To4() != nil {
+		proto = "ip4"
+	}
+	bits, _ := n.Mask.Size()
+	return fmt.Sprintf("/%s/%s/ipcidr/%d", proto, n.IP, bits)
+}
+
+// isOnionAddr returns true for Tor onion service addresses, which can be
+// announced but not listened on directly.
+func isOnionAddr(addr ma.Multiaddr) bool {
+	for _, p := range addr.Protocols() {
+		if p.Code == ma.P_ONION || p.Code == ma.P_ONION3 {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/test"
 )
 
 var ccfgTestJSON = []byte(`
@@ -104,6 +107,607 @@ func TestLoadJSON(t *testing.T) {
 		}
 	})
 
+	t.Run("peer addresses", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.PeerAddresses = []string{"/ip4/1.2.3.4/tcp/9096/ipfs/QmUfSFm12eYCaRdypg48m8RqkXfLW7A2ZeGZb2skeHHDGA"}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.PeerAddresses) != 1 {
+			t.Error("expected 1 peer address")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.PeerAddresses = []string{"abc"} })
+		if err == nil {
+			t.Error("expected error parsing peer_addresses")
+		}
+	})
+
+	t.Run("extra listen and announce addresses", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.ExtraListenAddrs = []string{"/ip4/0.0.0.0/tcp/9098/ws"}
+			j.AnnounceAddrs = []string{"/onion3/vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd:9096"}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.ExtraListenAddrs) != 1 || len(cfg.AnnounceAddrs) != 1 {
+			t.Error("expected extra listen and announce addresses to be loaded")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.ExtraListenAddrs = []string{"abc"} })
+		if err == nil {
+			t.Error("expected error parsing extra_listen_multiaddresses")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) {
+			j.ExtraListenAddrs = []string{"/onion3/vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd:9096"}
+		})
+		if err == nil {
+			t.Error("expected error listening on an onion address")
+		}
+	})
+
+	t.Run("no announce addresses", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.NoAnnounceAddrs = []string{"/ip4/1.2.3.4/tcp/9096", "/ip4/10.0.0.0/ipcidr/8"}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.NoAnnounceAddrs) != 1 || len(cfg.NoAnnounceCIDRs) != 1 {
+			t.Fatal("expected a no-announce address and a no-announce range")
+		}
+		if cfg.NoAnnounceCIDRs[0].String() != "10.0.0.0/8" {
+			t.Errorf("unexpected no-announce range: %s", cfg.NoAnnounceCIDRs[0])
+		}
+
+		jcfg, err := cfg.toConfigJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jcfg.NoAnnounceAddrs) != 2 || jcfg.NoAnnounceAddrs[1] != "/ip4/10.0.0.0/ipcidr/8" {
+			t.Errorf("unexpected no_announce_multiaddresses: %s", jcfg.NoAnnounceAddrs)
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.NoAnnounceAddrs = []string{"/ip4/10.0.0.0/ipcidr/99"} })
+		if err == nil {
+			t.Error("expected error parsing no_announce_multiaddresses")
+		}
+	})
+
+	t.Run("unpin retention", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.UnpinRetention = "24h" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.UnpinRetention != 24*time.Hour {
+			t.Error("expected unpin_retention to be 24h")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.UnpinRetention = "-1h" })
+		if err == nil {
+			t.Error("expected error with negative unpin_retention")
+		}
+	})
+
+	t.Run("peerstore ttl", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.PeerstoreTTL = "" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PeerstoreTTL != DefaultPeerstoreTTL {
+			t.Error("expected default peerstore_ttl")
+		}
+
+		cfg, err = loadJSON2(t, func(j *configJSON) { j.PeerstoreTTL = "0s" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PeerstoreTTL != 0 {
+			t.Error("expected peerstore_ttl to be 0")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.PeerstoreTTL = "-1h" })
+		if err == nil {
+			t.Error("expected error with negative peerstore_ttl")
+		}
+	})
+
+	t.Run("reconnect interval", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.ReconnectInterval = "" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ReconnectInterval != DefaultReconnectInterval ||
+			cfg.ReconnectMaxInterval != DefaultReconnectMaxInterval {
+			t.Error("expected default reconnect intervals")
+		}
+
+		cfg, err = loadJSON2(t, func(j *configJSON) { j.ReconnectInterval = "0s" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ReconnectInterval != 0 {
+			t.Error("expected reconnect_interval to be 0")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.ReconnectInterval = "-1s" })
+		if err == nil {
+			t.Error("expected error with negative reconnect_interval")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) {
+			j.ReconnectInterval = "1m"
+			j.ReconnectMaxInterval = "30s"
+		})
+		if err == nil {
+			t.Error("expected error with reconnect_max_interval lower than reconnect_interval")
+		}
+	})
+
+	t.Run("replication scaling", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.ReplicationScalingInterval = "1m"
+			j.ReplicationScalingUpThreshold = 50
+			j.ReplicationScalingMax = 10
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ReplicationScalingInterval != time.Minute ||
+			cfg.ReplicationScalingUpThreshold != 50 ||
+			cfg.ReplicationScalingDownThreshold != DefaultReplicationScalingDownThreshold ||
+			cfg.ReplicationScalingMax != 10 {
+			t.Error("replication scaling options not loaded correctly")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.ReplicationScalingInterval = "1m" })
+		if err == nil {
+			t.Error("expected error without replication_scaling_max")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) {
+			j.ReplicationScalingInterval = "1m"
+			j.ReplicationScalingMax = 10
+			j.ReplicationScalingDownThreshold = 200
+		})
+		if err == nil {
+			t.Error("expected error with down threshold over up threshold")
+		}
+	})
+
+	t.Run("status all limits", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.StatusAllConcurrency = 3
+			j.StatusAllPeerTimeout = "10s"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.StatusAllConcurrency != 3 || cfg.StatusAllPeerTimeout != 10*time.Second {
+			t.Error("expected status_all_concurrency and status_all_peer_timeout to be set")
+		}
+
+		cfg, err = loadJSON2(t, func(j *configJSON) {
+			j.StatusAllConcurrency = 0
+			j.StatusAllPeerTimeout = ""
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.StatusAllConcurrency != DefaultStatusAllConcurrency ||
+			cfg.StatusAllPeerTimeout != DefaultStatusAllPeerTimeout {
+			t.Error("expected default status all limits")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.StatusAllConcurrency = -1 })
+		if err == nil {
+			t.Error("expected error with negative status_all_concurrency")
+		}
+	})
+
+	t.Run("pin size limits", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.PinSizeEstimationTimeout = "0s"
+			j.MaxPinSize = 1 << 30
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PinSizeEstimationTimeout != 0 || cfg.MaxPinSize != 1<<30 {
+			t.Error("expected pin_size_estimation_timeout and max_pin_size to be set")
+		}
+
+		cfg, err = loadJSON2(t, func(j *configJSON) { j.PinSizeEstimationTimeout = "" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PinSizeEstimationTimeout != DefaultPinSizeEstimationTimeout {
+			t.Error("expected default pin_size_estimation_timeout")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.PinSizeEstimationTimeout = "-1s" })
+		if err == nil {
+			t.Error("expected error with negative pin_size_estimation_timeout")
+		}
+	})
+
+	t.Run("pin rate limit", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.PinRateLimit = 50
+			j.PinRateMaxWait = "1m"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PinRateLimit != 50 || cfg.PinRateMaxWait != time.Minute {
+			t.Error("expected pin_rate_limit and pin_rate_max_wait to be set")
+		}
+
+		cfg, err = loadJSON2(t, func(j *configJSON) { j.PinRateMaxWait = "" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PinRateLimit != DefaultPinRateLimit || cfg.PinRateMaxWait != DefaultPinRateMaxWait {
+			t.Error("expected default pin rate limit options")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.PinRateLimit = -1 })
+		if err == nil {
+			t.Error("expected error with negative pin_rate_limit")
+		}
+	})
+
+	t.Run("add delegation", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.AddDelegationMinFreeSpace = 1 << 30
+			j.AddDelegationMaxAdds = 4
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.AddDelegationMinFreeSpace != 1<<30 || cfg.AddDelegationMaxAdds != 4 {
+			t.Error("expected add delegation options to be set")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.AddDelegationMaxAdds = -1 })
+		if err == nil {
+			t.Error("expected error with negative add_delegation_max_adds")
+		}
+	})
+
+	t.Run("block put strategy", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.BlockPutStrategy = "local" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.BlockPutStrategy != BlockPutLocal {
+			t.Error("expected block_put_strategy to be set")
+		}
+
+		cfg, err = loadJSON2(t, func(j *configJSON) { j.BlockPutStrategy = "" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.BlockPutStrategy != BlockPutFanout {
+			t.Error("expected the default block_put_strategy")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.BlockPutStrategy = "everywhere" })
+		if err == nil {
+			t.Error("expected error with an unknown block_put_strategy")
+		}
+	})
+
+	t.Run("reallocation verification", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.ReallocationVerification = "min"
+			j.ReallocationVerificationTimeout = "5m"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ReallocationVerification != ReallocationVerifyMin ||
+			cfg.ReallocationVerificationTimeout != 5*time.Minute {
+			t.Error("expected reallocation_verification options to be set")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.ReallocationVerification = "some" })
+		if err == nil {
+			t.Error("expected error with an unknown reallocation_verification")
+		}
+	})
+
+	t.Run("block transfer", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.DisableBlockTransfer = true
+			j.BlockTransferBandwidthLimit = 1 << 20
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.DisableBlockTransfer || cfg.BlockTransferBandwidthLimit != 1<<20 {
+			t.Error("expected block transfer options to be set")
+		}
+	})
+
+	t.Run("rpc compression", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.RPCCompression = RPCCompressionSnappy })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.RPCCompression != RPCCompressionSnappy {
+			t.Error("expected rpc_compression to be set")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.RPCCompression = "zip" })
+		if err == nil {
+			t.Error("expected error with an unknown rpc_compression")
+		}
+	})
+
+	t.Run("rpc class budgets", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.RPCClassBudgets = map[RPCClass]RPCClassBudget{
+				RPCClassBulk: {MaxConcurrent: 2, Bandwidth: 1 << 20},
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := cfg.RPCClassBudgets[RPCClassBulk]; b.MaxConcurrent != 2 || b.Bandwidth != 1<<20 {
+			t.Error("expected rpc_class_budgets to be set")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) {
+			j.RPCClassBudgets = map[RPCClass]RPCClassBudget{"urgent": {MaxConcurrent: 1}}
+		})
+		if err == nil {
+			t.Error("expected error with an unknown rpc class")
+		}
+	})
+
+	t.Run("content policy", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.DeniedCIDs = []string{test.Cid1.String()}
+			j.AllowedCIDs = []string{test.Cid2.String()}
+			j.DenylistURLs = []string{"https://example.org/denylist"}
+			j.DenylistUpdateInterval = "10m"
+			j.DenylistForceBulkUnpin = true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.DeniedCIDs) != 1 || !cfg.DeniedCIDs[0].Equals(test.Cid1) ||
+			len(cfg.AllowedCIDs) != 1 || !cfg.AllowedCIDs[0].Equals(test.Cid2) {
+			t.Error("expected denied_cids and allowed_cids to be set")
+		}
+		if len(cfg.DenylistURLs) != 1 || cfg.DenylistUpdateInterval != 10*time.Minute {
+			t.Error("expected denylist_urls and denylist_update_interval to be set")
+		}
+		if !cfg.DenylistForceBulkUnpin {
+			t.Error("expected denylist_force_bulk_unpin to be set")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.DeniedCIDs = []string{"abc"} })
+		if err == nil {
+			t.Error("expected error with an invalid denied CID")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.DenylistURLs = []string{"ftp://example.org"} })
+		if err == nil {
+			t.Error("expected error with an invalid denylist URL")
+		}
+	})
+
+	t.Run("signed pins", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.RequireSignedPins = true
+			j.AuthorizedKeys = []string{test.PeerID1.Pretty()}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.RequireSignedPins || len(cfg.AuthorizedKeys) != 1 || cfg.AuthorizedKeys[0] != test.PeerID1 {
+			t.Error("expected require_signed_pins and authorized_keys to be set")
+		}
+		if cfg.SignedPinMaxAge != DefaultSignedPinMaxAge {
+			t.Error("expected the default signed_pin_max_age")
+		}
+
+		cfg, err = loadJSON2(t, func(j *configJSON) { j.SignedPinMaxAge = "1m" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.SignedPinMaxAge != time.Minute {
+			t.Error("expected signed_pin_max_age to be set")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.SignedPinMaxAge = "-1m" })
+		if err == nil {
+			t.Error("expected error with a negative signed_pin_max_age")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.RequireSignedPins = true })
+		if err == nil {
+			t.Error("expected error requiring signed pins without authorized keys")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.AuthorizedKeys = []string{"abc"} })
+		if err == nil {
+			t.Error("expected error with an invalid authorized key")
+		}
+	})
+
+	t.Run("dht provide interval", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.DHTProvideInterval = "12h" })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.DHTProvideInterval != 12*time.Hour {
+			t.Error("expected dht_provide_interval to be set")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.DHTProvideInterval = "-1s" })
+		if err == nil {
+			t.Error("expected error with negative dht_provide_interval")
+		}
+	})
+
+	t.Run("allocation components", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.AllocationInformer = "custom-informer"
+			j.Allocator = "custom-allocator"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.AllocationInformer != "custom-informer" || cfg.Allocator != "custom-allocator" {
+			t.Error("allocation components not loaded correctly")
+		}
+	})
+
+	t.Run("refuse incompatible peers", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.RefuseIncompatiblePeers = true })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.RefuseIncompatiblePeers {
+			t.Error("expected refuse_incompatible_peers to be loaded")
+		}
+	})
+
+	t.Run("enable fault injection", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.EnableFaultInjection = true })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.EnableFaultInjection {
+			t.Error("expected enable_fault_injection to be loaded")
+		}
+	})
+
+	t.Run("allocatable", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.Allocatable = nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.Allocatable {
+			t.Error("expected peers to be allocatable by default")
+		}
+
+		notAllocatable := false
+		cfg, err = loadJSON2(t, func(j *configJSON) { j.Allocatable = &notAllocatable })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.IsAllocatable() {
+			t.Error("expected allocatable to be false")
+		}
+	})
+
+	t.Run("bulk unpins", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.BulkUnpinThreshold != DefaultBulkUnpinThreshold ||
+			cfg.BulkUnpinThresholdPercent != DefaultBulkUnpinThresholdPercent ||
+			!cfg.BulkUnpinConfirmation ||
+			cfg.BulkUnpinConfirmTimeout != DefaultBulkUnpinConfirmTimeout {
+			t.Error("expected bulk unpin defaults")
+		}
+
+		noConfirmation := false
+		cfg, err = loadJSON2(t, func(j *configJSON) {
+			j.BulkUnpinThreshold = -1
+			j.BulkUnpinThresholdPercent = 10
+			j.BulkUnpinConfirmation = &noConfirmation
+			j.BulkUnpinConfirmTimeout = "1m"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.BulkUnpinThreshold != -1 ||
+			cfg.BulkUnpinThresholdPercent != 10 ||
+			cfg.BulkUnpinConfirmation ||
+			cfg.BulkUnpinConfirmTimeout != time.Minute {
+			t.Error("expected bulk unpin options to be set")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.BulkUnpinThresholdPercent = 101 })
+		if err == nil {
+			t.Error("expected error with bulk_unpin_threshold_percent over 100")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.BulkUnpinConfirmTimeout = "-1s" })
+		if err == nil {
+			t.Error("expected error with negative bulk_unpin_confirm_timeout")
+		}
+	})
+
+	t.Run("connection gating", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.ConnectionAllowPeers = []string{"QmUfSFm12eYCaRdypg48m8RqkXfLW7A2ZeGZb2skeHHDGA"}
+			j.ConnectionAllowCIDRs = []string{"10.0.0.0/8"}
+			j.ConnectionDenyPeers = []string{"QmUfSFm12eYCaRdypg48m8RqkXfLW7A2ZeGZb2skeHHDGA"}
+			j.ConnectionDenyCIDRs = []string{"10.1.0.0/16", "192.168.1.1/32"}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.ConnectionAllowPeers) != 1 || len(cfg.ConnectionAllowCIDRs) != 1 {
+			t.Error("expected connection allow lists to be loaded")
+		}
+		denyPeers, denyCIDRs := cfg.GetConnectionDenyList()
+		if len(denyPeers) != 1 || len(denyCIDRs) != 2 {
+			t.Error("expected connection deny lists to be loaded")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.ConnectionDenyCIDRs = []string{"10.0.0.1"} })
+		if err == nil {
+			t.Error("expected error parsing connection_deny_cidrs")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.ConnectionAllowPeers = []string{"abc"} })
+		if err == nil {
+			t.Error("expected error parsing connection_allow_peers")
+		}
+	})
+
+	t.Run("pubsub signing", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.PubsubMessageSigning || !cfg.PubsubStrictSignatureVerification {
+			t.Error("expected pubsub signing and verification by default")
+		}
+
+		disabled := false
+		cfg, err = loadJSON2(t, func(j *configJSON) {
+			j.PubsubMessageSigning = &disabled
+			j.PubsubStrictSignatureVerification = &disabled
+			j.PubsubValidateThrottle = 100
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PubsubMessageSigning || cfg.PubsubStrictSignatureVerification {
+			t.Error("expected pubsub signing to be disabled")
+		}
+		if cfg.PubsubValidateThrottle != 100 {
+			t.Error("expected pubsub_validate_throttle to be loaded")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) { j.PubsubMessageSigning = &disabled })
+		if err == nil {
+			t.Error("expected error: strict verification without signing")
+		}
+	})
+
 	t.Run("bad secret", func(t *testing.T) {
 		_, err := loadJSON2(t, func(j *configJSON) { j.Secret = "abc" })
 		if err == nil {
@@ -217,4 +821,16 @@ func TestValidate(t *testing.T) {
 	if cfg.Validate() == nil {
 		t.Fatal("expected error validating")
 	}
+
+	cfg.Default()
+	cfg.PubsubValidateThrottle = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.RecoveryConcurrency = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
 }
@@ -1,12 +1,14 @@
 package ipfscluster
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -17,6 +19,8 @@ import (
 	"github.com/ipfs/ipfs-cluster/config"
 	"github.com/ipfs/ipfs-cluster/informer/numpin"
 	"github.com/ipfs/ipfs-cluster/monitor/pubsubmon"
+	"github.com/ipfs/ipfs-cluster/policy"
+	"github.com/ipfs/ipfs-cluster/ratelimit"
 	"github.com/ipfs/ipfs-cluster/state"
 	"github.com/ipfs/ipfs-cluster/test"
 	"github.com/ipfs/ipfs-cluster/version"
@@ -24,8 +28,10 @@ import (
 	gopath "github.com/ipfs/go-path"
 
 	cid "github.com/ipfs/go-cid"
+	crypto "github.com/libp2p/go-libp2p-crypto"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 type mockComponent struct {
@@ -134,6 +140,37 @@ func (ipfs *mockConnector) BlockGet(ctx context.Context, c cid.Cid) ([]byte, err
 	return d.([]byte), nil
 }
 
+func (ipfs *mockConnector) Provide(ctx context.Context, c cid.Cid) error {
+	return nil
+}
+
+func (ipfs *mockConnector) BitswapStat(ctx context.Context) (*api.IPFSBitswapStat, error) {
+	return &api.IPFSBitswapStat{}, nil
+}
+
+func (ipfs *mockConnector) PinProgress(ctx context.Context) ([]*api.PinProgress, error) {
+	return nil, nil
+}
+
+func (ipfs *mockConnector) DAGSize(ctx context.Context, c cid.Cid) (uint64, error) {
+	return 500, nil
+}
+
+func (ipfs *mockConnector) DAGBlocks(ctx context.Context, c cid.Cid, maxDepth int) ([]*api.IPFSBlockStat, error) {
+	return []*api.IPFSBlockStat{
+		{Cid: c, Size: 100},
+		{Cid: test.Cid4, Size: 400},
+	}, nil
+}
+
+func (ipfs *mockConnector) RepoGC(ctx context.Context) (*api.RepoGC, error) {
+	return &api.RepoGC{Removed: 2}, nil
+}
+
+func (ipfs *mockConnector) DaemonInfo(ctx context.Context) (*api.IPFSDaemonInfo, error) {
+	return &api.IPFSDaemonInfo{Version: "0.4.22", Routing: "dht", StorageMax: "10G"}, nil
+}
+
 type mockTracer struct {
 	mockComponent
 }
@@ -153,10 +190,10 @@ func testingCluster(t *testing.T) (*Cluster, *mockAPI, *mockConnector, PinTracke
 	api := &mockAPI{}
 	proxy := &mockProxy{}
 	ipfs := &mockConnector{}
-	tracker := makePinTracker(t, ident.ID, maptrackerCfg, statelesstrackerCfg, clusterCfg.Peername)
 	tracer := &mockTracer{}
 
 	store := makeStore(t, badgerCfg)
+	tracker := makePinTracker(t, ident.ID, maptrackerCfg, statelesstrackerCfg, clusterCfg.Peername, store)
 	cons := makeConsensus(t, store, host, pubsub, dht, raftCfg, false, crdtCfg)
 
 	var peersF func(context.Context) ([]peer.ID, error)
@@ -164,7 +201,7 @@ func testingCluster(t *testing.T) (*Cluster, *mockAPI, *mockConnector, PinTracke
 		peersF = cons.Peers
 	}
 	psmonCfg.CheckInterval = 2 * time.Second
-	mon, err := pubsubmon.New(ctx, psmonCfg, pubsub, peersF)
+	mon, err := pubsubmon.New(ctx, psmonCfg, pubsub, peersF, store)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -188,7 +225,7 @@ func testingCluster(t *testing.T) (*Cluster, *mockAPI, *mockConnector, PinTracke
 		tracker,
 		mon,
 		alloc,
-		inf,
+		[]Informer{inf},
 		tracer,
 	)
 	if err != nil {
@@ -246,6 +283,66 @@ func TestClusterStateSync(t *testing.T) {
 	if err != nil {
 		t.Fatal("sync with recover should have worked:", err)
 	}
+
+	last, err := cl.LastStateSyncLocal(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Peer != cl.id || last.Start.IsZero() || last.End.Before(last.Start) {
+		t.Errorf("unexpected last state sync: %+v", last)
+	}
+	// The stateless tracker does not report items which are not in
+	// the shared state, so there is nothing to untrack. With crdt,
+	// removing the item from the state untracks it already.
+	if ptracker == "map" && consensus == "raft" && last.Changed != 1 {
+		t.Errorf("expected 1 changed item, got %d", last.Changed)
+	}
+	if last.Error != "" {
+		t.Error("expected no error")
+	}
+}
+
+func TestClusterStateSyncPrunesAllocations(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	err := cl.Pin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+
+	old := time.Now().Add(-time.Hour)
+	cl.recordAllocation(&api.AllocationExplanation{Cid: test.Cid1, TS: old})
+	cl.recordAllocation(&api.AllocationExplanation{Cid: test.Cid2, TS: old})
+
+	// Explanations are kept in the datastore.
+	if ok, err := cl.allocationStore().Has(allocationKey(test.Cid1)); err != nil || !ok {
+		t.Fatal("expected the allocation explanation to be stored")
+	}
+
+	err = cl.StateSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Not in the state, but more recent than the last StateSync.
+	cl.recordAllocation(&api.AllocationExplanation{Cid: test.Cid3, TS: time.Now()})
+	err = cl.StateSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cl.AllocationExplanationLocal(ctx, test.Cid1); err != nil {
+		t.Error("explanations for pins in the state should be kept")
+	}
+	if _, err := cl.AllocationExplanationLocal(ctx, test.Cid2); err == nil {
+		t.Error("old explanations for pins not in the state should be pruned")
+	}
+	if _, err := cl.AllocationExplanationLocal(ctx, test.Cid3); err != nil {
+		t.Error("recent explanations should be kept")
+	}
 }
 
 func TestClusterID(t *testing.T) {
@@ -263,11 +360,165 @@ func TestClusterID(t *testing.T) {
 	if id.Version != version.Version.String() {
 		t.Error("version should match current version")
 	}
+	if !id.Allocatable {
+		t.Error("peers should be allocatable by default")
+	}
+	if id.FreeSpace != 900 {
+		t.Error("expected free space from the ipfs repo stats")
+	}
+	if id.PinQueue != 0 || id.PinErrors != 0 {
+		t.Error("expected an empty pin queue and no errors")
+	}
 	//if id.PublicKey == nil {
 	//	t.Error("publicKey should not be empty")
 	//}
 }
 
+func TestClusterSetAllocatable(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	err := cl.SetAllocatable(ctx, cl.id, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cl.ID(ctx).Allocatable {
+		t.Error("peer should not be allocatable")
+	}
+	if cl.config.Allocatable {
+		t.Error("the configuration should have been updated")
+	}
+
+	err = cl.SetAllocatable(ctx, cl.id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cl.ID(ctx).Allocatable {
+		t.Error("peer should be allocatable")
+	}
+}
+
+func TestClusterConnectionDeny(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	deny := &api.ConnectionFilter{
+		Peers: []peer.ID{test.PeerID1},
+		CIDRs: []string{"10.0.0.0/8"},
+	}
+	err := cl.ConnectionDeny(ctx, deny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// adding twice should not duplicate entries
+	err = cl.ConnectionDeny(ctx, deny)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := cl.ConnectionDenyList(ctx)
+	if len(list.Peers) != 1 || list.Peers[0] != test.PeerID1 {
+		t.Error("expected the peer in the deny list")
+	}
+	if len(list.CIDRs) != 1 || list.CIDRs[0] != "10.0.0.0/8" {
+		t.Error("expected the cidr in the deny list")
+	}
+	if cl.connGater.allowedPeer(test.PeerID1) {
+		t.Error("peer should not be allowed")
+	}
+	addr, _ := ma.NewMultiaddr("/ip4/10.1.2.3/tcp/9096")
+	if !cl.connGater.filters.AddrBlocked(addr) {
+		t.Error("address should be blocked")
+	}
+
+	err = cl.ConnectionDeny(ctx, &api.ConnectionFilter{CIDRs: []string{"abc"}})
+	if err == nil {
+		t.Error("expected an error with a bad cidr")
+	}
+	err = cl.ConnectionDeny(ctx, &api.ConnectionFilter{Peers: []peer.ID{cl.id}})
+	if err == nil {
+		t.Error("expected an error denying ourselves")
+	}
+
+	err = cl.ConnectionUndeny(ctx, deny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list = cl.ConnectionDenyList(ctx)
+	if len(list.Peers) != 0 || len(list.CIDRs) != 0 {
+		t.Error("expected an empty deny list")
+	}
+	if !cl.connGater.allowedPeer(test.PeerID1) {
+		t.Error("peer should be allowed")
+	}
+	if cl.connGater.filters.AddrBlocked(addr) {
+		t.Error("address should not be blocked")
+	}
+}
+
+func TestClusterReservations(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	m := &api.Metric{
+		Name:       numpin.MetricName,
+		Peer:       test.PeerID1,
+		Value:      "1",
+		Valid:      true,
+		ReceivedAt: time.Now().UnixNano(),
+	}
+
+	cl.reservations.reserve([]peer.ID{test.PeerID1, test.PeerID1, test.PeerID2})
+
+	adjusted := cl.reserveMetrics(ctx, []*api.Metric{m})
+	if len(adjusted) != 1 || adjusted[0].Value != "3" {
+		t.Fatal("expected the metric to account for 2 reservations")
+	}
+
+	rm, err := cl.reservations.metric(cl.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := parseReservationsMetric(rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[test.PeerID1] != 2 || counts[test.PeerID2] != 1 {
+		t.Error("unexpected reservations metric:", rm.Value)
+	}
+
+	// A metric sent before the reservations does not reconcile them,
+	// even when received later.
+	stale := *m
+	stale.SentAt = m.ReceivedAt - int64(time.Second)
+	stale.ReceivedAt = time.Now().UnixNano()
+	adjusted = cl.reserveMetrics(ctx, []*api.Metric{&stale})
+	if adjusted[0].Value != "3" {
+		t.Error("reservations should be kept for stale metrics")
+	}
+
+	// A newer metric reconciles the reservations
+	m2 := *m
+	m2.SentAt = time.Now().UnixNano()
+	m2.ReceivedAt = m2.SentAt
+	adjusted = cl.reserveMetrics(ctx, []*api.Metric{&m2})
+	if adjusted[0].Value != "1" {
+		t.Error("reservations should have been dropped")
+	}
+	if _, ok := cl.reservations.counts()[test.PeerID1]; ok {
+		t.Error("reservations should have been dropped")
+	}
+	if !cl.reservations.isDirty() {
+		t.Error("reservations should need to be published")
+	}
+}
+
 func TestClusterPin(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
@@ -296,121 +547,1058 @@ func TestClusterPin(t *testing.T) {
 	}
 }
 
-func TestClusterPinPath(t *testing.T) {
+func TestClusterPinTimestamps(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
 
-	pin, err := cl.PinPath(ctx, &api.PinPath{Path: test.PathIPFS2})
+	c := test.Cid1
+	before := time.Now()
+	err := cl.Pin(ctx, api.PinCid(c))
 	if err != nil {
 		t.Fatal("pin should have worked:", err)
 	}
-	if !pin.Cid.Equals(test.CidResolved) {
-		t.Error("expected a different cid, found", pin.Cid.String())
+	pinDelay()
+
+	pin, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.CreatedAt.Before(before) || !pin.UpdatedAt.Equal(pin.CreatedAt) {
+		t.Errorf("unexpected timestamps: %s %s", pin.CreatedAt, pin.UpdatedAt)
 	}
 
-	// test an error case
-	_, err = cl.PinPath(ctx, &api.PinPath{Path: test.InvalidPath1})
-	if err == nil {
-		t.Error("expected an error but things worked")
+	// Repin with different options
+	pin2 := api.PinCid(c)
+	pin2.Name = "updated"
+	err = cl.Pin(ctx, pin2)
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	updated, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.CreatedAt.Equal(pin.CreatedAt) {
+		t.Error("created_at should have been preserved")
+	}
+	if !updated.UpdatedAt.After(pin.UpdatedAt) {
+		t.Error("updated_at should have been updated")
 	}
 }
 
-func TestAddFile(t *testing.T) {
+func TestClusterPinProvenance(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
-	sth := test.NewShardingTestHelper()
-	defer sth.Clean(t)
-
-	t.Run("local", func(t *testing.T) {
-		params := api.DefaultAddParams()
-		params.Shard = false
-		params.Name = "testlocal"
-		mfr, closer := sth.GetTreeMultiReader(t)
-		defer closer.Close()
-		r := multipart.NewReader(mfr, mfr.Boundary())
-		c, err := cl.AddFile(r, params)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if c.String() != test.ShardingDirBalancedRootCID {
-			t.Fatal("unexpected root CID for local add")
-		}
-
-		pinDelay()
-
-		pin := cl.StatusLocal(ctx, c)
-		if pin.Error != "" {
-			t.Fatal(pin.Error)
-		}
-		if pin.Status != api.TrackerStatusPinned {
-			t.Error("cid should be pinned")
-		}
-
-		cl.Unpin(ctx, c) // unpin so we can pin the shard in next test
-		pinDelay()
-	})
 
-	t.Run("shard", func(t *testing.T) {
-		params := api.DefaultAddParams()
-		params.Shard = true
-		params.Name = "testshard"
-		mfr, closer := sth.GetTreeMultiReader(t)
-		defer closer.Close()
-		r := multipart.NewReader(mfr, mfr.Boundary())
-		c, err := cl.AddFile(r, params)
-		if err != nil {
-			t.Fatal(err)
-		}
+	c := test.Cid1
+	pin := api.PinCid(c)
+	pin.Provenance = &api.PinProvenance{Source: api.ProvenanceRESTAPI, User: "alice"}
+	err := cl.Pin(ctx, pin)
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
 
-		if c.String() != test.ShardingDirBalancedRootCID {
-			t.Fatal("unexpected root CID for local add")
-		}
+	created, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := api.PinProvenance{Source: api.ProvenanceRESTAPI, User: "alice", Peer: cl.id}
+	if created.Provenance == nil || *created.Provenance != expected {
+		t.Errorf("unexpected provenance: %v", created.Provenance)
+	}
+	if created.CreatedBy == nil || *created.CreatedBy != expected {
+		t.Errorf("unexpected created_by: %v", created.CreatedBy)
+	}
 
-		pinDelay()
+	// Re-allocations keep the original creator
+	created.Provenance = &api.PinProvenance{Source: api.ProvenanceReallocation}
+	created.Name = "reallocated"
+	err = cl.Pin(ctx, created)
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
 
-		// We know that this produces 14 shards.
-		sharding.VerifyShards(t, c, cl, cl.ipfs, 14)
-	})
+	updated, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Provenance == nil || updated.Provenance.Source != api.ProvenanceReallocation {
+		t.Errorf("unexpected provenance: %v", updated.Provenance)
+	}
+	if updated.CreatedBy == nil || *updated.CreatedBy != expected {
+		t.Errorf("created_by should have been preserved: %v", updated.CreatedBy)
+	}
 }
 
-func TestUnpinShard(t *testing.T) {
+func TestClusterSnapshots(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
-	sth := test.NewShardingTestHelper()
-	defer sth.Clean(t)
 
-	params := api.DefaultAddParams()
-	params.Shard = true
-	params.Name = "testshard"
-	mfr, closer := sth.GetTreeMultiReader(t)
-	defer closer.Close()
-	r := multipart.NewReader(mfr, mfr.Boundary())
-	root, err := cl.AddFile(r, params)
+	pinWithName := func(c cid.Cid, name string) {
+		pin := api.PinCid(c)
+		pin.Name = name
+		err := cl.Pin(ctx, pin)
+		if err != nil {
+			t.Fatal("pin should have worked:", err)
+		}
+		pinDelay()
+	}
+
+	_, err := cl.SnapshotCreate(ctx, "empty")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	pinDelay()
-
-	// We know that this produces 14 shards.
-	sharding.VerifyShards(t, root, cl, cl.ipfs, 14)
+	pinWithName(test.Cid1, "a")
+	pinWithName(test.Cid2, "b")
 
-	// skipping errors, VerifyShards has checked
-	pinnedCids := []cid.Cid{}
-	pinnedCids = append(pinnedCids, root)
-	metaPin, _ := cl.PinGet(ctx, root)
-	cDag, _ := cl.PinGet(ctx, *metaPin.Reference)
-	pinnedCids = append(pinnedCids, cDag.Cid)
-	cDagBlock, _ := cl.ipfs.BlockGet(ctx, cDag.Cid)
-	cDagNode, _ := sharding.CborDataToNode(cDagBlock, "cbor")
-	for _, l := range cDagNode.Links() {
-		pinnedCids = append(pinnedCids, l.Cid)
+	snap, err := cl.SnapshotCreate(ctx, "before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Pins != 2 {
+		t.Errorf("expected 2 pins in the snapshot, got %d", snap.Pins)
+	}
+	if _, err := cl.SnapshotCreate(ctx, "before"); err == nil {
+		t.Error("expected an error reusing a snapshot name")
+	}
+	if _, err := cl.SnapshotCreate(ctx, "bad/name"); err == nil {
+		t.Error("expected an error with an invalid name")
+	}
+
+	// Risky changes
+	pinWithName(test.Cid1, "renamed")
+	pinWithName(test.Cid3, "c")
+	err = cl.Unpin(ctx, test.Cid2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+
+	diff, err := cl.SnapshotDiff(ctx, api.SnapshotDiffQuery{From: "before", To: api.SnapshotCurrent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Pin) != 2 || len(diff.Unpin) != 1 || !diff.Unpin[0].Cid.Equals(test.Cid2) {
+		t.Errorf("unexpected diff: %d to pin, %d to unpin", len(diff.Pin), len(diff.Unpin))
+	}
+
+	res, err := cl.SnapshotRestore(ctx, api.SnapshotRestoreQuery{Name: "before"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors)
+	}
+	if len(res.Pinned) != 2 || len(res.Unpinned) != 1 || !res.Unpinned[0].Equals(test.Cid3) {
+		t.Errorf("unexpected restore: %d pinned, %d unpinned", len(res.Pinned), len(res.Unpinned))
+	}
+
+	pin, err := cl.PinGet(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.Name != "a" {
+		t.Error("expected the pin options to be restored")
+	}
+	if _, err := cl.PinGet(ctx, test.Cid2); err != nil {
+		t.Error("expected Cid2 to be pinned again")
+	}
+	if _, err := cl.PinGet(ctx, test.Cid3); err == nil {
+		t.Error("expected Cid3 to be unpinned")
+	}
+
+	diff, err = cl.SnapshotDiff(ctx, api.SnapshotDiffQuery{From: "before", To: api.SnapshotCurrent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Pin) != 0 || len(diff.Unpin) != 0 {
+		t.Error("expected no differences after restoring")
+	}
+
+	// Restoring the empty snapshot unpins everything.
+	cl.config.BulkUnpinConfirmation = true
+	q := api.SnapshotRestoreQuery{Name: "empty"}
+	_, err = cl.SnapshotRestore(ctx, q)
+	if buErr, ok := api.AsBulkUnpinError(err); !ok || buErr.Token != "" {
+		t.Fatalf("expected the restore to be refused: %v", err)
+	}
+	q.Options.Force = true
+	_, err = cl.SnapshotRestore(ctx, q)
+	buErr, ok := api.AsBulkUnpinError(err)
+	if !ok || buErr.Token == "" {
+		t.Fatalf("expected a confirmation token: %v", err)
+	}
+	q.Options.Confirm = buErr.Token
+	res, err = cl.SnapshotRestore(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	if len(res.Unpinned) != 2 {
+		t.Errorf("expected 2 unpins, got %d", len(res.Unpinned))
+	}
+	recs, err := cl.AuditLog(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := recs[len(recs)-1]; last.Operation != "restore of snapshot empty" || last.Outcome != api.AuditCompleted {
+		t.Errorf("unexpected last audit record: %+v", last)
+	}
+
+	for _, name := range []string{"before", "empty"} {
+		err = cl.SnapshotDelete(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	snaps, err := cl.Snapshots(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 0 {
+		t.Error("expected no snapshots")
+	}
+}
+
+func TestClusterPinSize(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	c := test.Cid1
+	err := cl.Pin(ctx, api.PinCid(c))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	pin, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See mockConnector.DAGSize
+	if pin.Size != 500 {
+		t.Errorf("expected the estimated size to be recorded, got %d", pin.Size)
+	}
+
+	cl.config.MaxPinSize = 499
+	err = cl.Pin(ctx, api.PinCid(test.Cid2))
+	if err == nil {
+		t.Error("expected an error pinning over the maximum pin size")
+	}
+}
+
+func TestClusterAddDelegate(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	// See mockConnector.RepoStat: 900 bytes free.
+	cl.config.AddDelegationMinFreeSpace = 500
+	cl.config.AddDelegationMaxAdds = 2
+	if reason := cl.addDelegationReason(ctx, 1); reason != "" {
+		t.Error("adds should not be delegated:", reason)
+	}
+	if reason := cl.addDelegationReason(ctx, 2); reason == "" {
+		t.Error("adds should be delegated when too many are in progress")
+	}
+	cl.config.AddDelegationMinFreeSpace = 1000
+	if reason := cl.addDelegationReason(ctx, 0); reason == "" {
+		t.Error("adds should be delegated when low on free space")
+	}
+
+	// No other peers
+	pid, err := cl.AddDelegate(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid != "" {
+		t.Error("adds should be performed locally without other peers")
+	}
+
+	metric := func(p peer.ID, v string) *api.Metric {
+		m := &api.Metric{Name: cl.informer.Name(), Peer: p, Value: v, Valid: true}
+		m.SetTTL(time.Minute)
+		return m
+	}
+	metrics := []*api.Metric{
+		metric(cl.id, "0"),
+		metric(test.PeerID1, "5"),
+		metric(test.PeerID2, "3"),
+	}
+	cl.consensus.Trust(ctx, test.PeerID1)
+	cl.consensus.Trust(ctx, test.PeerID2)
+	// The allocator (ascendalloc) prefers the lowest value, leaving out
+	// this peer.
+	if d := cl.chooseAddDelegate(ctx, metrics); d != test.PeerID2 {
+		t.Errorf("expected %s as delegate, got %s", test.PeerID2, d)
+	}
+
+	// Untrusted peers are left out (raft trusts every peer).
+	if consensus == "crdt" {
+		cl.consensus.Distrust(ctx, test.PeerID2)
+		if d := cl.chooseAddDelegate(ctx, metrics); d != test.PeerID1 {
+			t.Errorf("expected %s as delegate, got %s", test.PeerID1, d)
+		}
+	}
+}
+
+func TestClusterBlockAllocateStrategy(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	rpcapi := &ClusterRPCAPI{c: cl}
+	opts := api.PinOptions{ReplicationFactorMin: 1, ReplicationFactorMax: 1}
+
+	cl.config.BlockPutStrategy = BlockPutLocal
+	var allocs []peer.ID
+	err := rpcapi.BlockAllocate(ctx, api.PinWithOpts(cid.Undef, opts), &allocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allocs) != 1 || allocs[0] != cl.id {
+		t.Errorf("blocks should be put in the local peer only: %s", allocs)
+	}
+
+	// Shards are always spread over their allocations.
+	shard := api.PinWithOpts(cid.Undef, opts)
+	shard.Type = api.ShardType
+	shard.MaxDepth = 1
+	allocs = nil
+	err = rpcapi.BlockAllocate(ctx, shard, &allocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allocs) != 1 {
+		t.Errorf("expected the shard to be allocated: %s", allocs)
+	}
+
+	// The pin is allocated where the blocks were put.
+	pin := api.PinWithOpts(test.Cid1, opts)
+	pin.Allocations = []peer.ID{cl.id}
+	err = cl.Pin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := cl.PinGet(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pinned.Allocations) != 1 || pinned.Allocations[0] != cl.id {
+		t.Errorf("unexpected allocations: %s", pinned.Allocations)
+	}
+}
+
+func TestClusterContentPolicy(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	err := cl.Pin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	cl.policy = policy.New([]cid.Cid{test.Cid3}, []cid.Cid{test.Cid1, test.Cid2, test.Cid3})
+
+	err = cl.Pin(ctx, api.PinCid(test.Cid2))
+	if err == nil {
+		t.Error("denied CIDs should not be pinned")
+	}
+	err = cl.Pin(ctx, api.PinCid(test.Cid3))
+	if err != nil {
+		t.Error("allowed CIDs should be pinned:", err)
+	}
+
+	// Existing pins are removed
+	err = cl.StateSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	if _, err := cl.PinGet(ctx, test.Cid1); err == nil {
+		t.Error("denied pins should have been removed")
+	}
+
+	// Removing the whole pinset needs to be forced
+	cl.policy = policy.New(nil, []cid.Cid{test.Cid3})
+	err = cl.StateSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	if _, err := cl.PinGet(ctx, test.Cid3); err != nil {
+		t.Error("bulk removals by the denylist should be refused")
+	}
+
+	cl.config.DenylistForceBulkUnpin = true
+	err = cl.StateSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	if _, err := cl.PinGet(ctx, test.Cid3); err == nil {
+		t.Error("forced bulk removals should be done")
+	}
+
+	recs, err := cl.AuditLog(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcomes := make(map[string]int)
+	for _, rec := range recs {
+		outcomes[rec.Outcome]++
+	}
+	if outcomes[api.AuditRefused] != 2 || outcomes[api.AuditForced] != 1 || outcomes[api.AuditCompleted] != 2 {
+		t.Errorf("unexpected audit records: %v", outcomes)
+	}
+}
+
+func TestClusterSignedPins(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	authorized, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := peer.IDFromPrivateKey(authorized)
+	cl.config.RequireSignedPins = true
+	cl.config.AuthorizedKeys = []peer.ID{pid}
+	cl.config.UnpinRetention = time.Hour
+	cl.pinVerifier = newPinVerifier(cl.config)
+
+	sign := func(op string, pin *api.Pin, key crypto.PrivKey) *api.Pin {
+		sig, err := api.SignPin(op, pin, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pin.Signature = sig
+		return pin
+	}
+	call := func(method string, pin *api.Pin) error {
+		switch method {
+		case "PinWithResponse":
+			return cl.rpcClient.CallContext(ctx, "", "Cluster", method, pin, &api.PinResponse{})
+		case "RestorePin":
+			return cl.rpcClient.CallContext(ctx, "", "Cluster", method, pin, &api.Pin{})
+		}
+		return cl.rpcClient.CallContext(ctx, "", "Cluster", method, pin, &struct{}{})
+	}
+	newPin := func() *api.Pin {
+		return api.PinWithOpts(test.Cid1, api.PinOptions{Name: "signed"})
+	}
+
+	if err := call("Pin", newPin()); err == nil {
+		t.Error("unsigned pins should be refused")
+	}
+	if err := call("Pin", sign(api.SignedPinOp, newPin(), other)); err == nil {
+		t.Error("pins signed by unauthorized keys should be refused")
+	}
+	if err := call("Pin", sign(api.SignedUnpinOp, newPin(), authorized)); err == nil {
+		t.Error("pins signed for another operation should be refused")
+	}
+	tampered := sign(api.SignedPinOp, newPin(), authorized)
+	tampered.Name = "tampered"
+	if err := call("Pin", tampered); err == nil {
+		t.Error("pins with options modified after signing should be refused")
+	}
+
+	// Signed before the pin below, submitted after it.
+	old := sign(api.SignedPinOp, api.PinWithOpts(test.Cid1, api.PinOptions{Name: "old"}), authorized)
+	signed := sign(api.SignedPinOp, newPin(), authorized)
+	if err := call("PinWithResponse", signed); err != nil {
+		t.Fatal("signed pin should have worked:", err)
+	}
+	pinDelay()
+
+	pinfo := cl.StatusLocal(ctx, test.Cid1)
+	if pinfo.Status != api.TrackerStatusPinned {
+		t.Error("the signed pin should be tracked")
+	}
+	if err := call("PinWithResponse", signed); err == nil {
+		t.Error("replayed pins should be refused")
+	}
+
+	if err := call("Unpin", api.PinCid(test.Cid1)); err == nil {
+		t.Error("unsigned unpins should be refused")
+	}
+	unpin := sign(api.SignedUnpinOp, api.PinCid(test.Cid1), authorized)
+	if err := call("Unpin", unpin); err != nil {
+		t.Fatal("signed unpin should have worked:", err)
+	}
+	pinDelay()
+	if err := call("Unpin", unpin); err == nil {
+		t.Error("replayed unpins should be refused")
+	}
+
+	if err := call("RestorePin", api.PinCid(test.Cid1)); err == nil {
+		t.Error("unsigned restores should be refused")
+	}
+	if err := call("RestorePin", sign(api.SignedUnpinOp, api.PinCid(test.Cid1), authorized)); err == nil {
+		t.Error("restores signed for another operation should be refused")
+	}
+	if err := call("RestorePin", sign(api.SignedRestoreOp, api.PinCid(test.Cid1), authorized)); err != nil {
+		t.Fatal("signed restore should have worked:", err)
+	}
+
+	// Requests signed before the pin in the state cannot revert it.
+	if err := call("Pin", old); err == nil {
+		t.Error("requests signed before the current pin should be refused")
+	}
+
+	pinPath := &api.PinPath{Path: test.PathIPFS2}
+	err = cl.rpcClient.CallContext(ctx, "", "Cluster", "PinPath", pinPath, &api.Pin{})
+	if err == nil {
+		t.Error("requests with paths should be refused")
+	}
+}
+
+func TestPinVerifierRequests(t *testing.T) {
+	key, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := peer.IDFromPrivateKey(key)
+	cfg := &Config{}
+	cfg.Default()
+	cfg.RequireSignedPins = true
+	cfg.AuthorizedKeys = []peer.ID{pid}
+	v := newPinVerifier(cfg)
+
+	pin := api.PinCid(test.Cid1)
+	sig, err := api.SignPin(api.SignedPinOp, pin, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin.Signature = sig
+	if err := v.verifyRequest(api.SignedPinOp, pin, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.verifyRequest(api.SignedPinOp, pin, nil); err == nil {
+		t.Error("replayed requests should be refused")
+	}
+
+	// Default replication factors are set when committing pins.
+	committed := *pin
+	committed.ReplicationFactorMin = cfg.ReplicationFactorMin
+	committed.ReplicationFactorMax = cfg.ReplicationFactorMax
+	if err := v.verify(api.SignedPinOp, &committed); err != nil {
+		t.Error("pins with the default replication factors should verify:", err)
+	}
+	committed.ReplicationFactorMax = cfg.ReplicationFactorMax + 1
+	if err := v.verify(api.SignedPinOp, &committed); err == nil {
+		t.Error("pins with other replication factors should not verify")
+	}
+
+	// Requests must be recent.
+	v.maxAge = 10 * time.Millisecond
+	p := api.PinCid(test.Cid2)
+	sig, err = api.SignPin(api.SignedUnpinOp, p, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Signature = sig
+	time.Sleep(50 * time.Millisecond)
+	if err := v.verifyRequest(api.SignedUnpinOp, p, nil); err == nil {
+		t.Error("old requests should be refused")
+	}
+}
+
+func TestClusterPinRateLimit(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	cl.config.PinRateLimit = 1
+	cl.config.PinRateMaxWait = 0
+	cl.pinRate = ratelimit.NewBucket(1)
+
+	err := cl.Pin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	err = cl.Pin(ctx, api.PinCid(test.Cid2))
+	if _, ok := api.AsRateLimitError(err); !ok {
+		t.Fatal("expected a rate limit error:", err)
+	}
+	if _, err := cl.PinGet(ctx, test.Cid2); err == nil {
+		t.Error("the refused pin should not be in the state")
+	}
+}
+
+func TestClusterPinnedBytes(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	err := cl.Pin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	cl.config.PinSizeEstimationTimeout = 0
+	err = cl.Pin(ctx, api.PinCid(test.Cid2))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	pb, err := cl.PinnedBytes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See mockConnector.DAGSize and RepoStat
+	if pb.Pins != 2 || pb.UnknownSize != 1 || pb.RepoSize != 100 {
+		t.Errorf("unexpected estimate: %+v", pb)
+	}
+	if pb.Bytes != 500 {
+		t.Errorf("expected 500 bytes pinned, got %d", pb.Bytes)
+	}
+}
+
+func TestClusterRequestContext(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+
+	reqCtx, reqCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer reqCancel()
+	opCtx, cancel := cl.requestContext(reqCtx, nil)
+	defer cancel()
+	select {
+	case <-opCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("operation context should be done when the request deadline expires")
+	}
+
+	opCtx, cancel = cl.requestContext(ctx, nil)
+	defer cancel()
+	cl.Shutdown(ctx)
+	select {
+	case <-opCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("operation context should be done when the cluster shuts down")
+	}
+}
+
+func TestClusterStorageUsage(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	for _, c := range []cid.Cid{test.Cid1, test.Cid2} {
+		err := cl.Pin(ctx, api.PinCid(c))
+		if err != nil {
+			t.Fatal("pin should have worked:", err)
+		}
+	}
+	pinDelay()
+
+	usage, err := cl.StorageUsage(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See mockConnector.DAGBlocks: both DAGs share a 400 bytes block.
+	if usage.Pins != 2 || usage.SampledPins != 2 || usage.FailedPins != 0 {
+		t.Errorf("unexpected pin counts: %+v", usage)
+	}
+	if usage.UniqueBlocks != 3 || usage.UniqueBytes != 600 {
+		t.Errorf("unexpected unique usage: %+v", usage)
+	}
+	if usage.ReplicatedBytes != 1000 {
+		t.Errorf("expected 1000 replicated bytes, got %d", usage.ReplicatedBytes)
+	}
+
+	usage, err = cl.StorageUsage(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Pins != 2 || usage.SampledPins != 1 || usage.UniqueBytes != 1000 {
+		t.Errorf("unexpected sampled usage: %+v", usage)
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		wait     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tc := range cases {
+		wait := reconnectBackoff(time.Second, 10*time.Second, tc.attempts)
+		if wait != tc.wait {
+			t.Errorf("attempts %d: expected %s, got %s", tc.attempts, tc.wait, wait)
+		}
+	}
+}
+
+func TestClusterReconnect(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	cl.checkReconnect(ctx)
+	status := cl.ReconnectStatus(ctx)
+	if status.State != api.ReconnectStateAlone || status.KnownPeers != 0 {
+		t.Errorf("a single peer should be alone: %+v", status)
+	}
+
+	// Nobody listens there.
+	addr, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/1/ipfs/" + test.PeerID2.Pretty())
+	cl.reconnect.addBootstrap(addr)
+
+	wait := cl.checkReconnect(ctx)
+	status = cl.ReconnectStatus(ctx)
+	if status.State != api.ReconnectStateReconnecting {
+		t.Fatalf("expected the peer to be reconnecting: %+v", status)
+	}
+	if status.KnownPeers != 1 || status.Attempts != 1 || status.LastError == "" {
+		t.Errorf("unexpected reconnect status: %+v", status)
+	}
+	if wait != 2*cl.config.ReconnectInterval {
+		t.Errorf("expected to wait twice the reconnect interval, got %s", wait)
+	}
+}
+
+func TestClusterPinDirect(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	c := test.Cid1
+	depth := 0
+	err := cl.Pin(ctx, api.PinWithOpts(c, api.PinOptions{Depth: &depth}))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	pin, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.MaxDepth != 0 {
+		t.Error("expected a direct pin")
+	}
+
+	if st := cl.StatusLocal(ctx, c); st.Status != api.TrackerStatusPinned {
+		t.Error("direct pin should be pinned:", st.Status)
+	}
+
+	changed, err := cl.SyncAllLocal(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Error("direct pin should not have changed after sync")
+	}
+
+	if st := cl.StatusLocal(ctx, c); st.Status != api.TrackerStatusPinned {
+		t.Error("direct pin should still be pinned:", st.Status)
+	}
+}
+
+func TestClusterPinDuplicate(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	c := test.Cid1
+	_, dup, err := cl.pinDedup(ctx, api.PinCid(c))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	if dup {
+		t.Error("first pin should not be a duplicate")
+	}
+
+	pinDelay()
+
+	pin, dup, err := cl.pinDedup(ctx, api.PinCid(c))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	if !dup {
+		t.Error("second identical pin should be a duplicate")
+	}
+	if !pin.Cid.Equals(c) {
+		t.Error("expected the existing pin")
+	}
+
+	// Different options should not be considered a duplicate.
+	opts := api.PinOptions{Name: "different"}
+	_, dup, err = cl.pinDedup(ctx, api.PinWithOpts(c, opts))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	if dup {
+		t.Error("pin with different options should not be a duplicate")
+	}
+}
+
+func TestClusterPinWithResponseRPC(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	pinWithResponse := func() (*api.PinResponse, error) {
+		var resp api.PinResponse
+		err := cl.rpcClient.CallContext(ctx, "", "Cluster", "PinWithResponse", api.PinCid(test.Cid1), &resp)
+		return &resp, err
+	}
+
+	// Identical submissions, concurrent or not, only pin once.
+	resps := make([]*api.PinResponse, 5)
+	errs := make([]error, len(resps))
+	var wg sync.WaitGroup
+	for i := range resps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resps[i], errs[i] = pinWithResponse()
+		}(i)
+	}
+	wg.Wait()
+
+	submitted := 0
+	for i, resp := range resps {
+		if errs[i] != nil {
+			t.Fatal("pin should have worked:", errs[i])
+		}
+		if !resp.Cid.Equals(test.Cid1) {
+			t.Error("expected the pin in the response")
+		}
+		if !resp.Duplicate {
+			submitted++
+		}
+	}
+	if submitted != 1 {
+		t.Errorf("expected a single submission, got %d", submitted)
+	}
+
+	pinDelay()
+
+	resp, err := pinWithResponse()
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	if !resp.Duplicate {
+		t.Error("pinning again should be a duplicate")
+	}
+
+	// Cluster.Pin keeps its reply type for peers of older versions.
+	err = cl.rpcClient.CallContext(ctx, "", "Cluster", "Pin", api.PinCid(test.Cid2), &struct{}{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+}
+
+func TestClusterPinPath(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	pin, err := cl.PinPath(ctx, &api.PinPath{Path: test.PathIPFS2})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	if !pin.Cid.Equals(test.CidResolved) {
+		t.Error("expected a different cid, found", pin.Cid.String())
+	}
+
+	// test an error case
+	_, err = cl.PinPath(ctx, &api.PinPath{Path: test.InvalidPath1})
+	if err == nil {
+		t.Error("expected an error but things worked")
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+	sth := test.NewShardingTestHelper()
+	defer sth.Clean(t)
+
+	t.Run("local", func(t *testing.T) {
+		params := api.DefaultAddParams()
+		params.Shard = false
+		params.Name = "testlocal"
+		mfr, closer := sth.GetTreeMultiReader(t)
+		defer closer.Close()
+		r := multipart.NewReader(mfr, mfr.Boundary())
+		c, err := cl.AddFile(r, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.String() != test.ShardingDirBalancedRootCID {
+			t.Fatal("unexpected root CID for local add")
+		}
+
+		pinDelay()
+
+		pin := cl.StatusLocal(ctx, c)
+		if pin.Error != "" {
+			t.Fatal(pin.Error)
+		}
+		if pin.Status != api.TrackerStatusPinned {
+			t.Error("cid should be pinned")
+		}
+
+		cl.Unpin(ctx, c) // unpin so we can pin the shard in next test
+		pinDelay()
+	})
+
+	t.Run("shard", func(t *testing.T) {
+		params := api.DefaultAddParams()
+		params.Shard = true
+		params.Name = "testshard"
+		mfr, closer := sth.GetTreeMultiReader(t)
+		defer closer.Close()
+		r := multipart.NewReader(mfr, mfr.Boundary())
+		c, err := cl.AddFile(r, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.String() != test.ShardingDirBalancedRootCID {
+			t.Fatal("unexpected root CID for local add")
+		}
+
+		pinDelay()
+
+		// We know that this produces 14 shards.
+		sharding.VerifyShards(t, c, cl, cl.ipfs, 14)
+	})
+}
+
+func TestShardedPinStatus(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+	sth := test.NewShardingTestHelper()
+	defer sth.Clean(t)
+
+	params := api.DefaultAddParams()
+	params.Shard = true
+	params.Name = "testshard"
+	mfr, closer := sth.GetTreeMultiReader(t)
+	defer closer.Close()
+	r := multipart.NewReader(mfr, mfr.Boundary())
+	root, err := cl.AddFile(r, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinDelay()
+
+	gpi, err := cl.Status(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpi.Shards == nil {
+		t.Fatal("expected the status of the shards")
+	}
+	// We know that this produces 14 shards.
+	if gpi.Shards.Total != 14 || gpi.Shards.Pinned != 14 || len(gpi.Shards.Missing) != 0 {
+		t.Errorf("unexpected shards status: %+v", gpi.Shards)
+	}
+	for _, s := range gpi.Shards.Shards {
+		if len(s.Pinned) != 1 || s.Pinned[0] != cl.id {
+			t.Errorf("expected shard %s to be pinned by this peer", s.Cid)
+		}
+	}
+
+	// Regular pins carry no shards.
+	err = cl.Pin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	gpi, err = cl.Status(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpi.Shards != nil {
+		t.Error("regular pins should not have shards")
+	}
+}
+
+func TestUnpinShard(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+	sth := test.NewShardingTestHelper()
+	defer sth.Clean(t)
+
+	params := api.DefaultAddParams()
+	params.Shard = true
+	params.Name = "testshard"
+	mfr, closer := sth.GetTreeMultiReader(t)
+	defer closer.Close()
+	r := multipart.NewReader(mfr, mfr.Boundary())
+	root, err := cl.AddFile(r, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinDelay()
+
+	// We know that this produces 14 shards.
+	sharding.VerifyShards(t, root, cl, cl.ipfs, 14)
+
+	// skipping errors, VerifyShards has checked
+	pinnedCids := []cid.Cid{}
+	pinnedCids = append(pinnedCids, root)
+	metaPin, _ := cl.PinGet(ctx, root)
+	cDag, _ := cl.PinGet(ctx, *metaPin.Reference)
+	pinnedCids = append(pinnedCids, cDag.Cid)
+	cDagBlock, _ := cl.ipfs.BlockGet(ctx, cDag.Cid)
+	cDagNode, _ := sharding.CborDataToNode(cDagBlock, "cbor")
+	for _, l := range cDagNode.Links() {
+		pinnedCids = append(pinnedCids, l.Cid)
 	}
 
 	t.Run("unpin clusterdag should fail", func(t *testing.T) {
@@ -501,411 +1689,1195 @@ func TestUnpinShard(t *testing.T) {
 // 	}
 // 	err = cl.Pin(metaPin)
 // 	if err != nil {
-// 		t.Fatal("pin should have worked:", err)
+// 		t.Fatal("pin should have worked:", err)
+// 	}
+// }
+
+// func TestClusterPinMeta(t *testing.T) {
+// 	cl, _, _, _ := testingCluster(t)
+// 	defer cleanState()
+// 	defer cl.Shutdown()
+
+// 	singleShardedPin(t, cl)
+// }
+
+// func TestClusterUnpinShardFail(t *testing.T) {
+// 	cl, _, _, _ := testingCluster(t)
+// 	defer cleanState()
+// 	defer cl.Shutdown()
+
+// 	singleShardedPin(t, cl)
+// 	// verify pins
+// 	if len(cl.Pins()) != 3 {
+// 		t.Fatal("should have 3 pins")
+// 	}
+// 	// Unpinning metadata should fail
+// 	cShard, _ := cid.Decode(test.ShardCid)
+// 	cCdag, _ := cid.Decode(test.CdagCid)
+
+// 	err := cl.Unpin(cShard)
+// 	if err == nil {
+// 		t.Error("should error when unpinning shard")
+// 	}
+// 	err = cl.Unpin(cCdag)
+// 	if err == nil {
+// 		t.Error("should error when unpinning cluster dag")
+// 	}
+// }
+
+// func TestClusterUnpinMeta(t *testing.T) {
+// 	cl, _, _, _ := testingCluster(t)
+// 	defer cleanState()
+// 	defer cl.Shutdown()
+
+// 	singleShardedPin(t, cl)
+// 	// verify pins
+// 	if len(cl.Pins()) != 3 {
+// 		t.Fatal("should have 3 pins")
+// 	}
+// 	// Unpinning from root should work
+// 	cMeta, _ := cid.Decode(test.MetaRootCid)
+
+// 	err := cl.Unpin(cMeta)
+// 	if err != nil {
+// 		t.Error(err)
+// 	}
+// }
+
+// func pinTwoParentsOneShard(t *testing.T, cl *Cluster) {
+// 	singleShardedPin(t, cl)
+
+// 	cShard, _ := cid.Decode(test.ShardCid)
+// 	cShard2, _ := cid.Decode(test.ShardCid2)
+// 	cCdag2, _ := cid.Decode(test.CdagCid2)
+// 	cMeta2, _ := cid.Decode(test.MetaRootCid2)
+// 	pinMeta(t, cl, []cid.Cid{cShard, cShard2}, cCdag2, cMeta2)
+
+// 	shardPin, err := cl.PinGet(cShard)
+// 	if err != nil {
+// 		t.Fatal("pin should be in state")
+// 	}
+// 	if shardPin.Parents.Len() != 2 {
+// 		t.Fatal("unexpected parent set in shared shard")
+// 	}
+
+// 	shardPin2, err := cl.PinGet(cShard2)
+// 	if shardPin2.Parents.Len() != 1 {
+// 		t.Fatal("unexpected parent set in unshared shard")
+// 	}
+// 	if err != nil {
+// 		t.Fatal("pin should be in state")
+// 	}
+// }
+
+// func TestClusterPinShardTwoParents(t *testing.T) {
+// 	cl, _, _, _ := testingCluster(t)
+// 	defer cleanState()
+// 	defer cl.Shutdown()
+
+// 	pinTwoParentsOneShard(t, cl)
+
+// 	cShard, _ := cid.Decode(test.ShardCid)
+// 	shardPin, err := cl.PinGet(cShard)
+// 	if err != nil {
+// 		t.Fatal("double pinned shard should be pinned")
+// 	}
+// 	if shardPin.Parents == nil || shardPin.Parents.Len() != 2 {
+// 		t.Fatal("double pinned shard should have two parents")
+// 	}
+// }
+
+// func TestClusterUnpinShardSecondParent(t *testing.T) {
+// 	cl, _, _, _ := testingCluster(t)
+// 	defer cleanState()
+// 	defer cl.Shutdown()
+
+// 	pinTwoParentsOneShard(t, cl)
+// 	if len(cl.Pins()) != 6 {
+// 		t.Fatal("should have 6 pins")
+// 	}
+// 	cMeta2, _ := cid.Decode(test.MetaRootCid2)
+// 	err := cl.Unpin(cMeta2)
+// 	if err != nil {
+// 		t.Error(err)
+// 	}
+
+// 	pinDelay()
+
+// 	if len(cl.Pins()) != 3 {
+// 		t.Fatal("should have 3 pins")
+// 	}
+
+// 	cShard, _ := cid.Decode(test.ShardCid)
+// 	cCdag, _ := cid.Decode(test.CdagCid)
+// 	shardPin, err := cl.PinGet(cShard)
+// 	if err != nil {
+// 		t.Fatal("double pinned shard node should still be pinned")
+// 	}
+// 	if shardPin.Parents == nil || shardPin.Parents.Len() != 1 ||
+// 		!shardPin.Parents.Has(cCdag) {
+// 		t.Fatalf("shard node should have single original parent %v", shardPin.Parents.Keys())
+// 	}
+// }
+
+// func TestClusterUnpinShardFirstParent(t *testing.T) {
+// 	cl, _, _, _ := testingCluster(t)
+// 	defer cleanState()
+// 	defer cl.Shutdown()
+
+// 	pinTwoParentsOneShard(t, cl)
+// 	if len(cl.Pins()) != 6 {
+// 		t.Fatal("should have 6 pins")
+// 	}
+
+// 	cMeta, _ := cid.Decode(test.MetaRootCid)
+// 	err := cl.Unpin(cMeta)
+// 	if err != nil {
+// 		t.Error(err)
+// 	}
+// 	if len(cl.Pins()) != 4 {
+// 		t.Fatal("should have 4 pins")
+// 	}
+
+// 	cShard, _ := cid.Decode(test.ShardCid)
+// 	cShard2, _ := cid.Decode(test.ShardCid2)
+// 	cCdag2, _ := cid.Decode(test.CdagCid2)
+// 	shardPin, err := cl.PinGet(cShard)
+// 	if err != nil {
+// 		t.Fatal("double pinned shard node should still be pinned")
+// 	}
+// 	if shardPin.Parents == nil || shardPin.Parents.Len() != 1 ||
+// 		!shardPin.Parents.Has(cCdag2) {
+// 		t.Fatal("shard node should have single original parent")
+// 	}
+// 	_, err = cl.PinGet(cShard2)
+// 	if err != nil {
+// 		t.Fatal("other shard shoud still be pinned too")
+// 	}
+// }
+
+// func TestClusterPinTwoMethodsFail(t *testing.T) {
+// 	cl, _, _, _ := testingCluster(t)
+// 	defer cleanState()
+// 	defer cl.Shutdown()
+
+// 	// First pin normally then sharding pin fails
+// 	c, _ := cid.Decode(test.MetaRootCid)
+// 	err := cl.Pin(api.PinCid(c))
+// 	if err != nil {
+// 		t.Fatal("pin should have worked:", err)
+// 	}
+
+// 	cCdag, _ := cid.Decode(test.CdagCid)
+// 	cMeta, _ := cid.Decode(test.MetaRootCid)
+// 	metaPin := api.Pin{
+// 		Cid:        cMeta,
+// 		Type:       api.MetaType,
+// 		Clusterdag: cCdag,
+// 	}
+// 	err = cl.Pin(metaPin)
+// 	if err == nil {
+// 		t.Fatal("pin should have failed:", err)
+// 	}
+
+// 	err = cl.Unpin(c)
+// 	if err != nil {
+// 		t.Fatal("unpin should have worked:", err)
+// 	}
+
+// 	singleShardedPin(t, cl)
+// 	err = cl.Pin(api.PinCid(c))
+// 	if err == nil {
+// 		t.Fatal("pin should have failed:", err)
 // 	}
 // }
 
-// func TestClusterPinMeta(t *testing.T) {
+// func TestClusterRePinShard(t *testing.T) {
 // 	cl, _, _, _ := testingCluster(t)
 // 	defer cleanState()
 // 	defer cl.Shutdown()
 
-// 	singleShardedPin(t, cl)
+// 	cCdag, _ := cid.Decode(test.CdagCid)
+// 	cShard, _ := cid.Decode(test.ShardCid)
+// 	shardPin := api.Pin{
+// 		Cid:                  cShard,
+// 		Type:                 api.ShardType,
+// 		ReplicationFactorMin: -1,
+// 		ReplicationFactorMax: -1,
+// 		Recursive:            true,
+// 	}
+// 	err := cl.Pin(shardPin)
+// 	if err != nil {
+// 		t.Fatal("shard pin should have worked:", err)
+// 	}
+
+// 	parents := cid.NewSet()
+// 	parents.Add(cCdag)
+// 	shardPin.Parents = parents
+// 	err = cl.Pin(shardPin)
+// 	if err != nil {
+// 		t.Fatal("repinning shard pin with different parents should have worked:", err)
+// 	}
+
+// 	shardPin.ReplicationFactorMin = 3
+// 	shardPin.ReplicationFactorMax = 5
+// 	err = cl.Pin(shardPin)
+// 	if err == nil {
+// 		t.Fatal("repinning shard pin with different repl factors should have failed:", err)
+// 	}
 // }
 
-// func TestClusterUnpinShardFail(t *testing.T) {
-// 	cl, _, _, _ := testingCluster(t)
-// 	defer cleanState()
-// 	defer cl.Shutdown()
+func TestClusterPins(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	c := test.Cid1
+	err := cl.Pin(ctx, api.PinCid(c))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	pinDelay()
+
+	pins, err := cl.Pins(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 1 {
+		t.Fatal("pin should be part of the state")
+	}
+	if !pins[0].Cid.Equals(c) || pins[0].ReplicationFactorMin != -1 || pins[0].ReplicationFactorMax != -1 {
+		t.Error("the Pin does not look as expected")
+	}
+}
+
+func TestClusterPinGet(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	c := test.Cid1
+	err := cl.Pin(ctx, api.PinCid(c))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	pin, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pin.Cid.Equals(c) || pin.ReplicationFactorMin != -1 || pin.ReplicationFactorMax != -1 {
+		t.Error("the Pin does not look as expected")
+	}
+
+	_, err = cl.PinGet(ctx, test.Cid2)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestClusterUnpin(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	c := test.Cid1
+	// Unpin should error without pin being committed to state
+	err := cl.Unpin(ctx, c)
+	if err == nil {
+		t.Error("unpin should have failed")
+	}
+
+	// Unpin after pin should succeed
+	err = cl.Pin(ctx, api.PinCid(c))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Error("unpin should have worked:", err)
+	}
+
+	// test another error case
+	cl.consensus.Shutdown(ctx)
+	err = cl.Unpin(ctx, c)
+	if err == nil {
+		t.Error("expected an error but things worked")
+	}
+}
+
+func TestClusterUnpinRetention(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	cl.config.UnpinRetention = time.Hour
+	c := test.Cid1
+
+	_, err := cl.RestorePin(ctx, c)
+	if err == nil {
+		t.Error("restoring a non existing pin should have failed")
+	}
+
+	err = cl.Pin(ctx, api.PinCid(c))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	_, err = cl.RestorePin(ctx, c)
+	if err == nil {
+		t.Error("restoring a pin not scheduled for removal should have failed")
+	}
+
+	err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	pin, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal("pin should be retained:", err)
+	}
+	if !pin.IsScheduledForRemoval() {
+		t.Error("pin should be scheduled for removal")
+	}
+
+	pin, err = cl.RestorePin(ctx, c)
+	if err != nil {
+		t.Fatal("restore should have worked:", err)
+	}
+	if pin.IsScheduledForRemoval() {
+		t.Error("restored pin should not be scheduled for removal")
+	}
+	pin, err = cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.IsScheduledForRemoval() {
+		t.Error("pin in the state should not be scheduled for removal")
+	}
+
+	// Schedule again and let the retention period expire.
+	cl.config.UnpinRetention = time.Millisecond
+	err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	err = cl.StateSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cl.PinGet(ctx, c)
+	if err == nil {
+		t.Error("pin should have been removed after the retention period")
+	}
+
+	// Unpinning a scheduled pin removes it right away.
+	cl.config.UnpinRetention = time.Hour
+	err = cl.Pin(ctx, api.PinCid(c))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	_, err = cl.PinGet(ctx, c)
+	if err == nil {
+		t.Error("pin should have been removed")
+	}
+}
+
+func TestClusterJoinToken(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	_, err := cl.JoinToken(ctx, 0)
+	if err == nil {
+		t.Error("expected an error with a zero ttl")
+	}
+
+	token, err := cl.JoinToken(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ParseJoinToken(token.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Peer != cl.id {
+		t.Error("the token should be signed by the cluster peer")
+	}
+	if !bytes.Equal(info.Secret, cl.config.Secret) {
+		t.Error("the token should carry the cluster secret")
+	}
+	if len(info.Addrs) == 0 {
+		t.Error("the token should carry the peer addresses")
+	}
+	if !info.Expires.Equal(token.Expires) {
+		t.Error("unexpected expiration date")
+	}
+
+	_, err = ParseJoinToken(token.Token[:len(token.Token)-4] + "AAAA")
+	if err == nil {
+		t.Error("expected an error parsing a tampered token")
+	}
+
+	token, err = cl.JoinToken(ctx, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	_, err = ParseJoinToken(token.Token)
+	if err == nil {
+		t.Error("expected an error parsing an expired token")
+	}
+}
+
+func TestClusterUnpinPath(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	// Unpin should error without pin being committed to state
+	_, err := cl.UnpinPath(ctx, test.PathIPFS2)
+	if err == nil {
+		t.Error("unpin with path should have failed")
+	}
+
+	// Unpin after pin should succeed
+	pin, err := cl.PinPath(ctx, &api.PinPath{Path: test.PathIPFS2})
+	if err != nil {
+		t.Fatal("pin with should have worked:", err)
+	}
+	if !pin.Cid.Equals(test.CidResolved) {
+		t.Error("expected a different cid, found", pin.Cid.String())
+	}
+
+	pin, err = cl.UnpinPath(ctx, test.PathIPFS2)
+	if err != nil {
+		t.Error("unpin with path should have worked:", err)
+	}
+	if !pin.Cid.Equals(test.CidResolved) {
+		t.Error("expected a different cid, found", pin.Cid.String())
+	}
+}
+
+func TestClusterBulkUnpinGuard(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	cl.config.BulkUnpinThreshold = 10
+	cl.config.BulkUnpinThresholdPercent = 50
+	cl.config.BulkUnpinConfirmation = true
+
+	err := cl.checkBulkUnpin(ctx, "cleanup", 5, 100, false)
+	if err != nil {
+		t.Error("unpins under both limits should be allowed:", err)
+	}
+
+	err = cl.checkBulkUnpin(ctx, "cleanup", 3, 4, false)
+	buErr, ok := api.AsBulkUnpinError(err)
+	if !ok || buErr.Limit != 2 || buErr.Token != "" {
+		t.Errorf("expected a bulk unpin error with a limit of 2: %v", err)
+	}
 
-// 	singleShardedPin(t, cl)
-// 	// verify pins
-// 	if len(cl.Pins()) != 3 {
-// 		t.Fatal("should have 3 pins")
-// 	}
-// 	// Unpinning metadata should fail
-// 	cShard, _ := cid.Decode(test.ShardCid)
-// 	cCdag, _ := cid.Decode(test.CdagCid)
+	err = cl.checkBulkUnpin(ctx, "cleanup", 3, 4, true)
+	if err != nil {
+		t.Error("forced unpins should be allowed:", err)
+	}
 
-// 	err := cl.Unpin(cShard)
-// 	if err == nil {
-// 		t.Error("should error when unpinning shard")
-// 	}
-// 	err = cl.Unpin(cCdag)
-// 	if err == nil {
-// 		t.Error("should error when unpinning cluster dag")
-// 	}
-// }
+	request := func(op string, confirm string) (string, error) {
+		opts := api.BulkUnpinOptions{Force: true, Confirm: confirm}
+		err := cl.checkBulkUnpinRequest(ctx, op, 20, 100, opts)
+		if buErr, ok := api.AsBulkUnpinError(err); ok {
+			return buErr.Token, err
+		}
+		return "", err
+	}
 
-// func TestClusterUnpinMeta(t *testing.T) {
-// 	cl, _, _, _ := testingCluster(t)
-// 	defer cleanState()
-// 	defer cl.Shutdown()
+	token, err := request("restore", "")
+	if err == nil || token == "" {
+		t.Fatal("forced requests should return a confirmation token")
+	}
+	_, err = request("restore", token)
+	if err != nil {
+		t.Error("confirmed requests should be allowed:", err)
+	}
+	_, err = request("restore", token)
+	if err == nil {
+		t.Error("tokens should only be valid once")
+	}
 
-// 	singleShardedPin(t, cl)
-// 	// verify pins
-// 	if len(cl.Pins()) != 3 {
-// 		t.Fatal("should have 3 pins")
-// 	}
-// 	// Unpinning from root should work
-// 	cMeta, _ := cid.Decode(test.MetaRootCid)
+	token, _ = request("restore", "")
+	_, err = request("delete", token)
+	if err == nil {
+		t.Error("tokens should only be valid for the same operation")
+	}
 
-// 	err := cl.Unpin(cMeta)
-// 	if err != nil {
-// 		t.Error(err)
-// 	}
-// }
+	cl.config.BulkUnpinConfirmTimeout = time.Millisecond
+	token, _ = request("restore", "")
+	time.Sleep(10 * time.Millisecond)
+	_, err = request("restore", token)
+	if err == nil {
+		t.Error("expired tokens should not be valid")
+	}
 
-// func pinTwoParentsOneShard(t *testing.T, cl *Cluster) {
-// 	singleShardedPin(t, cl)
+	cl.config.BulkUnpinConfirmation = false
+	_, err = request("restore", "")
+	if err != nil {
+		t.Error("forced requests need no confirmation when disabled:", err)
+	}
 
-// 	cShard, _ := cid.Decode(test.ShardCid)
-// 	cShard2, _ := cid.Decode(test.ShardCid2)
-// 	cCdag2, _ := cid.Decode(test.CdagCid2)
-// 	cMeta2, _ := cid.Decode(test.MetaRootCid2)
-// 	pinMeta(t, cl, []cid.Cid{cShard, cShard2}, cCdag2, cMeta2)
+	recs, err := cl.AuditLog(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcomes := []string{
+		api.AuditRefused,
+		api.AuditForced,
+		api.AuditPending,
+		api.AuditConfirmed,
+	}
+	if len(recs) < len(outcomes) {
+		t.Fatalf("expected at least %d audit records", len(outcomes))
+	}
+	for i, o := range outcomes {
+		if recs[i].Outcome != o || recs[i].Peer != cl.id {
+			t.Errorf("unexpected audit record %d: %+v", i, recs[i])
+		}
+	}
+	if last := recs[len(recs)-1]; last.Outcome != api.AuditForced || last.Operation != "restore" {
+		t.Errorf("unexpected last audit record: %+v", last)
+	}
+}
 
-// 	shardPin, err := cl.PinGet(cShard)
-// 	if err != nil {
-// 		t.Fatal("pin should be in state")
-// 	}
-// 	if shardPin.Parents.Len() != 2 {
-// 		t.Fatal("unexpected parent set in shared shard")
-// 	}
+func TestClusterAuditLog(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
 
-// 	shardPin2, err := cl.PinGet(cShard2)
-// 	if shardPin2.Parents.Len() != 1 {
-// 		t.Fatal("unexpected parent set in unshared shard")
-// 	}
-// 	if err != nil {
-// 		t.Fatal("pin should be in state")
-// 	}
-// }
+	defer func(max int) { auditLogMaxRecords = max }(auditLogMaxRecords)
+	auditLogMaxRecords = 3
 
-// func TestClusterPinShardTwoParents(t *testing.T) {
-// 	cl, _, _, _ := testingCluster(t)
-// 	defer cleanState()
-// 	defer cl.Shutdown()
+	for i := 0; i < 5; i++ {
+		cl.audit(ctx, fmt.Sprintf("op%d", i), api.AuditCompleted, "")
+	}
 
-// 	pinTwoParentsOneShard(t, cl)
+	recs, err := cl.AuditLog(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("expected 3 audit records, got %d", len(recs))
+	}
+	for i, rec := range recs {
+		if rec.Operation != fmt.Sprintf("op%d", i+2) {
+			t.Errorf("unexpected audit record %d: %+v", i, rec)
+		}
+	}
+}
 
-// 	cShard, _ := cid.Decode(test.ShardCid)
-// 	shardPin, err := cl.PinGet(cShard)
-// 	if err != nil {
-// 		t.Fatal("double pinned shard should be pinned")
-// 	}
-// 	if shardPin.Parents == nil || shardPin.Parents.Len() != 2 {
-// 		t.Fatal("double pinned shard should have two parents")
-// 	}
-// }
+func TestClusterPeers(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+	peers := cl.Peers(ctx)
+	if len(peers) != 1 {
+		t.Fatal("expected 1 peer")
+	}
 
-// func TestClusterUnpinShardSecondParent(t *testing.T) {
-// 	cl, _, _, _ := testingCluster(t)
-// 	defer cleanState()
-// 	defer cl.Shutdown()
+	ident := &config.Identity{}
+	err := ident.LoadJSON(testingIdentity)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-// 	pinTwoParentsOneShard(t, cl)
-// 	if len(cl.Pins()) != 6 {
-// 		t.Fatal("should have 6 pins")
-// 	}
-// 	cMeta2, _ := cid.Decode(test.MetaRootCid2)
-// 	err := cl.Unpin(cMeta2)
-// 	if err != nil {
-// 		t.Error(err)
-// 	}
+	if peers[0].ID != ident.ID {
+		fmt.Println(peers[0].ID)
+		fmt.Println(ident.ID)
+		t.Error("bad member")
+	}
+}
 
-// 	pinDelay()
+func TestVersion(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+	if cl.Version() != version.Version.String() {
+		t.Error("bad Version()")
+	}
+}
 
-// 	if len(cl.Pins()) != 3 {
-// 		t.Fatal("should have 3 pins")
-// 	}
+func TestClusterRuntimeStats(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
 
-// 	cShard, _ := cid.Decode(test.ShardCid)
-// 	cCdag, _ := cid.Decode(test.CdagCid)
-// 	shardPin, err := cl.PinGet(cShard)
-// 	if err != nil {
-// 		t.Fatal("double pinned shard node should still be pinned")
-// 	}
-// 	if shardPin.Parents == nil || shardPin.Parents.Len() != 1 ||
-// 		!shardPin.Parents.Has(cCdag) {
-// 		t.Fatalf("shard node should have single original parent %v", shardPin.Parents.Keys())
-// 	}
-// }
+	rs := cl.RuntimeStatsLocal(ctx)
+	if rs.Peer != cl.id || rs.Goroutines == 0 || rs.HeapAlloc == 0 {
+		t.Errorf("unexpected runtime stats: %+v", rs)
+	}
+}
 
-// func TestClusterUnpinShardFirstParent(t *testing.T) {
-// 	cl, _, _, _ := testingCluster(t)
-// 	defer cleanState()
-// 	defer cl.Shutdown()
+func TestClusterInfo(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
 
-// 	pinTwoParentsOneShard(t, cl)
-// 	if len(cl.Pins()) != 6 {
-// 		t.Fatal("should have 6 pins")
-// 	}
+	err := cl.Pin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
 
-// 	cMeta, _ := cid.Decode(test.MetaRootCid)
-// 	err := cl.Unpin(cMeta)
-// 	if err != nil {
-// 		t.Error(err)
-// 	}
-// 	if len(cl.Pins()) != 4 {
-// 		t.Fatal("should have 4 pins")
-// 	}
+	info, err := cl.ClusterInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Peers != 1 || info.UnreachablePeers != 0 || info.Pins != 1 {
+		t.Errorf("unexpected cluster info: %+v", info)
+	}
+	if info.FreeSpace != 900 || info.OldestVersion != version.Version.String() {
+		t.Errorf("unexpected cluster info: %+v", info)
+	}
+	if info.Consensus != consensus {
+		t.Errorf("expected %s consensus, got %s", consensus, info.Consensus)
+	}
 
-// 	cShard, _ := cid.Decode(test.ShardCid)
-// 	cShard2, _ := cid.Decode(test.ShardCid2)
-// 	cCdag2, _ := cid.Decode(test.CdagCid2)
-// 	shardPin, err := cl.PinGet(cShard)
-// 	if err != nil {
-// 		t.Fatal("double pinned shard node should still be pinned")
-// 	}
-// 	if shardPin.Parents == nil || shardPin.Parents.Len() != 1 ||
-// 		!shardPin.Parents.Has(cCdag2) {
-// 		t.Fatal("shard node should have single original parent")
-// 	}
-// 	_, err = cl.PinGet(cShard2)
-// 	if err != nil {
-// 		t.Fatal("other shard shoud still be pinned too")
-// 	}
-// }
+	// Cached facts are returned until they expire.
+	err = cl.Pin(ctx, api.PinCid(test.Cid2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	cached, err := cl.ClusterInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Pins != 1 || !cached.UpdatedAt.Equal(info.UpdatedAt) {
+		t.Error("expected the cached cluster info")
+	}
+}
 
-// func TestClusterPinTwoMethodsFail(t *testing.T) {
-// 	cl, _, _, _ := testingCluster(t)
-// 	defer cleanState()
-// 	defer cl.Shutdown()
+func TestIPFSDrift(t *testing.T) {
+	daemon := func(name, version, routing string) *api.IPFSDaemonInfo {
+		return &api.IPFSDaemonInfo{
+			Peername:   name,
+			Version:    version,
+			Routing:    routing,
+			StorageMax: "10G",
+		}
+	}
 
-// 	// First pin normally then sharding pin fails
-// 	c, _ := cid.Decode(test.MetaRootCid)
-// 	err := cl.Pin(api.PinCid(c))
-// 	if err != nil {
-// 		t.Fatal("pin should have worked:", err)
-// 	}
+	drift := ipfsDrift([]*api.IPFSDaemonInfo{
+		daemon("a", "0.5.0", "dht"),
+		daemon("b", "0.5.0", "dht"),
+		{Peername: "c", Error: "unreachable"},
+	})
+	if len(drift.Warnings) != 0 || drift.Dangerous {
+		t.Errorf("expected no drift: %+v", drift)
+	}
 
-// 	cCdag, _ := cid.Decode(test.CdagCid)
-// 	cMeta, _ := cid.Decode(test.MetaRootCid)
-// 	metaPin := api.Pin{
-// 		Cid:        cMeta,
-// 		Type:       api.MetaType,
-// 		Clusterdag: cCdag,
-// 	}
-// 	err = cl.Pin(metaPin)
-// 	if err == nil {
-// 		t.Fatal("pin should have failed:", err)
-// 	}
+	drift = ipfsDrift([]*api.IPFSDaemonInfo{
+		daemon("a", "0.5.0", "dht"),
+		daemon("b", "0.5.1", "dht"),
+	})
+	if len(drift.Warnings) != 1 || drift.Dangerous {
+		t.Errorf("patch versions should only cause a warning: %+v", drift)
+	}
 
-// 	err = cl.Unpin(c)
-// 	if err != nil {
-// 		t.Fatal("unpin should have worked:", err)
-// 	}
+	drift = ipfsDrift([]*api.IPFSDaemonInfo{
+		daemon("a", "0.5.0", "dht"),
+		daemon("b", "0.4.23", "dht"),
+	})
+	if len(drift.Warnings) != 1 || !drift.Dangerous {
+		t.Errorf("minor versions should be dangerous: %+v", drift)
+	}
+	if drift.Warnings[0] != "peers run different IPFS versions: 0.4.23 (b), 0.5.0 (a)" {
+		t.Errorf("unexpected warning: %s", drift.Warnings[0])
+	}
 
-// 	singleShardedPin(t, cl)
-// 	err = cl.Pin(api.PinCid(c))
-// 	if err == nil {
-// 		t.Fatal("pin should have failed:", err)
-// 	}
-// }
+	drift = ipfsDrift([]*api.IPFSDaemonInfo{
+		daemon("a", "0.5.0", "dht"),
+		daemon("b", "0.5.0", "none"),
+	})
+	if len(drift.Warnings) != 1 || !drift.Dangerous {
+		t.Errorf("routing types should be dangerous: %+v", drift)
+	}
+}
 
-// func TestClusterRePinShard(t *testing.T) {
-// 	cl, _, _, _ := testingCluster(t)
-// 	defer cleanState()
-// 	defer cl.Shutdown()
+func TestClusterIPFSDrift(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
 
-// 	cCdag, _ := cid.Decode(test.CdagCid)
-// 	cShard, _ := cid.Decode(test.ShardCid)
-// 	shardPin := api.Pin{
-// 		Cid:                  cShard,
-// 		Type:                 api.ShardType,
-// 		ReplicationFactorMin: -1,
-// 		ReplicationFactorMax: -1,
-// 		Recursive:            true,
-// 	}
-// 	err := cl.Pin(shardPin)
-// 	if err != nil {
-// 		t.Fatal("shard pin should have worked:", err)
-// 	}
+	drift, err := cl.IPFSDrift(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drift.Daemons) != 1 || len(drift.Warnings) != 0 {
+		t.Fatalf("unexpected drift report: %+v", drift)
+	}
+	d := drift.Daemons[0]
+	if d.Peer != cl.id || d.Version != "0.4.22" || d.Routing != "dht" {
+		t.Errorf("unexpected daemon info: %+v", d)
+	}
+}
 
-// 	parents := cid.NewSet()
-// 	parents.Add(cCdag)
-// 	shardPin.Parents = parents
-// 	err = cl.Pin(shardPin)
-// 	if err != nil {
-// 		t.Fatal("repinning shard pin with different parents should have worked:", err)
-// 	}
+func TestClusterVersions(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
 
-// 	shardPin.ReplicationFactorMin = 3
-// 	shardPin.ReplicationFactorMax = 5
-// 	err = cl.Pin(shardPin)
-// 	if err == nil {
-// 		t.Fatal("repinning shard pin with different repl factors should have failed:", err)
-// 	}
-// }
+	versions := cl.Versions(ctx)
+	if len(versions) != 1 {
+		t.Fatal("expected 1 peer")
+	}
+	v := versions[0]
+	if !v.Compatible || v.Version != version.Version.String() ||
+		v.StateVersion != version.StateVersion {
+		t.Errorf("unexpected version report: %+v", v)
+	}
 
-func TestClusterPins(t *testing.T) {
+	// The version of unreachable peers cannot be verified.
+	err := cl.checkPeerVersion(ctx, test.PeerID2)
+	if err != nil {
+		t.Error("incompatible peers should only cause a warning by default")
+	}
+	cl.config.RefuseIncompatiblePeers = true
+	err = cl.checkPeerVersion(ctx, test.PeerID2)
+	if err == nil {
+		t.Error("expected an error with refuse_incompatible_peers")
+	}
+	err = cl.checkPeerVersion(ctx, cl.id)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClusterRecoverAllLocal(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
 
-	c := test.Cid1
-	err := cl.Pin(ctx, api.PinCid(c))
+	err := cl.Pin(ctx, api.PinCid(test.Cid1))
 	if err != nil {
 		t.Fatal("pin should have worked:", err)
 	}
 
 	pinDelay()
 
-	pins, err := cl.Pins(ctx)
+	recov, err := cl.RecoverAllLocal(ctx)
 	if err != nil {
-		t.Fatal(err)
+		t.Error("did not expect an error")
 	}
-	if len(pins) != 1 {
-		t.Fatal("pin should be part of the state")
+	if len(recov) != 1 {
+		t.Fatalf("there should be only one pin, got = %d", len(recov))
 	}
-	if !pins[0].Cid.Equals(c) || pins[0].ReplicationFactorMin != -1 || pins[0].ReplicationFactorMax != -1 {
-		t.Error("the Pin does not look as expected")
+	if recov[0].Status != api.TrackerStatusPinned {
+		t.Errorf("the pin should have been recovered, got = %v", recov[0].Status)
 	}
 }
 
-func TestClusterPinGet(t *testing.T) {
+func TestClusterRecoveryJob(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
 
-	c := test.Cid1
-	err := cl.Pin(ctx, api.PinCid(c))
+	_, err := cl.RecoveryStatus(ctx)
+	if err == nil {
+		t.Error("expected an error before any job is started")
+	}
+
+	err = cl.Pin(ctx, api.PinCid(test.Cid1))
 	if err != nil {
-		t.Fatal("pin should have worked:", err)
+		t.Fatal(err)
 	}
+	pinDelay()
 
-	pin, err := cl.PinGet(ctx, c)
+	job, err := cl.RecoveryStart(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !pin.Cid.Equals(c) || pin.ReplicationFactorMin != -1 || pin.ReplicationFactorMax != -1 {
-		t.Error("the Pin does not look as expected")
+	if job.Peer != cl.id || job.State != api.RecoveryJobRunning {
+		t.Errorf("unexpected recovery job: %+v", job)
 	}
 
-	_, err = cl.PinGet(ctx, test.Cid2)
-	if err == nil {
-		t.Fatal("expected an error")
+	for i := 0; i < 10 && job.State == api.RecoveryJobRunning; i++ {
+		time.Sleep(500 * time.Millisecond)
+		job, err = cl.RecoveryStatus(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.State != api.RecoveryJobDone {
+		t.Fatalf("expected the job to be done: %+v", job)
+	}
+	// Nothing is in error state.
+	if job.Total != 0 || job.Failed != 0 || job.Finished.IsZero() {
+		t.Errorf("unexpected recovery job: %+v", job)
 	}
 }
 
-func TestClusterUnpin(t *testing.T) {
+func TestRecoveryState(t *testing.T) {
+	rs := &recoveryState{}
+	_, err := rs.start(test.PeerID1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rs.start(test.PeerID1)
+	if err != errRecoveryRunning {
+		t.Error("expected an error starting a second job")
+	}
+
+	rs.setTotal(2)
+	rs.progress(test.Cid1, nil)
+	rs.progress(test.Cid2, errors.New("pin error"))
+	rs.finish(nil)
+
+	job := rs.current()
+	if job.Recovered != 1 || job.Failed != 1 || len(job.Errors) != 1 || job.State != api.RecoveryJobDone {
+		t.Errorf("unexpected recovery job: %+v", job)
+	}
+	_, err = rs.start(test.PeerID1)
+	if err != nil {
+		t.Error("finished jobs should not stop new ones")
+	}
+}
+
+func TestClusterProvide(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
 
-	c := test.Cid1
-	// Unpin should error without pin being committed to state
-	err := cl.Unpin(ctx, c)
+	_, err := cl.Provide(ctx, test.Cid1)
 	if err == nil {
-		t.Error("unpin should have failed")
+		t.Error("expected an error providing an unpinned cid")
 	}
 
-	// Unpin after pin should succeed
-	err = cl.Pin(ctx, api.PinCid(c))
+	err = cl.Pin(ctx, api.PinCid(test.Cid1))
 	if err != nil {
 		t.Fatal("pin should have worked:", err)
 	}
-	err = cl.Unpin(ctx, c)
+
+	results, err := cl.Provide(ctx, test.Cid1)
 	if err != nil {
-		t.Error("unpin should have worked:", err)
+		t.Fatal(err)
 	}
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got = %d", len(results))
+	}
+	if results[0].Peer != cl.id || results[0].Error != "" {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+}
 
-	// test another error case
-	cl.consensus.Shutdown(ctx)
-	err = cl.Unpin(ctx, c)
-	if err == nil {
-		t.Error("expected an error but things worked")
+func TestClusterScaledReplication(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	cl.config.ReplicationScalingUpThreshold = 10
+	cl.config.ReplicationScalingDownThreshold = 1
+	cl.config.ReplicationScalingMax = 3
+
+	pin := api.PinCid(test.Cid1)
+	pin.ReplicationFactorMin = 1
+	pin.ReplicationFactorMax = 2
+
+	testcases := []struct {
+		name     string
+		meta     map[string]string
+		rplMin   int
+		rplMax   int
+		score    float64
+		expMin   int
+		expMax   int
+		expScale bool
+	}{
+		{"popular", nil, 1, 2, 15, 2, 3, true},
+		{"at bound", nil, 2, 3, 15, 2, 3, false},
+		{"steady", nil, 1, 2, 5, 1, 2, false},
+		{"unpopular not scaled", nil, 1, 2, 0, 1, 2, false},
+		{
+			"unpopular scaled",
+			map[string]string{scalingOrigMinMetaKey: "1", scalingOrigMaxMetaKey: "2"},
+			2, 3, 0, 1, 2, true,
+		},
+		{
+			"unpopular at origin",
+			map[string]string{scalingOrigMinMetaKey: "1", scalingOrigMaxMetaKey: "2"},
+			1, 2, 0, 1, 2, false,
+		},
+		{"everywhere", nil, -1, -1, 15, -1, -1, false},
+	}
+
+	for _, tc := range testcases {
+		pin.Metadata = tc.meta
+		pin.ReplicationFactorMin = tc.rplMin
+		pin.ReplicationFactorMax = tc.rplMax
+		rplMin, rplMax, scale := cl.scaledReplication(pin, tc.score)
+		if rplMin != tc.expMin || rplMax != tc.expMax || scale != tc.expScale {
+			t.Errorf("%s: got %d %d %t", tc.name, rplMin, rplMax, scale)
+		}
 	}
 }
 
-func TestClusterUnpinPath(t *testing.T) {
+func TestClusterScheduledPins(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
 
-	// Unpin should error without pin being committed to state
-	_, err := cl.UnpinPath(ctx, test.PathIPFS2)
+	c := test.Cid1
+	now := time.Now()
+	pin := api.PinCid(c)
+	pin.ScheduleAt = now.Add(time.Hour)
+	err := cl.Pin(ctx, pin)
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pin, err = cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pin.IsScheduled() || len(pin.Allocations) != 0 {
+		t.Error("pin should be deferred and unallocated")
+	}
+	if st := cl.tracker.Status(ctx, c); st.Status != api.TrackerStatusRemote {
+		t.Error("deferred pins should be tracked as remote, got", st.Status)
+	}
+
+	cl.runScheduledPins(ctx, now)
+	pin, err = cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pin.IsScheduled() {
+		t.Error("pin should not have run yet")
+	}
+
+	cl.runScheduledPins(ctx, now.Add(2*time.Hour))
+	pin, err = cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.IsScheduled() || pin.LastRun.IsZero() {
+		t.Error("pin should have run")
+	}
+	if pin.Provenance.Source != api.ProvenanceScheduler {
+		t.Error("unexpected provenance:", pin.Provenance.Source)
+	}
+
+	// Recurring pins need a path and a valid recurrence.
+	pin = api.PinCid(test.Cid2)
+	pin.Recurrence = "@hourly"
+	err = cl.Pin(ctx, pin)
 	if err == nil {
-		t.Error("unpin with path should have failed")
+		t.Error("recurring pins without a path should fail")
+	}
+	opts := api.PinOptions{Recurrence: "bad"}
+	_, err = cl.PinPath(ctx, &api.PinPath{Path: test.PathIPFS2, PinOptions: opts})
+	if err == nil {
+		t.Error("pinning with a bad recurrence should fail")
 	}
 
-	// Unpin after pin should succeed
-	pin, err := cl.PinPath(ctx, &api.PinPath{Path: test.PathIPFS2})
+	opts.Recurrence = "@hourly"
+	pin, err = cl.PinPath(ctx, &api.PinPath{Path: test.PathIPFS2, PinOptions: opts})
 	if err != nil {
-		t.Fatal("pin with should have worked:", err)
+		t.Fatal("pin should have worked:", err)
 	}
-	if !pin.Cid.Equals(test.CidResolved) {
-		t.Error("expected a different cid, found", pin.Cid.String())
+	if pin.RecurrencePath != test.PathIPFS2 {
+		t.Error("unexpected recurrence path:", pin.RecurrencePath)
 	}
 
-	pin, err = cl.UnpinPath(ctx, test.PathIPFS2)
+	cl.runScheduledPins(ctx, time.Now().Add(2*time.Hour))
+	pin, err = cl.PinGet(ctx, test.CidResolved)
 	if err != nil {
-		t.Error("unpin with path should have worked:", err)
+		t.Fatal(err)
 	}
-	if !pin.Cid.Equals(test.CidResolved) {
-		t.Error("expected a different cid, found", pin.Cid.String())
+	if pin.LastRun.IsZero() {
+		t.Error("recurrence should have run")
 	}
 }
 
-func TestClusterPeers(t *testing.T) {
+func TestClusterRepoGC(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
-	peers := cl.Peers(ctx)
-	if len(peers) != 1 {
-		t.Fatal("expected 1 peer")
-	}
 
-	ident := &config.Identity{}
-	err := ident.LoadJSON(testingIdentity)
+	cl.config.RepoGCGracePeriod = time.Hour
+	cl.config.RepoGCMaxWait = 0
+	err := cl.gcGuard.blockPut("", func() error { return nil })
 	if err != nil {
 		t.Fatal(err)
 	}
+	_, err = cl.RepoGCLocal(ctx)
+	if err == nil {
+		t.Error("garbage collection should be refused after adding blocks")
+	}
 
-	if peers[0].ID != ident.ID {
-		fmt.Println(peers[0].ID)
-		fmt.Println(ident.ID)
-		t.Error("bad member")
+	cl.config.RepoGCGracePeriod = time.Millisecond
+	time.Sleep(10 * time.Millisecond)
+	res, err := cl.RepoGCLocal(ctx)
+	if err != nil {
+		t.Fatal("garbage collection should have worked:", err)
 	}
+	if res.Peer != cl.id || res.Removed != 2 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	// Blocks are not added while a garbage collection runs.
+	cl.gcGuard.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		cl.gcGuard.blockPut("", func() error { return nil })
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Error("block put should wait for the garbage collection")
+	case <-time.After(50 * time.Millisecond):
+	}
+	cl.gcGuard.mu.Unlock()
+	<-done
 }
 
-func TestVersion(t *testing.T) {
+func TestClusterRepoGCAdds(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
-	if cl.Version() != version.Version.String() {
-		t.Error("bad Version()")
+
+	cl.config.RepoGCGracePeriod = 100 * time.Millisecond
+	cl.config.RepoGCMaxWait = 0
+	err := cl.gcGuard.blockPut("add1", func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if reason := cl.repoGCUnsafe(ctx); !strings.Contains(reason, "adds") {
+		t.Error("an add in progress should prevent garbage collections:", reason)
+	}
+
+	cl.gcGuard.addDone("add1")
+	time.Sleep(60 * time.Millisecond)
+	if reason := cl.repoGCUnsafe(ctx); reason != "" {
+		t.Error("garbage collection should be safe after the add finished:", reason)
+	}
+
+	// Adds which stop putting blocks are considered aborted.
+	cl.gcGuard.blockPut("add2", func() error { return nil })
+	time.Sleep(150 * time.Millisecond)
+	if reason := cl.repoGCUnsafe(ctx); reason != "" {
+		t.Error("aborted adds should not prevent garbage collections:", reason)
+	}
+	if len(cl.gcGuard.adds) != 0 {
+		t.Error("aborted adds should be forgotten")
 	}
 }
 
-func TestClusterRecoverAllLocal(t *testing.T) {
+func TestClusterRepoGCUntrackedPins(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer cl.Shutdown(ctx)
 
-	err := cl.Pin(ctx, api.PinCid(test.Cid1))
+	cl.config.RepoGCGracePeriod = time.Millisecond
+	cl.config.RepoGCMaxWait = 0
+
+	pin := api.PinCid(test.Cid1)
+	err := cl.Pin(ctx, pin)
 	if err != nil {
 		t.Fatal("pin should have worked:", err)
 	}
+	pinDelay()
+	if reason := cl.repoGCUnsafe(ctx); reason != "" {
+		t.Fatal("garbage collection should be safe once pinned:", reason)
+	}
+
+	// The pin is in the state and not queued by the tracker.
+	pin, err = cl.PinGet(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.tracker.Untrack(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	_, err = cl.RepoGCLocal(ctx)
+	if err == nil {
+		t.Error("garbage collection should be refused with pins not queued")
+	}
 
+	err = cl.tracker.Track(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
 	pinDelay()
+	_, err = cl.RepoGCLocal(ctx)
+	if err != nil {
+		t.Error("garbage collection should have worked:", err)
+	}
+}
 
-	recov, err := cl.RecoverAllLocal(ctx)
+func TestClusterPeerMigrate(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	pin := api.PinCid(test.Cid1)
+	pin.ReplicationFactorMin = 1
+	pin.ReplicationFactorMax = 1
+	err := cl.Pin(ctx, pin)
 	if err != nil {
-		t.Error("did not expect an error")
+		t.Fatal("pin should have worked:", err)
 	}
-	if len(recov) != 1 {
-		t.Fatalf("there should be only one pin, got = %d", len(recov))
+
+	mig, err := cl.PeerMigrate(ctx, test.PeerID2)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if recov[0].Status != api.TrackerStatusPinned {
-		t.Errorf("the pin should have been recovered, got = %v", recov[0].Status)
+	if len(mig.Pins) != 0 || len(mig.Errors) != 0 {
+		t.Error("no pins are allocated to PeerID2")
+	}
+
+	// There is no other peer to migrate to.
+	mig, err = cl.PeerMigrate(ctx, cl.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mig.Pins) != 0 || len(mig.Errors) != 1 {
+		t.Errorf("expected an error migrating the pin: %+v", mig)
 	}
 }
@@ -3,6 +3,7 @@ package ipfscluster
 import (
 	"context"
 	"encoding/hex"
+	"net"
 
 	"github.com/ipfs/ipfs-cluster/config"
 	libp2p "github.com/libp2p/go-libp2p"
@@ -13,6 +14,7 @@ import (
 	pnet "github.com/libp2p/go-libp2p-pnet"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	routedhost "github.com/libp2p/go-libp2p/p2p/host/routed"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 // NewClusterHost creates a libp2p Host with the options from the provided
@@ -25,18 +27,25 @@ func NewClusterHost(
 	cfg *Config,
 ) (host.Host, *pubsub.PubSub, *dht.IpfsDHT, error) {
 
+	opts := []libp2p.Option{
+		libp2p.ListenAddrs(append([]ma.Multiaddr{cfg.ListenAddr}, cfg.ExtraListenAddrs...)...),
+		libp2p.NATPortMap(),
+	}
+	if len(cfg.AnnounceAddrs) > 0 || len(cfg.NoAnnounceAddrs) > 0 || len(cfg.NoAnnounceCIDRs) > 0 {
+		opts = append(opts, libp2p.AddrsFactory(announceAddrsFactory(cfg)))
+	}
+
 	h, err := newHost(
 		ctx,
 		cfg.Secret,
 		ident.PrivateKey,
-		libp2p.ListenAddrs(cfg.ListenAddr),
-		libp2p.NATPortMap(),
+		opts...,
 	)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	psub, err := newPubSub(ctx, h)
+	psub, err := newPubSub(ctx, h, cfg)
 	if err != nil {
 		h.Close()
 		return nil, nil, nil, err
@@ -51,6 +60,48 @@ func NewClusterHost(
 	return routedHost(h, idht), psub, idht, nil
 }
 
+// announceAddrsFactory returns the addresses advertised by the cluster
+// host: the AnnounceAddrs, if any, or the listen addresses, leaving out
+// the NoAnnounceAddrs and the addresses in the NoAnnounceCIDRs.
+func announceAddrsFactory(cfg *Config) func([]ma.Multiaddr) []ma.Multiaddr {
+	return func(addrs []ma.Multiaddr) []ma.Multiaddr {
+		if len(cfg.AnnounceAddrs) > 0 {
+			addrs = cfg.AnnounceAddrs
+		}
+
+		var out []ma.Multiaddr
+		for _, addr := range addrs {
+			if !noAnnounce(cfg, addr) {
+				out = append(out, addr)
+			}
+		}
+		return out
+	}
+}
+
+func noAnnounce(cfg *Config, addr ma.Multiaddr) bool {
+	for _, a := range cfg.NoAnnounceAddrs {
+		if a.Equal(addr) {
+			return true
+		}
+	}
+
+	ipStr, err := addr.ValueForProtocol(ma.P_IP4)
+	if err != nil {
+		ipStr, err = addr.ValueForProtocol(ma.P_IP6)
+	}
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(ipStr)
+	for _, n := range cfg.NoAnnounceCIDRs {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func newHost(ctx context.Context, secret []byte, priv crypto.PrivKey, opts ...libp2p.Option) (host.Host, error) {
 	var prot ipnet.Protector
 	var err error
@@ -81,13 +132,15 @@ func newDHT(ctx context.Context, h host.Host) (*dht.IpfsDHT, error) {
 	return dht.New(ctx, h)
 }
 
-func newPubSub(ctx context.Context, h host.Host) (*pubsub.PubSub, error) {
-	return pubsub.NewGossipSub(
-		ctx,
-		h,
-		pubsub.WithMessageSigning(true),
-		pubsub.WithStrictSignatureVerification(true),
-	)
+func newPubSub(ctx context.Context, h host.Host, cfg *Config) (*pubsub.PubSub, error) {
+	opts := []pubsub.Option{
+		pubsub.WithMessageSigning(cfg.PubsubMessageSigning),
+		pubsub.WithStrictSignatureVerification(cfg.PubsubStrictSignatureVerification),
+	}
+	if cfg.PubsubValidateThrottle > 0 {
+		opts = append(opts, pubsub.WithValidateThrottle(cfg.PubsubValidateThrottle))
+	}
+	return pubsub.NewGossipSub(ctx, h, opts...)
 }
 
 func routedHost(h host.Host, d *dht.IpfsDHT) host.Host {
@@ -0,0 +1,76 @@
+package ipfscluster
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/ipfs-cluster/config"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestNewClusterHostExtraAddrs(t *testing.T) {
+	ctx := context.Background()
+	ident, err := config.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ListenAddr, _ = ma.NewMultiaddr("/ip4/127.0.0.1/tcp/0")
+	wsAddr, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/0/ws")
+	cfg.ExtraListenAddrs = []ma.Multiaddr{wsAddr}
+	announceAddr, _ := ma.NewMultiaddr("/onion3/vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd:9096")
+	cfg.AnnounceAddrs = []ma.Multiaddr{announceAddr}
+
+	h, _, idht, err := NewClusterHost(ctx, ident, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer idht.Close()
+
+	ws := false
+	for _, a := range h.Network().ListenAddresses() {
+		if strings.HasSuffix(a.String(), "/ws") {
+			ws = true
+		}
+	}
+	if !ws {
+		t.Error("expected the host to listen on a websocket address")
+	}
+
+	addrs := h.Addrs()
+	if len(addrs) != 1 || !addrs[0].Equal(announceAddr) {
+		t.Errorf("expected the host to announce only %s: %s", announceAddr, addrs)
+	}
+}
+
+func TestAnnounceAddrsFactory(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+
+	public, _ := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/9096")
+	private, _ := ma.NewMultiaddr("/ip4/10.1.1.1/tcp/9096")
+	local, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/9096")
+	listen := []ma.Multiaddr{public, private, local}
+
+	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+	cfg.NoAnnounceCIDRs = []*net.IPNet{n}
+	cfg.NoAnnounceAddrs = []ma.Multiaddr{local}
+
+	addrs := announceAddrsFactory(cfg)(listen)
+	if len(addrs) != 1 || !addrs[0].Equal(public) {
+		t.Errorf("expected only %s to be announced: %s", public, addrs)
+	}
+
+	announce, _ := ma.NewMultiaddr("/ip4/10.2.2.2/tcp/9096")
+	cfg.AnnounceAddrs = []ma.Multiaddr{public, announce}
+	addrs = announceAddrsFactory(cfg)(listen)
+	if len(addrs) != 1 || !addrs[0].Equal(public) {
+		t.Errorf("no-announce ranges should apply to announce addresses: %s", addrs)
+	}
+}
@@ -0,0 +1,89 @@
+package ipfscluster
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"time"
+
+	semver "github.com/blang/semver"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// clusterInfoCacheTTL is how long the facts returned by ClusterInfo are
+// reused, so that frequent monitoring scrapes do not contact every peer
+// each time.
+var clusterInfoCacheTTL = 10 * time.Second
+
+// ClusterInfo returns aggregate facts about the cluster: number of peers,
+// pins, errored items and free space, the consensus component in use and
+// the oldest version run by a peer. The facts are gathered from every peer
+// and cached for a short time.
+func (c *Cluster) ClusterInfo(ctx context.Context) (*api.ClusterInfo, error) {
+	_, span := trace.StartSpan(ctx, "cluster/ClusterInfo")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	// Holding the lock while gathering avoids concurrent requests
+	// contacting every peer at once.
+	c.clusterInfoMux.Lock()
+	defer c.clusterInfoMux.Unlock()
+
+	if info := c.clusterInfo; info != nil && time.Since(info.UpdatedAt) < clusterInfoCacheTTL {
+		return info, nil
+	}
+
+	pins, err := c.Pins(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &api.ClusterInfo{
+		Pins:      len(pins),
+		Consensus: componentName(c.consensus),
+	}
+	if leader, err := c.consensus.Leader(ctx); err == nil {
+		info.Leader = leader
+	}
+
+	var oldest semver.Version
+	for _, id := range c.Peers(ctx) {
+		info.Peers++
+		if id.Error != "" {
+			info.UnreachablePeers++
+			continue
+		}
+		info.FreeSpace += id.FreeSpace
+		info.PinErrors += id.PinErrors
+
+		v, err := semver.Parse(id.Version)
+		if err != nil {
+			logger.Warningf("%s reports an invalid version: %s", id.ID, id.Version)
+			continue
+		}
+		if info.OldestVersion == "" || v.LT(oldest) {
+			oldest = v
+			info.OldestVersion = id.Version
+		}
+	}
+
+	info.UpdatedAt = time.Now()
+	c.clusterInfo = info
+	return info, nil
+}
+
+// componentName returns the name of the package implementing a component
+// (i.e. "raft" or "crdt" for the consensus component).
+func componentName(comp interface{}) string {
+	t := reflect.TypeOf(comp)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return path.Base(t.PkgPath())
+}
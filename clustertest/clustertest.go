@@ -0,0 +1,542 @@
+// Package clustertest provides an in-process IPFS Cluster harness for
+// integration tests. It starts a number of cluster peers in the same
+// process, connected over the loopback interface, each one backed by an
+// IPFS mock (see the test package) or by a real IPFS daemon, and with the
+// REST API listening on a random port. Applications built on top of IPFS
+// Cluster can use it to test against actual cluster behavior:
+//
+//	h := clustertest.New(t, clustertest.Options{Peers: 3})
+//	defer h.Shutdown()
+//
+//	client, _ := client.NewDefaultClient(&client.Config{
+//		APIAddr: h.Peers[0].APIMultiaddr(),
+//	})
+//	...
+//	h.WaitForStatus(c, api.TrackerStatusPinned)
+package clustertest
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/allocator/descendalloc"
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/api/rest"
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/ipfs/ipfs-cluster/consensus/crdt"
+	"github.com/ipfs/ipfs-cluster/consensus/raft"
+	"github.com/ipfs/ipfs-cluster/datastore/badger"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs/ipfs-cluster/informer/disk"
+	"github.com/ipfs/ipfs-cluster/ipfsconn/ipfshttp"
+	"github.com/ipfs/ipfs-cluster/monitor/pubsubmon"
+	"github.com/ipfs/ipfs-cluster/observations"
+	"github.com/ipfs/ipfs-cluster/pintracker/maptracker"
+	"github.com/ipfs/ipfs-cluster/pintracker/stateless"
+	"github.com/ipfs/ipfs-cluster/test"
+
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	host "github.com/libp2p/go-libp2p-host"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	peer "github.com/libp2p/go-libp2p-peer"
+	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// DefaultPeers is the number of peers started when not specified.
+const DefaultPeers = 3
+
+// Timeout limits how long the harness waits for the cluster to converge
+// (leader election, metrics, pin status).
+var Timeout = 30 * time.Second
+
+func init() {
+	// GossipSub needs to heartbeat to discover newly connected hosts.
+	// This speeds things up a little.
+	pubsub.GossipSubHeartbeatInterval = 50 * time.Millisecond
+}
+
+// Options configure a Harness.
+type Options struct {
+	// Peers is the number of cluster peers to start. Defaults to
+	// DefaultPeers.
+	Peers int
+	// Consensus is the consensus component to use: "raft" (default)
+	// or "crdt".
+	Consensus string
+	// PinTracker is the pin tracker to use: "map" (default) or
+	// "stateless".
+	PinTracker string
+	// IPFSNodeAddrs are the API addresses of real IPFS daemons, which
+	// are assigned to the peers in turn. When empty, every peer uses
+	// its own IPFS mock.
+	IPFSNodeAddrs []ma.Multiaddr
+	// Folder is where peers store their data. Defaults to a temporary
+	// folder, which is removed on Shutdown.
+	Folder string
+}
+
+// Peer is a cluster peer started by the Harness.
+type Peer struct {
+	Cluster *ipfscluster.Cluster
+	// API is the REST API of the peer.
+	API *rest.API
+	// IPFSMock is the IPFS mock used by the peer, or nil when it uses
+	// a real IPFS daemon.
+	IPFSMock *test.IpfsMock
+	Host     host.Host
+
+	consensus ipfscluster.Consensus
+	monitor   ipfscluster.PeerMonitor
+	informer  ipfscluster.Informer
+	dht       *dht.IpfsDHT
+	shutdown  bool
+}
+
+// ID returns the peer ID.
+func (p *Peer) ID() peer.ID {
+	return p.Host.ID()
+}
+
+// APIAddr returns the address of the REST API in host:port format.
+func (p *Peer) APIAddr() string {
+	addr, _ := p.API.HTTPAddress()
+	return addr
+}
+
+// APIMultiaddr returns the address of the REST API as a multiaddress,
+// as used in the REST API client configuration.
+func (p *Peer) APIMultiaddr() ma.Multiaddr {
+	_, port, err := net.SplitHostPort(p.APIAddr())
+	if err != nil {
+		return nil
+	}
+	addr, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/" + port)
+	return addr
+}
+
+// ClusterAddr returns a multiaddress, including the peer ID, which can be
+// used to join the peer.
+func (p *Peer) ClusterAddr() ma.Multiaddr {
+	for _, a := range p.Host.Addrs() {
+		if _, err := a.ValueForProtocol(ma.P_IP4); err == nil {
+			addr, _ := ma.NewMultiaddr(fmt.Sprintf("%s/ipfs/%s", a, peer.IDB58Encode(p.ID())))
+			return addr
+		}
+	}
+	return nil
+}
+
+// Harness is a set of in-process cluster peers.
+type Harness struct {
+	// Peers are the cluster peers, in the order they were started.
+	// The first one is the one the others join.
+	Peers []*Peer
+
+	t            testing.TB
+	opts         Options
+	secret       []byte
+	folder       string
+	removeFolder bool
+}
+
+// New starts a cluster with the given options and waits until it is ready:
+// all peers have joined, there is a leader (with raft) and metrics from
+// every peer are available. It fails the test on errors.
+func New(t testing.TB, opts Options) *Harness {
+	t.Helper()
+
+	if opts.Peers <= 0 {
+		opts.Peers = DefaultPeers
+	}
+	if opts.Consensus == "" {
+		opts.Consensus = "raft"
+	}
+	if opts.PinTracker == "" {
+		opts.PinTracker = "map"
+	}
+
+	h := &Harness{
+		t:      t,
+		opts:   opts,
+		secret: make([]byte, 32),
+		folder: opts.Folder,
+	}
+
+	_, err := rand.Read(h.secret)
+	h.checkErr(err)
+
+	if h.folder == "" {
+		h.folder, err = ioutil.TempDir("", "clustertest")
+		h.checkErr(err)
+		h.removeFolder = true
+	}
+
+	for i := 0; i < opts.Peers; i++ {
+		h.startPeer()
+	}
+	h.WaitForLeader()
+	h.WaitForHealthy()
+	return h
+}
+
+func (h *Harness) checkErr(err error) {
+	h.t.Helper()
+	if err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// AddPeer starts a new peer and joins it to the cluster.
+func (h *Harness) AddPeer() *Peer {
+	h.t.Helper()
+	p := h.startPeer()
+	h.WaitForLeader()
+	h.WaitForHealthy()
+	return p
+}
+
+// startPeer creates a new peer, which joins the first one (unless it is
+// the first one).
+func (h *Harness) startPeer() *Peer {
+	h.t.Helper()
+	ctx := context.Background()
+	n := len(h.Peers)
+	first := n == 0
+
+	priv, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+	h.checkErr(err)
+	pid, err := peer.IDFromPrivateKey(priv)
+	h.checkErr(err)
+	ident := &config.Identity{ID: pid, PrivateKey: priv}
+	folder := filepath.Join(h.folder, pid.Pretty())
+
+	clusterCfg := &ipfscluster.Config{}
+	apiCfg := &rest.Config{}
+	ipfshttpCfg := &ipfshttp.Config{}
+	raftCfg := &raft.Config{}
+	crdtCfg := &crdt.Config{}
+	badgerCfg := &badger.Config{}
+	maptrackerCfg := &maptracker.Config{}
+	statelessCfg := &stateless.Config{}
+	psmonCfg := &pubsubmon.Config{}
+	diskInfCfg := &disk.Config{}
+	tracingCfg := &observations.TracingConfig{}
+
+	h.checkErr(clusterCfg.LoadJSON(clusterCfgJSON))
+	h.checkErr(apiCfg.LoadJSON(apiCfgJSON))
+	h.checkErr(ipfshttpCfg.LoadJSON(ipfsCfgJSON))
+	h.checkErr(raftCfg.LoadJSON(raftCfgJSON))
+	h.checkErr(crdtCfg.LoadJSON(crdtCfgJSON))
+	badgerCfg.Default()
+	h.checkErr(maptrackerCfg.LoadJSON(trackerCfgJSON))
+	h.checkErr(statelessCfg.LoadJSON(trackerCfgJSON))
+	h.checkErr(psmonCfg.LoadJSON(monCfgJSON))
+	h.checkErr(diskInfCfg.LoadJSON(diskInfCfgJSON))
+	tracingCfg.Default()
+
+	clusterCfg.Secret = h.secret
+	clusterCfg.Peername = fmt.Sprintf("peer_%d", n)
+	clusterCfg.SetBaseDir(folder)
+	raftCfg.DataFolder = folder
+	badgerCfg.Folder = filepath.Join(folder, "badger")
+	tracingCfg.ServiceName = clusterCfg.Peername
+
+	var mock *test.IpfsMock
+	if len(h.opts.IPFSNodeAddrs) > 0 {
+		ipfshttpCfg.NodeAddr = h.opts.IPFSNodeAddrs[n%len(h.opts.IPFSNodeAddrs)]
+	} else {
+		mock = test.NewIpfsMock(h.t)
+		nodeAddr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/%s/tcp/%d", mock.Addr, mock.Port))
+		h.checkErr(err)
+		ipfshttpCfg.NodeAddr = nodeAddr
+	}
+
+	hst, psub, idht, err := ipfscluster.NewClusterHost(ctx, ident, clusterCfg)
+	h.checkErr(err)
+
+	restAPI, err := rest.NewAPI(ctx, apiCfg)
+	h.checkErr(err)
+
+	ipfs, err := ipfshttp.NewConnector(ipfshttpCfg)
+	h.checkErr(err)
+
+	alloc := descendalloc.NewAllocator()
+	inf, err := disk.NewInformer(diskInfCfg)
+	h.checkErr(err)
+
+	var store ds.Datastore
+	var cons ipfscluster.Consensus
+	var peersF func(context.Context) ([]peer.ID, error)
+	switch h.opts.Consensus {
+	case "raft":
+		store = inmem.New()
+		raftCon, err := raft.NewConsensus(hst, raftCfg, store, !first)
+		h.checkErr(err)
+		cons = raftCon
+		peersF = raftCon.Peers
+	case "crdt":
+		store, err = badger.New(badgerCfg)
+		h.checkErr(err)
+		cons, err = crdt.New(hst, idht, psub, crdtCfg, store)
+		h.checkErr(err)
+	default:
+		h.t.Fatalf("unknown consensus: %s", h.opts.Consensus)
+	}
+
+	var tracker ipfscluster.PinTracker
+	switch h.opts.PinTracker {
+	case "map":
+		tracker = maptracker.NewMapPinTracker(maptrackerCfg, pid, clusterCfg.Peername, store)
+	case "stateless":
+		tracker = stateless.New(statelessCfg, pid, clusterCfg.Peername, store)
+	default:
+		h.t.Fatalf("unknown pintracker: %s", h.opts.PinTracker)
+	}
+
+	mon, err := pubsubmon.New(ctx, psmonCfg, psub, peersF, store)
+	h.checkErr(err)
+
+	tracer, err := observations.SetupTracing(tracingCfg)
+	h.checkErr(err)
+
+	cl, err := ipfscluster.NewCluster(
+		ctx,
+		hst,
+		idht,
+		clusterCfg,
+		store,
+		cons,
+		[]ipfscluster.API{restAPI},
+		ipfs,
+		tracker,
+		mon,
+		alloc,
+		[]ipfscluster.Informer{inf},
+		tracer,
+	)
+	h.checkErr(err)
+
+	p := &Peer{
+		Cluster:   cl,
+		API:       restAPI,
+		IPFSMock:  mock,
+		Host:      hst,
+		consensus: cons,
+		monitor:   mon,
+		informer:  inf,
+		dht:       idht,
+	}
+
+	// every peer trusts itself: none of them is a follower.
+	cons.Trust(ctx, pid)
+	if !first {
+		for _, other := range h.Peers {
+			// all previous peers trust the new one and the
+			// new one trusts them.
+			other.consensus.Trust(ctx, pid)
+			cons.Trust(ctx, other.ID())
+		}
+		err = cl.Join(ctx, h.Peers[0].ClusterAddr())
+		h.checkErr(err)
+	}
+
+	select {
+	case <-cl.Ready():
+	case <-time.After(Timeout):
+		h.t.Fatalf("timed out waiting for %s to be ready", clusterCfg.Peername)
+	}
+
+	h.Peers = append(h.Peers, p)
+	h.connect(p)
+	return p
+}
+
+// connect makes sure the given peer is connected to all others and
+// bootstraps its DHT.
+func (h *Harness) connect(p *Peer) {
+	ctx := context.Background()
+	for _, other := range h.Peers {
+		if other == p || other.shutdown {
+			continue
+		}
+		p.Host.Peerstore().AddAddrs(other.ID(), other.Host.Addrs(), peerstore.PermanentAddrTTL)
+		other.Host.Peerstore().AddAddrs(p.ID(), p.Host.Addrs(), peerstore.PermanentAddrTTL)
+		_, err := p.Host.Network().DialPeer(ctx, other.ID())
+		if err != nil {
+			h.t.Log(err)
+		}
+	}
+
+	p.dht.BootstrapWithConfig(ctx, dht.BootstrapConfig{
+		Queries: 1,
+		Period:  600 * time.Millisecond,
+		Timeout: 300 * time.Millisecond,
+	})
+}
+
+// Clusters returns the running Cluster objects.
+func (h *Harness) Clusters() []*ipfscluster.Cluster {
+	var clusters []*ipfscluster.Cluster
+	for _, p := range h.Peers {
+		if !p.shutdown {
+			clusters = append(clusters, p.Cluster)
+		}
+	}
+	return clusters
+}
+
+// ShutdownPeer stops the given peer, without removing it from the
+// cluster.
+func (h *Harness) ShutdownPeer(p *Peer) {
+	h.t.Helper()
+	if p.shutdown {
+		return
+	}
+	p.shutdown = true
+	err := p.Cluster.Shutdown(context.Background())
+	if err != nil {
+		h.t.Error(err)
+	}
+	if p.IPFSMock != nil {
+		p.IPFSMock.Close()
+	}
+}
+
+// Shutdown stops all peers and removes the data folder when it was created
+// by the harness.
+func (h *Harness) Shutdown() {
+	h.t.Helper()
+	var wg sync.WaitGroup
+	for _, p := range h.Peers {
+		if p.shutdown {
+			continue
+		}
+		wg.Add(1)
+		go func(p *Peer) {
+			defer wg.Done()
+			h.ShutdownPeer(p)
+		}(p)
+	}
+	wg.Wait()
+
+	if h.removeFolder {
+		os.RemoveAll(h.folder)
+	}
+}
+
+// WaitForLeader waits until all running peers know the raft leader. It
+// returns immediately with other consensus components.
+func (h *Harness) WaitForLeader() {
+	h.t.Helper()
+	if h.opts.Consensus != "raft" {
+		return
+	}
+
+	h.waitFor("a leader", func() bool {
+		for _, p := range h.Peers {
+			if p.shutdown {
+				continue
+			}
+			if _, err := p.consensus.Leader(context.Background()); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// WaitForHealthy waits until every running peer lists all the others as
+// cluster peers and has received valid metrics from them.
+func (h *Harness) WaitForHealthy() {
+	h.t.Helper()
+	h.waitFor("healthy peers", func() bool {
+		ctx := context.Background()
+		running := len(h.Clusters())
+		for _, p := range h.Peers {
+			if p.shutdown {
+				continue
+			}
+			peers := 0
+			for _, id := range p.Cluster.Peers(ctx) {
+				if id.Error == "" {
+					peers++
+				}
+			}
+			if peers < running {
+				return false
+			}
+
+			metrics := p.monitor.LatestMetrics(ctx, p.informer.Name())
+			healthy := 0
+			for _, m := range metrics {
+				if !m.Expired() {
+					healthy++
+				}
+			}
+			if healthy < running {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// WaitForStatus waits until the given CID has the given status in all the
+// peers it is allocated to, as seen from the first running peer.
+func (h *Harness) WaitForStatus(c cid.Cid, status api.TrackerStatus) {
+	h.t.Helper()
+	clusters := h.Clusters()
+	if len(clusters) == 0 {
+		h.t.Fatal("no running peers")
+	}
+
+	h.waitFor(fmt.Sprintf("%s to be %s", c, status), func() bool {
+		ginfo, err := clusters[0].Status(context.Background(), c)
+		if err != nil {
+			return false
+		}
+		found := false
+		for _, pinfo := range ginfo.PeerMap {
+			switch pinfo.Status {
+			case status:
+				found = true
+			case api.TrackerStatusRemote:
+			default:
+				return false
+			}
+		}
+		return found
+	})
+}
+
+func (h *Harness) waitFor(what string, f func() bool) {
+	h.t.Helper()
+	timer := time.NewTimer(Timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if f() {
+			return
+		}
+		select {
+		case <-timer.C:
+			h.t.Fatalf("timed out waiting for %s", what)
+		case <-ticker.C:
+		}
+	}
+}
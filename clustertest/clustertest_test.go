@@ -0,0 +1,65 @@
+package clustertest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/api/rest/client"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func testHarness(t *testing.T, consensus string) {
+	ctx := context.Background()
+	h := New(t, Options{Peers: 3, Consensus: consensus})
+	defer h.Shutdown()
+
+	if len(h.Clusters()) != 3 {
+		t.Fatal("expected 3 running peers")
+	}
+
+	for _, p := range h.Peers {
+		peers := p.Cluster.Peers(ctx)
+		if len(peers) != 3 {
+			t.Errorf("%s sees %d peers", p.ID(), len(peers))
+		}
+	}
+
+	err := h.Peers[0].Cluster.Pin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.WaitForStatus(test.Cid1, api.TrackerStatusPinned)
+
+	c, err := client.NewDefaultClient(&client.Config{
+		APIAddr: h.Peers[1].APIMultiaddr(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ginfo, err := c.Status(ctx, test.Cid1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ginfo.PeerMap) != 3 {
+		t.Error("expected status from all peers through the API")
+	}
+
+	p := h.AddPeer()
+	if len(h.Clusters()) != 4 {
+		t.Fatal("expected 4 running peers")
+	}
+
+	h.ShutdownPeer(p)
+	if len(h.Clusters()) != 3 {
+		t.Fatal("expected 3 running peers")
+	}
+}
+
+func TestHarnessRaft(t *testing.T) {
+	testHarness(t, "raft")
+}
+
+func TestHarnessCRDT(t *testing.T) {
+	testHarness(t, "crdt")
+}
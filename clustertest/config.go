@@ -0,0 +1,68 @@
+package clustertest
+
+// Configurations tuned to make in-process clusters converge fast. Listen
+// addresses use port 0 so that several harnesses can run in parallel.
+
+var clusterCfgJSON = []byte(`{
+    "leave_on_shutdown": false,
+    "listen_multiaddress": "/ip4/127.0.0.1/tcp/0",
+    "state_sync_interval": "1m0s",
+    "ipfs_sync_interval": "2m10s",
+    "replication_factor": -1,
+    "monitor_ping_interval": "250ms",
+    "peer_watch_interval": "100ms",
+    "disable_repinning": false
+}`)
+
+var raftCfgJSON = []byte(`{
+    "wait_for_leader_timeout": "10s",
+    "commit_retries": 2,
+    "commit_retry_delay": "50ms",
+    "backups_rotate": 2,
+    "network_timeout": "5s",
+    "heartbeat_timeout": "100ms",
+    "election_timeout": "100ms",
+    "commit_timeout": "50ms",
+    "max_append_entries": 256,
+    "trailing_logs": 10240,
+    "snapshot_interval": "2m0s",
+    "snapshot_threshold": 8192,
+    "leader_lease_timeout": "80ms"
+}`)
+
+var crdtCfgJSON = []byte(`{
+    "cluster_name": "clustertest",
+    "trusted_peers": [],
+    "rebroadcast_interval": "150ms"
+}`)
+
+var apiCfgJSON = []byte(`{
+    "http_listen_multiaddress": "/ip4/127.0.0.1/tcp/0",
+    "read_timeout": "0",
+    "read_header_timeout": "5s",
+    "write_timeout": "0",
+    "idle_timeout": "2m0s"
+}`)
+
+var ipfsCfgJSON = []byte(`{
+    "node_multiaddress": "/ip4/127.0.0.1/tcp/5001",
+    "connect_swarms_delay": "7s",
+    "pin_method": "pin",
+    "pin_timeout": "30s",
+    "unpin_timeout": "15s"
+}`)
+
+var trackerCfgJSON = []byte(`{
+    "max_pin_queue_size": 4092,
+    "concurrent_pins": 1
+}`)
+
+var monCfgJSON = []byte(`{
+    "check_interval": "400ms",
+    "failure_threshold": 6
+}`)
+
+var diskInfCfgJSON = []byte(`{
+    "metric_ttl": "250ms",
+    "metric_type": "freespace"
+}`)
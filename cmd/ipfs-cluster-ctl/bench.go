@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	humanize "github.com/dustin/go-humanize"
+	cid "github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
+	mh "github.com/multiformats/go-multihash"
+	cli "github.com/urfave/cli"
+)
+
+// benchOps are the supported benchmark operations.
+var benchOps = map[string]struct{}{
+	"pin":    {},
+	"add":    {},
+	"status": {},
+}
+
+// benchOpStats summarizes the latencies of one type of operation.
+type benchOpStats struct {
+	Op         string        `json:"op"`
+	Count      int           `json:"count"`
+	Errors     int           `json:"errors"`
+	Throughput float64       `json:"throughput"`
+	Min        time.Duration `json:"min"`
+	Mean       time.Duration `json:"mean"`
+	P50        time.Duration `json:"p50"`
+	P90        time.Duration `json:"p90"`
+	P99        time.Duration `json:"p99"`
+	Max        time.Duration `json:"max"`
+	LastError  string        `json:"last_error,omitempty"`
+}
+
+// benchReport is the result of a benchmark run.
+type benchReport struct {
+	Duration    time.Duration   `json:"duration"`
+	Concurrency int             `json:"concurrency"`
+	Operations  []*benchOpStats `json:"operations"`
+}
+
+type benchRecorder struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+	lastError map[string]string
+}
+
+func (r *benchRecorder) record(op string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[op]++
+		r.lastError[op] = err.Error()
+		return
+	}
+	r.latencies[op] = append(r.latencies[op], d)
+}
+
+// stats computes the summary for the given operation. Latency figures only
+// take successful operations into account.
+func (r *benchRecorder) stats(op string, elapsed time.Duration) *benchOpStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lats := r.latencies[op]
+	st := &benchOpStats{
+		Op:        op,
+		Count:     len(lats),
+		Errors:    r.errors[op],
+		LastError: r.lastError[op],
+	}
+	if len(lats) == 0 {
+		return st
+	}
+
+	sort.Slice(lats, func(i, j int) bool { return lats[i] < lats[j] })
+	var total time.Duration
+	for _, l := range lats {
+		total += l
+	}
+	st.Throughput = float64(len(lats)) / elapsed.Seconds()
+	st.Min = lats[0]
+	st.Max = lats[len(lats)-1]
+	st.Mean = total / time.Duration(len(lats))
+	st.P50 = percentile(lats, 50)
+	st.P90 = percentile(lats, 90)
+	st.P99 = percentile(lats, 99)
+	return st
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// bench generates load against the cluster API.
+type bench struct {
+	ops     []string
+	size    int
+	pinOpts api.PinOptions
+
+	mu      sync.Mutex
+	created []cid.Cid
+}
+
+func (b *bench) addCreated(c cid.Cid) {
+	b.mu.Lock()
+	b.created = append(b.created, c)
+	b.mu.Unlock()
+}
+
+func (b *bench) randomCreated() (cid.Cid, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.created) == 0 {
+		return cid.Undef, false
+	}
+	return b.created[rand.Intn(len(b.created))], true
+}
+
+// randomCid returns a CID using the identity hash on random data. IPFS can
+// pin them without fetching anything from the network, so that pinning
+// measures cluster overhead only.
+func randomCid() (cid.Cid, error) {
+	data := make([]byte, 32)
+	rand.Read(data)
+	hash, err := mh.Sum(data, mh.ID, -1)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(cid.Raw, hash), nil
+}
+
+func (b *bench) run(ctx context.Context, op string) error {
+	switch op {
+	case "pin":
+		c, err := randomCid()
+		if err != nil {
+			return err
+		}
+		err = globalClient.Pin(ctx, c, b.pinOpts)
+		if err != nil {
+			return err
+		}
+		b.addCreated(c)
+		return nil
+	case "add":
+		return b.add(ctx)
+	case "status":
+		c, ok := b.randomCreated()
+		if !ok {
+			var err error
+			c, err = randomCid()
+			if err != nil {
+				return err
+			}
+		}
+		_, err := globalClient.Status(ctx, c, false)
+		return err
+	default:
+		return fmt.Errorf("unknown operation: %s", op)
+	}
+}
+
+func (b *bench) add(ctx context.Context) error {
+	data := make([]byte, b.size)
+	rand.Read(data)
+
+	dir := files.NewMapDirectory(map[string]files.Node{
+		"bench": files.NewBytesFile(data),
+	})
+	mfr := files.NewMultiFileReader(dir, true)
+
+	params := api.DefaultAddParams()
+	params.ReplicationFactorMin = b.pinOpts.ReplicationFactorMin
+	params.ReplicationFactorMax = b.pinOpts.ReplicationFactorMax
+	params.Name = b.pinOpts.Name
+
+	out := make(chan *api.AddedOutput, 1)
+	var last *api.AddedOutput
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for o := range out {
+			last = o
+		}
+	}()
+
+	err := globalClient.AddMultiFile(ctx, mfr, params, out)
+	<-done
+	if err != nil {
+		return err
+	}
+	if last == nil {
+		return errors.New("add returned no output")
+	}
+	b.addCreated(last.Cid)
+	return nil
+}
+
+// cleanup unpins everything pinned or added during the benchmark.
+func (b *bench) cleanup(ctx context.Context, concurrency int) {
+	work := make(chan cid.Cid)
+	var failed int32
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				if err := globalClient.Unpin(ctx, c); err != nil {
+					atomic.AddInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	for _, c := range b.created {
+		work <- c
+	}
+	close(work)
+	wg.Wait()
+	if failed > 0 {
+		out("failed to unpin %d items\n", failed)
+	}
+}
+
+func runBench(ctx context.Context, c *cli.Context) {
+	ops := strings.Split(c.String("ops"), ",")
+	for _, op := range ops {
+		if _, ok := benchOps[op]; !ok {
+			checkErr("", fmt.Errorf("unknown operation: %s", op))
+		}
+	}
+
+	size, err := humanize.ParseBytes(c.String("size"))
+	checkErr("parsing size", err)
+
+	concurrency := c.Int("concurrency")
+	if concurrency <= 0 {
+		checkErr("", errors.New("concurrency must be positive"))
+	}
+	duration := c.Duration("duration")
+	count := int64(c.Int("count"))
+	if duration <= 0 && count <= 0 {
+		checkErr("", errors.New("a duration or a count is needed"))
+	}
+	rate := c.Float64("rate")
+
+	rand.Seed(time.Now().UnixNano())
+
+	b := &bench{
+		ops:  ops,
+		size: int(size),
+		pinOpts: api.PinOptions{
+			ReplicationFactorMin: c.Int("replication-min"),
+			ReplicationFactorMax: c.Int("replication-max"),
+			Name:                 c.String("name"),
+		},
+	}
+	rec := &benchRecorder{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+		lastError: make(map[string]string),
+	}
+
+	// stopCtx stops starting new operations. Operations in flight
+	// use the global context and are allowed to finish.
+	stopCtx := context.Background()
+	var cancel context.CancelFunc
+	if duration > 0 {
+		stopCtx, cancel = context.WithTimeout(stopCtx, duration)
+	} else {
+		stopCtx, cancel = context.WithCancel(stopCtx)
+	}
+	defer cancel()
+
+	var tokens <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+
+	out(
+		"running %s with %d workers for %s (count: %d, rate: %.2f ops/s)...\n",
+		strings.Join(ops, ","), concurrency, duration, count, rate,
+	)
+
+	var started int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if tokens != nil {
+					select {
+					case <-stopCtx.Done():
+						return
+					case <-tokens:
+					}
+				} else if stopCtx.Err() != nil {
+					return
+				}
+
+				n := atomic.AddInt64(&started, 1)
+				if count > 0 && n > count {
+					cancel()
+					return
+				}
+				op := ops[(n-1)%int64(len(ops))]
+				opStart := time.Now()
+				err := b.run(ctx, op)
+				rec.record(op, time.Since(opStart), err)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := &benchReport{
+		Duration:    elapsed,
+		Concurrency: concurrency,
+	}
+	seen := make(map[string]struct{})
+	for _, op := range ops {
+		if _, ok := seen[op]; ok {
+			continue
+		}
+		seen[op] = struct{}{}
+		report.Operations = append(report.Operations, rec.stats(op, elapsed))
+	}
+
+	if !c.Bool("no-cleanup") && len(b.created) > 0 {
+		out("unpinning %d items...\n", len(b.created))
+		b.cleanup(ctx, concurrency)
+	}
+
+	formatResponse(c, report, nil)
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api/rest/client"
+
+	ma "github.com/multiformats/go-multiaddr"
+	cli "github.com/urfave/cli"
+)
+
+// Operators managing several independent clusters can save the API endpoint
+// of each of them under a name in the clusters file (see the "clusters"
+// command) and select one with --cluster <name>, instead of giving --host
+// and the credentials with every command. Flags given explicitly override
+// the saved values. When no cluster nor --host is given, the default
+// cluster, if any, is used. With --all-clusters, read-only commands run
+// against every saved cluster in turn.
+
+// DefaultClustersFile is the name of the clusters file inside the cluster
+// folder of the user's home.
+const DefaultClustersFile = "ctl-clusters.json"
+
+// clusterEndpoint holds the options to contact the API of a cluster. They
+// match the global flags.
+type clusterEndpoint struct {
+	Host               string `json:"host"`
+	Secret             string `json:"secret,omitempty"`
+	HTTPS              bool   `json:"https,omitempty"`
+	NoCheckCertificate bool   `json:"no_check_certificate,omitempty"`
+	BasicAuth          string `json:"basic_auth,omitempty"`
+	Token              string `json:"token,omitempty"`
+	ForceHTTP          bool   `json:"force_http,omitempty"`
+}
+
+// clustersFile is the content of the clusters file.
+type clustersFile struct {
+	Default  string                      `json:"default,omitempty"`
+	Clusters map[string]*clusterEndpoint `json:"clusters"`
+}
+
+// savedCluster is how a saved cluster is listed. Credentials are not shown.
+type savedCluster struct {
+	Name    string `json:"name"`
+	Host    string `json:"host"`
+	Default bool   `json:"default"`
+}
+
+// namedClient is the API client of a saved cluster.
+type namedClient struct {
+	name   string
+	client client.Client
+}
+
+// allClustersCommands are the read-only commands that can run against all
+// the saved clusters with --all-clusters.
+var allClustersCommands = map[string]bool{
+	"id":                    true,
+	"peers ls":              true,
+	"peers peerstore ls":    true,
+	"peers deny ls":         true,
+	"pin ls":                true,
+	"pin details":           true,
+	"snapshot ls":           true,
+	"status":                true,
+	"version":               true,
+	"health statesync last": true,
+	"health info":           true,
+	"health versions":       true,
+	"health runtime":        true,
+	"health ipfs":           true,
+	"health recovery":       true,
+	"health storage":        true,
+	"health reconnect":      true,
+	"health logs":           true,
+	"health metrics":        true,
+	"faults ls":             true,
+}
+
+// localCommands do not contact any cluster.
+var localCommands = map[string]bool{
+	"clusters": true,
+	"commands": true,
+}
+
+var (
+	// allClients are the clients of every saved cluster, with
+	// --all-clusters.
+	allClients []namedClient
+	// fanningOut is set while a command runs against all the clusters.
+	fanningOut bool
+	// fanOutCode is the exit code of the worst failed request while
+	// fanning out.
+	fanOutCode int
+)
+
+func defaultClustersPath() string {
+	// Same as ipfs-cluster-service: HOME first, so that HOME hacks work.
+	home := os.Getenv("HOME")
+	if home == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return ""
+		}
+		home = usr.HomeDir
+	}
+	return filepath.Join(home, ".ipfs-cluster", DefaultClustersFile)
+}
+
+// loadClusters reads the clusters file. A missing file has no clusters.
+func loadClusters(path string) (*clustersFile, error) {
+	cf := &clustersFile{
+		Clusters: make(map[string]*clusterEndpoint),
+	}
+	if path == "" {
+		return cf, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, cf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if cf.Clusters == nil {
+		cf.Clusters = make(map[string]*clusterEndpoint)
+	}
+	return cf, nil
+}
+
+// save writes the clusters file, which may contain credentials, so that
+// only the user can read it.
+func (cf *clustersFile) save(path string) error {
+	if path == "" {
+		return errors.New("no clusters file given")
+	}
+	data, err := json.MarshalIndent(cf, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// names returns the names of the saved clusters, sorted.
+func (cf *clustersFile) names() []string {
+	names := make([]string, 0, len(cf.Clusters))
+	for name := range cf.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// endpointFromFlags returns the endpoint given with the global flags.
+func endpointFromFlags(c *cli.Context) *clusterEndpoint {
+	return &clusterEndpoint{
+		Host:               c.GlobalString("host"),
+		Secret:             c.GlobalString("secret"),
+		HTTPS:              c.GlobalBool("https"),
+		NoCheckCertificate: c.GlobalBool("no-check-certificate"),
+		BasicAuth:          c.GlobalString("basic-auth"),
+		Token:              c.GlobalString("token"),
+		ForceHTTP:          c.GlobalBool("force-http"),
+	}
+}
+
+// withFlags returns a copy of a saved endpoint with the values of the
+// global flags given explicitly.
+func (ep *clusterEndpoint) withFlags(c *cli.Context) *clusterEndpoint {
+	res := *ep
+	flags := endpointFromFlags(c)
+	if c.GlobalIsSet("host") {
+		res.Host = flags.Host
+	}
+	if c.GlobalIsSet("secret") {
+		res.Secret = flags.Secret
+	}
+	if c.GlobalIsSet("https") {
+		res.HTTPS = flags.HTTPS
+	}
+	if c.GlobalIsSet("no-check-certificate") {
+		res.NoCheckCertificate = flags.NoCheckCertificate
+	}
+	if c.GlobalIsSet("basic-auth") {
+		res.BasicAuth = flags.BasicAuth
+	}
+	if c.GlobalIsSet("token") {
+		res.Token = flags.Token
+	}
+	if c.GlobalIsSet("force-http") {
+		res.ForceHTTP = flags.ForceHTTP
+	}
+	return &res
+}
+
+// newClient creates an API client for the given endpoint.
+func newClient(c *cli.Context, ep *clusterEndpoint) client.Client {
+	cfg := &client.Config{}
+	if c.GlobalBool("debug") {
+		cfg.LogLevel = "debug"
+	}
+
+	addr, err := ma.NewMultiaddr(ep.Host)
+	checkErr("parsing host multiaddress", err)
+
+	cfg.APIAddr = addr
+	if ep.Secret != "" {
+		secret, err := hex.DecodeString(ep.Secret)
+		checkErr("parsing secret", err)
+		cfg.ProtectorKey = secret
+	}
+
+	cfg.Timeout = time.Duration(c.GlobalInt("timeout")) * time.Second
+
+	if client.IsPeerAddress(cfg.APIAddr) && ep.HTTPS {
+		logger.Warning("Using libp2p-http. SSL flags will be ignored")
+	}
+
+	cfg.SSL = ep.HTTPS
+	cfg.NoVerifyCert = ep.NoCheckCertificate
+	user, pass := parseCredentials(ep.BasicAuth)
+	cfg.Username = user
+	cfg.Password = pass
+	cfg.Token = ep.Token
+	if (user != "" || cfg.Token != "") && !cfg.SSL && !ep.ForceHTTP {
+		logger.Warning("SSL automatically enabled with authentication credentials. Set \"force-http\" to disable")
+		cfg.SSL = true
+	}
+
+	cl, err := client.NewDefaultClient(cfg)
+	checkErr("creating API client", err)
+	return cl
+}
+
+// setupClients creates the API client for the selected cluster or, with
+// --all-clusters, for every saved cluster.
+func setupClients(c *cli.Context) {
+	cf, err := loadClusters(c.GlobalString("clusters-file"))
+	checkErr("reading clusters file", err)
+
+	if c.GlobalBool("all-clusters") {
+		if c.GlobalString("cluster") != "" {
+			checkErr("", errors.New("--cluster and --all-clusters cannot be used together"))
+		}
+		for _, name := range cf.names() {
+			cl := newClient(c, cf.Clusters[name].withFlags(c))
+			allClients = append(allClients, namedClient{name: name, client: cl})
+		}
+		return
+	}
+
+	ep := endpointFromFlags(c)
+	name := c.GlobalString("cluster")
+	if name == "" && !c.GlobalIsSet("host") {
+		name = cf.Default
+	}
+	if name != "" {
+		saved, ok := cf.Clusters[name]
+		if !ok {
+			checkErr("", fmt.Errorf("unknown cluster: %s", name))
+		}
+		ep = saved.withFlags(c)
+	}
+
+	globalClient = newClient(c, ep)
+}
+
+// requestFailed exits with the given code after a failed request, unless
+// the command is running against all the clusters, where the remaining
+// clusters are still contacted.
+func requestFailed(code int) {
+	if !fanningOut {
+		os.Exit(code)
+	}
+	if code > fanOutCode {
+		fanOutCode = code
+	}
+}
+
+// setupAllClusters wraps the actions of the given commands so that the
+// read-only ones run against every saved cluster with --all-clusters, and
+// the others refuse it.
+func setupAllClusters(cmds []cli.Command, parent string) {
+	for i := range cmds {
+		cmd := &cmds[i]
+		name := cmd.Name
+		if parent != "" {
+			name = parent + " " + cmd.Name
+		}
+		if localCommands[name] {
+			continue
+		}
+		setupAllClusters(cmd.Subcommands, name)
+
+		action, ok := cmd.Action.(func(*cli.Context) error)
+		if !ok {
+			continue
+		}
+		if allClustersCommands[name] {
+			cmd.Action = fanOut(action)
+		} else {
+			cmd.Action = singleCluster(name, action)
+		}
+	}
+}
+
+// fanOut runs an action against every saved cluster with --all-clusters.
+// The output of each is preceded by the name of the cluster.
+func fanOut(action func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		if !c.GlobalBool("all-clusters") {
+			return action(c)
+		}
+		if len(allClients) == 0 {
+			checkErr("", errors.New("no clusters saved. Use \"ipfs-cluster-ctl clusters add\""))
+		}
+
+		fanningOut = true
+		for _, nc := range allClients {
+			if c.GlobalString("encoding") == "text" {
+				fmt.Printf("=== %s ===\n", nc.name)
+			} else {
+				out("=== %s ===\n", nc.name)
+			}
+			globalClient = nc.client
+			err := action(c)
+			if err != nil {
+				return err
+			}
+		}
+		fanningOut = false
+		if fanOutCode != 0 {
+			os.Exit(fanOutCode)
+		}
+		return nil
+	}
+}
+
+// singleCluster refuses --all-clusters for commands which are not
+// read-only.
+func singleCluster(name string, action func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		if c.GlobalBool("all-clusters") {
+			checkErr("", fmt.Errorf("--all-clusters is not supported by \"%s\"", name))
+		}
+		return action(c)
+	}
+}
+
+func clustersCommand() cli.Command {
+	return cli.Command{
+		Name:  "clusters",
+		Usage: "Manage the saved cluster endpoints",
+		Description: `
+These commands manage the API endpoints of the clusters saved in the clusters
+file (--clusters-file), so that they can be contacted by name with
+--cluster <name>. Read-only commands can also run against all of them with
+--all-clusters.
+`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "ls",
+				Usage:     "list the saved clusters",
+				ArgsUsage: " ",
+				Action: func(c *cli.Context) error {
+					cf, err := loadClusters(c.GlobalString("clusters-file"))
+					checkErr("reading clusters file", err)
+					var resp []*savedCluster
+					for _, name := range cf.names() {
+						resp = append(resp, &savedCluster{
+							Name:    name,
+							Host:    cf.Clusters[name].Host,
+							Default: name == cf.Default,
+						})
+					}
+					formatResponse(c, resp, nil)
+					return nil
+				},
+			},
+			{
+				Name:  "add",
+				Usage: "save a cluster endpoint",
+				Description: `
+This command saves the API endpoint given with the global flags (--host,
+--secret, --https, --no-check-certificate, --basic-auth, --token and
+--force-http) under the given name. For example:
+
+  ipfs-cluster-ctl --host /dns4/eu.example.org/tcp/9094 --basic-auth user:pass clusters add prod-eu
+
+Note that credentials are stored in the clusters file as given.
+`,
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "default",
+						Usage: "use this cluster when none is given",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					name := c.Args().First()
+					if name == "" {
+						checkErr("", errors.New("provide a cluster name"))
+					}
+					path := c.GlobalString("clusters-file")
+					cf, err := loadClusters(path)
+					checkErr("reading clusters file", err)
+					if _, ok := cf.Clusters[name]; ok {
+						checkErr("", fmt.Errorf("cluster %s already exists. Remove it first", name))
+					}
+					cf.Clusters[name] = endpointFromFlags(c)
+					if c.Bool("default") {
+						cf.Default = name
+					}
+					checkErr("saving clusters file", cf.save(path))
+					return nil
+				},
+			},
+			{
+				Name:      "rm",
+				Usage:     "remove a saved cluster",
+				ArgsUsage: "<name>",
+				Action: func(c *cli.Context) error {
+					name := c.Args().First()
+					path := c.GlobalString("clusters-file")
+					cf, err := loadClusters(path)
+					checkErr("reading clusters file", err)
+					if _, ok := cf.Clusters[name]; !ok {
+						checkErr("", fmt.Errorf("unknown cluster: %s", name))
+					}
+					delete(cf.Clusters, name)
+					if cf.Default == name {
+						cf.Default = ""
+					}
+					checkErr("saving clusters file", cf.save(path))
+					return nil
+				},
+			},
+			{
+				Name:  "default",
+				Usage: "set the cluster used when none is given",
+				Description: `
+This command sets the saved cluster contacted when neither --cluster nor
+--host are given. Without a name, the default cluster is unset and the
+default --host is contacted again.
+`,
+				ArgsUsage: "[name]",
+				Action: func(c *cli.Context) error {
+					name := c.Args().First()
+					path := c.GlobalString("clusters-file")
+					cf, err := loadClusters(path)
+					checkErr("reading clusters file", err)
+					if _, ok := cf.Clusters[name]; name != "" && !ok {
+						checkErr("", fmt.Errorf("unknown cluster: %s", name))
+					}
+					cf.Default = name
+					checkErr("saving clusters file", cf.save(path))
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	humanize "github.com/dustin/go-humanize"
 	peer "github.com/libp2p/go-libp2p-peer"
 
 	"github.com/ipfs/ipfs-cluster/api"
@@ -52,6 +53,8 @@ func textFormatObject(resp interface{}) {
 		textFormatPrintGPInfo(resp.(*api.GlobalPinInfo))
 	case *api.Pin:
 		textFormatPrintPin(resp.(*api.Pin))
+	case *api.PinDetails:
+		textFormatPrintPinDetails(resp.(*api.PinDetails))
 	case *api.AddedOutput:
 		textFormatPrintAddedOutput(resp.(*api.AddedOutput))
 	case *addedOutputQuiet:
@@ -62,6 +65,52 @@ func textFormatObject(resp interface{}) {
 		textFormatPrintError(resp.(*api.Error))
 	case *api.Metric:
 		textFormatPrintMetric(resp.(*api.Metric))
+	case *api.StateSync:
+		textFormatPrintStateSync(resp.(*api.StateSync))
+	case *api.AuditRecord:
+		textFormatPrintAuditRecord(resp.(*api.AuditRecord))
+	case *api.RepoGC:
+		textFormatPrintRepoGC(resp.(*api.RepoGC))
+	case *api.PingResult:
+		textFormatPrintPingResult(resp.(*api.PingResult))
+	case *api.KnownPeer:
+		textFormatPrintKnownPeer(resp.(*api.KnownPeer))
+	case *api.PeerVersion:
+		textFormatPrintPeerVersion(resp.(*api.PeerVersion))
+	case *api.RuntimeStats:
+		textFormatPrintRuntimeStats(resp.(*api.RuntimeStats))
+	case *api.StatusSummary:
+		textFormatPrintStatusSummary(resp.(*api.StatusSummary))
+	case *api.StorageUsage:
+		textFormatPrintStorageUsage(resp.(*api.StorageUsage))
+	case *api.IPFSDrift:
+		textFormatPrintIPFSDrift(resp.(*api.IPFSDrift))
+	case *api.ClusterInfo:
+		textFormatPrintClusterInfo(resp.(*api.ClusterInfo))
+	case *api.RecoveryJob:
+		textFormatPrintRecoveryJob(resp.(*api.RecoveryJob))
+	case *api.ReconnectStatus:
+		textFormatPrintReconnectStatus(resp.(*api.ReconnectStatus))
+	case *api.LogEntry:
+		textFormatPrintLogEntry(resp.(*api.LogEntry))
+	case *benchReport:
+		textFormatPrintBenchReport(resp.(*benchReport))
+	case *savedCluster:
+		textFormatPrintSavedCluster(resp.(*savedCluster))
+	case *api.JoinToken:
+		textFormatPrintJoinToken(resp.(*api.JoinToken))
+	case *api.ConnectionFilter:
+		textFormatPrintConnectionFilter(resp.(*api.ConnectionFilter))
+	case *api.FaultRule:
+		textFormatPrintFaultRule(resp.(*api.FaultRule))
+	case *api.ProvideResult:
+		textFormatPrintProvideResult(resp.(*api.ProvideResult))
+	case *api.Snapshot:
+		textFormatPrintSnapshot(resp.(*api.Snapshot))
+	case *api.SnapshotDiff:
+		textFormatPrintSnapshotDiff(resp.(*api.SnapshotDiff))
+	case *api.SnapshotRestore:
+		textFormatPrintSnapshotRestore(resp.(*api.SnapshotRestore))
 	case []*api.ID:
 		for _, item := range resp.([]*api.ID) {
 			textFormatObject(item)
@@ -86,6 +135,54 @@ func textFormatObject(resp interface{}) {
 		for _, item := range resp.([]*api.Metric) {
 			textFormatObject(item)
 		}
+	case []*api.StateSync:
+		for _, item := range resp.([]*api.StateSync) {
+			textFormatObject(item)
+		}
+	case []*api.AuditRecord:
+		for _, item := range resp.([]*api.AuditRecord) {
+			textFormatObject(item)
+		}
+	case []*api.RepoGC:
+		for _, item := range resp.([]*api.RepoGC) {
+			textFormatObject(item)
+		}
+	case []*api.PingResult:
+		for _, item := range resp.([]*api.PingResult) {
+			textFormatObject(item)
+		}
+	case []*api.PeerVersion:
+		for _, item := range resp.([]*api.PeerVersion) {
+			textFormatObject(item)
+		}
+	case []*api.KnownPeer:
+		for _, item := range resp.([]*api.KnownPeer) {
+			textFormatObject(item)
+		}
+	case []*api.RuntimeStats:
+		for _, item := range resp.([]*api.RuntimeStats) {
+			textFormatObject(item)
+		}
+	case []*api.FaultRule:
+		for _, item := range resp.([]*api.FaultRule) {
+			textFormatObject(item)
+		}
+	case []*api.ProvideResult:
+		for _, item := range resp.([]*api.ProvideResult) {
+			textFormatObject(item)
+		}
+	case []*api.LogEntry:
+		for _, item := range resp.([]*api.LogEntry) {
+			textFormatObject(item)
+		}
+	case []*api.Snapshot:
+		for _, item := range resp.([]*api.Snapshot) {
+			textFormatObject(item)
+		}
+	case []*savedCluster:
+		for _, item := range resp.([]*savedCluster) {
+			textFormatObject(item)
+		}
 	default:
 		checkErr("", errors.New("unsupported type returned"))
 	}
@@ -104,6 +201,27 @@ func textFormatPrintID(obj *api.ID) {
 		len(obj.ClusterPeers)-1,
 	)
 
+	if obj.Allocatable {
+		fmt.Println("  > Allocatable: yes")
+	} else {
+		fmt.Println("  > Allocatable: no")
+	}
+	if obj.TrackerPaused {
+		fmt.Println("  > Pin tracker: paused")
+	}
+
+	fmt.Printf(
+		"  > Health: version %s | %s free | %d queued | %d errors",
+		obj.Version,
+		humanize.Bytes(obj.FreeSpace),
+		obj.PinQueue,
+		obj.PinErrors,
+	)
+	if !obj.LastHeartbeat.IsZero() {
+		fmt.Printf(" | last heartbeat %s ago", time.Since(obj.LastHeartbeat).Round(time.Second))
+	}
+	fmt.Println()
+
 	addrs := make(sort.StringSlice, 0, len(obj.Addresses))
 	for _, a := range obj.Addresses {
 		addrs = append(addrs, a.String())
@@ -144,12 +262,65 @@ func textFormatPrintGPInfo(obj *api.GlobalPinInfo) {
 		} else {
 			fmt.Printf("    > %-15s : %s", k, strings.ToUpper(v.Status.String()))
 		}
+		if v.Paused {
+			fmt.Printf(" (PAUSED)")
+		}
 		if v.Error != "" {
 			fmt.Printf(": %s", v.Error)
 		}
+		if bs := v.Bitswap; bs != nil {
+			fmt.Printf(": wantlist %d, %d peers, %s received (%s/s)", bs.WantlistSize, bs.Peers, humanize.Bytes(bs.DataReceived), humanize.Bytes(uint64(bs.DataReceivedRate)))
+		}
+		if pg := v.Progress; pg != nil {
+			fmt.Printf(": %d blocks, %s fetched", pg.Blocks, humanize.Bytes(pg.Bytes))
+			if pg.TotalBytes > 0 {
+				fmt.Printf(" of ~%s (%.0f%%)", humanize.Bytes(pg.TotalBytes), pg.Percent)
+			}
+		}
 		txt, _ := v.TS.MarshalText()
 		fmt.Printf(" | %s\n", txt)
 	}
+
+	if obj.Shards != nil {
+		textFormatPrintShardsStatus(obj.Shards)
+	}
+
+	if obj.Allocation != nil {
+		textFormatPrintAllocationExplanation(obj.Allocation)
+	}
+}
+
+func textFormatPrintShardsStatus(obj *api.ShardsStatus) {
+	fmt.Printf("  Shards: %d/%d pinned\n", obj.Pinned, obj.Total)
+	for i, s := range obj.Shards {
+		pinned := make([]string, 0, len(s.Pinned))
+		for _, p := range s.Pinned {
+			pinned = append(pinned, p.Pretty())
+		}
+		if len(pinned) == 0 {
+			pinned = append(pinned, "MISSING")
+		}
+		fmt.Printf("    > #%-4d %s : %s\n", i, s.Cid, strings.Join(pinned, ", "))
+	}
+}
+
+func textFormatPrintAllocationExplanation(obj *api.AllocationExplanation) {
+	txt, _ := obj.TS.MarshalText()
+	fmt.Printf("  Allocated by %s using %s and %s metrics | %s\n", obj.Peer.Pretty(), obj.Allocator, obj.Informer, txt)
+	fmt.Println("  Metrics:")
+	for _, m := range obj.Metrics {
+		fmt.Printf("    > %-15s : %s\n", m.Peer.Pretty(), m.Value)
+	}
+	fmt.Println("  Allocations:")
+	for _, p := range obj.Allocations {
+		fmt.Printf("    > %s\n", p.Pretty())
+	}
+	if len(obj.Rejected) > 0 {
+		fmt.Println("  Rejected:")
+		for _, r := range obj.Rejected {
+			fmt.Printf("    > %-15s : %s\n", r.Peer.Pretty(), r.Reason)
+		}
+	}
 }
 
 func textFormatPrintPInfo(obj *api.PinInfo) {
@@ -166,6 +337,17 @@ func textFormatPrintVersion(obj *api.Version) {
 	fmt.Println(obj.Version)
 }
 
+func textFormatPrintPinDetails(obj *api.PinDetails) {
+	textFormatPrintPin(obj.Pin)
+	if obj.Pin.CreatedBy != nil {
+		fmt.Printf("Created by: %s\n", obj.Pin.CreatedBy)
+	}
+	if obj.Pin.Provenance != nil {
+		fmt.Printf("Last submitted by: %s\n", obj.Pin.Provenance)
+	}
+	textFormatPrintGPInfo(obj.Status)
+}
+
 func textFormatPrintPin(obj *api.Pin) {
 	fmt.Printf("%s | %s | %s | ", obj.Cid, obj.Name, strings.ToUpper(obj.Type.String()))
 
@@ -188,10 +370,30 @@ func textFormatPrintPin(obj *api.Pin) {
 		recStr = fmt.Sprintf("Recursive-%d", obj.MaxDepth)
 	}
 
-	fmt.Printf(" | %s\n", recStr)
+	fmt.Printf(" | %s", recStr)
+	if obj.Size > 0 {
+		fmt.Printf(" | Size: %s", humanize.Bytes(obj.Size))
+	}
+	if obj.IsScheduledForRemoval() {
+		fmt.Printf(" | Remove at: %s", obj.RemoveAt.UTC().Format(time.RFC3339))
+	}
+	if obj.IsScheduled() {
+		fmt.Printf(" | Scheduled at: %s", obj.ScheduleAt.UTC().Format(time.RFC3339))
+	}
+	if obj.Recurrence != "" {
+		fmt.Printf(" | Recurrence: %s (%s)", obj.Recurrence, obj.RecurrencePath)
+	}
+	if !obj.CreatedAt.IsZero() {
+		fmt.Printf(" | Created: %s", obj.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Printf("\n")
 }
 
 func textFormatPrintAddedOutput(obj *api.AddedOutput) {
+	if obj.Manifest != nil {
+		fmt.Printf("manifest %s %s\n", obj.Manifest, obj.Name)
+		return
+	}
 	fmt.Printf("added %s %s\n", obj.Cid, obj.Name)
 }
 
@@ -208,6 +410,339 @@ func textFormatPrintMetric(obj *api.Metric) {
 	fmt.Printf("%s: %s | Expire: %s\n", peer.IDB58Encode(obj.Peer), obj.Value, date)
 }
 
+func textFormatPrintRepoGC(obj *api.RepoGC) {
+	fmt.Printf("%s | %s | Removed: %d blocks", obj.Peer.Pretty(), obj.Peername, obj.Removed)
+	if obj.Error != "" {
+		fmt.Printf(" | ERROR: %s", obj.Error)
+	}
+	fmt.Printf("\n")
+}
+
+func textFormatPrintStateSync(obj *api.StateSync) {
+	fmt.Printf("%s | %s | ", obj.Peer.Pretty(), obj.PeerName)
+	if obj.Start.IsZero() {
+		if obj.Error != "" {
+			fmt.Printf("ERROR: %s\n", obj.Error)
+			return
+		}
+		fmt.Printf("Never synced\n")
+		return
+	}
+	fmt.Printf(
+		"Last sync: %s (took %s) | Changed: %d",
+		obj.Start.UTC().Format(time.RFC3339),
+		obj.End.Sub(obj.Start),
+		obj.Changed,
+	)
+	if obj.Error != "" {
+		fmt.Printf(" | ERROR: %s", obj.Error)
+	}
+	fmt.Printf("\n")
+}
+
+func textFormatPrintAuditRecord(obj *api.AuditRecord) {
+	fmt.Printf(
+		"%s | %s | %s | %s",
+		obj.Time.UTC().Format(time.RFC3339),
+		obj.Peer.Pretty(),
+		obj.Operation,
+		strings.ToUpper(obj.Outcome),
+	)
+	if obj.Details != "" {
+		fmt.Printf(" | %s", obj.Details)
+	}
+	fmt.Printf("\n")
+}
+
+func textFormatPrintPingResult(obj *api.PingResult) {
+	if obj.Error != "" {
+		fmt.Printf("%s | ERROR: %s\n", obj.Peer.Pretty(), obj.Error)
+		return
+	}
+	fmt.Printf(
+		"%s | RTT: %s | Clock skew: %s\n",
+		obj.Peer.Pretty(),
+		obj.RTT,
+		obj.ClockSkew,
+	)
+}
+
+func textFormatPrintBenchReport(obj *benchReport) {
+	fmt.Printf("Duration: %s | Workers: %d\n", obj.Duration.Round(time.Millisecond), obj.Concurrency)
+	fmt.Printf(
+		"%-8s %8s %8s %10s %10s %10s %10s %10s %10s %10s\n",
+		"op", "count", "errors", "ops/s", "min", "mean", "p50", "p90", "p99", "max",
+	)
+	round := func(d time.Duration) time.Duration {
+		return d.Round(10 * time.Microsecond)
+	}
+	for _, st := range obj.Operations {
+		fmt.Printf(
+			"%-8s %8d %8d %10.2f %10s %10s %10s %10s %10s %10s\n",
+			st.Op,
+			st.Count,
+			st.Errors,
+			st.Throughput,
+			round(st.Min),
+			round(st.Mean),
+			round(st.P50),
+			round(st.P90),
+			round(st.P99),
+			round(st.Max),
+		)
+	}
+	for _, st := range obj.Operations {
+		if st.LastError != "" {
+			fmt.Printf("last %s error: %s\n", st.Op, st.LastError)
+		}
+	}
+}
+
+func textFormatPrintKnownPeer(obj *api.KnownPeer) {
+	switch {
+	case obj.Connected:
+		fmt.Printf("%s | connected\n", obj.ID.Pretty())
+	case obj.LastSeen.IsZero():
+		fmt.Printf("%s | never seen\n", obj.ID.Pretty())
+	default:
+		fmt.Printf("%s | last seen %s ago\n", obj.ID.Pretty(), time.Since(obj.LastSeen).Round(time.Second))
+	}
+	for _, a := range obj.Addresses {
+		fmt.Printf("  > %s\n", a.Value())
+	}
+}
+
+func textFormatPrintPeerVersion(obj *api.PeerVersion) {
+	fmt.Printf("%s | %s | ", obj.Peer.Pretty(), obj.Peername)
+	if obj.Version != "" {
+		fmt.Printf(
+			"%s | RPC: %s | State: %d | ",
+			obj.Version,
+			obj.RPCProtocolVersion,
+			obj.StateVersion,
+		)
+	}
+	if obj.Compatible {
+		fmt.Printf("OK\n")
+		return
+	}
+	fmt.Printf("INCOMPATIBLE: %s\n", obj.Error)
+}
+
+func textFormatPrintRuntimeStats(obj *api.RuntimeStats) {
+	fmt.Printf("%s | %s | ", obj.Peer.Pretty(), obj.Peername)
+	if obj.Error != "" {
+		fmt.Printf("ERROR: %s\n", obj.Error)
+		return
+	}
+	fds := "unknown"
+	if obj.OpenFDs >= 0 {
+		fds = fmt.Sprintf("%d", obj.OpenFDs)
+	}
+	fmt.Printf(
+		"Goroutines: %d | Heap: %s / %s | GCs: %d (last pause %s) | Open FDs: %s\n",
+		obj.Goroutines,
+		humanize.Bytes(obj.HeapAlloc),
+		humanize.Bytes(obj.HeapSys),
+		obj.NumGC,
+		obj.LastGCPause,
+		fds,
+	)
+}
+
+func textFormatPrintStatusSummary(obj *api.StatusSummary) {
+	for _, p := range obj.Peers {
+		fmt.Printf("%s | %s | ", p.Peer.Pretty(), p.Peername)
+		if p.Error != "" {
+			fmt.Printf("ERROR: %s\n", p.Error)
+			continue
+		}
+		fmt.Println(formatStatusCounts(p.Counts))
+	}
+	fmt.Printf("Total | %s\n", formatStatusCounts(obj.Totals))
+}
+
+func formatStatusCounts(sc api.StatusCounts) string {
+	return fmt.Sprintf(
+		"Pinned: %d | Pinning: %d | Unpinning: %d | Queued: %d | Error: %d | Remote: %d",
+		sc.Pinned,
+		sc.Pinning,
+		sc.Unpinning,
+		sc.Queued,
+		sc.Error,
+		sc.Remote,
+	)
+}
+
+func textFormatPrintStorageUsage(obj *api.StorageUsage) {
+	fmt.Printf("Pins: %d (sampled: %d, failed: %d)\n", obj.Pins, obj.SampledPins, obj.FailedPins)
+	fmt.Printf("Unique blocks: %d\n", obj.UniqueBlocks)
+	fmt.Printf("Unique size: %s\n", humanize.Bytes(obj.UniqueBytes))
+	fmt.Printf("Replicated size: %s\n", humanize.Bytes(obj.ReplicatedBytes))
+	if obj.SampledPins < obj.Pins {
+		fmt.Println("Totals are estimated from the sampled pins.")
+	}
+}
+
+func textFormatPrintIPFSDrift(obj *api.IPFSDrift) {
+	for _, d := range obj.Daemons {
+		if d.Error != "" {
+			fmt.Printf("%s | %s | ERROR: %s\n", d.Peer.Pretty(), d.Peername, d.Error)
+			continue
+		}
+		fmt.Printf(
+			"%s | %s | IPFS %s | Routing: %s | StorageMax: %s\n",
+			d.Peer.Pretty(),
+			d.Peername,
+			d.Version,
+			d.Routing,
+			d.StorageMax,
+		)
+	}
+	for _, w := range obj.Warnings {
+		fmt.Printf("WARNING: %s\n", w)
+	}
+	if obj.Dangerous {
+		fmt.Println("The IPFS daemons are dangerously mismatched.")
+	}
+}
+
+func textFormatPrintClusterInfo(obj *api.ClusterInfo) {
+	fmt.Printf("Peers: %d (unreachable: %d)\n", obj.Peers, obj.UnreachablePeers)
+	fmt.Printf("Pins: %d\n", obj.Pins)
+	fmt.Printf("Pin errors: %d\n", obj.PinErrors)
+	fmt.Printf("Free space: %s\n", humanize.Bytes(obj.FreeSpace))
+	fmt.Printf("Consensus: %s", obj.Consensus)
+	if obj.Leader != "" {
+		fmt.Printf(" (leader: %s)", obj.Leader.Pretty())
+	}
+	fmt.Println()
+	fmt.Printf("Oldest version: %s\n", obj.OldestVersion)
+	fmt.Printf("Updated: %s\n", obj.UpdatedAt.UTC().Format(time.RFC3339))
+}
+
+func textFormatPrintRecoveryJob(obj *api.RecoveryJob) {
+	fmt.Printf("%s | %s | recovered: %d/%d | failed: %d\n", obj.Peer.Pretty(), obj.State, obj.Recovered, obj.Total, obj.Failed)
+	fmt.Printf("Started: %s\n", obj.Started.UTC().Format(time.RFC3339))
+	if !obj.Finished.IsZero() {
+		fmt.Printf("Finished: %s\n", obj.Finished.UTC().Format(time.RFC3339))
+	}
+	if obj.Error != "" {
+		fmt.Printf("ERROR: %s\n", obj.Error)
+	}
+	for _, e := range obj.Errors {
+		fmt.Printf("  - %s\n", e)
+	}
+}
+
+func textFormatPrintReconnectStatus(obj *api.ReconnectStatus) {
+	fmt.Printf("%s | %s | connected: %d/%d\n", obj.Peer.Pretty(), obj.State, obj.ConnectedPeers, obj.KnownPeers)
+	if !obj.LastConnected.IsZero() {
+		fmt.Printf("Last connected: %s\n", obj.LastConnected.UTC().Format(time.RFC3339))
+	}
+	if obj.State != api.ReconnectStateReconnecting {
+		return
+	}
+	fmt.Printf("Failed attempts: %d\n", obj.Attempts)
+	if obj.LastError != "" {
+		fmt.Printf("Last error: %s\n", obj.LastError)
+	}
+	fmt.Printf("Next attempt: %s\n", obj.NextAttempt.UTC().Format(time.RFC3339))
+}
+
+func textFormatPrintLogEntry(obj *api.LogEntry) {
+	fmt.Printf(
+		"%s %-8s %s: %s\n",
+		obj.Time.Format("2006-01-02 15:04:05.000"),
+		strings.ToUpper(obj.Level),
+		obj.Component,
+		obj.Message,
+	)
+}
+
+func textFormatPrintJoinToken(obj *api.JoinToken) {
+	fmt.Printf("%s\n", obj.Token)
+	fmt.Printf("Expires: %s\n", obj.Expires.UTC().Format(time.RFC3339))
+}
+
+func textFormatPrintConnectionFilter(obj *api.ConnectionFilter) {
+	for _, p := range obj.Peers {
+		fmt.Printf("%s\n", p.Pretty())
+	}
+	for _, c := range obj.CIDRs {
+		fmt.Printf("%s\n", c)
+	}
+}
+
+func textFormatPrintSnapshot(obj *api.Snapshot) {
+	fmt.Printf(
+		"%s | %d pins | Created: %s\n",
+		obj.Name,
+		obj.Pins,
+		obj.CreatedAt.UTC().Format(time.RFC3339),
+	)
+}
+
+func textFormatPrintSnapshotDiff(obj *api.SnapshotDiff) {
+	for _, pin := range obj.Pin {
+		fmt.Printf("+ %s | %s\n", pin.Cid, pin.Name)
+	}
+	for _, pin := range obj.Unpin {
+		fmt.Printf("- %s | %s\n", pin.Cid, pin.Name)
+	}
+	fmt.Printf("%s -> %s: %d to pin, %d to unpin\n", obj.From, obj.To, len(obj.Pin), len(obj.Unpin))
+}
+
+func textFormatPrintSnapshotRestore(obj *api.SnapshotRestore) {
+	for _, c := range obj.Pinned {
+		fmt.Printf("pinned %s\n", c)
+	}
+	for _, c := range obj.Unpinned {
+		fmt.Printf("unpinned %s\n", c)
+	}
+	for _, e := range obj.Errors {
+		fmt.Printf("ERROR: %s\n", e)
+	}
+	fmt.Printf(
+		"Restored snapshot %s: %d pinned, %d unpinned, %d errors\n",
+		obj.Name,
+		len(obj.Pinned),
+		len(obj.Unpinned),
+		len(obj.Errors),
+	)
+}
+
+func textFormatPrintFaultRule(obj *api.FaultRule) {
+	method := obj.Method
+	if method == "" {
+		method = "*"
+	}
+	fmt.Printf("%s %s: %.2f%%", obj.Target, method, obj.Percent)
+	if obj.Delay > 0 {
+		fmt.Printf(" | Delay: %s", obj.Delay)
+	}
+	if obj.Error != "" {
+		fmt.Printf(" | Error: %s", obj.Error)
+	}
+	fmt.Printf("\n")
+}
+
+func textFormatPrintProvideResult(obj *api.ProvideResult) {
+	if obj.Error != "" {
+		fmt.Printf("%s | ERROR: %s\n", obj.Peer.Pretty(), obj.Error)
+		return
+	}
+	fmt.Printf("%s | PROVIDED\n", obj.Peer.Pretty())
+}
+
+func textFormatPrintSavedCluster(obj *savedCluster) {
+	mark := " "
+	if obj.Default {
+		mark = "*"
+	}
+	fmt.Printf("%s %s | %s\n", mark, obj.Name, obj.Host)
+}
+
 func textFormatPrintError(obj *api.Error) {
 	fmt.Printf("An error occurred:\n")
 	fmt.Printf("  Code: %d\n", obj.Code)
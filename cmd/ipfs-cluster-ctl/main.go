@@ -3,23 +3,23 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	uuid "github.com/google/uuid"
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/api/graphviz"
 	"github.com/ipfs/ipfs-cluster/api/rest/client"
 
 	cid "github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log"
 	peer "github.com/libp2p/go-libp2p-peer"
-	ma "github.com/multiformats/go-multiaddr"
 	cli "github.com/urfave/cli"
 
 	"contrib.go.opencensus.io/exporter/jaeger"
@@ -56,7 +56,8 @@ specific one.
 %s uses the IPFS Cluster API to perform requests and display
 responses in a user-readable format. The location of the IPFS
 Cluster server is assumed to be %s, but can be
-configured with the --host option. To use the secure libp2p-http
+configured with the --host option, or saved under a name and
+selected with --cluster (see "%s clusters"). To use the secure libp2p-http
 API endpoint, use "--host" with the full cluster libp2p listener
 address (including the "/ipfs/<peerID>" part), and --secret (the
 32-byte cluster secret as it appears in the cluster configuration).
@@ -68,7 +69,8 @@ https://github.com/ipfs/ipfs-cluster.
 	programName,
 	programName,
 	programName,
-	defaultHost)
+	defaultHost,
+	programName)
 
 type peerAddBody struct {
 	Addr string `json:"peer_multiaddress"`
@@ -132,54 +134,45 @@ func main() {
 requires authorization. implies --https, which you can disable with --force-http`,
 			EnvVar: "CLUSTER_CREDENTIALS",
 		},
+		cli.StringFlag{
+			Name: "token",
+			Usage: `<token> specify a bearer token (i.e. an OpenID Connect ID token) for servers
+that require it. implies --https, which you can disable with --force-http`,
+			EnvVar: "CLUSTER_TOKEN",
+		},
 		cli.BoolFlag{
 			Name:  "force-http, f",
-			Usage: "force HTTP. only valid when using BasicAuth",
+			Usage: "force HTTP. only valid when using BasicAuth or a token",
+		},
+		cli.StringFlag{
+			Name:   "cluster",
+			Usage:  "contact the saved cluster with this name (see \"clusters\")",
+			EnvVar: "CLUSTER_CTL_CLUSTER",
+		},
+		cli.BoolFlag{
+			Name:  "all-clusters",
+			Usage: "run a read-only command against all the saved clusters",
+		},
+		cli.StringFlag{
+			Name:   "clusters-file",
+			Value:  defaultClustersPath(),
+			Usage:  "file where the endpoints of the saved clusters are kept",
+			EnvVar: "CLUSTER_CTL_CLUSTERS_FILE",
 		},
 	}
 
 	app.Before = func(c *cli.Context) error {
-		cfg := &client.Config{}
-
 		if c.Bool("debug") {
 			logging.SetLogLevel("cluster-ctl", "debug")
-			cfg.LogLevel = "debug"
 			logger.Debug("debug level enabled")
 		}
 
-		addr, err := ma.NewMultiaddr(c.String("host"))
-		checkErr("parsing host multiaddress", err)
-
-		cfg.APIAddr = addr
-		if hexSecret := c.String("secret"); hexSecret != "" {
-			secret, err := hex.DecodeString(hexSecret)
-			checkErr("parsing secret", err)
-			cfg.ProtectorKey = secret
-		}
-
-		cfg.Timeout = time.Duration(c.Int("timeout")) * time.Second
-
-		if client.IsPeerAddress(cfg.APIAddr) && c.Bool("https") {
-			logger.Warning("Using libp2p-http. SSL flags will be ignored")
-		}
-
-		cfg.SSL = c.Bool("https")
-		cfg.NoVerifyCert = c.Bool("no-check-certificate")
-		user, pass := parseCredentials(c.String("basic-auth"))
-		cfg.Username = user
-		cfg.Password = pass
-		if user != "" && !cfg.SSL && !c.Bool("force-http") {
-			logger.Warning("SSL automatically enabled with basic auth credentials. Set \"force-http\" to disable")
-			cfg.SSL = true
-		}
-
 		enc := c.String("encoding")
 		if enc != "text" && enc != "json" {
 			checkErr("", errors.New("unsupported encoding"))
 		}
 
-		globalClient, err = client.NewDefaultClient(cfg)
-		checkErr("creating API client", err)
+		setupClients(c)
 
 		// TODO: need to figure out best way to configure tracing for ctl
 		// leaving the following as it is still useful for local debugging.
@@ -223,6 +216,11 @@ This command displays information about the peer that the tool is contacting
 					Usage: "list the nodes participating in the IPFS Cluster",
 					Description: `
 This command provides a list of the ID information of all the peers in the Cluster.
+
+Along with it, the health of every peer is shown: the cluster version it runs,
+the free space in its IPFS repository, the number of operations in its pin
+queue, the number of items in error state and how long ago the contacted peer
+received its last heartbeat.
 `,
 					Flags:     []cli.Flag{},
 					ArgsUsage: " ",
@@ -240,18 +238,212 @@ This command removes a peer from the cluster. If the peer is online, it will
 automatically shut down. All other cluster peers should be online for the
 operation to succeed, otherwise some nodes may be left with an outdated list of
 cluster peers.
+
+When the --migrate flag is passed, the pins allocated to the peer are first
+re-allocated to other peers, and the peer is only removed once they have been
+pinned by their new allocations. Progress is reported as pins finish
+migrating. If some pins cannot be re-allocated or pinned, or the
+--migrate-timeout expires, the peer is not removed.
 `,
 					ArgsUsage: "<peer ID>",
-					Flags:     []cli.Flag{},
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "migrate",
+							Usage: "re-allocate the pins of the peer and wait for them before removing it",
+						},
+						cli.DurationFlag{
+							Name:  "migrate-timeout",
+							Value: 0,
+							Usage: "how long to wait for the migration, default is indefinitely",
+						},
+					},
 					Action: func(c *cli.Context) error {
 						pid := c.Args().First()
 						p, err := peer.IDB58Decode(pid)
 						checkErr("parsing peer ID", err)
+						if c.Bool("migrate") {
+							err := migratePeer(ctx, p, c.Duration("migrate-timeout"))
+							checkErr("migrating pins", err)
+						}
 						cerr := globalClient.PeerRm(ctx, p)
 						formatResponse(c, nil, cerr)
 						return nil
 					},
 				},
+				{
+					Name:  "token",
+					Usage: "manage join tokens",
+					Subcommands: []cli.Command{
+						{
+							Name:  "create",
+							Usage: "create a token for a new peer to join the Cluster",
+							Description: `
+This command creates a join token. A new peer can present it with
+"ipfs-cluster-service daemon --join-token <token>" to bootstrap to the
+cluster through the peer that created it, without manually copying the
+cluster secret and the peer multiaddresses.
+
+The token is signed by the peer that creates it and is only accepted until
+it expires. Note that it carries the cluster secret: anyone obtaining it
+before it expires is able to join the cluster.
+`,
+							ArgsUsage: " ",
+							Flags: []cli.Flag{
+								cli.DurationFlag{
+									Name:  "ttl",
+									Value: time.Hour,
+									Usage: "how long the token is valid",
+								},
+							},
+							Action: func(c *cli.Context) error {
+								resp, cerr := globalClient.JoinToken(ctx, c.Duration("ttl"))
+								formatResponse(c, resp, cerr)
+								return nil
+							},
+						},
+					},
+				},
+				{
+					Name:  "peerstore",
+					Usage: "list and seed known peer addresses",
+					Description: `
+These commands manage the addresses that the peer that the tool is contacting
+knows for other peers. They are persisted and restored on restarts until the
+peer has not been seen for longer than the peerstore_ttl configuration
+option.
+`,
+					Subcommands: []cli.Command{
+						{
+							Name:      "ls",
+							Usage:     "list known peers and their addresses",
+							ArgsUsage: " ",
+							Action: func(c *cli.Context) error {
+								resp, cerr := globalClient.Peerstore(ctx)
+								formatResponse(c, resp, cerr)
+								return nil
+							},
+						},
+						{
+							Name:      "add",
+							Usage:     "add peer addresses",
+							ArgsUsage: "<multiaddress/p2p/peerID>...",
+							Action: func(c *cli.Context) error {
+								var addrs []api.Multiaddr
+								for _, a := range c.Args() {
+									addr, err := api.NewMultiaddr(a)
+									checkErr("parsing multiaddress", err)
+									addrs = append(addrs, addr)
+								}
+								resp, cerr := globalClient.PeerstoreAdd(ctx, addrs)
+								formatResponse(c, resp, cerr)
+								return nil
+							},
+						},
+					},
+				},
+				{
+					Name:  "deny",
+					Usage: "manage the connection deny list",
+					Description: `
+These commands manage the peers and IP ranges which are not allowed to
+connect to the peer that the tool is contacting. Existing connections are
+closed when they are denied. The deny list is stored in the peer's
+configuration and survives restarts.
+`,
+					Subcommands: []cli.Command{
+						{
+							Name:      "ls",
+							Usage:     "list denied peers and IP ranges",
+							ArgsUsage: " ",
+							Action: func(c *cli.Context) error {
+								resp, cerr := globalClient.ConnectionDenyList(ctx)
+								formatResponse(c, resp, cerr)
+								return nil
+							},
+						},
+						{
+							Name:      "add",
+							Usage:     "deny connections from peers or IP ranges",
+							ArgsUsage: "<peer ID|CIDR>...",
+							Action: func(c *cli.Context) error {
+								cerr := globalClient.ConnectionDeny(ctx, parseConnectionFilter(c.Args()))
+								formatResponse(c, nil, cerr)
+								return nil
+							},
+						},
+						{
+							Name:      "rm",
+							Usage:     "allow connections from denied peers or IP ranges again",
+							ArgsUsage: "<peer ID|CIDR>...",
+							Action: func(c *cli.Context) error {
+								cerr := globalClient.ConnectionUndeny(ctx, parseConnectionFilter(c.Args()))
+								formatResponse(c, nil, cerr)
+								return nil
+							},
+						},
+					},
+				},
+				{
+					Name:  "allocatable",
+					Usage: "set whether a peer accepts new allocations",
+					Description: `
+This command sets whether a peer is a candidate for new allocations. Peers
+which are not allocatable stay in the cluster and keep pinning the content
+already allocated to them, but are not chosen to hold new pins. The setting
+is stored in the peer's configuration and survives restarts.
+
+The current value for every peer is shown by "peers ls".
+`,
+					ArgsUsage: "<peer ID> <true|false>",
+					Flags:     []cli.Flag{},
+					Action: func(c *cli.Context) error {
+						pid := c.Args().Get(0)
+						p, err := peer.IDB58Decode(pid)
+						checkErr("parsing peer ID", err)
+						allocatable, err := strconv.ParseBool(c.Args().Get(1))
+						checkErr("parsing allocatable value", err)
+						cerr := globalClient.SetAllocatable(ctx, p, allocatable)
+						formatResponse(c, nil, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "pause",
+					Usage: "pause the pin tracker of a peer",
+					Description: `
+This command pauses the pin tracker of a peer: it stops starting the pin and
+unpin operations in its queue, i.e. while its IPFS daemon is garbage-collected
+or upgraded, without shutting down the peer. Operations in progress finish
+and new ones are still queued, and shown as paused by "status". The tracker
+is resumed with "peers resume" or when the peer restarts.
+
+Whether every peer is paused is shown by "peers ls".
+`,
+					ArgsUsage: "<peer ID>",
+					Action: func(c *cli.Context) error {
+						p, err := peer.IDB58Decode(c.Args().First())
+						checkErr("parsing peer ID", err)
+						cerr := globalClient.SetTrackerPaused(ctx, p, true)
+						formatResponse(c, nil, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "resume",
+					Usage: "resume the pin tracker of a peer",
+					Description: `
+This command lets the pin tracker of a peer paused with "peers pause" start
+the operations in its queue again.
+`,
+					ArgsUsage: "<peer ID>",
+					Action: func(c *cli.Context) error {
+						p, err := peer.IDB58Decode(c.Args().First())
+						checkErr("parsing peer ID", err)
+						cerr := globalClient.SetTrackerPaused(ctx, p, false)
+						formatResponse(c, nil, cerr)
+						return nil
+					},
+				},
 			},
 		},
 		{
@@ -353,6 +545,14 @@ cluster "pin add".
 					Name:  "nocopy",
 					Usage: "Add the URL using filestore. Implies raw-leaves. (experimental)",
 				},
+				cli.BoolFlag{
+					Name:  "manifest",
+					Usage: "Add and pin a manifest listing the CIDs of all added files. Cannot be used along sharding",
+				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "Use an add profile defined in the cluster API configuration. Its options override those given here",
+				},
 				// TODO: Uncomment when sharding is supported.
 				// cli.BoolFlag{
 				//	Name:  "shard",
@@ -418,6 +618,8 @@ cluster "pin add".
 				if p.NoCopy {
 					p.RawLeaves = true
 				}
+				p.Manifest = c.Bool("manifest")
+				p.Profile = c.String("profile")
 
 				out := make(chan *api.AddedOutput, 1)
 				var wg sync.WaitGroup
@@ -490,8 +692,29 @@ An optional allocations argument can be provided, allocations should be a
 comma-separated list of peer IDs on which we want to pin. Peers in allocations
 are prioritized over automatically-determined ones, but replication factors
 would stil be respected.
+
+By default, the whole DAG is pinned recursively. The --max-depth option
+limits how deep the DAG is pinned: 0 pins only the root block (a direct pin)
+and positive values pin the given number of levels below it.
+
+The --schedule-at option defers the pin until the given time (RFC3339). The
+CID is part of the state right away, but it is only allocated and pinned by
+the cluster peers once the time comes.
+
+The --recurrence option takes a cron expression (i.e. "0 3 * * *" or
+"@daily") on which the given path (usually an /ipns/ path) is resolved
+again. When it points to a new CID, the new CID is pinned with the same
+options and the previous one is unpinned.
+
+The --priority option lets small or urgent pins go ahead of the pins queued
+before them on peers using the stateless pintracker: operations with a higher
+priority are processed first (default: 0).
+
+The --pin-timeout option cancels the pin operations which take longer than
+the given duration (i.e. "30m") on a peer and sets them in error. By default,
+the timeout configured in the pintracker of each peer applies.
 `,
-					ArgsUsage: "<CID>",
+					ArgsUsage: "<CID|Path>",
 					Flags: []cli.Flag{
 						cli.IntFlag{
 							Name:  "replication, r",
@@ -517,6 +740,29 @@ would stil be respected.
 							Value: "",
 							Usage: "Sets a name for this pin",
 						},
+						cli.IntFlag{
+							Name:  "max-depth",
+							Value: -1,
+							Usage: "Limits how deep the DAG is pinned (-1: recursive, 0: direct)",
+						},
+						cli.StringFlag{
+							Name:  "schedule-at",
+							Usage: "Defers the pin until the given time (RFC3339)",
+						},
+						cli.StringFlag{
+							Name:  "recurrence",
+							Usage: "Resolves the path again on the given cron schedule",
+						},
+						cli.IntFlag{
+							Name:  "priority",
+							Value: 0,
+							Usage: "Sets the priority of the pin operations (higher go first)",
+						},
+						cli.DurationFlag{
+							Name:  "pin-timeout",
+							Value: 0,
+							Usage: "Cancels the pin operations which take longer than this on a peer",
+						},
 						cli.BoolFlag{
 							Name:  "no-status, ns",
 							Usage: "Prevents fetching pin status after pinning (faster, quieter)",
@@ -552,6 +798,18 @@ would stil be respected.
 							Name:                 c.String("name"),
 							UserAllocations:      userAllocs,
 						}
+						if c.IsSet("max-depth") {
+							depth := c.Int("max-depth")
+							opts.Depth = &depth
+						}
+						if at := c.String("schedule-at"); at != "" {
+							t, err := time.Parse(time.RFC3339, at)
+							checkErr("parsing schedule-at", err)
+							opts.ScheduleAt = t
+						}
+						opts.Recurrence = c.String("recurrence")
+						opts.Priority = c.Int("priority")
+						opts.PinTimeout = c.Duration("pin-timeout")
 
 						pin, cerr := globalClient.PinPath(ctx, arg, opts)
 						if cerr != nil {
@@ -577,6 +835,11 @@ trigger unpinning operations in all the IPFS nodes holding the content.
 When the request has succeeded, the command returns the status of the CID
 in the cluster. The CID should disappear from the list offered by "pin ls",
 although unpinning operations in the cluster may take longer or fail.
+
+When the cluster has an unpin retention period configured, the CID is only
+scheduled for removal and stays pinned until the period expires. During
+this time it is shown by "pin ls" and can be restored with "pin restore".
+Removing it again removes it right away.
 `,
 					ArgsUsage: "<CID>",
 					Flags: []cli.Flag{
@@ -610,6 +873,41 @@ although unpinning operations in the cluster may take longer or fail.
 						return nil
 					},
 				},
+				{
+					Name:  "restore",
+					Usage: "Cancel the removal of an unpinned CID",
+					Description: `
+This command cancels the removal of a CID which has been unpinned while an
+unpin retention period is configured in the cluster, and which is still
+scheduled for removal. The CID stays pinned with its original options and
+allocations.
+`,
+					ArgsUsage: "<CID>",
+					Action: func(c *cli.Context) error {
+						ci, err := cid.Decode(c.Args().First())
+						checkErr("parsing cid", err)
+						resp, cerr := globalClient.RestorePin(ctx, ci)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "provide",
+					Usage: "Announce a pinned CID to the IPFS network",
+					Description: `
+This command asks the IPFS daemons of the peers allocated to a pin to
+announce its root CID to the IPFS network (DHT) right away, regardless of
+their reprovider strategy. The result for every peer is printed.
+`,
+					ArgsUsage: "<CID>",
+					Action: func(c *cli.Context) error {
+						ci, err := cid.Decode(c.Args().First())
+						checkErr("parsing cid", err)
+						resp, cerr := globalClient.Provide(ctx, ci)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
 				{
 					Name:  "ls",
 					Usage: "List items in the cluster pinset",
@@ -620,6 +918,10 @@ any monitoring information about the IPFS status of the CIDs, it
 merely represents the list of pins which are part of the shared state of
 the cluster. For IPFS-status information about the pins, use "status".
 
+The --since option only lists the pins which were added to the cluster at
+or after the given time. It takes an RFC3339 timestamp or a duration
+(i.e. "168h" for the last week). Results are then sorted by creation time.
+
 The filter only takes effect when listing all pins. The possible values are:
   - all
   - pin
@@ -634,6 +936,10 @@ The filter only takes effect when listing all pins. The possible values are:
 							Usage: "Comma separated list of pin types. See help above.",
 							Value: "pin",
 						},
+						cli.StringFlag{
+							Name:  "since",
+							Usage: "only list pins created since the given time or duration ago",
+						},
 					},
 					Action: func(c *cli.Context) error {
 						cidStr := c.Args().First()
@@ -649,12 +955,131 @@ The filter only takes effect when listing all pins. The possible values are:
 								filter |= api.PinTypeFromString(f)
 							}
 
+							if sinceStr := c.String("since"); sinceStr != "" {
+								since, err := parseSince(sinceStr)
+								checkErr("parsing since", err)
+								resp, cerr := globalClient.AllocationsSince(ctx, filter, since)
+								formatResponse(c, resp, cerr)
+								return nil
+							}
+
 							resp, cerr := globalClient.Allocations(ctx, filter)
 							formatResponse(c, resp, cerr)
 						}
 						return nil
 					},
 				},
+				{
+					Name:  "details",
+					Usage: "Show a pin together with its status",
+					Description: `
+This command shows the item from the cluster pinset for the given CID
+(as "pin ls" does) along with its current status in every cluster peer
+(as "status" does), using a single request.
+`,
+					ArgsUsage: "<CID>",
+					Action: func(c *cli.Context) error {
+						ci, err := cid.Decode(c.Args().First())
+						checkErr("parsing cid", err)
+						resp, cerr := globalClient.PinDetails(ctx, ci)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:  "snapshot",
+			Usage: "Manage pinset snapshots",
+			Description: `
+These commands manage named snapshots of the cluster pinset, which can be
+taken before risky batch changes and used to restore the pinset later.
+Snapshots are stored by the peer that the tool is contacting.
+`,
+			Subcommands: []cli.Command{
+				{
+					Name:      "create",
+					Usage:     "take a snapshot of the pinset",
+					ArgsUsage: "<name>",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.SnapshotCreate(ctx, c.Args().First())
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:      "ls",
+					Usage:     "list the stored snapshots",
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.Snapshots(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "diff",
+					Usage: "compare two snapshots",
+					Description: `
+This command lists the pins to pin (+) and to unpin (-) to go from the first
+snapshot to the second one. Use "current" for the current pinset. When only
+one snapshot is given, it is compared to the current pinset.
+`,
+					ArgsUsage: "<from> [to]",
+					Action: func(c *cli.Context) error {
+						to := c.Args().Get(1)
+						if to == "" {
+							to = api.SnapshotCurrent
+						}
+						resp, cerr := globalClient.SnapshotDiff(ctx, c.Args().First(), to)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "restore",
+					Usage: "restore the pinset to a snapshot",
+					Description: `
+This command pins and unpins what is needed for the pinset to match the given
+snapshot. Pins present in both keep their allocations. Use "snapshot diff"
+first to review the changes.
+
+Restores which would unpin more than the share of the pinset allowed by the
+peer configuration are refused unless --force is given. When the peer
+requires confirmations, a forced restore returns a token, and the restore
+only happens when run again with --confirm <token>.
+`,
+					ArgsUsage: "<name>",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "force",
+							Usage: "allow unpinning many items at once",
+						},
+						cli.StringFlag{
+							Name:  "confirm",
+							Usage: "confirmation token returned by a forced restore",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						opts := api.BulkUnpinOptions{
+							Force:   c.Bool("force") || c.String("confirm") != "",
+							Confirm: c.String("confirm"),
+						}
+						resp, cerr := globalClient.SnapshotRestore(ctx, c.Args().First(), opts)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:      "rm",
+					Usage:     "remove a snapshot",
+					ArgsUsage: "<name>",
+					Action: func(c *cli.Context) error {
+						cerr := globalClient.SnapshotDelete(ctx, c.Args().First())
+						formatResponse(c, nil, cerr)
+						return nil
+					},
+				},
 			},
 		},
 		{
@@ -674,9 +1099,20 @@ contacted cluster peer. By default, status will be fetched from all peers.
 
 When the --filter flag is passed, it will only fetch the peer information
 where status of the pin matches at least one of the filter values (a comma
-separated list). The following are valid status values:
+separated list). Filtering happens on every peer, so leaving "remote" out
+of the filter is a cheap way of obtaining only the status of the peers
+which are allocated to each item. The following are valid status values:
+
+` + trackerStatusAllString() + `
 
-` + trackerStatusAllString(),
+When the --explain flag is passed along with a CID, the response includes
+which allocator decided the allocations for it, the metrics that were used
+and which peers were not chosen and why.
+
+When the --summary flag is passed, only the number of items in each status is
+shown, for every peer and in total. Each peer counts its own items, which makes
+this much cheaper than fetching the full status list.
+`,
 			ArgsUsage: "[CID]",
 			Flags: []cli.Flag{
 				localFlag(),
@@ -684,20 +1120,37 @@ separated list). The following are valid status values:
 					Name:  "filter",
 					Usage: "comma-separated list of filters",
 				},
+				cli.BoolFlag{
+					Name:  "explain",
+					Usage: "include allocation decisions for the given CID",
+				},
+				cli.BoolFlag{
+					Name:  "summary",
+					Usage: "only show the number of items in each status",
+				},
 			},
 			Action: func(c *cli.Context) error {
+				if c.Bool("summary") {
+					resp, cerr := globalClient.StatusSummary(ctx, c.Bool("local"))
+					formatResponse(c, resp, cerr)
+					return nil
+				}
+
 				cidStr := c.Args().First()
 				if cidStr != "" {
 					ci, err := cid.Decode(cidStr)
 					checkErr("parsing cid", err)
-					resp, cerr := globalClient.Status(ctx, ci, c.Bool("local"))
+					var resp *api.GlobalPinInfo
+					var cerr error
+					if c.Bool("explain") {
+						resp, cerr = globalClient.StatusExplain(ctx, ci, c.Bool("local"))
+					} else {
+						resp, cerr = globalClient.Status(ctx, ci, c.Bool("local"))
+					}
 					formatResponse(c, resp, cerr)
 				} else {
-					filterFlag := c.String("filter")
-					filter := api.TrackerStatusFromString(c.String("filter"))
-					if filter == api.TrackerStatusUndefined && filterFlag != "" {
-						checkErr("parsing filter flag", errors.New("invalid filter name"))
-					}
+					filter, err := api.TrackerStatusFilterFromString(c.String("filter"))
+					checkErr("parsing filter flag", err)
 					resp, cerr := globalClient.StatusAll(ctx, filter, c.Bool("local"))
 					formatResponse(c, resp, cerr)
 				}
@@ -748,31 +1201,118 @@ This command asks Cluster peers to re-track or re-forget CIDs in
 error state, usually because the IPFS pin or unpin operation has failed.
 
 The command will wait for any operations to succeed and will return the status
-of the item upon completion. Note that, when running on the full sets of tracked
-CIDs (without argument), it may take a considerably long time.
+of the item upon completion.
+
+Without a CID argument, it starts a recovery job on the contacted peer and
+returns it. The job recovers every item in error state on any peer in the
+background, a few at a time ("recovery_concurrency" in the cluster
+configuration), starting with the items pinned by the fewest peers. Its
+progress can be followed with "health recovery", or by passing the --wait flag.
 
 When the --local flag is passed, it will only trigger recover
-operations on the contacted peer (as opposed to on every peer).
+operations on the contacted peer (as opposed to on every peer). Without a CID
+argument, these run at once and the command waits for all of them, which may
+take a considerably long time.
+
+When the --peer flag is passed, it will trigger recover operations for all
+the items allocated to the given peer, and only on that peer. This is useful
+to restore a single peer (i.e. after its IPFS repository was lost) without
+touching the rest of the cluster. It cannot be combined with a CID argument.
 `,
 			ArgsUsage: "[CID]",
 			Flags: []cli.Flag{
 				localFlag(),
+				cli.StringFlag{
+					Name:  "peer",
+					Usage: "recover all the items allocated to the given peer ID",
+				},
+				cli.BoolFlag{
+					Name:  "wait, w",
+					Usage: "wait for the recovery job to finish, reporting progress",
+				},
 			},
 			Action: func(c *cli.Context) error {
 				cidStr := c.Args().First()
-				if cidStr != "" {
+				if pidStr := c.String("peer"); pidStr != "" {
+					if cidStr != "" || c.Bool("local") {
+						checkErr("", errors.New("--peer cannot be used with a CID argument or --local"))
+					}
+					pid, err := peer.IDB58Decode(pidStr)
+					checkErr("parsing peer ID", err)
+					resp, cerr := globalClient.RecoverPeer(ctx, pid)
+					formatResponse(c, resp, cerr)
+				} else if cidStr != "" {
 					ci, err := cid.Decode(cidStr)
 					checkErr("parsing cid", err)
 					resp, cerr := globalClient.Recover(ctx, ci, c.Bool("local"))
 					formatResponse(c, resp, cerr)
+				} else if c.Bool("local") {
+					resp, cerr := globalClient.RecoverAll(ctx, true)
+					formatResponse(c, resp, cerr)
 				} else {
-					resp, cerr := globalClient.RecoverAll(ctx, c.Bool("local"))
+					resp, cerr := globalClient.RecoveryStart(ctx)
+					if cerr == nil && c.Bool("wait") {
+						resp, cerr = waitForRecovery(ctx)
+					}
 					formatResponse(c, resp, cerr)
 				}
 				return nil
 			},
 		},
+		{
+			Name:  "audit",
+			Usage: "Show the audit log of a peer",
+			Description: `
+This command shows the audit log of the contacted peer, oldest records first.
+The audit log records destructive operations which the peer has run or refused
+to run, like operations unpinning many items at once, and their outcome.
+
+Operations which would unpin more items than the threshold configured in the
+cluster must be forced. When confirmations are enabled, forcing them returns a
+token instead, and they only run when repeated with it. Every attempt is
+recorded in the audit log.
+`,
+			ArgsUsage: " ",
+			Action: func(c *cli.Context) error {
+				resp, cerr := globalClient.AuditLog(ctx)
+				formatResponse(c, resp, cerr)
+				return nil
+			},
+		},
 
+		{
+			Name:        "ipfs",
+			Usage:       "Manage the IPFS daemons of the cluster peers",
+			Description: "Manage the IPFS daemons of the cluster peers",
+			Subcommands: []cli.Command{
+				{
+					Name:  "gc",
+					Usage: "Run garbage collection on the IPFS repositories",
+					Description: `
+This command runs the garbage collector of the IPFS daemons of every cluster
+peer, removing the blocks which are not pinned, and prints the number of
+blocks removed by each of them.
+
+Unless disabled in their configuration ("repo_gc_grace_period"), peers run
+it in GC-safe mode: they postpone it while blocks are being added to their
+IPFS daemon and while pins are not pinned locally yet, so that content about
+to be pinned is not removed, and refuse it when this takes too long.
+
+When the --local flag is passed, only the IPFS daemon of the contacted peer
+is garbage collected.
+`,
+					ArgsUsage: " ",
+					Flags: []cli.Flag{
+						localFlag(),
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.RepoGC(ctx, c.Bool("local"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+			},
+		},
 		{
 			Name:  "version",
 			Usage: "Retrieve cluster version",
@@ -827,12 +1367,268 @@ graph of the connections.  Output is a dot file encoding the cluster's connectio
 							checkErr("creating output file", err)
 						}
 						defer w.Close()
-						err = makeDot(resp, w, c.Bool("all-ipfs-peers"))
+						err = graphviz.WriteDot(resp, w, c.Bool("all-ipfs-peers"))
 						checkErr("printing graph", err)
 
 						return nil
 					},
 				},
+				{
+					Name:  "statesync",
+					Usage: "Inspect and trigger syncs of the shared state",
+					Description: `
+Cluster peers regularly sync the shared state to their pin trackers, making
+sure that they track every item in the shared state (and no others) and that
+items are tracked as local or remote according to their allocations.
+
+The subcommands of this command allow to inspect the results of the last sync
+on each peer and to trigger a new sync on demand.
+`,
+					Subcommands: []cli.Command{
+						{
+							Name:  "last",
+							Usage: "Show the results of the last state sync",
+							Description: `
+This command shows when the last state sync ran on each peer, how many items
+changed their tracking because of it and any errors.
+
+When the --local flag is passed, only the contacted peer is queried.
+`,
+							ArgsUsage: " ",
+							Flags: []cli.Flag{
+								localFlag(),
+							},
+							Action: func(c *cli.Context) error {
+								resp, cerr := globalClient.LastStateSync(ctx, c.Bool("local"))
+								formatResponse(c, resp, cerr)
+								return nil
+							},
+						},
+						{
+							Name:  "run",
+							Usage: "Trigger a state sync",
+							Description: `
+This command triggers a state sync on every peer and waits for it to finish,
+showing the results.
+
+When the --local flag is passed, the sync only runs on the contacted peer.
+When the --peer flag is passed, it only runs on the given peer.
+`,
+							ArgsUsage: " ",
+							Flags: []cli.Flag{
+								localFlag(),
+								cli.StringFlag{
+									Name:  "peer",
+									Usage: "only sync the given peer ID",
+								},
+							},
+							Action: func(c *cli.Context) error {
+								if pidStr := c.String("peer"); pidStr != "" {
+									pid, err := peer.IDB58Decode(pidStr)
+									checkErr("parsing peer ID", err)
+									resp, cerr := globalClient.StateSyncPeer(ctx, pid)
+									formatResponse(c, resp, cerr)
+									return nil
+								}
+								resp, cerr := globalClient.StateSync(ctx, c.Bool("local"))
+								formatResponse(c, resp, cerr)
+								return nil
+							},
+						},
+					},
+				},
+				{
+					Name:  "ping",
+					Usage: "Measure RPC latency and clock skew to cluster peers",
+					Description: `
+This command makes the contacted peer send an RPC request to the given peer,
+or to every cluster peer when none is given, and shows the round-trip time of
+the request and the estimated skew of the remote peer's clock (positive when
+it is ahead of the contacted peer's).
+
+It helps telling apart timeouts caused by network problems from those caused
+by slow operations, along with clock differences affecting metric expiry.
+`,
+					ArgsUsage: "[peer ID]",
+					Action: func(c *cli.Context) error {
+						var pid peer.ID
+						if pidStr := c.Args().First(); pidStr != "" {
+							var err error
+							pid, err = peer.IDB58Decode(pidStr)
+							checkErr("parsing peer ID", err)
+						}
+						resp, cerr := globalClient.Ping(ctx, pid)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "info",
+					Usage: "Show aggregate facts about the cluster",
+					Description: `
+This command shows the number of cluster peers (and how many could not be
+contacted), the number of pins in the shared state, the number of items in
+error state across all peers, the free space left in their IPFS repositories,
+the consensus component and its leader, and the oldest version run by a peer.
+
+The facts are gathered from every peer and cached for a few seconds, so this
+command is cheap enough to be used by monitoring scripts.
+`,
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.ClusterInfo(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "versions",
+					Usage: "Show the versions run by cluster peers",
+					Description: `
+This command shows the cluster, RPC protocol and shared state versions run by
+every cluster peer, and whether they are compatible with those of the
+contacted peer.
+
+Incompatible peers should be upgraded as soon as possible: they may fail to
+communicate with the rest or misread the shared state. Set
+"refuse_incompatible_peers" in the cluster configuration to stop them from
+joining.
+`,
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.Versions(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "runtime",
+					Usage: "Show the Go runtime stats of cluster peers",
+					Description: `
+This command shows the number of goroutines, heap usage, garbage collections
+and open file descriptors of every cluster peer. Steadily growing values
+usually point to a leak.
+
+When the --local flag is passed, only the contacted peer is queried.
+`,
+					ArgsUsage: " ",
+					Flags: []cli.Flag{
+						localFlag(),
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.RuntimeStats(ctx, c.Bool("local"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "ipfs",
+					Usage: "Compare the IPFS daemons of cluster peers",
+					Description: `
+This command shows the version of the IPFS daemon of every cluster peer, along
+with the IPFS settings which should be the same everywhere (Routing.Type and
+Datastore.StorageMax), and warns about the differences between them.
+
+Differences in the major or minor IPFS versions or in the routing type are
+flagged as dangerous. Peers check for them regularly and log them (see
+"ipfs_drift_check_interval" in the cluster configuration).
+`,
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.IPFSDrift(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "recovery",
+					Usage: "Show the progress of the last recovery job",
+					Description: `
+This command shows the progress of the last recovery job started on the
+contacted peer with "recover": how many items in error state it found, how
+many have been recovered and which failed.
+`,
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.RecoveryStatus(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "storage",
+					Usage: "Show how much disk the cluster pinset uses",
+					Description: `
+This command inspects the DAGs of every item in the pinset and reports the
+number of unique blocks and bytes they reference, along with the bytes taken
+by all their replicas. Blocks shared between several pins are only counted
+once towards the unique figures.
+
+Walking every DAG can take long on large pinsets. The --sample flag limits the
+inspection to the given number of random pins and extrapolates the totals
+from them.
+`,
+					ArgsUsage: " ",
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "sample",
+							Value: 0,
+							Usage: "only inspect this many random pins (0 means all)",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.StorageUsage(ctx, c.Int("sample"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "reconnect",
+					Usage: "Show whether the peer is in contact with the cluster",
+					Description: `
+This command reports how many of the known cluster peers are connected to the
+peer. When none is (i.e. after a network partition), the peer keeps trying to
+reconnect to them, waiting longer after every failed attempt. The number of
+failed attempts, the last error and the time of the next attempt are shown
+then.
+`,
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.ReconnectStatus(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "logs",
+					Usage: "Show recent log entries of the peer",
+					Description: `
+This command shows the most recent log entries captured in memory by the peer,
+oldest first. A limited number of entries is kept for every component
+(logging facility). Entries below the log level of a component are not
+captured.
+
+The --component flag shows only the entries of the given component (i.e.
+pintracker) and the --level flag only those with the given level or a more
+severe one (critical, error, warn, notice, info, debug).
+`,
+					ArgsUsage: " ",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "component",
+							Usage: "only show entries of this component",
+						},
+						cli.StringFlag{
+							Name:  "level",
+							Usage: "only show entries with this level or a more severe one",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.Logs(ctx, c.String("component"), c.String("level"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
 				{
 					Name:  "metrics",
 					Usage: "List latest metrics logged by this peer",
@@ -860,6 +1656,166 @@ but usually are:
 				},
 			},
 		},
+		{
+			Name:  "bench",
+			Usage: "Generate load against the cluster and report latencies",
+			Description: `
+This command generates synthetic load against the cluster API and reports the
+throughput and the latency percentiles of every operation, in order to
+validate the sizing of a cluster before rolling it out to production.
+
+The following operations are supported (--ops):
+
+- pin: pins random CIDs. They use the identity hash, so IPFS pins them
+  without fetching any content and the latencies reflect the cluster
+  overhead.
+- add: adds random content of the given --size.
+- status: requests the status of items pinned or added during the run.
+
+Operations are issued in turn by --concurrency workers during the given
+--duration or until --count operations are started, optionally limited to
+--rate operations per second. Everything pinned or added is unpinned at the
+end of the run, unless --no-cleanup is set. Latency figures only account for
+successful operations.
+`,
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "ops",
+					Value: "pin,status",
+					Usage: "comma-separated operations to run: pin, add, status",
+				},
+				cli.DurationFlag{
+					Name:  "duration, d",
+					Value: 30 * time.Second,
+					Usage: "how long to generate load for (0 to use --count only)",
+				},
+				cli.IntFlag{
+					Name:  "count, n",
+					Usage: "stop after this number of operations (0 for no limit)",
+				},
+				cli.IntFlag{
+					Name:  "concurrency, c",
+					Value: 4,
+					Usage: "number of concurrent workers",
+				},
+				cli.Float64Flag{
+					Name:  "rate",
+					Usage: "maximum operations per second (0 for no limit)",
+				},
+				cli.StringFlag{
+					Name:  "size",
+					Value: "4KiB",
+					Usage: "size of the content added by add operations",
+				},
+				cli.StringFlag{
+					Name:  "name",
+					Value: "bench",
+					Usage: "name of the pins created",
+				},
+				cli.IntFlag{
+					Name:  "replication-min, rmin",
+					Usage: "minimum replication factor of the pins created (0 for the cluster default)",
+				},
+				cli.IntFlag{
+					Name:  "replication-max, rmax",
+					Usage: "maximum replication factor of the pins created (0 for the cluster default)",
+				},
+				cli.BoolFlag{
+					Name:  "no-cleanup",
+					Usage: "do not unpin the items created after the run",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				runBench(ctx, c)
+				return nil
+			},
+		},
+		{
+			Name:  "faults",
+			Usage: "Manage fault injection on a peer",
+			Description: `
+These commands manage the faults injected by the peer that the tool is
+contacting, which allow to rehearse failure handling and to verify alerting
+end to end. The peer must have "enable_fault_injection" set in the cluster
+section of its configuration. Rules are kept in memory and are lost when the
+peer restarts.
+
+Rules delay or fail a percentage of the calls to the IPFS connector ("ipfs"
+target), or of the RPC requests received from other peers ("rpc" target). A
+method name ("Pin", "PinTracker.Track"...) restricts a rule to a single IPFS
+connector method or RPC endpoint. Failed RPC requests are rejected by the
+peer as unauthorized.
+`,
+			Subcommands: []cli.Command{
+				{
+					Name:      "ls",
+					Usage:     "list the active fault injection rules",
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.Faults(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:      "add",
+					Usage:     "add a fault injection rule",
+					ArgsUsage: " ",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "target",
+							Value: "ipfs",
+							Usage: "calls affected by the fault: ipfs or rpc",
+						},
+						cli.StringFlag{
+							Name:  "method",
+							Usage: "only affect this IPFS connector method or RPC endpoint",
+						},
+						cli.Float64Flag{
+							Name:  "percent",
+							Value: 100,
+							Usage: "percentage of the calls affected",
+						},
+						cli.DurationFlag{
+							Name:  "delay",
+							Usage: "delay the affected calls by this duration",
+						},
+						cli.StringFlag{
+							Name:  "error",
+							Usage: "fail the affected calls with this error",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						rules, cerr := globalClient.Faults(ctx)
+						if cerr != nil {
+							formatResponse(c, nil, cerr)
+							return nil
+						}
+						rules = append(rules, &api.FaultRule{
+							Target:  c.String("target"),
+							Method:  c.String("method"),
+							Percent: c.Float64("percent"),
+							Delay:   c.Duration("delay"),
+							Error:   c.String("error"),
+						})
+						cerr = globalClient.SetFaults(ctx, rules)
+						formatResponse(c, nil, cerr)
+						return nil
+					},
+				},
+				{
+					Name:      "clear",
+					Usage:     "remove all fault injection rules",
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						cerr := globalClient.SetFaults(ctx, nil)
+						formatResponse(c, nil, cerr)
+						return nil
+					},
+				},
+			},
+		},
 		{
 			Name:      "commands",
 			Usage:     "List all commands",
@@ -870,7 +1826,9 @@ but usually are:
 				return nil
 			},
 		},
+		clustersCommand(),
 	}
+	setupAllClusters(app.Commands, "")
 
 	app.Run(os.Args)
 }
@@ -911,7 +1869,9 @@ func formatResponse(c *cli.Context, resp interface{}, err error) {
 	if err != nil {
 		cerr, ok := err.(*api.Error)
 		if !ok {
-			checkErr("", err)
+			out("error: %s\n", err)
+			requestFailed(1)
+			return
 		}
 		switch enc {
 		case "text":
@@ -922,10 +1882,11 @@ func formatResponse(c *cli.Context, resp interface{}, err error) {
 			checkErr("", errors.New("unsupported encoding selected"))
 		}
 		if cerr.Code == 0 {
-			os.Exit(1) // problem with the call
+			requestFailed(1) // problem with the call
 		} else {
-			os.Exit(2) // call went fine, response has an error
+			requestFailed(2) // call went fine, response has an error
 		}
+		return
 	}
 
 	switch enc {
@@ -953,6 +1914,37 @@ func parseCredentials(userInput string) (string, string) {
 	}
 }
 
+// parseConnectionFilter takes peer IDs and IP ranges in CIDR notation.
+func parseConnectionFilter(args []string) *api.ConnectionFilter {
+	if len(args) == 0 {
+		checkErr("", errors.New("need at least one peer ID or CIDR"))
+	}
+	f := &api.ConnectionFilter{}
+	for _, arg := range args {
+		if strings.Contains(arg, "/") {
+			f.CIDRs = append(f.CIDRs, arg)
+			continue
+		}
+		p, err := peer.IDB58Decode(arg)
+		checkErr("parsing peer ID", err)
+		f.Peers = append(f.Peers, p)
+	}
+	return f
+}
+
+// parseSince takes an RFC3339 timestamp or a duration counting back
+// from now.
+func parseSince(str string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return time.Time{}, errors.New("use an RFC3339 timestamp or a duration")
+	}
+	return time.Now().Add(-d), nil
+}
+
 func handlePinResponseFormatFlags(
 	ctx context.Context,
 	c *cli.Context,
@@ -980,6 +1972,105 @@ func handlePinResponseFormatFlags(
 	formatResponse(c, status, cerr)
 }
 
+// migratePeer re-allocates the pins of the given peer and waits until
+// they are pinned by their new allocations, reporting progress.
+func migratePeer(ctx context.Context, p peer.ID, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	mig, err := globalClient.PeerMigrate(ctx, p)
+	if err != nil {
+		return err
+	}
+	if len(mig.Errors) > 0 {
+		for _, e := range mig.Errors {
+			out("%s\n", e)
+		}
+		return fmt.Errorf("%d pins could not be re-allocated. The peer was not removed", len(mig.Errors))
+	}
+
+	total := len(mig.Pins)
+	out("migrating %d pins out of %s\n", total, p.Pretty())
+	pending := mig.Pins
+	for len(pending) > 0 {
+		var stillPending []*api.Pin
+		for _, pin := range pending {
+			gpi, err := globalClient.Status(ctx, pin.Cid, false)
+			if err != nil {
+				return err
+			}
+			done, err := migrated(gpi, pin.Allocations)
+			if err != nil {
+				return fmt.Errorf("%s: %s. The peer was not removed", pin.Cid, err)
+			}
+			if !done {
+				stillPending = append(stillPending, pin)
+			}
+		}
+		if len(stillPending) < len(pending) {
+			out("%d/%d pins migrated\n", total-len(stillPending), total)
+		}
+		pending = stillPending
+		if len(pending) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d pins not migrated yet: %s. The peer was not removed", len(pending), ctx.Err())
+		case <-time.After(defaultWaitCheckFreq):
+		}
+	}
+	return nil
+}
+
+// waitForRecovery waits until the recovery job of the contacted peer
+// finishes, reporting progress.
+func waitForRecovery(ctx context.Context) (*api.RecoveryJob, error) {
+	done := -1
+	for {
+		job, err := globalClient.RecoveryStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if job.State != api.RecoveryJobRunning {
+			return job, nil
+		}
+		if job.Recovered+job.Failed > done {
+			done = job.Recovered + job.Failed
+			out("%d/%d items recovered (%d failed)\n", job.Recovered, job.Total, job.Failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(defaultWaitCheckFreq):
+		}
+	}
+}
+
+// migrated returns true when the given allocations have pinned the
+// content, and an error when any of them failed to.
+func migrated(gpi *api.GlobalPinInfo, allocations []peer.ID) (bool, error) {
+	for _, a := range allocations {
+		pinfo, ok := gpi.PeerMap[peer.IDB58Encode(a)]
+		if !ok {
+			return false, nil
+		}
+		switch {
+		case pinfo.Status == api.TrackerStatusPinned:
+		case pinfo.Status.Match(api.TrackerStatusError):
+			return false, fmt.Errorf("error pinning on %s: %s", a.Pretty(), pinfo.Error)
+		default:
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 func waitFor(
 	ci cid.Cid,
 	target api.TrackerStatus,
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/allocator/descendalloc"
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/api/rest"
+	"github.com/ipfs/ipfs-cluster/api/rest/client"
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/ipfs/ipfs-cluster/consensus/crdt"
+	"github.com/ipfs/ipfs-cluster/datastore/badger"
+	"github.com/ipfs/ipfs-cluster/informer/disk"
+	"github.com/ipfs/ipfs-cluster/ipfsconn/ipfshttp"
+	"github.com/ipfs/ipfs-cluster/monitor/pubsubmon"
+	"github.com/ipfs/ipfs-cluster/observations"
+	"github.com/ipfs/ipfs-cluster/pintracker/stateless"
+	"github.com/ipfs/ipfs-cluster/pstoremgr"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+	cli "github.com/urfave/cli"
+)
+
+// follower holds the paths for a followed cluster.
+type follower struct {
+	name         string
+	path         string
+	configPath   string
+	identityPath string
+}
+
+func newFollower(name, basePath string) *follower {
+	path := filepath.Join(basePath, name)
+	locker = &lock{path: path}
+	return &follower{
+		name:         name,
+		path:         path,
+		configPath:   filepath.Join(path, DefaultConfigFile),
+		identityPath: filepath.Join(path, DefaultIdentityFile),
+	}
+}
+
+func (f *follower) initialized() bool {
+	_, err := os.Stat(f.configPath)
+	return err == nil
+}
+
+// source reads the remote configuration source and its expected signer
+// (if any) from the configuration file.
+func (f *follower) source() (string, string, error) {
+	bs, err := ioutil.ReadFile(f.configPath)
+	if err != nil {
+		return "", "", err
+	}
+	var jcfg struct {
+		Source       string `json:"source"`
+		SourceSigner string `json:"source_signer"`
+	}
+	err = json.Unmarshal(bs, &jcfg)
+	return jcfg.Source, jcfg.SourceSigner, err
+}
+
+// sourceURL converts the given configuration location to a URL. CIDs,
+// IPFS paths and DNSLink domains are fetched through the given gateway
+// and, unless they point to a JSON file, are expected to contain the
+// well-known follower configuration file.
+func sourceURL(src, gateway string) (string, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		return src, nil
+	}
+
+	var ipfsPath string
+	switch {
+	case strings.HasPrefix(src, "/ipfs/"), strings.HasPrefix(src, "/ipns/"):
+		ipfsPath = src
+	default:
+		if c, err := cid.Decode(src); err == nil {
+			ipfsPath = "/ipfs/" + c.String()
+		} else if strings.Contains(src, ".") && !strings.Contains(src, "/") {
+			ipfsPath = "/ipns/" + src
+		} else {
+			return "", errors.New("configuration source must be a URL, a CID, an IPFS path or a DNSLink domain")
+		}
+	}
+
+	if !strings.HasSuffix(ipfsPath, ".json") {
+		ipfsPath = path.Join(ipfsPath, config.FollowerConfigName)
+	}
+	return fmt.Sprintf("http://%s%s", gateway, ipfsPath), nil
+}
+
+// checkSigner makes sure that the loaded remote configuration, when
+// signed, was published by one of the cluster's trusted peers.
+func checkSigner(cfgMgr *config.Manager, cfgs *cfgs) error {
+	signer := cfgMgr.Signer()
+	if signer == "" {
+		logger.Warning("the remote configuration is not signed")
+		return nil
+	}
+	for _, p := range cfgs.crdtCfg.TrustedPeers {
+		if p == signer {
+			return nil
+		}
+	}
+	return fmt.Errorf("the remote configuration is signed by %s, which is not a trusted peer", signer.Pretty())
+}
+
+// listClustersCmd lists the clusters with an initialized follower.
+func listClustersCmd(c *cli.Context) error {
+	absPath, err := filepath.Abs(c.String("config"))
+	checkErr("obtaining the configuration folder", err)
+
+	entries, err := ioutil.ReadDir(absPath)
+	if os.IsNotExist(err) {
+		out("No clusters are being followed. See \"%s --help\".\n", programName)
+		return nil
+	}
+	checkErr("reading %s", err, absPath)
+
+	fmt.Printf("Clusters followed (%s):\n\n", absPath)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		f := newFollower(e.Name(), absPath)
+		if !f.initialized() {
+			continue
+		}
+		fmt.Printf("  - %s\n", f.name)
+	}
+	return nil
+}
+
+func (f *follower) initCmd(c *cli.Context) error {
+	if !c.Args().Present() {
+		return cli.NewExitError("a configuration URL or CID is required", 1)
+	}
+
+	url, err := sourceURL(c.Args().First(), c.String("gateway"))
+	checkErr("parsing configuration source", err)
+
+	if f.initialized() {
+		checkErr("", fmt.Errorf("%s is already initialized. Remove %s to re-initialize", f.name, f.path))
+	}
+
+	err = os.MkdirAll(f.path, 0700)
+	checkErr("creating configuration folder (%s)", err, f.path)
+
+	localCfg := map[string]string{"source": url}
+	if signer := c.String("signer"); signer != "" {
+		_, err := peer.IDB58Decode(signer)
+		checkErr("parsing signer peer ID", err)
+		localCfg["source_signer"] = signer
+	}
+
+	srcCfg, err := config.DefaultJSONMarshal(localCfg)
+	checkErr("generating configuration", err)
+	err = ioutil.WriteFile(f.configPath, srcCfg, 0600)
+	checkErr("saving configuration", err)
+	out("configuration written to %s\n", f.configPath)
+
+	if _, err := os.Stat(f.identityPath); os.IsNotExist(err) {
+		ident, err := config.NewIdentity()
+		checkErr("generating an identity", err)
+
+		err = ident.SaveJSON(f.identityPath)
+		checkErr("saving "+DefaultIdentityFile, err)
+		out("new identity written to %s\n", f.identityPath)
+	}
+
+	out("\n%s follower peer initialized. Start it with \"%s %s run\".\n", f.name, programName, f.name)
+	return nil
+}
+
+func (f *follower) runCmd(c *cli.Context) error {
+	if !f.initialized() {
+		checkErr("", fmt.Errorf("%s is not initialized. Run \"%s %s init <url>\" first", f.name, programName, f.name))
+	}
+
+	logger.Info("Initializing. For verbose output run with \"-l debug\". Please wait...")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Execution lock
+	locker.lock()
+	defer locker.tryUnlock()
+
+	cfgMgr, ident, cfgs := makeAndLoadConfigs(f)
+	defer cfgMgr.Shutdown()
+
+	if len(cfgs.crdtCfg.TrustedPeers) == 0 {
+		checkErr("", errors.New("the remote configuration does not set any crdt trusted peers: there is nothing to follow"))
+	}
+	checkErr("verifying the remote configuration", checkSigner(cfgMgr, cfgs))
+
+	cluster, err := createCluster(ctx, ident, cfgs)
+	checkErr("starting cluster", err)
+
+	return handleSignals(ctx, cluster)
+}
+
+// createCluster creates a cluster peer with the components used by
+// followers: CRDT consensus, stateless pintracker and the disk informer,
+// so that trusted peers can allocate content to this peer.
+func createCluster(
+	ctx context.Context,
+	ident *config.Identity,
+	cfgs *cfgs,
+) (*ipfscluster.Cluster, error) {
+	host, pubsub, dht, err := ipfscluster.NewClusterHost(ctx, ident, cfgs.clusterCfg)
+	checkErr("creating libP2P Host", err)
+
+	peerstoreMgr := pstoremgr.New(host, cfgs.clusterCfg.GetPeerstorePath())
+	peerstoreMgr.ImportPeersFromPeerstore(false)
+	peerstoreMgr.ImportPeers(cfgs.clusterCfg.PeerAddresses, false)
+
+	store, err := badger.New(cfgs.badgerCfg)
+	checkErr("creating datastore", err)
+
+	api, err := rest.NewAPIWithHostAndDatastore(ctx, cfgs.apiCfg, host, store)
+	checkErr("creating REST API component", err)
+
+	connector, err := ipfshttp.NewConnector(cfgs.ipfshttpCfg)
+	checkErr("creating IPFS Connector component", err)
+
+	informer, err := disk.NewInformer(cfgs.diskInfCfg)
+	checkErr("creating informer", err)
+	alloc := descendalloc.NewAllocator()
+
+	tracer, err := observations.SetupTracing(&observations.TracingConfig{})
+	checkErr("setting up Tracing", err)
+
+	tracker := stateless.New(cfgs.statelessTrackerCfg, host.ID(), cfgs.clusterCfg.Peername, store)
+
+	cons, err := crdt.New(
+		host,
+		dht,
+		pubsub,
+		cfgs.crdtCfg,
+		store,
+	)
+	if err != nil {
+		store.Close()
+		checkErr("creating CRDT component", err)
+	}
+
+	mon, err := pubsubmon.New(ctx, cfgs.pubsubmonCfg, pubsub, nil, store)
+	if err != nil {
+		store.Close()
+		checkErr("setting up PeerMonitor", err)
+	}
+
+	cluster, err := ipfscluster.NewCluster(
+		ctx,
+		host,
+		dht,
+		cfgs.clusterCfg,
+		store,
+		cons,
+		[]ipfscluster.API{api},
+		connector,
+		tracker,
+		mon,
+		alloc,
+		[]ipfscluster.Informer{informer},
+		tracer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Now that everything is in place, connect to the cluster peers.
+	go peerstoreMgr.ImportPeers(cfgs.clusterCfg.PeerAddresses, true)
+	return cluster, nil
+}
+
+func handleSignals(ctx context.Context, cluster *ipfscluster.Cluster) error {
+	signalChan := make(chan os.Signal, 20)
+	signal.Notify(
+		signalChan,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+		syscall.SIGHUP,
+	)
+
+	var ctrlcCount int
+	for {
+		select {
+		case <-signalChan:
+			ctrlcCount++
+			switch ctrlcCount {
+			case 1:
+				go func() {
+					err := cluster.Shutdown(ctx)
+					checkErr("shutting down cluster", err)
+				}()
+			case 2:
+				out("\nShutdown is taking too long! Press Ctrl-c again to manually kill the follower peer.\n")
+			case 3:
+				out("exiting follower peer NOW\n")
+				locker.tryUnlock()
+				os.Exit(-1)
+			}
+		case <-cluster.Done():
+			return nil
+		}
+	}
+}
+
+func (f *follower) listCmd(c *cli.Context) error {
+	if !locker.locked() {
+		checkErr("", fmt.Errorf("the %s follower peer is not running", f.name))
+	}
+
+	apiCfg := &rest.Config{}
+	apiCfg.Default()
+	apiAddr, _ := ma.NewMultiaddr(defaultAPIAddr)
+	apiCfg.HTTPListenAddr = apiAddr
+	err := apiCfg.ApplyEnvVars()
+	checkErr("applying environment variables to the API configuration", err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cl, err := client.NewDefaultClient(&client.Config{
+		APIAddr: apiCfg.HTTPListenAddr,
+	})
+	checkErr("creating API client", err)
+
+	pins, err := cl.Allocations(ctx, api.DataType|api.MetaType)
+	checkErr("listing pinset", err)
+
+	// Pins without a local status are shown as remote, so there is
+	// no need to fetch them.
+	var filter api.TrackerStatus
+	for _, st := range api.TrackerStatusAll() {
+		if st != api.TrackerStatusRemote {
+			filter |= st
+		}
+	}
+	statuses, err := cl.StatusAll(ctx, filter, true)
+	checkErr("obtaining local pin status", err)
+
+	status := make(map[string]string, len(statuses))
+	for _, gpi := range statuses {
+		for _, pinfo := range gpi.PeerMap {
+			status[gpi.Cid.String()] = pinfo.Status.String()
+		}
+	}
+
+	for _, p := range pins {
+		st, ok := status[p.Cid.String()]
+		if !ok {
+			st = "remote"
+		}
+		fmt.Printf("%-48s | %-20s | %s\n", p.Cid, p.Name, st)
+	}
+	return nil
+}
+
+func (f *follower) infoCmd(c *cli.Context) error {
+	if !f.initialized() {
+		out("%s is not initialized. Run \"%s %s init <url>\" first.\n", f.name, programName, f.name)
+		return nil
+	}
+
+	src, signer, err := f.source()
+	checkErr("reading configuration source", err)
+
+	ident := &config.Identity{}
+	err = ident.LoadJSONFromFile(f.identityPath)
+	checkErr("loading identity from %s", err, f.identityPath)
+
+	running := "no"
+	if locker.locked() {
+		running = "yes"
+	}
+
+	fmt.Printf("Information about follower peer for Cluster \"%s\":\n\n", f.name)
+	fmt.Printf("Config folder: %s\n", f.path)
+	fmt.Printf("Config source URL: %s\n", src)
+	if signer != "" {
+		fmt.Printf("Config signer: %s\n", signer)
+	}
+	fmt.Printf("Peer ID: %s\n", ident.ID.Pretty())
+	fmt.Printf("Running: %s\n", running)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/api/rest"
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/ipfs/ipfs-cluster/consensus/crdt"
+	"github.com/ipfs/ipfs-cluster/datastore/badger"
+	"github.com/ipfs/ipfs-cluster/informer/disk"
+	"github.com/ipfs/ipfs-cluster/ipfsconn/ipfshttp"
+	"github.com/ipfs/ipfs-cluster/monitor/pubsubmon"
+	"github.com/ipfs/ipfs-cluster/pintracker/stateless"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// The follower REST API only listens locally. It is used by the
+// "list" command.
+const defaultAPIAddr = "/ip4/127.0.0.1/tcp/9097"
+
+// cfgs holds the configurations for the components used by
+// follower peers. Other sections in the remote configuration
+// are ignored.
+type cfgs struct {
+	clusterCfg          *ipfscluster.Config
+	apiCfg              *rest.Config
+	ipfshttpCfg         *ipfshttp.Config
+	crdtCfg             *crdt.Config
+	statelessTrackerCfg *stateless.Config
+	pubsubmonCfg        *pubsubmon.Config
+	diskInfCfg          *disk.Config
+	badgerCfg           *badger.Config
+}
+
+func makeConfigs() (*config.Manager, *cfgs) {
+	cfg := config.NewManager()
+	clusterCfg := &ipfscluster.Config{}
+	apiCfg := &rest.Config{}
+	ipfshttpCfg := &ipfshttp.Config{}
+	crdtCfg := &crdt.Config{}
+	statelessCfg := &stateless.Config{}
+	pubsubmonCfg := &pubsubmon.Config{}
+	diskInfCfg := &disk.Config{}
+	badgerCfg := &badger.Config{}
+	cfg.RegisterComponent(config.Cluster, clusterCfg)
+	cfg.RegisterComponent(config.API, apiCfg)
+	cfg.RegisterComponent(config.IPFSConn, ipfshttpCfg)
+	cfg.RegisterComponent(config.Consensus, crdtCfg)
+	cfg.RegisterComponent(config.PinTracker, statelessCfg)
+	cfg.RegisterComponent(config.Monitor, pubsubmonCfg)
+	cfg.RegisterComponent(config.Informer, diskInfCfg)
+	cfg.RegisterComponent(config.Datastore, badgerCfg)
+	return cfg, &cfgs{
+		clusterCfg,
+		apiCfg,
+		ipfshttpCfg,
+		crdtCfg,
+		statelessCfg,
+		pubsubmonCfg,
+		diskInfCfg,
+		badgerCfg,
+	}
+}
+
+// makeAndLoadConfigs loads the follower configuration, which triggers
+// fetching it from its remote source, and applies the settings which
+// are specific to follower peers. Environment variables are applied last
+// so that they can be used to override any of the values.
+func makeAndLoadConfigs(f *follower) (*config.Manager, *config.Identity, *cfgs) {
+	ident := &config.Identity{}
+	err := ident.LoadJSONFromFile(f.identityPath)
+	checkErr("loading identity from %s", err, f.identityPath)
+	err = ident.ApplyEnvVars()
+	checkErr("applying environment variables to the identity", err)
+
+	cfgMgr, cfgs := makeConfigs()
+	err = cfgMgr.LoadJSONFromFile(f.configPath)
+	checkErr("loading configuration", err)
+
+	cfgs.clusterCfg.LeaveOnShutdown = false
+	apiAddr, _ := ma.NewMultiaddr(defaultAPIAddr)
+	cfgs.apiCfg.HTTPListenAddr = apiAddr
+	cfgs.apiCfg.Libp2pListenAddr = nil
+	cfgs.apiCfg.BasicAuthCreds = nil
+	cfgs.apiCfg.HTTPAuthBackends = nil
+	cfgs.apiCfg.Libp2pAuthBackends = nil
+
+	err = cfgMgr.ApplyEnvVars()
+	checkErr("applying environment variables to configuration", err)
+	return cfgMgr, ident, cfgs
+}
@@ -0,0 +1,277 @@
+// The ipfs-cluster-follow application.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/ipfs/ipfs-cluster/version"
+
+	semver "github.com/blang/semver"
+	logging "github.com/ipfs/go-log"
+	cli "github.com/urfave/cli"
+)
+
+// ProgramName of this application
+const programName = `ipfs-cluster-follow`
+
+// flag defaults
+const (
+	defaultLogLevel = "info"
+	defaultGateway  = "127.0.0.1:8080"
+)
+
+// We store a commit id here
+var commit string
+
+// Description provides a short summary of the functionality of this tool
+var Description = fmt.Sprintf(`
+%s helps running IPFS Cluster follower peers.
+
+Follower peers replicate the pinset of a collaborative cluster. They
+follow the cluster state as published by a set of trusted peers and pin the
+content allocated to them in the local IPFS daemon, but they cannot modify
+the cluster pinset.
+
+Followers are configured purely from a remote configuration, which is
+published by the cluster operators and can be fetched from a URL or from
+IPFS (in which case a local IPFS gateway is used). Each followed cluster
+is identified by a name, and its identity, configuration and data are stored
+in a subfolder of ~/%s.
+
+For feedback, bug reports or any additional information, visit
+https://github.com/ipfs/ipfs-cluster.
+
+
+EXAMPLES:
+
+Initialize a follower peer for a cluster:
+
+$ %s my-cluster init https://example.org/my-cluster/service.json
+
+Run the follower peer:
+
+$ %s my-cluster run
+
+List the items in the followed pinset and their local status:
+
+$ %s my-cluster list
+`,
+	programName,
+	DefaultFolder,
+	programName,
+	programName,
+	programName,
+)
+
+var logger = logging.Logger("follow")
+
+// Default location for the configurations and data
+var (
+	// DefaultFolder is the name of the follower folder
+	DefaultFolder = ".ipfs-cluster-follow"
+	// DefaultPath is set on init() to $HOME/DefaultFolder
+	// and holds the folders for all the followed clusters
+	DefaultPath string
+	// The name of the configuration file inside the cluster folder
+	DefaultConfigFile = "service.json"
+	// The name of the identity file inside the cluster folder
+	DefaultIdentityFile = "identity.json"
+)
+
+func init() {
+	// Set build information.
+	if build, err := semver.NewBuildVersion(commit); err == nil {
+		version.Version.Build = []string{"git" + build}
+	}
+
+	// We try guessing user's home from the HOME variable. This
+	// allows HOME hacks for things like Snapcraft builds. HOME
+	// should be set in all UNIX by the OS. Alternatively, we fall back to
+	// usr.HomeDir (which should work on Windows etc.).
+	home := os.Getenv("HOME")
+	if home == "" {
+		usr, err := user.Current()
+		if err != nil {
+			panic(fmt.Sprintf("cannot get current user: %s", err))
+		}
+		home = usr.HomeDir
+	}
+
+	DefaultPath = filepath.Join(home, DefaultFolder)
+}
+
+func out(m string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, m, a...)
+}
+
+func checkErr(doing string, err error, args ...interface{}) {
+	if err != nil {
+		if len(args) > 0 {
+			doing = fmt.Sprintf(doing, args...)
+		}
+		out("error %s: %s\n", doing, err)
+		if locker != nil {
+			err = locker.tryUnlock()
+			if err != nil {
+				out("error releasing execution lock: %s\n", err)
+			}
+		}
+		os.Exit(1)
+	}
+}
+
+func main() {
+	app := cli.NewApp()
+	app.Name = programName
+	app.Usage = "IPFS Cluster Follower"
+	app.UsageText = fmt.Sprintf("%s [global options] <clusterName> [subcommand]...", programName)
+	app.Description = Description
+	//app.Copyright = "© Protocol Labs, Inc."
+	app.Version = version.Version.String()
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "config, c",
+			Value:  DefaultPath,
+			Usage:  "path to the followers configuration and data `FOLDER`",
+			EnvVar: "IPFS_CLUSTER_FOLLOW_PATH",
+		},
+		cli.BoolFlag{
+			Name:  "debug, d",
+			Usage: "enable full debug logging (very verbose)",
+		},
+		cli.StringFlag{
+			Name:  "loglevel, l",
+			Value: defaultLogLevel,
+			Usage: "set the loglevel for cluster components only [critical, error, warning, info, debug]",
+		},
+	}
+
+	app.Before = func(c *cli.Context) error {
+		setupLogLevel(c.String("loglevel"))
+		if c.Bool("debug") {
+			setupDebug()
+		}
+		return nil
+	}
+
+	app.Action = func(c *cli.Context) error {
+		if !c.Args().Present() {
+			return listClustersCmd(c)
+		}
+
+		clusterName := c.Args().First()
+		if clusterName == "help" {
+			cli.ShowAppHelp(c)
+			os.Exit(0)
+		}
+
+		absPath, err := filepath.Abs(c.String("config"))
+		checkErr("obtaining the configuration folder", err)
+
+		f := newFollower(clusterName, absPath)
+
+		subApp := cli.NewApp()
+		subApp.Name = fmt.Sprintf("%s %s", programName, clusterName)
+		subApp.HelpName = subApp.Name
+		subApp.Usage = fmt.Sprintf("Follower peer management for \"%s\"", clusterName)
+		subApp.UsageText = fmt.Sprintf("%s %s [subcommand]", programName, clusterName)
+		subApp.Action = f.infoCmd
+		subApp.HideVersion = true
+		subApp.Commands = []cli.Command{
+			{
+				Name:      "init",
+				Usage:     "initializes the follower peer",
+				ArgsUsage: "<url|cid|path|domain>",
+				Description: fmt.Sprintf(`
+This command initializes a follower peer for the cluster named "%s". It
+generates a new identity and stores a %s file which points to the
+given remote configuration. The configuration is fetched every time the
+follower peer runs, so that any updates published by the cluster operators
+are picked up automatically.
+
+The remote configuration can be provided as an HTTP(s) URL, or as a CID, an
+/ipfs/ or /ipns/ path or a DNSLink domain. In the latter cases it will be
+fetched from the local IPFS gateway (%s, can be changed with
+--gateway) and, unless the path points to a JSON file, it is expected to be a
+folder containing a "%s" file, as published by
+"ipfs-cluster-service follower publish".
+
+Configurations published that way are signed by a trusted peer. Use --signer
+to only accept configurations signed by the given peer ID. In any case,
+signed configurations are only accepted when the signer is one of the trusted
+peers listed in them.
+`,
+					clusterName,
+					DefaultConfigFile,
+					defaultGateway,
+					config.FollowerConfigName,
+				),
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "gateway",
+						Value: defaultGateway,
+						Usage: "gateway used to fetch configurations from IPFS",
+					},
+					cli.StringFlag{
+						Name:  "signer",
+						Usage: "only accept configurations signed by this peer `ID`",
+					},
+				},
+				Action: f.initCmd,
+			},
+			{
+				Name:  "run",
+				Usage: "runs the follower peer",
+				Description: fmt.Sprintf(`
+This command runs an IPFS Cluster follower peer for the cluster named "%s".
+
+The remote configuration is fetched and loaded before starting the peer. The
+follower peer only accepts updates to the pinset coming from the
+cluster's trusted peers, and pins the content allocated to it in
+the local IPFS daemon.
+`,
+					clusterName,
+				),
+				Action: f.runCmd,
+			},
+			{
+				Name:  "list",
+				Usage: "lists the items in the followed pinset",
+				Description: `
+This command lists all the items in the pinset of the followed cluster along
+with their status in the local peer. It requires a running follower peer.
+`,
+				Action: f.listCmd,
+			},
+			{
+				Name:  "info",
+				Usage: "displays information about this follower peer (default)",
+				Description: `
+This command displays information about this follower peer: its peer ID, the
+configuration source and whether it is currently running.
+`,
+				Action: f.infoCmd,
+			},
+		}
+
+		return subApp.Run(append([]string{subApp.Name}, c.Args().Tail()...))
+	}
+
+	app.Run(os.Args)
+}
+
+func setupLogLevel(lvl string) {
+	for f := range ipfscluster.LoggingFacilities {
+		ipfscluster.SetFacilityLogLevel(f, lvl)
+	}
+	ipfscluster.SetFacilityLogLevel("follow", lvl)
+}
+
+func setupDebug() {
+	ipfscluster.SetFacilityLogLevel("*", "DEBUG")
+}
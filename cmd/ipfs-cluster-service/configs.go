@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/allocator/webhookalloc"
 	"github.com/ipfs/ipfs-cluster/api/ipfsproxy"
 	"github.com/ipfs/ipfs-cluster/api/rest"
 	"github.com/ipfs/ipfs-cluster/config"
@@ -14,11 +15,17 @@ import (
 	"github.com/ipfs/ipfs-cluster/datastore/badger"
 	"github.com/ipfs/ipfs-cluster/informer/disk"
 	"github.com/ipfs/ipfs-cluster/informer/numpin"
+	"github.com/ipfs/ipfs-cluster/informer/pinnedbytes"
+	"github.com/ipfs/ipfs-cluster/informer/popularity"
 	"github.com/ipfs/ipfs-cluster/ipfsconn/ipfshttp"
 	"github.com/ipfs/ipfs-cluster/monitor/pubsubmon"
 	"github.com/ipfs/ipfs-cluster/observations"
 	"github.com/ipfs/ipfs-cluster/pintracker/maptracker"
 	"github.com/ipfs/ipfs-cluster/pintracker/stateless"
+	"github.com/ipfs/ipfs-cluster/registry"
+
+	// registers the "plugin" informer and allocator
+	_ "github.com/ipfs/ipfs-cluster/plugins/host"
 )
 
 type cfgs struct {
@@ -33,9 +40,13 @@ type cfgs struct {
 	pubsubmonCfg        *pubsubmon.Config
 	diskInfCfg          *disk.Config
 	numpinInfCfg        *numpin.Config
+	pinnedbytesInfCfg   *pinnedbytes.Config
+	popularityInfCfg    *popularity.Config
+	webhookAllocCfg     *webhookalloc.Config
 	metricsCfg          *observations.MetricsConfig
 	tracingCfg          *observations.TracingConfig
 	badgerCfg           *badger.Config
+	registryCfgs        *registry.Configs
 }
 
 func makeConfigs() (*config.Manager, *cfgs) {
@@ -51,6 +62,9 @@ func makeConfigs() (*config.Manager, *cfgs) {
 	pubsubmonCfg := &pubsubmon.Config{}
 	diskInfCfg := &disk.Config{}
 	numpinInfCfg := &numpin.Config{}
+	pinnedbytesInfCfg := &pinnedbytes.Config{}
+	popularityInfCfg := &popularity.Config{}
+	webhookAllocCfg := &webhookalloc.Config{}
 	metricsCfg := &observations.MetricsConfig{}
 	tracingCfg := &observations.TracingConfig{}
 	badgerCfg := &badger.Config{}
@@ -65,9 +79,13 @@ func makeConfigs() (*config.Manager, *cfgs) {
 	cfg.RegisterComponent(config.Monitor, pubsubmonCfg)
 	cfg.RegisterComponent(config.Informer, diskInfCfg)
 	cfg.RegisterComponent(config.Informer, numpinInfCfg)
+	cfg.RegisterComponent(config.Informer, pinnedbytesInfCfg)
+	cfg.RegisterComponent(config.Informer, popularityInfCfg)
+	cfg.RegisterComponent(config.Allocator, webhookAllocCfg)
 	cfg.RegisterComponent(config.Observations, metricsCfg)
 	cfg.RegisterComponent(config.Observations, tracingCfg)
 	cfg.RegisterComponent(config.Datastore, badgerCfg)
+	registryCfgs := registry.NewConfigs(cfg)
 	return cfg, &cfgs{
 		clusterCfg,
 		apiCfg,
@@ -80,9 +98,13 @@ func makeConfigs() (*config.Manager, *cfgs) {
 		pubsubmonCfg,
 		diskInfCfg,
 		numpinInfCfg,
+		pinnedbytesInfCfg,
+		popularityInfCfg,
+		webhookAllocCfg,
 		metricsCfg,
 		tracingCfg,
 		badgerCfg,
+		registryCfgs,
 	}
 }
 
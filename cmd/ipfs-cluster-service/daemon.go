@@ -12,12 +12,15 @@ import (
 	ipfscluster "github.com/ipfs/ipfs-cluster"
 	"github.com/ipfs/ipfs-cluster/allocator/ascendalloc"
 	"github.com/ipfs/ipfs-cluster/allocator/descendalloc"
+	"github.com/ipfs/ipfs-cluster/allocator/webhookalloc"
 	"github.com/ipfs/ipfs-cluster/api/ipfsproxy"
 	"github.com/ipfs/ipfs-cluster/api/rest"
 	"github.com/ipfs/ipfs-cluster/consensus/crdt"
 	"github.com/ipfs/ipfs-cluster/consensus/raft"
 	"github.com/ipfs/ipfs-cluster/informer/disk"
 	"github.com/ipfs/ipfs-cluster/informer/numpin"
+	"github.com/ipfs/ipfs-cluster/informer/pinnedbytes"
+	"github.com/ipfs/ipfs-cluster/informer/popularity"
 	"github.com/ipfs/ipfs-cluster/ipfsconn/ipfshttp"
 	"github.com/ipfs/ipfs-cluster/monitor/pubsubmon"
 	"github.com/ipfs/ipfs-cluster/observations"
@@ -64,6 +67,14 @@ func daemon(c *cli.Context) error {
 
 	defer cfgMgr.Shutdown()
 
+	if token := c.String("join-token"); token != "" {
+		info, err := ipfscluster.ParseJoinToken(token)
+		checkErr("reading join token", err)
+		applyJoinToken(cfgs, info)
+		saveConfig(cfgMgr)
+		bootstraps = append(bootstraps, info.Addrs...)
+	}
+
 	if c.Bool("stats") {
 		cfgs.metricsCfg.EnableStats = true
 	}
@@ -81,6 +92,10 @@ func daemon(c *cli.Context) error {
 		cfgs.clusterCfg.LeaveOnShutdown = true
 	}
 
+	if c.Bool("trust-state") {
+		cfgs.statelessTrackerCfg.TrustStateOnStartup = true
+	}
+
 	cluster, err := createCluster(ctx, c, ident, cfgs, raftStaging)
 	checkErr("starting cluster", err)
 
@@ -117,8 +132,11 @@ func createCluster(
 	// fail.
 	// Connections will happen as needed during bootstrap, rpc etc.
 	peerstoreMgr.ImportPeersFromPeerstore(false)
+	peerstoreMgr.ImportPeers(cfgs.clusterCfg.PeerAddresses, false)
+
+	store := setupDatastore(c.String("consensus"), ident, cfgs)
 
-	api, err := rest.NewAPIWithHost(ctx, cfgs.apiCfg, host)
+	api, err := rest.NewAPIWithHostAndDatastore(ctx, cfgs.apiCfg, host, store)
 	checkErr("creating REST API component", err)
 
 	proxy, err := ipfsproxy.New(cfgs.ipfsproxyCfg)
@@ -135,13 +153,20 @@ func createCluster(
 		cfgs.maptrackerCfg,
 		cfgs.statelessTrackerCfg,
 		cfgs.clusterCfg.Peername,
+		store,
 	)
 
-	informer, alloc := setupAllocation(
-		c.String("alloc"),
-		cfgs.diskInfCfg,
-		cfgs.numpinInfCfg,
-	)
+	informer, alloc := setupAllocation(c.String("alloc"), cfgs)
+
+	popularityInf, err := popularity.NewInformer(cfgs.popularityInfCfg)
+	checkErr("creating popularity informer", err)
+	informers := []ipfscluster.Informer{informer, popularityInf}
+
+	if informer.Name() != pinnedbytes.MetricName {
+		pinnedbytesInf, err := pinnedbytes.NewInformer(cfgs.pinnedbytesInfCfg)
+		checkErr("creating pinnedbytes informer", err)
+		informers = append(informers, pinnedbytesInf)
+	}
 
 	ipfscluster.ReadyTimeout = cfgs.raftCfg.WaitForLeaderTimeout + 5*time.Second
 
@@ -151,8 +176,6 @@ func createCluster(
 	tracer, err := observations.SetupTracing(cfgs.tracingCfg)
 	checkErr("setting up Tracing", err)
 
-	store := setupDatastore(c.String("consensus"), ident, cfgs)
-
 	cons, err := setupConsensus(
 		c.String("consensus"),
 		host,
@@ -172,7 +195,7 @@ func createCluster(
 		peersF = cons.Peers
 	}
 
-	mon, err := pubsubmon.New(ctx, cfgs.pubsubmonCfg, pubsub, peersF)
+	mon, err := pubsubmon.New(ctx, cfgs.pubsubmonCfg, pubsub, peersF, store)
 	if err != nil {
 		store.Close()
 		checkErr("setting up PeerMonitor", err)
@@ -190,11 +213,19 @@ func createCluster(
 		tracker,
 		mon,
 		alloc,
-		informer,
+		informers,
 		tracer,
 	)
 }
 
+// applyJoinToken sets the cluster secret carried by a join token and
+// trusts the peer that issued it.
+func applyJoinToken(cfgs *cfgs, info *ipfscluster.JoinTokenInfo) {
+	cfgs.clusterCfg.Secret = info.Secret
+	trusted := append(cfgs.crdtCfg.TrustedPeers, info.Peer)
+	cfgs.crdtCfg.TrustedPeers = uniquePeers(trusted)
+}
+
 // bootstrap will bootstrap this peer to one of the bootstrap addresses
 // if there are any.
 func bootstrap(ctx context.Context, cluster *ipfscluster.Cluster, bootstraps []ma.Multiaddr) {
@@ -253,28 +284,75 @@ Note that this may corrupt the local cluster state.
 	}
 }
 
-func setupAllocation(
-	name string,
-	diskInfCfg *disk.Config,
-	numpinInfCfg *numpin.Config,
-) (ipfscluster.Informer, ipfscluster.PinAllocator) {
-	switch name {
-	case "disk", "disk-freespace":
-		informer, err := disk.NewInformer(diskInfCfg)
-		checkErr("creating informer", err)
-		return informer, descendalloc.NewAllocator()
-	case "disk-reposize":
-		informer, err := disk.NewInformer(diskInfCfg)
-		checkErr("creating informer", err)
-		return informer, ascendalloc.NewAllocator()
-	case "numpin", "pincount":
-		informer, err := numpin.NewInformer(numpinInfCfg)
-		checkErr("creating informer", err)
-		return informer, ascendalloc.NewAllocator()
-	default:
+// allocationStrategy names the informer and the allocator used by one of the
+// allocation strategies that can be passed to the daemon.
+type allocationStrategy struct {
+	informer  string
+	allocator string
+}
+
+var allocationStrategies = map[string]allocationStrategy{
+	"disk":           {"disk", "descendalloc"},
+	"disk-freespace": {"disk", "descendalloc"},
+	"disk-reposize":  {"disk", "ascendalloc"},
+	"numpin":         {"numpin", "ascendalloc"},
+	"pincount":       {"numpin", "ascendalloc"},
+	"pinnedbytes":    {"pinnedbytes", "ascendalloc"},
+}
+
+// setupAllocation creates the informer and the allocator used by the given
+// allocation strategy, unless they are selected in the cluster
+// configuration.
+func setupAllocation(name string, cfgs *cfgs) (ipfscluster.Informer, ipfscluster.PinAllocator) {
+	informerName := cfgs.clusterCfg.AllocationInformer
+	allocatorName := cfgs.clusterCfg.Allocator
+
+	strategy, ok := allocationStrategies[name]
+	if !ok && (informerName == "" || allocatorName == "") {
 		err := errors.New("unknown allocation strategy")
 		checkErr("", err)
-		return nil, nil
+	}
+	if informerName == "" {
+		informerName = strategy.informer
+	}
+	if allocatorName == "" {
+		allocatorName = strategy.allocator
+	}
+
+	informer, err := setupInformer(informerName, cfgs)
+	checkErr("creating informer", err)
+	alloc, err := setupAllocator(allocatorName, cfgs)
+	checkErr("creating allocator", err)
+	return informer, alloc
+}
+
+// setupInformer creates a built-in informer or one compiled in through the
+// registry.
+func setupInformer(name string, cfgs *cfgs) (ipfscluster.Informer, error) {
+	switch name {
+	case "disk":
+		return disk.NewInformer(cfgs.diskInfCfg)
+	case "numpin":
+		return numpin.NewInformer(cfgs.numpinInfCfg)
+	case "pinnedbytes":
+		return pinnedbytes.NewInformer(cfgs.pinnedbytesInfCfg)
+	default:
+		return cfgs.registryCfgs.NewInformer(name)
+	}
+}
+
+// setupAllocator creates a built-in allocator or one compiled in through
+// the registry.
+func setupAllocator(name string, cfgs *cfgs) (ipfscluster.PinAllocator, error) {
+	switch name {
+	case "ascendalloc":
+		return ascendalloc.NewAllocator(), nil
+	case "descendalloc":
+		return descendalloc.NewAllocator(), nil
+	case "webhookalloc":
+		return webhookalloc.NewAllocator(cfgs.webhookAllocCfg)
+	default:
+		return cfgs.registryCfgs.NewAllocator(name)
 	}
 }
 
@@ -284,14 +362,15 @@ func setupPinTracker(
 	mapCfg *maptracker.Config,
 	statelessCfg *stateless.Config,
 	peerName string,
+	store ds.Datastore,
 ) ipfscluster.PinTracker {
 	switch name {
 	case "map":
-		ptrk := maptracker.NewMapPinTracker(mapCfg, h.ID(), peerName)
+		ptrk := maptracker.NewMapPinTracker(mapCfg, h.ID(), peerName, store)
 		logger.Debug("map pintracker loaded")
 		return ptrk
 	case "stateless":
-		ptrk := stateless.New(statelessCfg, h.ID(), peerName)
+		ptrk := stateless.New(statelessCfg, h.ID(), peerName, store)
 		logger.Debug("stateless pintracker loaded")
 		return ptrk
 	default:
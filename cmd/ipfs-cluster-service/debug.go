@@ -0,0 +1,382 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/api/rest/client"
+	"github.com/ipfs/ipfs-cluster/version"
+
+	manet "github.com/multiformats/go-multiaddr-net"
+	cli "github.com/urfave/cli"
+)
+
+// redactedValue replaces sensitive values in the debug bundle.
+const redactedValue = "<redacted>"
+
+// sensitiveKeys are the (partial) names of configuration keys whose values
+// are never included in debug bundles.
+var sensitiveKeys = []string{
+	"secret",
+	"private_key",
+	"password",
+	"credentials",
+	"token",
+}
+
+// sensitiveSectionKeys are the names of configuration keys whose values are
+// only sensitive inside a given section. For example, the webhook allocator
+// headers usually carry authorization tokens, while the REST API headers
+// are harmless and useful when debugging.
+var sensitiveSectionKeys = map[string][]string{
+	"webhookalloc": {"headers"},
+}
+
+// bundle writes the files of a debug bundle into a gzipped tarball. Errors
+// gathering any of the files do not abort the bundle: they are collected
+// and written to an errors.txt file.
+type bundle struct {
+	tw     *tar.Writer
+	errors []string
+}
+
+func (b *bundle) addFile(name string, data []byte) {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	err := b.tw.WriteHeader(hdr)
+	if err == nil {
+		_, err = b.tw.Write(data)
+	}
+	if err != nil {
+		b.addError(name, err)
+	}
+}
+
+func (b *bundle) addJSON(name string, obj interface{}, err error) {
+	if err != nil {
+		b.addError(name, err)
+		return
+	}
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		b.addError(name, err)
+		return
+	}
+	b.addFile(name, data)
+}
+
+func (b *bundle) addError(name string, err error) {
+	out("warning: %s not included: %s\n", name, err)
+	b.errors = append(b.errors, fmt.Sprintf("%s: %s", name, err))
+}
+
+// debugBundle gathers information useful to debug issues with this peer
+// into a single tarball which can be attached to support requests.
+func debugBundle(c *cli.Context) error {
+	output := c.String("output")
+	if output == "" {
+		output = fmt.Sprintf("ipfs-cluster-debug-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+
+	f, err := os.Create(output)
+	checkErr("creating bundle file", err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	b := &bundle{tw: tar.NewWriter(gz)}
+
+	b.addFile("version.txt", []byte(version.Version.String()+"\n"))
+
+	for _, path := range []string{configPath, identityPath} {
+		name := filepath.Base(path)
+		data, err := redactedJSONFile(path)
+		if err != nil {
+			b.addError(name, err)
+			continue
+		}
+		b.addFile(name, data)
+	}
+
+	for _, path := range c.StringSlice("log-file") {
+		name := "logs/" + filepath.Base(path)
+		data, err := tailFile(path, c.Int("log-lines"))
+		if err != nil {
+			b.addError(name, err)
+			continue
+		}
+		b.addFile(name, data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Duration("timeout"))
+	defer cancel()
+
+	cfgMgr, cfgs := makeConfigs()
+	defer cfgMgr.Shutdown()
+	err = cfgMgr.LoadJSONFileAndEnv(configPath)
+	if err != nil {
+		b.addError("daemon information", fmt.Errorf("loading configuration: %s", err))
+	} else {
+		addDaemonInfo(ctx, b, cfgs)
+		addProfiles(ctx, b, cfgs)
+	}
+
+	if len(b.errors) > 0 {
+		b.addFile("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n"))
+	}
+
+	checkErr("writing bundle", b.tw.Close())
+	checkErr("writing bundle", gz.Close())
+	out("debug bundle written to %s\n", output)
+	return nil
+}
+
+// addDaemonInfo queries the REST API of the running peer for its
+// consensus, tracker and connectivity status.
+func addDaemonInfo(ctx context.Context, b *bundle, cfgs *cfgs) {
+	apiCfg := cfgs.apiCfg
+	clientCfg := &client.Config{
+		APIAddr:      apiCfg.HTTPListenAddr,
+		SSL:          apiCfg.TLS != nil,
+		NoVerifyCert: true,
+		Timeout:      30 * time.Second,
+	}
+	if user, pw, ok := adminCredentials(apiCfg.BasicAuthCreds, apiCfg.BasicAuthRoles); ok {
+		clientCfg.Username = user
+		clientCfg.Password = pw
+	}
+
+	cl, err := client.NewDefaultClient(clientCfg)
+	if err != nil {
+		b.addError("daemon information", err)
+		return
+	}
+
+	id, err := cl.ID(ctx)
+	b.addJSON("daemon/id.json", id, err)
+	if err != nil {
+		// The daemon is likely not running. Do not insist.
+		return
+	}
+
+	peers, err := cl.Peers(ctx)
+	b.addJSON("daemon/peers.json", peers, err)
+
+	graph, err := cl.GetConnectGraph(ctx)
+	b.addJSON("daemon/connection_graph.json", graph, err)
+
+	queued, err := cl.StatusAll(
+		ctx,
+		api.TrackerStatusQueued|api.TrackerStatusPinning|api.TrackerStatusUnpinning,
+		true,
+	)
+	b.addJSON("daemon/tracker_queue.json", queued, err)
+
+	errored, err := cl.StatusAll(ctx, api.TrackerStatusError, true)
+	b.addJSON("daemon/tracker_errors.json", errored, err)
+
+	syncs, err := cl.LastStateSync(ctx, true)
+	b.addJSON("daemon/last_state_sync.json", syncs, err)
+}
+
+// addProfiles fetches goroutine and heap profiles from the pprof endpoint
+// of the running peer, which is only available when stats are enabled.
+func addProfiles(ctx context.Context, b *bundle, cfgs *cfgs) {
+	if !cfgs.metricsCfg.EnableStats {
+		b.addError("profiles", fmt.Errorf("metrics.enable_stats is disabled"))
+		return
+	}
+
+	_, addr, err := manet.DialArgs(cfgs.metricsCfg.PrometheusEndpoint)
+	if err != nil {
+		b.addError("profiles", err)
+		return
+	}
+
+	profiles := []struct {
+		name string
+		path string
+	}{
+		{"profiles/goroutine.txt", "/debug/pprof/goroutine?debug=2"},
+		{"profiles/heap.pb.gz", "/debug/pprof/heap"},
+	}
+
+	for _, p := range profiles {
+		data, err := httpGet(ctx, "http://"+addr+p.path)
+		if err != nil {
+			b.addError(p.name, err)
+			continue
+		}
+		b.addFile(p.name, data)
+	}
+}
+
+func httpGet(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// adminCredentials returns the credentials of a REST API user with the
+// admin role, if any are configured.
+func adminCredentials(creds, roles map[string]string) (string, string, bool) {
+	users := make([]string, 0, len(creds))
+	for user := range creds {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	for _, user := range users {
+		if role := roles[user]; role == "" || role == "admin" {
+			return user, creds[user], true
+		}
+	}
+	return "", "", false
+}
+
+// redactedJSONFile reads a JSON file and replaces any sensitive values in
+// it.
+func redactedJSONFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var obj interface{}
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(redactJSON(obj))
+	return buf.Bytes(), err
+}
+
+// redactJSON replaces the values of sensitive keys in a decoded JSON
+// object. For objects under sensitive keys (i.e. basic auth credentials),
+// only their values are redacted.
+func redactJSON(obj interface{}) interface{} {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if !isSensitiveKey(key) {
+				redactSectionKeys(key, val)
+				v[key] = redactJSON(val)
+				continue
+			}
+			v[key] = redactValue(val)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactJSON(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactSectionKeys redacts the values of the keys in a configuration
+// section which are only sensitive in that section.
+func redactSectionKeys(section string, obj interface{}) {
+	keys, ok := sensitiveSectionKeys[strings.ToLower(section)]
+	if !ok {
+		return
+	}
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, k := range keys {
+		if val, ok := m[k]; ok {
+			m[k] = redactValue(val)
+		}
+	}
+}
+
+// redactValue returns the redacted version of a sensitive value. Objects keep
+// their keys and only their values are redacted.
+func redactValue(val interface{}) interface{} {
+	if m, ok := val.(map[string]interface{}); ok {
+		for k := range m {
+			m[k] = redactedValue
+		}
+		return m
+	}
+	if val != nil && val != "" {
+		return redactedValue
+	}
+	return val
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// tailFile returns the last n lines of a file.
+func tailFile(path string, n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make([]string, 0, n)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(lines) == n {
+			lines = lines[1:]
+		}
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, l := range lines {
+		buf.WriteString(l)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
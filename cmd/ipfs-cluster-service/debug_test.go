@@ -0,0 +1,115 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testDebugConfig = []byte(`{
+  "cluster": {
+    "secret": "2588b80d5cb05374fa142aed6cbb047d1f4ef8ef15e37eba68c65b9d30df67ed"
+  },
+  "api": {
+    "restapi": {
+      "basic_auth_credentials": {
+        "admin": "adminpass"
+      },
+      "headers": {
+        "Access-Control-Allow-Origin": ["*"]
+      }
+    }
+  },
+  "allocator": {
+    "webhookalloc": {
+      "endpoint": "https://allocator.example.com/allocate",
+      "headers": {
+        "Authorization": "Bearer abcdef"
+      }
+    }
+  }
+}`)
+
+func TestDebugBundleRedactsConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debug-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "service.json")
+	err = ioutil.WriteFile(path, testDebugConfig, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := redactedJSONFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	b := &bundle{tw: tar.NewWriter(&buf)}
+	b.addFile("service.json", data)
+	if err := b.tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.errors) > 0 {
+		t.Fatal(b.errors)
+	}
+
+	tr := tar.NewReader(&buf)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "service.json" {
+		t.Fatal("unexpected file in bundle:", hdr.Name)
+	}
+	bundled, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"2588b80d5cb0", "adminpass", "Bearer abcdef"} {
+		if bytes.Contains(bundled, []byte(secret)) {
+			t.Errorf("bundle contains %q", secret)
+		}
+	}
+
+	var cfg struct {
+		API struct {
+			RestAPI struct {
+				Credentials map[string]string   `json:"basic_auth_credentials"`
+				Headers     map[string][]string `json:"headers"`
+			} `json:"restapi"`
+		} `json:"api"`
+		Allocator struct {
+			WebhookAlloc struct {
+				Endpoint string            `json:"endpoint"`
+				Headers  map[string]string `json:"headers"`
+			} `json:"webhookalloc"`
+		} `json:"allocator"`
+	}
+	err = json.Unmarshal(bundled, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.API.RestAPI.Credentials["admin"] != redactedValue {
+		t.Error("credentials should keep their users but not their passwords")
+	}
+	if cfg.API.RestAPI.Headers["Access-Control-Allow-Origin"][0] != "*" {
+		t.Error("restapi headers should not be redacted")
+	}
+	if cfg.Allocator.WebhookAlloc.Headers["Authorization"] != redactedValue {
+		t.Error("webhookalloc headers should be redacted")
+	}
+	if cfg.Allocator.WebhookAlloc.Endpoint == redactedValue {
+		t.Error("webhookalloc endpoint should not be redacted")
+	}
+}
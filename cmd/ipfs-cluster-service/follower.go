@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ipfs/ipfs-cluster/config"
+
+	shell "github.com/ipfs/go-ipfs-api"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+	cli "github.com/urfave/cli"
+)
+
+// publishFollowerConfig generates a signed follower configuration and adds
+// it to IPFS so that follower peers can be initialized from its CID or
+// from a DNSLink pointing to it.
+func publishFollowerConfig(c *cli.Context) error {
+	cfgMgr, ident, cfgs := makeAndLoadConfigs()
+	defer cfgMgr.Shutdown()
+
+	tmplMgr, tmplCfgs := makeConfigs()
+	defer tmplMgr.Shutdown()
+
+	if tmpl := c.String("template"); tmpl != "" {
+		bs, err := ioutil.ReadFile(tmpl)
+		checkErr("reading template", err)
+		err = tmplMgr.LoadJSON(bs)
+		checkErr("loading template", err)
+	} else {
+		err := tmplMgr.Default()
+		checkErr("generating default follower configuration", err)
+		// Peers keep their own hostname as peername.
+		tmplCfgs.clusterCfg.Peername = ""
+		tmplCfgs.clusterCfg.Secret = cfgs.clusterCfg.Secret
+		tmplCfgs.crdtCfg.ClusterName = cfgs.crdtCfg.ClusterName
+	}
+
+	for _, a := range c.StringSlice("peer-address") {
+		addr, err := ma.NewMultiaddr(a)
+		checkErr("parsing peer address (%s)", err, a)
+		tmplCfgs.clusterCfg.PeerAddresses = append(tmplCfgs.clusterCfg.PeerAddresses, addr)
+	}
+
+	// The publishing peer signs the configuration, so it must be
+	// trusted by the followers.
+	trusted := append(tmplCfgs.crdtCfg.TrustedPeers, cfgs.crdtCfg.TrustedPeers...)
+	trusted = append(trusted, ident.ID)
+	tmplCfgs.crdtCfg.TrustedPeers = uniquePeers(trusted)
+
+	payload, err := tmplMgr.ToJSON()
+	checkErr("generating follower configuration", err)
+
+	signed, err := config.SignJSON(payload, ident.PrivateKey)
+	checkErr("signing follower configuration", err)
+
+	if c.Bool("no-add") {
+		fmt.Printf("%s\n", signed)
+		return nil
+	}
+
+	dir, err := ioutil.TempDir("", "ipfs-cluster-follower")
+	checkErr("creating temporary folder", err)
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, config.FollowerConfigName), signed, 0600)
+	checkErr("writing follower configuration", err)
+
+	_, nodeAddr, err := manet.DialArgs(cfgs.ipfshttpCfg.NodeAddr)
+	checkErr("parsing IPFS node address", err)
+
+	sh := shell.NewShell(nodeAddr)
+	sh.SetTimeout(cfgs.ipfshttpCfg.IPFSRequestTimeout)
+	root, err := sh.AddDir(dir)
+	checkErr("adding follower configuration to IPFS", err)
+	if root == "" {
+		checkErr("", fmt.Errorf("IPFS (%s) did not return a CID for the follower configuration", nodeAddr))
+	}
+
+	out("follower configuration signed by %s and added to IPFS\n\n", ident.ID.Pretty())
+	fmt.Printf("/ipfs/%s/%s\n", root, config.FollowerConfigName)
+	out(`
+Follower peers can be initialized with:
+
+  $ ipfs-cluster-follow <clusterName> init %s
+
+To publish it under a domain name, set the following DNS TXT record and
+initialize followers with the domain instead:
+
+  _dnslink.<domain>  TXT  "dnslink=/ipfs/%s"
+
+Remember to pin the configuration (or add it to the cluster) so that it
+stays available.
+`, root, root)
+	return nil
+}
+
+func uniquePeers(peers []peer.ID) []peer.ID {
+	seen := make(map[peer.ID]struct{}, len(peers))
+	result := make([]peer.ID, 0, len(peers))
+	for _, p := range peers {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		result = append(result, p)
+	}
+	return result
+}
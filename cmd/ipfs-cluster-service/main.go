@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"time"
 
 	ipfscluster "github.com/ipfs/ipfs-cluster"
 	"github.com/ipfs/ipfs-cluster/config"
@@ -31,6 +32,7 @@ const (
 
 const (
 	stateCleanupPrompt           = "The peer state will be removed.  Existing pins may be lost."
+	staleCleanupPrompt           = "Stale data will be removed from the peer datastore."
 	configurationOverwritePrompt = "The configuration file will be overwritten."
 )
 
@@ -93,6 +95,11 @@ $ ipfs-cluster-service daemon
 Launch a peer and join existing cluster:
 
 $ ipfs-cluster-service daemon --bootstrap /ip4/192.168.1.2/tcp/9096/ipfs/QmPSoSaPXpyunaBwHs1rZBKYSqRV4bLRk32VGYLuvdrypL
+
+Launch a peer and join existing cluster with a token obtained with
+"ipfs-cluster-ctl peers token create" (sets the cluster secret):
+
+$ ipfs-cluster-service daemon --join-token <token>
 `,
 	programName,
 	programName,
@@ -319,6 +326,10 @@ remove the %s file first and clean any Raft state.
 					Name:  "bootstrap, j",
 					Usage: "join a cluster providing an existing peers multiaddress(es)",
 				},
+				cli.StringFlag{
+					Name:  "join-token",
+					Usage: "join a cluster with a token from \"ipfs-cluster-ctl peers token create\". Saves its secret to the configuration",
+				},
 				cli.BoolFlag{
 					Name:   "leave, x",
 					Usage:  "remove peer from cluster on exit. Overrides \"leave_on_shutdown\"",
@@ -332,7 +343,7 @@ remove the %s file first and clean any Raft state.
 				cli.StringFlag{
 					Name:  "alloc, a",
 					Value: defaultAllocation,
-					Usage: "allocation strategy to use [disk-freespace,disk-reposize,numpin].",
+					Usage: "allocation strategy to use [disk-freespace,disk-reposize,numpin,pinnedbytes]. Overridden by \"allocation_informer\" and \"allocator\"",
 				},
 				cli.StringFlag{
 					Name:   "pintracker",
@@ -340,6 +351,10 @@ remove the %s file first and clean any Raft state.
 					Hidden: true,
 					Usage:  "pintracker to use [map,stateless].",
 				},
+				cli.BoolFlag{
+					Name:  "trust-state",
+					Usage: "assume the pins in the shared state are pinned and verify them in the background (stateless pintracker only). Overrides \"trust_state_on_startup\"",
+				},
 				cli.BoolFlag{
 					Name:  "stats",
 					Usage: "enable stats collection",
@@ -460,12 +475,22 @@ to import. If no argument is provided, stdin will be used.
 This command removes any persisted consensus data in this peer, including the
 current pinset (state). The next start of the peer will be like the first start
 to all effects. Peers may need to bootstrap and sync from scratch after this.
+
+With --stale, only data which is no longer useful is removed, and the pinset
+is kept: deleted pins left behind in the CRDT datastore (tombstones), pin
+operations left behind by the last run, metrics persisted for peers which have
+not been seen in 24 hours, and datastore keys which do not belong to any
+cluster component. The reclaimed space is reported.
 `,
 					Flags: []cli.Flag{
 						cli.BoolFlag{
 							Name:  "force, f",
 							Usage: "skip confirmation prompt",
 						},
+						cli.BoolFlag{
+							Name:  "stale",
+							Usage: "only remove stale data, keeping the pinset",
+						},
 						cli.StringFlag{
 							Name:  "consensus",
 							Value: "raft",
@@ -476,9 +501,13 @@ to all effects. Peers may need to bootstrap and sync from scratch after this.
 						locker.lock()
 						defer locker.tryUnlock()
 
+						prompt := stateCleanupPrompt
+						if c.Bool("stale") {
+							prompt = staleCleanupPrompt
+						}
 						confirm := fmt.Sprintf(
 							"%s Continue? [y/n]:",
-							stateCleanupPrompt,
+							prompt,
 						)
 						if !c.Bool("force") && !yesNoPrompt(confirm) {
 							return nil
@@ -487,6 +516,12 @@ to all effects. Peers may need to bootstrap and sync from scratch after this.
 						cfgMgr, ident, cfgs := makeAndLoadConfigs()
 						defer cfgMgr.Shutdown()
 						mgr := newStateManager(c.String("consensus"), ident, cfgs)
+						if c.Bool("stale") {
+							report, err := mgr.CleanStale()
+							checkErr("cleaning stale data", err)
+							printStaleCleanup(report)
+							return nil
+						}
 						checkErr("cleaning state", mgr.Clean())
 						logger.Info("data correctly cleaned up")
 						return nil
@@ -494,6 +529,162 @@ to all effects. Peers may need to bootstrap and sync from scratch after this.
 				},
 			},
 		},
+		{
+			Name:  "follower",
+			Usage: "Manages the configuration distributed to follower peers",
+			Subcommands: []cli.Command{
+				{
+					Name:  "publish",
+					Usage: "publish a signed follower configuration to IPFS",
+					Description: fmt.Sprintf(`
+This command generates a configuration for follower peers (run with
+ipfs-cluster-follow), signs it with this peer's identity and adds it to the
+configured IPFS daemon as a folder containing a "%s" file. The
+resulting CID (or a DNSLink domain pointing to it) can be used to initialize
+follower peers, which will fetch the latest configuration every time they
+start.
+
+By default, the configuration is generated from defaults, using the cluster
+secret and the CRDT cluster name of this peer. A full configuration file can
+be given with --template instead. In both cases, the CRDT trusted peers of
+this peer, along with this peer itself, are added to the trusted peers of the
+follower configuration. Use --peer-address to set the addresses which
+followers will use to connect to the cluster.
+`,
+						config.FollowerConfigName,
+					),
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "template, t",
+							Usage: "use the configuration in `FILE` as template",
+						},
+						cli.StringSliceFlag{
+							Name:  "peer-address",
+							Usage: "multiaddress of a cluster peer that followers should connect to",
+						},
+						cli.BoolFlag{
+							Name:  "no-add",
+							Usage: "print the signed configuration instead of adding it to IPFS",
+						},
+					},
+					Action: publishFollowerConfig,
+				},
+			},
+		},
+		{
+			Name:  "simulate",
+			Usage: "Report how pins would be allocated with a different configuration",
+			Description: `
+This command simulates, offline, how the pins in a state export would be
+allocated with a different allocation configuration, and reports how many
+pins every peer would hold before and after the change, along with the pins
+which could not be allocated. Nothing is modified: it can be used to plan
+allocator and replication factor changes before applying them to
+production.
+
+The state is read from a file produced by "state export". The metrics are
+read from a JSON file containing the peer metrics used for allocations, as
+produced by "ipfs-cluster-ctl --enc=json health metrics <name>". The output
+for the "allocatable" metric can be appended to it so that peers which do
+not accept new allocations are taken into account.
+
+The informer and the allocator are selected like in the daemon, using the
+configuration of this peer, and can be overridden with --informer and
+--allocator. By default, current allocations are kept whenever they remain
+valid, as a running cluster would do. With --reallocate, the whole pinset is
+allocated from scratch. Note that metrics are adjusted as pins are
+allocated during the simulation, but they already account for the current
+allocations.
+`,
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "state",
+					Usage: "read the state export from `FILE`",
+				},
+				cli.StringFlag{
+					Name:  "metrics",
+					Usage: "read the metrics snapshot from `FILE`",
+				},
+				cli.StringFlag{
+					Name:  "alloc, a",
+					Value: defaultAllocation,
+					Usage: "allocation strategy to use [disk-freespace,disk-reposize,numpin,pinnedbytes]. Overridden by \"allocation_informer\" and \"allocator\", and by --informer and --allocator",
+				},
+				cli.StringFlag{
+					Name:  "informer",
+					Usage: "name of the informer whose metrics are used for allocations",
+				},
+				cli.StringFlag{
+					Name:  "allocator",
+					Usage: "name of the allocator to simulate",
+				},
+				cli.IntFlag{
+					Name:  "replication-min, rmin",
+					Usage: "replication factor min for all regular pins (0 keeps the current ones)",
+				},
+				cli.IntFlag{
+					Name:  "replication-max, rmax",
+					Usage: "replication factor max for all regular pins (0 keeps the current ones)",
+				},
+				cli.BoolFlag{
+					Name:  "reallocate",
+					Usage: "ignore current allocations and allocate every pin from scratch",
+				},
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "print the report in JSON format",
+				},
+			},
+			Action: simulate,
+		},
+		{
+			Name:  "debug",
+			Usage: "Tools to debug and report issues with this peer",
+			Subcommands: []cli.Command{
+				{
+					Name:  "bundle",
+					Usage: "gather debugging information into a tarball",
+					Description: fmt.Sprintf(`
+This command gathers information useful to debug issues with this peer into
+a single tarball, which can be attached when filing support issues.
+
+The bundle includes the %s and %s files, with secrets, private
+keys and credentials redacted, and the last lines of any log files given with
+--log-file. When the peer is running, its REST API is used to obtain the
+peer and consensus status, the pins queued or in progress in the pin tracker
+and the connectivity between peers. Goroutine and heap profiles are included
+when metrics are enabled (metrics.enable_stats).
+
+Any information which could not be gathered is listed in errors.txt.
+`,
+						DefaultConfigFile,
+						DefaultIdentityFile,
+					),
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "output, o",
+							Usage: "write the bundle to `FILE` (default: ipfs-cluster-debug-<date>.tar.gz)",
+						},
+						cli.StringSliceFlag{
+							Name:  "log-file",
+							Usage: "include the last lines of the log in `FILE`",
+						},
+						cli.IntFlag{
+							Name:  "log-lines",
+							Value: 1000,
+							Usage: "number of lines to include from each log file",
+						},
+						cli.DurationFlag{
+							Name:  "timeout",
+							Value: time.Minute,
+							Usage: "timeout for requests to the running peer",
+						},
+					},
+					Action: debugBundle,
+				},
+			},
+		},
 		{
 			Name:  "version",
 			Usage: "Prints the ipfs-cluster version",
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/api"
+
+	cli "github.com/urfave/cli"
+)
+
+// readPins reads a state export (one JSON pin after another).
+func readPins(path string) ([]*api.Pin, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pins []*api.Pin
+	dec := json.NewDecoder(f)
+	for {
+		var pin api.Pin
+		err := dec.Decode(&pin)
+		if err == io.EOF {
+			return pins, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		pins = append(pins, &pin)
+	}
+}
+
+// readMetrics reads metrics from a file containing JSON metrics or lists
+// of metrics, one after another, such as the output of several
+// "ipfs-cluster-ctl --enc=json health metrics" commands.
+func readMetrics(path string) ([]*api.Metric, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var metrics []*api.Metric
+	dec := json.NewDecoder(f)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return metrics, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			var list []*api.Metric
+			err = json.Unmarshal(raw, &list)
+			metrics = append(metrics, list...)
+		} else {
+			var m api.Metric
+			err = json.Unmarshal(raw, &m)
+			metrics = append(metrics, &m)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func simulate(c *cli.Context) error {
+	statePath := c.String("state")
+	metricsPath := c.String("metrics")
+	if statePath == "" || metricsPath == "" {
+		checkErr("", errors.New("--state and --metrics are needed"))
+	}
+
+	cfgMgr, cfgs := makeConfigs()
+	defer cfgMgr.Shutdown()
+	checkErr("reading configuration", cfgMgr.LoadJSONFileAndEnv(configPath))
+
+	// Only the in-memory configuration is modified, so that the
+	// components are created with the given names.
+	if name := c.String("informer"); name != "" {
+		cfgs.clusterCfg.AllocationInformer = name
+	}
+	if name := c.String("allocator"); name != "" {
+		cfgs.clusterCfg.Allocator = name
+	}
+	informer, alloc := setupAllocation(c.String("alloc"), cfgs)
+
+	pins, err := readPins(statePath)
+	checkErr("reading state", err)
+	metrics, err := readMetrics(metricsPath)
+	checkErr("reading metrics", err)
+
+	report, err := ipfscluster.SimulateAllocations(
+		context.Background(),
+		pins,
+		metrics,
+		ipfscluster.SimulationOptions{
+			Informer:             informer,
+			Allocator:            alloc,
+			ReplicationFactorMin: c.Int("replication-min"),
+			ReplicationFactorMax: c.Int("replication-max"),
+			Reallocate:           c.Bool("reallocate"),
+		},
+	)
+	checkErr("simulating allocations", err)
+
+	if c.Bool("json") {
+		data, err := json.MarshalIndent(report, "", "  ")
+		checkErr("generating json output", err)
+		fmt.Printf("%s\n", data)
+		return nil
+	}
+	printSimulationReport(report)
+	return nil
+}
+
+func printSimulationReport(r *ipfscluster.SimulationReport) {
+	fmt.Printf("Informer: %s | Allocator: %s\n", r.Informer, r.Allocator)
+	fmt.Printf("Pins: %d | Changed: %d | Failed: %d\n\n", r.Pins, r.Changed, len(r.Failed))
+
+	fmt.Printf("%-52s %20s %8s %8s %8s %8s\n", "PEER", "METRIC", "BEFORE", "AFTER", "ADDED", "REMOVED")
+	for _, p := range r.Peers {
+		metric := p.Metric
+		if metric == "" {
+			metric = "-"
+		}
+		fmt.Printf("%-52s %20s %8d %8d %8d %8d\n", p.Peer.Pretty(), metric, p.Before, p.After, p.Added, p.Removed)
+	}
+
+	if len(r.Failed) > 0 {
+		fmt.Printf("\nFailed allocations:\n")
+		for _, f := range r.Failed {
+			fmt.Printf("  - %s: %s\n", f.Cid, f.Error)
+		}
+	}
+}
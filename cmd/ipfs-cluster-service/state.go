@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	ipfscluster "github.com/ipfs/ipfs-cluster"
 	"github.com/ipfs/ipfs-cluster/api"
@@ -14,10 +15,13 @@ import (
 	"github.com/ipfs/ipfs-cluster/consensus/raft"
 	"github.com/ipfs/ipfs-cluster/datastore/badger"
 	"github.com/ipfs/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs/ipfs-cluster/monitor/pubsubmon"
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
 	"github.com/ipfs/ipfs-cluster/pstoremgr"
 	"github.com/ipfs/ipfs-cluster/state"
 
 	ds "github.com/ipfs/go-datastore"
+	query "github.com/ipfs/go-datastore/query"
 )
 
 type stateManager interface {
@@ -25,6 +29,7 @@ type stateManager interface {
 	ExportState(io.Writer) error
 	GetStore() (ds.Datastore, error)
 	Clean() error
+	CleanStale() (*staleCleanup, error)
 }
 
 func newStateManager(consensus string, ident *config.Identity, cfgs *cfgs) stateManager {
@@ -98,6 +103,11 @@ func (raftsm *raftStateManager) Clean() error {
 	return raft.CleanupRaft(raftsm.cfgs.raftCfg)
 }
 
+// CleanStale does nothing, as raft does not use a persistent datastore.
+func (raftsm *raftStateManager) CleanStale() (*staleCleanup, error) {
+	return &staleCleanup{}, nil
+}
+
 type crdtStateManager struct {
 	ident *config.Identity
 	cfgs  *cfgs
@@ -161,6 +171,147 @@ func (crdtsm *crdtStateManager) Clean() error {
 	return crdt.Clean(context.Background(), crdtsm.cfgs.crdtCfg, store)
 }
 
+func (crdtsm *crdtStateManager) CleanStale() (*staleCleanup, error) {
+	store, err := crdtsm.GetStore()
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return cleanStale(context.Background(), crdtsm.cfgs.crdtCfg, store)
+}
+
+// staleMetricAge is the age after which persisted metrics are considered
+// stale (i.e. they belong to peers which have left the cluster).
+var staleMetricAge = 24 * time.Hour
+
+// staleCleanup summarizes the data removed by cleanStale.
+type staleCleanup struct {
+	Tombstones cleanupCount
+	Operations cleanupCount
+	Metrics    cleanupCount
+	Orphans    cleanupCount
+	DiskBefore uint64
+	DiskAfter  uint64
+}
+
+// cleanupCount holds the number and size (keys and values) of removed
+// datastore entries.
+type cleanupCount struct {
+	Entries int
+	Size    uint64
+}
+
+// cleanStale removes data which accumulates in the datastore over time
+// without being useful: deleted elements in the CRDT set, tracker
+// operations left behind by a previous run, persisted metrics which have
+// not been updated in a long time and keys which do not belong to any
+// cluster component.
+func cleanStale(ctx context.Context, crdtCfg *crdt.Config, store ds.Datastore) (*staleCleanup, error) {
+	var err error
+	report := &staleCleanup{}
+	report.DiskBefore, _ = ds.DiskUsage(store)
+
+	report.Tombstones.Entries, report.Tombstones.Size, err = crdt.CleanTombstones(ctx, crdtCfg, store)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tracker discards these on start.
+	report.Operations, err = deleteEntries(store, query.Query{Prefix: optracker.CompactionNamespace, KeysOnly: true}, func(e query.Entry) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Metrics, err = deleteEntries(store, query.Query{Prefix: pubsubmon.DatastoreNamespace}, func(e query.Entry) bool {
+		var m struct {
+			ReceivedAt int64 `json:"received_at"`
+		}
+		if err := json.Unmarshal(e.Value, &m); err != nil {
+			return true
+		}
+		return time.Since(time.Unix(0, m.ReceivedAt)) > staleMetricAge
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	known := []ds.Key{
+		ds.NewKey(crdtCfg.DatastoreNamespace),
+		ds.NewKey(optracker.CompactionNamespace),
+		ds.NewKey(pubsubmon.DatastoreNamespace),
+		ds.NewKey(pstoremgr.DatastoreNamespace),
+	}
+	report.Orphans, err = deleteEntries(store, query.Query{KeysOnly: true}, func(e query.Entry) bool {
+		k := ds.NewKey(e.Key)
+		for _, ns := range known {
+			if ns.Equal(k) || ns.IsAncestorOf(k) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if gcds, ok := store.(ds.GCDatastore); ok {
+		err := gcds.CollectGarbage()
+		if err != nil {
+			return nil, err
+		}
+	}
+	report.DiskAfter, _ = ds.DiskUsage(store)
+	return report, nil
+}
+
+func printStaleCleanup(r *staleCleanup) {
+	fmt.Printf("Deleted pins (tombstones): %d entries (%d bytes)\n", r.Tombstones.Entries, r.Tombstones.Size)
+	fmt.Printf("Tracker operations:        %d entries (%d bytes)\n", r.Operations.Entries, r.Operations.Size)
+	fmt.Printf("Stale metrics:             %d entries (%d bytes)\n", r.Metrics.Entries, r.Metrics.Size)
+	fmt.Printf("Orphaned keys:             %d entries (%d bytes)\n", r.Orphans.Entries, r.Orphans.Size)
+	if r.DiskBefore > 0 {
+		fmt.Printf("Datastore disk usage:      %d bytes (before: %d bytes)\n", r.DiskAfter, r.DiskBefore)
+	}
+}
+
+// deleteEntries removes the datastore entries matching the given query for
+// which the given function returns true.
+func deleteEntries(store ds.Datastore, q query.Query, stale func(query.Entry) bool) (cleanupCount, error) {
+	var count cleanupCount
+	results, err := store.Query(q)
+	if err != nil {
+		return count, err
+	}
+
+	var keys []ds.Key
+	for r := range results.Next() {
+		if r.Error != nil {
+			results.Close()
+			return count, r.Error
+		}
+		if stale(r.Entry) {
+			keys = append(keys, ds.NewKey(r.Key))
+		}
+	}
+	results.Close()
+
+	for _, k := range keys {
+		size, err := store.GetSize(k)
+		if err != nil {
+			return count, err
+		}
+		err = store.Delete(k)
+		if err != nil {
+			return count, err
+		}
+		count.Entries++
+		count.Size += uint64(len(k.String()) + size)
+	}
+	return count, nil
+}
+
 func importState(r io.Reader, st state.State) error {
 	ctx := context.Background()
 	dec := json.NewDecoder(r)
@@ -9,11 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"path/filepath"
 	"sync"
 	"time"
 
 	logging "github.com/ipfs/go-log"
+	peer "github.com/libp2p/go-libp2p-peer"
 )
 
 var logger = logging.Logger("config")
@@ -108,6 +110,14 @@ type Manager struct {
 	// so it can be saved to the same place.
 	path    string
 	saveMux sync.Mutex
+
+	// if the configuration was obtained from a remote source,
+	// track it so that it is saved instead of the fetched contents.
+	source string
+	// when set, the remote configuration must be signed by this peer.
+	sourceSigner peer.ID
+	// the peer which signed the loaded remote configuration, if any.
+	signer peer.ID
 }
 
 // NewManager returns a correctly initialized Manager
@@ -169,7 +179,9 @@ func (cfg *Manager) watchSave(save <-chan struct{}) {
 // saved using json. Most configuration keys are converted into simple types
 // like strings, and key names aim to be self-explanatory for the user.
 type jsonConfig struct {
-	Cluster      *json.RawMessage `json:"cluster"`
+	Source       string           `json:"source,omitempty"`
+	SourceSigner string           `json:"source_signer,omitempty"`
+	Cluster      *json.RawMessage `json:"cluster,omitempty"`
 	Consensus    jsonSection      `json:"consensus,omitempty"`
 	API          jsonSection      `json:"api,omitempty"`
 	IPFSConn     jsonSection      `json:"ipfs_connector,omitempty"`
@@ -326,6 +338,68 @@ func (cfg *Manager) LoadJSONFromFile(path string) error {
 	return err
 }
 
+// LoadJSONFromHTTPSource reads a Configuration file from a URL and parses
+// it. The configuration is not saved to disk in full. Instead, only the
+// source is written, so that the configuration is obtained again every
+// time it is loaded. Configurations wrapped with SignJSON are verified
+// and unwrapped. When a source signer has been set, only configurations
+// signed by it are accepted. See LoadJSON too.
+func (cfg *Manager) LoadJSONFromHTTPSource(url string) error {
+	logger.Infof("loading configuration from %s", url)
+	cfg.source = url
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unsuccessful request (%d) fetching %s", resp.StatusCode, url)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	cfg.signer = ""
+	if isSignedJSON(body) {
+		payload, signer, err := OpenSignedJSON(body)
+		if err != nil {
+			return fmt.Errorf("error verifying configuration from %s: %s", url, err)
+		}
+		if cfg.sourceSigner != "" && signer != cfg.sourceSigner {
+			return fmt.Errorf("configuration from %s is signed by %s and not by %s", url, signer.Pretty(), cfg.sourceSigner.Pretty())
+		}
+		cfg.signer = signer
+		body = payload
+	} else if cfg.sourceSigner != "" {
+		return fmt.Errorf("configuration from %s is not signed", url)
+	}
+
+	return cfg.LoadJSON(body)
+}
+
+// Source returns the URL the configuration was obtained from, or an
+// empty string if it was not loaded from a remote source.
+func (cfg *Manager) Source() string {
+	return cfg.source
+}
+
+// SetSourceSigner makes remote configurations loaded by this Manager
+// only acceptable when signed by the given peer. It is saved along with
+// the source.
+func (cfg *Manager) SetSourceSigner(p peer.ID) {
+	cfg.sourceSigner = p
+}
+
+// Signer returns the peer which signed the remote configuration, or an
+// empty ID if the configuration was not signed.
+func (cfg *Manager) Signer() peer.ID {
+	return cfg.signer
+}
+
 // LoadJSONFileAndEnv calls LoadJSONFromFile followed by ApplyEnvVars,
 // reading and parsing a Configuration file and then overriding fields
 // with any values found in environment variables.
@@ -350,6 +424,20 @@ func (cfg *Manager) LoadJSON(bs []byte) error {
 		return err
 	}
 
+	if jcfg.Source != "" {
+		if cfg.source != "" {
+			return errors.New("a remote configuration cannot point to another source")
+		}
+		if jcfg.SourceSigner != "" {
+			signer, err := peer.IDB58Decode(jcfg.SourceSigner)
+			if err != nil {
+				return fmt.Errorf("error parsing source_signer: %s", err)
+			}
+			cfg.sourceSigner = signer
+		}
+		return cfg.LoadJSONFromHTTPSource(jcfg.Source)
+	}
+
 	cfg.jsonCfg = jcfg
 
 	// Load Cluster section. Needs to have been registered
@@ -429,6 +517,14 @@ func (cfg *Manager) SaveJSON(path string) error {
 // ToJSON provides a JSON representation of the configuration by
 // generating JSON for all componenents registered.
 func (cfg *Manager) ToJSON() ([]byte, error) {
+	if cfg.source != "" {
+		jcfg := &jsonConfig{Source: cfg.source}
+		if cfg.sourceSigner != "" {
+			jcfg.SourceSigner = peer.IDB58Encode(cfg.sourceSigner)
+		}
+		return DefaultJSONMarshal(jcfg)
+	}
+
 	err := cfg.Validate()
 	if err != nil {
 		return nil, err
@@ -5,6 +5,9 @@ package config_test
 
 import (
 	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/ipfs/ipfs-cluster/config"
@@ -118,3 +121,111 @@ func TestManager_ToJSON(t *testing.T) {
 		t.Errorf("mismatch between got: %s and want: %s", got, want)
 	}
 }
+
+func TestManager_LoadJSONFromHTTPSource(t *testing.T) {
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"cluster": {"a": "b"}}`)
+	})
+	mux.HandleFunc("/nested", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"source": "%s/config"}`, ts.URL)
+	})
+
+	cfgMgr := setupConfigManager()
+	err := cfgMgr.LoadJSON([]byte(fmt.Sprintf(`{"source": "%s/config"}`, ts.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfgMgr.Source() != ts.URL+"/config" {
+		t.Error("unexpected source:", cfgMgr.Source())
+	}
+
+	// Only the source should be saved
+	got, err := cfgMgr.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("{\n  \"source\": \"%s/config\"\n}", ts.URL)
+	if string(got) != want {
+		t.Errorf("mismatch between got: %s and want: %s", got, want)
+	}
+
+	cfgMgr = setupConfigManager()
+	err = cfgMgr.LoadJSONFromHTTPSource(ts.URL + "/nested")
+	if err == nil {
+		t.Error("expected an error loading a nested source")
+	}
+
+	cfgMgr = setupConfigManager()
+	err = cfgMgr.LoadJSONFromHTTPSource(ts.URL + "/notfound")
+	if err == nil {
+		t.Error("expected an error loading from a wrong url")
+	}
+}
+
+func TestManager_LoadJSONFromSignedHTTPSource(t *testing.T) {
+	ident, err := config.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := config.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := config.SignJSON([]byte(`{"cluster": {"a": "b"}}`), ident.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	mux.HandleFunc("/signed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(signed)
+	})
+	mux.HandleFunc("/unsigned", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"cluster": {"a": "b"}}`)
+	})
+
+	cfgMgr := setupConfigManager()
+	err = cfgMgr.LoadJSON([]byte(fmt.Sprintf(
+		`{"source": "%s/signed", "source_signer": "%s"}`,
+		ts.URL,
+		ident.ID.Pretty(),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfgMgr.Signer() != ident.ID {
+		t.Error("unexpected signer:", cfgMgr.Signer())
+	}
+
+	got, err := cfgMgr.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("{\n  \"source\": \"%s/signed\",\n  \"source_signer\": \"%s\"\n}", ts.URL, ident.ID.Pretty())
+	if string(got) != want {
+		t.Errorf("mismatch between got: %s and want: %s", got, want)
+	}
+
+	cfgMgr = setupConfigManager()
+	cfgMgr.SetSourceSigner(other.ID)
+	err = cfgMgr.LoadJSONFromHTTPSource(ts.URL + "/signed")
+	if err == nil {
+		t.Error("expected an error loading a configuration signed by another peer")
+	}
+
+	cfgMgr = setupConfigManager()
+	cfgMgr.SetSourceSigner(ident.ID)
+	err = cfgMgr.LoadJSONFromHTTPSource(ts.URL + "/unsigned")
+	if err == nil {
+		t.Error("expected an error loading an unsigned configuration")
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// FollowerConfigName is the well-known name under which follower
+// configurations are published to IPFS. Sources pointing to an IPFS
+// directory (a CID, an /ipfs/ or an /ipns/ path or a DNSLink domain) are
+// expected to contain a file with this name.
+const FollowerConfigName = "follower.json"
+
+// signedJSON wraps a JSON payload (usually a configuration) along with the
+// public key of the signer and the signature of the payload.
+type signedJSON struct {
+	Payload   json.RawMessage `json:"payload"`
+	PublicKey string          `json:"public_key"`
+	Signature string          `json:"signature"`
+}
+
+// compactJSON returns the compact form of the given JSON. Signatures are
+// produced over compacted payloads so that they survive any re-indentation.
+func compactJSON(bs []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	err := json.Compact(&buf, bs)
+	return buf.Bytes(), err
+}
+
+// SignJSON wraps the given JSON payload in a signed envelope using the
+// given private key. The result can be verified and unwrapped with
+// OpenSignedJSON.
+func SignJSON(payload []byte, key crypto.PrivKey) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("a private key is needed to sign")
+	}
+
+	compact, err := compactJSON(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := key.Sign(compact)
+	if err != nil {
+		return nil, err
+	}
+
+	pubBytes, err := crypto.MarshalPublicKey(key.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+
+	return DefaultJSONMarshal(&signedJSON{
+		Payload:   compact,
+		PublicKey: base64.StdEncoding.EncodeToString(pubBytes),
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	})
+}
+
+// isSignedJSON returns true when the given JSON looks like an envelope
+// produced by SignJSON.
+func isSignedJSON(bs []byte) bool {
+	var env map[string]json.RawMessage
+	if err := json.Unmarshal(bs, &env); err != nil {
+		return false
+	}
+	_, ok := env["signature"]
+	return ok
+}
+
+// OpenSignedJSON verifies the signature of an envelope produced by
+// SignJSON and returns the wrapped payload along with the peer ID
+// corresponding to the key that signed it.
+func OpenSignedJSON(bs []byte) ([]byte, peer.ID, error) {
+	env := &signedJSON{}
+	err := json.Unmarshal(bs, env)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pubBytes, err := base64.StdEncoding.DecodeString(env.PublicKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("error decoding public key: %s", err)
+	}
+	pub, err := crypto.UnmarshalPublicKey(pubBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("error unmarshaling public key: %s", err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(env.Signature)
+	if err != nil {
+		return nil, "", fmt.Errorf("error decoding signature: %s", err)
+	}
+
+	compact, err := compactJSON(env.Payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ok, err := pub.Verify(compact, sig)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", errors.New("invalid signature")
+	}
+
+	signer, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		return nil, "", err
+	}
+	return compact, signer, nil
+}
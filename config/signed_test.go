@@ -0,0 +1,48 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSignJSON(t *testing.T) {
+	ident, err := NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{ "cluster": { "peername": "follower" } }`)
+	signed, err := SignJSON(payload, ident.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isSignedJSON(signed) {
+		t.Fatal("signed JSON not recognized as such")
+	}
+	if isSignedJSON(payload) {
+		t.Error("payload recognized as signed JSON")
+	}
+
+	opened, signer, err := OpenSignedJSON(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != ident.ID {
+		t.Error("unexpected signer:", signer)
+	}
+	if !bytes.Equal(opened, []byte(`{"cluster":{"peername":"follower"}}`)) {
+		t.Errorf("unexpected payload: %s", opened)
+	}
+
+	tampered := bytes.Replace(signed, []byte("follower"), []byte("trusted"), 1)
+	_, _, err = OpenSignedJSON(tampered)
+	if err == nil {
+		t.Error("expected an error opening a tampered payload")
+	}
+
+	_, err = SignJSON([]byte("not json"), ident.PrivateKey)
+	if err == nil {
+		t.Error("expected an error signing invalid JSON")
+	}
+}
@@ -31,7 +31,13 @@ var testingClusterCfg = []byte(`{
     "replication_factor": -1,
     "monitor_ping_interval": "250ms",
     "peer_watch_interval": "100ms",
-    "disable_repinning": false
+    "disable_repinning": false,
+    "rpc_compression": "snappy",
+    "rpc_class_budgets": {
+        "bulk": {
+            "max_concurrent": 20
+        }
+    }
 }`)
 
 var testingRaftCfg = []byte(`{
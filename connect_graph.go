@@ -1,7 +1,13 @@
 package ipfscluster
 
 import (
+	"context"
+	"sync"
+	"time"
+
+	inet "github.com/libp2p/go-libp2p-net"
 	peer "github.com/libp2p/go-libp2p-peer"
+	ping "github.com/libp2p/go-libp2p/p2p/protocol/ping"
 
 	"go.opencensus.io/trace"
 
@@ -9,17 +15,24 @@ import (
 	"github.com/ipfs/ipfs-cluster/rpcutil"
 )
 
+// pingTimeout limits how long to wait for a ping when measuring
+// latencies for the connectivity graph.
+var pingTimeout = 5 * time.Second
+
 // ConnectGraph returns a description of which cluster peers and ipfs
-// daemons are connected to each other
+// daemons are connected to each other, along with the latency of the
+// connections among cluster peers.
 func (c *Cluster) ConnectGraph() (api.ConnectGraph, error) {
 	ctx, span := trace.StartSpan(c.ctx, "cluster/ConnectGraph")
 	defer span.End()
 
 	cg := api.ConnectGraph{
-		ClusterID:     c.host.ID(),
-		IPFSLinks:     make(map[string][]peer.ID),
-		ClusterLinks:  make(map[string][]peer.ID),
-		ClustertoIPFS: make(map[string]peer.ID),
+		ClusterID:        c.host.ID(),
+		IPFSLinks:        make(map[string][]peer.ID),
+		ClusterLinks:     make(map[string][]peer.ID),
+		ClustertoIPFS:    make(map[string]peer.ID),
+		ClusterLatencies: make(map[string]map[string]time.Duration),
+		IPFSErrors:       make(map[string]string),
 	}
 	members, err := c.consensus.Peers(ctx)
 	if err != nil {
@@ -58,9 +71,72 @@ func (c *Cluster) ConnectGraph() (api.ConnectGraph, error) {
 		c.recordIPFSLinks(&cg, pID)
 	}
 
+	c.recordLatencies(ctx, &cg, members)
 	return cg, nil
 }
 
+// recordLatencies asks every cluster peer for the latency of its
+// connections to the others.
+func (c *Cluster) recordLatencies(ctx context.Context, cg *api.ConnectGraph, members []peer.ID) {
+	latencies := make([]map[string]time.Duration, len(members), len(members))
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, len(members))
+	defer rpcutil.MultiCancel(cancels)
+
+	ifaces := make([]interface{}, len(members), len(members))
+	for i := range latencies {
+		ifaces[i] = &latencies[i]
+	}
+
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		members,
+		"Cluster",
+		"PeerLatencies",
+		members,
+		ifaces,
+	)
+
+	for i, err := range errs {
+		p := peer.IDB58Encode(members[i])
+		if err != nil {
+			logger.Debugf("RPC error obtaining latencies from %s: %s", p, err)
+			continue
+		}
+		if len(latencies[i]) > 0 {
+			cg.ClusterLatencies[p] = latencies[i]
+		}
+	}
+}
+
+// peerLatencies measures the round-trip time to the given peers which this
+// peer is connected to.
+func (c *Cluster) peerLatencies(ctx context.Context, peers []peer.ID) map[string]time.Duration {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	latencies := make(map[string]time.Duration)
+
+	for _, p := range peers {
+		if p == c.id || c.host.Network().Connectedness(p) != inet.Connected {
+			continue
+		}
+		wg.Add(1)
+		go func(p peer.ID) {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, pingTimeout)
+			defer cancel()
+			res, ok := <-ping.Ping(pctx, c.host, p)
+			if !ok || res.Error != nil {
+				return
+			}
+			mu.Lock()
+			latencies[peer.IDB58Encode(p)] = res.RTT
+			mu.Unlock()
+		}(p)
+	}
+	wg.Wait()
+	return latencies
+}
+
 func (c *Cluster) recordClusterLinks(cg *api.ConnectGraph, p string, peers []*api.ID) (bool, *api.ID) {
 	selfConnection := false
 	var pID *api.ID
@@ -82,6 +158,7 @@ func (c *Cluster) recordClusterLinks(cg *api.ConnectGraph, p string, peers []*ap
 func (c *Cluster) recordIPFSLinks(cg *api.ConnectGraph, pID *api.ID) {
 	ipfsID := pID.IPFS.ID
 	if pID.IPFS.Error != "" { // Only setting ipfs connections when no error occurs
+		cg.IPFSErrors[peer.IDB58Encode(pID.ID)] = pID.IPFS.Error
 		logger.Warningf("ipfs id: %s has error: %s. Skipping swarm connections", ipfsID.Pretty(), pID.IPFS.Error)
 		return
 	}
@@ -0,0 +1,105 @@
+package ipfscluster
+
+import (
+	"net"
+
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	swarm "github.com/libp2p/go-libp2p-swarm"
+	filter "github.com/libp2p/go-maddr-filter"
+)
+
+// connGater enforces the connection allow and deny lists from the
+// configuration on the cluster host. IP ranges are enforced by the swarm
+// address filters, which reject connections before they are upgraded.
+// Peer IDs are only known after the handshake, so connections to
+// forbidden peers are closed as soon as they are established.
+type connGater struct {
+	h   host.Host
+	cfg *Config
+
+	// filters are the swarm address filters. They are nil when the
+	// host network is not a libp2p swarm.
+	filters *filter.Filters
+}
+
+func newConnGater(h host.Host, cfg *Config) *connGater {
+	g := &connGater{
+		h:   h,
+		cfg: cfg,
+	}
+	if sw, ok := h.Network().(*swarm.Swarm); ok {
+		g.filters = sw.Filters
+	}
+	return g
+}
+
+// start applies the configured filters, closes any connection that should
+// not have been established and watches for new ones.
+func (g *connGater) start() {
+	if g.filters != nil && len(g.cfg.ConnectionAllowCIDRs) > 0 {
+		g.filters.DefaultAction = filter.ActionDeny
+	}
+	g.update()
+
+	g.h.Network().Notify(&inet.NotifyBundle{
+		ConnectedF: func(n inet.Network, c inet.Conn) {
+			if !g.allowedPeer(c.RemotePeer()) {
+				logger.Infof("closing connection from denied peer %s", c.RemotePeer())
+				// Closing from the notification would block the swarm.
+				go c.Close()
+			}
+		},
+	})
+}
+
+// update re-applies the IP range filters after the deny lists have changed
+// and closes the connections which are not allowed anymore.
+func (g *connGater) update() {
+	if g.filters != nil {
+		_, denyCIDRs := g.cfg.GetConnectionDenyList()
+		for _, action := range []filter.Action{filter.ActionAccept, filter.ActionDeny} {
+			for _, n := range g.filters.FiltersForAction(action) {
+				g.filters.RemoveLiteral(n)
+			}
+		}
+		// The last matching filter wins, so deny entries go last.
+		for _, n := range g.cfg.ConnectionAllowCIDRs {
+			g.filters.AddFilter(*n, filter.ActionAccept)
+		}
+		for _, n := range denyCIDRs {
+			g.filters.AddFilter(*n, filter.ActionDeny)
+		}
+	}
+
+	for _, c := range g.h.Network().Conns() {
+		if !g.allowedPeer(c.RemotePeer()) || g.blockedAddr(c) {
+			c.Close()
+		}
+	}
+}
+
+func (g *connGater) blockedAddr(c inet.Conn) bool {
+	return g.filters != nil && g.filters.AddrBlocked(c.RemoteMultiaddr())
+}
+
+// allowedPeer returns false when the peer is in the deny list or when
+// there is an allow list and it does not include it.
+func (g *connGater) allowedPeer(p peer.ID) bool {
+	denyPeers, _ := g.cfg.GetConnectionDenyList()
+	if containsPeer(denyPeers, p) {
+		return false
+	}
+	allowPeers := g.cfg.ConnectionAllowPeers
+	return len(allowPeers) == 0 || containsPeer(allowPeers, p)
+}
+
+func containsCIDR(nets []*net.IPNet, n *net.IPNet) bool {
+	for _, m := range nets {
+		if m.String() == n.String() {
+			return true
+		}
+	}
+	return false
+}
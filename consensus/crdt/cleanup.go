@@ -0,0 +1,123 @@
+package crdt
+
+import (
+	"context"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	query "github.com/ipfs/go-datastore/query"
+)
+
+// Layout of the go-ds-crdt set in the datastore.
+var (
+	setNs   = "s" // /<namespace>/s
+	elemsNs = "s" // /<namespace>/s/s/<key>/<id>
+	tombsNs = "t" // /<namespace>/s/t/<key>/<id>
+	keysNs  = "k" // /<namespace>/s/k/<key>/{v,p}
+)
+
+// CleanTombstones removes the elements of the CRDT set which have been
+// deleted (all their versions have been tombstoned) from the given
+// datastore, along with their tombstones and last values. Keys with
+// tombstones for versions which have not been received yet are kept. It
+// returns the number of removed datastore entries and their total size.
+//
+// CleanTombstones must not be used while a peer is using the datastore.
+func CleanTombstones(ctx context.Context, cfg *Config, store ds.Datastore) (int, uint64, error) {
+	logger.Info("cleaning deleted elements from CRDT datastore")
+	set := ds.NewKey(cfg.DatastoreNamespace).ChildString(setNs)
+	elemsPrefix := set.ChildString(elemsNs)
+	tombsPrefix := set.ChildString(tombsNs)
+	keysPrefix := set.ChildString(keysNs)
+
+	tombs, err := setIDs(store, tombsPrefix)
+	if err != nil {
+		return 0, 0, err
+	}
+	elems, err := setIDs(store, elemsPrefix)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var removable []ds.Key
+	for key, tombIDs := range tombs {
+		elemIDs := elems[key]
+		if !sameIDs(elemIDs, tombIDs) {
+			continue
+		}
+		k := ds.NewKey(key)
+		for id := range tombIDs {
+			removable = append(
+				removable,
+				elemsPrefix.Child(k).ChildString(id),
+				tombsPrefix.Child(k).ChildString(id),
+			)
+		}
+		removable = append(
+			removable,
+			keysPrefix.Child(k).ChildString("v"),
+			keysPrefix.Child(k).ChildString("p"),
+		)
+	}
+
+	removed := 0
+	var size uint64
+	for _, k := range removable {
+		if err := ctx.Err(); err != nil {
+			return removed, size, err
+		}
+		s, err := store.GetSize(k)
+		if err == ds.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return removed, size, err
+		}
+		err = store.Delete(k)
+		if err != nil {
+			return removed, size, err
+		}
+		removed++
+		size += uint64(len(k.String()) + s)
+	}
+	return removed, size, nil
+}
+
+// setIDs returns, for every element key under the given prefix, the set of
+// ids (versions) stored for it.
+func setIDs(store ds.Datastore, prefix ds.Key) (map[string]map[string]struct{}, error) {
+	results, err := store.Query(query.Query{
+		Prefix:   prefix.String(),
+		KeysOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	ids := make(map[string]map[string]struct{})
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		k := ds.NewKey(strings.TrimPrefix(r.Key, prefix.String()))
+		key := k.Parent().String()
+		if _, ok := ids[key]; !ok {
+			ids[key] = make(map[string]struct{})
+		}
+		ids[key][k.BaseNamespace()] = struct{}{}
+	}
+	return ids, nil
+}
+
+func sameIDs(a, b map[string]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -23,6 +23,8 @@ var (
 	DefaultDatastoreNamespace  = "/c" // from "/crdt"
 	DefaultRebroadcastInterval = time.Minute
 	DefaultTrustedPeers        = []peer.ID{}
+	DefaultBlacklistThreshold  = 0
+	DefaultBlacklistWindow     = time.Minute
 )
 
 // Config is the configuration object for Consensus.
@@ -36,7 +38,9 @@ type Config struct {
 
 	// Any update received from a peer outside this set is ignored and not
 	// forwarded. Trusted peers can also access additional RPC endpoints
-	// for this peer that are forbidden for other peers.
+	// for this peer that are forbidden for other peers. A peer which
+	// trusts other peers but is not in this set itself is a follower and
+	// never runs cluster-wide tasks.
 	TrustedPeers []peer.ID
 
 	// The interval before re-announcing the current state
@@ -50,6 +54,12 @@ type Config struct {
 	// All keys written to the datastore will be namespaced with this prefix
 	DatastoreNamespace string
 
+	// BlacklistThreshold is the number of updates from untrusted peers
+	// that a peer can forward within BlacklistWindow before it is
+	// blacklisted from pubsub. 0 disables blacklisting.
+	BlacklistThreshold int
+	BlacklistWindow    time.Duration
+
 	// Tracing enables propagation of contexts across binary boundaries.
 	Tracing bool
 }
@@ -61,6 +71,9 @@ type jsonConfig struct {
 
 	PeersetMetric      string `json:"peerset_metric,omitempty"`
 	DatastoreNamespace string `json:"datastore_namespace,omitempty"`
+
+	BlacklistThreshold int    `json:"blacklist_threshold,omitempty"`
+	BlacklistWindow    string `json:"blacklist_window,omitempty"`
 }
 
 // ConfigKey returns the section name for this type of configuration.
@@ -81,6 +94,14 @@ func (cfg *Config) Validate() error {
 	if cfg.RebroadcastInterval <= 0 {
 		return errors.New("crdt.rebroadcast_interval is invalid")
 	}
+
+	if cfg.BlacklistThreshold < 0 {
+		return errors.New("crdt.blacklist_threshold is invalid")
+	}
+
+	if cfg.BlacklistWindow <= 0 {
+		return errors.New("crdt.blacklist_window is invalid")
+	}
 	return nil
 }
 
@@ -107,9 +128,11 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 
 	config.SetIfNotDefault(jcfg.PeersetMetric, &cfg.PeersetMetric)
 	config.SetIfNotDefault(jcfg.DatastoreNamespace, &cfg.DatastoreNamespace)
+	config.SetIfNotDefault(jcfg.BlacklistThreshold, &cfg.BlacklistThreshold)
 	config.ParseDurations(
 		"crdt",
 		&config.DurationOpt{Duration: jcfg.RebroadcastInterval, Dst: &cfg.RebroadcastInterval, Name: "rebroadcast_interval"},
+		&config.DurationOpt{Duration: jcfg.BlacklistWindow, Dst: &cfg.BlacklistWindow, Name: "blacklist_window"},
 	)
 	return cfg.Validate()
 }
@@ -143,6 +166,14 @@ func (cfg *Config) toJSONConfig() *jsonConfig {
 		jcfg.RebroadcastInterval = cfg.RebroadcastInterval.String()
 	}
 
+	if cfg.BlacklistThreshold != DefaultBlacklistThreshold {
+		jcfg.BlacklistThreshold = cfg.BlacklistThreshold
+	}
+
+	if cfg.BlacklistWindow != DefaultBlacklistWindow {
+		jcfg.BlacklistWindow = cfg.BlacklistWindow.String()
+	}
+
 	return jcfg
 }
 
@@ -153,6 +184,8 @@ func (cfg *Config) Default() error {
 	cfg.PeersetMetric = DefaultPeersetMetric
 	cfg.DatastoreNamespace = DefaultDatastoreNamespace
 	cfg.TrustedPeers = DefaultTrustedPeers
+	cfg.BlacklistThreshold = DefaultBlacklistThreshold
+	cfg.BlacklistWindow = DefaultBlacklistWindow
 	return nil
 }
 
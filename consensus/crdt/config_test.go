@@ -3,6 +3,7 @@ package crdt
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 var cfgJSON = []byte(`
@@ -29,6 +30,21 @@ func TestLoadJSON(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error parsing trusted_peers")
 	}
+
+	cfg = &Config{}
+	err = cfg.LoadJSON([]byte(`
+{
+    "cluster_name": "test",
+    "trusted_peers": [],
+    "blacklist_threshold": 10,
+    "blacklist_window": "5m"
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BlacklistThreshold != 10 || cfg.BlacklistWindow != 5*time.Minute {
+		t.Error("expected blacklist options to be loaded")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -69,6 +85,18 @@ func TestDefault(t *testing.T) {
 	if cfg.Validate() == nil {
 		t.Fatal("expected error validating")
 	}
+
+	cfg.Default()
+	cfg.BlacklistThreshold = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.BlacklistWindow = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
 }
 
 func TestApplyEnvVars(t *testing.T) {
@@ -9,6 +9,7 @@ import (
 	ipfslite "github.com/hsanjuan/ipfs-lite"
 	dshelp "github.com/ipfs/go-ipfs-ds-help"
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/pubsubutil"
 	"github.com/ipfs/ipfs-cluster/state"
 	"github.com/ipfs/ipfs-cluster/state/dsstate"
 	multihash "github.com/multiformats/go-multihash"
@@ -126,11 +127,24 @@ func (css *Consensus) setup() {
 	}
 
 	// Validate pubsub messages for our topic (only accept
-	// from trusted sources)
+	// those authored by trusted sources, whoever forwards
+	// them). Peers which keep forwarding untrusted updates are
+	// eventually blacklisted.
+	scorer := pubsubutil.NewScorer(
+		css.pubsub,
+		topicName,
+		css.config.BlacklistThreshold,
+		css.config.BlacklistWindow,
+	)
 	err = css.pubsub.RegisterTopicValidator(
 		topicName,
 		func(ctx context.Context, p peer.ID, msg *pubsub.Message) bool {
-			return css.IsTrustedPeer(ctx, p)
+			if css.isTrustedUpdate(ctx, msg) {
+				return true
+			}
+			logger.Debugf("rejecting update from untrusted peer %s forwarded by %s", msg.GetFrom(), p)
+			scorer.Penalize(p)
+			return false
 		},
 	)
 	if err != nil {
@@ -287,13 +301,29 @@ func (css *Consensus) Ready(ctx context.Context) <-chan struct{} {
 }
 
 // IsTrustedPeer returns whether the given peer is taken into account
-// when submitting updates to the consensus state.
+// when submitting updates to the consensus state. This peer trusts itself
+// when it is in the trusted peers set, or when it trusts no other peers.
+// Otherwise it is a follower.
 func (css *Consensus) IsTrustedPeer(ctx context.Context, pid peer.ID) bool {
-	if pid == css.host.ID() {
-		return true
-	}
 	_, ok := css.trustedPeers.Load(pid)
-	return ok
+	if ok || pid != css.host.ID() {
+		return ok
+	}
+
+	trustsOthers := false
+	css.trustedPeers.Range(func(k, v interface{}) bool {
+		trustsOthers = true
+		return false
+	})
+	return !trustsOthers
+}
+
+// isTrustedUpdate returns whether the given pubsub message was authored by
+// a trusted peer, regardless of the peer which forwarded it. Our own updates
+// are always applied locally.
+func (css *Consensus) isTrustedUpdate(ctx context.Context, msg *pubsub.Message) bool {
+	author := peer.ID(msg.GetFrom())
+	return author == css.host.ID() || css.IsTrustedPeer(ctx, author)
 }
 
 // Trust marks a peer as "trusted".
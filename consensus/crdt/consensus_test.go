@@ -14,8 +14,10 @@ import (
 	libp2p "github.com/libp2p/go-libp2p"
 	host "github.com/libp2p/go-libp2p-host"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
+	peer "github.com/libp2p/go-libp2p-peer"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
 	routedhost "github.com/libp2p/go-libp2p/p2p/host/routed"
 )
 
@@ -297,6 +299,53 @@ func TestConsensusDistrustPeer(t *testing.T) {
 	}
 }
 
+func TestConsensusTrustSelf(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer clean(t, cc)
+	defer cc.Shutdown(ctx)
+
+	self := cc.host.ID()
+	if !cc.IsTrustedPeer(ctx, self) {
+		t.Error("a peer which trusts no one should trust itself")
+	}
+
+	cc.Trust(ctx, test.PeerID1)
+	if cc.IsTrustedPeer(ctx, self) {
+		t.Error("a peer which only trusts others is a follower")
+	}
+
+	cc.Trust(ctx, self)
+	if !cc.IsTrustedPeer(ctx, self) {
+		t.Error("a peer in the trusted set should trust itself")
+	}
+	if cc.IsTrustedPeer(ctx, test.PeerID2) {
+		t.Error("untrusted peer should not be trusted")
+	}
+}
+
+func TestConsensusTrustedUpdate(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer clean(t, cc)
+	defer cc.Shutdown(ctx)
+
+	cc.Trust(ctx, test.PeerID1)
+
+	update := func(author peer.ID) *pubsub.Message {
+		return &pubsub.Message{Message: &pb.Message{From: []byte(author)}}
+	}
+	if !cc.isTrustedUpdate(ctx, update(test.PeerID1)) {
+		t.Error("updates by trusted peers should be accepted")
+	}
+	if cc.isTrustedUpdate(ctx, update(test.PeerID2)) {
+		t.Error("updates by untrusted peers should be rejected")
+	}
+	if !cc.isTrustedUpdate(ctx, update(cc.host.ID())) {
+		t.Error("our own updates should be accepted")
+	}
+}
+
 func TestPeers(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
@@ -351,3 +400,56 @@ func TestOfflineState(t *testing.T) {
 		t.Error("there should be two pins in the state")
 	}
 }
+
+func TestCleanTombstones(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer clean(t, cc)
+	defer cc.Shutdown(ctx)
+
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cc.LogPin(ctx, testPin(test.Cid2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cc.LogUnpin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cc.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, size, err := CleanTombstones(ctx, cc.config, cc.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed == 0 || size == 0 {
+		t.Error("expected the unpinned element to be cleaned")
+	}
+
+	offlineState, err := OfflineState(cc.config, cc.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins, err := offlineState.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 1 || !pins[0].Cid.Equals(test.Cid2) {
+		t.Error("only the second pin should be in the state")
+	}
+
+	removed, _, err = CleanTombstones(ctx, cc.config, cc.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 0 {
+		t.Error("nothing should have been cleaned the second time")
+	}
+}
@@ -28,6 +28,7 @@ var (
 	DefaultNetworkTimeout       = 10 * time.Second
 	DefaultCommitRetryDelay     = 200 * time.Millisecond
 	DefaultBackupsRotate        = 6
+	DefaultMaxForwardedOps      = 64
 	DefaultDatastoreNamespace   = "/r" // from "/raft"
 )
 
@@ -58,6 +59,10 @@ type Config struct {
 	CommitRetries int
 	// How long to wait between retries
 	CommitRetryDelay time.Duration
+	// MaxForwardedOps limits how many operations a follower forwards
+	// to the leader at the same time. Further operations wait for
+	// one of them to finish.
+	MaxForwardedOps int
 	// BackupsRotate specifies the maximum number of Raft's DataFolder
 	// copies that we keep as backups (renaming) after cleanup.
 	BackupsRotate int
@@ -99,6 +104,9 @@ type jsonConfig struct {
 	// How long to wait between commit retries
 	CommitRetryDelay string `json:"commit_retry_delay"`
 
+	// How many operations can be forwarded to the leader at once
+	MaxForwardedOps int `json:"max_forwarded_ops,omitempty"`
+
 	// BackupsRotate specifies the maximum number of Raft's DataFolder
 	// copies that we keep as backups (renaming) after cleanup.
 	BackupsRotate int `json:"backups_rotate"`
@@ -171,6 +179,10 @@ func (cfg *Config) Validate() error {
 		return errors.New("commit_retry_delay is invalid")
 	}
 
+	if cfg.MaxForwardedOps <= 0 {
+		return errors.New("max_forwarded_ops should be larger than 0")
+	}
+
 	if cfg.BackupsRotate <= 0 {
 		return errors.New("backups_rotate should be larger than 0")
 	}
@@ -222,6 +234,7 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	config.SetIfNotDefault(networkTimeout, &cfg.NetworkTimeout)
 	cfg.CommitRetries = jcfg.CommitRetries
 	config.SetIfNotDefault(commitRetryDelay, &cfg.CommitRetryDelay)
+	config.SetIfNotDefault(jcfg.MaxForwardedOps, &cfg.MaxForwardedOps)
 	config.SetIfNotDefault(jcfg.BackupsRotate, &cfg.BackupsRotate)
 
 	// Raft values
@@ -253,6 +266,7 @@ func (cfg *Config) toJSONConfig() *jsonConfig {
 		NetworkTimeout:       cfg.NetworkTimeout.String(),
 		CommitRetries:        cfg.CommitRetries,
 		CommitRetryDelay:     cfg.CommitRetryDelay.String(),
+		MaxForwardedOps:      cfg.MaxForwardedOps,
 		BackupsRotate:        cfg.BackupsRotate,
 		HeartbeatTimeout:     cfg.RaftConfig.HeartbeatTimeout.String(),
 		ElectionTimeout:      cfg.RaftConfig.ElectionTimeout.String(),
@@ -278,6 +292,7 @@ func (cfg *Config) Default() error {
 	cfg.NetworkTimeout = DefaultNetworkTimeout
 	cfg.CommitRetries = DefaultCommitRetries
 	cfg.CommitRetryDelay = DefaultCommitRetryDelay
+	cfg.MaxForwardedOps = DefaultMaxForwardedOps
 	cfg.BackupsRotate = DefaultBackupsRotate
 	cfg.DatastoreNamespace = DefaultDatastoreNamespace
 	cfg.RaftConfig = hraft.DefaultConfig()
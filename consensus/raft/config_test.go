@@ -15,6 +15,7 @@ var cfgJSON = []byte(`
     "network_timeout": "1s",
     "commit_retries": 1,
     "commit_retry_delay": "200ms",
+    "max_forwarded_ops": 32,
     "backups_rotate": 5,
     "heartbeat_timeout": "1s",
     "election_timeout": "1s",
@@ -54,6 +55,17 @@ func TestLoadJSON(t *testing.T) {
 	if cfg.RaftConfig.LeaderLeaseTimeout != def.LeaderLeaseTimeout {
 		t.Error("expected default leader lease")
 	}
+
+	json.Unmarshal(cfgJSON, j)
+	j.MaxForwardedOps = 0
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxForwardedOps != DefaultMaxForwardedOps {
+		t.Error("expected default max_forwarded_ops")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -100,6 +112,12 @@ func TestDefault(t *testing.T) {
 		t.Fatal("expected error validating")
 	}
 
+	cfg.Default()
+	cfg.MaxForwardedOps = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
 	cfg.Default()
 	cfg.BackupsRotate = 0
 
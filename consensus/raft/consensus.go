@@ -10,10 +10,12 @@ import (
 	"sync"
 	"time"
 
+	"go.opencensus.io/stats"
 	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/observations"
 	"github.com/ipfs/ipfs-cluster/state"
 	"github.com/ipfs/ipfs-cluster/state/dsstate"
 
@@ -48,6 +50,9 @@ type Consensus struct {
 	rpcReady  chan struct{}
 	readyCh   chan struct{}
 
+	// limits the operations being forwarded to the leader
+	forwardSlots chan struct{}
+
 	shutdownLock sync.RWMutex
 	shutdown     bool
 }
@@ -105,6 +110,8 @@ func NewConsensus(
 		raft:      raft,
 		rpcReady:  make(chan struct{}, 1),
 		readyCh:   make(chan struct{}, 1),
+
+		forwardSlots: make(chan struct{}, cfg.MaxForwardedOps),
 	}
 
 	baseOp.consensus = cc
@@ -250,8 +257,8 @@ func (cc *Consensus) op(ctx context.Context, pin *api.Pin, t LogOpType) *LogOp {
 // returns true if the operation was redirected to the leader
 // note that if the leader just dissappeared, the rpc call will
 // fail because we haven't heard that it's gone.
-func (cc *Consensus) redirectToLeader(method string, arg interface{}) (bool, error) {
-	ctx, span := trace.StartSpan(cc.ctx, "consensus/redirectToLeader")
+func (cc *Consensus) redirectToLeader(ctx context.Context, method string, arg interface{}) (bool, error) {
+	ctx, span := trace.StartSpan(ctx, "consensus/redirectToLeader")
 	defer span.End()
 
 	var finalErr error
@@ -288,15 +295,13 @@ func (cc *Consensus) redirectToLeader(method string, arg interface{}) (bool, err
 		}
 
 		logger.Debugf("redirecting %s to leader: %s", method, leader.Pretty())
-		finalErr = cc.rpcClient.CallContext(
-			ctx,
-			leader,
-			"Consensus",
-			method,
-			arg,
-			&struct{}{},
-		)
+		finalErr = cc.forward(ctx, leader, method, arg)
 		if finalErr != nil {
+			if ctx.Err() != nil || cc.ctx.Err() != nil {
+				// the request is gone or we are shutting
+				// down. No point in retrying.
+				break
+			}
 			logger.Errorf("retrying to redirect request to leader: %s", finalErr)
 			time.Sleep(2 * cc.config.RaftConfig.HeartbeatTimeout)
 			continue
@@ -308,6 +313,93 @@ func (cc *Consensus) redirectToLeader(method string, arg interface{}) (bool, err
 	return true, finalErr
 }
 
+// forward sends an operation to the leader. At most MaxForwardedOps
+// operations are forwarded at once: the rest wait for a free slot, which
+// keeps a busy follower from piling up requests on the leader.
+func (cc *Consensus) forward(ctx context.Context, leader peer.ID, method string, arg interface{}) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/forward")
+	defer span.End()
+
+	start := time.Now()
+	select {
+	case cc.forwardSlots <- struct{}{}:
+		defer func() { <-cc.forwardSlots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-cc.ctx.Done():
+		return errors.New("consensus is shutting down")
+	}
+
+	err := cc.rpcClient.CallContext(
+		ctx,
+		leader,
+		"Consensus",
+		method,
+		arg,
+		&struct{}{},
+	)
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{
+			tag.Upsert(observations.OperationKey, method),
+			tag.Upsert(observations.ResultKey, result),
+		},
+		observations.ForwardedOps.M(1),
+		observations.ForwardLatency.M(float64(time.Since(start))/float64(time.Millisecond)),
+	)
+	return err
+}
+
+// waitForApplied waits until an operation which was committed by the
+// leader on our behalf has been applied to our own state, so that
+// forwarded operations return once their result is visible locally. It
+// gives up after NetworkTimeout, as the operation is committed anyways.
+func (cc *Consensus) waitForApplied(ctx context.Context, op *LogOp) {
+	ctx, span := trace.StartSpan(ctx, "consensus/waitForApplied")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, cc.config.NetworkTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(waitForUpdatesInterval)
+	defer ticker.Stop()
+
+	for {
+		if cc.isApplied(ctx, op) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			logger.Warningf("forwarded %s not yet applied locally: %s", op.Cid.Cid, ctx.Err())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isApplied returns true when the state reflects the given operation.
+func (cc *Consensus) isApplied(ctx context.Context, op *LogOp) bool {
+	st, err := cc.State(ctx)
+	if err != nil {
+		return false
+	}
+
+	switch op.Type {
+	case LogOpPin:
+		pin, err := st.Get(ctx, op.Cid.Cid)
+		return err == nil && pin.Equals(op.Cid)
+	case LogOpUnpin:
+		ok, err := st.Has(ctx, op.Cid.Cid)
+		return err == nil && !ok
+	}
+	return true
+}
+
 // commit submits a cc.consensus commit. It retries upon failures.
 func (cc *Consensus) commit(ctx context.Context, op *LogOp, rpcOp string, redirectArg interface{}) error {
 	ctx, span := trace.StartSpan(ctx, "consensus/commit")
@@ -335,10 +427,14 @@ func (cc *Consensus) commit(ctx context.Context, op *LogOp, rpcOp string, redire
 		// try to send it to the leader
 		// redirectToLeader has it's own retry loop. If this fails
 		// we're done here.
-		ok, err := cc.redirectToLeader(rpcOp, redirectArg)
-		if err != nil || ok {
+		ok, err := cc.redirectToLeader(ctx, rpcOp, redirectArg)
+		if err != nil {
 			return err
 		}
+		if ok {
+			cc.waitForApplied(ctx, op)
+			return nil
+		}
 
 		// Being here means we are the LEADER. We can commit.
 
@@ -403,7 +499,7 @@ func (cc *Consensus) AddPeer(ctx context.Context, pid peer.ID) error {
 		if finalErr != nil {
 			logger.Errorf("retrying to add peer. Attempt #%d failed: %s", i, finalErr)
 		}
-		ok, err := cc.redirectToLeader("AddPeer", pid)
+		ok, err := cc.redirectToLeader(ctx, "AddPeer", pid)
 		if err != nil || ok {
 			return err
 		}
@@ -434,7 +530,7 @@ func (cc *Consensus) RmPeer(ctx context.Context, pid peer.ID) error {
 		if finalErr != nil {
 			logger.Errorf("retrying to remove peer. Attempt #%d failed: %s", i, finalErr)
 		}
-		ok, err := cc.redirectToLeader("RmPeer", pid)
+		ok, err := cc.redirectToLeader(ctx, "RmPeer", pid)
 		if err != nil || ok {
 			return err
 		}
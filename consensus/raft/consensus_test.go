@@ -261,6 +261,55 @@ func TestConsensusLeader(t *testing.T) {
 	}
 }
 
+func TestConsensusForwardBackpressure(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	// Take all forwarding slots
+	for i := 0; i < cap(cc.forwardSlots); i++ {
+		cc.forwardSlots <- struct{}{}
+	}
+
+	fctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err := cc.forward(fctx, test.PeerID1, "LogPin", testPin(test.Cid1))
+	if err != context.DeadlineExceeded {
+		t.Fatal("expected forward to wait for a free slot:", err)
+	}
+}
+
+func TestConsensusIsApplied(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	pin := testPin(test.Cid1)
+	pinOp := cc.op(ctx, pin, LogOpPin)
+	unpinOp := cc.op(ctx, pin, LogOpUnpin)
+	if cc.isApplied(ctx, pinOp) {
+		t.Error("pin should not be applied yet")
+	}
+	if !cc.isApplied(ctx, unpinOp) {
+		t.Error("unpin should be applied for a missing pin")
+	}
+
+	err := cc.LogPin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	if !cc.isApplied(ctx, pinOp) {
+		t.Error("pin should be applied")
+	}
+	if cc.isApplied(ctx, unpinOp) {
+		t.Error("unpin should not be applied")
+	}
+}
+
 func TestRaftLatestSnapshot(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
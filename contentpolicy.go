@@ -0,0 +1,133 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/policy"
+)
+
+// Operators can deny content (see Config.DeniedCIDs and DenylistURLs).
+// Denied CIDs are refused when pinning, and pins of them found in the
+// shared state, either on every StateSync or after the denylists are
+// updated, are removed. Every enforcement action is recorded in the audit
+// log.
+
+// denylistFetchTimeout limits how long fetching a denylist may take.
+var denylistFetchTimeout = 5 * time.Minute
+
+// checkContentPolicy returns an error when the given pin is denied.
+func (c *Cluster) checkContentPolicy(ctx context.Context, pin *api.Pin) error {
+	denied, source := c.policy.Denied(pin.Cid)
+	if !denied {
+		return nil
+	}
+	c.audit(ctx, "pin "+pin.Cid.String(), api.AuditRefused, "denied by "+source)
+	return fmt.Errorf("%s is denied by the content policy of this peer", pin.Cid)
+}
+
+// denylistWatcher fetches the denylists every DenylistUpdateInterval and
+// removes the pins they deny.
+func (c *Cluster) denylistWatcher() {
+	ticker := time.NewTicker(c.config.DenylistUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		if c.updateDenylists(c.ctx) {
+			c.enforceContentPolicyAll(c.ctx)
+		}
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// updateDenylists fetches the denylists and returns whether any was
+// updated. Lists which cannot be fetched keep their previous entries.
+func (c *Cluster) updateDenylists(ctx context.Context) bool {
+	ctx, span := trace.StartSpan(ctx, "cluster/updateDenylists")
+	defer span.End()
+
+	client := &http.Client{Timeout: denylistFetchTimeout}
+	updated := false
+	for _, u := range c.config.DenylistURLs {
+		dl, err := policy.Fetch(ctx, client, u)
+		if err != nil {
+			logger.Errorf("error fetching denylist: %s", err)
+			continue
+		}
+		c.policy.SetList(u, dl)
+		logger.Infof("denylist %s updated: %d entries", u, dl.Len())
+		updated = true
+	}
+	return updated
+}
+
+func (c *Cluster) enforceContentPolicyAll(ctx context.Context) {
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	pins, err := cState.List(ctx)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	c.enforceContentPolicy(ctx, pins)
+}
+
+// enforceContentPolicy removes the given pins which are denied. Only the
+// coordinator does it. When it would unpin more than the allowed share of
+// the pinset, nothing is removed unless Config.DenylistForceBulkUnpin is
+// set.
+func (c *Cluster) enforceContentPolicy(ctx context.Context, pins []*api.Pin) {
+	ctx, span := trace.StartSpan(ctx, "cluster/enforceContentPolicy")
+	defer span.End()
+
+	if !c.isCoordinator(ctx) {
+		return
+	}
+
+	type deniedPin struct {
+		pin    *api.Pin
+		source string
+	}
+	var denied []deniedPin
+	for _, pin := range pins {
+		// Shards and cluster DAGs go away with their meta pin.
+		if pin.Type != api.DataType && pin.Type != api.MetaType {
+			continue
+		}
+		if ok, source := c.policy.Denied(pin.Cid); ok {
+			denied = append(denied, deniedPin{pin, source})
+		}
+	}
+	if len(denied) == 0 {
+		return
+	}
+
+	err := c.checkBulkUnpin(ctx, "denylist enforcement", len(denied), len(pins), c.config.DenylistForceBulkUnpin)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	for _, d := range denied {
+		op := fmt.Sprintf("unpin %s (%s)", d.pin.Cid, d.pin.Name)
+		details := "denied by " + d.source
+		err := c.removePin(ctx, d.pin)
+		if err != nil {
+			c.audit(ctx, op, api.AuditFailed, details+": "+err.Error())
+			continue
+		}
+		c.audit(ctx, op, api.AuditCompleted, details)
+	}
+}
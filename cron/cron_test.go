@@ -0,0 +1,74 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 2-4 * * 1-5",
+		"0 3 1,15 * *",
+		"30 0 * 1-12/2 7",
+		"@daily",
+	}
+	for _, spec := range valid {
+		if _, err := Parse(spec); err != nil {
+			t.Errorf("%s: %s", spec, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@often",
+	}
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%q should not parse", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	start := time.Date(2020, time.January, 31, 22, 10, 30, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2020, time.January, 31, 22, 11, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, time.January, 31, 22, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2020, time.February, 1, 3, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Sundays (as 7), 2020-02-02 is the first after start
+		{"0 12 * * 7", time.Date(2020, time.February, 2, 12, 0, 0, 0, time.UTC)},
+		// either the 15th or a Monday
+		{"0 0 15 * 1", time.Date(2020, time.February, 3, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := s.Next(start); !next.Equal(c.next) {
+			t.Errorf("%s: expected %s, got %s", c.spec, c.next, next)
+		}
+	}
+
+	never, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !never.Next(start).IsZero() {
+		t.Error("February 30th should never happen")
+	}
+}
@@ -0,0 +1,72 @@
+package ipfscluster
+
+import (
+	"context"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// DHT providing makes cluster peers announce provider records for the
+// root CIDs of the pins they hold, using their own libp2p host and DHT.
+// Every DHTProvideInterval (and once when the peer starts), every pin
+// which is pinned locally is announced. Provider records expire, so the
+// interval should stay well below their lifetime (24h).
+
+// dhtProvideTimeout limits how long announcing a single CID may take.
+var dhtProvideTimeout = time.Minute
+
+// dhtProvideWatcher announces the locally pinned roots periodically.
+func (c *Cluster) dhtProvideWatcher() {
+	ticker := time.NewTicker(c.config.DHTProvideInterval)
+	defer ticker.Stop()
+
+	c.provideRoots(c.ctx)
+	for {
+		select {
+		case <-ticker.C:
+			logger.Debug("auto-triggering dht providing")
+			c.provideRoots(c.ctx)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// provideRoots announces this peer on the DHT as a provider of all the
+// pins which are pinned locally.
+func (c *Cluster) provideRoots(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "cluster/provideRoots")
+	defer span.End()
+
+	pinned := c.tracker.StatusAll(ctx, api.TrackerStatusPinned)
+	provided := 0
+	for _, pinfo := range pinned {
+		if ctx.Err() != nil {
+			return
+		}
+		err := c.provideRoot(ctx, pinfo.Cid)
+		if err != nil {
+			logger.Debugf("error providing %s on the DHT: %s", pinfo.Cid, err)
+			continue
+		}
+		provided++
+	}
+	if provided < len(pinned) {
+		logger.Warningf("only %d out of %d pinned roots could be provided on the DHT", provided, len(pinned))
+		return
+	}
+	logger.Infof("provided %d pinned roots on the DHT", provided)
+}
+
+// provideRoot announces this peer on the DHT as a provider of the given
+// CID.
+func (c *Cluster) provideRoot(ctx context.Context, ci cid.Cid) error {
+	ctx, cancel := context.WithTimeout(ctx, dhtProvideTimeout)
+	defer cancel()
+	return c.dht.Provide(ctx, ci, true)
+}
@@ -0,0 +1,38 @@
+package ipfscluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func TestClusterProvideRoots(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	err := cl.Pin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	pinDelay()
+
+	// There are no other DHT peers to announce to, but the peer
+	// registers itself as provider anyways.
+	cl.provideRoots(ctx)
+
+	ctx2, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	for prov := range cl.dht.FindProvidersAsync(ctx2, test.Cid1, 1) {
+		if prov.ID != cl.id {
+			t.Errorf("unexpected provider: %s", prov.ID)
+		}
+		return
+	}
+	t.Error("the peer should be a provider of the pinned cid")
+}
@@ -0,0 +1,158 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/faults"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+var errFaultInjectionDisabled = errors.New("fault injection is not enabled in the configuration of this peer")
+
+// faultyIPFSConnector wraps an IPFSConnector and injects the faults
+// configured for the "ipfs" target before every call.
+type faultyIPFSConnector struct {
+	IPFSConnector
+	faults *faults.Injector
+}
+
+func (ipfs *faultyIPFSConnector) inject(ctx context.Context, method string) error {
+	return ipfs.faults.Inject(ctx, faults.TargetIPFS, method)
+}
+
+func (ipfs *faultyIPFSConnector) ID(ctx context.Context) (*api.IPFSID, error) {
+	if err := ipfs.inject(ctx, "ID"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.ID(ctx)
+}
+
+func (ipfs *faultyIPFSConnector) Pin(ctx context.Context, c cid.Cid, maxDepth int) error {
+	if err := ipfs.inject(ctx, "Pin"); err != nil {
+		return err
+	}
+	return ipfs.IPFSConnector.Pin(ctx, c, maxDepth)
+}
+
+func (ipfs *faultyIPFSConnector) Unpin(ctx context.Context, c cid.Cid) error {
+	if err := ipfs.inject(ctx, "Unpin"); err != nil {
+		return err
+	}
+	return ipfs.IPFSConnector.Unpin(ctx, c)
+}
+
+func (ipfs *faultyIPFSConnector) PinLsCid(ctx context.Context, c cid.Cid) (api.IPFSPinStatus, error) {
+	if err := ipfs.inject(ctx, "PinLsCid"); err != nil {
+		return api.IPFSPinStatusError, err
+	}
+	return ipfs.IPFSConnector.PinLsCid(ctx, c)
+}
+
+func (ipfs *faultyIPFSConnector) PinLs(ctx context.Context, typeFilter string) (map[string]api.IPFSPinStatus, error) {
+	if err := ipfs.inject(ctx, "PinLs"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.PinLs(ctx, typeFilter)
+}
+
+func (ipfs *faultyIPFSConnector) ConnectSwarms(ctx context.Context) error {
+	if err := ipfs.inject(ctx, "ConnectSwarms"); err != nil {
+		return err
+	}
+	return ipfs.IPFSConnector.ConnectSwarms(ctx)
+}
+
+func (ipfs *faultyIPFSConnector) SwarmPeers(ctx context.Context) ([]peer.ID, error) {
+	if err := ipfs.inject(ctx, "SwarmPeers"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.SwarmPeers(ctx)
+}
+
+func (ipfs *faultyIPFSConnector) ConfigKey(keypath string) (interface{}, error) {
+	if err := ipfs.inject(context.Background(), "ConfigKey"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.ConfigKey(keypath)
+}
+
+func (ipfs *faultyIPFSConnector) RepoStat(ctx context.Context) (*api.IPFSRepoStat, error) {
+	if err := ipfs.inject(ctx, "RepoStat"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.RepoStat(ctx)
+}
+
+func (ipfs *faultyIPFSConnector) Resolve(ctx context.Context, path string) (cid.Cid, error) {
+	if err := ipfs.inject(ctx, "Resolve"); err != nil {
+		return cid.Undef, err
+	}
+	return ipfs.IPFSConnector.Resolve(ctx, path)
+}
+
+func (ipfs *faultyIPFSConnector) BlockPut(ctx context.Context, b *api.NodeWithMeta) error {
+	if err := ipfs.inject(ctx, "BlockPut"); err != nil {
+		return err
+	}
+	return ipfs.IPFSConnector.BlockPut(ctx, b)
+}
+
+func (ipfs *faultyIPFSConnector) BlockGet(ctx context.Context, c cid.Cid) ([]byte, error) {
+	if err := ipfs.inject(ctx, "BlockGet"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.BlockGet(ctx, c)
+}
+
+func (ipfs *faultyIPFSConnector) Provide(ctx context.Context, c cid.Cid) error {
+	if err := ipfs.inject(ctx, "Provide"); err != nil {
+		return err
+	}
+	return ipfs.IPFSConnector.Provide(ctx, c)
+}
+
+func (ipfs *faultyIPFSConnector) BitswapStat(ctx context.Context) (*api.IPFSBitswapStat, error) {
+	if err := ipfs.inject(ctx, "BitswapStat"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.BitswapStat(ctx)
+}
+
+func (ipfs *faultyIPFSConnector) PinProgress(ctx context.Context) ([]*api.PinProgress, error) {
+	if err := ipfs.inject(ctx, "PinProgress"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.PinProgress(ctx)
+}
+
+func (ipfs *faultyIPFSConnector) DAGSize(ctx context.Context, c cid.Cid) (uint64, error) {
+	if err := ipfs.inject(ctx, "DAGSize"); err != nil {
+		return 0, err
+	}
+	return ipfs.IPFSConnector.DAGSize(ctx, c)
+}
+
+func (ipfs *faultyIPFSConnector) DAGBlocks(ctx context.Context, c cid.Cid, maxDepth int) ([]*api.IPFSBlockStat, error) {
+	if err := ipfs.inject(ctx, "DAGBlocks"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.DAGBlocks(ctx, c, maxDepth)
+}
+
+func (ipfs *faultyIPFSConnector) DaemonInfo(ctx context.Context) (*api.IPFSDaemonInfo, error) {
+	if err := ipfs.inject(ctx, "DaemonInfo"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.DaemonInfo(ctx)
+}
+
+func (ipfs *faultyIPFSConnector) RepoGC(ctx context.Context) (*api.RepoGC, error) {
+	if err := ipfs.inject(ctx, "RepoGC"); err != nil {
+		return nil, err
+	}
+	return ipfs.IPFSConnector.RepoGC(ctx)
+}
@@ -0,0 +1,135 @@
+// Package faults implements fault injection for IPFS Cluster peers. An
+// Injector holds a set of api.FaultRule which delay or fail a percentage of
+// the operations they match, so that operators can rehearse failure
+// handling and verify their alerting end to end.
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	logging "github.com/ipfs/go-log"
+)
+
+var logger = logging.Logger("faults")
+
+// Fault targets.
+const (
+	// TargetIPFS matches calls to the IPFS connector.
+	TargetIPFS = "ipfs"
+	// TargetRPC matches RPC requests received from other peers.
+	TargetRPC = "rpc"
+)
+
+// Injector decides which operations are delayed or failed according to a
+// set of rules. It is safe for concurrent use.
+type Injector struct {
+	mu    sync.RWMutex
+	rules []*api.FaultRule
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+}
+
+// New returns an Injector without rules, which does not inject any faults.
+func New() *Injector {
+	return &Injector{
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Validate checks that a rule can be used.
+func Validate(r *api.FaultRule) error {
+	switch r.Target {
+	case TargetIPFS, TargetRPC:
+	default:
+		return fmt.Errorf("invalid fault target %q (use %q or %q)", r.Target, TargetIPFS, TargetRPC)
+	}
+	if r.Percent <= 0 || r.Percent > 100 {
+		return fmt.Errorf("fault percent must be in (0, 100]: %v", r.Percent)
+	}
+	if r.Delay < 0 {
+		return errors.New("fault delay cannot be negative")
+	}
+	if r.Delay == 0 && r.Error == "" {
+		return errors.New("faults need a delay, an error or both")
+	}
+	return nil
+}
+
+// Rules returns a copy of the current rules.
+func (inj *Injector) Rules() []*api.FaultRule {
+	inj.mu.RLock()
+	defer inj.mu.RUnlock()
+
+	rules := make([]*api.FaultRule, 0, len(inj.rules))
+	for _, r := range inj.rules {
+		rCopy := *r
+		rules = append(rules, &rCopy)
+	}
+	return rules
+}
+
+// SetRules replaces the current rules. An empty list stops all fault
+// injection. Nothing is changed when any of the rules is invalid.
+func (inj *Injector) SetRules(rules []*api.FaultRule) error {
+	newRules := make([]*api.FaultRule, 0, len(rules))
+	for _, r := range rules {
+		if err := Validate(r); err != nil {
+			return err
+		}
+		rCopy := *r
+		newRules = append(newRules, &rCopy)
+	}
+
+	inj.mu.Lock()
+	inj.rules = newRules
+	inj.mu.Unlock()
+	return nil
+}
+
+// Inject applies the rules matching the given target and method. Every
+// matching rule fires with its own probability: it sleeps for the rule's
+// delay and then returns an error when the rule has one. The context error is returned if it is cancelled while sleeping.
+func (inj *Injector) Inject(ctx context.Context, target, method string) error {
+	inj.mu.RLock()
+	rules := inj.rules
+	inj.mu.RUnlock()
+
+	for _, r := range rules {
+		if r.Target != target || (r.Method != "" && r.Method != method) {
+			continue
+		}
+		if !inj.roll(r.Percent) {
+			continue
+		}
+
+		if r.Delay > 0 {
+			logger.Debugf("delaying %s %s by %s", target, method, r.Delay)
+			timer := time.NewTimer(r.Delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if r.Error != "" {
+			logger.Debugf("failing %s %s: %s", target, method, r.Error)
+			return fmt.Errorf("injected fault: %s", r.Error)
+		}
+	}
+	return nil
+}
+
+func (inj *Injector) roll(percent float64) bool {
+	inj.rngMu.Lock()
+	defer inj.rngMu.Unlock()
+	return inj.rng.Float64()*100 < percent
+}
@@ -0,0 +1,122 @@
+package faults
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+func TestValidate(t *testing.T) {
+	invalid := []*api.FaultRule{
+		{Target: "disk", Percent: 10, Error: "boom"},
+		{Target: TargetIPFS, Percent: 0, Error: "boom"},
+		{Target: TargetIPFS, Percent: 101, Error: "boom"},
+		{Target: TargetRPC, Percent: 10, Delay: -time.Second},
+		{Target: TargetRPC, Percent: 10},
+	}
+	for i, r := range invalid {
+		if Validate(r) == nil {
+			t.Errorf("rule %d should be invalid", i)
+		}
+	}
+
+	valid := &api.FaultRule{Target: TargetRPC, Method: "PinTracker.Track", Percent: 100, Delay: time.Second}
+	if err := Validate(valid); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	inj := New()
+	rules := []*api.FaultRule{
+		{Target: TargetIPFS, Percent: 50, Error: "boom"},
+	}
+	err := inj.SetRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = inj.SetRules(append(rules, &api.FaultRule{Target: "disk"}))
+	if err == nil {
+		t.Fatal("expected an error with an invalid rule")
+	}
+
+	// Modifying the returned rules does not change the injector.
+	got := inj.Rules()
+	if len(got) != 1 || got[0].Percent != 50 {
+		t.Fatalf("unexpected rules: %+v", got)
+	}
+	got[0].Percent = 100
+	if inj.Rules()[0].Percent != 50 {
+		t.Error("rules should be copied")
+	}
+
+	err = inj.SetRules(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inj.Rules()) != 0 {
+		t.Error("rules should have been cleared")
+	}
+}
+
+func TestInject(t *testing.T) {
+	ctx := context.Background()
+	inj := New()
+
+	if err := inj.Inject(ctx, TargetIPFS, "Pin"); err != nil {
+		t.Fatal("no faults expected without rules")
+	}
+
+	err := inj.SetRules([]*api.FaultRule{
+		{Target: TargetIPFS, Method: "Pin", Percent: 100, Error: "boom"},
+		{Target: TargetRPC, Percent: 100, Delay: 50 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inj.Inject(ctx, TargetIPFS, "Pin"); err == nil {
+		t.Error("expected an injected error")
+	}
+	if err := inj.Inject(ctx, TargetIPFS, "Unpin"); err != nil {
+		t.Error("Unpin should not be affected")
+	}
+
+	start := time.Now()
+	if err := inj.Inject(ctx, TargetRPC, "PinTracker.Track"); err != nil {
+		t.Error(err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("expected a delay")
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := inj.Inject(cctx, TargetRPC, "PinTracker.Track"); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestInjectPercent(t *testing.T) {
+	ctx := context.Background()
+	inj := New()
+	err := inj.SetRules([]*api.FaultRule{
+		{Target: TargetIPFS, Percent: 50, Error: "boom"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if inj.Inject(ctx, TargetIPFS, "Pin") != nil {
+			failed++
+		}
+	}
+	if failed < 350 || failed > 650 {
+		t.Errorf("expected around half of the calls to fail: %d", failed)
+	}
+}
@@ -0,0 +1,53 @@
+package ipfscluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/faults"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func TestFaultyIPFSConnector(t *testing.T) {
+	ctx := context.Background()
+	injector := faults.New()
+	ipfs := &faultyIPFSConnector{IPFSConnector: &mockConnector{}, faults: injector}
+
+	err := ipfs.Pin(ctx, test.Cid1, -1)
+	if err != nil {
+		t.Fatal("pin should work without rules:", err)
+	}
+
+	err = injector.SetRules([]*api.FaultRule{
+		{Target: faults.TargetIPFS, Method: "Pin", Percent: 100, Error: "boom"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ipfs.Pin(ctx, test.Cid1, -1)
+	if err == nil {
+		t.Error("expected an injected error")
+	}
+	err = ipfs.Unpin(ctx, test.Cid1)
+	if err != nil {
+		t.Error("unpin should not be affected:", err)
+	}
+}
+
+func TestClusterFaultsDisabled(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	_, err := cl.Faults(ctx)
+	if err == nil {
+		t.Error("expected an error when fault injection is disabled")
+	}
+	err = cl.SetFaults(ctx, nil)
+	if err == nil {
+		t.Error("expected an error when fault injection is disabled")
+	}
+}
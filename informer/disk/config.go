@@ -14,8 +14,12 @@ const envConfigKey = "cluster_disk"
 
 // Default values for disk Config
 const (
-	DefaultMetricTTL  = 30 * time.Second
-	DefaultMetricType = MetricFreeSpace
+	DefaultMetricTTL             = 30 * time.Second
+	DefaultMetricType            = MetricFreeSpace
+	DefaultMetricPushInterval    = 0 // TTL/2
+	DefaultMetricMinPushInterval = 0 // not adaptive
+	DefaultMetricChangeThreshold = 0.05
+	DefaultReservationSize       = 100 * 1024 * 1024 // 100MiB
 )
 
 // String returns a string representation for MetricType.
@@ -36,11 +40,28 @@ type Config struct {
 
 	MetricTTL time.Duration
 	Type      MetricType
+	// MetricPushInterval sets how often metrics are published. When 0,
+	// they are published every MetricTTL/2.
+	MetricPushInterval time.Duration
+	// MetricMinPushInterval, when set, lets the push interval shorten
+	// down to this value while the metric changes fast.
+	MetricMinPushInterval time.Duration
+	// MetricChangeThreshold is the relative change in the metric value
+	// (i.e. 0.05 for 5%) between pushes which is considered fast.
+	MetricChangeThreshold float64
+	// ReservationSize is the number of bytes by which the metric is
+	// adjusted for every pin allocated to a peer until a new metric
+	// from that peer is received.
+	ReservationSize uint64
 }
 
 type jsonConfig struct {
-	MetricTTL string `json:"metric_ttl"`
-	Type      string `json:"metric_type"`
+	MetricTTL             string  `json:"metric_ttl"`
+	Type                  string  `json:"metric_type"`
+	MetricPushInterval    string  `json:"metric_push_interval"`
+	MetricMinPushInterval string  `json:"metric_min_push_interval"`
+	MetricChangeThreshold float64 `json:"metric_change_threshold"`
+	ReservationSize       *uint64 `json:"reservation_size"`
 }
 
 // ConfigKey returns a human-friendly identifier for this type of Metric.
@@ -52,6 +73,10 @@ func (cfg *Config) ConfigKey() string {
 func (cfg *Config) Default() error {
 	cfg.MetricTTL = DefaultMetricTTL
 	cfg.Type = DefaultMetricType
+	cfg.MetricPushInterval = DefaultMetricPushInterval
+	cfg.MetricMinPushInterval = DefaultMetricMinPushInterval
+	cfg.MetricChangeThreshold = DefaultMetricChangeThreshold
+	cfg.ReservationSize = DefaultReservationSize
 	return nil
 }
 
@@ -78,6 +103,18 @@ func (cfg *Config) Validate() error {
 	if cfg.Type.String() == "" {
 		return errors.New("disk.metric_type is invalid")
 	}
+
+	if cfg.MetricPushInterval < 0 || cfg.MetricPushInterval >= cfg.MetricTTL {
+		return errors.New("disk.metric_push_interval should be lower than disk.metric_ttl")
+	}
+
+	if cfg.MetricMinPushInterval < 0 {
+		return errors.New("disk.metric_min_push_interval is invalid")
+	}
+
+	if cfg.MetricChangeThreshold < 0 {
+		return errors.New("disk.metric_change_threshold is invalid")
+	}
 	return nil
 }
 
@@ -100,6 +137,23 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	t, _ := time.ParseDuration(jcfg.MetricTTL)
 	cfg.MetricTTL = t
 
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.MetricPushInterval, Dst: &cfg.MetricPushInterval, Name: "metric_push_interval"},
+		&config.DurationOpt{Duration: jcfg.MetricMinPushInterval, Dst: &cfg.MetricMinPushInterval, Name: "metric_min_push_interval"},
+	)
+	if err != nil {
+		return err
+	}
+
+	if jcfg.MetricChangeThreshold != 0 {
+		cfg.MetricChangeThreshold = jcfg.MetricChangeThreshold
+	}
+
+	if jcfg.ReservationSize != nil {
+		cfg.ReservationSize = *jcfg.ReservationSize
+	}
+
 	switch jcfg.Type {
 	case "reposize":
 		cfg.Type = MetricRepoSize
@@ -123,7 +177,11 @@ func (cfg *Config) ToJSON() (raw []byte, err error) {
 
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	return &jsonConfig{
-		MetricTTL: cfg.MetricTTL.String(),
-		Type:      cfg.Type.String(),
+		MetricTTL:             cfg.MetricTTL.String(),
+		Type:                  cfg.Type.String(),
+		MetricPushInterval:    cfg.MetricPushInterval.String(),
+		MetricMinPushInterval: cfg.MetricMinPushInterval.String(),
+		MetricChangeThreshold: cfg.MetricChangeThreshold,
+		ReservationSize:       &cfg.ReservationSize,
 	}
 }
@@ -5,11 +5,14 @@ package disk
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	logging "github.com/ipfs/go-log"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/informer/util"
 	"go.opencensus.io/trace"
 )
 
@@ -28,8 +31,9 @@ var logger = logging.Logger("diskinfo")
 // Informer is a simple object to implement the ipfscluster.Informer
 // and Component interfaces.
 type Informer struct {
-	config    *Config
-	rpcClient *rpc.Client
+	config       *Config
+	rpcClient    *rpc.Client
+	pushInterval *util.PushInterval
 }
 
 // NewInformer returns an initialized informer using the given InformerConfig.
@@ -41,6 +45,11 @@ func NewInformer(cfg *Config) (*Informer, error) {
 
 	return &Informer{
 		config: cfg,
+		pushInterval: &util.PushInterval{
+			Interval:        cfg.MetricPushInterval,
+			MinInterval:     cfg.MetricMinPushInterval,
+			ChangeThreshold: cfg.MetricChangeThreshold,
+		},
 	}, nil
 }
 
@@ -65,6 +74,50 @@ func (disk *Informer) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// PushInterval returns how long to wait before publishing a new metric.
+func (disk *Informer) PushInterval(m *api.Metric) time.Duration {
+	return disk.pushInterval.Next(m)
+}
+
+// Reserve returns a copy of the given metric adjusted by the configured
+// ReservationSize for every pin: free space is reduced and repository size
+// is increased.
+func (disk *Informer) Reserve(m *api.Metric, pins int) *api.Metric {
+	n, err := strconv.ParseUint(m.Value, 10, 64)
+	if err != nil {
+		return m
+	}
+
+	size := disk.config.ReservationSize * uint64(pins)
+	switch disk.config.Type {
+	case MetricFreeSpace:
+		if size > n {
+			n = 0
+		} else {
+			n -= size
+		}
+	case MetricRepoSize:
+		n += size
+	}
+
+	reserved := *m
+	reserved.Value = fmt.Sprintf("%d", n)
+	return &reserved
+}
+
+// FreeSpace returns the free space reported by the given metric when
+// the informer is configured to provide the "freespace" metric.
+func (disk *Informer) FreeSpace(m *api.Metric) (uint64, bool) {
+	if disk.config.Type != MetricFreeSpace || !m.Valid {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(m.Value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
 // GetMetric returns the metric obtained by this
 // Informer.
 func (disk *Informer) GetMetric(ctx context.Context) *api.Metric {
@@ -100,6 +100,72 @@ func TestRepoSize(t *testing.T) {
 	}
 }
 
+func TestReserve(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ReservationSize = 1000
+
+	inf, err := NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &api.Metric{
+		Name:  inf.Name(),
+		Value: "98000",
+		Valid: true,
+	}
+
+	reserved := inf.Reserve(m, 3)
+	if reserved.Value != "95000" {
+		t.Error("expected free space to be reduced:", reserved.Value)
+	}
+	if m.Value != "98000" {
+		t.Error("original metric should not be modified")
+	}
+
+	if reserved = inf.Reserve(m, 100); reserved.Value != "0" {
+		t.Error("expected free space to be 0:", reserved.Value)
+	}
+
+	cfg.Type = MetricRepoSize
+	if reserved = inf.Reserve(m, 2); reserved.Value != "100000" {
+		t.Error("expected repo size to be increased:", reserved.Value)
+	}
+}
+
+func TestFreeSpaceFromMetric(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+
+	inf, err := NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &api.Metric{
+		Name:  inf.Name(),
+		Value: "98000",
+		Valid: true,
+	}
+
+	free, ok := inf.FreeSpace(m)
+	if !ok || free != 98000 {
+		t.Error("expected 98000 bytes of free space:", free)
+	}
+
+	m.Valid = false
+	if _, ok := inf.FreeSpace(m); ok {
+		t.Error("invalid metrics should not report free space")
+	}
+
+	m.Valid = true
+	cfg.Type = MetricRepoSize
+	if _, ok := inf.FreeSpace(m); ok {
+		t.Error("reposize metrics should not report free space")
+	}
+}
+
 func TestWithErrors(t *testing.T) {
 	ctx := context.Background()
 	cfg := &Config{}
@@ -14,7 +14,10 @@ const envConfigKey = "cluster_numpin"
 
 // These are the default values for a Config.
 const (
-	DefaultMetricTTL = 10 * time.Second
+	DefaultMetricTTL             = 10 * time.Second
+	DefaultMetricPushInterval    = 0 // TTL/2
+	DefaultMetricMinPushInterval = 0 // not adaptive
+	DefaultMetricChangeThreshold = 0.05
 )
 
 // Config allows to initialize an Informer.
@@ -22,10 +25,22 @@ type Config struct {
 	config.Saver
 
 	MetricTTL time.Duration
+	// MetricPushInterval sets how often metrics are published. When 0,
+	// they are published every MetricTTL/2.
+	MetricPushInterval time.Duration
+	// MetricMinPushInterval, when set, lets the push interval shorten
+	// down to this value while the number of pins changes fast.
+	MetricMinPushInterval time.Duration
+	// MetricChangeThreshold is the relative change in the number of
+	// pins (i.e. 0.05 for 5%) between pushes which is considered fast.
+	MetricChangeThreshold float64
 }
 
 type jsonConfig struct {
-	MetricTTL string `json:"metric_ttl"`
+	MetricTTL             string  `json:"metric_ttl"`
+	MetricPushInterval    string  `json:"metric_push_interval"`
+	MetricMinPushInterval string  `json:"metric_min_push_interval"`
+	MetricChangeThreshold float64 `json:"metric_change_threshold"`
 }
 
 // ConfigKey returns a human-friendly identifier for this
@@ -37,6 +52,9 @@ func (cfg *Config) ConfigKey() string {
 // Default initializes this Config with sensible values.
 func (cfg *Config) Default() error {
 	cfg.MetricTTL = DefaultMetricTTL
+	cfg.MetricPushInterval = DefaultMetricPushInterval
+	cfg.MetricMinPushInterval = DefaultMetricMinPushInterval
+	cfg.MetricChangeThreshold = DefaultMetricChangeThreshold
 	return nil
 }
 
@@ -60,6 +78,18 @@ func (cfg *Config) Validate() error {
 		return errors.New("disk.metric_ttl is invalid")
 	}
 
+	if cfg.MetricPushInterval < 0 || cfg.MetricPushInterval >= cfg.MetricTTL {
+		return errors.New("numpin.metric_push_interval should be lower than numpin.metric_ttl")
+	}
+
+	if cfg.MetricMinPushInterval < 0 {
+		return errors.New("numpin.metric_min_push_interval is invalid")
+	}
+
+	if cfg.MetricChangeThreshold < 0 {
+		return errors.New("numpin.metric_change_threshold is invalid")
+	}
+
 	return nil
 }
 
@@ -80,6 +110,19 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	t, _ := time.ParseDuration(jcfg.MetricTTL)
 	cfg.MetricTTL = t
 
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.MetricPushInterval, Dst: &cfg.MetricPushInterval, Name: "metric_push_interval"},
+		&config.DurationOpt{Duration: jcfg.MetricMinPushInterval, Dst: &cfg.MetricMinPushInterval, Name: "metric_min_push_interval"},
+	)
+	if err != nil {
+		return err
+	}
+
+	if jcfg.MetricChangeThreshold != 0 {
+		cfg.MetricChangeThreshold = jcfg.MetricChangeThreshold
+	}
+
 	return cfg.Validate()
 }
 
@@ -92,6 +135,9 @@ func (cfg *Config) ToJSON() ([]byte, error) {
 
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	return &jsonConfig{
-		MetricTTL: cfg.MetricTTL.String(),
+		MetricTTL:             cfg.MetricTTL.String(),
+		MetricPushInterval:    cfg.MetricPushInterval.String(),
+		MetricMinPushInterval: cfg.MetricMinPushInterval.String(),
+		MetricChangeThreshold: cfg.MetricChangeThreshold,
 	}
 }
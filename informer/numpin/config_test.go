@@ -29,6 +29,29 @@ func TestLoadJSON(t *testing.T) {
 	if err == nil {
 		t.Error("expected error decoding metric_ttl")
 	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.MetricPushInterval = "500ms"
+	j.MetricMinPushInterval = "100ms"
+	j.MetricChangeThreshold = 0.2
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MetricPushInterval != 500*time.Millisecond ||
+		cfg.MetricMinPushInterval != 100*time.Millisecond ||
+		cfg.MetricChangeThreshold != 0.2 {
+		t.Error("push interval options not loaded correctly")
+	}
+
+	j.MetricPushInterval = "2s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with metric_push_interval over metric_ttl")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -57,6 +80,12 @@ func TestDefault(t *testing.T) {
 		t.Fatal("expected error validating")
 	}
 
+	cfg.Default()
+	cfg.MetricMinPushInterval = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
 }
 
 func TestApplyEnvVars(t *testing.T) {
@@ -5,10 +5,13 @@ package numpin
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/informer/util"
 	"go.opencensus.io/trace"
 )
 
@@ -18,8 +21,9 @@ var MetricName = "numpin"
 // Informer is a simple object to implement the ipfscluster.Informer
 // and Component interfaces
 type Informer struct {
-	config    *Config
-	rpcClient *rpc.Client
+	config       *Config
+	rpcClient    *rpc.Client
+	pushInterval *util.PushInterval
 }
 
 // NewInformer returns an initialized Informer.
@@ -31,6 +35,11 @@ func NewInformer(cfg *Config) (*Informer, error) {
 
 	return &Informer{
 		config: cfg,
+		pushInterval: &util.PushInterval{
+			Interval:        cfg.MetricPushInterval,
+			MinInterval:     cfg.MetricMinPushInterval,
+			ChangeThreshold: cfg.MetricChangeThreshold,
+		},
 	}, nil
 }
 
@@ -55,6 +64,23 @@ func (npi *Informer) Name() string {
 	return MetricName
 }
 
+// PushInterval returns how long to wait before publishing a new metric.
+func (npi *Informer) PushInterval(m *api.Metric) time.Duration {
+	return npi.pushInterval.Next(m)
+}
+
+// Reserve returns a copy of the given metric with the given number of pins
+// added to its value.
+func (npi *Informer) Reserve(m *api.Metric, pins int) *api.Metric {
+	n, err := strconv.Atoi(m.Value)
+	if err != nil {
+		return m
+	}
+	reserved := *m
+	reserved.Value = fmt.Sprintf("%d", n+pins)
+	return &reserved
+}
+
 // GetMetric contacts the IPFSConnector component and
 // requests the `pin ls` command. We return the number
 // of pins in IPFS.
@@ -74,11 +100,11 @@ func (npi *Informer) GetMetric(ctx context.Context) *api.Metric {
 	// about the number of pins in IPFS. See RPCAPI docs.
 	err := npi.rpcClient.CallContext(
 		ctx,
-		"",              // Local call
-		"IPFSConnector", // Service name
-		"PinLs",         // Method name
-		"recursive",     // in arg
-		&pinMap,         // out arg
+		"",                 // Local call
+		"IPFSConnector",    // Service name
+		"PinLs",            // Method name
+		"recursive,direct", // in arg
+		&pinMap,            // out arg
 	)
 
 	valid := err == nil
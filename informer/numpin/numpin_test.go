@@ -50,3 +50,25 @@ func Test(t *testing.T) {
 		t.Error("bad metric value")
 	}
 }
+
+func TestReserve(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+	inf, err := NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &api.Metric{
+		Name:  MetricName,
+		Value: "2",
+		Valid: true,
+	}
+	reserved := inf.Reserve(m, 3)
+	if reserved.Value != "5" {
+		t.Error("expected reserved pins to be added:", reserved.Value)
+	}
+	if m.Value != "2" {
+		t.Error("original metric should not be modified")
+	}
+}
@@ -0,0 +1,115 @@
+package pinnedbytes
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/kelseyhightower/envconfig"
+)
+
+const configKey = "pinnedbytes"
+const envConfigKey = "cluster_pinnedbytes"
+
+// These are the default values for a Config.
+const (
+	DefaultMetricTTL          = 30 * time.Second
+	DefaultMetricPushInterval = 0 // TTL/2
+)
+
+// Config allows to initialize an Informer.
+type Config struct {
+	config.Saver
+
+	MetricTTL time.Duration
+	// MetricPushInterval sets how often metrics are published. When 0,
+	// they are published every MetricTTL/2. Every metric is computed
+	// from the current pinset and repository size.
+	MetricPushInterval time.Duration
+}
+
+type jsonConfig struct {
+	MetricTTL          string `json:"metric_ttl"`
+	MetricPushInterval string `json:"metric_push_interval"`
+}
+
+// ConfigKey returns a human-friendly identifier for this
+// Config's type.
+func (cfg *Config) ConfigKey() string {
+	return configKey
+}
+
+// Default initializes this Config with sensible values.
+func (cfg *Config) Default() error {
+	cfg.MetricTTL = DefaultMetricTTL
+	cfg.MetricPushInterval = DefaultMetricPushInterval
+	return nil
+}
+
+// ApplyEnvVars fills in any Config fields found
+// as environment variables.
+func (cfg *Config) ApplyEnvVars() error {
+	jcfg := cfg.toJSONConfig()
+
+	err := envconfig.Process(envConfigKey, jcfg)
+	if err != nil {
+		return err
+	}
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+// Validate checks that the fields of this configuration have
+// sensible values.
+func (cfg *Config) Validate() error {
+	if cfg.MetricTTL <= 0 {
+		return errors.New("pinnedbytes.metric_ttl is invalid")
+	}
+
+	if cfg.MetricPushInterval < 0 || cfg.MetricPushInterval >= cfg.MetricTTL {
+		return errors.New("pinnedbytes.metric_push_interval should be lower than pinnedbytes.metric_ttl")
+	}
+
+	return nil
+}
+
+// LoadJSON parses a raw JSON byte-slice as generated by ToJSON().
+func (cfg *Config) LoadJSON(raw []byte) error {
+	jcfg := &jsonConfig{}
+	err := json.Unmarshal(raw, jcfg)
+	if err != nil {
+		return err
+	}
+
+	cfg.Default()
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.MetricTTL, Dst: &cfg.MetricTTL, Name: "metric_ttl"},
+		&config.DurationOpt{Duration: jcfg.MetricPushInterval, Dst: &cfg.MetricPushInterval, Name: "metric_push_interval"},
+	)
+	if err != nil {
+		return err
+	}
+
+	return cfg.Validate()
+}
+
+// ToJSON generates a human-friendly JSON representation of this Config.
+func (cfg *Config) ToJSON() ([]byte, error) {
+	jcfg := cfg.toJSONConfig()
+
+	return config.DefaultJSONMarshal(jcfg)
+}
+
+func (cfg *Config) toJSONConfig() *jsonConfig {
+	return &jsonConfig{
+		MetricTTL:          cfg.MetricTTL.String(),
+		MetricPushInterval: cfg.MetricPushInterval.String(),
+	}
+}
@@ -0,0 +1,111 @@
+// Package pinnedbytes implements an ipfs-cluster informer which estimates
+// how many bytes this peer is pinning and returns it as api.Metric.
+//
+// The estimate is obtained from the sizes of the pins allocated to the
+// peer, as recorded in the shared state when they were allocated, and it is
+// reconciled with the size of the IPFS repository every time the metric is
+// produced (see api.PinnedBytes).
+package pinnedbytes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	logging "github.com/ipfs/go-log"
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/informer/util"
+	"go.opencensus.io/trace"
+)
+
+var logger = logging.Logger("pinnedbytes")
+
+// MetricName specifies the name of our metric
+var MetricName = "pinnedbytes"
+
+// Informer is a simple object to implement the ipfscluster.Informer
+// and Component interfaces
+type Informer struct {
+	config       *Config
+	rpcClient    *rpc.Client
+	pushInterval *util.PushInterval
+}
+
+// NewInformer returns an initialized Informer.
+func NewInformer(cfg *Config) (*Informer, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Informer{
+		config: cfg,
+		pushInterval: &util.PushInterval{
+			Interval: cfg.MetricPushInterval,
+		},
+	}, nil
+}
+
+// SetClient provides us with an rpc.Client which allows
+// contacting other components in the cluster.
+func (pbi *Informer) SetClient(c *rpc.Client) {
+	pbi.rpcClient = c
+}
+
+// Shutdown is called on cluster shutdown. We just invalidate
+// any metrics from this point.
+func (pbi *Informer) Shutdown(ctx context.Context) error {
+	_, span := trace.StartSpan(ctx, "informer/pinnedbytes/Shutdown")
+	defer span.End()
+
+	pbi.rpcClient = nil
+	return nil
+}
+
+// Name returns the name of this informer
+func (pbi *Informer) Name() string {
+	return MetricName
+}
+
+// PushInterval returns how long to wait before publishing a new metric.
+func (pbi *Informer) PushInterval(m *api.Metric) time.Duration {
+	return pbi.pushInterval.Next(m)
+}
+
+// GetMetric asks the Cluster component for the estimated number of bytes
+// pinned by this peer.
+func (pbi *Informer) GetMetric(ctx context.Context) *api.Metric {
+	ctx, span := trace.StartSpan(ctx, "informer/pinnedbytes/GetMetric")
+	defer span.End()
+
+	if pbi.rpcClient == nil {
+		return &api.Metric{
+			Name:  MetricName,
+			Valid: false,
+		}
+	}
+
+	var pb api.PinnedBytes
+	err := pbi.rpcClient.CallContext(
+		ctx,
+		"",
+		"Cluster",
+		"PinnedBytes",
+		struct{}{},
+		&pb,
+	)
+	if err != nil {
+		logger.Error(err)
+	}
+
+	m := &api.Metric{
+		Name:  MetricName,
+		Value: fmt.Sprintf("%d", pb.Bytes),
+		Valid: err == nil,
+	}
+
+	m.SetTTL(pbi.config.MetricTTL)
+	return m
+}
@@ -0,0 +1,67 @@
+package pinnedbytes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+)
+
+type mockService struct {
+	err error
+}
+
+func mockRPCClient(t *testing.T, svc *mockService) *rpc.Client {
+	s := rpc.NewServer(nil, "mock")
+	c := rpc.NewClientWithServer(nil, "mock", s)
+	err := s.RegisterName("Cluster", svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func (mock *mockService) PinnedBytes(ctx context.Context, in struct{}, out *api.PinnedBytes) error {
+	if mock.err != nil {
+		return mock.err
+	}
+	*out = api.PinnedBytes{
+		Bytes:    3000,
+		Pins:     3,
+		RepoSize: 2500,
+	}
+	return nil
+}
+
+func Test(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	inf, err := NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := inf.GetMetric(ctx)
+	if m.Valid {
+		t.Error("metric should be invalid")
+	}
+
+	svc := &mockService{}
+	inf.SetClient(mockRPCClient(t, svc))
+	m = inf.GetMetric(ctx)
+	if !m.Valid {
+		t.Error("metric should be valid")
+	}
+	if m.Name != MetricName || m.Value != "3000" {
+		t.Error("bad metric:", m.Name, m.Value)
+	}
+
+	svc.err = errors.New("state not available")
+	m = inf.GetMetric(ctx)
+	if m.Valid {
+		t.Error("metric should be invalid when the estimate fails")
+	}
+}
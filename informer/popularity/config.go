@@ -0,0 +1,159 @@
+package popularity
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/kelseyhightower/envconfig"
+)
+
+const configKey = "popularity"
+const envConfigKey = "cluster_popularity"
+
+// These are the default values for a Config.
+const (
+	DefaultMetricTTL          = 30 * time.Second
+	DefaultMetricPushInterval = 0 // TTL/2
+	DefaultHalfLife           = time.Hour
+	DefaultMaxCids            = 100
+	DefaultLogPollInterval    = time.Second
+)
+
+// Config allows to initialize an Informer.
+type Config struct {
+	config.Saver
+
+	MetricTTL time.Duration
+	// MetricPushInterval sets how often metrics are published. When 0,
+	// they are published every MetricTTL/2.
+	MetricPushInterval time.Duration
+	// HalfLife is the time it takes for the popularity score of a Cid
+	// to halve when it is not accessed.
+	HalfLife time.Duration
+	// MaxCids is the number of most popular Cids included in the metric.
+	MaxCids int
+	// AccessLogs is a list of files (i.e. gateway or reverse proxy
+	// access logs) which are followed to count accesses to content.
+	AccessLogs []string
+	// LogPollInterval sets how often access logs are checked for new
+	// lines.
+	LogPollInterval time.Duration
+}
+
+type jsonConfig struct {
+	MetricTTL          string   `json:"metric_ttl"`
+	MetricPushInterval string   `json:"metric_push_interval"`
+	HalfLife           string   `json:"half_life"`
+	MaxCids            int      `json:"max_cids"`
+	AccessLogs         []string `json:"access_logs"`
+	LogPollInterval    string   `json:"log_poll_interval"`
+}
+
+// ConfigKey returns a human-friendly identifier for this
+// Config's type.
+func (cfg *Config) ConfigKey() string {
+	return configKey
+}
+
+// Default initializes this Config with sensible values.
+func (cfg *Config) Default() error {
+	cfg.MetricTTL = DefaultMetricTTL
+	cfg.MetricPushInterval = DefaultMetricPushInterval
+	cfg.HalfLife = DefaultHalfLife
+	cfg.MaxCids = DefaultMaxCids
+	cfg.AccessLogs = []string{}
+	cfg.LogPollInterval = DefaultLogPollInterval
+	return nil
+}
+
+// ApplyEnvVars fills in any Config fields found
+// as environment variables.
+func (cfg *Config) ApplyEnvVars() error {
+	jcfg := cfg.toJSONConfig()
+
+	err := envconfig.Process(envConfigKey, jcfg)
+	if err != nil {
+		return err
+	}
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+// Validate checks that the fields of this configuration have
+// sensible values.
+func (cfg *Config) Validate() error {
+	if cfg.MetricTTL <= 0 {
+		return errors.New("popularity.metric_ttl is invalid")
+	}
+
+	if cfg.MetricPushInterval < 0 || cfg.MetricPushInterval >= cfg.MetricTTL {
+		return errors.New("popularity.metric_push_interval should be lower than popularity.metric_ttl")
+	}
+
+	if cfg.HalfLife <= 0 {
+		return errors.New("popularity.half_life is invalid")
+	}
+
+	if cfg.MaxCids <= 0 {
+		return errors.New("popularity.max_cids should be positive")
+	}
+
+	if cfg.LogPollInterval <= 0 {
+		return errors.New("popularity.log_poll_interval is invalid")
+	}
+
+	return nil
+}
+
+// LoadJSON parses a raw JSON byte-slice as generated by ToJSON().
+func (cfg *Config) LoadJSON(raw []byte) error {
+	jcfg := &jsonConfig{}
+	err := json.Unmarshal(raw, jcfg)
+	if err != nil {
+		return err
+	}
+
+	cfg.Default()
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.MetricTTL, Dst: &cfg.MetricTTL, Name: "metric_ttl"},
+		&config.DurationOpt{Duration: jcfg.MetricPushInterval, Dst: &cfg.MetricPushInterval, Name: "metric_push_interval"},
+		&config.DurationOpt{Duration: jcfg.HalfLife, Dst: &cfg.HalfLife, Name: "half_life"},
+		&config.DurationOpt{Duration: jcfg.LogPollInterval, Dst: &cfg.LogPollInterval, Name: "log_poll_interval"},
+	)
+	if err != nil {
+		return err
+	}
+
+	config.SetIfNotDefault(jcfg.MaxCids, &cfg.MaxCids)
+	if jcfg.AccessLogs != nil {
+		cfg.AccessLogs = jcfg.AccessLogs
+	}
+
+	return cfg.Validate()
+}
+
+// ToJSON generates a human-friendly JSON representation of this Config.
+func (cfg *Config) ToJSON() ([]byte, error) {
+	jcfg := cfg.toJSONConfig()
+
+	return config.DefaultJSONMarshal(jcfg)
+}
+
+func (cfg *Config) toJSONConfig() *jsonConfig {
+	return &jsonConfig{
+		MetricTTL:          cfg.MetricTTL.String(),
+		MetricPushInterval: cfg.MetricPushInterval.String(),
+		HalfLife:           cfg.HalfLife.String(),
+		MaxCids:            cfg.MaxCids,
+		AccessLogs:         cfg.AccessLogs,
+		LogPollInterval:    cfg.LogPollInterval.String(),
+	}
+}
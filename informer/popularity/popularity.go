@@ -0,0 +1,256 @@
+// Package popularity implements an ipfs-cluster informer which keeps track
+// of how often content is accessed through this peer (via the IPFS proxy or
+// access logs) and publishes the most popular Cids as an api.Metric.
+//
+// The metric value is a JSON object mapping Cids to popularity scores.
+// Every access adds 1 to the score of a Cid, and scores decay exponentially
+// with the configured half-life, so that they reflect recent usage.
+package popularity
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/informer/util"
+	"go.opencensus.io/trace"
+)
+
+var logger = logging.Logger("popularity")
+
+// MetricName specifies the name of our metric
+var MetricName = "popularity"
+
+// scores below this value are forgotten.
+const minScore = 0.01
+
+type score struct {
+	value float64
+	last  time.Time
+}
+
+// Informer is an ipfscluster.Informer which tracks content accesses. It
+// also implements the ipfscluster.AccessRecorder interface.
+type Informer struct {
+	config       *Config
+	pushInterval *util.PushInterval
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	rpcClient *rpc.Client
+	scores    map[cid.Cid]*score
+	shutdown  bool
+}
+
+// NewInformer returns an initialized Informer. The access logs set in the
+// configuration are followed until the informer is shut down. Additional
+// sources may be attached with AddSource().
+func NewInformer(cfg *Config) (*Informer, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	inf := &Informer{
+		config: cfg,
+		pushInterval: &util.PushInterval{
+			Interval: cfg.MetricPushInterval,
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		scores: make(map[cid.Cid]*score),
+	}
+
+	for _, path := range cfg.AccessLogs {
+		inf.AddSource(&LogSource{
+			Path:         path,
+			PollInterval: cfg.LogPollInterval,
+		})
+	}
+	return inf, nil
+}
+
+// AddSource starts reading accesses from the given Source. It stops when
+// the informer is shut down.
+func (pi *Informer) AddSource(src Source) {
+	pi.wg.Add(1)
+	go func() {
+		defer pi.wg.Done()
+		src.Run(pi.ctx, pi.RecordAccess)
+	}()
+}
+
+// SetClient provides us with an rpc.Client which allows
+// contacting other components in the cluster.
+func (pi *Informer) SetClient(c *rpc.Client) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	pi.rpcClient = c
+}
+
+// Shutdown stops all access sources and invalidates any metrics from this
+// point.
+func (pi *Informer) Shutdown(ctx context.Context) error {
+	_, span := trace.StartSpan(ctx, "informer/popularity/Shutdown")
+	defer span.End()
+
+	pi.cancel()
+	pi.wg.Wait()
+
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	pi.rpcClient = nil
+	pi.shutdown = true
+	return nil
+}
+
+// Name returns the name of this informer
+func (pi *Informer) Name() string {
+	return MetricName
+}
+
+// PushInterval returns how long to wait before publishing a new metric.
+func (pi *Informer) PushInterval(m *api.Metric) time.Duration {
+	return pi.pushInterval.Next(m)
+}
+
+// decay returns the value of a score at the given time.
+func (pi *Informer) decay(s *score, now time.Time) float64 {
+	elapsed := now.Sub(s.last)
+	if elapsed <= 0 {
+		return s.value
+	}
+	return s.value * math.Exp2(-float64(elapsed)/float64(pi.config.HalfLife))
+}
+
+// RecordAccess registers an access to the given Cid.
+func (pi *Informer) RecordAccess(c cid.Cid) {
+	if !c.Defined() {
+		return
+	}
+
+	now := time.Now()
+
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	s, ok := pi.scores[c]
+	if !ok {
+		pi.scores[c] = &score{value: 1, last: now}
+		return
+	}
+	s.value = pi.decay(s, now) + 1
+	s.last = now
+}
+
+// Scores returns the current, decayed, popularity scores of the most
+// accessed Cids (up to MaxCids). Cids whose scores have decayed to
+// negligible values are forgotten.
+func (pi *Informer) Scores() map[string]float64 {
+	now := time.Now()
+
+	pi.mu.Lock()
+	type entry struct {
+		cid   cid.Cid
+		value float64
+	}
+	entries := make([]entry, 0, len(pi.scores))
+	for c, s := range pi.scores {
+		v := pi.decay(s, now)
+		if v < minScore {
+			delete(pi.scores, c)
+			continue
+		}
+		entries = append(entries, entry{cid: c, value: v})
+	}
+	pi.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].value > entries[j].value
+	})
+	if len(entries) > pi.config.MaxCids {
+		entries = entries[:pi.config.MaxCids]
+	}
+
+	scores := make(map[string]float64, len(entries))
+	for _, e := range entries {
+		scores[e.cid.String()] = e.value
+	}
+	return scores
+}
+
+// GetMetric returns a metric with the popularity scores of the most
+// accessed Cids.
+func (pi *Informer) GetMetric(ctx context.Context) *api.Metric {
+	_, span := trace.StartSpan(ctx, "informer/popularity/GetMetric")
+	defer span.End()
+
+	pi.mu.Lock()
+	shutdown := pi.shutdown
+	pi.mu.Unlock()
+
+	if shutdown {
+		return &api.Metric{
+			Valid: false,
+		}
+	}
+
+	value, err := json.Marshal(pi.Scores())
+	if err != nil {
+		logger.Error(err)
+	}
+
+	m := &api.Metric{
+		Name:  MetricName,
+		Value: string(value),
+		Valid: err == nil,
+	}
+
+	m.SetTTL(pi.config.MetricTTL)
+	return m
+}
+
+// ParseMetric returns the popularity scores contained in a metric
+// produced by this informer.
+func ParseMetric(m *api.Metric) (map[string]float64, error) {
+	scores := make(map[string]float64)
+	if m == nil || m.Value == "" {
+		return scores, nil
+	}
+	err := json.Unmarshal([]byte(m.Value), &scores)
+	return scores, err
+}
+
+// Aggregate adds up the popularity scores of the given metrics (usually
+// one per peer), thus providing cluster-wide popularity of content. Invalid
+// or expired metrics are ignored.
+func Aggregate(metrics []*api.Metric) map[string]float64 {
+	total := make(map[string]float64)
+	for _, m := range metrics {
+		if m == nil || !m.Valid || m.Expired() {
+			continue
+		}
+		scores, err := ParseMetric(m)
+		if err != nil {
+			logger.Warningf("error parsing popularity metric from %s: %s", m.Peer, err)
+			continue
+		}
+		for c, v := range scores {
+			total[c] += v
+		}
+	}
+	return total
+}
@@ -0,0 +1,196 @@
+package popularity
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func testInformer(t *testing.T) *Informer {
+	cfg := &Config{}
+	cfg.Default()
+	inf, err := NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inf
+}
+
+func Test(t *testing.T) {
+	ctx := context.Background()
+	inf := testInformer(t)
+	defer inf.Shutdown(ctx)
+
+	inf.RecordAccess(test.Cid1)
+	inf.RecordAccess(test.Cid1)
+	inf.RecordAccess(test.Cid2)
+
+	m := inf.GetMetric(ctx)
+	if !m.Valid || m.Name != MetricName {
+		t.Fatal("metric should be valid")
+	}
+
+	scores, err := ParseMetric(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 2 {
+		t.Fatal("expected 2 cids in metric")
+	}
+	if s := scores[test.Cid1.String()]; s <= 1.9 || s > 2 {
+		t.Error("bad score for Cid1:", s)
+	}
+	if scores[test.Cid1.String()] <= scores[test.Cid2.String()] {
+		t.Error("Cid1 should be more popular than Cid2")
+	}
+
+	inf.Shutdown(ctx)
+	m = inf.GetMetric(ctx)
+	if m.Valid {
+		t.Error("metric should be invalid after shutdown")
+	}
+}
+
+func TestDecayAndMaxCids(t *testing.T) {
+	inf := testInformer(t)
+	defer inf.Shutdown(context.Background())
+	inf.config.HalfLife = time.Minute
+	inf.config.MaxCids = 1
+
+	inf.RecordAccess(test.Cid1)
+	inf.RecordAccess(test.Cid2)
+	inf.RecordAccess(test.Cid2)
+
+	// age Cid1 by one half-life and Cid3 until it is forgotten.
+	inf.RecordAccess(test.Cid3)
+	inf.mu.Lock()
+	inf.scores[test.Cid1].last = inf.scores[test.Cid1].last.Add(-time.Minute)
+	inf.scores[test.Cid3].last = inf.scores[test.Cid3].last.Add(-time.Hour)
+	inf.mu.Unlock()
+
+	scores := inf.Scores()
+	if len(scores) != 1 {
+		t.Fatal("expected only the most popular cid")
+	}
+	if _, ok := scores[test.Cid2.String()]; !ok {
+		t.Error("Cid2 should be the most popular")
+	}
+
+	inf.config.MaxCids = 10
+	scores = inf.Scores()
+	if s := scores[test.Cid1.String()]; s < 0.49 || s > 0.51 {
+		t.Error("Cid1 score should have halved:", s)
+	}
+	if _, ok := scores[test.Cid3.String()]; ok {
+		t.Error("Cid3 should have been forgotten")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	m1 := &api.Metric{
+		Name:  MetricName,
+		Value: fmt.Sprintf(`{"%s": 1.5, "%s": 1}`, test.Cid1, test.Cid2),
+		Valid: true,
+	}
+	m1.SetTTL(time.Minute)
+	m2 := &api.Metric{
+		Name:  MetricName,
+		Value: fmt.Sprintf(`{"%s": 2}`, test.Cid1),
+		Valid: true,
+	}
+	m2.SetTTL(time.Minute)
+	invalid := &api.Metric{
+		Name:  MetricName,
+		Value: fmt.Sprintf(`{"%s": 10}`, test.Cid2),
+		Valid: false,
+	}
+	broken := &api.Metric{
+		Name:  MetricName,
+		Value: "abc",
+		Valid: true,
+	}
+	broken.SetTTL(time.Minute)
+
+	total := Aggregate([]*api.Metric{m1, m2, invalid, broken})
+	if total[test.Cid1.String()] != 3.5 || total[test.Cid2.String()] != 1 {
+		t.Error("bad aggregation:", total)
+	}
+}
+
+func TestExtractCids(t *testing.T) {
+	line := fmt.Sprintf(
+		`127.0.0.1 - - [16/Oct/2026:10:00:00 +0000] "GET /ipfs/%s/a.txt HTTP/1.1" 200 12 "-" "curl" %s.ipfs.dweb.link`,
+		test.Cid1,
+		test.Cid4,
+	)
+	cids := ExtractCids(line)
+	if len(cids) != 2 || !cids[0].Equals(test.Cid1) || !cids[1].Equals(test.Cid4) {
+		t.Error("bad cids extracted:", cids)
+	}
+
+	if len(ExtractCids(`"GET /ipfs/notacid HTTP/1.1"`)) != 0 {
+		t.Error("should not extract invalid cids")
+	}
+}
+
+func TestLogSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "popularity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access.log")
+
+	// lines written before starting are ignored.
+	err = ioutil.WriteFile(path, []byte(fmt.Sprintf("GET /ipfs/%s\n", test.Cid3)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.AccessLogs = []string{path}
+	cfg.LogPollInterval = 20 * time.Millisecond
+	inf, err := NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inf.Shutdown(context.Background())
+	time.Sleep(100 * time.Millisecond)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(f, "GET /ipfs/%s\n", test.Cid1)
+	fmt.Fprintf(f, "GET /ipfs/%s", test.Cid2) // incomplete
+	f.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	scores := inf.Scores()
+	if len(scores) != 1 || scores[test.Cid1.String()] == 0 {
+		t.Fatal("expected only Cid1 to be recorded:", scores)
+	}
+
+	// truncation starts reading from the beginning.
+	err = ioutil.WriteFile(path, []byte(fmt.Sprintf("GET /ipfs/%s\n", test.Cid4)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	scores = inf.Scores()
+	if scores[test.Cid4.String()] == 0 {
+		t.Error("expected Cid4 to be recorded after truncation:", scores)
+	}
+	if _, ok := scores[test.Cid2.String()]; ok {
+		t.Error("incomplete line should not have been recorded")
+	}
+}
@@ -0,0 +1,142 @@
+package popularity
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// Source provides content accesses to the Informer. Run should call
+// record for every access until the given context is cancelled.
+type Source interface {
+	Run(ctx context.Context, record func(cid.Cid))
+}
+
+var (
+	// matches /ipfs/<cid> paths in requests.
+	pathRegexp = regexp.MustCompile(`/ipfs/([A-Za-z0-9]+)`)
+	// matches <cid>.ipfs.<domain> subdomain gateway hosts.
+	subdomainRegexp = regexp.MustCompile(`\b([a-z0-9]+)\.ipfs\.`)
+)
+
+// ExtractCids returns the Cids accessed according to an access log line.
+// It understands both path (/ipfs/<cid>/...) and subdomain
+// (<cid>.ipfs.<domain>) gateway requests.
+func ExtractCids(line string) []cid.Cid {
+	var cids []cid.Cid
+	seen := make(map[cid.Cid]struct{})
+	for _, re := range []*regexp.Regexp{pathRegexp, subdomainRegexp} {
+		for _, m := range re.FindAllStringSubmatch(line, -1) {
+			c, err := cid.Decode(m[1])
+			if err != nil {
+				continue
+			}
+			if _, ok := seen[c]; ok {
+				continue
+			}
+			seen[c] = struct{}{}
+			cids = append(cids, c)
+		}
+	}
+	return cids
+}
+
+// LogSource is a Source which follows an access log file (i.e. from an
+// IPFS gateway or a reverse proxy in front of it) and records the Cids
+// requested in every new line. Only lines written after the source starts
+// are considered. Truncated or rotated files are read from the start.
+type LogSource struct {
+	Path         string
+	PollInterval time.Duration
+}
+
+// Run follows the log file until the context is cancelled.
+func (ls *LogSource) Run(ctx context.Context, record func(cid.Cid)) {
+	interval := ls.PollInterval
+	if interval <= 0 {
+		interval = DefaultLogPollInterval
+	}
+
+	var f *os.File
+	var reader *bufio.Reader
+	var offset int64
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	open := func(fromEnd bool) {
+		var err error
+		f, err = os.Open(ls.Path)
+		if err != nil {
+			f = nil
+			logger.Debugf("cannot open access log %s: %s", ls.Path, err)
+			return
+		}
+		offset = 0
+		if fromEnd {
+			offset, err = f.Seek(0, io.SeekEnd)
+			if err != nil {
+				logger.Error(err)
+			}
+		}
+		reader = bufio.NewReader(f)
+	}
+
+	open(true)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var partial string
+	for {
+		if f != nil {
+			for {
+				line, err := reader.ReadString('\n')
+				offset += int64(len(line))
+				if err != nil {
+					// keep incomplete lines until they
+					// are finished.
+					partial += line
+					break
+				}
+				for _, c := range ExtractCids(partial + line) {
+					record(c)
+				}
+				partial = ""
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if f == nil {
+			// The file did not exist when we started: read
+			// it from the start when it appears.
+			open(false)
+			continue
+		}
+
+		// Reopen the file when it has been truncated or
+		// replaced (i.e. log rotation).
+		st, err := os.Stat(ls.Path)
+		if err != nil {
+			continue
+		}
+		cur, err := f.Stat()
+		if err != nil || !os.SameFile(st, cur) || st.Size() < offset {
+			f.Close()
+			partial = ""
+			open(false)
+		}
+	}
+}
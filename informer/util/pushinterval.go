@@ -0,0 +1,84 @@
+// Package util provides helpers shared by informer implementations.
+package util
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// PushInterval decides how often an informer's metrics should be
+// published. By default, metrics are pushed at a fixed interval (or
+// every TTL/2 when it is not set). When a minimum interval is given, the
+// interval adapts to how fast the metric value changes: it is halved
+// (down to the minimum) every time the value changes by more than
+// ChangeThreshold and doubled back (up to the base interval) when it does
+// not.
+type PushInterval struct {
+	// Interval is the base interval. When 0, TTL/2 is used.
+	Interval time.Duration
+	// MinInterval is the shortest interval when values change fast.
+	// 0 disables adaptive intervals.
+	MinInterval time.Duration
+	// ChangeThreshold is the relative change in a numeric metric
+	// value (i.e. 0.1 for 10%) which is considered fast. Any change
+	// counts for non-numeric values.
+	ChangeThreshold float64
+
+	mu        sync.Mutex
+	lastValue string
+	current   time.Duration
+}
+
+// Next returns how long to wait before pushing a new metric, given the
+// last one pushed.
+func (pi *PushInterval) Next(m *api.Metric) time.Duration {
+	base := pi.Interval
+	if base <= 0 {
+		base = m.GetTTL() / 2
+	}
+
+	if pi.MinInterval <= 0 || pi.MinInterval >= base {
+		return base
+	}
+
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	if pi.current == 0 {
+		pi.current = base
+	}
+
+	if m.Valid && pi.lastValue != "" && changed(pi.lastValue, m.Value, pi.ChangeThreshold) {
+		pi.current /= 2
+		if pi.current < pi.MinInterval {
+			pi.current = pi.MinInterval
+		}
+	} else {
+		pi.current *= 2
+		if pi.current > base {
+			pi.current = base
+		}
+	}
+
+	if m.Valid {
+		pi.lastValue = m.Value
+	}
+	return pi.current
+}
+
+// changed returns true when the relative change between two metric
+// values is over the given threshold.
+func changed(old, new string, threshold float64) bool {
+	oldN, err1 := strconv.ParseFloat(old, 64)
+	newN, err2 := strconv.ParseFloat(new, 64)
+	if err1 != nil || err2 != nil {
+		return old != new
+	}
+
+	diff := math.Abs(newN - oldN)
+	return diff/math.Max(math.Abs(oldN), 1) > threshold
+}
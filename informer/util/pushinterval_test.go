@@ -0,0 +1,74 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+func metric(value string) *api.Metric {
+	m := &api.Metric{
+		Name:  "test",
+		Value: value,
+		Valid: true,
+	}
+	m.SetTTL(10 * time.Second)
+	return m
+}
+
+func TestPushIntervalDefault(t *testing.T) {
+	pi := &PushInterval{}
+	// GetTTL() returns the time left until expiration
+	if next := pi.Next(metric("1")); next > 5*time.Second || next < 4*time.Second {
+		t.Errorf("expected TTL/2, got %s", next)
+	}
+
+	pi = &PushInterval{Interval: time.Second}
+	if next := pi.Next(metric("1")); next != time.Second {
+		t.Errorf("expected configured interval, got %s", next)
+	}
+
+	// values changing do not matter when not adaptive
+	if next := pi.Next(metric("1000")); next != time.Second {
+		t.Errorf("expected configured interval, got %s", next)
+	}
+}
+
+func TestPushIntervalAdaptive(t *testing.T) {
+	pi := &PushInterval{
+		Interval:        8 * time.Second,
+		MinInterval:     time.Second,
+		ChangeThreshold: 0.1,
+	}
+
+	expect := func(value string, d time.Duration) {
+		t.Helper()
+		if next := pi.Next(metric(value)); next != d {
+			t.Errorf("value %s: expected %s, got %s", value, d, next)
+		}
+	}
+
+	expect("100", 8*time.Second)
+	expect("105", 8*time.Second) // under threshold
+	expect("200", 4*time.Second)
+	expect("400", 2*time.Second)
+	expect("800", time.Second)
+	expect("1600", time.Second) // min interval
+	expect("1600", 2*time.Second)
+	expect("1600", 4*time.Second)
+	expect("1600", 8*time.Second)
+	expect("1600", 8*time.Second) // base interval
+
+	// invalid metrics do not count as changes
+	invalid := metric("0")
+	invalid.Valid = false
+	if next := pi.Next(invalid); next != 8*time.Second {
+		t.Errorf("expected base interval with invalid metric, got %s", next)
+	}
+	expect("1600", 8*time.Second)
+
+	// non numeric values
+	expect("a", 4*time.Second)
+	expect("a", 8*time.Second)
+}
@@ -8,6 +8,7 @@ package ipfscluster
 
 import (
 	"context"
+	"time"
 
 	"github.com/ipfs/ipfs-cluster/api"
 	"github.com/ipfs/ipfs-cluster/state"
@@ -96,6 +97,25 @@ type IPFSConnector interface {
 	BlockPut(context.Context, *api.NodeWithMeta) error
 	// BlockGet retrieves the raw data of an IPFS block.
 	BlockGet(context.Context, cid.Cid) ([]byte, error)
+	// Provide announces to the network that the IPFS daemon provides
+	// the given CID.
+	Provide(context.Context, cid.Cid) error
+	// BitswapStat returns the bitswap statistics of the IPFS daemon.
+	BitswapStat(context.Context) (*api.IPFSBitswapStat, error)
+	// PinProgress returns how much of their DAGs the ongoing pins
+	// have fetched.
+	PinProgress(context.Context) ([]*api.PinProgress, error)
+	// DAGSize returns the cumulative size of the DAG under the given
+	// CID.
+	DAGSize(context.Context, cid.Cid) (uint64, error)
+	// DAGBlocks returns the blocks of the DAG under the given CID, down
+	// to the given depth (-1 for the whole DAG), and their sizes.
+	DAGBlocks(context.Context, cid.Cid, int) ([]*api.IPFSBlockStat, error)
+	// RepoGC runs the garbage collector of the IPFS daemon.
+	RepoGC(context.Context) (*api.RepoGC, error)
+	// DaemonInfo returns the version of the IPFS daemon and the
+	// configuration values which should match across the cluster.
+	DaemonInfo(context.Context) (*api.IPFSDaemonInfo, error)
 }
 
 // Peered represents a component which needs to be aware of the peers
@@ -117,8 +137,10 @@ type PinTracker interface {
 	// Untrack tells the tracker that a Cid is to be forgotten. The tracker
 	// may perform an IPFS unpin operation.
 	Untrack(context.Context, cid.Cid) error
-	// StatusAll returns the list of pins with their local status.
-	StatusAll(context.Context) []*api.PinInfo
+	// StatusAll returns the list of pins with their local status,
+	// limited to those matching the given filter. A
+	// TrackerStatusUndefined filter matches everything.
+	StatusAll(context.Context, api.TrackerStatus) []*api.PinInfo
 	// Status returns the local status of a given Cid.
 	Status(context.Context, cid.Cid) *api.PinInfo
 	// SyncAll makes sure that all tracked Cids reflect the real IPFS status.
@@ -133,6 +155,31 @@ type PinTracker interface {
 	Recover(context.Context, cid.Cid) (*api.PinInfo, error)
 }
 
+// OperationCounter is an optional interface for PinTrackers. It allows to
+// obtain the size of the pin queue and the number of items in error without
+// listing every tracked item.
+type OperationCounter interface {
+	// OperationCounts returns the number of queued operations and
+	// the number of operations which failed.
+	OperationCounts(context.Context) (queued int, errors int)
+}
+
+// OperationStatsReporter is an optional interface for PinTrackers. It
+// allows to obtain the number and the age of the operations in each phase,
+// which are recorded as metrics.
+type OperationStatsReporter interface {
+	OperationStats(context.Context) []*api.OperationStats
+}
+
+// Pauser is an optional interface for PinTrackers. It allows to stop them
+// temporarily from starting queued pin and unpin operations, i.e. while the
+// IPFS daemon is under maintenance, without shutting down the peer.
+type Pauser interface {
+	Pause(context.Context) error
+	Resume(context.Context) error
+	Paused() bool
+}
+
 // Informer provides Metric information from a peer. The metrics produced by
 // informers are then passed to a PinAllocator which will use them to
 // determine where to pin content. The metric is agnostic to the rest of
@@ -141,6 +188,36 @@ type Informer interface {
 	Component
 	Name() string
 	GetMetric(context.Context) *api.Metric
+	// PushInterval returns how long to wait before publishing a new
+	// metric, given the last one published.
+	PushInterval(*api.Metric) time.Duration
+}
+
+// Reserver is an optional interface for Informers. It allows to adjust
+// their metrics to account for allocations which were made after the metric
+// was produced (reservations), until a new metric reflecting them is
+// received.
+type Reserver interface {
+	// Reserve returns a copy of the given metric adjusted as if the
+	// given number of pins had been allocated to the metric's peer.
+	Reserve(m *api.Metric, pins int) *api.Metric
+}
+
+// FreeSpacer is an optional interface for Informers whose metrics carry the
+// free space available in a peer. It allows to skip peers which do not have
+// enough space for a pin of known size when allocating it.
+type FreeSpacer interface {
+	// FreeSpace returns the free space in bytes reported by the given
+	// metric, or false if it does not report it.
+	FreeSpace(m *api.Metric) (uint64, bool)
+}
+
+// AccessRecorder is an optional interface for Informers. It allows them to
+// be notified whenever content is accessed through this peer, for example
+// to produce usage-based metrics.
+type AccessRecorder interface {
+	// RecordAccess registers an access to the given Cid.
+	RecordAccess(cid.Cid)
 }
 
 // PinAllocator decides where to pin certain content. In order to make such
@@ -149,6 +226,8 @@ type Informer interface {
 // allocate the content.
 type PinAllocator interface {
 	Component
+	// Name returns a short identifier for the allocation strategy.
+	Name() string
 	// Allocate returns the list of peers that should be assigned to
 	// Pin content in order of preference (from the most preferred to the
 	// least). The "current" map contains valid metrics for peers
@@ -158,6 +237,15 @@ type PinAllocator interface {
 	Allocate(ctx context.Context, c cid.Cid, current, candidates, priority map[peer.ID]*api.Metric) ([]peer.ID, error)
 }
 
+// PinAwareAllocator is an optional interface for PinAllocators which need
+// the full pin request (replication factors, name, metadata...) and not
+// only its CID in order to decide on allocations. When the allocator
+// implements it, AllocatePin is used instead of Allocate.
+type PinAwareAllocator interface {
+	PinAllocator
+	AllocatePin(ctx context.Context, pin *api.Pin, current, candidates, priority map[peer.ID]*api.Metric) ([]peer.ID, error)
+}
+
 // PeerMonitor is a component in charge of publishing a peer's metrics and
 // reading metrics from other peers in the cluster. The PinAllocator will
 // use the metrics provided by the monitor as candidates for Pin allocations.
@@ -22,6 +22,7 @@ import (
 	"github.com/ipfs/ipfs-cluster/datastore/badger"
 	"github.com/ipfs/ipfs-cluster/datastore/inmem"
 	"github.com/ipfs/ipfs-cluster/informer/disk"
+	"github.com/ipfs/ipfs-cluster/informer/popularity"
 	"github.com/ipfs/ipfs-cluster/ipfsconn/ipfshttp"
 	"github.com/ipfs/ipfs-cluster/monitor/pubsubmon"
 	"github.com/ipfs/ipfs-cluster/observations"
@@ -192,20 +193,20 @@ func createComponents(
 
 	ipfs, err := ipfshttp.NewConnector(ipfshttpCfg)
 	checkErr(t, err)
-	tracker := makePinTracker(t, ident.ID, maptrackerCfg, statelesstrackerCfg, clusterCfg.Peername)
 
 	alloc := descendalloc.NewAllocator()
 	inf, err := disk.NewInformer(diskInfCfg)
 	checkErr(t, err)
 
 	store := makeStore(t, badgerCfg)
+	tracker := makePinTracker(t, ident.ID, maptrackerCfg, statelesstrackerCfg, clusterCfg.Peername, store)
 	cons := makeConsensus(t, store, host, pubsub, dht, raftCfg, staging, crdtCfg)
 
 	var peersF func(context.Context) ([]peer.ID, error)
 	if consensus == "raft" {
 		peersF = cons.Peers
 	}
-	mon, err := pubsubmon.New(ctx, psmonCfg, pubsub, peersF)
+	mon, err := pubsubmon.New(ctx, psmonCfg, pubsub, peersF, store)
 	checkErr(t, err)
 
 	tracingCfg.ServiceName = peername
@@ -241,13 +242,13 @@ func makeConsensus(t *testing.T, store ds.Datastore, h host.Host, psub *pubsub.P
 	}
 }
 
-func makePinTracker(t *testing.T, pid peer.ID, mptCfg *maptracker.Config, sptCfg *stateless.Config, peerName string) PinTracker {
+func makePinTracker(t *testing.T, pid peer.ID, mptCfg *maptracker.Config, sptCfg *stateless.Config, peerName string, store ds.Datastore) PinTracker {
 	var ptrkr PinTracker
 	switch ptracker {
 	case "map":
-		ptrkr = maptracker.NewMapPinTracker(mptCfg, pid, peerName)
+		ptrkr = maptracker.NewMapPinTracker(mptCfg, pid, peerName, store)
 	case "stateless":
-		ptrkr = stateless.New(sptCfg, pid, peerName)
+		ptrkr = stateless.New(sptCfg, pid, peerName, store)
 	default:
 		panic("bad pintracker")
 	}
@@ -255,7 +256,7 @@ func makePinTracker(t *testing.T, pid peer.ID, mptCfg *maptracker.Config, sptCfg
 }
 
 func createCluster(t *testing.T, host host.Host, dht *dht.IpfsDHT, clusterCfg *Config, store ds.Datastore, consensus Consensus, apis []API, ipfs IPFSConnector, tracker PinTracker, mon PeerMonitor, alloc PinAllocator, inf Informer, tracer Tracer) *Cluster {
-	cl, err := NewCluster(context.Background(), host, dht, clusterCfg, store, consensus, apis, ipfs, tracker, mon, alloc, inf, tracer)
+	cl, err := NewCluster(context.Background(), host, dht, clusterCfg, store, consensus, apis, ipfs, tracker, mon, alloc, []Informer{inf}, tracer)
 	checkErr(t, err)
 	return cl
 }
@@ -299,7 +300,9 @@ func createHost(t *testing.T, priv crypto.PrivKey, clusterSecret []byte, listen
 
 	// Pubsub needs to be created BEFORE connecting the peers,
 	// otherwise they are not picked up.
-	psub, err := newPubSub(ctx, h)
+	cfg := &Config{}
+	cfg.setDefaults()
+	psub, err := newPubSub(ctx, h, cfg)
 	checkErr(t, err)
 	return routedHost(h, d), psub, d
 }
@@ -333,12 +336,15 @@ func createClusters(t *testing.T) ([]*Cluster, []*test.IpfsMock) {
 
 	// Start first node
 	clusters[0] = createCluster(t, hosts[0], dhts[0], cfgs[0], stores[0], cons[0], apis[0], ipfss[0], trackers[0], mons[0], allocs[0], infs[0], tracers[0])
+	// all clusters trust themselves: none of them is a follower
+	clusters[0].consensus.Trust(ctx, hosts[0].ID())
 	<-clusters[0].Ready()
 	bootstrapAddr := clusterAddr(clusters[0])
 
 	// Start the rest and join
 	for i := 1; i < nClusters; i++ {
 		clusters[i] = createCluster(t, hosts[i], dhts[i], cfgs[i], stores[i], cons[i], apis[i], ipfss[i], trackers[i], mons[i], allocs[i], infs[i], tracers[i])
+		clusters[i].consensus.Trust(ctx, hosts[i].ID())
 		for j := 0; j < i; j++ {
 			// all previous clusters trust the new one
 			clusters[j].consensus.Trust(ctx, hosts[i].ID())
@@ -524,6 +530,85 @@ func TestClustersVersion(t *testing.T) {
 	runF(t, clusters, f)
 }
 
+func TestClustersVersions(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+
+	versions := clusters[0].Versions(ctx)
+	if len(versions) != nClusters {
+		t.Fatal("expected a version report for every peer")
+	}
+	for _, v := range versions {
+		if !v.Compatible || v.Error != "" {
+			t.Errorf("expected compatible peer: %+v", v)
+		}
+	}
+}
+
+func TestClustersRuntimeStats(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+
+	results, err := clusters[0].RuntimeStatsAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != nClusters {
+		t.Fatal("expected runtime stats for every peer")
+	}
+	for _, rs := range results {
+		if rs.Error != "" || rs.Goroutines == 0 {
+			t.Errorf("unexpected runtime stats: %+v", rs)
+		}
+	}
+}
+
+func TestClustersConnectionDeny(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+
+	if nClusters < 2 {
+		t.Skip("need at least 2 clusters")
+	}
+
+	delay()
+
+	denied := clusters[1].id
+	err := clusters[0].ConnectionDeny(ctx, &api.ConnectionFilter{Peers: []peer.ID{denied}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Existing connections are closed and new ones are closed
+	// as soon as they are established.
+	time.Sleep(time.Second)
+	for i := 0; i < 10; i++ {
+		if len(clusters[0].host.Network().ConnsToPeer(denied)) == 0 {
+			break
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	if len(clusters[0].host.Network().ConnsToPeer(denied)) != 0 {
+		t.Error("the denied peer should not be connected")
+	}
+
+	err = clusters[0].ConnectionUndeny(ctx, &api.ConnectionFilter{Peers: []peer.ID{denied}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = clusters[0].host.Connect(ctx, peerstore.PeerInfo{ID: denied, Addrs: clusters[1].host.Addrs()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if len(clusters[0].host.Network().ConnsToPeer(denied)) == 0 {
+		t.Error("the peer should be connected after removing it from the deny list")
+	}
+}
+
 func TestClustersPeers(t *testing.T) {
 	ctx := context.Background()
 	clusters, mock := createClusters(t)
@@ -565,6 +650,9 @@ func TestClustersPeers(t *testing.T) {
 		if id.IPFS.ID != id2.IPFS.ID {
 			t.Error("expected same ipfs daemon ID")
 		}
+		if id2.LastHeartbeat.IsZero() {
+			t.Error("expected a heartbeat from every peer")
+		}
 	}
 }
 
@@ -597,7 +685,7 @@ func TestClustersPin(t *testing.T) {
 		delay()
 	}
 	fpinned := func(t *testing.T, c *Cluster) {
-		status := c.tracker.StatusAll(ctx)
+		status := c.tracker.StatusAll(ctx, api.TrackerStatusUndefined)
 		for _, v := range status {
 			if v.Status != api.TrackerStatusPinned {
 				t.Errorf("%s should have been pinned but it is %s", v.Cid, v.Status)
@@ -638,7 +726,7 @@ func TestClustersPin(t *testing.T) {
 
 	delay()
 	funpinned := func(t *testing.T, c *Cluster) {
-		status := c.tracker.StatusAll(ctx)
+		status := c.tracker.StatusAll(ctx, api.TrackerStatusUndefined)
 		for _, v := range status {
 			t.Errorf("%s should have been unpinned but it is %s", v.Cid, v.Status)
 		}
@@ -646,6 +734,30 @@ func TestClustersPin(t *testing.T) {
 	runF(t, clusters, funpinned)
 }
 
+func TestClustersStatusSummary(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	clusters[0].Pin(ctx, api.PinCid(test.Cid1))
+	pinDelay()
+
+	summary, err := clusters[0].StatusSummary(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Peers) != nClusters {
+		t.Fatal("expected a summary for every peer")
+	}
+	for _, p := range summary.Peers {
+		if p.Error != "" || p.Counts.Pinned != 1 {
+			t.Errorf("unexpected peer summary: %+v", p)
+		}
+	}
+	if summary.Totals.Pinned != nClusters {
+		t.Errorf("expected %d pinned items in total: %+v", nClusters, summary.Totals)
+	}
+}
+
 func TestClustersStatusAll(t *testing.T) {
 	ctx := context.Background()
 	clusters, mock := createClusters(t)
@@ -655,7 +767,7 @@ func TestClustersStatusAll(t *testing.T) {
 	pinDelay()
 	// Global status
 	f := func(t *testing.T, c *Cluster) {
-		statuses, err := c.StatusAll(ctx)
+		statuses, err := c.StatusAll(ctx, api.TrackerStatusUndefined)
 		if err != nil {
 			t.Error(err)
 		}
@@ -693,6 +805,76 @@ func TestClustersStatusAll(t *testing.T) {
 	runF(t, clusters, f)
 }
 
+func TestClustersStatusAllConcurrency(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	h := test.Cid1
+	clusters[0].Pin(ctx, api.PinCid(h))
+	pinDelay()
+
+	// Peers are asked one by one.
+	clusters[0].config.StatusAllConcurrency = 1
+	statuses, err := clusters[0].StatusAll(ctx, api.TrackerStatusUndefined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 {
+		t.Fatal("bad status. Expected one item")
+	}
+	for pid, pinfo := range statuses[0].PeerMap {
+		if pinfo.Status != api.TrackerStatusPinned {
+			t.Errorf("%s: the hash should have been pinned: %s", pid, pinfo.Error)
+		}
+	}
+	if len(statuses[0].PeerMap) != nClusters {
+		t.Error("expected the status of all peers")
+	}
+}
+
+func TestClustersStatusAllFilter(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	h := test.Cid1
+	opts := api.PinOptions{
+		ReplicationFactorMin: 1,
+		ReplicationFactorMax: 1,
+	}
+	clusters[0].Pin(ctx, api.PinWithOpts(h, opts))
+	pinDelay()
+
+	f := func(t *testing.T, c *Cluster) {
+		statuses, err := c.StatusAll(ctx, api.TrackerStatusPinned)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(statuses) != 1 {
+			t.Fatal("bad status. Expected one item")
+		}
+		if len(statuses[0].PeerMap) != 1 {
+			t.Errorf("expected only the allocated peer in status: %v", statuses[0].PeerMap)
+		}
+
+		statuses, err = c.StatusAll(ctx, api.TrackerStatusRemote)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(statuses) != 1 {
+			t.Fatal("bad status. Expected one item")
+		}
+		for _, pinfo := range statuses[0].PeerMap {
+			if pinfo.Status != api.TrackerStatusRemote {
+				t.Errorf("expected only remote statuses: %v", pinfo)
+			}
+		}
+		if len(statuses[0].PeerMap) != nClusters-1 {
+			t.Errorf("expected %d remote statuses", nClusters-1)
+		}
+	}
+	runF(t, clusters, f)
+}
+
 func TestClustersStatusAllWithErrors(t *testing.T) {
 	ctx := context.Background()
 	clusters, mock := createClusters(t)
@@ -711,7 +893,7 @@ func TestClustersStatusAllWithErrors(t *testing.T) {
 			return
 		}
 
-		statuses, err := c.StatusAll(ctx)
+		statuses, err := c.StatusAll(ctx, api.TrackerStatusUndefined)
 		if err != nil {
 			t.Error(err)
 		}
@@ -968,6 +1150,197 @@ func TestClustersRecoverLocal(t *testing.T) {
 	runF(t, clusters, f)
 }
 
+func TestClustersStateSync(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+
+	clusters[0].Pin(ctx, api.PinCid(test.Cid1))
+	pinDelay()
+
+	results, err := clusters[0].StateSyncAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != nClusters {
+		t.Fatal("expected a result for every peer")
+	}
+	for _, r := range results {
+		if r.Error != "" || r.Start.IsZero() {
+			t.Errorf("unexpected state sync result: %+v", r)
+		}
+	}
+
+	target := clusters[nClusters-1]
+	result, err := clusters[0].StateSyncPeer(ctx, target.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Peer != target.id {
+		t.Error("expected result from the target peer")
+	}
+
+	last, err := clusters[0].LastStateSyncAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range last {
+		if r.Peer == target.id && !r.Start.Equal(result.Start) {
+			t.Error("expected last state sync to match the one triggered")
+		}
+	}
+}
+
+func TestClustersRemoveExpiredPins(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	for _, c := range clusters {
+		c.config.UnpinRetention = time.Millisecond
+	}
+
+	waitForLeaderAndMetrics(t, clusters)
+
+	// A single peer removes expired pins, also without a leader (crdt).
+	coordinators := 0
+	for _, c := range clusters {
+		if c.isCoordinator(ctx) {
+			coordinators++
+		}
+	}
+	if coordinators != 1 {
+		t.Fatalf("expected a single coordinator, got %d", coordinators)
+	}
+
+	h := test.Cid1
+	err := clusters[0].Pin(ctx, api.PinCid(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	err = clusters[0].Unpin(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+
+	for _, c := range clusters {
+		err := c.StateSync(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	pinDelay()
+
+	f := func(t *testing.T, c *Cluster) {
+		_, err := c.PinGet(ctx, h)
+		if err == nil {
+			t.Error("pin should have been removed after the retention period")
+		}
+	}
+	runF(t, clusters, f)
+}
+
+func TestClustersPing(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+
+	results, err := clusters[0].PingAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != nClusters {
+		t.Fatal("expected a result for every peer")
+	}
+	for _, r := range results {
+		if r.Error != "" || r.RTT <= 0 {
+			t.Errorf("unexpected ping result: %+v", r)
+		}
+		// All peers share the clock.
+		if r.ClockSkew > r.RTT || r.ClockSkew < -r.RTT {
+			t.Errorf("clock skew should be bounded by the rtt: %+v", r)
+		}
+	}
+
+	target := clusters[nClusters-1]
+	target.Shutdown(ctx)
+	result := clusters[0].PingPeer(ctx, target.id)
+	if result.Peer != target.id || result.Error == "" {
+		t.Errorf("expected an error pinging a down peer: %+v", result)
+	}
+}
+
+func TestClustersRecoverPeer(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	h := test.ErrorCid // This cid always fails
+	h2 := test.Cid2
+
+	ttlDelay()
+
+	clusters[0].Pin(ctx, api.PinCid(h))
+	clusters[0].Pin(ctx, api.PinCid(h2))
+	pinDelay()
+	pinDelay()
+
+	target := clusters[nClusters-1].id
+	f := func(t *testing.T, c *Cluster) {
+		pinfos, err := c.RecoverPeer(ctx, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pinfos) != 2 {
+			t.Fatalf("expected 2 items, got %d", len(pinfos))
+		}
+		for _, pinfo := range pinfos {
+			if pinfo.Peer != target {
+				t.Error("only the given peer should have recovered")
+			}
+			if pinfo.Cid.Equals(h2) && pinfo.Status != api.TrackerStatusPinned {
+				t.Errorf("element is %s and not Pinned", pinfo.Status)
+			}
+		}
+	}
+	runF(t, clusters, f)
+
+	// Wait for queue to be processed
+	delay()
+
+	info := clusters[nClusters-1].StatusLocal(ctx, h)
+	if info.Status != api.TrackerStatusPinError {
+		t.Errorf("element is %s and not PinError", info.Status)
+	}
+}
+
+func TestClustersRecoverPeerDown(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+
+	clusters[0].Pin(ctx, api.PinCid(test.Cid1))
+	clusters[0].Pin(ctx, api.PinCid(test.Cid2))
+	pinDelay()
+
+	target := clusters[nClusters-1]
+	target.Shutdown(ctx)
+
+	// Every item is reported, with the error.
+	pinfos, err := clusters[0].RecoverPeer(ctx, target.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pinfos) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(pinfos))
+	}
+	for _, pinfo := range pinfos {
+		if pinfo.Peer != target.id || pinfo.Status != api.TrackerStatusClusterError || pinfo.Error == "" {
+			t.Errorf("expected a cluster error for %s: %+v", pinfo.Cid, pinfo)
+		}
+	}
+}
+
 func TestClustersRecover(t *testing.T) {
 	ctx := context.Background()
 	clusters, mock := createClusters(t)
@@ -1038,6 +1411,15 @@ func TestClustersRecover(t *testing.T) {
 			t.Error("the GlobalPinInfo should show Pinned in all peers")
 		}
 	}
+
+	if len(ginfo.Results) != nClusters {
+		t.Fatal("expected a result for every peer")
+	}
+	for _, res := range ginfo.Results {
+		if res.Outcome != api.RPCSuccess {
+			t.Errorf("%s: unexpected outcome %s: %s", res.Peer, res.Outcome, res.Error)
+		}
+	}
 }
 
 func TestClustersShutdown(t *testing.T) {
@@ -1116,7 +1498,7 @@ func TestClustersReplicationOverall(t *testing.T) {
 
 	f := func(t *testing.T, c *Cluster) {
 		// confirm that the pintracker state matches the current global state
-		pinfos := c.tracker.StatusAll(ctx)
+		pinfos := c.tracker.StatusAll(ctx, api.TrackerStatusUndefined)
 		if len(pinfos) != nClusters {
 			t.Error("Pinfos does not have the expected pins")
 		}
@@ -1209,6 +1591,247 @@ func TestClustersReplicationFactorMax(t *testing.T) {
 	runF(t, clusters, f)
 }
 
+func TestClustersReplicationScaling(t *testing.T) {
+	ctx := context.Background()
+	if nClusters < 3 {
+		t.Skip("Need at least 3 peers")
+	}
+
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	for _, c := range clusters {
+		c.config.ReplicationFactorMin = 1
+		c.config.ReplicationFactorMax = 1
+		c.config.ReplicationScalingUpThreshold = 10
+		c.config.ReplicationScalingDownThreshold = 1
+		c.config.ReplicationScalingMax = 2
+	}
+
+	ttlDelay()
+
+	h := test.Cid1
+	err := clusters[0].Pin(ctx, api.PinCid(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+
+	publishPopularity := func(score float64) {
+		for _, c := range clusters {
+			m := &api.Metric{
+				Name:  popularity.MetricName,
+				Peer:  c.id,
+				Value: fmt.Sprintf(`{"%s": %f}`, h, score),
+				Valid: true,
+			}
+			m.SetTTL(time.Minute)
+			err := c.monitor.PublishMetric(ctx, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		delay()
+		for _, c := range clusters {
+			c.scaleReplication(ctx)
+		}
+		pinDelay()
+	}
+
+	publishPopularity(20)
+
+	runF(t, clusters, func(t *testing.T, c *Cluster) {
+		p, err := c.PinGet(ctx, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ReplicationFactorMin != 2 || p.ReplicationFactorMax != 2 {
+			t.Errorf("replication should have been raised to 2: %d %d", p.ReplicationFactorMin, p.ReplicationFactorMax)
+		}
+		if len(p.Allocations) != 2 {
+			t.Error("should have 2 allocations")
+		}
+		if p.Metadata[scalingOrigMaxMetaKey] != "1" {
+			t.Error("original replication factor should have been kept")
+		}
+	})
+
+	// The replication scaling maximum has been reached.
+	publishPopularity(20)
+
+	runF(t, clusters, func(t *testing.T, c *Cluster) {
+		p, err := c.PinGet(ctx, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ReplicationFactorMax != 2 {
+			t.Error("replication should not go over replication_scaling_max")
+		}
+	})
+
+	publishPopularity(0)
+
+	runF(t, clusters, func(t *testing.T, c *Cluster) {
+		p, err := c.PinGet(ctx, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ReplicationFactorMin != 1 || p.ReplicationFactorMax != 1 {
+			t.Errorf("replication should have been lowered back to 1: %d %d", p.ReplicationFactorMin, p.ReplicationFactorMax)
+		}
+		if len(p.Allocations) != 1 {
+			t.Error("should have 1 allocation")
+		}
+		if _, ok := p.Metadata[scalingOrigMaxMetaKey]; ok {
+			t.Error("scaling metadata should have been removed")
+		}
+	})
+}
+
+func TestClustersNotAllocatable(t *testing.T) {
+	ctx := context.Background()
+	if nClusters < 3 {
+		t.Skip("Need at least 3 peers")
+	}
+
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	for _, c := range clusters {
+		c.config.ReplicationFactorMin = 1
+		c.config.ReplicationFactorMax = nClusters
+	}
+
+	ttlDelay()
+
+	excluded := clusters[1].id
+	err := clusters[0].SetAllocatable(ctx, excluded, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clusters[1].ID(ctx).Allocatable {
+		t.Fatal("peer should not be allocatable")
+	}
+
+	delay()
+
+	h := test.Cid1
+	err = clusters[0].Pin(ctx, api.PinCid(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinDelay()
+
+	p, err := clusters[0].PinGet(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Allocations) != nClusters-1 {
+		t.Errorf("expected %d allocations, got %d", nClusters-1, len(p.Allocations))
+	}
+	if containsPeer(p.Allocations, excluded) {
+		t.Error("a peer which is not allocatable was allocated")
+	}
+}
+
+func TestClustersNotEnoughSpace(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	for _, c := range clusters {
+		c.config.ReplicationFactorMin = 1
+		c.config.ReplicationFactorMax = nClusters
+	}
+
+	ttlDelay()
+
+	// The ipfs mock has 10GB of storage.
+	pin := api.PinCid(test.Cid1)
+	pin.Size = 1 << 40
+	err := clusters[0].Pin(ctx, pin)
+	if err == nil {
+		t.Fatal("expected an error allocating a pin larger than the free space")
+	}
+
+	pin = api.PinCid(test.Cid1)
+	pin.Size = 1 << 20
+	err = clusters[0].Pin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+
+	p, err := clusters[0].PinGet(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Allocations) != nClusters {
+		t.Errorf("expected %d allocations, got %d", nClusters, len(p.Allocations))
+	}
+}
+
+func TestClustersAllocationExplanation(t *testing.T) {
+	ctx := context.Background()
+	if nClusters < 3 {
+		t.Skip("Need at least 3 peers")
+	}
+
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	for _, c := range clusters {
+		c.config.ReplicationFactorMin = 1
+		c.config.ReplicationFactorMax = nClusters - 2
+	}
+
+	ttlDelay()
+
+	h := test.Cid1
+	err := clusters[0].Pin(ctx, api.PinCid(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinDelay()
+
+	// Any peer should be able to find out how the pin was allocated.
+	f := func(t *testing.T, c *Cluster) {
+		expl, err := c.AllocationExplanation(ctx, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expl.Peer != clusters[0].id {
+			t.Error("allocation should have been decided by the first peer")
+		}
+
+		if expl.Allocator != clusters[0].allocator.Name() {
+			t.Error("unexpected allocator name:", expl.Allocator)
+		}
+
+		if len(expl.Metrics) != nClusters {
+			t.Errorf("expected %d metrics, got %d", nClusters, len(expl.Metrics))
+		}
+
+		if len(expl.Allocations) != nClusters-2 {
+			t.Error("should have nClusters - 2 allocations")
+		}
+
+		if len(expl.Rejected) != 2 {
+			t.Error("should have rejected 2 peers")
+		}
+	}
+	runF(t, clusters, f)
+
+	err = clusters[0].Unpin(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = clusters[0].AllocationExplanationLocal(ctx, h)
+	if err == nil {
+		t.Error("allocation explanation should be gone after unpinning")
+	}
+}
+
 // This tests checks that repinning something that is overpinned
 // removes some allocations
 func TestClustersReplicationFactorMaxLower(t *testing.T) {
@@ -1590,6 +2213,133 @@ func TestClustersReplicationRealloc(t *testing.T) {
 	}
 }
 
+func TestClustersReallocationVerification(t *testing.T) {
+	ctx := context.Background()
+	if nClusters < 2 {
+		t.Skip("Need at least 2 peers")
+	}
+
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	for _, c := range clusters {
+		c.config.ReplicationFactorMin = 1
+		c.config.ReplicationFactorMax = 1
+		c.config.ReallocationVerification = ReallocationVerifyMin
+	}
+	pollInterval := reallocationPollInterval
+	reallocationPollInterval = 100 * time.Millisecond
+	defer func() { reallocationPollInterval = pollInterval }()
+
+	ttlDelay()
+
+	h := test.Cid1
+	err := clusters[0].Pin(ctx, api.PinCid(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+
+	pin, err := clusters[0].PinGet(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := pin.Allocations[0]
+
+	mig, err := clusters[0].PeerMigrate(ctx, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mig.Pins) != 1 || len(mig.Errors) != 0 {
+		t.Fatalf("expected 1 migrated pin: %+v", mig)
+	}
+	to := mig.Pins[0].Allocations
+	if len(to) != 1 || to[0] == from {
+		t.Fatalf("unexpected new allocations: %s", to)
+	}
+
+	// The old peer is unallocated once the new one has pinned.
+	pinDelay()
+	pin, err = clusters[0].PinGet(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pin.Allocations) != 1 || pin.Allocations[0] != to[0] {
+		t.Errorf("expected %s to be the only allocation: %s", to[0], pin.Allocations)
+	}
+	for _, c := range clusters {
+		if c.id != to[0] {
+			continue
+		}
+		if st := c.tracker.Status(ctx, h).Status; st != api.TrackerStatusPinned {
+			t.Errorf("expected the new allocation to have pinned, got %s", st)
+		}
+	}
+}
+
+func TestClustersRepairShards(t *testing.T) {
+	ctx := context.Background()
+	if nClusters < 2 {
+		t.Skip("Need at least 2 peers")
+	}
+
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	for _, c := range clusters {
+		c.config.ReplicationFactorMin = 1
+		c.config.ReplicationFactorMax = 1
+	}
+
+	ttlDelay()
+
+	lost := clusters[1].id
+	ref := test.Cid2
+	shard := api.PinWithOpts(test.Cid1, api.PinOptions{
+		ReplicationFactorMin: 1,
+		ReplicationFactorMax: 1,
+		Name:                 "testshard-shard-0",
+	})
+	shard.Type = api.ShardType
+	shard.MaxDepth = 1
+	shard.Reference = &ref
+	_, _, err := clusters[0].pin(ctx, shard, []peer.ID{}, []peer.ID{lost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+
+	pins, err := clusters[0].Pins(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := clusters[0].repairShards(ctx, lost, pins)
+	if len(rep.Pins) != 1 || len(rep.Errors) != 0 {
+		t.Fatalf("expected 1 repaired shard: %+v", rep)
+	}
+
+	pinDelay()
+	pin, err := clusters[0].PinGet(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pin.Allocations) != 1 || pin.Allocations[0] == lost {
+		t.Fatalf("expected the shard to be re-allocated: %s", pin.Allocations)
+	}
+	for _, c := range clusters {
+		if c.id != pin.Allocations[0] {
+			continue
+		}
+		if st := c.tracker.Status(ctx, test.Cid1).Status; st != api.TrackerStatusPinned {
+			t.Errorf("expected the new allocation to have pinned, got %s", st)
+		}
+	}
+
+	// Other pins are left to the regular repinning.
+	rep = clusters[0].repairShards(ctx, pin.Allocations[0], []*api.Pin{api.PinCid(test.Cid3)})
+	if len(rep.Pins) != 0 {
+		t.Error("only shard pins should be repaired")
+	}
+}
+
 // In this test we try to pin something when there are not
 // as many available peers a we need. It's like before, except
 // more peers are killed.
@@ -1763,6 +2513,25 @@ func validateClusterGraph(t *testing.T, graph api.ConnectGraph, clusterIDs map[s
 	if len(graph.ClustertoIPFS) > len(clusterIDs) {
 		t.Error("More cluster to ipfs links recorded in graph than expected")
 	}
+
+	// Check that latencies are recorded only among connected peers
+	for id1, lats := range graph.ClusterLatencies {
+		if _, ok := clusterIDs[id1]; !ok {
+			t.Errorf("disconnected peer %s has recorded latencies", id1)
+			continue
+		}
+		for id2, lat := range lats {
+			if _, ok := clusterIDs[id2]; !ok || id1 == id2 {
+				t.Errorf("unexpected latency from %s to %s", id1, id2)
+			}
+			if lat <= 0 {
+				t.Errorf("expected a positive latency from %s to %s", id1, id2)
+			}
+		}
+	}
+	if len(graph.IPFSErrors) != 0 {
+		t.Error("no ipfs errors expected:", graph.IPFSErrors)
+	}
 }
 
 // In this test we get a cluster graph report from a random peer in a healthy
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -23,7 +24,9 @@ const (
 	DefaultPinMethod          = "refs"
 	DefaultIPFSRequestTimeout = 5 * time.Minute
 	DefaultPinTimeout         = 24 * time.Hour
+	DefaultPinStallTimeout    = 10 * time.Minute
 	DefaultUnpinTimeout       = 3 * time.Hour
+	DefaultRepoGCTimeout      = time.Hour
 )
 
 // Config is used to initialize a Connector and allows to customize
@@ -50,9 +53,32 @@ type Config struct {
 	// Pin Operation timeout
 	PinTimeout time.Duration
 
+	// PinStallTimeout cancels pin operations when the IPFS daemon has
+	// not received any blocks for this long, as reported by the bitswap
+	// statistics. Pins which keep making progress are only limited by
+	// the PinTimeout. 0 disables it.
+	PinStallTimeout time.Duration
+
 	// Unpin Operation timeout
 	UnpinTimeout time.Duration
 
+	// RepoGCTimeout limits how long a garbage collection run of the
+	// IPFS repository may take.
+	RepoGCTimeout time.Duration
+
+	// GatewayURL is the public URL of the HTTP gateway of the IPFS
+	// daemon, if any (i.e. "https://gw1.example.com"). It is shared
+	// with the other peers so that clients can be directed to the
+	// gateways of the peers holding some content.
+	GatewayURL string
+
+	// ReproviderStrategy, when set, is applied to the IPFS daemon
+	// configuration ("Reprovider.Strategy") on startup: "all" announces
+	// every block, "pinned" only pinned content and "roots" only the
+	// roots of pins. The IPFS daemon must be restarted for a changed
+	// strategy to be used.
+	ReproviderStrategy string
+
 	// Tracing flag used to skip tracing specific paths when not enabled.
 	Tracing bool
 }
@@ -63,7 +89,11 @@ type jsonConfig struct {
 	PinMethod          string `json:"pin_method"`
 	IPFSRequestTimeout string `json:"ipfs_request_timeout"`
 	PinTimeout         string `json:"pin_timeout"`
+	PinStallTimeout    string `json:"pin_stall_timeout"`
 	UnpinTimeout       string `json:"unpin_timeout"`
+	RepoGCTimeout      string `json:"repo_gc_timeout,omitempty"`
+	GatewayURL         string `json:"gateway_url,omitempty"`
+	ReproviderStrategy string `json:"reprovider_strategy,omitempty"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -79,7 +109,11 @@ func (cfg *Config) Default() error {
 	cfg.PinMethod = DefaultPinMethod
 	cfg.IPFSRequestTimeout = DefaultIPFSRequestTimeout
 	cfg.PinTimeout = DefaultPinTimeout
+	cfg.PinStallTimeout = DefaultPinStallTimeout
 	cfg.UnpinTimeout = DefaultUnpinTimeout
+	cfg.RepoGCTimeout = DefaultRepoGCTimeout
+	cfg.GatewayURL = ""
+	cfg.ReproviderStrategy = ""
 
 	return nil
 }
@@ -126,9 +160,30 @@ func (cfg *Config) Validate() error {
 		err = errors.New("ipfshttp.pin_timeout invalid")
 	}
 
+	if cfg.PinStallTimeout < 0 {
+		err = errors.New("ipfshttp.pin_stall_timeout invalid")
+	}
+
 	if cfg.UnpinTimeout < 0 {
 		err = errors.New("ipfshttp.unpin_timeout invalid")
 	}
+
+	if cfg.RepoGCTimeout < 0 {
+		err = errors.New("ipfshttp.repo_gc_timeout invalid")
+	}
+
+	if cfg.GatewayURL != "" {
+		u, uerr := url.Parse(cfg.GatewayURL)
+		if uerr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			err = errors.New("ipfshttp.gateway_url should be an http(s) URL")
+		}
+	}
+
+	switch cfg.ReproviderStrategy {
+	case "", "all", "pinned", "roots":
+	default:
+		err = errors.New("ipfshttp.reprovider_strategy should be all, pinned or roots")
+	}
 	return err
 
 }
@@ -160,13 +215,17 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 		&config.DurationOpt{Duration: jcfg.ConnectSwarmsDelay, Dst: &cfg.ConnectSwarmsDelay, Name: "connect_swarms_delay"},
 		&config.DurationOpt{Duration: jcfg.IPFSRequestTimeout, Dst: &cfg.IPFSRequestTimeout, Name: "ipfs_request_timeout"},
 		&config.DurationOpt{Duration: jcfg.PinTimeout, Dst: &cfg.PinTimeout, Name: "pin_timeout"},
+		&config.DurationOpt{Duration: jcfg.PinStallTimeout, Dst: &cfg.PinStallTimeout, Name: "pin_stall_timeout"},
 		&config.DurationOpt{Duration: jcfg.UnpinTimeout, Dst: &cfg.UnpinTimeout, Name: "unpin_timeout"},
+		&config.DurationOpt{Duration: jcfg.RepoGCTimeout, Dst: &cfg.RepoGCTimeout, Name: "repo_gc_timeout"},
 	)
 	if err != nil {
 		return err
 	}
 
 	config.SetIfNotDefault(jcfg.PinMethod, &cfg.PinMethod)
+	config.SetIfNotDefault(jcfg.GatewayURL, &cfg.GatewayURL)
+	config.SetIfNotDefault(jcfg.ReproviderStrategy, &cfg.ReproviderStrategy)
 
 	return cfg.Validate()
 }
@@ -198,7 +257,11 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 	jcfg.PinMethod = cfg.PinMethod
 	jcfg.IPFSRequestTimeout = cfg.IPFSRequestTimeout.String()
 	jcfg.PinTimeout = cfg.PinTimeout.String()
+	jcfg.PinStallTimeout = cfg.PinStallTimeout.String()
 	jcfg.UnpinTimeout = cfg.UnpinTimeout.String()
+	jcfg.RepoGCTimeout = cfg.RepoGCTimeout.String()
+	jcfg.GatewayURL = cfg.GatewayURL
+	jcfg.ReproviderStrategy = cfg.ReproviderStrategy
 
 	return
 }
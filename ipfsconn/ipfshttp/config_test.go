@@ -14,6 +14,7 @@ var cfgJSON = []byte(`
       "pin_method": "pin",
       "ipfs_request_timeout": "5m0s",
       "pin_timeout": "24h",
+      "pin_stall_timeout": "10m",
       "unpin_timeout": "3h"
 }
 `)
@@ -33,6 +34,63 @@ func TestLoadJSON(t *testing.T) {
 	if err == nil {
 		t.Error("expected error in node_multiaddress")
 	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.GatewayURL = "https://gw.example.com"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GatewayURL != "https://gw.example.com" {
+		t.Error("expected gateway_url to be set")
+	}
+
+	j.GatewayURL = "gw.example.com"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in gateway_url")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.ReproviderStrategy = "roots"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ReproviderStrategy != "roots" {
+		t.Error("expected reprovider_strategy to be set")
+	}
+
+	j.ReproviderStrategy = "everything"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in reprovider_strategy")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.PinStallTimeout = ""
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PinStallTimeout != DefaultPinStallTimeout {
+		t.Error("expected default pin_stall_timeout")
+	}
+
+	j.PinStallTimeout = "-1s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in pin_stall_timeout")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -3,6 +3,7 @@
 package ipfshttp
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -23,6 +24,7 @@ import (
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
 	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
 
 	cid "github.com/ipfs/go-cid"
@@ -44,6 +46,10 @@ var logger = logging.Logger("ipfshttp")
 // only the 10th will trigger a SendInformerMetrics call.
 var updateMetricMod = 10
 
+// pinProgressInterval is how often the data received by IPFS is shared
+// between the ongoing pins (see PinProgress).
+var pinProgressInterval = 2 * time.Second
+
 // Connector implements the IPFSConnector interface
 // and provides a component which  is used to perform
 // on-demand requests against the configured IPFS daemom
@@ -63,6 +69,18 @@ type Connector struct {
 	updateMetricMutex sync.Mutex
 	updateMetricCount int
 
+	// last bitswap stats, used to calculate the data received rate
+	bitswapMu     sync.Mutex
+	lastBitswap   *api.IPFSBitswapStat
+	lastBitswapTS time.Time
+
+	// progress of the ongoing pins, by Cid, and the data received by
+	// IPFS when it was last measured.
+	progressMu       sync.Mutex
+	progress         map[string]*pinProgress
+	lastDataReceived uint64
+	measured         bool
+
 	shutdownLock sync.Mutex
 	shutdown     bool
 	wg           sync.WaitGroup
@@ -85,10 +103,40 @@ type ipfsIDResp struct {
 	Addresses []string
 }
 
+type ipfsVersionResp struct {
+	Version string
+}
+
 type ipfsResolveResp struct {
 	Path string
 }
 
+type ipfsObjectStatResp struct {
+	Hash           string
+	NumLinks       int
+	CumulativeSize uint64
+}
+
+type ipfsRefsResp struct {
+	Ref string
+	Err string
+}
+
+type ipfsPinAddResp struct {
+	Pins     []string
+	Progress uint64
+}
+
+type ipfsRepoGCResp struct {
+	Key   map[string]string
+	Error string
+}
+
+type ipfsBlockStatResp struct {
+	Key  string
+	Size uint64
+}
+
 type ipfsSwarmPeersResp struct {
 	Peers []ipfsPeer
 }
@@ -101,6 +149,13 @@ type ipfsStream struct {
 	Protocol string
 }
 
+type ipfsBitswapStatResp struct {
+	Wantlist       []json.RawMessage
+	Peers          []string
+	BlocksReceived uint64
+	DataReceived   uint64
+}
+
 // NewConnector creates the component and leaves it ready to be started
 func NewConnector(cfg *Config) (*Connector, error) {
 	err := cfg.Validate()
@@ -146,6 +201,7 @@ func NewConnector(cfg *Config) (*Connector, error) {
 		nodeAddr: nodeAddr,
 		rpcReady: make(chan struct{}, 1),
 		client:   c,
+		progress: make(map[string]*pinProgress),
 	}
 
 	go ipfs.run()
@@ -177,10 +233,41 @@ func (ipfs *Connector) run() {
 			// do not hang this goroutine if this call hangs
 			// otherwise we hang during shutdown
 			go ipfs.ConnectSwarms(ipfs.ctx)
+			err := ipfs.setReproviderStrategy(ipfs.ctx)
+			if err != nil {
+				logger.Errorf("error setting the IPFS reprovider strategy: %s", err)
+			}
 		case <-ipfs.ctx.Done():
 			return
 		}
 	}()
+
+	ipfs.wg.Add(1)
+	go ipfs.measurePinProgress()
+}
+
+// setReproviderStrategy sets the configured reprovider strategy in the
+// IPFS daemon configuration, unless it is already used.
+func (ipfs *Connector) setReproviderStrategy(ctx context.Context) error {
+	strategy := ipfs.config.ReproviderStrategy
+	if strategy == "" {
+		return nil
+	}
+
+	current, err := ipfs.ConfigKey("Reprovider/Strategy")
+	if err == nil && current == strategy {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+	path := fmt.Sprintf("config?arg=Reprovider.Strategy&arg=%s", url.QueryEscape(strategy))
+	err = ipfs.postDiscardBodyCtx(ctx, path)
+	if err != nil {
+		return err
+	}
+	logger.Warningf("IPFS reprovider strategy set to %q. The IPFS daemon must be restarted to use it", strategy)
+	return nil
 }
 
 // SetClient makes the component ready to perform RPC
@@ -243,7 +330,8 @@ func (ipfs *Connector) ID(ctx context.Context) (*api.IPFSID, error) {
 	}
 
 	id := &api.IPFSID{
-		ID: pID,
+		ID:         pID,
+		GatewayURL: ipfs.config.GatewayURL,
 	}
 
 	mAddrs := make([]api.Multiaddr, len(res.Addresses), len(res.Addresses))
@@ -279,6 +367,11 @@ func (ipfs *Connector) Pin(ctx context.Context, hash cid.Cid, maxDepth int) erro
 
 	defer ipfs.updateInformerMetric(ctx)
 
+	start := time.Now()
+	stalled := ipfs.watchPinProgress(ctx, cancel, hash)
+	prog := ipfs.startPinProgress(hash)
+	defer ipfs.donePinProgress(prog)
+
 	var pinArgs string
 	switch {
 	case maxDepth < 0:
@@ -292,20 +385,253 @@ func (ipfs *Connector) Pin(ctx context.Context, hash cid.Cid, maxDepth int) erro
 	switch ipfs.config.PinMethod {
 	case "refs": // do refs -r first
 		path := fmt.Sprintf("refs?arg=%s&%s", hash, pinArgs)
-		err := ipfs.postDiscardBodyCtx(ctx, path)
+		err := ipfs.fetchRefs(ctx, path, prog)
 		if err != nil {
-			return err
+			return ipfs.pinStalledErr(err, stalled)
 		}
 		logger.Debugf("Refs for %s sucessfully fetched", hash)
 		stats.Record(ctx, observations.Pins.M(1))
 	}
 
-	path := fmt.Sprintf("pin/add?arg=%s&%s", hash, pinArgs)
-	_, err = ipfs.postCtx(ctx, path, "", nil)
-	if err == nil {
-		logger.Info("IPFS Pin request succeeded: ", hash)
+	path := fmt.Sprintf("pin/add?arg=%s&%s&progress=true", hash, pinArgs)
+	err = ipfs.pinAdd(ctx, path, prog)
+	if err != nil {
+		return ipfs.pinStalledErr(err, stalled)
 	}
-	return err
+	logger.Info("IPFS Pin request succeeded: ", hash)
+	ipfs.recordPinLatency(ctx, hash, time.Since(start))
+	return nil
+}
+
+// watchPinProgress cancels a pin operation, using the given cancel
+// function, when the IPFS daemon does not receive any blocks during the
+// PinStallTimeout. The returned channel is closed when that happens. The
+// watchdog stops once the pin context is done.
+func (ipfs *Connector) watchPinProgress(ctx context.Context, cancel context.CancelFunc, hash cid.Cid) <-chan struct{} {
+	stallTimeout := ipfs.config.PinStallTimeout
+	if stallTimeout <= 0 {
+		return nil
+	}
+
+	stalled := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(stallTimeout / 10)
+		defer ticker.Stop()
+
+		var blocksReceived uint64
+		lastProgress := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				stat, err := ipfs.BitswapStat(ctx)
+				if err != nil {
+					// do not cancel the pin because the
+					// daemon failed to report.
+					continue
+				}
+				if stat.BlocksReceived != blocksReceived {
+					blocksReceived = stat.BlocksReceived
+					lastProgress = time.Now()
+					continue
+				}
+				if time.Since(lastProgress) >= stallTimeout {
+					logger.Warningf("cancelling pin of %s: no blocks received in %s", hash, stallTimeout)
+					close(stalled)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return stalled
+}
+
+// pinStalledErr returns a descriptive error instead of the given one when
+// the pin was cancelled for not making progress.
+func (ipfs *Connector) pinStalledErr(err error, stalled <-chan struct{}) error {
+	select {
+	case <-stalled:
+		return fmt.Errorf("pin stalled: no blocks received in %s", ipfs.config.PinStallTimeout)
+	default:
+		return err
+	}
+}
+
+// fetchRefs makes a refs request, which fetches the blocks of a DAG, and
+// counts the blocks in the progress of the pin.
+func (ipfs *Connector) fetchRefs(ctx context.Context, path string, prog *pinProgress) error {
+	res, err := ipfs.doPostCtx(ctx, ipfs.client, ipfs.apiURL(), path, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return checkResponse(path, res.StatusCode, body)
+	}
+
+	var blocks uint64
+	dec := json.NewDecoder(res.Body)
+	for {
+		var ref ipfsRefsResp
+		err := dec.Decode(&ref)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		blocks++
+		ipfs.setPinBlocks(prog, blocks)
+	}
+}
+
+// pinAdd makes a pin/add request with progress and records the blocks
+// fetched in the progress of the pin. Errors happening once the response
+// has started are reported in the X-Stream-Error trailer.
+func (ipfs *Connector) pinAdd(ctx context.Context, path string, prog *pinProgress) error {
+	res, err := ipfs.doPostCtx(ctx, ipfs.client, ipfs.apiURL(), path, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		return checkResponse(path, res.StatusCode, body)
+	}
+
+	pinned := false
+	dec := json.NewDecoder(res.Body)
+	for {
+		var resp ipfsPinAddResp
+		err := dec.Decode(&resp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(resp.Pins) > 0 {
+			pinned = true
+		}
+		ipfs.setPinBlocks(prog, resp.Progress)
+	}
+
+	if msg := res.Trailer.Get("X-Stream-Error"); msg != "" {
+		return fmt.Errorf("IPFS unsuccessful: %s", msg)
+	}
+	if !pinned {
+		return fmt.Errorf("IPFS-post '%s' did not complete", path)
+	}
+	return nil
+}
+
+// recordPinLatency records the time taken to pin a DAG, tagged with the
+// bucket corresponding to the cumulative size of the DAG.
+func (ipfs *Connector) recordPinLatency(ctx context.Context, hash cid.Cid, latency time.Duration) {
+	sizeBucket := observations.DAGSizeUnknown
+	size, err := ipfs.DAGSize(ctx, hash)
+	if err != nil {
+		logger.Debugf("error obtaining the DAG size of %s: %s", hash, err)
+	} else {
+		sizeBucket = observations.DAGSizeBucket(size)
+	}
+
+	ms := float64(latency) / float64(time.Millisecond)
+	err = stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{tag.Upsert(observations.DAGSizeKey, sizeBucket)},
+		observations.PinLatency.M(ms),
+	)
+	if err != nil {
+		logger.Debug(err)
+	}
+}
+
+// DAGSize returns the cumulative size of the DAG under the given
+// CID, as reported by object/stat.
+func (ipfs *Connector) DAGSize(ctx context.Context, hash cid.Cid) (uint64, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/DAGSize")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+	res, err := ipfs.postCtx(ctx, "object/stat?arg="+hash.String(), "", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var stat ipfsObjectStatResp
+	err = json.Unmarshal(res, &stat)
+	if err != nil {
+		return 0, err
+	}
+	return stat.CumulativeSize, nil
+}
+
+// DAGBlocks returns the blocks of the DAG under the given CID, down to the
+// given depth (-1 for the whole DAG), along with their sizes. Every block
+// is listed once.
+func (ipfs *Connector) DAGBlocks(ctx context.Context, root cid.Cid, maxDepth int) ([]*api.IPFSBlockStat, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/DAGBlocks")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+
+	cids := []cid.Cid{root}
+	if maxDepth != 0 {
+		q := url.Values{}
+		q.Set("arg", root.String())
+		q.Set("recursive", "true")
+		q.Set("unique", "true")
+		if maxDepth > 0 {
+			q.Set("max-depth", fmt.Sprintf("%d", maxDepth))
+		}
+		res, err := ipfs.postCtx(ctx, "refs?"+q.Encode(), "", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(res))
+		for {
+			var ref ipfsRefsResp
+			err := dec.Decode(&ref)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if ref.Err != "" {
+				return nil, errors.New(ref.Err)
+			}
+			c, err := cid.Decode(ref.Ref)
+			if err != nil {
+				return nil, err
+			}
+			if !c.Equals(root) {
+				cids = append(cids, c)
+			}
+		}
+	}
+
+	blocks := make([]*api.IPFSBlockStat, 0, len(cids))
+	for _, c := range cids {
+		res, err := ipfs.postCtx(ctx, "block/stat?arg="+c.String(), "", nil)
+		if err != nil {
+			return nil, err
+		}
+		var stat ipfsBlockStatResp
+		err = json.Unmarshal(res, &stat)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &api.IPFSBlockStat{Cid: c, Size: stat.Size})
+	}
+	return blocks, nil
 }
 
 // Unpin performs an unpin request against the configured IPFS
@@ -338,17 +664,30 @@ func (ipfs *Connector) Unpin(ctx context.Context, hash cid.Cid) error {
 
 // PinLs performs a "pin ls --type typeFilter" request against the configured
 // IPFS daemon and returns a map of cid strings and their status.
+// typeFilter may be a comma-separated list of types (i.e. "recursive,direct"),
+// in which case a request is made for each of them.
 func (ipfs *Connector) PinLs(ctx context.Context, typeFilter string) (map[string]api.IPFSPinStatus, error) {
 	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/PinLs")
 	defer span.End()
 
+	statusMap := make(map[string]api.IPFSPinStatus)
+	for _, t := range strings.Split(typeFilter, ",") {
+		err := ipfs.pinLsType(ctx, t, statusMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return statusMap, nil
+}
+
+func (ipfs *Connector) pinLsType(ctx context.Context, typeFilter string, statusMap map[string]api.IPFSPinStatus) error {
 	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
 	defer cancel()
 	body, err := ipfs.postCtx(ctx, "pin/ls?type="+typeFilter, "", nil)
 
 	// Some error talking to the daemon
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	var res ipfsPinLsResp
@@ -356,14 +695,13 @@ func (ipfs *Connector) PinLs(ctx context.Context, typeFilter string) (map[string
 	if err != nil {
 		logger.Error("parsing pin/ls response")
 		logger.Error(string(body))
-		return nil, err
+		return err
 	}
 
-	statusMap := make(map[string]api.IPFSPinStatus)
 	for k, v := range res.Keys {
 		statusMap[k] = api.IPFSPinStatusFromString(v.Type)
 	}
-	return statusMap, nil
+	return nil
 }
 
 // PinLsCid performs a "pin ls <hash>" request. It first tries with
@@ -535,6 +873,17 @@ func (ipfs *Connector) ConnectSwarms(ctx context.Context) error {
 	return nil
 }
 
+// Provide announces to the network that the IPFS daemon provides the given
+// CID. Only the CID itself is announced, not its children.
+func (ipfs *Connector) Provide(ctx context.Context, hash cid.Cid) error {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/Provide")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+	return ipfs.postDiscardBodyCtx(ctx, fmt.Sprintf("dht/provide?arg=%s", hash))
+}
+
 // ConfigKey fetches the IPFS daemon configuration and retrieves the value for
 // a given configuration key. For example, "Datastore/StorageMax" will return
 // the value for StorageMax in the Datastore configuration object.
@@ -581,6 +930,49 @@ func getConfigValue(path []string, cfg map[string]interface{}) (interface{}, err
 	}
 }
 
+// DaemonInfo returns the version of the IPFS daemon and the configuration
+// values which should be the same in every peer of the cluster. Values
+// which are not set in the IPFS configuration are left empty.
+func (ipfs *Connector) DaemonInfo(ctx context.Context) (*api.IPFSDaemonInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/DaemonInfo")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+	res, err := ipfs.postCtx(ctx, "version", "", nil)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	var version ipfsVersionResp
+	err = json.Unmarshal(res, &version)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	res, err = ipfs.postCtx(ctx, "config/show", "", nil)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	var cfg map[string]interface{}
+	err = json.Unmarshal(res, &cfg)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	info := &api.IPFSDaemonInfo{Version: version.Version}
+	if v, err := getConfigValue([]string{"Routing", "Type"}, cfg); err == nil {
+		info.Routing, _ = v.(string)
+	}
+	if v, err := getConfigValue([]string{"Datastore", "StorageMax"}, cfg); err == nil {
+		info.StorageMax, _ = v.(string)
+	}
+	return info, nil
+}
+
 // RepoStat returns the DiskUsage and StorageMax repo/stat values from the
 // ipfs daemon, in bytes, wrapped as an IPFSRepoStat object.
 func (ipfs *Connector) RepoStat(ctx context.Context) (*api.IPFSRepoStat, error) {
@@ -604,6 +996,43 @@ func (ipfs *Connector) RepoStat(ctx context.Context) (*api.IPFSRepoStat, error)
 	return &stats, nil
 }
 
+// RepoGC runs the garbage collector of the IPFS daemon and returns the
+// number of blocks removed. Blocks which could not be removed are reported
+// in the Error field of the result.
+func (ipfs *Connector) RepoGC(ctx context.Context) (*api.RepoGC, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/RepoGC")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.RepoGCTimeout)
+	defer cancel()
+	res, err := ipfs.postCtx(ctx, "repo/gc?stream-errors=true", "", nil)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	result := &api.RepoGC{}
+	var errs []string
+	dec := json.NewDecoder(bytes.NewReader(res))
+	for {
+		var gcResp ipfsRepoGCResp
+		err := dec.Decode(&gcResp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if gcResp.Error != "" {
+			errs = append(errs, gcResp.Error)
+			continue
+		}
+		result.Removed++
+	}
+	result.Error = strings.Join(errs, "; ")
+	return result, nil
+}
+
 // Resolve accepts ipfs or ipns path and resolves it into a cid
 func (ipfs *Connector) Resolve(ctx context.Context, path string) (cid.Cid, error) {
 	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/Resolve")
@@ -709,6 +1138,176 @@ func (ipfs *Connector) BlockGet(ctx context.Context, c cid.Cid) ([]byte, error)
 	return ipfs.postCtx(ctx, url, "", nil)
 }
 
+// BitswapStat returns the bitswap statistics of the IPFS daemon. The
+// data received rate is calculated from the statistics obtained in the
+// previous call, and is 0 for the first one.
+func (ipfs *Connector) BitswapStat(ctx context.Context) (*api.IPFSBitswapStat, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/BitswapStat")
+	defer span.End()
+
+	resp, err := ipfs.bitswapStat(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	stat := &api.IPFSBitswapStat{
+		WantlistSize:   len(resp.Wantlist),
+		Peers:          len(resp.Peers),
+		BlocksReceived: resp.BlocksReceived,
+		DataReceived:   resp.DataReceived,
+	}
+
+	now := time.Now()
+	ipfs.bitswapMu.Lock()
+	defer ipfs.bitswapMu.Unlock()
+	last := ipfs.lastBitswap
+	if last != nil && stat.DataReceived >= last.DataReceived {
+		elapsed := now.Sub(ipfs.lastBitswapTS).Seconds()
+		if elapsed > 0 {
+			stat.DataReceivedRate = float64(stat.DataReceived-last.DataReceived) / elapsed
+		}
+	}
+	ipfs.lastBitswap = stat
+	ipfs.lastBitswapTS = now
+	return stat, nil
+}
+
+func (ipfs *Connector) bitswapStat(ctx context.Context) (*ipfsBitswapStatResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+	res, err := ipfs.postCtx(ctx, "bitswap/stat", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ipfsBitswapStatResp
+	err = json.Unmarshal(res, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// pinProgress tracks an ongoing pin. IPFS reports the blocks fetched by
+// each pin, but only the data received by all of them, so it is shared
+// between the pins in proportion to the blocks they fetched since it was
+// last measured.
+type pinProgress struct {
+	cid        cid.Cid
+	blocks     uint64
+	lastBlocks uint64 // blocks when the data received was last measured
+	bytes      uint64
+}
+
+func (ipfs *Connector) startPinProgress(hash cid.Cid) *pinProgress {
+	ipfs.progressMu.Lock()
+	defer ipfs.progressMu.Unlock()
+	prog := &pinProgress{cid: hash}
+	ipfs.progress[hash.String()] = prog
+	return prog
+}
+
+func (ipfs *Connector) donePinProgress(prog *pinProgress) {
+	ipfs.progressMu.Lock()
+	defer ipfs.progressMu.Unlock()
+	key := prog.cid.String()
+	if ipfs.progress[key] == prog {
+		delete(ipfs.progress, key)
+	}
+}
+
+// setPinBlocks records the blocks fetched by a pin. They never go back, as
+// pin/add counts again the blocks fetched by refs.
+func (ipfs *Connector) setPinBlocks(prog *pinProgress, blocks uint64) {
+	ipfs.progressMu.Lock()
+	defer ipfs.progressMu.Unlock()
+	if blocks > prog.blocks {
+		prog.blocks = blocks
+	}
+}
+
+// measurePinProgress shares the data received by IPFS between the ongoing
+// pins every pinProgressInterval, until the connector is shut down.
+func (ipfs *Connector) measurePinProgress() {
+	defer ipfs.wg.Done()
+	ticker := time.NewTicker(pinProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ipfs.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ipfs.progressMu.Lock()
+		pinning := len(ipfs.progress) > 0
+		if !pinning {
+			ipfs.measured = false
+		}
+		ipfs.progressMu.Unlock()
+		if !pinning {
+			continue
+		}
+
+		resp, err := ipfs.bitswapStat(ipfs.ctx)
+		if err != nil {
+			logger.Debugf("error measuring pin progress: %s", err)
+			continue
+		}
+		ipfs.shareDataReceived(resp.DataReceived)
+	}
+}
+
+func (ipfs *Connector) shareDataReceived(dataReceived uint64) {
+	ipfs.progressMu.Lock()
+	defer ipfs.progressMu.Unlock()
+
+	last := ipfs.lastDataReceived
+	measured := ipfs.measured
+	ipfs.lastDataReceived = dataReceived
+	ipfs.measured = true
+	if !measured || dataReceived < last || len(ipfs.progress) == 0 {
+		// the first measurement, or the daemon restarted
+		for _, prog := range ipfs.progress {
+			prog.lastBlocks = prog.blocks
+		}
+		return
+	}
+
+	received := float64(dataReceived - last)
+	var newBlocks uint64
+	for _, prog := range ipfs.progress {
+		newBlocks += prog.blocks - prog.lastBlocks
+	}
+	for _, prog := range ipfs.progress {
+		share := 1 / float64(len(ipfs.progress))
+		if newBlocks > 0 {
+			share = float64(prog.blocks-prog.lastBlocks) / float64(newBlocks)
+		}
+		prog.bytes += uint64(received * share)
+		prog.lastBlocks = prog.blocks
+	}
+}
+
+// PinProgress returns the progress of the ongoing pins: the blocks fetched
+// and an estimation of the bytes received for each of them.
+func (ipfs *Connector) PinProgress(ctx context.Context) ([]*api.PinProgress, error) {
+	ipfs.progressMu.Lock()
+	defer ipfs.progressMu.Unlock()
+
+	progress := make([]*api.PinProgress, 0, len(ipfs.progress))
+	for _, prog := range ipfs.progress {
+		progress = append(progress, &api.PinProgress{
+			Cid:    prog.cid,
+			Blocks: prog.blocks,
+			Bytes:  prog.bytes,
+		})
+	}
+	return progress, nil
+}
+
 // // FetchRefs asks IPFS to download blocks recursively to the given depth.
 // // It discards the response, but waits until it completes.
 // func (ipfs *Connector) FetchRefs(ctx context.Context, c cid.Cid, maxDepth int) error {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,7 +12,10 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/observations"
 	"github.com/ipfs/ipfs-cluster/test"
+
+	"go.opencensus.io/stats/view"
 )
 
 func init() {
@@ -48,6 +52,7 @@ func TestIPFSID(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
 	defer ipfs.Shutdown(ctx)
+	ipfs.config.GatewayURL = "https://gw.example.com"
 	id, err := ipfs.ID(ctx)
 	if err != nil {
 		t.Fatal(err)
@@ -58,6 +63,9 @@ func TestIPFSID(t *testing.T) {
 	if len(id.Addresses) != 1 {
 		t.Error("expected 1 address")
 	}
+	if id.GatewayURL != "https://gw.example.com" {
+		t.Error("expected the configured gateway url")
+	}
 	if id.Error != "" {
 		t.Error("expected no error")
 	}
@@ -101,6 +109,73 @@ func TestIPFSPin(t *testing.T) {
 	t.Run("method=refs", func(t *testing.T) { testPin(t, "refs") })
 }
 
+func TestIPFSPinStalled(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	ipfs.config.PinMethod = "pin"
+	ipfs.config.PinTimeout = 2 * time.Second
+	ipfs.config.PinStallTimeout = 500 * time.Millisecond
+
+	// The mock never finishes pinning SlowCid1. As long as blocks are
+	// received, only the PinTimeout applies.
+	start := time.Now()
+	err := ipfs.Pin(ctx, test.SlowCid1, -1)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), "stalled") {
+		t.Error("the pin was making progress and should not have stalled")
+	}
+	if time.Since(start) < ipfs.config.PinTimeout {
+		t.Error("the pin should have only stopped with the pin timeout")
+	}
+
+	mock.SetBitswapStalled(true)
+	start = time.Now()
+	err = ipfs.Pin(ctx, test.SlowCid1, -1)
+	if err == nil || !strings.Contains(err.Error(), "stalled") {
+		t.Fatal("expected a stalled pin error:", err)
+	}
+	if time.Since(start) >= ipfs.config.PinTimeout {
+		t.Error("the pin should have been cancelled before the pin timeout")
+	}
+}
+
+func TestIPFSPinLatency(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	err := view.Register(observations.PinLatencyView)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(observations.PinLatencyView)
+
+	err = ipfs.Pin(ctx, test.Cid1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(observations.PinLatencyView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if len(rows[0].Tags) != 1 || rows[0].Tags[0].Value != "<=1MiB" {
+		t.Errorf("unexpected tags: %v", rows[0].Tags)
+	}
+	if rows[0].Data.(*view.DistributionData).Count != 1 {
+		t.Error("expected one latency measurement")
+	}
+}
+
 func TestIPFSUnpin(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -162,6 +237,40 @@ func TestIPFSPinLs(t *testing.T) {
 	}
 }
 
+func TestIPFSPinLsDirect(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	ipfs.Pin(ctx, test.Cid1, -1)
+	ipfs.Pin(ctx, test.Cid2, 0)
+
+	ipsMap, err := ipfs.PinLs(ctx, "recursive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ipsMap) != 1 || !ipsMap[test.Cid1.String()].IsPinned(-1) {
+		t.Error("only c1 should appear pinned recursively")
+	}
+
+	ipsMap, err = ipfs.PinLs(ctx, "recursive,direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ipsMap) != 2 {
+		t.Fatal("the map does not contain expected keys")
+	}
+	if !ipsMap[test.Cid2.String()].IsPinned(0) {
+		t.Error("c2 should appear pinned directly")
+	}
+
+	ips, err := ipfs.PinLsCid(ctx, test.Cid2)
+	if err != nil || !ips.IsPinned(0) {
+		t.Error("c2 should appear pinned directly")
+	}
+}
+
 func TestIPFSShutdown(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -257,6 +366,41 @@ func TestBlockGet(t *testing.T) {
 	}
 }
 
+func TestRepoGC(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	res, err := ipfs.RepoGC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See the ipfs mock implementation
+	if res.Removed != 2 {
+		t.Error("expected 2 removed blocks, got", res.Removed)
+	}
+	if res.Error == "" {
+		t.Error("expected an error for the block which was not removed")
+	}
+}
+
+func TestDaemonInfo(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	info, err := ipfs.DaemonInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See the ipfs mock implementation
+	if info.Version != "m.o.c.k" || info.Routing != "dht" || info.StorageMax != "10G" {
+		t.Errorf("unexpected daemon info: %+v", info)
+	}
+}
+
 func TestRepoStat(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -287,6 +431,158 @@ func TestRepoStat(t *testing.T) {
 	}
 }
 
+func TestDAGSize(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	size, err := ipfs.DAGSize(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See the ipfs mock implementation
+	if size != 1024 {
+		t.Errorf("expected 1024 bytes of size, got %d", size)
+	}
+}
+
+func TestDAGBlocks(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	// See the ipfs mock implementation
+	blocks, err := ipfs.DAGBlocks(ctx, test.Cid1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+	if !blocks[0].Cid.Equals(test.Cid1) || !blocks[1].Cid.Equals(test.Cid4) {
+		t.Error("unexpected blocks")
+	}
+	if blocks[0].Size != 256 {
+		t.Error("expected 256 bytes blocks")
+	}
+
+	blocks, err = ipfs.DAGBlocks(ctx, test.Cid1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 {
+		t.Error("direct DAGs should only include the root")
+	}
+}
+
+func TestBitswapStat(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	s, err := ipfs.BitswapStat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See the ipfs mock implementation
+	if s.WantlistSize != 1 || s.Peers != 2 {
+		t.Errorf("unexpected bitswap stats: %+v", s)
+	}
+	if s.DataReceived != 1024 {
+		t.Error("expected 1024 bytes received")
+	}
+	if s.DataReceivedRate != 0 {
+		t.Error("the first call should not have a data received rate")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	s, err = ipfs.BitswapStat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DataReceivedRate <= 0 || s.DataReceivedRate > 1024/0.1 {
+		t.Errorf("unexpected data received rate: %f", s.DataReceivedRate)
+	}
+}
+
+func TestPinProgress(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	// The mock fetches the root of SlowCid1 and never finishes.
+	pinCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ipfs.Pin(pinCtx, test.SlowCid1, -1)
+	}()
+
+	var progress []*api.PinProgress
+	for i := 0; i < 50; i++ {
+		time.Sleep(20 * time.Millisecond)
+		progress, _ = ipfs.PinProgress(ctx)
+		if len(progress) == 1 && progress[0].Blocks == 1 {
+			break
+		}
+	}
+	if len(progress) != 1 || !progress[0].Cid.Equals(test.SlowCid1) || progress[0].Blocks != 1 {
+		t.Fatalf("expected the progress of the ongoing pin: %+v", progress)
+	}
+
+	cancel()
+	<-done
+	progress, _ = ipfs.PinProgress(ctx)
+	if len(progress) != 0 {
+		t.Error("finished pins should not report progress")
+	}
+
+	err := ipfs.Pin(ctx, test.Cid1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress, _ = ipfs.PinProgress(ctx)
+	if len(progress) != 0 {
+		t.Error("finished pins should not report progress")
+	}
+}
+
+func TestShareDataReceived(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	p1 := ipfs.startPinProgress(test.Cid1)
+	p2 := ipfs.startPinProgress(test.Cid2)
+
+	ipfs.shareDataReceived(1000) // first measurement
+	ipfs.setPinBlocks(p1, 3)
+	ipfs.setPinBlocks(p2, 1)
+	ipfs.shareDataReceived(5000)
+	if p1.bytes != 3000 || p2.bytes != 1000 {
+		t.Errorf("data should be shared by blocks fetched: %d %d", p1.bytes, p2.bytes)
+	}
+
+	// no new blocks: shared equally
+	ipfs.shareDataReceived(6000)
+	if p1.bytes != 3500 || p2.bytes != 1500 {
+		t.Errorf("data should be shared equally: %d %d", p1.bytes, p2.bytes)
+	}
+
+	ipfs.setPinBlocks(p1, 2)
+	if p1.blocks != 3 {
+		t.Error("blocks should not go back")
+	}
+
+	ipfs.donePinProgress(p1)
+	ipfs.donePinProgress(p2)
+}
+
 func TestResolve(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -302,6 +598,55 @@ func TestResolve(t *testing.T) {
 	}
 }
 
+func TestProvide(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	err := ipfs.Provide(ctx, test.Cid1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = ipfs.Provide(ctx, test.ErrorCid)
+	if err == nil {
+		t.Error("expected an error providing ErrorCid")
+	}
+}
+
+func TestSetReproviderStrategy(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	err := ipfs.setReproviderStrategy(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := mock.ReproviderStrategy(); s != "" {
+		t.Errorf("strategy should not have been set: %s", s)
+	}
+
+	ipfs.config.ReproviderStrategy = "roots"
+	err = ipfs.setReproviderStrategy(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := mock.ReproviderStrategy(); s != "roots" {
+		t.Errorf("expected roots strategy: %s", s)
+	}
+
+	v, err := ipfs.ConfigKey("Reprovider/Strategy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "roots" {
+		t.Error("the strategy should be reported by the configuration")
+	}
+}
+
 func TestConfigKey(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -0,0 +1,191 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	semver "github.com/blang/semver"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/rpcutil"
+)
+
+// Peers are expected to run the same IPFS version and the same relevant
+// IPFS settings. The drift report compares the IPFS daemons of every peer
+// and, when IPFSDriftCheckInterval is set, it is checked regularly and
+// its warnings are logged.
+
+// IPFSDrift compares the IPFS daemons of every cluster peer.
+func (c *Cluster) IPFSDrift(ctx context.Context) (*api.IPFSDrift, error) {
+	_, span := trace.StartSpan(ctx, "cluster/IPFSDrift")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	lenMembers := len(members)
+
+	replies := make([]*api.IPFSDaemonInfo, lenMembers, lenMembers)
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, lenMembers)
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		members,
+		"Cluster",
+		"IPFSDaemonInfoLocal",
+		struct{}{},
+		rpcutil.CopyIPFSDaemonInfosToIfaces(replies),
+	)
+
+	for i, err := range errs {
+		if err != nil {
+			logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, members[i], err)
+			replies[i] = &api.IPFSDaemonInfo{
+				Peer:  members[i],
+				Error: err.Error(),
+			}
+		}
+	}
+	return ipfsDrift(replies), nil
+}
+
+// IPFSDaemonInfoLocal returns the version and settings of the IPFS daemon
+// of this peer.
+func (c *Cluster) IPFSDaemonInfoLocal(ctx context.Context) *api.IPFSDaemonInfo {
+	_, span := trace.StartSpan(ctx, "cluster/IPFSDaemonInfoLocal")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	info, err := c.ipfs.DaemonInfo(ctx)
+	if err != nil {
+		info = &api.IPFSDaemonInfo{Error: err.Error()}
+	}
+	info.Peer = c.id
+	info.Peername = c.config.Peername
+	return info
+}
+
+// ipfsDrift builds the drift report for the given daemons. Daemons with
+// errors are not compared.
+func ipfsDrift(daemons []*api.IPFSDaemonInfo) *api.IPFSDrift {
+	drift := &api.IPFSDrift{
+		Daemons:  daemons,
+		Warnings: []string{},
+	}
+
+	versions := make(map[string][]string)
+	routings := make(map[string][]string)
+	storageMaxs := make(map[string][]string)
+	for _, d := range daemons {
+		if d.Error != "" {
+			continue
+		}
+		name := d.Peername
+		if name == "" {
+			name = d.Peer.Pretty()
+		}
+		versions[d.Version] = append(versions[d.Version], name)
+		routings[d.Routing] = append(routings[d.Routing], name)
+		storageMaxs[d.StorageMax] = append(storageMaxs[d.StorageMax], name)
+	}
+
+	if len(versions) > 1 {
+		drift.Warnings = append(drift.Warnings, "peers run different IPFS versions: "+describeDrift(versions))
+		drift.Dangerous = drift.Dangerous || !sameMinorVersions(versions)
+	}
+	if len(routings) > 1 {
+		drift.Warnings = append(drift.Warnings, "peers use different IPFS routing types: "+describeDrift(routings))
+		drift.Dangerous = true
+	}
+	if len(storageMaxs) > 1 {
+		drift.Warnings = append(drift.Warnings, "peers use different IPFS StorageMax values: "+describeDrift(storageMaxs))
+	}
+	return drift
+}
+
+// sameMinorVersions returns true when all the given versions only differ
+// in their patch number.
+func sameMinorVersions(versions map[string][]string) bool {
+	var first *semver.Version
+	for v := range versions {
+		parsed, err := semver.ParseTolerant(v)
+		if err != nil {
+			return false
+		}
+		if first == nil {
+			first = &parsed
+			continue
+		}
+		if parsed.Major != first.Major || parsed.Minor != first.Minor {
+			return false
+		}
+	}
+	return true
+}
+
+// describeDrift lists each value with the peers using it, i.e.
+// "0.5.0 (peer1, peer2), 0.4.23 (peer3)".
+func describeDrift(values map[string][]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := k
+		if value == "" {
+			value = "unset"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", value, strings.Join(values[k], ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ipfsDriftWatcher checks the IPFS drift report every
+// IPFSDriftCheckInterval and logs its warnings. Only the coordinator does
+// it.
+func (c *Cluster) ipfsDriftWatcher() {
+	ticker := time.NewTicker(c.config.IPFSDriftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkIPFSDrift(c.ctx)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cluster) checkIPFSDrift(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "cluster/checkIPFSDrift")
+	defer span.End()
+
+	if !c.isCoordinator(ctx) {
+		return
+	}
+
+	drift, err := c.IPFSDrift(ctx)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	for _, w := range drift.Warnings {
+		logger.Warningf("IPFS drift: %s", w)
+	}
+	if drift.Dangerous {
+		logger.Error("cluster peers run dangerously mismatched IPFS daemons: align their versions and settings")
+	}
+}
@@ -0,0 +1,133 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/config"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"go.opencensus.io/trace"
+)
+
+// Join tokens bundle everything a new peer needs to bootstrap to a cluster
+// (the addresses of an existing peer and the cluster secret) in a single
+// string. They are signed by the peer that creates them and expire after
+// the given TTL. Note that the token carries the cluster secret: anyone
+// holding it before it expires can join the cluster.
+
+// joinTokenPayload is the signed content of a join token.
+type joinTokenPayload struct {
+	Addrs   []string  `json:"addrs"`
+	Secret  string    `json:"secret"`
+	Expires time.Time `json:"expires"`
+}
+
+// JoinTokenInfo holds the verified contents of a join token.
+type JoinTokenInfo struct {
+	// Peer is the peer that created and signed the token.
+	Peer peer.ID
+	// Addrs are the addresses of Peer, to be used for bootstrapping.
+	Addrs []ma.Multiaddr
+	// Secret is the cluster secret.
+	Secret []byte
+	// Expires is the time after which the token is no longer valid.
+	Expires time.Time
+}
+
+// JoinToken creates a new join token, valid for the given amount of time,
+// which allows a new peer to bootstrap to the cluster through this peer.
+func (c *Cluster) JoinToken(ctx context.Context, ttl time.Duration) (*api.JoinToken, error) {
+	_, span := trace.StartSpan(ctx, "cluster/JoinToken")
+	defer span.End()
+
+	if ttl <= 0 {
+		return nil, errors.New("the join token TTL must be positive")
+	}
+
+	key := c.host.Peerstore().PrivKey(c.id)
+	if key == nil {
+		return nil, errors.New("no private key available to sign the join token")
+	}
+
+	payload := joinTokenPayload{
+		Secret:  EncodeProtectorKey(c.config.Secret),
+		Expires: time.Now().Add(ttl).UTC(),
+	}
+	for _, addr := range c.host.Addrs() {
+		joined := api.MustLibp2pMultiaddrJoin(api.NewMultiaddrWithValue(addr), c.id)
+		payload.Addrs = append(payload.Addrs, joined.String())
+	}
+
+	bs, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := config.SignJSON(bs, key)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("join token created (expires at %s)", payload.Expires)
+	return &api.JoinToken{
+		Token:   base64.RawURLEncoding.EncodeToString(signed),
+		Expires: payload.Expires,
+	}, nil
+}
+
+// ParseJoinToken verifies the signature and the expiration date of a join
+// token and returns its contents.
+func ParseJoinToken(token string) (*JoinTokenInfo, error) {
+	signed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding join token: %s", err)
+	}
+
+	bs, signer, err := config.OpenSignedJSON(signed)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying join token: %s", err)
+	}
+
+	var payload joinTokenPayload
+	err = json.Unmarshal(bs, &payload)
+	if err != nil {
+		return nil, fmt.Errorf("error reading join token: %s", err)
+	}
+
+	if time.Now().After(payload.Expires) {
+		return nil, fmt.Errorf("the join token expired at %s", payload.Expires)
+	}
+
+	secret, err := DecodeClusterSecret(payload.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("error reading join token secret: %s", err)
+	}
+
+	info := &JoinTokenInfo{
+		Peer:    signer,
+		Secret:  secret,
+		Expires: payload.Expires,
+	}
+	for _, a := range payload.Addrs {
+		addr, err := ma.NewMultiaddr(a)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing join token address: %s", err)
+		}
+		pid, _, err := api.Libp2pMultiaddrSplit(addr)
+		if err != nil || pid != signer {
+			return nil, fmt.Errorf("join token address %s does not belong to %s", addr, signer)
+		}
+		info.Addrs = append(info.Addrs, addr)
+	}
+	if len(info.Addrs) == 0 {
+		return nil, errors.New("the join token carries no addresses")
+	}
+	return info, nil
+}
@@ -0,0 +1,143 @@
+package ipfscluster
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	logging "github.com/ipfs/go-log"
+	colorable "github.com/mattn/go-colorable"
+	gologging "github.com/whyrusleeping/go-logging"
+)
+
+// The most recent log entries of every component are kept in memory, so
+// that operators can inspect them through the API (see Cluster.Logs)
+// without access to the peer's logs. Only entries enabled by the log level
+// of each component are captured.
+
+// logBufferSize is the number of recent log entries kept per component.
+var logBufferSize = 500
+
+var capturedLogs = newLogBuffer(logBufferSize)
+
+// logBuffer is a logging backend which keeps a ring buffer of log entries
+// per component.
+type logBuffer struct {
+	size int
+
+	mu    sync.Mutex
+	rings map[string]*logRing
+}
+
+type logRing struct {
+	entries []*api.LogEntry
+	next    int // position of the oldest entry once full
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{
+		size:  size,
+		rings: make(map[string]*logRing),
+	}
+}
+
+// Log implements go-logging's Backend.
+func (lb *logBuffer) Log(level gologging.Level, calldepth int, rec *gologging.Record) error {
+	entry := &api.LogEntry{
+		Time:      rec.Time,
+		Component: rec.Module,
+		Level:     strings.ToLower(level.String()),
+		Message:   rec.Message(),
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	r, ok := lb.rings[rec.Module]
+	if !ok {
+		r = &logRing{}
+		lb.rings[rec.Module] = r
+	}
+	if len(r.entries) < lb.size {
+		r.entries = append(r.entries, entry)
+		return nil
+	}
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % lb.size
+	return nil
+}
+
+// entries returns, oldest first, the entries of the given component (or
+// of all components when empty) with the given severity or a more severe
+// one.
+func (lb *logBuffer) entries(component string, severity int) []*api.LogEntry {
+	lb.mu.Lock()
+	var all []*api.LogEntry
+	for name, r := range lb.rings {
+		if component != "" && name != component {
+			continue
+		}
+		all = append(all, r.entries[r.next:]...)
+		all = append(all, r.entries[:r.next]...)
+	}
+	lb.mu.Unlock()
+
+	filtered := make([]*api.LogEntry, 0, len(all))
+	for _, e := range all {
+		sev, err := api.LogLevelSeverity(e.Level)
+		if err == nil && sev <= severity {
+			filtered = append(filtered, e)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Time.Before(filtered[j].Time)
+	})
+	return filtered
+}
+
+// captureLogs adds capturedLogs to the logging backends set up by go-log
+// (stderr and the GOLOG_FILE, if any). Setting the backends resets the log
+// levels, so they are restored afterwards.
+func captureLogs() {
+	defaultLevel := gologging.GetLevel("")
+	levels := make(map[string]gologging.Level)
+	for _, sub := range logging.GetSubsystems() {
+		levels[sub] = gologging.GetLevel(sub)
+	}
+
+	backends := []gologging.Backend{
+		gologging.NewLogBackend(colorable.NewColorableStderr(), "", 0),
+		capturedLogs,
+	}
+	if logfp := os.Getenv("GOLOG_FILE"); logfp != "" {
+		f, err := os.OpenFile(logfp, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err == nil {
+			backends = append(backends, gologging.NewLogBackend(f, "", 0))
+		}
+	}
+	gologging.SetBackend(backends...)
+
+	gologging.SetLevel(defaultLevel, "")
+	for sub, lvl := range levels {
+		gologging.SetLevel(lvl, sub)
+	}
+}
+
+// Logs returns the recent log entries captured by this peer which match
+// the given query, oldest first.
+func (c *Cluster) Logs(ctx context.Context, q api.LogQuery) ([]*api.LogEntry, error) {
+	_, span := trace.StartSpan(ctx, "cluster/Logs")
+	defer span.End()
+
+	severity, err := api.LogLevelSeverity(q.Level)
+	if err != nil {
+		return nil, err
+	}
+	return capturedLogs.entries(q.Component, severity), nil
+}
@@ -0,0 +1,67 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	gologging "github.com/whyrusleeping/go-logging"
+)
+
+func TestLogBuffer(t *testing.T) {
+	lb := newLogBuffer(3)
+	for i := 0; i < 5; i++ {
+		rec := &gologging.Record{Module: "pintracker"}
+		lb.Log(gologging.INFO, 0, rec)
+	}
+	lb.Log(gologging.WARNING, 0, &gologging.Record{Module: "cluster"})
+
+	if n := len(lb.entries("pintracker", 5)); n != 3 {
+		t.Errorf("expected 3 pintracker entries, got %d", n)
+	}
+	if n := len(lb.entries("", 5)); n != 4 {
+		t.Errorf("expected 4 entries, got %d", n)
+	}
+	warnings := lb.entries("", 2)
+	if len(warnings) != 1 || warnings[0].Component != "cluster" || warnings[0].Level != "warning" {
+		t.Errorf("unexpected warnings: %+v", warnings)
+	}
+}
+
+func TestClusterLogs(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer cl.Shutdown(ctx)
+
+	// Tests usually run with a higher log level.
+	SetFacilityLogLevel("cluster", "WARNING")
+	defer SetFacilityLogLevel("cluster", logLevel)
+
+	msg := fmt.Sprintf("captured warning %p", cl)
+	logger.Warning(msg)
+
+	entries, err := cl.Logs(ctx, api.LogQuery{Component: "cluster", Level: "warn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, e := range entries {
+		if e.Message == msg {
+			found = true
+		}
+		if e.Level != "warning" && e.Level != "error" && e.Level != "critical" {
+			t.Errorf("unexpected entry level: %s", e.Level)
+		}
+	}
+	if !found {
+		t.Error("expected the warning to be captured")
+	}
+
+	_, err = cl.Logs(ctx, api.LogQuery{Level: "loud"})
+	if err == nil {
+		t.Error("expected an error with an unknown level")
+	}
+}
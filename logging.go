@@ -17,6 +17,7 @@ func init() {
 		ansiYellow + "%{module:10.10s}: %{color:reset}%{message} " +
 		ansiGray + "%{shortfile}%{color:reset}"
 	logging.SetupLogging()
+	captureLogs()
 }
 
 // LoggingFacilities provides a list of logging identifiers
@@ -39,6 +40,9 @@ var LoggingFacilities = map[string]string{
 	"localdags":    "INFO",
 	"adder":        "INFO",
 	"optracker":    "INFO",
+	"audit":        "INFO",
+	"faults":       "INFO",
+	"policy":       "INFO",
 }
 
 // LoggingFacilitiesExtra provides logging identifiers
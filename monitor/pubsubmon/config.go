@@ -14,8 +14,12 @@ const envConfigKey = "cluster_pubsubmon"
 
 // Default values for this Config.
 const (
-	DefaultCheckInterval    = 15 * time.Second
-	DefaultFailureThreshold = 3.0
+	DefaultCheckInterval      = 15 * time.Second
+	DefaultFailureThreshold   = 3.0
+	DefaultRestoredMetricsTTL = 30 * time.Second
+	DefaultBlacklistThreshold = 0
+	DefaultBlacklistWindow    = time.Minute
+	DefaultClockSkewThreshold = 5 * time.Second
 )
 
 // Config allows to initialize a Monitor and customize some parameters.
@@ -27,11 +31,31 @@ type Config struct {
 	// The greater the threshold value the more leniency is granted.
 	// A value between 2.0 and 4.0 is suggested for the threshold.
 	FailureThreshold float64
+	// RestoredMetricsTTL is the TTL given to the metrics persisted by
+	// the monitor when they are restored on start. They are then
+	// replaced by fresh ones as soon as those are received. 0 disables
+	// metric persistence.
+	RestoredMetricsTTL time.Duration
+	// BlacklistThreshold is the number of invalid metric messages (i.e.
+	// metrics from a peer published by a different one) that a peer can
+	// send within BlacklistWindow before it is blacklisted from pubsub.
+	// 0 disables blacklisting.
+	BlacklistThreshold int
+	BlacklistWindow    time.Duration
+	// ClockSkewThreshold is the difference between the time at which
+	// metrics are published by a peer and the local time at which they
+	// are received above which the clock of that peer is considered
+	// skewed and a warning is logged. 0 disables skew detection.
+	ClockSkewThreshold time.Duration
 }
 
 type jsonConfig struct {
-	CheckInterval    string   `json:"check_interval"`
-	FailureThreshold *float64 `json:"failure_threshold"`
+	CheckInterval      string   `json:"check_interval"`
+	FailureThreshold   *float64 `json:"failure_threshold"`
+	RestoredMetricsTTL string   `json:"restored_metrics_ttl"`
+	BlacklistThreshold int      `json:"blacklist_threshold"`
+	BlacklistWindow    string   `json:"blacklist_window"`
+	ClockSkewThreshold string   `json:"clock_skew_threshold"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -43,6 +67,10 @@ func (cfg *Config) ConfigKey() string {
 func (cfg *Config) Default() error {
 	cfg.CheckInterval = DefaultCheckInterval
 	cfg.FailureThreshold = DefaultFailureThreshold
+	cfg.RestoredMetricsTTL = DefaultRestoredMetricsTTL
+	cfg.BlacklistThreshold = DefaultBlacklistThreshold
+	cfg.BlacklistWindow = DefaultBlacklistWindow
+	cfg.ClockSkewThreshold = DefaultClockSkewThreshold
 	return nil
 }
 
@@ -70,6 +98,22 @@ func (cfg *Config) Validate() error {
 		return errors.New("pubsubmon.failure_threshold too low")
 	}
 
+	if cfg.RestoredMetricsTTL < 0 {
+		return errors.New("pubsubmon.restored_metrics_ttl is invalid")
+	}
+
+	if cfg.BlacklistThreshold < 0 {
+		return errors.New("pubsubmon.blacklist_threshold is invalid")
+	}
+
+	if cfg.BlacklistWindow <= 0 {
+		return errors.New("pubsubmon.blacklist_window is invalid")
+	}
+
+	if cfg.ClockSkewThreshold < 0 {
+		return errors.New("pubsubmon.clock_skew_threshold is invalid")
+	}
+
 	return nil
 }
 
@@ -95,6 +139,17 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 		cfg.FailureThreshold = *jcfg.FailureThreshold
 	}
 
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.RestoredMetricsTTL, Dst: &cfg.RestoredMetricsTTL, Name: "restored_metrics_ttl"},
+		&config.DurationOpt{Duration: jcfg.BlacklistWindow, Dst: &cfg.BlacklistWindow, Name: "blacklist_window"},
+		&config.DurationOpt{Duration: jcfg.ClockSkewThreshold, Dst: &cfg.ClockSkewThreshold, Name: "clock_skew_threshold"},
+	)
+	if err != nil {
+		return err
+	}
+	cfg.BlacklistThreshold = jcfg.BlacklistThreshold
+
 	return cfg.Validate()
 }
 
@@ -107,7 +162,11 @@ func (cfg *Config) ToJSON() ([]byte, error) {
 
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	return &jsonConfig{
-		CheckInterval:    cfg.CheckInterval.String(),
-		FailureThreshold: &cfg.FailureThreshold,
+		CheckInterval:      cfg.CheckInterval.String(),
+		FailureThreshold:   &cfg.FailureThreshold,
+		RestoredMetricsTTL: cfg.RestoredMetricsTTL.String(),
+		BlacklistThreshold: cfg.BlacklistThreshold,
+		BlacklistWindow:    cfg.BlacklistWindow.String(),
+		ClockSkewThreshold: cfg.ClockSkewThreshold.String(),
 	}
 }
@@ -10,7 +10,8 @@ import (
 var cfgJSON = []byte(`
 {
       "check_interval": "15s",
-      "failure_threshold": 3.0
+      "failure_threshold": 3.0,
+      "restored_metrics_ttl": "1m"
 }
 `)
 
@@ -30,6 +31,44 @@ func TestLoadJSON(t *testing.T) {
 	if err == nil {
 		t.Error("expected error decoding check_interval")
 	}
+
+	json.Unmarshal(cfgJSON, j)
+	j.RestoredMetricsTTL = "0s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RestoredMetricsTTL != 0 {
+		t.Error("expected restored_metrics_ttl to be 0")
+	}
+
+	j.RestoredMetricsTTL = "abc"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error decoding restored_metrics_ttl")
+	}
+
+	json.Unmarshal(cfgJSON, j)
+	j.BlacklistThreshold = 5
+	j.BlacklistWindow = "30s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BlacklistThreshold != 5 || cfg.BlacklistWindow != 30*time.Second {
+		t.Error("expected blacklist options to be loaded")
+	}
+
+	json.Unmarshal(cfgJSON, j)
+	j.ClockSkewThreshold = "-1s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with negative clock_skew_threshold")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -58,6 +97,30 @@ func TestDefault(t *testing.T) {
 	if cfg.Validate() == nil {
 		t.Fatal("expected error validating")
 	}
+
+	cfg.Default()
+	cfg.RestoredMetricsTTL = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.BlacklistThreshold = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.BlacklistWindow = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.ClockSkewThreshold = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
 }
 
 func TestApplyEnvVars(t *testing.T) {
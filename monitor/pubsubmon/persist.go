@@ -0,0 +1,73 @@
+package pubsubmon
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	ds "github.com/ipfs/go-datastore"
+	namespace "github.com/ipfs/go-datastore/namespace"
+	query "github.com/ipfs/go-datastore/query"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// DatastoreNamespace is the datastore namespace under which the latest
+// metrics received from every peer are persisted.
+var DatastoreNamespace = "/monitor"
+
+func metricKey(m *api.Metric) ds.Key {
+	return ds.NewKey(m.Name).ChildString(peer.IDB58Encode(m.Peer))
+}
+
+// persistMetric stores the given metric as the latest one received for
+// its name and peer. Only valid metrics are persisted.
+func (mon *Monitor) persistMetric(m *api.Metric) {
+	if mon.store == nil || !m.Valid || mon.ctx.Err() != nil {
+		return
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	err = mon.store.Put(metricKey(m), b)
+	if err != nil {
+		logger.Errorf("error persisting metric: %s", err)
+	}
+}
+
+// restoreMetrics loads the persisted metrics into the metrics store, so
+// that they are available before the first round of metrics is received
+// after a restart. Restored metrics expire after RestoredMetricsTTL.
+func (mon *Monitor) restoreMetrics(ctx context.Context) error {
+	results, err := mon.store.Query(query.Query{})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	n := 0
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		m := &api.Metric{}
+		err := json.Unmarshal(r.Value, m)
+		if err != nil {
+			logger.Warningf("discarding unreadable persisted metric %s: %s", r.Key, err)
+			continue
+		}
+		m.SetTTL(mon.config.RestoredMetricsTTL)
+		mon.metrics.Add(m)
+		n++
+	}
+	logger.Debugf("restored %d persisted metrics", n)
+	return nil
+}
+
+func wrapStore(store ds.Datastore) ds.Datastore {
+	return namespace.Wrap(store, ds.NewKey(DatastoreNamespace))
+}
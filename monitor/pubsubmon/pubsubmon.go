@@ -7,11 +7,14 @@ import (
 	"context"
 
 	"sync"
+	"time"
 
 	"github.com/ipfs/ipfs-cluster/api"
 	"github.com/ipfs/ipfs-cluster/monitor/metrics"
+	"github.com/ipfs/ipfs-cluster/pubsubutil"
 	"go.opencensus.io/trace"
 
+	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 	peer "github.com/libp2p/go-libp2p-peer"
@@ -36,13 +39,18 @@ type Monitor struct {
 
 	pubsub       *pubsub.PubSub
 	subscription *pubsub.Subscription
+	scorer       *pubsubutil.Scorer
 	peers        PeersFunc
 
 	metrics *metrics.Store
 	checker *metrics.Checker
+	store   ds.Datastore
 
 	config *Config
 
+	skewMux sync.Mutex
+	skewed  map[peer.ID]bool
+
 	shutdownLock sync.Mutex
 	shutdown     bool
 	wg           sync.WaitGroup
@@ -55,11 +63,16 @@ type PeersFunc func(context.Context) ([]peer.ID, error)
 // New creates a new PubSub monitor, using the given host, config and
 // PeersFunc. The PeersFunc can be nil. In this case, no metric filtering is
 // done based on peers (any peer is considered part of the peerset).
+//
+// When a datastore is given, the latest metrics received from every peer
+// are persisted to it and restored when the monitor starts, so that they
+// are not lost on restarts. The datastore can be nil.
 func New(
 	ctx context.Context,
 	cfg *Config,
 	psub *pubsub.PubSub,
 	peers PeersFunc,
+	store ds.Datastore,
 ) (*Monitor, error) {
 	err := cfg.Validate()
 	if err != nil {
@@ -71,24 +84,45 @@ func New(
 	mtrs := metrics.NewStore()
 	checker := metrics.NewChecker(ctx, mtrs, cfg.FailureThreshold)
 
-	subscription, err := psub.Subscribe(PubsubTopic)
-	if err != nil {
-		cancel()
-		return nil, err
-	}
-
 	mon := &Monitor{
 		ctx:      ctx,
 		cancel:   cancel,
 		rpcReady: make(chan struct{}, 1),
 
-		pubsub:       psub,
-		subscription: subscription,
-		peers:        peers,
+		pubsub: psub,
+		scorer: pubsubutil.NewScorer(
+			psub,
+			PubsubTopic,
+			cfg.BlacklistThreshold,
+			cfg.BlacklistWindow,
+		),
+		peers: peers,
 
 		metrics: mtrs,
 		checker: checker,
 		config:  cfg,
+		skewed:  make(map[peer.ID]bool),
+	}
+
+	err = psub.RegisterTopicValidator(PubsubTopic, mon.validateMetric)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	mon.subscription, err = psub.Subscribe(PubsubTopic)
+	if err != nil {
+		psub.UnregisterTopicValidator(PubsubTopic)
+		cancel()
+		return nil, err
+	}
+
+	if store != nil && cfg.RestoredMetricsTTL > 0 {
+		mon.store = wrapStore(store)
+		err = mon.restoreMetrics(ctx)
+		if err != nil {
+			logger.Errorf("error restoring persisted metrics: %s", err)
+		}
 	}
 
 	go mon.run()
@@ -119,6 +153,7 @@ func (mon *Monitor) logFromPubsub() {
 				continue
 			}
 
+			receivedAt := time.Now()
 			data := msg.GetData()
 			buf := bytes.NewBuffer(data)
 			dec := msgpack.Multicodec(msgpackHandle).Decoder(buf)
@@ -133,6 +168,7 @@ func (mon *Monitor) logFromPubsub() {
 				metric.Name,
 				metric.Peer,
 			)
+			mon.checkClockSkew(ctx, &metric, receivedAt)
 
 			err = mon.LogMetric(ctx, &metric)
 			if err != nil {
@@ -143,6 +179,30 @@ func (mon *Monitor) logFromPubsub() {
 	}
 }
 
+// validateMetric is the pubsub validator for the metrics topic. It rejects
+// messages which cannot be decoded or which carry metrics for a peer other
+// than their author, so that peers cannot spoof the metrics of others.
+// Rejected messages count towards blacklisting the peer which sent them.
+func (mon *Monitor) validateMetric(ctx context.Context, p peer.ID, msg *pubsub.Message) bool {
+	buf := bytes.NewBuffer(msg.GetData())
+	dec := msgpack.Multicodec(msgpackHandle).Decoder(buf)
+	metric := api.Metric{}
+	err := dec.Decode(&metric)
+	if err != nil {
+		logger.Warningf("rejecting undecodable metric from %s: %s", p, err)
+		mon.scorer.Penalize(p)
+		return false
+	}
+
+	author := peer.ID(msg.GetFrom())
+	if metric.Peer != author {
+		logger.Warningf("rejecting metric for %s published by %s", metric.Peer, author)
+		mon.scorer.Penalize(p)
+		return false
+	}
+	return true
+}
+
 // SetClient saves the given rpc.Client  for later use
 func (mon *Monitor) SetClient(c *rpc.Client) {
 	mon.rpcClient = c
@@ -179,6 +239,7 @@ func (mon *Monitor) LogMetric(ctx context.Context, m *api.Metric) error {
 	defer span.End()
 
 	mon.metrics.Add(m)
+	mon.persistMetric(m)
 	logger.Debugf("pubsub mon logged '%s' metric from '%s'. Expires on %d", m.Name, m.Peer, m.Expire)
 	return nil
 }
@@ -193,10 +254,14 @@ func (mon *Monitor) PublishMetric(ctx context.Context, m *api.Metric) error {
 		return nil
 	}
 
+	// Timestamp a copy so that receivers can detect clock skew.
+	sent := *m
+	sent.SentAt = time.Now().UnixNano()
+
 	var b bytes.Buffer
 
 	enc := msgpack.Multicodec(msgpackHandle).Encoder(&b)
-	err := enc.Encode(m)
+	err := enc.Encode(&sent)
 	if err != nil {
 		logger.Error(err)
 		return err
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	ds "github.com/ipfs/go-datastore"
 	libp2p "github.com/libp2p/go-libp2p"
 	peer "github.com/libp2p/go-libp2p-peer"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
@@ -17,6 +18,7 @@ import (
 	host "github.com/libp2p/go-libp2p-host"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
 	"github.com/ipfs/ipfs-cluster/test"
 )
 
@@ -62,6 +64,10 @@ func peers(ctx context.Context) ([]peer.ID, error) {
 }
 
 func testPeerMonitor(t *testing.T) (*Monitor, host.Host, func()) {
+	return testPeerMonitorWithStore(t, nil)
+}
+
+func testPeerMonitorWithStore(t *testing.T, store ds.Datastore) (*Monitor, host.Host, func()) {
 	ctx := context.Background()
 	h, err := libp2p.New(
 		context.Background(),
@@ -86,7 +92,7 @@ func testPeerMonitor(t *testing.T) (*Monitor, host.Host, func()) {
 	cfg := &Config{}
 	cfg.Default()
 	cfg.CheckInterval = 2 * time.Second
-	mon, err := New(ctx, cfg, psub, peers)
+	mon, err := New(ctx, cfg, psub, peers, store)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -231,6 +237,48 @@ func TestPeerMonitorLogMetric(t *testing.T) {
 	}
 }
 
+func TestPeerMonitorRestoreMetrics(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	pm, _, shutdown := testPeerMonitorWithStore(t, store)
+	mf := newMetricFactory()
+
+	pm.LogMetric(ctx, mf.newMetric("test", test.PeerID1))
+	pm.LogMetric(ctx, mf.newMetric("test", test.PeerID1))
+	pm.LogMetric(ctx, mf.newMetric("test", test.PeerID2))
+	invalid := mf.newMetric("test", test.PeerID3)
+	invalid.Valid = false
+	pm.LogMetric(ctx, invalid)
+	shutdown()
+
+	pm, _, shutdown = testPeerMonitorWithStore(t, store)
+	defer shutdown()
+
+	latestMetrics := pm.LatestMetrics(ctx, "test")
+	if len(latestMetrics) != 2 {
+		t.Fatal("expected 2 restored metrics")
+	}
+
+	for _, v := range latestMetrics {
+		switch v.Peer {
+		case test.PeerID1:
+			if v.Value != "1" {
+				t.Error("expected the last metric to be restored")
+			}
+		case test.PeerID2:
+			if v.Value != "2" {
+				t.Error("bad metric value")
+			}
+		default:
+			t.Error("bad peer")
+		}
+
+		if ttl := v.GetTTL(); ttl <= 5*time.Second || ttl > DefaultRestoredMetricsTTL {
+			t.Errorf("expected restored metric TTL, got %s", ttl)
+		}
+	}
+}
+
 func TestPeerMonitorPublishMetric(t *testing.T) {
 	ctx := context.Background()
 	pm, host, shutdown := testPeerMonitor(t)
@@ -256,7 +304,7 @@ func TestPeerMonitorPublishMetric(t *testing.T) {
 
 	mf := newMetricFactory()
 
-	metric := mf.newMetric("test", test.PeerID1)
+	metric := mf.newMetric("test", host.ID())
 	err = pm.PublishMetric(ctx, metric)
 	if err != nil {
 		t.Fatal(err)
@@ -265,7 +313,9 @@ func TestPeerMonitorPublishMetric(t *testing.T) {
 	time.Sleep(500 * time.Millisecond)
 
 	checkMetric := func(t *testing.T, pm *Monitor) {
-		latestMetrics := pm.LatestMetrics(ctx, "test")
+		// The metric comes from a peer outside the test peerset, so
+		// the store is checked directly.
+		latestMetrics := pm.metrics.LatestValid("test")
 		if len(latestMetrics) != 1 {
 			t.Fatal(host.ID(), "expected 1 published metric")
 		}
@@ -279,6 +329,9 @@ func TestPeerMonitorPublishMetric(t *testing.T) {
 			receivedMetric.Name != metric.Name {
 			t.Fatal("it should be exactly the same metric we published")
 		}
+		if receivedMetric.SentAt == 0 {
+			t.Error("published metrics should be timestamped")
+		}
 	}
 
 	t.Log("pm1")
@@ -287,6 +340,82 @@ func TestPeerMonitorPublishMetric(t *testing.T) {
 	checkMetric(t, pm2)
 }
 
+func TestPeerMonitorPublishSpoofedMetric(t *testing.T) {
+	ctx := context.Background()
+	pm, host, shutdown := testPeerMonitor(t)
+	defer shutdown()
+
+	pm2, host2, shutdown2 := testPeerMonitor(t)
+	defer shutdown2()
+
+	time.Sleep(200 * time.Millisecond)
+
+	err := host.Connect(
+		context.Background(),
+		peerstore.PeerInfo{
+			ID:    host2.ID(),
+			Addrs: host2.Addrs(),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	mf := newMetricFactory()
+
+	// host publishes a metric on behalf of another peer.
+	err = pm.PublishMetric(ctx, mf.newMetric("test", test.PeerID1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	if len(pm2.LatestMetrics(ctx, "test")) != 0 {
+		t.Error("spoofed metrics should be discarded")
+	}
+}
+
+func TestPeerMonitorClockSkew(t *testing.T) {
+	ctx := context.Background()
+	pm, _, shutdown := testPeerMonitor(t)
+	defer shutdown()
+
+	now := time.Now()
+	m := &api.Metric{
+		Name:   "ping",
+		Peer:   test.PeerID2,
+		SentAt: now.Add(-time.Minute).UnixNano(),
+	}
+	pm.checkClockSkew(ctx, m, now)
+	skewed := pm.SkewedPeers()
+	if len(skewed) != 1 || skewed[0] != test.PeerID2 {
+		t.Fatal("expected peer to be detected as skewed")
+	}
+
+	m.SentAt = now.Add(-100 * time.Millisecond).UnixNano()
+	pm.checkClockSkew(ctx, m, now)
+	if len(pm.SkewedPeers()) != 0 {
+		t.Error("peer should no longer be skewed")
+	}
+
+	// Metrics without timestamps are ignored.
+	m.SentAt = 0
+	pm.checkClockSkew(ctx, m, now)
+	if len(pm.SkewedPeers()) != 0 {
+		t.Error("untimestamped metrics should be ignored")
+	}
+
+	pm.config.ClockSkewThreshold = 0
+	m.SentAt = now.Add(time.Hour).UnixNano()
+	pm.checkClockSkew(ctx, m, now)
+	if len(pm.SkewedPeers()) != 0 {
+		t.Error("skew detection should be disabled")
+	}
+}
+
 func TestPeerMonitorAlerts(t *testing.T) {
 	ctx := context.Background()
 	pm, _, shutdown := testPeerMonitor(t)
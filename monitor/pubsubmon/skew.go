@@ -0,0 +1,72 @@
+package pubsubmon
+
+import (
+	"context"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/observations"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// Skewed clocks make metrics expire too early or too late on the peers
+// receiving them, which affects failure detection and allocations. Every
+// published metric carries the time at which it was sent, which is
+// compared with the local time when receiving it. The difference includes
+// the delivery delay, so only offsets above ClockSkewThreshold are
+// reported.
+
+// checkClockSkew estimates the clock skew of the peer which published the
+// given metric, records it and warns when the peer becomes (or stops
+// being) skewed.
+func (mon *Monitor) checkClockSkew(ctx context.Context, m *api.Metric, receivedAt time.Time) {
+	if mon.config.ClockSkewThreshold == 0 || m.SentAt == 0 {
+		return
+	}
+
+	skew := time.Unix(0, m.SentAt).Sub(receivedAt)
+	stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{tag.Upsert(observations.RemotePeerKey, m.Peer.Pretty())},
+		observations.ClockSkew.M(float64(skew)/float64(time.Millisecond)),
+	)
+
+	skewed := skew > mon.config.ClockSkewThreshold || skew < -mon.config.ClockSkewThreshold
+
+	mon.skewMux.Lock()
+	wasSkewed := mon.skewed[m.Peer]
+	if skewed {
+		mon.skewed[m.Peer] = true
+	} else {
+		delete(mon.skewed, m.Peer)
+	}
+	mon.skewMux.Unlock()
+
+	switch {
+	case skewed && !wasSkewed:
+		logger.Warningf(
+			"the clock of peer %s is off by %s (threshold %s). Metric expiry and failure detection will not work correctly: make sure the clocks of all peers are synchronized",
+			m.Peer,
+			skew.Round(time.Millisecond),
+			mon.config.ClockSkewThreshold,
+		)
+	case !skewed && wasSkewed:
+		logger.Infof("the clock of peer %s is no longer skewed", m.Peer)
+	}
+}
+
+// SkewedPeers returns the peers whose clocks were skewed when their last
+// metric was received.
+func (mon *Monitor) SkewedPeers() []peer.ID {
+	mon.skewMux.Lock()
+	defer mon.skewMux.Unlock()
+
+	peers := make([]peer.ID, 0, len(mon.skewed))
+	for p := range mon.skewed {
+		peers = append(peers, p)
+	}
+	return peers
+}
@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 
 	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
 
 	"github.com/ipfs/ipfs-cluster/config"
 )
@@ -23,6 +25,8 @@ const (
 	DefaultEnableStats        = false
 	DefaultPrometheusEndpoint = "/ip4/0.0.0.0/tcp/8888"
 	DefaultReportingInterval  = 2 * time.Second
+	DefaultPushPrefix         = "ipfscluster"
+	DefaultPushInterval       = 10 * time.Second
 
 	DefaultEnableTracing       = false
 	DefaultJaegerAgentEndpoint = "/ip4/0.0.0.0/udp/6831"
@@ -37,12 +41,25 @@ type MetricsConfig struct {
 	EnableStats        bool
 	PrometheusEndpoint ma.Multiaddr
 	ReportingInterval  time.Duration
+
+	// StatsdEndpoint (UDP) and GraphiteEndpoint (TCP, plaintext
+	// protocol), when set, receive the latest value of every metric
+	// every PushInterval, in addition to the Prometheus endpoint.
+	// Metric names start with PushPrefix.
+	StatsdEndpoint   ma.Multiaddr
+	GraphiteEndpoint ma.Multiaddr
+	PushPrefix       string
+	PushInterval     time.Duration
 }
 
 type jsonMetricsConfig struct {
 	EnableStats        bool   `json:"enable_stats"`
 	PrometheusEndpoint string `json:"prometheus_endpoint"`
 	ReportingInterval  string `json:"reporting_interval"`
+	StatsdEndpoint     string `json:"statsd_endpoint,omitempty"`
+	GraphiteEndpoint   string `json:"graphite_endpoint,omitempty"`
+	PushPrefix         string `json:"push_prefix,omitempty"`
+	PushInterval       string `json:"push_interval,omitempty"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -56,6 +73,10 @@ func (cfg *MetricsConfig) Default() error {
 	endpointAddr, _ := ma.NewMultiaddr(DefaultPrometheusEndpoint)
 	cfg.PrometheusEndpoint = endpointAddr
 	cfg.ReportingInterval = DefaultReportingInterval
+	cfg.StatsdEndpoint = nil
+	cfg.GraphiteEndpoint = nil
+	cfg.PushPrefix = DefaultPushPrefix
+	cfg.PushInterval = DefaultPushInterval
 
 	return nil
 }
@@ -83,6 +104,29 @@ func (cfg *MetricsConfig) Validate() error {
 		if cfg.ReportingInterval < 0 {
 			return errors.New("metrics.reporting_interval is invalid")
 		}
+		if err := validatePushEndpoint("statsd_endpoint", cfg.StatsdEndpoint, "udp"); err != nil {
+			return err
+		}
+		if err := validatePushEndpoint("graphite_endpoint", cfg.GraphiteEndpoint, "tcp"); err != nil {
+			return err
+		}
+		if (cfg.StatsdEndpoint != nil || cfg.GraphiteEndpoint != nil) && cfg.PushInterval <= 0 {
+			return errors.New("metrics.push_interval is invalid")
+		}
+	}
+	return nil
+}
+
+func validatePushEndpoint(name string, addr ma.Multiaddr, transport string) error {
+	if addr == nil {
+		return nil
+	}
+	network, _, err := manet.DialArgs(addr)
+	if err != nil {
+		return fmt.Errorf("metrics.%s is invalid: %s", name, err)
+	}
+	if !strings.HasPrefix(network, transport) {
+		return fmt.Errorf("metrics.%s should be a %s address", name, transport)
 	}
 	return nil
 }
@@ -119,6 +163,20 @@ func (cfg *MetricsConfig) loadMetricsOptions(jcfg *jsonMetricsConfig) error {
 	}
 	cfg.PrometheusEndpoint = endpointAddr
 
+	if jcfg.StatsdEndpoint != "" {
+		cfg.StatsdEndpoint, err = ma.NewMultiaddr(jcfg.StatsdEndpoint)
+		if err != nil {
+			return fmt.Errorf("loadMetricsOptions: StatsdEndpoint multiaddr: %v", err)
+		}
+	}
+	if jcfg.GraphiteEndpoint != "" {
+		cfg.GraphiteEndpoint, err = ma.NewMultiaddr(jcfg.GraphiteEndpoint)
+		if err != nil {
+			return fmt.Errorf("loadMetricsOptions: GraphiteEndpoint multiaddr: %v", err)
+		}
+	}
+	config.SetIfNotDefault(jcfg.PushPrefix, &cfg.PushPrefix)
+
 	return config.ParseDurations(
 		metricsConfigKey,
 		&config.DurationOpt{
@@ -126,6 +184,11 @@ func (cfg *MetricsConfig) loadMetricsOptions(jcfg *jsonMetricsConfig) error {
 			Dst:      &cfg.ReportingInterval,
 			Name:     "metrics.reporting_interval",
 		},
+		&config.DurationOpt{
+			Duration: jcfg.PushInterval,
+			Dst:      &cfg.PushInterval,
+			Name:     "metrics.push_interval",
+		},
 	)
 }
 
@@ -137,11 +200,20 @@ func (cfg *MetricsConfig) ToJSON() ([]byte, error) {
 }
 
 func (cfg *MetricsConfig) toJSONConfig() *jsonMetricsConfig {
-	return &jsonMetricsConfig{
+	jcfg := &jsonMetricsConfig{
 		EnableStats:        cfg.EnableStats,
 		PrometheusEndpoint: cfg.PrometheusEndpoint.String(),
 		ReportingInterval:  cfg.ReportingInterval.String(),
+		PushPrefix:         cfg.PushPrefix,
+		PushInterval:       cfg.PushInterval.String(),
+	}
+	if cfg.StatsdEndpoint != nil {
+		jcfg.StatsdEndpoint = cfg.StatsdEndpoint.String()
+	}
+	if cfg.GraphiteEndpoint != nil {
+		jcfg.GraphiteEndpoint = cfg.GraphiteEndpoint.String()
 	}
+	return jcfg
 }
 
 // TracingConfig configures tracing.
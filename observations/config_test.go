@@ -3,6 +3,7 @@ package observations
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestApplyEnvVars(t *testing.T) {
@@ -24,3 +25,40 @@ func TestApplyEnvVars(t *testing.T) {
 		t.Fatal("failed to override enable_tracing with env var")
 	}
 }
+
+func TestMetricsConfigPush(t *testing.T) {
+	cfg := &MetricsConfig{}
+	cfg.Default()
+	cfg.EnableStats = true
+
+	jcfg := cfg.toJSONConfig()
+	jcfg.StatsdEndpoint = "/ip4/127.0.0.1/udp/8125"
+	jcfg.GraphiteEndpoint = "/ip4/127.0.0.1/tcp/2003"
+	jcfg.PushPrefix = "cluster1"
+	jcfg.PushInterval = "30s"
+	err := cfg.applyJSONConfig(jcfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.StatsdEndpoint == nil || cfg.GraphiteEndpoint == nil {
+		t.Error("expected push endpoints to be set")
+	}
+	if cfg.PushPrefix != "cluster1" || cfg.PushInterval != 30*time.Second {
+		t.Error("expected push_prefix and push_interval to be set")
+	}
+
+	jcfg = cfg.toJSONConfig()
+	jcfg.StatsdEndpoint = "/ip4/127.0.0.1/tcp/8125"
+	err = cfg.applyJSONConfig(jcfg)
+	if err == nil {
+		t.Error("expected error with a tcp statsd_endpoint")
+	}
+
+	jcfg = cfg.toJSONConfig()
+	jcfg.StatsdEndpoint = "/ip4/127.0.0.1/udp/8125"
+	jcfg.GraphiteEndpoint = "/ip4/127.0.0.1/udp/2003"
+	err = cfg.applyJSONConfig(jcfg)
+	if err == nil {
+		t.Error("expected error with a udp graphite_endpoint")
+	}
+}
@@ -15,8 +15,41 @@ var (
 	latencyDistribution      = view.Distribution(0, 0.01, 0.05, 0.1, 0.3, 0.6, 0.8, 1, 2, 3, 4, 5, 6, 8, 10, 13, 16, 20, 25, 30, 40, 50, 65, 80, 100, 130, 160, 200, 250, 300, 400, 500, 650, 800, 1000, 2000, 5000, 10000, 20000, 50000, 100000)
 	bytesDistribution        = view.Distribution(0, 24, 32, 64, 128, 256, 512, 1024, 2048, 4096, 16384, 65536, 262144, 1048576)
 	messageCountDistribution = view.Distribution(1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536)
+	// pins can take from milliseconds to days (in ms).
+	pinLatencyDistribution = view.Distribution(0, 100, 500, 1000, 5000, 10000, 30000, 60000, 300000, 600000, 1800000, 3600000, 7200000, 21600000, 43200000, 86400000)
 )
 
+// dagSizeBuckets are the upper bounds (in bytes) of the DAG size buckets
+// used to tag pin latency measurements.
+var dagSizeBuckets = []struct {
+	limit uint64
+	label string
+}{
+	{1 << 20, "1MiB"},
+	{16 << 20, "16MiB"},
+	{128 << 20, "128MiB"},
+	{1 << 30, "1GiB"},
+	{8 << 30, "8GiB"},
+	{64 << 30, "64GiB"},
+	{512 << 30, "512GiB"},
+}
+
+// DAGSizeUnknown is the DAGSizeKey value used when the size of the pinned
+// DAG could not be obtained.
+const DAGSizeUnknown = "unknown"
+
+// DAGSizeBucket returns the DAGSizeKey tag value corresponding to the
+// given DAG size in bytes. Values look like "<=16MiB" and are suitable
+// for Prometheus labels.
+func DAGSizeBucket(size uint64) string {
+	for _, b := range dagSizeBuckets {
+		if size <= b.limit {
+			return "<=" + b.label
+		}
+	}
+	return ">" + dagSizeBuckets[len(dagSizeBuckets)-1].label
+}
+
 // attributes
 var (
 	ClientIPAttribute = "http.client.ip"
@@ -26,6 +59,13 @@ var (
 var (
 	HostKey       = makeKey("host")
 	RemotePeerKey = makeKey("remote_peer")
+	DAGSizeKey    = makeKey("dag_size")
+	OperationKey  = makeKey("operation")
+	ResultKey     = makeKey("result")
+	APIKey        = makeKey("api")
+	ReasonKey     = makeKey("reason")
+	DirectionKey  = makeKey("direction")
+	StatusKey     = makeKey("status")
 )
 
 // metrics
@@ -38,6 +78,50 @@ var (
 	Peers = stats.Int64("cluster/peers", "Number of cluster peers", stats.UnitDimensionless)
 	// Alerts is the number of alerts that have been sent due to peers not sending "ping" heartbeats in time.
 	Alerts = stats.Int64("cluster/alerts", "Number of alerts triggered", stats.UnitDimensionless)
+	// PinLatency is the time taken by IPFS to pin a DAG. Measurements are
+	// tagged with the DAG size bucket (see DAGSizeBucket).
+	PinLatency = stats.Float64("ipfsconn/pin_latency", "Time taken to pin a DAG, by DAG size", stats.UnitMilliseconds)
+	// AddedBytes is the amount of block data added to the cluster. Its
+	// rate is the add throughput.
+	AddedBytes = stats.Int64("adder/added_bytes", "Bytes of blocks added", stats.UnitBytes)
+	// AddsInFlight is the number of adds in progress.
+	AddsInFlight = stats.Int64("adder/adds_in_flight", "Number of adds in progress", stats.UnitDimensionless)
+	// AddDroppedProgress counts the add progress updates dropped because
+	// the client was not reading them fast enough.
+	AddDroppedProgress = stats.Int64("adder/dropped_progress", "Number of add progress updates dropped", stats.UnitDimensionless)
+	// ClockSkew is the estimated offset of the clock of a remote peer,
+	// measured when receiving its metrics. It includes the delivery delay.
+	ClockSkew = stats.Float64("monitor/clock_skew", "Estimated clock offset of a remote peer", stats.UnitMilliseconds)
+	// ForwardedOps counts the consensus operations that a follower
+	// forwarded to the leader, tagged by operation and result.
+	ForwardedOps = stats.Int64("consensus/forwarded_ops", "Number of operations forwarded to the leader", stats.UnitDimensionless)
+	// ForwardLatency is the time taken by the leader to commit a
+	// forwarded operation, including the wait for a forwarding slot.
+	ForwardLatency = stats.Float64("consensus/forward_latency", "Time taken to commit a forwarded operation", stats.UnitMilliseconds)
+	// Goroutines is the number of goroutines of the peer process.
+	Goroutines = stats.Int64("runtime/goroutines", "Number of goroutines", stats.UnitDimensionless)
+	// HeapAlloc is the amount of heap memory in use by the peer process.
+	HeapAlloc = stats.Int64("runtime/heap_alloc", "Bytes of allocated heap objects", stats.UnitBytes)
+	// GCPause is the duration of the last garbage collection pause.
+	GCPause = stats.Float64("runtime/gc_pause", "Duration of the last GC pause", stats.UnitMilliseconds)
+	// OpenFDs is the number of file descriptors open by the peer process.
+	OpenFDs = stats.Int64("runtime/open_fds", "Number of open file descriptors", stats.UnitDimensionless)
+	// RejectedRequests counts the API requests refused because their
+	// body was too large or was sent too slowly, tagged by API and
+	// reason.
+	RejectedRequests = stats.Int64("api/rejected_requests", "Number of API requests rejected by the request limits", stats.UnitDimensionless)
+	// BlockTransfers counts the block transfers with other cluster
+	// peers, tagged by direction ("sent" or "received") and result.
+	BlockTransfers = stats.Int64("cluster/block_transfers", "Number of block transfers with other cluster peers", stats.UnitDimensionless)
+	// BlockTransferBytes is the amount of block data exchanged directly
+	// with other cluster peers, tagged by direction and remote peer.
+	BlockTransferBytes = stats.Int64("cluster/block_transfer_bytes", "Bytes of blocks transferred with other cluster peers", stats.UnitBytes)
+	// TrackerOperations is the number of pintracker operations which
+	// are queued, in progress or in error, tagged by status.
+	TrackerOperations = stats.Int64("pintracker/operations", "Number of pin and unpin operations", stats.UnitDimensionless)
+	// TrackerOperationAge is how long the oldest pintracker operation
+	// of each status has been in it, tagged by status.
+	TrackerOperationAge = stats.Float64("pintracker/oldest_operation_age", "Age of the oldest pin or unpin operation", stats.UnitMilliseconds)
 )
 
 // views, which is just the aggregation of the metrics
@@ -66,11 +150,123 @@ var (
 		Aggregation: messageCountDistribution,
 	}
 
+	PinLatencyView = &view.View{
+		Measure:     PinLatency,
+		TagKeys:     []tag.Key{HostKey, DAGSizeKey},
+		Aggregation: pinLatencyDistribution,
+	}
+
+	AddedBytesView = &view.View{
+		Measure:     AddedBytes,
+		TagKeys:     []tag.Key{HostKey},
+		Aggregation: view.Sum(),
+	}
+
+	AddsInFlightView = &view.View{
+		Measure:     AddsInFlight,
+		TagKeys:     []tag.Key{HostKey},
+		Aggregation: view.LastValue(),
+	}
+
+	AddDroppedProgressView = &view.View{
+		Measure:     AddDroppedProgress,
+		TagKeys:     []tag.Key{HostKey},
+		Aggregation: view.Sum(),
+	}
+
+	ClockSkewView = &view.View{
+		Measure:     ClockSkew,
+		TagKeys:     []tag.Key{HostKey, RemotePeerKey},
+		Aggregation: view.LastValue(),
+	}
+
+	ForwardedOpsView = &view.View{
+		Measure:     ForwardedOps,
+		TagKeys:     []tag.Key{HostKey, OperationKey, ResultKey},
+		Aggregation: view.Count(),
+	}
+
+	ForwardLatencyView = &view.View{
+		Measure:     ForwardLatency,
+		TagKeys:     []tag.Key{HostKey, OperationKey},
+		Aggregation: latencyDistribution,
+	}
+
+	GoroutinesView = &view.View{
+		Measure:     Goroutines,
+		TagKeys:     []tag.Key{HostKey},
+		Aggregation: view.LastValue(),
+	}
+
+	HeapAllocView = &view.View{
+		Measure:     HeapAlloc,
+		TagKeys:     []tag.Key{HostKey},
+		Aggregation: view.LastValue(),
+	}
+
+	GCPauseView = &view.View{
+		Measure:     GCPause,
+		TagKeys:     []tag.Key{HostKey},
+		Aggregation: view.LastValue(),
+	}
+
+	OpenFDsView = &view.View{
+		Measure:     OpenFDs,
+		TagKeys:     []tag.Key{HostKey},
+		Aggregation: view.LastValue(),
+	}
+
+	RejectedRequestsView = &view.View{
+		Measure:     RejectedRequests,
+		TagKeys:     []tag.Key{HostKey, APIKey, ReasonKey},
+		Aggregation: view.Count(),
+	}
+
+	BlockTransfersView = &view.View{
+		Measure:     BlockTransfers,
+		TagKeys:     []tag.Key{HostKey, DirectionKey, ResultKey},
+		Aggregation: view.Count(),
+	}
+
+	BlockTransferBytesView = &view.View{
+		Measure:     BlockTransferBytes,
+		TagKeys:     []tag.Key{HostKey, DirectionKey, RemotePeerKey},
+		Aggregation: view.Sum(),
+	}
+
+	TrackerOperationsView = &view.View{
+		Measure:     TrackerOperations,
+		TagKeys:     []tag.Key{HostKey, StatusKey},
+		Aggregation: view.LastValue(),
+	}
+
+	TrackerOperationAgeView = &view.View{
+		Measure:     TrackerOperationAge,
+		TagKeys:     []tag.Key{HostKey, StatusKey},
+		Aggregation: view.LastValue(),
+	}
+
 	DefaultViews = []*view.View{
 		PinsView,
 		TrackerPinsView,
 		PeersView,
 		AlertsView,
+		PinLatencyView,
+		AddedBytesView,
+		AddsInFlightView,
+		AddDroppedProgressView,
+		ClockSkewView,
+		ForwardedOpsView,
+		ForwardLatencyView,
+		GoroutinesView,
+		HeapAllocView,
+		GCPauseView,
+		OpenFDsView,
+		RejectedRequestsView,
+		BlockTransfersView,
+		BlockTransferBytesView,
+		TrackerOperationsView,
+		TrackerOperationAgeView,
 	}
 )
 
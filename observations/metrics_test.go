@@ -0,0 +1,36 @@
+package observations
+
+import "testing"
+
+func TestDAGSizeBucket(t *testing.T) {
+	cases := []struct {
+		size uint64
+		want string
+	}{
+		{0, "<=1MiB"},
+		{1 << 20, "<=1MiB"},
+		{1<<20 + 1, "<=16MiB"},
+		{100 << 20, "<=128MiB"},
+		{2 << 30, "<=8GiB"},
+		{1 << 40, ">512GiB"},
+	}
+
+	for _, c := range cases {
+		if got := DAGSizeBucket(c.size); got != c.want {
+			t.Errorf("DAGSizeBucket(%d): got %s, want %s", c.size, got, c.want)
+		}
+	}
+}
+
+func TestRuntimeStats(t *testing.T) {
+	rs := RuntimeStats()
+	if rs.Goroutines <= 0 {
+		t.Error("expected some goroutines")
+	}
+	if rs.HeapAlloc == 0 || rs.HeapSys == 0 {
+		t.Error("expected heap usage")
+	}
+	if rs.OpenFDs == 0 {
+		t.Error("expected open file descriptors or -1")
+	}
+}
@@ -0,0 +1,213 @@
+package observations
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats/view"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// pushDialTimeout limits how long to wait for connections to the
+// StatsD and Graphite endpoints.
+var pushDialTimeout = 5 * time.Second
+
+// statsdMaxPacket is the largest StatsD datagram sent, so that packets are
+// not fragmented on common networks.
+const statsdMaxPacket = 1432
+
+// pushExporter is an OpenCensus view exporter which keeps the latest value
+// of every metric and pushes them all to StatsD and/or Graphite every
+// push interval, for monitoring setups which do not scrape Prometheus.
+type pushExporter struct {
+	prefix   string
+	statsd   ma.Multiaddr
+	graphite ma.Multiaddr
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newPushExporter(cfg *MetricsConfig) *pushExporter {
+	return &pushExporter{
+		prefix:   cfg.PushPrefix,
+		statsd:   cfg.StatsdEndpoint,
+		graphite: cfg.GraphiteEndpoint,
+		values:   make(map[string]float64),
+	}
+}
+
+// ExportView implements view.Exporter.
+func (pe *pushExporter) ExportView(vd *view.Data) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	for _, row := range vd.Rows {
+		name := pe.metricName(vd.View.Name, row)
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			pe.values[name] = float64(data.Value)
+		case *view.SumData:
+			pe.values[name] = data.Value
+		case *view.LastValueData:
+			pe.values[name] = data.Value
+		case *view.DistributionData:
+			pe.values[name+".count"] = float64(data.Count)
+			pe.values[name+".mean"] = data.Mean
+			pe.values[name+".min"] = data.Min
+			pe.values[name+".max"] = data.Max
+		}
+	}
+}
+
+// metricName builds a dot-separated metric name out of the prefix, the
+// view name and the tags of the row.
+func (pe *pushExporter) metricName(viewName string, row *view.Row) string {
+	parts := []string{}
+	if pe.prefix != "" {
+		parts = append(parts, pe.prefix)
+	}
+	parts = append(parts, sanitizeMetricName(viewName))
+	for _, t := range row.Tags {
+		parts = append(parts, sanitizeMetricName(t.Key.Name()), sanitizeMetricName(t.Value))
+	}
+	return strings.Join(parts, ".")
+}
+
+// sanitizeMetricName replaces the characters which have a meaning in the
+// StatsD and Graphite protocols.
+func sanitizeMetricName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// snapshot returns the current metric names, sorted, and their values.
+func (pe *pushExporter) snapshot() ([]string, map[string]float64) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	names := make([]string, 0, len(pe.values))
+	values := make(map[string]float64, len(pe.values))
+	for name, v := range pe.values {
+		names = append(names, name)
+		values[name] = v
+	}
+	sort.Strings(names)
+	return names, values
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// statsdPackets formats the metrics as StatsD gauges, packing as many as
+// possible in every datagram.
+func statsdPackets(names []string, values map[string]float64) [][]byte {
+	var packets [][]byte
+	var buf bytes.Buffer
+	for _, name := range names {
+		line := fmt.Sprintf("%s:%s|g\n", name, formatValue(values[name]))
+		if buf.Len() > 0 && buf.Len()+len(line) > statsdMaxPacket {
+			packets = append(packets, append([]byte{}, buf.Bytes()...))
+			buf.Reset()
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		packets = append(packets, buf.Bytes())
+	}
+	return packets
+}
+
+// graphiteLines formats the metrics using the Graphite plaintext protocol.
+func graphiteLines(names []string, values map[string]float64, now time.Time) []byte {
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s %s %d\n", name, formatValue(values[name]), now.Unix())
+	}
+	return buf.Bytes()
+}
+
+func dialPushEndpoint(addr ma.Multiaddr) (net.Conn, error) {
+	network, host, err := manet.DialArgs(addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.DialTimeout(network, host, pushDialTimeout)
+}
+
+// push sends the current metrics to the configured endpoints.
+func (pe *pushExporter) push() error {
+	names, values := pe.snapshot()
+	if len(names) == 0 {
+		return nil
+	}
+
+	var errs []string
+	if pe.statsd != nil {
+		if err := pe.pushStatsd(names, values); err != nil {
+			errs = append(errs, fmt.Sprintf("statsd: %s", err))
+		}
+	}
+	if pe.graphite != nil {
+		if err := pe.pushGraphite(names, values); err != nil {
+			errs = append(errs, fmt.Sprintf("graphite: %s", err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error pushing metrics: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (pe *pushExporter) pushStatsd(names []string, values map[string]float64) error {
+	conn, err := dialPushEndpoint(pe.statsd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, p := range statsdPackets(names, values) {
+		if _, err := conn.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pe *pushExporter) pushGraphite(names []string, values map[string]float64) error {
+	conn, err := dialPushEndpoint(pe.graphite)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(pushDialTimeout))
+	_, err = conn.Write(graphiteLines(names, values, time.Now()))
+	return err
+}
+
+// run pushes the metrics every interval, forever.
+func (pe *pushExporter) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := pe.push(); err != nil {
+			logger.Warning(err)
+		}
+	}
+}
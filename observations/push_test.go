@@ -0,0 +1,119 @@
+package observations
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func testPushData(t *testing.T) *view.Data {
+	key, err := tag.NewKey("peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &view.Data{
+		View: &view.View{Name: "pins/queued"},
+		Rows: []*view.Row{
+			{
+				Tags: []tag.Tag{{Key: key, Value: "QmPeer"}},
+				Data: &view.CountData{Value: 3},
+			},
+			{
+				Data: &view.DistributionData{Count: 2, Min: 1, Max: 3, Mean: 2},
+			},
+		},
+	}
+}
+
+func TestPushExporterFormat(t *testing.T) {
+	cfg := &MetricsConfig{}
+	cfg.Default()
+	pe := newPushExporter(cfg)
+	pe.ExportView(testPushData(t))
+
+	names, values := pe.snapshot()
+	if len(names) != 5 {
+		t.Fatalf("expected 5 metrics, got %d: %s", len(names), names)
+	}
+	if v := values["ipfscluster.pins_queued.peer.QmPeer"]; v != 3 {
+		t.Errorf("unexpected count value: %f", v)
+	}
+	if v := values["ipfscluster.pins_queued.mean"]; v != 2 {
+		t.Errorf("unexpected mean value: %f", v)
+	}
+
+	packets := statsdPackets(names, values)
+	if len(packets) != 1 || !strings.Contains(string(packets[0]), "ipfscluster.pins_queued.max:3|g\n") {
+		t.Errorf("unexpected statsd packets: %q", packets)
+	}
+
+	big := make(map[string]float64)
+	var bigNames []string
+	for i := 0; i < 200; i++ {
+		name := strings.Repeat("a", 20) + string(rune('a'+i%26)) + strings.Repeat("b", i/26)
+		bigNames = append(bigNames, name)
+		big[name] = float64(i)
+	}
+	for _, p := range statsdPackets(bigNames, big) {
+		if len(p) > statsdMaxPacket {
+			t.Errorf("statsd packet too large: %d bytes", len(p))
+		}
+	}
+}
+
+func TestPushExporterPush(t *testing.T) {
+	udp, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	tcp, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+
+	cfg := &MetricsConfig{}
+	cfg.Default()
+	cfg.PushPrefix = "test"
+	cfg.StatsdEndpoint, _ = ma.NewMultiaddr("/ip4/127.0.0.1/udp/" + strings.Split(udp.LocalAddr().String(), ":")[1])
+	cfg.GraphiteEndpoint, _ = ma.NewMultiaddr("/ip4/127.0.0.1/tcp/" + strings.Split(tcp.Addr().String(), ":")[1])
+
+	graphite := make(chan string, 1)
+	go func() {
+		conn, err := tcp.Accept()
+		if err != nil {
+			graphite <- ""
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		graphite <- line
+	}()
+
+	pe := newPushExporter(cfg)
+	pe.ExportView(testPushData(t))
+	if err := pe.push(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, statsdMaxPacket)
+	n, _, err := udp.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(buf[:n]), "test.pins_queued.count:2|g\n") {
+		t.Errorf("unexpected statsd packet: %q", buf[:n])
+	}
+
+	line := <-graphite
+	if !strings.HasPrefix(line, "test.pins_queued.count 2 ") {
+		t.Errorf("unexpected graphite line: %q", line)
+	}
+}
@@ -0,0 +1,63 @@
+package observations
+
+import (
+	"context"
+	"io/ioutil"
+	"runtime"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	"go.opencensus.io/stats"
+)
+
+// RuntimeStats returns the current Go runtime stats of this process. The
+// Peer and Peername fields are left for the caller to fill in.
+func RuntimeStats() *api.RuntimeStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	var lastPause time.Duration
+	if ms.NumGC > 0 {
+		lastPause = time.Duration(ms.PauseNs[(ms.NumGC+255)%256])
+	}
+
+	return &api.RuntimeStats{
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   ms.HeapAlloc,
+		HeapSys:     ms.HeapSys,
+		NumGC:       ms.NumGC,
+		LastGCPause: lastPause,
+		OpenFDs:     openFDs(),
+	}
+}
+
+// openFDs returns the number of file descriptors open by this process or
+// -1 if it cannot be obtained (i.e. there is no /proc filesystem).
+func openFDs() int {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(fds)
+}
+
+// recordRuntimeStats records the runtime metrics every interval. It never
+// returns.
+func recordRuntimeStats(interval time.Duration) {
+	ctx := context.Background()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		rs := RuntimeStats()
+		measurements := []stats.Measurement{
+			Goroutines.M(int64(rs.Goroutines)),
+			HeapAlloc.M(int64(rs.HeapAlloc)),
+			GCPause.M(float64(rs.LastGCPause) / float64(time.Millisecond)),
+		}
+		if rs.OpenFDs >= 0 {
+			measurements = append(measurements, OpenFDs.M(int64(rs.OpenFDs)))
+		}
+		stats.Record(ctx, measurements...)
+	}
+}
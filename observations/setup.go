@@ -80,6 +80,12 @@ func setupMetrics(cfg *MetricsConfig) error {
 	view.RegisterExporter(pe)
 	view.SetReportingPeriod(cfg.ReportingInterval)
 
+	if cfg.StatsdEndpoint != nil || cfg.GraphiteEndpoint != nil {
+		push := newPushExporter(cfg)
+		view.RegisterExporter(push)
+		go push.run(cfg.PushInterval)
+	}
+
 	// register the metrics views of interest
 	if err := view.Register(DefaultViews...); err != nil {
 		return err
@@ -106,6 +112,8 @@ func setupMetrics(cfg *MetricsConfig) error {
 		return err
 	}
 
+	go recordRuntimeStats(cfg.ReportingInterval)
+
 	_, promAddr, err := manet.DialArgs(cfg.PrometheusEndpoint)
 	if err != nil {
 		return err
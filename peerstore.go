@@ -0,0 +1,100 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// The addresses learned for other peers are persisted in the datastore
+// regularly and when shutting down, and restored on start. Peers behind
+// dynamic IPs can then be found again after a restart even if the
+// addresses in the configuration are outdated. Addresses of peers not seen
+// for longer than PeerstoreTTL are forgotten.
+
+// peerstorePersistInterval is how often known peer addresses are persisted.
+var peerstorePersistInterval = time.Minute
+
+func (c *Cluster) peerstoreWatcher() {
+	ticker := time.NewTicker(peerstorePersistInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.persistPeerstore()
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cluster) persistPeerstore() {
+	err := c.peerManager.PersistPeers(c.host.Peerstore().PeersWithAddrs())
+	if err != nil {
+		logger.Errorf("error persisting peer addresses: %s", err)
+	}
+}
+
+// Peerstore returns the peers known to this peer's libp2p host, along with
+// their addresses and the last time they were seen.
+func (c *Cluster) Peerstore(ctx context.Context) []*api.KnownPeer {
+	_, span := trace.StartSpan(ctx, "cluster/Peerstore")
+	defer span.End()
+
+	return c.peerManager.KnownPeers()
+}
+
+// PeerstoreAdd adds the given addresses to the peerstore and persists them,
+// dialing the peers. Addresses must include the /p2p/ part. It returns the
+// peerstore entries for the peers involved.
+func (c *Cluster) PeerstoreAdd(ctx context.Context, addrs []api.Multiaddr) ([]*api.KnownPeer, error) {
+	_, span := trace.StartSpan(ctx, "cluster/PeerstoreAdd")
+	defer span.End()
+
+	if len(addrs) == 0 {
+		return nil, errors.New("no addresses given")
+	}
+
+	pids := make(map[peer.ID]struct{})
+	for _, addr := range addrs {
+		pid, _, err := api.Libp2pMultiaddrSplit(addr.Value())
+		if err != nil {
+			return nil, err
+		}
+		if pid == c.id {
+			return nil, errors.New("cannot add addresses for this peer")
+		}
+		pids[pid] = struct{}{}
+	}
+
+	for _, addr := range addrs {
+		err := c.peerManager.ImportPeer(addr.Value(), true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	peers := make([]peer.ID, 0, len(pids))
+	for pid := range pids {
+		peers = append(peers, pid)
+	}
+	err := c.peerManager.PersistPeers(peers)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []*api.KnownPeer
+	for _, kp := range c.peerManager.KnownPeers() {
+		if _, ok := pids[kp.ID]; ok {
+			added = append(added, kp)
+		}
+	}
+	return added, nil
+}
@@ -3,6 +3,7 @@ package maptracker
 import (
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 
@@ -16,6 +17,11 @@ const envConfigKey = "cluster_maptracker"
 const (
 	DefaultMaxPinQueueSize = 50000
 	DefaultConcurrentPins  = 10
+	// By default, completed operations are never compacted.
+	DefaultMaxCompletedOperations = 0
+	DefaultCompactCompletedAfter  = 0 * time.Second
+	// By default, operations never time out.
+	DefaultOperationTimeout = 0 * time.Second
 )
 
 // Config allows to initialize a Monitor and customize some parameters.
@@ -28,11 +34,26 @@ type Config struct {
 	// daemon in parallel. If the pinning method is "refs", it might increase
 	// speed. Unpin requests are always processed one by one.
 	ConcurrentPins int
+	// MaxCompletedOperations is the maximum number of completed (done or
+	// errored) operations kept in memory. Older ones are compacted into
+	// the datastore. 0 means no limit.
+	MaxCompletedOperations int
+	// CompactCompletedAfter is the age after which completed operations
+	// are compacted into the datastore. 0 means never.
+	CompactCompletedAfter time.Duration
+	// OperationTimeout is how long a pin or unpin operation may run
+	// before it is cancelled and set in error, so that stuck operations
+	// do not hold a worker forever. The PinTimeout of a pin overrides it.
+	// 0 means no timeout.
+	OperationTimeout time.Duration
 }
 
 type jsonConfig struct {
-	MaxPinQueueSize int `json:"max_pin_queue_size"`
-	ConcurrentPins  int `json:"concurrent_pins"`
+	MaxPinQueueSize        int    `json:"max_pin_queue_size"`
+	ConcurrentPins         int    `json:"concurrent_pins"`
+	MaxCompletedOperations int    `json:"max_completed_operations"`
+	CompactCompletedAfter  string `json:"compact_completed_after"`
+	OperationTimeout       string `json:"operation_timeout,omitempty"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -44,6 +65,9 @@ func (cfg *Config) ConfigKey() string {
 func (cfg *Config) Default() error {
 	cfg.MaxPinQueueSize = DefaultMaxPinQueueSize
 	cfg.ConcurrentPins = DefaultConcurrentPins
+	cfg.MaxCompletedOperations = DefaultMaxCompletedOperations
+	cfg.CompactCompletedAfter = DefaultCompactCompletedAfter
+	cfg.OperationTimeout = DefaultOperationTimeout
 	return nil
 }
 
@@ -71,6 +95,18 @@ func (cfg *Config) Validate() error {
 		return errors.New("maptracker.concurrent_pins is too low")
 	}
 
+	if cfg.MaxCompletedOperations < 0 {
+		return errors.New("maptracker.max_completed_operations is invalid")
+	}
+
+	if cfg.CompactCompletedAfter < 0 {
+		return errors.New("maptracker.compact_completed_after is invalid")
+	}
+
+	if cfg.OperationTimeout < 0 {
+		return errors.New("maptracker.operation_timeout is invalid")
+	}
+
 	return nil
 }
 
@@ -92,6 +128,16 @@ func (cfg *Config) LoadJSON(raw []byte) error {
 func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	config.SetIfNotDefault(jcfg.MaxPinQueueSize, &cfg.MaxPinQueueSize)
 	config.SetIfNotDefault(jcfg.ConcurrentPins, &cfg.ConcurrentPins)
+	config.SetIfNotDefault(jcfg.MaxCompletedOperations, &cfg.MaxCompletedOperations)
+
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.CompactCompletedAfter, Dst: &cfg.CompactCompletedAfter, Name: "compact_completed_after"},
+		&config.DurationOpt{Duration: jcfg.OperationTimeout, Dst: &cfg.OperationTimeout, Name: "operation_timeout"},
+	)
+	if err != nil {
+		return err
+	}
 
 	return cfg.Validate()
 }
@@ -105,7 +151,10 @@ func (cfg *Config) ToJSON() ([]byte, error) {
 
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	return &jsonConfig{
-		MaxPinQueueSize: cfg.MaxPinQueueSize,
-		ConcurrentPins:  cfg.ConcurrentPins,
+		MaxPinQueueSize:        cfg.MaxPinQueueSize,
+		ConcurrentPins:         cfg.ConcurrentPins,
+		MaxCompletedOperations: cfg.MaxCompletedOperations,
+		CompactCompletedAfter:  cfg.CompactCompletedAfter.String(),
+		OperationTimeout:       cfg.OperationTimeout.String(),
 	}
 }
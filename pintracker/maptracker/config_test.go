@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 )
 
 var cfgJSON = []byte(`
 {
       "max_pin_queue_size": 4092,
-      "concurrent_pins": 2
+      "concurrent_pins": 2,
+      "max_completed_operations": 1000,
+      "compact_completed_after": "1h"
 }
 `)
 
@@ -32,6 +35,31 @@ func TestLoadJSON(t *testing.T) {
 	if cfg.ConcurrentPins != 10 {
 		t.Error("expected 10 concurrent pins")
 	}
+	if cfg.MaxCompletedOperations != 1000 {
+		t.Error("expected 1000 max completed operations")
+	}
+	if cfg.CompactCompletedAfter != time.Hour {
+		t.Error("expected compact_completed_after to be 1h")
+	}
+
+	j.CompactCompletedAfter = "abc"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error parsing compact_completed_after")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.OperationTimeout = "10m"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OperationTimeout != 10*time.Minute {
+		t.Error("expected operation_timeout to be 10m")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -59,6 +87,12 @@ func TestDefault(t *testing.T) {
 	if cfg.Validate() == nil {
 		t.Fatal("expected error validating")
 	}
+
+	cfg.Default()
+	cfg.MaxCompletedOperations = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
 }
 
 func TestApplyEnvVars(t *testing.T) {
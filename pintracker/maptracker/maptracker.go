@@ -5,6 +5,7 @@ package maptracker
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 
 	"go.opencensus.io/trace"
@@ -14,6 +15,7 @@ import (
 	"github.com/ipfs/ipfs-cluster/pintracker/util"
 
 	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 	peer "github.com/libp2p/go-libp2p-peer"
@@ -48,15 +50,27 @@ type MapPinTracker struct {
 }
 
 // NewMapPinTracker returns a new object which has been correcly
-// initialized with the given configuration.
-func NewMapPinTracker(cfg *Config, pid peer.ID, peerName string) *MapPinTracker {
+// initialized with the given configuration. Completed operations are
+// compacted into the given datastore according to the configuration.
+func NewMapPinTracker(cfg *Config, pid peer.ID, peerName string, store ds.Datastore) *MapPinTracker {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	optrk := optracker.NewOperationTrackerWithCompaction(
+		ctx,
+		pid,
+		peerName,
+		optracker.CompactionOptions{
+			Datastore:    store,
+			MaxCompleted: cfg.MaxCompletedOperations,
+			CompactAfter: cfg.CompactCompletedAfter,
+		},
+	)
+
 	mpt := &MapPinTracker{
 		ctx:       ctx,
 		cancel:    cancel,
 		config:    cfg,
-		optracker: optracker.NewOperationTracker(ctx, pid, peerName),
+		optracker: optrk,
 		rpcReady:  make(chan struct{}, 1),
 		peerID:    pid,
 		pinCh:     make(chan *optracker.Operation, cfg.MaxPinQueueSize),
@@ -82,8 +96,13 @@ func (mpt *MapPinTracker) opWorker(ctx context.Context, pinF func(*optracker.Ope
 				continue
 			}
 			op.SetPhase(optracker.PhaseInProgress)
+			op.StartTimeout(mpt.config.OperationTimeout)
 			err := pinF(op) // call pin/unpin
 			if err != nil {
+				if d, ok := op.TimedOut(); ok {
+					op.SetError(fmt.Errorf("operation timed out after %s: %s", d, err))
+					continue
+				}
 				if op.Cancelled() {
 					// there was an error because
 					// we were cancelled. Move on.
@@ -240,19 +259,53 @@ func (mpt *MapPinTracker) Untrack(ctx context.Context, c cid.Cid) error {
 // Status returns information for a Cid tracked by this
 // MapPinTracker.
 func (mpt *MapPinTracker) Status(ctx context.Context, c cid.Cid) *api.PinInfo {
-	ctx, span := trace.StartSpan(mpt.ctx, "tracker/map/Status")
+	ctx, span := trace.StartSpan(ctx, "tracker/map/Status")
 	defer span.End()
 
-	return mpt.optracker.Get(ctx, c)
+	pinfo := mpt.optracker.Get(ctx, c)
+	err := util.AttachBitswapStat(ctx, mpt.rpcClient, pinfo)
+	if err != nil {
+		logger.Debugf("error obtaining bitswap stats: %s", err)
+	}
+	err = util.AttachPinProgress(ctx, mpt.rpcClient, pinfo)
+	if err != nil {
+		logger.Debugf("error obtaining pin progress: %s", err)
+	}
+	return pinfo
 }
 
 // StatusAll returns information for all Cids tracked by this
-// MapPinTracker.
-func (mpt *MapPinTracker) StatusAll(ctx context.Context) []*api.PinInfo {
-	ctx, span := trace.StartSpan(mpt.ctx, "tracker/map/StatusAll")
+// MapPinTracker which match the given filter.
+func (mpt *MapPinTracker) StatusAll(ctx context.Context, filter api.TrackerStatus) []*api.PinInfo {
+	ctx, span := trace.StartSpan(ctx, "tracker/map/StatusAll")
 	defer span.End()
 
-	return mpt.optracker.GetAll(ctx)
+	all := mpt.optracker.GetAll(ctx)
+	pinfos := all
+	if filter != api.TrackerStatusUndefined {
+		pinfos = nil
+		for _, pinfo := range all {
+			if pinfo.Status.Match(filter) {
+				pinfos = append(pinfos, pinfo)
+			}
+		}
+	}
+
+	err := util.AttachBitswapStat(ctx, mpt.rpcClient, pinfos...)
+	if err != nil {
+		logger.Debugf("error obtaining bitswap stats: %s", err)
+	}
+	err = util.AttachPinProgress(ctx, mpt.rpcClient, pinfos...)
+	if err != nil {
+		logger.Debugf("error obtaining pin progress: %s", err)
+	}
+	return pinfos
+}
+
+// OperationCounts returns the number of queued and errored operations.
+func (mpt *MapPinTracker) OperationCounts(ctx context.Context) (int, int) {
+	return mpt.optracker.PhaseCount(ctx, optracker.PhaseQueued),
+		mpt.optracker.PhaseCount(ctx, optracker.PhaseError)
 }
 
 // Sync verifies that the status of a Cid matches that of
@@ -264,11 +317,12 @@ func (mpt *MapPinTracker) StatusAll(ctx context.Context) []*api.PinInfo {
 // An error is returned if we are unable to contact
 // the IPFS daemon.
 func (mpt *MapPinTracker) Sync(ctx context.Context, c cid.Cid) (*api.PinInfo, error) {
-	ctx, span := trace.StartSpan(mpt.ctx, "tracker/map/Sync")
+	ctx, span := trace.StartSpan(ctx, "tracker/map/Sync")
 	defer span.End()
 
 	var ips api.IPFSPinStatus
-	err := mpt.rpcClient.Call(
+	err := mpt.rpcClient.CallContext(
+		ctx,
 		"",
 		"IPFSConnector",
 		"PinLsCid",
@@ -293,16 +347,17 @@ func (mpt *MapPinTracker) Sync(ctx context.Context, c cid.Cid) (*api.PinInfo, er
 // with Recover().
 // An error is returned if we are unable to contact the IPFS daemon.
 func (mpt *MapPinTracker) SyncAll(ctx context.Context) ([]*api.PinInfo, error) {
-	ctx, span := trace.StartSpan(mpt.ctx, "tracker/map/SyncAll")
+	ctx, span := trace.StartSpan(ctx, "tracker/map/SyncAll")
 	defer span.End()
 
 	var ipsMap map[string]api.IPFSPinStatus
 	var results []*api.PinInfo
-	err := mpt.rpcClient.Call(
+	err := mpt.rpcClient.CallContext(
+		ctx,
 		"",
 		"IPFSConnector",
 		"PinLs",
-		"recursive",
+		"recursive,direct",
 		&ipsMap,
 	)
 	if err != nil {
@@ -321,7 +376,7 @@ func (mpt *MapPinTracker) SyncAll(ctx context.Context) ([]*api.PinInfo, error) {
 		return results, nil
 	}
 
-	status := mpt.StatusAll(ctx)
+	status := mpt.StatusAll(ctx, api.TrackerStatusUndefined)
 	for _, pInfoOrig := range status {
 		var pInfoNew *api.PinInfo
 		c := pInfoOrig.Cid
@@ -404,7 +459,7 @@ func (mpt *MapPinTracker) syncStatus(ctx context.Context, c cid.Cid, ips api.IPF
 // Recover will re-queue a Cid in error state for the failed operation,
 // possibly retriggering an IPFS pinning operation.
 func (mpt *MapPinTracker) Recover(ctx context.Context, c cid.Cid) (*api.PinInfo, error) {
-	ctx, span := trace.StartSpan(mpt.ctx, "tracker/map/Recover")
+	ctx, span := trace.StartSpan(ctx, "tracker/map/Recover")
 	defer span.End()
 
 	logger.Infof("Attempting to recover %s", c)
@@ -413,7 +468,27 @@ func (mpt *MapPinTracker) Recover(ctx context.Context, c cid.Cid) (*api.PinInfo,
 
 	switch pInfo.Status {
 	case api.TrackerStatusPinError:
-		err = mpt.enqueue(ctx, api.PinCid(c), optracker.OperationPin, mpt.pinCh)
+		// re-pin the item as it is in the shared state, so that its
+		// options (max depth, priority, timeout...) are respected.
+		var gpin api.Pin
+		err = mpt.rpcClient.CallContext(
+			ctx,
+			"",
+			"Cluster",
+			"PinGet",
+			c,
+			&gpin,
+		)
+		if err != nil {
+			if rpc.IsRPCError(err) {
+				logger.Error(err)
+				return mpt.optracker.Get(ctx, c), err
+			}
+			// it isn't in the global state: nothing to recover
+			mpt.optracker.CleanError(ctx, c)
+			return mpt.optracker.Get(ctx, c), nil
+		}
+		err = mpt.enqueue(ctx, &gpin, optracker.OperationPin, mpt.pinCh)
 	case api.TrackerStatusUnpinError:
 		err = mpt.enqueue(ctx, api.PinCid(c), optracker.OperationUnpin, mpt.unpinCh)
 	}
@@ -422,7 +497,7 @@ func (mpt *MapPinTracker) Recover(ctx context.Context, c cid.Cid) (*api.PinInfo,
 
 // RecoverAll attempts to recover all items tracked by this peer.
 func (mpt *MapPinTracker) RecoverAll(ctx context.Context) ([]*api.PinInfo, error) {
-	ctx, span := trace.StartSpan(mpt.ctx, "tracker/map/RecoverAll")
+	ctx, span := trace.StartSpan(ctx, "tracker/map/RecoverAll")
 	defer span.End()
 
 	pInfos := mpt.optracker.GetAll(ctx)
@@ -13,6 +13,7 @@ import (
 	peer "github.com/libp2p/go-libp2p-peer"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
 	"github.com/ipfs/ipfs-cluster/test"
 )
 
@@ -67,7 +68,7 @@ func testSlowMapPinTracker(t *testing.T) *MapPinTracker {
 	cfg := &Config{}
 	cfg.Default()
 	cfg.ConcurrentPins = 1
-	mpt := NewMapPinTracker(cfg, test.PeerID1, test.PeerName1)
+	mpt := NewMapPinTracker(cfg, test.PeerID1, test.PeerName1, inmem.New())
 	mpt.SetClient(mockRPCClient(t))
 	return mpt
 }
@@ -76,7 +77,7 @@ func testMapPinTracker(t *testing.T) *MapPinTracker {
 	cfg := &Config{}
 	cfg.Default()
 	cfg.ConcurrentPins = 1
-	mpt := NewMapPinTracker(cfg, test.PeerID1, test.PeerName1)
+	mpt := NewMapPinTracker(cfg, test.PeerID1, test.PeerName1, inmem.New())
 	mpt.SetClient(test.NewMockRPCClient(t))
 	return mpt
 }
@@ -204,7 +205,7 @@ func TestStatusAll(t *testing.T) {
 
 	time.Sleep(200 * time.Millisecond)
 
-	stAll := mpt.StatusAll(context.Background())
+	stAll := mpt.StatusAll(context.Background(), api.TrackerStatusUndefined)
 	if len(stAll) != 2 {
 		t.Logf("%+v", stAll)
 		t.Fatal("expected 2 pins")
@@ -300,6 +301,25 @@ func TestRecoverAll(t *testing.T) {
 	}
 }
 
+func TestRecoverNotInState(t *testing.T) {
+	ctx := context.Background()
+	mpt := testMapPinTracker(t)
+	defer mpt.Shutdown(ctx)
+
+	// Cid4 is not part of the shared state
+	mpt.Track(ctx, testPin(test.Cid4, -1, -1))
+	time.Sleep(100 * time.Millisecond)
+	mpt.optracker.SetError(ctx, test.Cid4, errors.New("fakeerror"))
+
+	info, err := mpt.Recover(ctx, test.Cid4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Status != api.TrackerStatusUnpinned {
+		t.Errorf("expected unpinned, got %s", info.Status)
+	}
+}
+
 func TestSyncAll(t *testing.T) {
 	ctx := context.Background()
 	mpt := testMapPinTracker(t)
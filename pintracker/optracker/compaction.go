@@ -0,0 +1,243 @@
+package optracker
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	namespace "github.com/ipfs/go-datastore/namespace"
+	query "github.com/ipfs/go-datastore/query"
+	dssync "github.com/ipfs/go-datastore/sync"
+)
+
+// CompactionNamespace is the datastore namespace under which completed
+// operations are compacted.
+var CompactionNamespace = "/optracker"
+
+// DefaultCompactionInterval specifies how often completed operations are
+// checked for compaction.
+var DefaultCompactionInterval = time.Minute
+
+// CompactionOptions control how many completed (done or errored)
+// operations an OperationTracker keeps in memory. Completed operations
+// over the limits are moved to a datastore, where they can still be
+// queried, but without the associated memory overhead.
+type CompactionOptions struct {
+	// Datastore where completed operations are compacted. An
+	// in-memory datastore is used when unset.
+	Datastore ds.Datastore
+	// MaxCompleted is the maximum number of completed operations kept
+	// in memory. The oldest ones are compacted first. 0 means no limit.
+	MaxCompleted int
+	// CompactAfter is the age after which completed operations are
+	// compacted. 0 disables age-based compaction.
+	CompactAfter time.Duration
+	// Interval specifies how often compaction runs. Defaults to
+	// DefaultCompactionInterval.
+	Interval time.Duration
+}
+
+func (opts CompactionOptions) enabled() bool {
+	return opts.MaxCompleted > 0 || opts.CompactAfter > 0
+}
+
+// compactedOp is the datastore representation of a completed Operation.
+type compactedOp struct {
+	Cid   cid.Cid       `json:"cid"`
+	Type  OperationType `json:"type"`
+	Phase Phase         `json:"phase"`
+	Error string        `json:"error,omitempty"`
+	TS    time.Time     `json:"ts"`
+}
+
+// toOperation returns a completed Operation from a compacted one. It is
+// not cancellable, as it is not in flight anymore.
+func (cop *compactedOp) toOperation(ctx context.Context) *Operation {
+	return &Operation{
+		ctx:    ctx,
+		cancel: func() {},
+		opType: cop.Type,
+		pin:    api.PinCid(cop.Cid),
+		phase:  cop.Phase,
+		error:  cop.Error,
+		ts:     cop.TS,
+	}
+}
+
+func compactedKey(c cid.Cid) ds.Key {
+	return ds.NewKey(c.String())
+}
+
+// initCompaction sets up the compaction datastore, removing anything
+// left by previous runs (the tracker state is not persistent), and
+// launches the compaction loop.
+func (opt *OperationTracker) initCompaction(opts CompactionOptions) {
+	store := opts.Datastore
+	if store == nil {
+		store = dssync.MutexWrap(ds.NewMapDatastore())
+	}
+	opt.store = namespace.Wrap(store, ds.NewKey(CompactionNamespace))
+	opt.compaction = opts
+
+	res, err := opt.store.Query(query.Query{KeysOnly: true})
+	if err != nil {
+		logger.Error(err)
+	} else {
+		entries, err := res.Rest()
+		if err != nil {
+			logger.Error(err)
+		}
+		for _, e := range entries {
+			opt.store.Delete(ds.NewKey(e.Key))
+		}
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultCompactionInterval
+	}
+	go opt.compactionLoop(interval)
+}
+
+func (opt *OperationTracker) compactionLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-opt.ctx.Done():
+			return
+		case <-ticker.C:
+			opt.Compact(opt.ctx)
+		}
+	}
+}
+
+// Compact moves completed operations over the configured limits to the
+// datastore. It is a no-op when compaction is not enabled.
+func (opt *OperationTracker) Compact(ctx context.Context) {
+	if opt.store == nil {
+		return
+	}
+
+	opt.mu.Lock()
+	defer opt.mu.Unlock()
+
+	var completed []*Operation
+	for _, op := range opt.operations {
+		if ph := op.Phase(); ph == PhaseDone || ph == PhaseError {
+			completed = append(completed, op)
+		}
+	}
+
+	// oldest first
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].Timestamp().Before(completed[j].Timestamp())
+	})
+
+	excess := 0
+	if max := opt.compaction.MaxCompleted; max > 0 && len(completed) > max {
+		excess = len(completed) - max
+	}
+
+	now := time.Now()
+	compacted := 0
+	for i, op := range completed {
+		tooOld := opt.compaction.CompactAfter > 0 && now.Sub(op.Timestamp()) > opt.compaction.CompactAfter
+		if i >= excess && !tooOld {
+			break // the rest are newer
+		}
+		err := opt.unsafePutCompacted(op)
+		if err != nil {
+			logger.Error("error compacting operation: ", err)
+			return
+		}
+		delete(opt.operations, op.Cid().String())
+		compacted++
+	}
+
+	if compacted > 0 {
+		logger.Debugf("compacted %d completed operations", compacted)
+	}
+}
+
+func (opt *OperationTracker) unsafePutCompacted(op *Operation) error {
+	cop := &compactedOp{
+		Cid:   op.Cid(),
+		Type:  op.Type(),
+		Phase: op.Phase(),
+		Error: op.Error(),
+		TS:    op.Timestamp(),
+	}
+	v, err := json.Marshal(cop)
+	if err != nil {
+		return err
+	}
+	return opt.store.Put(compactedKey(cop.Cid), v)
+}
+
+// unsafeGetCompacted returns a compacted operation for the given Cid, if
+// any.
+func (opt *OperationTracker) unsafeGetCompacted(c cid.Cid) (*Operation, bool) {
+	if opt.store == nil {
+		return nil, false
+	}
+	v, err := opt.store.Get(compactedKey(c))
+	if err != nil {
+		if err != ds.ErrNotFound {
+			logger.Error(err)
+		}
+		return nil, false
+	}
+	cop := &compactedOp{}
+	err = json.Unmarshal(v, cop)
+	if err != nil {
+		logger.Error(err)
+		return nil, false
+	}
+	return cop.toOperation(opt.ctx), true
+}
+
+// unsafeAllCompacted returns all compacted operations, indexed by Cid.
+func (opt *OperationTracker) unsafeAllCompacted() map[string]*Operation {
+	ops := make(map[string]*Operation)
+	if opt.store == nil {
+		return ops
+	}
+
+	res, err := opt.store.Query(query.Query{})
+	if err != nil {
+		logger.Error(err)
+		return ops
+	}
+	defer res.Close()
+
+	for r := range res.Next() {
+		if r.Error != nil {
+			logger.Error(r.Error)
+			break
+		}
+		cop := &compactedOp{}
+		err := json.Unmarshal(r.Value, cop)
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+		ops[cop.Cid.String()] = cop.toOperation(opt.ctx)
+	}
+	return ops
+}
+
+func (opt *OperationTracker) unsafeDeleteCompacted(c cid.Cid) {
+	if opt.store == nil {
+		return
+	}
+	err := opt.store.Delete(compactedKey(c))
+	if err != nil && err != ds.ErrNotFound {
+		logger.Error(err)
+	}
+}
@@ -0,0 +1,180 @@
+package optracker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/test"
+
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+)
+
+func testCompactingOperationTracker(ctx context.Context, opts CompactionOptions) *OperationTracker {
+	if opts.Interval == 0 {
+		opts.Interval = time.Hour // compact manually
+	}
+	return NewOperationTrackerWithCompaction(ctx, test.PeerID1, test.PeerName1, opts)
+}
+
+func inMemory(t *testing.T, opt *OperationTracker, c cid.Cid) bool {
+	opt.mu.RLock()
+	defer opt.mu.RUnlock()
+	_, ok := opt.operations[c.String()]
+	return ok
+}
+
+func TestOperationTracker_CompactMaxCompleted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := testCompactingOperationTracker(ctx, CompactionOptions{MaxCompleted: 1})
+
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseDone)
+	time.Sleep(time.Millisecond)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid2), OperationPin, PhaseDone)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid3), OperationPin, PhaseInProgress)
+
+	opt.Compact(ctx)
+
+	if inMemory(t, opt, test.Cid1) {
+		t.Error("oldest completed operation should have been compacted")
+	}
+	if !inMemory(t, opt, test.Cid2) {
+		t.Error("newest completed operation should be in memory")
+	}
+	if !inMemory(t, opt, test.Cid3) {
+		t.Error("in-progress operations are never compacted")
+	}
+
+	st, ok := opt.Status(ctx, test.Cid1)
+	if !ok || st != api.TrackerStatusPinned {
+		t.Error("compacted operation should keep its status")
+	}
+
+	pinfo, ok := opt.GetExists(ctx, test.Cid1)
+	if !ok || !pinfo.Cid.Equals(test.Cid1) {
+		t.Error("compacted operation should exist")
+	}
+
+	if len(opt.GetAll(ctx)) != 3 {
+		t.Error("expected 3 operations")
+	}
+
+	if len(opt.Filter(ctx, PhaseDone)) != 2 {
+		t.Error("expected 2 done operations")
+	}
+}
+
+func TestOperationTracker_CompactAfter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := testCompactingOperationTracker(ctx, CompactionOptions{CompactAfter: 50 * time.Millisecond})
+
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseDone)
+	opt.Compact(ctx)
+	if !inMemory(t, opt, test.Cid1) {
+		t.Fatal("operation should not have been compacted yet")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	opt.Compact(ctx)
+	if inMemory(t, opt, test.Cid1) {
+		t.Fatal("operation should have been compacted")
+	}
+}
+
+func TestOperationTracker_CompactionLoop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := testCompactingOperationTracker(ctx, CompactionOptions{
+		MaxCompleted: 1,
+		Interval:     20 * time.Millisecond,
+	})
+
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseDone)
+	time.Sleep(time.Millisecond)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid2), OperationPin, PhaseDone)
+
+	time.Sleep(100 * time.Millisecond)
+	if inMemory(t, opt, test.Cid1) {
+		t.Error("operation should have been compacted by the loop")
+	}
+}
+
+func TestOperationTracker_CompactedOperations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := dssync.MutexWrap(ds.NewMapDatastore())
+	opts := CompactionOptions{
+		Datastore:    store,
+		CompactAfter: time.Nanosecond,
+	}
+	opt := testCompactingOperationTracker(ctx, opts)
+
+	compact := func() {
+		time.Sleep(time.Millisecond)
+		opt.Compact(ctx)
+	}
+
+	t.Run("set error", func(t *testing.T) {
+		opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseDone)
+		compact()
+		opt.SetError(ctx, test.Cid1, errors.New("fake error"))
+		pinfo := opt.Get(ctx, test.Cid1)
+		if pinfo.Status != api.TrackerStatusPinError || pinfo.Error != "fake error" {
+			t.Error("compacted operation should have been set to error")
+		}
+		if inMemory(t, opt, test.Cid1) {
+			t.Error("compacted operation should stay compacted")
+		}
+	})
+
+	t.Run("clean error", func(t *testing.T) {
+		opt.CleanError(ctx, test.Cid1)
+		if _, ok := opt.GetExists(ctx, test.Cid1); ok {
+			t.Error("errored operation should have been removed")
+		}
+	})
+
+	t.Run("clean all done", func(t *testing.T) {
+		opt.TrackNewOperation(ctx, api.PinCid(test.Cid2), OperationPin, PhaseDone)
+		compact()
+		opt.CleanAllDone(ctx)
+		if _, ok := opt.GetExists(ctx, test.Cid2); ok {
+			t.Error("done operation should have been removed")
+		}
+	})
+
+	t.Run("track new operation", func(t *testing.T) {
+		opt.TrackNewOperation(ctx, api.PinCid(test.Cid3), OperationPin, PhaseDone)
+		compact()
+		opt.TrackNewOperation(ctx, api.PinCid(test.Cid3), OperationUnpin, PhaseQueued)
+		opt.Clean(ctx, opt.operations[test.Cid3.String()])
+		if _, ok := opt.GetExists(ctx, test.Cid3); ok {
+			t.Error("compacted operation should have been replaced")
+		}
+	})
+
+	t.Run("cleared on start", func(t *testing.T) {
+		opt.TrackNewOperation(ctx, api.PinCid(test.Cid4), OperationPin, PhaseDone)
+		compact()
+		opt2 := testCompactingOperationTracker(ctx, opts)
+		if _, ok := opt2.GetExists(ctx, test.Cid4); ok {
+			t.Error("compacted operations should not survive restarts")
+		}
+	})
+}
+
+func TestOperationTracker_CleanError(t *testing.T) {
+	ctx := context.Background()
+	opt := testOperationTracker(t)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseError)
+	opt.CleanError(ctx, test.Cid1)
+	if _, ok := opt.GetExists(ctx, test.Cid1); ok {
+		t.Error("errored operation should have been removed")
+	}
+}
@@ -65,6 +65,11 @@ type Operation struct {
 	phase Phase
 	error string
 	ts    time.Time
+
+	// set by StartTimeout
+	timer    *time.Timer
+	timeout  time.Duration
+	timedOut bool
 }
 
 // NewOperation creates a new Operation.
@@ -120,9 +125,47 @@ func (op *Operation) Cancel() {
 	ctx, span := trace.StartSpan(op.ctx, "optracker/Cancel")
 	_ = ctx
 	defer span.End()
+	op.mu.Lock()
+	if op.timer != nil {
+		op.timer.Stop()
+	}
+	op.mu.Unlock()
 	op.cancel()
 }
 
+// StartTimeout makes the operation cancel itself, as timed out, unless it
+// is cancelled within the given duration. The PinTimeout of the pin takes
+// precedence when set. A duration of 0 means no timeout.
+func (op *Operation) StartTimeout(d time.Duration) {
+	if op.pin.PinTimeout > 0 {
+		d = op.pin.PinTimeout
+	}
+	if d <= 0 {
+		return
+	}
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if op.timer != nil {
+		op.timer.Stop()
+	}
+	op.timeout = d
+	op.timer = time.AfterFunc(d, func() {
+		op.mu.Lock()
+		op.timedOut = true
+		op.mu.Unlock()
+		op.cancel()
+	})
+}
+
+// TimedOut returns whether the operation was cancelled because it timed
+// out, along with its timeout.
+func (op *Operation) TimedOut() (time.Duration, bool) {
+	op.mu.RLock()
+	defer op.mu.RUnlock()
+	return op.timeout, op.timedOut
+}
+
 // Phase returns the Phase.
 func (op *Operation) Phase() Phase {
 	op.mu.RLock()
@@ -51,3 +51,30 @@ func TestOperation(t *testing.T) {
 		t.Error("should be in unpin error")
 	}
 }
+
+func TestOperationTimeout(t *testing.T) {
+	op := NewOperation(context.Background(), api.PinCid(test.Cid1), OperationPin, PhaseInProgress)
+	op.StartTimeout(50 * time.Millisecond)
+	select {
+	case <-op.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the operation to time out")
+	}
+	if d, ok := op.TimedOut(); !ok || d != 50*time.Millisecond {
+		t.Errorf("expected the operation to be timed out after 50ms: %s %t", d, ok)
+	}
+
+	// The timeout of the pin takes precedence.
+	pin := api.PinCid(test.Cid2)
+	pin.PinTimeout = time.Hour
+	op = NewOperation(context.Background(), pin, OperationPin, PhaseInProgress)
+	op.StartTimeout(50 * time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := op.TimedOut(); ok || op.Cancelled() {
+		t.Error("the operation should not have timed out")
+	}
+	op.Cancel()
+	if _, ok := op.TimedOut(); ok {
+		t.Error("a cancelled operation should not time out")
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"go.opencensus.io/trace"
 
 	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log"
 	peer "github.com/libp2p/go-libp2p-peer"
 )
@@ -30,6 +31,12 @@ type OperationTracker struct {
 
 	mu         sync.RWMutex
 	operations map[string]*Operation
+	// queued operations are reported as paused while set.
+	paused bool
+
+	// completed operations are moved here when compaction is enabled.
+	store      ds.Datastore
+	compaction CompactionOptions
 }
 
 func (opt *OperationTracker) String() string {
@@ -60,6 +67,18 @@ func NewOperationTracker(ctx context.Context, pid peer.ID, peerName string) *Ope
 	}
 }
 
+// NewOperationTrackerWithCompaction creates a new OperationTracker which
+// compacts completed operations into a datastore as specified by the given
+// options. When the options do not set any limits, this is equivalent to
+// NewOperationTracker.
+func NewOperationTrackerWithCompaction(ctx context.Context, pid peer.ID, peerName string, opts CompactionOptions) *OperationTracker {
+	opt := NewOperationTracker(ctx, pid, peerName)
+	if opts.enabled() {
+		opt.initCompaction(opts)
+	}
+	return opt
+}
+
 // TrackNewOperation will create, track and return a new operation unless
 // one already exists to do the same thing, in which case nil is returned.
 //
@@ -82,6 +101,7 @@ func (opt *OperationTracker) TrackNewOperation(ctx context.Context, pin *api.Pin
 		}
 		op.Cancel() // cancel ongoing operation and replace it
 	}
+	opt.unsafeDeleteCompacted(pin.Cid)
 
 	op2 := NewOperation(ctx, pin, typ, ph)
 	logger.Debugf("'%s' on cid '%s' has been created with phase '%s'", typ, cidStr, ph)
@@ -102,13 +122,22 @@ func (opt *OperationTracker) Clean(ctx context.Context, op *Operation) {
 	}
 }
 
+// IsCurrent returns whether the given operation is the one we are tracking
+// for its Cid (compares pointers).
+func (opt *OperationTracker) IsCurrent(op *Operation) bool {
+	opt.mu.RLock()
+	defer opt.mu.RUnlock()
+	op2, ok := opt.operations[op.Cid().String()]
+	return ok && op == op2
+}
+
 // Status returns the TrackerStatus associated to the last operation known
 // with the given Cid. It returns false if we are not tracking any operation
 // for the given Cid.
 func (opt *OperationTracker) Status(ctx context.Context, c cid.Cid) (api.TrackerStatus, bool) {
 	opt.mu.RLock()
 	defer opt.mu.RUnlock()
-	op, ok := opt.operations[c.String()]
+	op, ok := opt.unsafeGet(c)
 	if !ok {
 		return 0, false
 	}
@@ -125,7 +154,16 @@ func (opt *OperationTracker) SetError(ctx context.Context, c cid.Cid, err error)
 	defer opt.mu.Unlock()
 	op, ok := opt.operations[c.String()]
 	if !ok {
-		return
+		op, ok = opt.unsafeGetCompacted(c)
+		if !ok {
+			return
+		}
+		// keep it compacted
+		defer func() {
+			if err := opt.unsafePutCompacted(op); err != nil {
+				logger.Error(err)
+			}
+		}()
 	}
 
 	if ty := op.Type(); ty == OperationRemote {
@@ -149,14 +187,29 @@ func (opt *OperationTracker) unsafePinInfo(ctx context.Context, op *Operation) a
 			Error:    "",
 		}
 	}
-	return api.PinInfo{
+	pinfo := api.PinInfo{
 		Cid:      op.Cid(),
 		Peer:     opt.pid,
 		PeerName: opt.peerName,
 		Status:   op.ToTrackerStatus(),
 		TS:       op.Timestamp(),
 		Error:    op.Error(),
+		Paused:   opt.paused && op.Phase() == PhaseQueued,
+	}
+	if pinfo.Status == api.TrackerStatusPinning {
+		// the rest of the progress is obtained from IPFS
+		pinfo.Progress = &api.PinProgress{Cid: op.Cid()}
+		pinfo.Progress.SetTotalBytes(op.Pin().Size)
 	}
+	return pinfo
+}
+
+// SetPaused sets whether queued operations are reported as paused, that
+// is, waiting until the tracker resumes.
+func (opt *OperationTracker) SetPaused(paused bool) {
+	opt.mu.Lock()
+	defer opt.mu.Unlock()
+	opt.paused = paused
 }
 
 // Get returns a PinInfo object for Cid.
@@ -166,7 +219,7 @@ func (opt *OperationTracker) Get(ctx context.Context, c cid.Cid) *api.PinInfo {
 
 	opt.mu.RLock()
 	defer opt.mu.RUnlock()
-	op := opt.operations[c.String()]
+	op, _ := opt.unsafeGet(c)
 	pInfo := opt.unsafePinInfo(ctx, op)
 	if pInfo.Cid == cid.Undef {
 		pInfo.Cid = c
@@ -182,7 +235,7 @@ func (opt *OperationTracker) GetExists(ctx context.Context, c cid.Cid) (*api.Pin
 
 	opt.mu.RLock()
 	defer opt.mu.RUnlock()
-	op, ok := opt.operations[c.String()]
+	op, ok := opt.unsafeGet(c)
 	if !ok {
 		return nil, false
 	}
@@ -198,7 +251,7 @@ func (opt *OperationTracker) GetAll(ctx context.Context) []*api.PinInfo {
 	var pinfos []*api.PinInfo
 	opt.mu.RLock()
 	defer opt.mu.RUnlock()
-	for _, op := range opt.operations {
+	for _, op := range opt.unsafeAll() {
 		pinfo := opt.unsafePinInfo(ctx, op)
 		pinfos = append(pinfos, &pinfo)
 	}
@@ -208,9 +261,9 @@ func (opt *OperationTracker) GetAll(ctx context.Context) []*api.PinInfo {
 // CleanError removes the associated Operation, if it is
 // in PhaseError.
 func (opt *OperationTracker) CleanError(ctx context.Context, c cid.Cid) {
-	opt.mu.RLock()
-	defer opt.mu.RUnlock()
-	errop, ok := opt.operations[c.String()]
+	opt.mu.Lock()
+	defer opt.mu.Unlock()
+	errop, ok := opt.unsafeGet(c)
 	if !ok {
 		return
 	}
@@ -219,17 +272,18 @@ func (opt *OperationTracker) CleanError(ctx context.Context, c cid.Cid) {
 		return
 	}
 
-	opt.Clean(ctx, errop)
-	return
+	delete(opt.operations, c.String())
+	opt.unsafeDeleteCompacted(c)
 }
 
 // CleanAllDone deletes any operation from the tracker that is in PhaseDone.
 func (opt *OperationTracker) CleanAllDone(ctx context.Context) {
 	opt.mu.Lock()
 	defer opt.mu.Unlock()
-	for _, op := range opt.operations {
+	for _, op := range opt.unsafeAll() {
 		if op.Phase() == PhaseDone {
 			delete(opt.operations, op.Cid().String())
+			opt.unsafeDeleteCompacted(op.Cid())
 		}
 	}
 }
@@ -253,7 +307,7 @@ func (opt *OperationTracker) Filter(ctx context.Context, filters ...interface{})
 	var pinfos []*api.PinInfo
 	opt.mu.RLock()
 	defer opt.mu.RUnlock()
-	ops := filterOpsMap(ctx, opt.operations, filters)
+	ops := filterOpsMap(ctx, opt.unsafeAll(), filters)
 	for _, op := range ops {
 		pinfo := opt.unsafePinInfo(ctx, op)
 		pinfos = append(pinfos, &pinfo)
@@ -261,6 +315,55 @@ func (opt *OperationTracker) Filter(ctx context.Context, filters ...interface{})
 	return pinfos
 }
 
+// PhaseCount returns the number of operations in the given phase.
+func (opt *OperationTracker) PhaseCount(ctx context.Context, ph Phase) int {
+	return len(opt.filterOps(ctx, ph))
+}
+
+// statsStatuses are the statuses reported by Stats, in order.
+var statsStatuses = []api.TrackerStatus{
+	api.TrackerStatusPinQueued,
+	api.TrackerStatusPinning,
+	api.TrackerStatusUnpinQueued,
+	api.TrackerStatusUnpinning,
+	api.TrackerStatusError,
+}
+
+// Stats returns how many pin and unpin operations are queued, in progress
+// and in error, and how long ago the oldest of each entered that phase.
+// Compacted operations are not included.
+func (opt *OperationTracker) Stats(ctx context.Context) []*api.OperationStats {
+	ctx, span := trace.StartSpan(ctx, "optracker/Stats")
+	defer span.End()
+
+	byStatus := make(map[api.TrackerStatus]*api.OperationStats, len(statsStatuses))
+	res := make([]*api.OperationStats, 0, len(statsStatuses))
+	for _, st := range statsStatuses {
+		s := &api.OperationStats{Status: st}
+		byStatus[st] = s
+		res = append(res, s)
+	}
+
+	now := time.Now()
+	opt.mu.RLock()
+	defer opt.mu.RUnlock()
+	for _, op := range opt.operations {
+		st := op.ToTrackerStatus()
+		if st.Match(api.TrackerStatusError) {
+			st = api.TrackerStatusError
+		}
+		s, ok := byStatus[st]
+		if !ok {
+			continue
+		}
+		s.Count++
+		if age := now.Sub(op.Timestamp()); age > s.OldestAge {
+			s.OldestAge = age
+		}
+	}
+	return res
+}
+
 // filterOps returns a slice that only contains operations
 // with the matching filter. Note, only supports
 // filters of type OperationType or Phase, any other type
@@ -269,12 +372,34 @@ func (opt *OperationTracker) filterOps(ctx context.Context, filters ...interface
 	var fltops []*Operation
 	opt.mu.RLock()
 	defer opt.mu.RUnlock()
-	for _, op := range filterOpsMap(ctx, opt.operations, filters) {
+	for _, op := range filterOpsMap(ctx, opt.unsafeAll(), filters) {
 		fltops = append(fltops, op)
 	}
 	return fltops
 }
 
+// unsafeGet returns the operation for the given Cid, looking into
+// compacted operations when it is not in memory.
+func (opt *OperationTracker) unsafeGet(c cid.Cid) (*Operation, bool) {
+	op, ok := opt.operations[c.String()]
+	if ok {
+		return op, true
+	}
+	return opt.unsafeGetCompacted(c)
+}
+
+// unsafeAll returns all tracked operations, including compacted ones.
+func (opt *OperationTracker) unsafeAll() map[string]*Operation {
+	if opt.store == nil {
+		return opt.operations
+	}
+	ops := opt.unsafeAllCompacted()
+	for k, op := range opt.operations {
+		ops[k] = op
+	}
+	return ops
+}
+
 func filterOpsMap(ctx context.Context, ops map[string]*Operation, filters []interface{}) map[string]*Operation {
 	fltops := make(map[string]*Operation)
 	if len(filters) < 1 {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/ipfs/ipfs-cluster/api"
 	"github.com/ipfs/ipfs-cluster/test"
@@ -200,6 +201,55 @@ func TestOperationTracker_OpContext(t *testing.T) {
 	}
 }
 
+func TestOperationTracker_PhaseCount(t *testing.T) {
+	ctx := context.Background()
+	opt := testOperationTracker(t)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseQueued)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid2), OperationUnpin, PhaseQueued)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid3), OperationPin, PhaseError)
+
+	if n := opt.PhaseCount(ctx, PhaseQueued); n != 2 {
+		t.Errorf("expected 2 queued operations, got %d", n)
+	}
+	if n := opt.PhaseCount(ctx, PhaseError); n != 1 {
+		t.Errorf("expected 1 errored operation, got %d", n)
+	}
+}
+
+func TestOperationTracker_Stats(t *testing.T) {
+	ctx := context.Background()
+	opt := testOperationTracker(t)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseQueued)
+	time.Sleep(50 * time.Millisecond)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid2), OperationPin, PhaseQueued)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid3), OperationUnpin, PhaseError)
+	opt.TrackNewOperation(ctx, api.PinCid(test.Cid4), OperationPin, PhaseError)
+	opt.TrackNewOperation(ctx, api.PinCid(test.SlowCid1), OperationRemote, PhaseDone)
+
+	expected := map[api.TrackerStatus]int{
+		api.TrackerStatusPinQueued:   2,
+		api.TrackerStatusPinning:     0,
+		api.TrackerStatusUnpinQueued: 0,
+		api.TrackerStatusUnpinning:   0,
+		api.TrackerStatusError:       2,
+	}
+	stats := opt.Stats(ctx)
+	if len(stats) != len(expected) {
+		t.Fatalf("expected %d statuses, got %d", len(expected), len(stats))
+	}
+	for _, s := range stats {
+		if s.Count != expected[s.Status] {
+			t.Errorf("%s: expected %d operations, got %d", s.Status, expected[s.Status], s.Count)
+		}
+		if s.Count == 0 && s.OldestAge != 0 {
+			t.Errorf("%s: expected no age without operations", s.Status)
+		}
+		if s.Status == api.TrackerStatusPinQueued && s.OldestAge < 50*time.Millisecond {
+			t.Errorf("expected the age of the oldest queued pin: %s", s.OldestAge)
+		}
+	}
+}
+
 func TestOperationTracker_filterOps(t *testing.T) {
 	ctx := context.Background()
 	testOpsMap := map[string]*Operation{
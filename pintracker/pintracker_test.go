@@ -13,6 +13,7 @@ import (
 
 	ipfscluster "github.com/ipfs/ipfs-cluster"
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
 	"github.com/ipfs/ipfs-cluster/pintracker/maptracker"
 	"github.com/ipfs/ipfs-cluster/pintracker/stateless"
 	"github.com/ipfs/ipfs-cluster/test"
@@ -123,7 +124,7 @@ func testSlowMapPinTracker(t testing.TB) *maptracker.MapPinTracker {
 	cfg := &maptracker.Config{}
 	cfg.Default()
 	cfg.ConcurrentPins = 1
-	mpt := maptracker.NewMapPinTracker(cfg, test.PeerID1, test.PeerName1)
+	mpt := maptracker.NewMapPinTracker(cfg, test.PeerID1, test.PeerName1, inmem.New())
 	mpt.SetClient(mockRPCClient(t))
 	return mpt
 }
@@ -132,7 +133,7 @@ func testMapPinTracker(t testing.TB) *maptracker.MapPinTracker {
 	cfg := &maptracker.Config{}
 	cfg.Default()
 	cfg.ConcurrentPins = 1
-	mpt := maptracker.NewMapPinTracker(cfg, test.PeerID1, test.PeerName1)
+	mpt := maptracker.NewMapPinTracker(cfg, test.PeerID1, test.PeerName1, inmem.New())
 	mpt.SetClient(test.NewMockRPCClient(t))
 	return mpt
 }
@@ -140,7 +141,7 @@ func testMapPinTracker(t testing.TB) *maptracker.MapPinTracker {
 func testSlowStatelessPinTracker(t testing.TB) *stateless.Tracker {
 	cfg := &stateless.Config{}
 	cfg.Default()
-	mpt := stateless.New(cfg, test.PeerID1, test.PeerName1)
+	mpt := stateless.New(cfg, test.PeerID1, test.PeerName1, inmem.New())
 	mpt.SetClient(mockRPCClient(t))
 	return mpt
 }
@@ -148,7 +149,7 @@ func testSlowStatelessPinTracker(t testing.TB) *stateless.Tracker {
 func testStatelessPinTracker(t testing.TB) *stateless.Tracker {
 	cfg := &stateless.Config{}
 	cfg.Default()
-	spt := stateless.New(cfg, test.PeerID1, test.PeerName1)
+	spt := stateless.New(cfg, test.PeerID1, test.PeerName1, inmem.New())
 	spt.SetClient(test.NewMockRPCClient(t))
 	return spt
 }
@@ -265,6 +266,7 @@ func TestPinTracker_StatusAll(t *testing.T) {
 	type args struct {
 		c       *api.Pin
 		tracker ipfscluster.PinTracker
+		filter  api.TrackerStatus
 	}
 	tests := []struct {
 		name string
@@ -276,6 +278,7 @@ func TestPinTracker_StatusAll(t *testing.T) {
 			args{
 				api.PinWithOpts(test.Cid1, pinOpts),
 				testStatelessPinTracker(t),
+				api.TrackerStatusUndefined,
 			},
 			[]*api.PinInfo{
 				{
@@ -297,6 +300,7 @@ func TestPinTracker_StatusAll(t *testing.T) {
 			args{
 				api.PinWithOpts(test.Cid1, pinOpts),
 				testMapPinTracker(t),
+				api.TrackerStatusUndefined,
 			},
 			[]*api.PinInfo{
 				{
@@ -310,6 +314,7 @@ func TestPinTracker_StatusAll(t *testing.T) {
 			args{
 				api.PinWithOpts(test.Cid1, pinOpts),
 				testSlowStatelessPinTracker(t),
+				api.TrackerStatusUndefined,
 			},
 			[]*api.PinInfo{
 				{
@@ -323,6 +328,7 @@ func TestPinTracker_StatusAll(t *testing.T) {
 			args{
 				api.PinWithOpts(test.Cid1, pinOpts),
 				testSlowMapPinTracker(t),
+				api.TrackerStatusUndefined,
 			},
 			[]*api.PinInfo{
 				{
@@ -331,6 +337,47 @@ func TestPinTracker_StatusAll(t *testing.T) {
 				},
 			},
 		},
+		{
+			"stateless statusall remote only",
+			args{
+				api.PinWithOpts(test.Cid1, pinOpts),
+				testStatelessPinTracker(t),
+				api.TrackerStatusRemote,
+			},
+			[]*api.PinInfo{
+				{
+					Cid:    test.Cid2,
+					Status: api.TrackerStatusRemote,
+				},
+			},
+		},
+		{
+			"stateless statusall without remote",
+			args{
+				api.PinWithOpts(test.Cid1, pinOpts),
+				testStatelessPinTracker(t),
+				api.TrackerStatusPinned | api.TrackerStatusError,
+			},
+			[]*api.PinInfo{
+				{
+					Cid:    test.Cid1,
+					Status: api.TrackerStatusPinned,
+				},
+				{
+					Cid:    test.Cid3,
+					Status: api.TrackerStatusPinned,
+				},
+			},
+		},
+		{
+			"map statusall filtered out",
+			args{
+				api.PinWithOpts(test.Cid1, pinOpts),
+				testMapPinTracker(t),
+				api.TrackerStatusRemote,
+			},
+			[]*api.PinInfo{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -338,7 +385,7 @@ func TestPinTracker_StatusAll(t *testing.T) {
 				t.Errorf("PinTracker.Track() error = %v", err)
 			}
 			time.Sleep(1 * time.Second)
-			got := tt.args.tracker.StatusAll(context.Background())
+			got := tt.args.tracker.StatusAll(context.Background(), tt.args.filter)
 			if len(got) != len(tt.want) {
 				for _, pi := range got {
 					t.Logf("pinfo: %v", pi)
@@ -387,7 +434,7 @@ func BenchmarkPinTracker_StatusAll(b *testing.B) {
 		b.Run(tt.name, func(b *testing.B) {
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
-				tt.args.tracker.StatusAll(context.Background())
+				tt.args.tracker.StatusAll(context.Background(), api.TrackerStatusUndefined)
 			}
 		})
 	}
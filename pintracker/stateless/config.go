@@ -3,6 +3,8 @@ package stateless
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 
@@ -16,6 +18,20 @@ const envConfigKey = "cluster_stateless"
 const (
 	DefaultMaxPinQueueSize = 50000
 	DefaultConcurrentPins  = 10
+	// By default, completed operations are never compacted.
+	DefaultMaxCompletedOperations = 0
+	DefaultCompactCompletedAfter  = 0 * time.Second
+	// By default, operations never time out.
+	DefaultOperationTimeout    = 0 * time.Second
+	DefaultTrustStateOnStartup = false
+	DefaultVerifyBatchSize     = 1000
+	DefaultVerifyBatchInterval = time.Second
+	// By default, failed operations are not retried.
+	DefaultPinRetries       = 0
+	DefaultPinRetryDelay    = 5 * time.Second
+	DefaultPinRetryMaxDelay = 5 * time.Minute
+	DefaultPinRetryJitter   = 0.2
+	DefaultHookTimeout      = 30 * time.Second
 )
 
 // Config allows to initialize a Monitor and customize some parameters.
@@ -28,11 +44,83 @@ type Config struct {
 	// daemon in parallel. If the pinning method is "refs", it might increase
 	// speed. Unpin requests are always processed one by one.
 	ConcurrentPins int
+	// MaxCompletedOperations is the maximum number of completed (done or
+	// errored) operations kept in memory. Older ones are compacted into
+	// the datastore. 0 means no limit.
+	MaxCompletedOperations int
+	// CompactCompletedAfter is the age after which completed operations
+	// are compacted into the datastore. 0 means never.
+	CompactCompletedAfter time.Duration
+	// OperationTimeout is how long a pin or unpin operation may run
+	// before it is cancelled and set in error, so that stuck operations
+	// do not hold a worker forever. The PinTimeout of a pin overrides it.
+	// 0 means no timeout.
+	OperationTimeout time.Duration
+	// NamespaceMetadataKey is the pin metadata key whose value names
+	// the namespace (i.e. the tenant or origin) of a pin. When set, pin
+	// operations are dequeued fairly across namespaces, so that a large
+	// import in one of them does not hold back the pins of the others.
+	// Pins without the key belong to the "" namespace.
+	NamespaceMetadataKey string
+	// NamespaceWeights sets how many pin operations of a namespace are
+	// dequeued in each round, compared to others. Namespaces not listed
+	// have a weight of 1.
+	NamespaceWeights map[string]int
+	// TrustStateOnStartup makes the tracker assume, when the peer
+	// starts, that every pin in the shared state allocated to this peer
+	// is pinned by IPFS, instead of listing all the IPFS pins. This makes
+	// peers with very large pinsets responsive right away. The pins are
+	// then verified in the background, in batches, and those missing are
+	// pinned.
+	TrustStateOnStartup bool
+	// VerifyBatchSize is the number of pins checked in each batch of
+	// the background verification.
+	VerifyBatchSize int
+	// VerifyBatchInterval is the pause between batches of the
+	// background verification.
+	VerifyBatchInterval time.Duration
+	// PinRetries is how many times a failed pin or unpin operation is
+	// retried automatically before it is left in error. 0 disables
+	// retries.
+	PinRetries int
+	// PinRetryDelay is the delay before the first retry. It doubles
+	// with every retry.
+	PinRetryDelay time.Duration
+	// PinRetryMaxDelay is the maximum delay between retries.
+	PinRetryMaxDelay time.Duration
+	// PinRetryJitter randomizes the delays by up to this fraction of
+	// them, either way (i.e. 0.2 means +/-20%).
+	PinRetryJitter float64
+	// Hooks run commands, call webhooks or notify event sink plugins
+	// when pins tracked by this peer are pinned, unpinned or left in
+	// error.
+	Hooks []*Hook
+	// HookTimeout is how long each hook may run before it is
+	// cancelled. 0 means no timeout.
+	HookTimeout time.Duration
 }
 
 type jsonConfig struct {
-	MaxPinQueueSize int `json:"max_pin_queue_size"`
-	ConcurrentPins  int `json:"concurrent_pins"`
+	MaxPinQueueSize        int    `json:"max_pin_queue_size"`
+	ConcurrentPins         int    `json:"concurrent_pins"`
+	MaxCompletedOperations int    `json:"max_completed_operations"`
+	CompactCompletedAfter  string `json:"compact_completed_after"`
+	OperationTimeout       string `json:"operation_timeout,omitempty"`
+
+	NamespaceMetadataKey string         `json:"namespace_metadata_key,omitempty"`
+	NamespaceWeights     map[string]int `json:"namespace_weights,omitempty"`
+
+	TrustStateOnStartup bool   `json:"trust_state_on_startup,omitempty"`
+	VerifyBatchSize     int    `json:"verify_batch_size,omitempty"`
+	VerifyBatchInterval string `json:"verify_batch_interval,omitempty"`
+
+	PinRetries       int     `json:"pin_retries,omitempty"`
+	PinRetryDelay    string  `json:"pin_retry_delay,omitempty"`
+	PinRetryMaxDelay string  `json:"pin_retry_max_delay,omitempty"`
+	PinRetryJitter   float64 `json:"pin_retry_jitter,omitempty"`
+
+	Hooks       []*Hook `json:"hooks,omitempty"`
+	HookTimeout string  `json:"hook_timeout,omitempty"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -44,6 +132,20 @@ func (cfg *Config) ConfigKey() string {
 func (cfg *Config) Default() error {
 	cfg.MaxPinQueueSize = DefaultMaxPinQueueSize
 	cfg.ConcurrentPins = DefaultConcurrentPins
+	cfg.MaxCompletedOperations = DefaultMaxCompletedOperations
+	cfg.CompactCompletedAfter = DefaultCompactCompletedAfter
+	cfg.OperationTimeout = DefaultOperationTimeout
+	cfg.NamespaceMetadataKey = ""
+	cfg.NamespaceWeights = nil
+	cfg.TrustStateOnStartup = DefaultTrustStateOnStartup
+	cfg.VerifyBatchSize = DefaultVerifyBatchSize
+	cfg.VerifyBatchInterval = DefaultVerifyBatchInterval
+	cfg.PinRetries = DefaultPinRetries
+	cfg.PinRetryDelay = DefaultPinRetryDelay
+	cfg.PinRetryMaxDelay = DefaultPinRetryMaxDelay
+	cfg.PinRetryJitter = DefaultPinRetryJitter
+	cfg.Hooks = nil
+	cfg.HookTimeout = DefaultHookTimeout
 	return nil
 }
 
@@ -70,6 +172,62 @@ func (cfg *Config) Validate() error {
 	if cfg.ConcurrentPins <= 0 {
 		return errors.New("statelesstracker.concurrent_pins is too low")
 	}
+
+	if cfg.MaxCompletedOperations < 0 {
+		return errors.New("statelesstracker.max_completed_operations is invalid")
+	}
+
+	if cfg.CompactCompletedAfter < 0 {
+		return errors.New("statelesstracker.compact_completed_after is invalid")
+	}
+
+	if cfg.OperationTimeout < 0 {
+		return errors.New("statelesstracker.operation_timeout is invalid")
+	}
+
+	for ns, w := range cfg.NamespaceWeights {
+		if w <= 0 {
+			return fmt.Errorf("statelesstracker.namespace_weights: weight for %q should be larger than 0", ns)
+		}
+	}
+
+	// Missing pins found in a batch are queued at once.
+	if cfg.VerifyBatchSize <= 0 || cfg.VerifyBatchSize > cfg.MaxPinQueueSize {
+		return errors.New("statelesstracker.verify_batch_size should be between 1 and max_pin_queue_size")
+	}
+
+	if cfg.VerifyBatchInterval < 0 {
+		return errors.New("statelesstracker.verify_batch_interval is invalid")
+	}
+
+	if cfg.PinRetries < 0 {
+		return errors.New("statelesstracker.pin_retries is invalid")
+	}
+
+	if cfg.PinRetryDelay <= 0 {
+		return errors.New("statelesstracker.pin_retry_delay is invalid")
+	}
+
+	if cfg.PinRetryMaxDelay < cfg.PinRetryDelay {
+		return errors.New("statelesstracker.pin_retry_max_delay should not be lower than pin_retry_delay")
+	}
+
+	if cfg.PinRetryJitter < 0 || cfg.PinRetryJitter >= 1 {
+		return errors.New("statelesstracker.pin_retry_jitter should be between 0 and 1")
+	}
+
+	for i, h := range cfg.Hooks {
+		if h == nil {
+			return fmt.Errorf("statelesstracker.hooks: hook %d is empty", i)
+		}
+		if err := h.validate(cfg.NamespaceMetadataKey != ""); err != nil {
+			return fmt.Errorf("statelesstracker.hooks: hook %d: %s", i, err)
+		}
+	}
+
+	if cfg.HookTimeout < 0 {
+		return errors.New("statelesstracker.hook_timeout is invalid")
+	}
 	return nil
 }
 
@@ -91,6 +249,31 @@ func (cfg *Config) LoadJSON(raw []byte) error {
 func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	config.SetIfNotDefault(jcfg.MaxPinQueueSize, &cfg.MaxPinQueueSize)
 	config.SetIfNotDefault(jcfg.ConcurrentPins, &cfg.ConcurrentPins)
+	config.SetIfNotDefault(jcfg.MaxCompletedOperations, &cfg.MaxCompletedOperations)
+	config.SetIfNotDefault(jcfg.NamespaceMetadataKey, &cfg.NamespaceMetadataKey)
+	if len(jcfg.NamespaceWeights) > 0 {
+		cfg.NamespaceWeights = jcfg.NamespaceWeights
+	}
+	cfg.TrustStateOnStartup = jcfg.TrustStateOnStartup
+	config.SetIfNotDefault(jcfg.VerifyBatchSize, &cfg.VerifyBatchSize)
+	config.SetIfNotDefault(jcfg.PinRetries, &cfg.PinRetries)
+	if jcfg.PinRetryJitter != 0 {
+		cfg.PinRetryJitter = jcfg.PinRetryJitter
+	}
+	cfg.Hooks = jcfg.Hooks
+
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.CompactCompletedAfter, Dst: &cfg.CompactCompletedAfter, Name: "compact_completed_after"},
+		&config.DurationOpt{Duration: jcfg.OperationTimeout, Dst: &cfg.OperationTimeout, Name: "operation_timeout"},
+		&config.DurationOpt{Duration: jcfg.VerifyBatchInterval, Dst: &cfg.VerifyBatchInterval, Name: "verify_batch_interval"},
+		&config.DurationOpt{Duration: jcfg.PinRetryDelay, Dst: &cfg.PinRetryDelay, Name: "pin_retry_delay"},
+		&config.DurationOpt{Duration: jcfg.PinRetryMaxDelay, Dst: &cfg.PinRetryMaxDelay, Name: "pin_retry_max_delay"},
+		&config.DurationOpt{Duration: jcfg.HookTimeout, Dst: &cfg.HookTimeout, Name: "hook_timeout"},
+	)
+	if err != nil {
+		return err
+	}
 
 	return cfg.Validate()
 }
@@ -104,7 +287,21 @@ func (cfg *Config) ToJSON() ([]byte, error) {
 
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	return &jsonConfig{
-		MaxPinQueueSize: cfg.MaxPinQueueSize,
-		ConcurrentPins:  cfg.ConcurrentPins,
+		MaxPinQueueSize:        cfg.MaxPinQueueSize,
+		ConcurrentPins:         cfg.ConcurrentPins,
+		MaxCompletedOperations: cfg.MaxCompletedOperations,
+		CompactCompletedAfter:  cfg.CompactCompletedAfter.String(),
+		OperationTimeout:       cfg.OperationTimeout.String(),
+		NamespaceMetadataKey:   cfg.NamespaceMetadataKey,
+		NamespaceWeights:       cfg.NamespaceWeights,
+		TrustStateOnStartup:    cfg.TrustStateOnStartup,
+		VerifyBatchSize:        cfg.VerifyBatchSize,
+		VerifyBatchInterval:    cfg.VerifyBatchInterval.String(),
+		PinRetries:             cfg.PinRetries,
+		PinRetryDelay:          cfg.PinRetryDelay.String(),
+		PinRetryMaxDelay:       cfg.PinRetryMaxDelay.String(),
+		PinRetryJitter:         cfg.PinRetryJitter,
+		Hooks:                  cfg.Hooks,
+		HookTimeout:            cfg.HookTimeout.String(),
 	}
 }
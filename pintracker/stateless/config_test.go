@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 )
 
 var cfgJSON = []byte(`
 {
 	"max_pin_queue_size": 4092,
-	"concurrent_pins": 2
+	"concurrent_pins": 2,
+	"max_completed_operations": 1000,
+	"compact_completed_after": "1h"
 }
 `)
 
@@ -32,6 +35,137 @@ func TestLoadJSON(t *testing.T) {
 	if cfg.ConcurrentPins != 10 {
 		t.Error("expected 10 concurrent pins")
 	}
+	if cfg.MaxCompletedOperations != 1000 {
+		t.Error("expected 1000 max completed operations")
+	}
+	if cfg.CompactCompletedAfter != time.Hour {
+		t.Error("expected compact_completed_after to be 1h")
+	}
+
+	j.CompactCompletedAfter = "abc"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error parsing compact_completed_after")
+	}
+
+	j.CompactCompletedAfter = "1h"
+	j.OperationTimeout = "10m"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OperationTimeout != 10*time.Minute {
+		t.Error("expected operation_timeout to be 10m")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.NamespaceMetadataKey = "tenant"
+	j.NamespaceWeights = map[string]int{"urgent": 5}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NamespaceMetadataKey != "tenant" || cfg.NamespaceWeights["urgent"] != 5 {
+		t.Error("expected namespace options to be set")
+	}
+
+	j.NamespaceWeights = map[string]int{"urgent": 0}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error in namespace_weights")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.TrustStateOnStartup = true
+	j.VerifyBatchSize = 100
+	j.VerifyBatchInterval = "5s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.TrustStateOnStartup || cfg.VerifyBatchSize != 100 || cfg.VerifyBatchInterval != 5*time.Second {
+		t.Error("expected the state verification options to be set")
+	}
+
+	j.VerifyBatchSize = 5000 // larger than max_pin_queue_size
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error in verify_batch_size")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.PinRetries = 5
+	j.PinRetryDelay = "1s"
+	j.PinRetryMaxDelay = "1m"
+	j.PinRetryJitter = 0.5
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PinRetries != 5 || cfg.PinRetryDelay != time.Second ||
+		cfg.PinRetryMaxDelay != time.Minute || cfg.PinRetryJitter != 0.5 {
+		t.Error("expected the retry options to be set")
+	}
+
+	j.PinRetryMaxDelay = "500ms" // lower than pin_retry_delay
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error in pin_retry_max_delay")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.Hooks = []*Hook{
+		{Events: []string{HookPinned}, URL: "http://localhost:8080/pinned"},
+	}
+	j.HookTimeout = "10s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Hooks) != 1 || cfg.HookTimeout != 10*time.Second {
+		t.Error("expected the hook options to be set")
+	}
+
+	j.Hooks = []*Hook{{Events: []string{"replicated"}, URL: "http://localhost:8080"}}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error with an unknown hook event")
+	}
+
+	j.Hooks = []*Hook{{Events: []string{HookError}}}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error with a hook without exec, url or plugin")
+	}
+
+	j.Hooks = []*Hook{{Events: []string{HookError}, Plugin: []string{"/usr/local/bin/sink"}}}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Error(err)
+	}
+
+	j.Hooks = []*Hook{{Namespace: "tenant", Exec: []string{"true"}}}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error with a hook namespace without namespace_metadata_key")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -59,6 +193,12 @@ func TestDefault(t *testing.T) {
 	if cfg.Validate() == nil {
 		t.Fatal("expected error validating")
 	}
+
+	cfg.Default()
+	cfg.MaxCompletedOperations = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
 }
 
 func TestApplyEnvVars(t *testing.T) {
@@ -0,0 +1,98 @@
+package stateless
+
+import (
+	"sync"
+
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+)
+
+// fairQueue holds queued operations in a FIFO queue per namespace and
+// dequeues them with weighted round-robin: in every round, each namespace
+// with queued operations gets to dequeue as many operations as its weight.
+// This way, a namespace with a very long queue cannot starve the others.
+type fairQueue struct {
+	mu      sync.Mutex
+	queues  map[string][]*optracker.Operation
+	order   []string // namespaces with queued operations
+	next    int      // index in order of the namespace being served
+	served  int      // operations dequeued from it in this round
+	size    int
+	maxSize int
+	weights map[string]int
+}
+
+func newFairQueue(maxSize int, weights map[string]int) *fairQueue {
+	return &fairQueue{
+		queues:  make(map[string][]*optracker.Operation),
+		maxSize: maxSize,
+		weights: weights,
+	}
+}
+
+func (q *fairQueue) weight(ns string) int {
+	if w, ok := q.weights[ns]; ok {
+		return w
+	}
+	return 1
+}
+
+// push queues an operation in the given namespace. It returns false when
+// the queue is full.
+func (q *fairQueue) push(ns string, op *optracker.Operation) bool {
+	q.mu.Lock()
+	if q.size >= q.maxSize {
+		q.mu.Unlock()
+		return false
+	}
+	if _, ok := q.queues[ns]; !ok {
+		q.order = append(q.order, ns)
+	}
+	q.queues[ns] = append(q.queues[ns], op)
+	q.size++
+	q.mu.Unlock()
+	return true
+}
+
+// pop dequeues the next operation. It returns false when the queue is
+// empty.
+func (q *fairQueue) pop() (*optracker.Operation, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		return nil, false
+	}
+	if q.next >= len(q.order) {
+		q.next = 0
+	}
+
+	ns := q.order[q.next]
+	ops := q.queues[ns]
+	op := ops[0]
+	ops[0] = nil
+	q.size--
+	q.served++
+
+	if len(ops) == 1 {
+		// Namespace is done. The next one takes its place
+		// in the order.
+		delete(q.queues, ns)
+		q.order = append(q.order[:q.next], q.order[q.next+1:]...)
+		q.served = 0
+		return op, true
+	}
+
+	q.queues[ns] = ops[1:]
+	if q.served >= q.weight(ns) {
+		q.served = 0
+		q.next++
+	}
+	return op, true
+}
+
+// len returns the number of queued operations.
+func (q *fairQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
@@ -0,0 +1,63 @@
+package stateless
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func TestFairQueue(t *testing.T) {
+	ctx := context.Background()
+	q := newFairQueue(100, map[string]int{"b": 2})
+
+	newOp := func(ns string) *optracker.Operation {
+		pin := api.PinCid(test.Cid1)
+		pin.Metadata = map[string]string{"tenant": ns}
+		return optracker.NewOperation(ctx, pin, optracker.OperationPin, optracker.PhaseQueued)
+	}
+
+	// A large import in "a" queued before anything else
+	for i := 0; i < 5; i++ {
+		q.push("a", newOp("a"))
+	}
+	for i := 0; i < 4; i++ {
+		q.push("b", newOp("b"))
+	}
+	q.push("c", newOp("c"))
+
+	if q.len() != 10 {
+		t.Fatal("expected 10 queued operations")
+	}
+
+	var order string
+	for {
+		op, ok := q.pop()
+		if !ok {
+			break
+		}
+		order += op.Pin().Metadata["tenant"]
+	}
+
+	// "b" has weight 2, others 1.
+	if order != "abbcabbaaa" {
+		t.Errorf("unexpected dequeue order: %s", order)
+	}
+	if q.len() != 0 {
+		t.Error("expected an empty queue")
+	}
+}
+
+func TestFairQueueFull(t *testing.T) {
+	ctx := context.Background()
+	q := newFairQueue(1, nil)
+	op := optracker.NewOperation(ctx, api.PinCid(test.Cid1), optracker.OperationPin, optracker.PhaseQueued)
+	if !q.push("", op) {
+		t.Fatal("expected the operation to be queued")
+	}
+	if q.push("", op) {
+		t.Error("expected the queue to be full")
+	}
+}
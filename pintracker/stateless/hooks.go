@@ -0,0 +1,295 @@
+package stateless
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+	"github.com/ipfs/ipfs-cluster/plugins"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// Hooks (see Config.Hooks) run when a pin operation of this peer finishes:
+// when an item is pinned or unpinned, or when it is left in error once any
+// retries are exhausted. They are matched by the namespace or the metadata
+// of the pin and receive a HookEvent, as JSON or through an event sink
+// plugin, so that external workflows can react to pins without polling the
+// API. Hooks run one after the
+// other, in the order of the events, in the background. Events which
+// arrive while too many others wait are dropped.
+
+// Hook events.
+const (
+	HookPinned   = "pinned"
+	HookUnpinned = "unpinned"
+	HookError    = "error"
+)
+
+// hookQueueSize is the number of events which can wait for their hooks.
+var hookQueueSize = 1024
+
+// maxHookOutput is how much of the output of a failed hook is logged.
+const maxHookOutput = 1024
+
+// hookPluginTimeout limits how long event sink plugins take to start and
+// to handle an event when no HookTimeout is set.
+var hookPluginTimeout = time.Minute
+
+// Hook is run when a pin reaches one of the given events, if it matches the
+// given namespace and metadata. It runs a command, calls a webhook, sends
+// the event to an event sink plugin, or any combination of them.
+type Hook struct {
+	// Events is a list of the events (pinned, unpinned, error) which
+	// trigger the hook. All of them do when empty.
+	Events []string `json:"events,omitempty"`
+	// Namespace, when set, limits the hook to the pins in the
+	// namespace (see Config.NamespaceMetadataKey).
+	Namespace string `json:"namespace,omitempty"`
+	// Metadata, when set, limits the hook to the pins with all these
+	// metadata values.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Exec is a command, followed by its arguments, which is run with
+	// the HookEvent JSON as input. The event and the Cid are also set
+	// in the CLUSTER_HOOK_EVENT and CLUSTER_HOOK_CID environment
+	// variables.
+	Exec []string `json:"exec,omitempty"`
+	// URL is a webhook to which the HookEvent JSON is POSTed.
+	URL string `json:"url,omitempty"`
+	// Plugin is an event sink plugin (see the plugins package),
+	// followed by its arguments, to which the event is sent. The
+	// plugin is launched with the first event and kept running.
+	Plugin []string `json:"plugin,omitempty"`
+}
+
+// validate checks that the hook does something on valid events.
+func (h *Hook) validate(namespaces bool) error {
+	if len(h.Exec) == 0 && h.URL == "" && len(h.Plugin) == 0 {
+		return errors.New("exec, url or plugin should be set")
+	}
+	for _, ev := range h.Events {
+		switch ev {
+		case HookPinned, HookUnpinned, HookError:
+		default:
+			return fmt.Errorf("unknown event %q", ev)
+		}
+	}
+	if h.Namespace != "" && !namespaces {
+		return errors.New("namespace needs namespace_metadata_key")
+	}
+	return nil
+}
+
+// HookEvent is sent to the hooks. Error is set for the error event.
+type HookEvent struct {
+	Event     string    `json:"event"`
+	Peer      string    `json:"peer"`
+	Pin       *api.Pin  `json:"pin"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// hookCall is an event waiting for the hooks which matched it.
+type hookCall struct {
+	ev    *HookEvent
+	hooks []*Hook
+}
+
+// matchHooks returns the hooks triggered by the given event on the pin of
+// the given operation.
+func (spt *Tracker) matchHooks(event string, op *optracker.Operation) []*Hook {
+	var matched []*Hook
+	pin := op.Pin()
+	ns := spt.namespace(op)
+	for _, h := range spt.config.Hooks {
+		if len(h.Events) > 0 && !containsString(h.Events, event) {
+			continue
+		}
+		if h.Namespace != "" && h.Namespace != ns {
+			continue
+		}
+		if !matchMetadata(h.Metadata, pin.Metadata) {
+			continue
+		}
+		matched = append(matched, h)
+	}
+	return matched
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMetadata(want, have map[string]string) bool {
+	for k, v := range want {
+		if hv, ok := have[k]; !ok || hv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// triggerHooks queues the given event on the pin of the given operation for
+// the hooks that it matches.
+func (spt *Tracker) triggerHooks(event string, op *optracker.Operation) {
+	if len(spt.config.Hooks) == 0 {
+		return
+	}
+	hooks := spt.matchHooks(event, op)
+	if len(hooks) == 0 {
+		return
+	}
+
+	ev := &HookEvent{
+		Event:     event,
+		Peer:      peer.IDB58Encode(spt.peerID),
+		Pin:       op.Pin(),
+		Timestamp: time.Now(),
+	}
+	if event == HookError {
+		ev.Error = op.Error()
+	}
+
+	select {
+	case spt.hookCh <- &hookCall{ev: ev, hooks: hooks}:
+	default:
+		logger.Warningf("too many hook events waiting, dropping %s event for %s", event, op.Cid())
+	}
+}
+
+// runHooks runs the hooks of the queued events until the tracker is shut
+// down.
+func (spt *Tracker) runHooks() {
+	defer spt.wg.Done()
+	client := &http.Client{}
+	sinks := make(map[*Hook]*plugins.Client)
+	defer func() {
+		for _, sink := range sinks {
+			sink.Shutdown()
+		}
+	}()
+	for {
+		select {
+		case call := <-spt.hookCh:
+			body, err := json.Marshal(call.ev)
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			for _, h := range call.hooks {
+				err := spt.runHook(client, sinks, h, call.ev, body)
+				if err != nil {
+					logger.Errorf("%s hook for %s failed: %s", call.ev.Event, call.ev.Pin.Cid, err)
+				}
+			}
+		case <-spt.ctx.Done():
+			return
+		}
+	}
+}
+
+func (spt *Tracker) runHook(client *http.Client, sinks map[*Hook]*plugins.Client, h *Hook, ev *HookEvent, body []byte) error {
+	ctx := spt.ctx
+	if spt.config.HookTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spt.config.HookTimeout)
+		defer cancel()
+	}
+
+	if len(h.Exec) > 0 {
+		err := execHook(ctx, h.Exec, ev, body)
+		if err != nil {
+			return err
+		}
+	}
+	if h.URL != "" {
+		err := postHook(ctx, client, h.URL, body)
+		if err != nil {
+			return err
+		}
+	}
+	if len(h.Plugin) > 0 {
+		sink, ok := sinks[h]
+		if !ok {
+			timeout := spt.config.HookTimeout
+			if timeout == 0 {
+				timeout = hookPluginTimeout
+			}
+			var err error
+			sink, err = plugins.NewClient(plugins.KindEventSink, h.Plugin[0], h.Plugin[1:], timeout)
+			if err != nil {
+				return err
+			}
+			sinks[h] = sink
+		}
+		return pluginHook(ctx, sink, ev)
+	}
+	return nil
+}
+
+func pluginHook(ctx context.Context, sink *plugins.Client, ev *HookEvent) error {
+	pev := &plugins.Event{
+		Event:     ev.Event,
+		Peer:      ev.Peer,
+		Pin:       ev.Pin,
+		Error:     ev.Error,
+		Timestamp: ev.Timestamp,
+	}
+	return sink.Call(ctx, func(ctx context.Context, raw interface{}) error {
+		return raw.(plugins.EventSink).HandleEvent(ctx, pev)
+	})
+}
+
+func execHook(ctx context.Context, command []string, ev *HookEvent, body []byte) error {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(
+		os.Environ(),
+		"CLUSTER_HOOK_EVENT="+ev.Event,
+		"CLUSTER_HOOK_CID="+ev.Pin.Cid.String(),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(out) > maxHookOutput {
+			out = out[:maxHookOutput]
+		}
+		return fmt.Errorf("%s: %s: %s", command[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func postHook(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxHookOutput))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package stateless
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+	"github.com/ipfs/ipfs-cluster/plugins"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+// The test binary acts as an event sink plugin, which writes the events it
+// receives to the given file, when launched with this variable set.
+const testSinkEnv = "TEST_HOOK_SINK"
+
+type testSink struct{}
+
+func (testSink) Name(ctx context.Context) (string, error) { return "testsink", nil }
+
+func (testSink) HandleEvent(ctx context.Context, ev *plugins.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(os.Getenv(testSinkEnv), data, 0600)
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testSinkEnv) != "" && os.Getenv(plugins.MagicCookieKey) != "" {
+		plugins.ServeEventSink(testSink{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+
+	events := make(chan *HookEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev HookEvent
+		err := json.NewDecoder(r.Body).Decode(&ev)
+		if err != nil {
+			t.Error(err)
+		}
+		events <- &ev
+	}))
+	defer srv.Close()
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.Hooks = []*Hook{
+		{
+			Events:   []string{HookPinned, HookError},
+			Metadata: map[string]string{"site": "docs"},
+			URL:      srv.URL,
+		},
+	}
+	spt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
+	spt.SetClient(mockRPCClient(t))
+	defer spt.Shutdown(ctx)
+
+	// does not match the metadata
+	err := spt.Track(ctx, api.PinWithOpts(test.Cid1, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pin := api.PinWithOpts(test.Cid2, pinOpts)
+	pin.Metadata = map[string]string{"site": "docs"}
+	err = spt.Track(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fails to pin
+	pin = api.PinWithOpts(pinCancelCid, pinOpts)
+	pin.Metadata = map[string]string{"site": "docs"}
+	err = spt.Track(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]*HookEvent)
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-events:
+			got[ev.Pin.Cid.String()] = ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected two hook events")
+		}
+	}
+
+	if ev := got[test.Cid2.String()]; ev == nil || ev.Event != HookPinned || ev.Peer != test.PeerID1.Pretty() {
+		t.Errorf("expected a pinned event for %s: %+v", test.Cid2, ev)
+	}
+	if ev := got[pinCancelCid.String()]; ev == nil || ev.Event != HookError || ev.Error == "" {
+		t.Errorf("expected an error event for %s: %+v", pinCancelCid, ev)
+	}
+
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected hook event: %+v", ev)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestPluginHook(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	events := filepath.Join(dir, "events.json")
+	os.Setenv(testSinkEnv, events)
+	defer os.Unsetenv(testSinkEnv)
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.Hooks = []*Hook{
+		{
+			Events: []string{HookPinned},
+			Plugin: []string{os.Args[0]},
+		},
+	}
+	spt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
+	spt.SetClient(mockRPCClient(t))
+	defer spt.Shutdown(ctx)
+
+	err = spt.Track(ctx, api.PinWithOpts(test.Cid1, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data []byte
+	for i := 0; i < 50; i++ {
+		data, err = ioutil.ReadFile(events)
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal("the event sink did not receive the event:", err)
+	}
+
+	var ev plugins.Event
+	err = json.Unmarshal(data, &ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Event != HookPinned || !ev.Pin.Cid.Equals(test.Cid1) || ev.Peer != test.PeerID1.Pretty() {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+}
+
+func TestMatchHooks(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+	cfg.NamespaceMetadataKey = "tenant"
+	cfg.Hooks = []*Hook{
+		{Exec: []string{"true"}},
+		{Events: []string{HookUnpinned}, Exec: []string{"true"}},
+		{Namespace: "a", Exec: []string{"true"}},
+	}
+	spt := &Tracker{config: cfg}
+
+	pin := api.PinWithOpts(test.Cid1, pinOpts)
+	pin.Metadata = map[string]string{"tenant": "a"}
+	op := optracker.NewOperation(context.Background(), pin, optracker.OperationPin, optracker.PhaseDone)
+	if n := len(spt.matchHooks(HookPinned, op)); n != 2 {
+		t.Errorf("expected 2 matching hooks, got %d", n)
+	}
+	if n := len(spt.matchHooks(HookUnpinned, op)); n != 3 {
+		t.Errorf("expected 3 matching hooks, got %d", n)
+	}
+
+	pin.Metadata = map[string]string{"tenant": "b"}
+	if n := len(spt.matchHooks(HookPinned, op)); n != 1 {
+		t.Errorf("expected 1 matching hook, got %d", n)
+	}
+}
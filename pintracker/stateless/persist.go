@@ -0,0 +1,120 @@
+package stateless
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	namespace "github.com/ipfs/go-datastore/namespace"
+	query "github.com/ipfs/go-datastore/query"
+)
+
+// Queued and in-flight pin and unpin operations are recorded in the
+// datastore, so that long backlogs are not lost when the peer restarts.
+// The records are removed once their operations succeed, and those left
+// are queued again when the tracker can make RPC requests. Failed
+// operations keep their records, as they may have failed because the peer
+// was stopping, and are thus retried after a restart. Operations replaced
+// by a newer one for the same CID share its record.
+
+// QueueNamespace is the datastore namespace under which queued operations
+// are recorded.
+var QueueNamespace = "/pintracker/queue"
+
+// queuedOp is the datastore representation of a queued operation.
+type queuedOp struct {
+	Type optracker.OperationType `json:"type"`
+	Pin  *api.Pin                `json:"pin"`
+}
+
+func queuedKey(c cid.Cid) ds.Key {
+	return ds.NewKey(c.String())
+}
+
+func newQueueStore(store ds.Datastore) ds.Datastore {
+	return namespace.Wrap(store, ds.NewKey(QueueNamespace))
+}
+
+// recordQueued records an operation which is about to be queued.
+func (spt *Tracker) recordQueued(op *optracker.Operation) {
+	v, err := json.Marshal(&queuedOp{
+		Type: op.Type(),
+		Pin:  op.Pin(),
+	})
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	spt.queueMu.Lock()
+	defer spt.queueMu.Unlock()
+	err = spt.queueStore.Put(queuedKey(op.Cid()), v)
+	if err != nil {
+		logger.Errorf("error recording the queued operation for %s: %s", op.Cid(), err)
+	}
+}
+
+// forgetQueued removes the record of a completed operation, unless it was
+// replaced by an operation of a different type meanwhile.
+func (spt *Tracker) forgetQueued(op *optracker.Operation) {
+	spt.queueMu.Lock()
+	defer spt.queueMu.Unlock()
+
+	key := queuedKey(op.Cid())
+	v, err := spt.queueStore.Get(key)
+	if err != nil {
+		if err != ds.ErrNotFound {
+			logger.Error(err)
+		}
+		return
+	}
+	var qop queuedOp
+	err = json.Unmarshal(v, &qop)
+	if err == nil && qop.Type != op.Type() {
+		return
+	}
+	err = spt.queueStore.Delete(key)
+	if err != nil {
+		logger.Errorf("error removing the queued operation for %s: %s", op.Cid(), err)
+	}
+}
+
+// restoreQueued queues again the recorded operations and returns how many
+// were restored.
+func (spt *Tracker) restoreQueued(ctx context.Context) int {
+	res, err := spt.queueStore.Query(query.Query{})
+	if err != nil {
+		logger.Error(err)
+		return 0
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		logger.Error(err)
+		return 0
+	}
+
+	restored := 0
+	for _, e := range entries {
+		var qop queuedOp
+		err := json.Unmarshal(e.Value, &qop)
+		if err != nil || qop.Pin == nil {
+			logger.Errorf("discarding unreadable queued operation %s: %v", e.Key, err)
+			spt.queueStore.Delete(ds.NewKey(e.Key))
+			continue
+		}
+		err = spt.enqueue(ctx, qop.Pin, qop.Type)
+		if err != nil {
+			logger.Errorf("error restoring the queued operation for %s: %s", qop.Pin.Cid, err)
+			continue
+		}
+		restored++
+	}
+	if restored > 0 {
+		logger.Infof("restored %d queued pin and unpin operations", restored)
+	}
+	return restored
+}
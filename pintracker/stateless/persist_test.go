@@ -0,0 +1,53 @@
+package stateless
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func TestRestoreQueued(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ConcurrentPins = 1
+
+	spt := New(cfg, test.PeerID1, test.PeerName1, store)
+	spt.SetClient(mockRPCClient(t))
+
+	// SlowCid1 keeps the only pin worker busy while Cid1 waits.
+	err := spt.Track(ctx, api.PinWithOpts(test.SlowCid1, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	err = spt.Track(ctx, api.PinWithOpts(test.Cid1, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spt.Shutdown(ctx)
+
+	spt2 := New(cfg, test.PeerID1, test.PeerName1, store)
+	defer spt2.Shutdown(ctx)
+	spt2.rpcClient = mockRPCClient(t)
+	if n := spt2.restoreQueued(ctx); n != 2 {
+		t.Fatalf("expected 2 restored operations, got %d", n)
+	}
+	if st, _ := spt2.optracker.Status(ctx, test.Cid1); st != api.TrackerStatusPinQueued && st != api.TrackerStatusPinning {
+		t.Errorf("expected Cid1 to be queued again: %s", st)
+	}
+
+	// Records are removed once the operations succeed.
+	for i := 0; i < 50; i++ {
+		if ok, _ := spt2.queueStore.Has(queuedKey(test.Cid1)); !ok {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Error("expected the record of Cid1 to be removed once pinned")
+}
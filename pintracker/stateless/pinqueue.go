@@ -0,0 +1,88 @@
+package stateless
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+)
+
+// pinQueue holds queued pin operations by priority (see
+// api.PinOptions.Priority). Operations with a higher priority are always
+// dequeued first. Those with the same priority are dequeued fairly across
+// namespaces (see fairQueue), or in FIFO order when namespaces are not
+// used.
+type pinQueue struct {
+	mu      sync.Mutex
+	levels  map[int]*fairQueue
+	prios   []int // priorities with queued operations, highest first
+	size    int
+	maxSize int
+	weights map[string]int
+
+	// receives a value when an operation is pushed.
+	notify chan struct{}
+}
+
+func newPinQueue(maxSize int, weights map[string]int) *pinQueue {
+	return &pinQueue{
+		levels:  make(map[int]*fairQueue),
+		maxSize: maxSize,
+		weights: weights,
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// push queues an operation with the given namespace and priority. It
+// returns false when the queue is full.
+func (q *pinQueue) push(ns string, prio int, op *optracker.Operation) bool {
+	q.mu.Lock()
+	if q.size >= q.maxSize {
+		q.mu.Unlock()
+		return false
+	}
+	level, ok := q.levels[prio]
+	if !ok {
+		level = newFairQueue(q.maxSize, q.weights)
+		q.levels[prio] = level
+		q.prios = append(q.prios, prio)
+		sort.Sort(sort.Reverse(sort.IntSlice(q.prios)))
+	}
+	level.push(ns, op)
+	q.size++
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop dequeues the next operation. It returns false when the queue is
+// empty.
+func (q *pinQueue) pop() (*optracker.Operation, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.prios) == 0 {
+		return nil, false
+	}
+
+	prio := q.prios[0]
+	level := q.levels[prio]
+	op, _ := level.pop()
+	q.size--
+	if level.len() == 0 {
+		delete(q.levels, prio)
+		q.prios = q.prios[1:]
+	}
+	return op, true
+}
+
+// len returns the number of queued operations.
+func (q *pinQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
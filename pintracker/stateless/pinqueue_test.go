@@ -0,0 +1,64 @@
+package stateless
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func TestPinQueue(t *testing.T) {
+	ctx := context.Background()
+	q := newPinQueue(100, nil)
+
+	push := func(name string, prio int) {
+		pin := api.PinCid(test.Cid1)
+		pin.Name = name
+		pin.Priority = prio
+		op := optracker.NewOperation(ctx, pin, optracker.OperationPin, optracker.PhaseQueued)
+		if !q.push("", prio, op) {
+			t.Fatal("expected the operation to be queued")
+		}
+	}
+
+	push("a", 0)
+	push("b", 0)
+	push("c", 5)
+	push("d", -1)
+	push("e", 5)
+
+	if q.len() != 5 {
+		t.Fatal("expected 5 queued operations")
+	}
+
+	var order string
+	for {
+		op, ok := q.pop()
+		if !ok {
+			break
+		}
+		order += op.Pin().Name
+	}
+
+	// Higher priorities first, FIFO within each priority.
+	if order != "ceabd" {
+		t.Errorf("unexpected dequeue order: %s", order)
+	}
+	if q.len() != 0 {
+		t.Error("expected an empty queue")
+	}
+}
+
+func TestPinQueueFull(t *testing.T) {
+	ctx := context.Background()
+	q := newPinQueue(1, nil)
+	op := optracker.NewOperation(ctx, api.PinCid(test.Cid1), optracker.OperationPin, optracker.PhaseQueued)
+	if !q.push("", 0, op) {
+		t.Fatal("expected the operation to be queued")
+	}
+	if q.push("", 1, op) {
+		t.Error("expected the queue to be full")
+	}
+}
@@ -0,0 +1,99 @@
+package stateless
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+)
+
+// Failed pin and unpin operations are retried automatically, up to
+// Config.PinRetries times, so that short IPFS daemon hiccups do not leave
+// pins in error until they are recovered by hand. The delay before each
+// retry doubles, starting with Config.PinRetryDelay and up to
+// Config.PinRetryMaxDelay, and is randomized by Config.PinRetryJitter so
+// that operations which failed together are not retried together.
+// Operations stay in error while they wait. Retries stop when the
+// operation is replaced, for example by a new Track, Untrack or Recover
+// request, which then starts over.
+
+// retryState tracks the retries of the failed operations for a Cid.
+type retryState struct {
+	op      *optracker.Operation // the last retry
+	retries int
+}
+
+// retryDelay returns how long to wait before the given retry (starting at
+// 0), including jitter.
+func retryDelay(cfg *Config, retry int) time.Duration {
+	delay := cfg.PinRetryDelay
+	for i := 0; i < retry && delay < cfg.PinRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > cfg.PinRetryMaxDelay {
+		delay = cfg.PinRetryMaxDelay
+	}
+	jitter := cfg.PinRetryJitter * (2*rand.Float64() - 1)
+	return time.Duration(float64(delay) * (1 + jitter))
+}
+
+// scheduleRetry queues the given failed operation again after a delay,
+// unless it has been retried too many times already. It returns false in
+// that case, when the operation is left in error.
+func (spt *Tracker) scheduleRetry(op *optracker.Operation) bool {
+	if spt.config.PinRetries <= 0 {
+		return false
+	}
+
+	key := op.Cid().String()
+	spt.retryMu.Lock()
+	retries := 0
+	if st, ok := spt.retries[key]; ok && st.op == op {
+		retries = st.retries
+	}
+	if retries >= spt.config.PinRetries {
+		delete(spt.retries, key)
+		spt.retryMu.Unlock()
+		logger.Errorf("%s for %s failed %d times, giving up: %s", op.Type(), key, retries+1, op.Error())
+		return false
+	}
+	spt.retries[key] = &retryState{op: op, retries: retries}
+	spt.retryMu.Unlock()
+
+	delay := retryDelay(spt.config, retries)
+	logger.Warningf("%s for %s failed, retrying in %s (%d/%d): %s", op.Type(), key, delay, retries+1, spt.config.PinRetries, op.Error())
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-spt.ctx.Done():
+			return
+		}
+
+		spt.retryMu.Lock()
+		defer spt.retryMu.Unlock()
+		if !spt.optracker.IsCurrent(op) || op.Phase() != optracker.PhaseError {
+			// replaced meanwhile
+			if st, ok := spt.retries[key]; ok && st.op == op {
+				delete(spt.retries, key)
+			}
+			return
+		}
+		newOp, err := spt.enqueueOp(spt.ctx, op.Pin(), op.Type())
+		if err != nil || newOp == nil {
+			delete(spt.retries, key)
+			return
+		}
+		spt.retries[key] = &retryState{op: newOp, retries: retries + 1}
+	}()
+	return true
+}
+
+// forgetRetries forgets the retries of a Cid once an operation for it
+// succeeds.
+func (spt *Tracker) forgetRetries(op *optracker.Operation) {
+	spt.retryMu.Lock()
+	defer spt.retryMu.Unlock()
+	delete(spt.retries, op.Cid().String())
+}
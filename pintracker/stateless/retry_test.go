@@ -0,0 +1,81 @@
+package stateless
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs/ipfs-cluster/test"
+)
+
+func TestRetryDelay(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+	cfg.PinRetryDelay = time.Second
+	cfg.PinRetryMaxDelay = 5 * time.Second
+	cfg.PinRetryJitter = 0
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, d := range expected {
+		if got := retryDelay(cfg, i); got != d {
+			t.Errorf("retry %d: expected %s, got %s", i, d, got)
+		}
+	}
+
+	cfg.PinRetryJitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := retryDelay(cfg, 0)
+		if d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("delay out of the jitter range: %s", d)
+		}
+	}
+}
+
+func TestPinRetries(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.PinRetries = 2
+	cfg.PinRetryDelay = 50 * time.Millisecond
+	cfg.PinRetryMaxDelay = 100 * time.Millisecond
+	cfg.PinRetryJitter = 0
+
+	spt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
+	defer spt.Shutdown(ctx)
+	spt.SetClient(mockRPCClient(t))
+
+	// The mock always fails to pin pinCancelCid.
+	err := spt.Track(ctx, api.PinWithOpts(pinCancelCid, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	first := spt.Status(ctx, pinCancelCid)
+	if first.Status != api.TrackerStatusPinError {
+		t.Fatalf("expected a pin error: %s", first.Status)
+	}
+
+	spt.retryMu.Lock()
+	pending := len(spt.retries)
+	spt.retryMu.Unlock()
+	if pending != 1 {
+		t.Errorf("expected a pending retry, got %d", pending)
+	}
+
+	time.Sleep(time.Second)
+	last := spt.Status(ctx, pinCancelCid)
+	if last.Status != api.TrackerStatusPinError {
+		t.Errorf("expected the pin to stay in error after the retries: %s", last.Status)
+	}
+	if !last.TS.After(first.TS) {
+		t.Error("expected the pin to have been retried")
+	}
+	spt.retryMu.Lock()
+	pending = len(spt.retries)
+	spt.retryMu.Unlock()
+	if pending != 0 {
+		t.Errorf("expected no pending retries after giving up, got %d", pending)
+	}
+}
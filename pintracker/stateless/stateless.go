@@ -6,6 +6,7 @@ package stateless
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -13,8 +14,10 @@ import (
 
 	"github.com/ipfs/ipfs-cluster/api"
 	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
+	"github.com/ipfs/ipfs-cluster/pintracker/util"
 
 	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 	peer "github.com/libp2p/go-libp2p-peer"
@@ -40,45 +43,155 @@ type Tracker struct {
 	pinCh   chan *optracker.Operation
 	unpinCh chan *optracker.Operation
 
+	// pin operations wait here until they are dispatched to pinCh, by
+	// priority and fairly across namespaces.
+	pinQueue *pinQueue
+
+	// records the queued operations so that they survive restarts.
+	queueStore ds.Datastore
+	queueMu    sync.Mutex
+
+	// failed operations waiting to be retried, by Cid.
+	retryMu sync.Mutex
+	retries map[string]*retryState
+
+	// while set, the shared state is trusted instead of listing the
+	// IPFS pins (see Config.TrustStateOnStartup).
+	trustMu    sync.RWMutex
+	trustState bool
+
+	// while paused, the workers do not start queued operations.
+	// pauseChanged is closed and replaced every time it changes.
+	pauseMu      sync.Mutex
+	paused       bool
+	pauseChanged chan struct{}
+
+	// events waiting for their hooks to run (see Config.Hooks).
+	hookCh chan *hookCall
+
 	shutdownMu sync.Mutex
 	shutdown   bool
 	wg         sync.WaitGroup
 }
 
-// New creates a new StatelessPinTracker.
-func New(cfg *Config, pid peer.ID, peerName string) *Tracker {
+// New creates a new StatelessPinTracker. Completed operations are compacted
+// into the given datastore according to the configuration, and queued
+// operations are recorded in it.
+func New(cfg *Config, pid peer.ID, peerName string, store ds.Datastore) *Tracker {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	optrk := optracker.NewOperationTrackerWithCompaction(
+		ctx,
+		pid,
+		peerName,
+		optracker.CompactionOptions{
+			Datastore:    store,
+			MaxCompleted: cfg.MaxCompletedOperations,
+			CompactAfter: cfg.CompactCompletedAfter,
+		},
+	)
+
 	spt := &Tracker{
-		config:    cfg,
-		peerID:    pid,
-		ctx:       ctx,
-		cancel:    cancel,
-		optracker: optracker.NewOperationTracker(ctx, pid, peerName),
-		rpcReady:  make(chan struct{}, 1),
-		pinCh:     make(chan *optracker.Operation, cfg.MaxPinQueueSize),
-		unpinCh:   make(chan *optracker.Operation, cfg.MaxPinQueueSize),
-	}
+		config:     cfg,
+		peerID:     pid,
+		ctx:        ctx,
+		cancel:     cancel,
+		optracker:  optrk,
+		rpcReady:   make(chan struct{}, 1),
+		pinCh:      make(chan *optracker.Operation),
+		unpinCh:    make(chan *optracker.Operation, cfg.MaxPinQueueSize),
+		pinQueue:   newPinQueue(cfg.MaxPinQueueSize, cfg.NamespaceWeights),
+		queueStore: newQueueStore(store),
+		retries:    make(map[string]*retryState),
+		trustState: cfg.TrustStateOnStartup,
+
+		pauseChanged: make(chan struct{}),
+		hookCh:       make(chan *hookCall, hookQueueSize),
+	}
+	// Pin operations are only handed to the workers when they are
+	// free, so that the pin queue decides the order.
+	go spt.dispatchPins()
 
 	for i := 0; i < spt.config.ConcurrentPins; i++ {
 		go spt.opWorker(spt.pin, spt.pinCh)
 	}
 	go spt.opWorker(spt.unpin, spt.unpinCh)
+
+	if cfg.TrustStateOnStartup {
+		spt.wg.Add(1)
+		go spt.verifyState()
+	}
+	if len(cfg.Hooks) > 0 {
+		spt.wg.Add(1)
+		go spt.runHooks()
+	}
 	return spt
 }
 
+// dispatchPins sends the operations in the pin queue to the pin workers.
+func (spt *Tracker) dispatchPins() {
+	for {
+		op, ok := spt.pinQueue.pop()
+		if !ok {
+			select {
+			case <-spt.pinQueue.notify:
+				continue
+			case <-spt.ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case spt.pinCh <- op:
+		case <-spt.ctx.Done():
+			return
+		}
+	}
+}
+
+// namespace returns the namespace of the pin in the given operation.
+func (spt *Tracker) namespace(op *optracker.Operation) string {
+	if spt.config.NamespaceMetadataKey == "" {
+		return ""
+	}
+	return op.Pin().Metadata[spt.config.NamespaceMetadataKey]
+}
+
 // receives a pin Function (pin or unpin) and a channel.
 // Used for both pinning and unpinning
 func (spt *Tracker) opWorker(pinF func(*optracker.Operation) error, opChan chan *optracker.Operation) {
 	logger.Debug("entering opworker")
 	ticker := time.NewTicker(10 * time.Second) //TODO(ajl): make config var
 	for {
+		paused, pauseChanged := spt.pauseState()
+		ops := opChan
+		if paused {
+			// operations stay queued until resumed
+			ops = nil
+		}
+
 		select {
+		case <-pauseChanged:
 		case <-ticker.C:
 			// every tick, clear out all Done operations
 			spt.optracker.CleanAllDone(spt.ctx)
-		case op := <-opChan:
-			if cont := applyPinF(pinF, op); cont {
+		case op := <-ops:
+			cont := applyPinF(pinF, op, spt.config.OperationTimeout)
+			switch op.Phase() {
+			case optracker.PhaseDone:
+				spt.forgetQueued(op)
+				spt.forgetRetries(op)
+				if op.Type() == optracker.OperationPin {
+					spt.triggerHooks(HookPinned, op)
+				} else {
+					spt.triggerHooks(HookUnpinned, op)
+				}
+			case optracker.PhaseError:
+				if !spt.scheduleRetry(op) {
+					spt.triggerHooks(HookError, op)
+				}
+			}
+			if cont {
 				continue
 			}
 
@@ -90,15 +203,20 @@ func (spt *Tracker) opWorker(pinF func(*optracker.Operation) error, opChan chan
 }
 
 // applyPinF returns true if caller should call `continue` inside calling loop.
-func applyPinF(pinF func(*optracker.Operation) error, op *optracker.Operation) bool {
+func applyPinF(pinF func(*optracker.Operation) error, op *optracker.Operation, timeout time.Duration) bool {
 	if op.Cancelled() {
 		// operation was cancelled. Move on.
 		// This saves some time, but not 100% needed.
 		return true
 	}
 	op.SetPhase(optracker.PhaseInProgress)
+	op.StartTimeout(timeout)
 	err := pinF(op) // call pin/unpin
 	if err != nil {
+		if d, ok := op.TimedOut(); ok {
+			op.SetError(fmt.Errorf("operation timed out after %s: %s", d, err))
+			return true
+		}
 		if op.Cancelled() {
 			// there was an error because
 			// we were cancelled. Move on.
@@ -153,42 +271,105 @@ func (spt *Tracker) unpin(op *optracker.Operation) error {
 
 // Enqueue puts a new operation on the queue, unless ongoing exists.
 func (spt *Tracker) enqueue(ctx context.Context, c *api.Pin, typ optracker.OperationType) error {
+	_, err := spt.enqueueOp(ctx, c, typ)
+	return err
+}
+
+// enqueueOp works like enqueue and returns the new operation, if any.
+func (spt *Tracker) enqueueOp(ctx context.Context, c *api.Pin, typ optracker.OperationType) (*optracker.Operation, error) {
 	ctx, span := trace.StartSpan(ctx, "tracker/stateless/enqueue")
 	defer span.End()
 
 	logger.Debugf("entering enqueue: pin: %+v", c)
 	op := spt.optracker.TrackNewOperation(ctx, c, typ, optracker.PhaseQueued)
 	if op == nil {
-		return nil // ongoing pin operation.
+		return nil, nil // ongoing pin operation.
 	}
 
-	var ch chan *optracker.Operation
+	if typ != optracker.OperationPin && typ != optracker.OperationUnpin {
+		return nil, errors.New("operation doesn't have a associated channel")
+	}
 
-	switch typ {
-	case optracker.OperationPin:
-		ch = spt.pinCh
-	case optracker.OperationUnpin:
-		ch = spt.unpinCh
-	default:
-		return errors.New("operation doesn't have a associated channel")
+	spt.recordQueued(op)
+	queued := false
+	if typ == optracker.OperationPin {
+		queued = spt.pinQueue.push(spt.namespace(op), op.Pin().Priority, op)
+	} else {
+		select {
+		case spt.unpinCh <- op:
+			queued = true
+		default:
+		}
 	}
 
-	select {
-	case ch <- op:
-	default:
+	if !queued {
 		err := errors.New("queue is full")
 		op.SetError(err)
 		op.Cancel()
 		logger.Error(err.Error())
-		return err
+		return op, err
 	}
+	return op, nil
+}
+
+// Pause stops the tracker from starting queued operations until Resume is
+// called, i.e. during IPFS repository maintenance or a daemon upgrade.
+// Operations in progress are not interrupted and new ones are still queued,
+// and reported as paused. The tracker is not paused after a restart.
+func (spt *Tracker) Pause(ctx context.Context) error {
+	_, span := trace.StartSpan(ctx, "tracker/stateless/Pause")
+	defer span.End()
+
+	spt.setPaused(true)
 	return nil
 }
 
+// Resume lets the tracker start queued operations again after Pause.
+func (spt *Tracker) Resume(ctx context.Context) error {
+	_, span := trace.StartSpan(ctx, "tracker/stateless/Resume")
+	defer span.End()
+
+	spt.setPaused(false)
+	return nil
+}
+
+// Paused returns whether the tracker is paused.
+func (spt *Tracker) Paused() bool {
+	paused, _ := spt.pauseState()
+	return paused
+}
+
+func (spt *Tracker) setPaused(paused bool) {
+	spt.pauseMu.Lock()
+	defer spt.pauseMu.Unlock()
+	if spt.paused == paused {
+		return
+	}
+	spt.paused = paused
+	spt.optracker.SetPaused(paused)
+	close(spt.pauseChanged)
+	spt.pauseChanged = make(chan struct{})
+	if paused {
+		logger.Info("pin tracker paused: queued operations will wait until resumed")
+	} else {
+		logger.Info("pin tracker resumed")
+	}
+}
+
+// pauseState returns whether the tracker is paused and a channel which is
+// closed when that changes.
+func (spt *Tracker) pauseState() (bool, <-chan struct{}) {
+	spt.pauseMu.Lock()
+	defer spt.pauseMu.Unlock()
+	return spt.paused, spt.pauseChanged
+}
+
 // SetClient makes the StatelessPinTracker ready to perform RPC requests to
-// other components.
+// other components. The operations recorded as queued when the peer
+// stopped are queued again.
 func (spt *Tracker) SetClient(c *rpc.Client) {
 	spt.rpcClient = c
+	spt.restoreQueued(spt.ctx)
 	spt.rpcReady <- struct{}{}
 }
 
@@ -262,21 +443,42 @@ func (spt *Tracker) Untrack(ctx context.Context, c cid.Cid) error {
 	return spt.enqueue(ctx, api.PinCid(c), optracker.OperationUnpin)
 }
 
-// StatusAll returns information for all Cids pinned to the local IPFS node.
-func (spt *Tracker) StatusAll(ctx context.Context) []*api.PinInfo {
+// localStatusFilter matches the statuses which are obtained from the
+// shared state and the IPFS daemon rather than from the operation tracker.
+const localStatusFilter = api.TrackerStatusPinned |
+	api.TrackerStatusUnpinned |
+	api.TrackerStatusRemote |
+	api.TrackerStatusSharded
+
+// StatusAll returns information for all Cids pinned to the local IPFS node
+// which match the given filter. The IPFS daemon is not queried when the
+// filter only asks for pins which are not allocated to this peer (remote
+// or sharded). Neither the shared state nor the IPFS daemon are queried
+// when the filter only asks for in-flight or errored items, as those are
+// all known to the operation tracker.
+func (spt *Tracker) StatusAll(ctx context.Context, filter api.TrackerStatus) []*api.PinInfo {
 	ctx, span := trace.StartSpan(ctx, "tracker/stateless/StatusAll")
 	defer span.End()
 
-	pininfos, err := spt.localStatus(ctx, true)
-	if err != nil {
-		logger.Error(err)
-		return nil
+	pininfos := make(map[string]*api.PinInfo)
+	if filter == api.TrackerStatusUndefined || filter&localStatusFilter != 0 {
+		var err error
+		pininfos, err = spt.localStatus(ctx, true, filter)
+		if err != nil {
+			logger.Error(err)
+			return nil
+		}
 	}
 
 	// get all inflight operations from optracker and
 	// put them into the map, deduplicating any already 'pinned' items with
-	// their inflight operation
+	// their inflight operation. Items whose operation does not match
+	// the filter are dropped.
 	for _, infop := range spt.optracker.GetAll(ctx) {
+		if !infop.Status.Match(filter) {
+			delete(pininfos, infop.Cid.String())
+			continue
+		}
 		pininfos[infop.Cid.String()] = infop
 	}
 
@@ -284,9 +486,29 @@ func (spt *Tracker) StatusAll(ctx context.Context) []*api.PinInfo {
 	for _, pi := range pininfos {
 		pis = append(pis, pi)
 	}
+
+	err := util.AttachBitswapStat(ctx, spt.rpcClient, pis...)
+	if err != nil {
+		logger.Debugf("error obtaining bitswap stats: %s", err)
+	}
+	if err := util.AttachPinProgress(ctx, spt.rpcClient, pis...); err != nil {
+		logger.Debugf("error obtaining pin progress: %s", err)
+	}
 	return pis
 }
 
+// OperationStats returns the number of queued, in progress and errored
+// operations, along with the age of the oldest of each.
+func (spt *Tracker) OperationStats(ctx context.Context) []*api.OperationStats {
+	return spt.optracker.Stats(ctx)
+}
+
+// OperationCounts returns the number of queued and errored operations.
+func (spt *Tracker) OperationCounts(ctx context.Context) (int, int) {
+	return spt.optracker.PhaseCount(ctx, optracker.PhaseQueued),
+		spt.optracker.PhaseCount(ctx, optracker.PhaseError)
+}
+
 // Status returns information for a Cid pinned to the local IPFS node.
 func (spt *Tracker) Status(ctx context.Context, c cid.Cid) *api.PinInfo {
 	ctx, span := trace.StartSpan(ctx, "tracker/stateless/Status")
@@ -295,13 +517,22 @@ func (spt *Tracker) Status(ctx context.Context, c cid.Cid) *api.PinInfo {
 	// check if c has an inflight operation or errorred operation in optracker
 	if oppi, ok := spt.optracker.GetExists(ctx, c); ok {
 		// if it does return the status of the operation
+		err := util.AttachBitswapStat(ctx, spt.rpcClient, oppi)
+		if err != nil {
+			logger.Debugf("error obtaining bitswap stats: %s", err)
+		}
+		err = util.AttachPinProgress(ctx, spt.rpcClient, oppi)
+		if err != nil {
+			logger.Debugf("error obtaining pin progress: %s", err)
+		}
 		return oppi
 	}
 
 	// check global state to see if cluster should even be caring about
 	// the provided cid
 	var gpin api.Pin
-	err := spt.rpcClient.Call(
+	err := spt.rpcClient.CallContext(
+		ctx,
 		"",
 		"Cluster",
 		"PinGet",
@@ -350,7 +581,8 @@ func (spt *Tracker) Status(ctx context.Context, c cid.Cid) *api.PinInfo {
 
 	// else attempt to get status from ipfs node
 	var ips api.IPFSPinStatus
-	err = spt.rpcClient.Call(
+	err = spt.rpcClient.CallContext(
+		ctx,
 		"",
 		"IPFSConnector",
 		"PinLsCid",
@@ -382,8 +614,14 @@ func (spt *Tracker) SyncAll(ctx context.Context) ([]*api.PinInfo, error) {
 	ctx, span := trace.StartSpan(ctx, "tracker/stateless/SyncAll")
 	defer span.End()
 
+	// Errors cannot be cleared based on the trusted state.
+	if spt.trustingState() {
+		logger.Debug("SyncAll: the shared state has not been verified yet")
+		return spt.getErrorsAll(ctx), nil
+	}
+
 	// get ipfs status for all
-	localpis, err := spt.localStatus(ctx, false)
+	localpis, err := spt.localStatus(ctx, false, api.TrackerStatusUndefined)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
@@ -418,7 +656,8 @@ func (spt *Tracker) Sync(ctx context.Context, c cid.Cid) (*api.PinInfo, error) {
 		// check global state to see if cluster should even be caring about
 		// the provided cid
 		var gpin api.Pin
-		err := spt.rpcClient.Call(
+		err := spt.rpcClient.CallContext(
+			ctx,
 			"",
 			"Cluster",
 			"PinGet",
@@ -460,7 +699,8 @@ func (spt *Tracker) Sync(ctx context.Context, c cid.Cid) (*api.PinInfo, error) {
 	if oppi.Status == api.TrackerStatusPinError {
 		// else attempt to get status from ipfs node
 		var ips api.IPFSPinStatus
-		err := spt.rpcClient.Call(
+		err := spt.rpcClient.CallContext(
+			ctx,
 			"",
 			"IPFSConnector",
 			"PinLsCid",
@@ -497,7 +737,7 @@ func (spt *Tracker) RecoverAll(ctx context.Context) ([]*api.PinInfo, error) {
 	ctx, span := trace.StartSpan(ctx, "tracker/stateless/RecoverAll")
 	defer span.End()
 
-	statuses := spt.StatusAll(ctx)
+	statuses := spt.StatusAll(ctx, api.TrackerStatusUndefined)
 	resp := make([]*api.PinInfo, 0)
 	for _, st := range statuses {
 		r, err := spt.Recover(ctx, st.Cid)
@@ -525,7 +765,27 @@ func (spt *Tracker) Recover(ctx context.Context, c cid.Cid) (*api.PinInfo, error
 	var err error
 	switch pInfo.Status {
 	case api.TrackerStatusPinError:
-		err = spt.enqueue(ctx, api.PinCid(c), optracker.OperationPin)
+		// re-pin the item as it is in the shared state, so that its
+		// options (max depth, priority, timeout...) are respected.
+		var gpin api.Pin
+		err = spt.rpcClient.CallContext(
+			ctx,
+			"",
+			"Cluster",
+			"PinGet",
+			c,
+			&gpin,
+		)
+		if err != nil {
+			if rpc.IsRPCError(err) {
+				logger.Error(err)
+				return spt.Status(ctx, c), err
+			}
+			// it isn't in the global state: nothing to recover
+			spt.optracker.CleanError(ctx, c)
+			return spt.Status(ctx, c), nil
+		}
+		err = spt.enqueue(ctx, &gpin, optracker.OperationPin)
 	case api.TrackerStatusUnpinError:
 		err = spt.enqueue(ctx, api.PinCid(c), optracker.OperationUnpin)
 	}
@@ -546,7 +806,7 @@ func (spt *Tracker) ipfsStatusAll(ctx context.Context) (map[string]*api.PinInfo,
 		"",
 		"IPFSConnector",
 		"PinLs",
-		"recursive",
+		"recursive,direct",
 		&ipsMap,
 	)
 	if err != nil {
@@ -573,8 +833,10 @@ func (spt *Tracker) ipfsStatusAll(ctx context.Context) (map[string]*api.PinInfo,
 
 // localStatus returns a joint set of consensusState and ipfsStatus
 // marking pins which should be meta or remote and leaving any ipfs pins that
-// aren't in the consensusState out.
-func (spt *Tracker) localStatus(ctx context.Context, incExtra bool) (map[string]*api.PinInfo, error) {
+// aren't in the consensusState out. Only items matching the given filter
+// are included. While the shared state is trusted, the pins allocated to
+// this peer are reported as pinned without asking IPFS.
+func (spt *Tracker) localStatus(ctx context.Context, incExtra bool, filter api.TrackerStatus) (map[string]*api.PinInfo, error) {
 	ctx, span := trace.StartSpan(ctx, "tracker/stateless/localStatus")
 	defer span.End()
 
@@ -595,38 +857,59 @@ func (spt *Tracker) localStatus(ctx context.Context, incExtra bool) (map[string]
 		return nil, err
 	}
 
-	// get statuses from ipfs node first
-	localpis, err := spt.ipfsStatusAll(ctx)
-	if err != nil {
-		logger.Error(err)
-		return nil, err
+	// get statuses from ipfs node first, unless we are only
+	// interested in items which are not pinned here.
+	trusted := spt.trustingState()
+	var localpis map[string]*api.PinInfo
+	if !trusted && (filter == api.TrackerStatusUndefined ||
+		filter&^(api.TrackerStatusRemote|api.TrackerStatusSharded) != 0) {
+		localpis, err = spt.ipfsStatusAll(ctx)
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
 	}
 
 	for _, p := range statePins {
 		pCid := p.Cid.String()
 		if p.Type == api.MetaType && incExtra {
 			// add pin to pininfos with sharded status
-			pininfos[pCid] = &api.PinInfo{
-				Cid:    p.Cid,
-				Peer:   spt.peerID,
-				Status: api.TrackerStatusSharded,
-				TS:     time.Now(),
+			if api.TrackerStatusSharded.Match(filter) {
+				pininfos[pCid] = &api.PinInfo{
+					Cid:    p.Cid,
+					Peer:   spt.peerID,
+					Status: api.TrackerStatusSharded,
+					TS:     time.Now(),
+				}
 			}
 			continue
 		}
 
 		if p.IsRemotePin(spt.peerID) && incExtra {
 			// add pin to pininfos with a status of remote
-			pininfos[pCid] = &api.PinInfo{
-				Cid:    p.Cid,
-				Peer:   spt.peerID,
-				Status: api.TrackerStatusRemote,
-				TS:     time.Now(),
+			if api.TrackerStatusRemote.Match(filter) {
+				pininfos[pCid] = &api.PinInfo{
+					Cid:    p.Cid,
+					Peer:   spt.peerID,
+					Status: api.TrackerStatusRemote,
+					TS:     time.Now(),
+				}
+			}
+			continue
+		}
+		if trusted {
+			if p.Type != api.MetaType && !p.IsRemotePin(spt.peerID) && api.TrackerStatusPinned.Match(filter) {
+				pininfos[pCid] = &api.PinInfo{
+					Cid:    p.Cid,
+					Peer:   spt.peerID,
+					Status: api.TrackerStatusPinned,
+					TS:     time.Now(),
+				}
 			}
 			continue
 		}
 		// lookup p in localpis
-		if lp, ok := localpis[pCid]; ok {
+		if lp, ok := localpis[pCid]; ok && lp.Status.Match(filter) {
 			pininfos[pCid] = lp
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +12,8 @@ import (
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs/ipfs-cluster/pintracker/optracker"
 	"github.com/ipfs/ipfs-cluster/test"
 )
 
@@ -46,7 +49,11 @@ func mockRPCClient(t *testing.T) *rpc.Client {
 func (mock *mockIPFS) Pin(ctx context.Context, in *api.Pin, out *struct{}) error {
 	switch in.Cid.String() {
 	case test.SlowCid1.String():
-		time.Sleep(2 * time.Second)
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	case pinCancelCid.String():
 		return ErrPinCancelCid
 	}
@@ -81,6 +88,21 @@ func (mock *mockIPFS) PinLsCid(ctx context.Context, in cid.Cid, out *api.IPFSPin
 	return nil
 }
 
+func (mock *mockIPFS) BitswapStat(ctx context.Context, in struct{}, out *api.IPFSBitswapStat) error {
+	*out = api.IPFSBitswapStat{
+		WantlistSize: 5,
+		Peers:        1,
+	}
+	return nil
+}
+
+func (mock *mockIPFS) PinProgress(ctx context.Context, in struct{}, out *[]*api.PinProgress) error {
+	*out = []*api.PinProgress{
+		{Cid: test.SlowCid1, Blocks: 10, Bytes: 2560},
+	}
+	return nil
+}
+
 func (mock *mockCluster) Pins(ctx context.Context, in struct{}, out *[]*api.Pin) error {
 	*out = []*api.Pin{
 		api.PinWithOpts(test.Cid1, pinOpts),
@@ -105,7 +127,7 @@ func testSlowStatelessPinTracker(t *testing.T) *Tracker {
 	cfg := &Config{}
 	cfg.Default()
 	cfg.ConcurrentPins = 1
-	mpt := New(cfg, test.PeerID1, test.PeerName1)
+	mpt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
 	mpt.SetClient(mockRPCClient(t))
 	return mpt
 }
@@ -114,7 +136,7 @@ func testStatelessPinTracker(t testing.TB) *Tracker {
 	cfg := &Config{}
 	cfg.Default()
 	cfg.ConcurrentPins = 1
-	spt := New(cfg, test.PeerID1, test.PeerName1)
+	spt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
 	spt.SetClient(test.NewMockRPCClient(t))
 	return spt
 }
@@ -201,6 +223,77 @@ func TestTrackUntrackWithCancel(t *testing.T) {
 	}
 }
 
+func TestStatusPinningBitswapStat(t *testing.T) {
+	ctx := context.Background()
+	spt := testSlowStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	slowPin := api.PinWithOpts(test.SlowCid1, pinOpts)
+	err := spt.Track(ctx, slowPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond) // let pinning start
+
+	pInfo := spt.Status(ctx, slowPin.Cid)
+	if pInfo.Status != api.TrackerStatusPinning {
+		t.Fatal("slowPin should be pinning and is:", pInfo.Status)
+	}
+	if pInfo.Bitswap == nil || pInfo.Bitswap.WantlistSize != 5 {
+		t.Error("expected bitswap stats for the pinning item")
+	}
+
+	pInfo = spt.Status(ctx, test.Cid1)
+	if pInfo.Bitswap != nil {
+		t.Error("bitswap stats should only be set on pinning items")
+	}
+
+	for _, pi := range spt.StatusAll(ctx, api.TrackerStatusPinning) {
+		if pi.Bitswap == nil {
+			t.Error("expected bitswap stats in StatusAll")
+		}
+	}
+}
+
+func TestStatusPinningProgress(t *testing.T) {
+	ctx := context.Background()
+	spt := testSlowStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	slowPin := api.PinWithOpts(test.SlowCid1, pinOpts)
+	slowPin.Size = 25600
+	err := spt.Track(ctx, slowPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond) // let pinning start
+
+	pInfo := spt.Status(ctx, slowPin.Cid)
+	if pInfo.Status != api.TrackerStatusPinning {
+		t.Fatal("slowPin should be pinning and is:", pInfo.Status)
+	}
+	prog := pInfo.Progress
+	if prog == nil || prog.Blocks != 10 || prog.Bytes != 2560 {
+		t.Fatalf("expected the progress of the pinning item: %+v", prog)
+	}
+	if prog.TotalBytes != 25600 || prog.Percent != 10 {
+		t.Errorf("expected a 10%% completion estimate: %+v", prog)
+	}
+
+	pInfo = spt.Status(ctx, test.Cid1)
+	if pInfo.Progress != nil {
+		t.Error("progress should only be set on pinning items")
+	}
+
+	for _, pi := range spt.StatusAll(ctx, api.TrackerStatusPinning) {
+		if pi.Progress == nil || pi.Progress.Blocks != 10 {
+			t.Error("expected the progress in StatusAll")
+		}
+	}
+}
+
 // This tracks a slow CID and then tracks a fast/normal one.
 // Because we are pinning the slow CID, the fast one will stay
 // queued. We proceed to untrack it then. Since it was never
@@ -469,6 +562,214 @@ func BenchmarkTracker_localStatus(b *testing.B) {
 	tracker := testStatelessPinTracker(b)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		tracker.localStatus(context.Background(), true)
+		tracker.localStatus(context.Background(), true, api.TrackerStatusUndefined)
+	}
+}
+
+type noStateCluster struct{}
+
+func (mock *noStateCluster) Pins(ctx context.Context, in struct{}, out *[]*api.Pin) error {
+	return errors.New("the shared state should not be listed")
+}
+
+func TestStatusAllTrackedOnlyFilter(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	spt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
+	defer spt.Shutdown(ctx)
+
+	s := rpc.NewServer(nil, "mock")
+	err := s.RegisterName("Cluster", &noStateCluster{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spt.SetClient(rpc.NewClientWithServer(nil, "mock", s))
+
+	op := spt.optracker.TrackNewOperation(ctx, api.PinWithOpts(test.Cid1, pinOpts), optracker.OperationPin, optracker.PhaseError)
+	if op == nil {
+		t.Fatal("expected an operation")
+	}
+
+	pinfos := spt.StatusAll(ctx, api.TrackerStatusPinError|api.TrackerStatusQueued)
+	if len(pinfos) != 1 || !pinfos[0].Cid.Equals(test.Cid1) {
+		t.Fatalf("expected only the errored item: %+v", pinfos)
+	}
+
+	// Pinned items need the shared state
+	if len(spt.StatusAll(ctx, api.TrackerStatusPinned)) != 0 {
+		t.Error("expected no results when the state cannot be listed")
+	}
+}
+
+func TestRecoverFromState(t *testing.T) {
+	ctx := context.Background()
+	spt := testStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	// Cid4 is not part of the shared state
+	spt.optracker.TrackNewOperation(ctx, api.PinCid(test.Cid4), optracker.OperationPin, optracker.PhaseError)
+	pinfo, err := spt.Recover(ctx, test.Cid4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinfo.Status != api.TrackerStatusUnpinned {
+		t.Errorf("expected unpinned, got %s", pinfo.Status)
+	}
+	if _, ok := spt.optracker.GetExists(ctx, test.Cid4); ok {
+		t.Error("the error should have been cleaned")
+	}
+
+	spt.optracker.TrackNewOperation(ctx, api.PinCid(test.Cid1), optracker.OperationPin, optracker.PhaseError)
+	_, err = spt.Recover(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	pinfo = spt.Status(ctx, test.Cid1)
+	if pinfo.Status != api.TrackerStatusPinned {
+		t.Errorf("expected pinned, got %s", pinfo.Status)
+	}
+}
+
+func TestTrackWithFairQueue(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.NamespaceMetadataKey = "tenant"
+	spt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
+	spt.SetClient(test.NewMockRPCClient(t))
+	defer spt.Shutdown(ctx)
+
+	pin := api.PinWithOpts(test.Cid1, pinOpts)
+	pin.Metadata = map[string]string{"tenant": "a"}
+	err := spt.Track(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if spt.pinQueue.len() != 0 {
+		t.Error("expected the operation to be dispatched")
+	}
+	pinfo := spt.Status(ctx, test.Cid1)
+	if pinfo.Status != api.TrackerStatusPinned {
+		t.Errorf("expected the item to be pinned: %s", pinfo.Status)
+	}
+}
+
+func TestTrustStateOnStartup(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.TrustStateOnStartup = true
+	cfg.VerifyBatchSize = 1
+	cfg.VerifyBatchInterval = 0
+	spt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
+	defer spt.Shutdown(ctx)
+
+	// Set the client without signaling it, so that the verification
+	// does not start yet.
+	spt.rpcClient = mockRPCClient(t)
+
+	// Cid3 is not pinned by IPFS, but the state is trusted.
+	pinfos := spt.StatusAll(ctx, api.TrackerStatusPinned)
+	if len(pinfos) != 2 {
+		t.Fatalf("expected every pin in the state to be pinned: %+v", pinfos)
+	}
+	pinfos, err := spt.SyncAll(ctx)
+	if err != nil || len(pinfos) != 0 {
+		t.Error("SyncAll should do nothing while the state is trusted")
+	}
+
+	spt.rpcReady <- struct{}{}
+	for i := 0; i < 20 && spt.trustingState(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if spt.trustingState() {
+		t.Fatal("expected the state to be verified")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// The mock fails to pin Cid3.
+	pinfo := spt.Status(ctx, test.Cid3)
+	if pinfo.Status != api.TrackerStatusPinError {
+		t.Errorf("expected Cid3 to be tracked again: %s", pinfo.Status)
+	}
+	pinfos = spt.StatusAll(ctx, api.TrackerStatusPinned)
+	if len(pinfos) != 1 || !pinfos[0].Cid.Equals(test.Cid1) {
+		t.Errorf("expected only Cid1 to be pinned: %+v", pinfos)
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ConcurrentPins = 1
+	cfg.OperationTimeout = 100 * time.Millisecond
+	spt := New(cfg, test.PeerID1, test.PeerName1, inmem.New())
+	defer spt.Shutdown(ctx)
+	spt.SetClient(mockRPCClient(t))
+
+	err := spt.Track(ctx, api.PinWithOpts(test.SlowCid1, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	pinfo := spt.Status(ctx, test.SlowCid1)
+	if pinfo.Status != api.TrackerStatusPinError || !strings.Contains(pinfo.Error, "timed out") {
+		t.Errorf("expected the pin to time out: %s %s", pinfo.Status, pinfo.Error)
+	}
+
+	// The timeout of the pin takes precedence.
+	opts := pinOpts
+	opts.PinTimeout = time.Minute
+	err = spt.Track(ctx, api.PinWithOpts(test.SlowCid1, opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	pinfo = spt.Status(ctx, test.SlowCid1)
+	if pinfo.Status != api.TrackerStatusPinning {
+		t.Errorf("expected the pin to be in progress: %s", pinfo.Status)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	ctx := context.Background()
+	spt := testSlowStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	err := spt.Pause(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !spt.Paused() {
+		t.Fatal("tracker should be paused")
+	}
+
+	err = spt.Track(ctx, api.PinWithOpts(test.Cid2, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	pinfo := spt.Status(ctx, test.Cid2)
+	if pinfo.Status != api.TrackerStatusPinQueued || !pinfo.Paused {
+		t.Errorf("expected a paused queued pin: %s %t", pinfo.Status, pinfo.Paused)
+	}
+
+	err = spt.Resume(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spt.Paused() {
+		t.Fatal("tracker should not be paused")
+	}
+	time.Sleep(200 * time.Millisecond)
+	pinfo = spt.Status(ctx, test.Cid2)
+	if pinfo.Status != api.TrackerStatusPinned || pinfo.Paused {
+		t.Errorf("expected the pin to be done: %s %t", pinfo.Status, pinfo.Paused)
 	}
 }
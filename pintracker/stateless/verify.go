@@ -0,0 +1,139 @@
+package stateless
+
+import (
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// When TrustStateOnStartup is set, the tracker does not list the IPFS pins
+// to find out what is pinned when the peer starts. It assumes that the pins
+// allocated to this peer in the shared state are pinned and verifies them
+// in the background instead, one batch at a time, pinning those which are
+// missing. Once done, the IPFS daemon is asked as usual.
+
+// stateRetryInterval is how long to wait before retrying to obtain the
+// shared state for verification.
+var stateRetryInterval = 5 * time.Second
+
+// trustingState returns true while the shared state is trusted.
+func (spt *Tracker) trustingState() bool {
+	spt.trustMu.RLock()
+	defer spt.trustMu.RUnlock()
+	return spt.trustState
+}
+
+func (spt *Tracker) stopTrustingState() {
+	spt.trustMu.Lock()
+	defer spt.trustMu.Unlock()
+	spt.trustState = false
+}
+
+// verifyState checks in batches that the pins in the shared state which are
+// allocated to this peer are pinned by IPFS, and tracks those which are not,
+// so that they are pinned.
+func (spt *Tracker) verifyState() {
+	defer spt.wg.Done()
+
+	select {
+	case <-spt.rpcReady:
+	case <-spt.ctx.Done():
+		return
+	}
+	if spt.ctx.Err() != nil { // rpcReady is closed on shutdown
+		return
+	}
+
+	ctx, span := trace.StartSpan(spt.ctx, "tracker/stateless/verifyState")
+	defer span.End()
+
+	var statePins []*api.Pin
+	for {
+		err := spt.rpcClient.CallContext(
+			ctx,
+			"",
+			"Cluster",
+			"Pins",
+			struct{}{},
+			&statePins,
+		)
+		if err == nil {
+			break
+		}
+		logger.Errorf("error obtaining the shared state to verify it: %s", err)
+		select {
+		case <-time.After(stateRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	var pins []*api.Pin
+	for _, p := range statePins {
+		if p.Type != api.MetaType && !p.IsRemotePin(spt.peerID) {
+			pins = append(pins, p)
+		}
+	}
+	logger.Infof("verifying %d pins from the shared state in the background", len(pins))
+
+	missing := 0
+	for start := 0; start < len(pins); start += spt.config.VerifyBatchSize {
+		end := start + spt.config.VerifyBatchSize
+		if end > len(pins) {
+			end = len(pins)
+		}
+		for _, p := range pins[start:end] {
+			if !spt.verifyPin(p) {
+				missing++
+			}
+		}
+		logger.Debugf("verified %d/%d pins (%d missing)", end, len(pins), missing)
+		if end == len(pins) {
+			break
+		}
+
+		select {
+		case <-time.After(spt.config.VerifyBatchInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	spt.stopTrustingState()
+	logger.Infof("shared state verified: %d pins were missing and have been queued", missing)
+}
+
+// verifyPin returns false when the given pin is not pinned by IPFS, in
+// which case it is tracked again. Pins with ongoing operations are left
+// alone, as are those which cannot be checked.
+func (spt *Tracker) verifyPin(p *api.Pin) bool {
+	ctx, span := trace.StartSpan(spt.ctx, "tracker/stateless/verifyPin")
+	defer span.End()
+
+	if _, ok := spt.optracker.GetExists(ctx, p.Cid); ok {
+		return true
+	}
+
+	var ips api.IPFSPinStatus
+	err := spt.rpcClient.CallContext(
+		ctx,
+		"",
+		"IPFSConnector",
+		"PinLsCid",
+		p.Cid,
+		&ips,
+	)
+	if err != nil {
+		logger.Errorf("error verifying %s: %s", p.Cid, err)
+		return true
+	}
+	if ips.ToTrackerStatus() == api.TrackerStatusPinned {
+		return true
+	}
+
+	logger.Debugf("%s is in the shared state but not pinned: tracking it", p.Cid)
+	spt.Track(ctx, p)
+	return false
+}
@@ -1,14 +1,21 @@
 package util
 
 import (
+	"context"
+
 	"github.com/ipfs/ipfs-cluster/api"
 
+	rpc "github.com/libp2p/go-libp2p-gorpc"
 	peer "github.com/libp2p/go-libp2p-peer"
 )
 
 // IsRemotePin determines whether a Pin's ReplicationFactor has
-// been met, so as to either pin or unpin it from the peer.
+// been met, so as to either pin or unpin it from the peer. Scheduled pins
+// are remote for every peer.
 func IsRemotePin(c *api.Pin, pid peer.ID) bool {
+	if c.IsScheduled() {
+		return true
+	}
 	if c.ReplicationFactorMax < 0 {
 		return false
 	}
@@ -20,3 +27,79 @@ func IsRemotePin(c *api.Pin, pid peer.ID) bool {
 	}
 	return true
 }
+
+// AttachBitswapStat obtains the bitswap statistics from the IPFS
+// daemon and attaches them to the given PinInfos which are pinning. The
+// daemon is not queried when none of them are.
+func AttachBitswapStat(ctx context.Context, rpcClient *rpc.Client, pinfos ...*api.PinInfo) error {
+	var pinning []*api.PinInfo
+	for _, pinfo := range pinfos {
+		if pinfo != nil && pinfo.Status == api.TrackerStatusPinning {
+			pinning = append(pinning, pinfo)
+		}
+	}
+	if len(pinning) == 0 {
+		return nil
+	}
+
+	var stat api.IPFSBitswapStat
+	err := rpcClient.CallContext(
+		ctx,
+		"",
+		"IPFSConnector",
+		"BitswapStat",
+		struct{}{},
+		&stat,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, pinfo := range pinning {
+		pinfo.Bitswap = &stat
+	}
+	return nil
+}
+
+// AttachPinProgress obtains the progress of the ongoing pins from the IPFS
+// connector and attaches it to the given PinInfos which are pinning. The
+// estimated size of the DAG is kept from the Progress already set in the
+// PinInfos, if any, to calculate the completion percentage.
+func AttachPinProgress(ctx context.Context, rpcClient *rpc.Client, pinfos ...*api.PinInfo) error {
+	pinning := make(map[string]*api.PinInfo)
+	for _, pinfo := range pinfos {
+		if pinfo != nil && pinfo.Status == api.TrackerStatusPinning {
+			pinning[pinfo.Cid.String()] = pinfo
+		}
+	}
+	if len(pinning) == 0 {
+		return nil
+	}
+
+	var progress []*api.PinProgress
+	err := rpcClient.CallContext(
+		ctx,
+		"",
+		"IPFSConnector",
+		"PinProgress",
+		struct{}{},
+		&progress,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, prog := range progress {
+		pinfo, ok := pinning[prog.Cid.String()]
+		if !ok {
+			continue
+		}
+		var total uint64
+		if pinfo.Progress != nil {
+			total = pinfo.Progress.TotalBytes
+		}
+		prog.SetTotalBytes(total)
+		pinfo.Progress = prog
+	}
+	return nil
+}
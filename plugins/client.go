@@ -0,0 +1,170 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	plugin "github.com/hashicorp/go-plugin"
+)
+
+// Client launches a plugin process with go-plugin and dispenses the plugin
+// it serves. The process is started again on demand when it has exited or
+// has been killed for not answering in time.
+type Client struct {
+	kind    string
+	command string
+	args    []string
+	timeout time.Duration
+	name    string
+
+	mu       sync.Mutex
+	client   *plugin.Client
+	raw      interface{}
+	shutdown bool
+}
+
+// NewClient returns a Client for a plugin of the given kind, which is run
+// with the given command and arguments. The timeout limits how long the
+// plugin can take to start and to answer a call. The process is launched on
+// the first call.
+func NewClient(kind, command string, args []string, timeout time.Duration) (*Client, error) {
+	if pluginMap(kind, nil) == nil {
+		return nil, fmt.Errorf("unknown plugin kind: %s", kind)
+	}
+	if command == "" {
+		return nil, fmt.Errorf("no %s plugin command given", kind)
+	}
+
+	return &Client{
+		kind:    kind,
+		command: command,
+		args:    args,
+		timeout: timeout,
+		name:    filepath.Base(command),
+	}, nil
+}
+
+// dispense returns the plugin, launching its process when it is not
+// running.
+func (c *Client) dispense() (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shutdown {
+		return nil, fmt.Errorf("plugin %s is shut down", c.name)
+	}
+
+	if c.client != nil {
+		if !c.client.Exited() {
+			return c.raw, nil
+		}
+		logger.Warningf("plugin %s exited. Restarting it", c.name)
+		c.client.Kill()
+		c.client = nil
+		c.raw = nil
+	}
+
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          pluginMap(c.kind, nil),
+		Cmd:              exec.Command(c.command, c.args...),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		StartTimeout:     c.timeout,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:   c.name,
+			Level:  hclog.Debug,
+			Output: &logWriter{},
+		}),
+	})
+
+	proto, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("error starting plugin %s: %s", c.name, err)
+	}
+	raw, err := proto.Dispense(c.kind)
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("plugin %s: %s", c.name, err)
+	}
+
+	logger.Infof("plugin %s started", c.name)
+	c.client = client
+	c.raw = raw
+	return raw, nil
+}
+
+// Call runs f with the dispensed plugin, launching the plugin process when
+// needed. The context given to f is cancelled after the timeout. Plugins
+// which do not answer in time are killed, so that they are started again
+// on the next call.
+func (c *Client) Call(ctx context.Context, f func(ctx context.Context, raw interface{}) error) error {
+	raw, err := c.dispense()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	err = f(ctx, raw)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		logger.Errorf("plugin %s did not answer in time. Killing it", c.name)
+		c.kill(raw)
+	}
+	return fmt.Errorf("plugin %s: %s", c.name, err)
+}
+
+// kill terminates the plugin process if it is still the one serving the
+// given plugin.
+func (c *Client) kill(raw interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil || c.raw != raw {
+		return
+	}
+	c.client.Kill()
+	c.client = nil
+	c.raw = nil
+}
+
+// Shutdown stops the plugin process. go-plugin asks the plugin to exit and
+// kills it if it does not do so in time.
+func (c *Client) Shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shutdown {
+		return
+	}
+	c.shutdown = true
+	if c.client != nil {
+		c.client.Kill()
+		c.client = nil
+		c.raw = nil
+	}
+}
+
+// logWriter logs every line written by go-plugin, which includes whatever
+// plugins write to their standard error.
+type logWriter struct{}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			logger.Debug(line)
+		}
+	}
+	return len(b), nil
+}
@@ -0,0 +1,225 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/test"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// The test binary acts as plugin when launched with TEST_PLUGIN set.
+const testPluginEnv = "TEST_PLUGIN"
+
+// The event sink plugin writes the events it receives to this file.
+const testEventsFileEnv = "TEST_PLUGIN_EVENTS"
+
+type testInformer struct {
+	hang bool
+}
+
+func (inf testInformer) Name(ctx context.Context) (string, error) { return "testinformer", nil }
+
+func (inf testInformer) GetMetric(ctx context.Context) (string, error) {
+	if inf.hang {
+		time.Sleep(time.Minute)
+	}
+	return "42", nil
+}
+
+type testEventSink struct{}
+
+func (testEventSink) Name(ctx context.Context) (string, error) { return "testsink", nil }
+
+func (testEventSink) HandleEvent(ctx context.Context, ev *Event) error {
+	if ev.Event == "error" && ev.Error == "" {
+		return errors.New("error events should carry an error")
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(os.Getenv(testEventsFileEnv), data, 0600)
+}
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testPluginEnv) {
+	case "informer":
+		ServeInformer(testInformer{})
+		os.Exit(0)
+	case "hang":
+		ServeInformer(testInformer{hang: true})
+		os.Exit(0)
+	case "eventsink":
+		ServeEventSink(testEventSink{})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func testClient(t *testing.T, kind, plugin string) *Client {
+	os.Setenv(testPluginEnv, plugin)
+	c, err := NewClient(kind, os.Args[0], nil, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func getMetric(ctx context.Context, c *Client) (string, error) {
+	var v string
+	err := c.Call(ctx, func(ctx context.Context, raw interface{}) error {
+		var err error
+		v, err = raw.(Informer).GetMetric(ctx)
+		return err
+	})
+	return v, err
+}
+
+func TestClientRestart(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t, KindInformer, "informer")
+	defer c.Shutdown()
+
+	v, err := getMetric(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "42" {
+		t.Error("unexpected metric:", v)
+	}
+
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	proc, err := os.FindProcess(client.ReattachConfig().Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proc.Kill()
+	for i := 0; !client.Exited(); i++ {
+		if i > 50 {
+			t.Fatal("the plugin should have exited")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	v, err = getMetric(ctx, c)
+	if err != nil {
+		t.Fatal("plugin should have been restarted:", err)
+	}
+	if v != "42" {
+		t.Error("unexpected metric:", v)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t, KindInformer, "hang")
+	c.timeout = 500 * time.Millisecond
+	defer c.Shutdown()
+
+	_, err := getMetric(ctx, c)
+	if err == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		t.Error("the plugin should have been killed")
+	}
+}
+
+func TestClientShutdown(t *testing.T) {
+	ctx := context.Background()
+	c := testClient(t, KindInformer, "informer")
+
+	_, err := getMetric(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Shutdown()
+	_, err = getMetric(ctx, c)
+	if err == nil {
+		t.Error("expected an error after shutdown")
+	}
+}
+
+func TestEventSink(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	events := filepath.Join(dir, "events.json")
+	os.Setenv(testEventsFileEnv, events)
+
+	c := testClient(t, KindEventSink, "eventsink")
+	defer c.Shutdown()
+
+	pin := api.PinCid(test.Cid1)
+	pin.Name = "testpin"
+	pin.Allocations = []peer.ID{test.PeerID1}
+	ev := &Event{
+		Event:     "error",
+		Peer:      peer.IDB58Encode(test.PeerID1),
+		Pin:       pin,
+		Error:     "pin failed",
+		Timestamp: time.Now(),
+	}
+	err = c.Call(ctx, func(ctx context.Context, raw interface{}) error {
+		return raw.(EventSink).HandleEvent(ctx, ev)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Event
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Event != ev.Event || got.Peer != ev.Peer || got.Error != ev.Error {
+		t.Errorf("unexpected event: %+v", got)
+	}
+	if !got.Pin.Cid.Equals(test.Cid1) || got.Pin.Name != "testpin" || len(got.Pin.Allocations) != 1 {
+		t.Errorf("unexpected pin: %+v", got.Pin)
+	}
+	if !got.Timestamp.Equal(ev.Timestamp) {
+		t.Error("the timestamp should be kept")
+	}
+
+	ev.Error = ""
+	err = c.Call(ctx, func(ctx context.Context, raw interface{}) error {
+		return raw.(EventSink).HandleEvent(ctx, ev)
+	})
+	if err == nil {
+		t.Error("expected an error from the plugin")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient("abc", os.Args[0], nil, time.Second)
+	if err == nil {
+		t.Error("expected an error with an unknown kind")
+	}
+	_, err = NewClient(KindInformer, "", nil, time.Second)
+	if err == nil {
+		t.Error("expected an error without command")
+	}
+}
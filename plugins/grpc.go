@@ -0,0 +1,307 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	pb "github.com/ipfs/ipfs-cluster/plugins/pb"
+
+	plugin "github.com/hashicorp/go-plugin"
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+	grpc "google.golang.org/grpc"
+)
+
+var errInvalidMetric = errors.New("the plugin returned an invalid metric")
+
+// InformerPlugin is the go-plugin Plugin for informers. Impl is only set
+// when serving.
+type InformerPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	Impl Informer
+}
+
+// GRPCServer registers the informer service.
+func (p *InformerPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	pb.RegisterInformerServer(s, &informerServer{impl: p.Impl})
+	return nil
+}
+
+// GRPCClient returns an Informer talking to the plugin.
+func (p *InformerPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &informerClient{client: pb.NewInformerClient(c)}, nil
+}
+
+type informerServer struct {
+	impl Informer
+}
+
+func (s *informerServer) Name(ctx context.Context, in *pb.Empty) (*pb.NameResponse, error) {
+	name, err := s.impl.Name(ctx)
+	return &pb.NameResponse{Name: name}, err
+}
+
+func (s *informerServer) GetMetric(ctx context.Context, in *pb.Empty) (*pb.MetricResponse, error) {
+	v, err := s.impl.GetMetric(ctx)
+	if err != nil {
+		logger.Errorf("error getting metric: %s", err)
+		return &pb.MetricResponse{}, nil
+	}
+	return &pb.MetricResponse{Value: v, Valid: true}, nil
+}
+
+type informerClient struct {
+	client pb.InformerClient
+}
+
+func (c *informerClient) Name(ctx context.Context) (string, error) {
+	resp, err := c.client.Name(ctx, &pb.Empty{})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetName(), nil
+}
+
+func (c *informerClient) GetMetric(ctx context.Context) (string, error) {
+	resp, err := c.client.GetMetric(ctx, &pb.Empty{})
+	if err != nil {
+		return "", err
+	}
+	if !resp.GetValid() {
+		return "", errInvalidMetric
+	}
+	return resp.GetValue(), nil
+}
+
+// AllocatorPlugin is the go-plugin Plugin for allocators. Impl is only set
+// when serving.
+type AllocatorPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	Impl Allocator
+}
+
+// GRPCServer registers the allocator service.
+func (p *AllocatorPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	pb.RegisterAllocatorServer(s, &allocatorServer{impl: p.Impl})
+	return nil
+}
+
+// GRPCClient returns an Allocator talking to the plugin.
+func (p *AllocatorPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &allocatorClient{client: pb.NewAllocatorClient(c)}, nil
+}
+
+type allocatorServer struct {
+	impl Allocator
+}
+
+func (s *allocatorServer) Name(ctx context.Context, in *pb.Empty) (*pb.NameResponse, error) {
+	name, err := s.impl.Name(ctx)
+	return &pb.NameResponse{Name: name}, err
+}
+
+func (s *allocatorServer) Allocate(ctx context.Context, in *pb.AllocateRequest) (*pb.AllocateResponse, error) {
+	pin, err := pinFromPb(in.GetPin())
+	if err != nil {
+		return nil, err
+	}
+	args := &AllocateArgs{Pin: pin}
+	for _, set := range []struct {
+		from []*pb.Metric
+		to   *[]*api.Metric
+	}{
+		{in.GetCurrent(), &args.Current},
+		{in.GetCandidates(), &args.Candidates},
+		{in.GetPriority(), &args.Priority},
+	} {
+		*set.to, err = metricsFromPb(set.from)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	allocs, err := s.impl.Allocate(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.AllocateResponse{Allocations: peersToPb(allocs)}, nil
+}
+
+type allocatorClient struct {
+	client pb.AllocatorClient
+}
+
+func (c *allocatorClient) Name(ctx context.Context) (string, error) {
+	resp, err := c.client.Name(ctx, &pb.Empty{})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetName(), nil
+}
+
+func (c *allocatorClient) Allocate(ctx context.Context, args *AllocateArgs) ([]peer.ID, error) {
+	resp, err := c.client.Allocate(ctx, &pb.AllocateRequest{
+		Pin:        pinToPb(args.Pin),
+		Current:    metricsToPb(args.Current),
+		Candidates: metricsToPb(args.Candidates),
+		Priority:   metricsToPb(args.Priority),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return peersFromPb(resp.GetAllocations())
+}
+
+// EventSinkPlugin is the go-plugin Plugin for event sinks. Impl is only set
+// when serving.
+type EventSinkPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	Impl EventSink
+}
+
+// GRPCServer registers the event sink service.
+func (p *EventSinkPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	pb.RegisterEventSinkServer(s, &eventSinkServer{impl: p.Impl})
+	return nil
+}
+
+// GRPCClient returns an EventSink talking to the plugin.
+func (p *EventSinkPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &eventSinkClient{client: pb.NewEventSinkClient(c)}, nil
+}
+
+type eventSinkServer struct {
+	impl EventSink
+}
+
+func (s *eventSinkServer) Name(ctx context.Context, in *pb.Empty) (*pb.NameResponse, error) {
+	name, err := s.impl.Name(ctx)
+	return &pb.NameResponse{Name: name}, err
+}
+
+func (s *eventSinkServer) HandleEvent(ctx context.Context, in *pb.Event) (*pb.Empty, error) {
+	pin, err := pinFromPb(in.GetPin())
+	if err != nil {
+		return nil, err
+	}
+	ev := &Event{
+		Event:     in.GetEvent(),
+		Peer:      in.GetPeer(),
+		Pin:       pin,
+		Error:     in.GetError(),
+		Timestamp: time.Unix(0, in.GetTimestamp()),
+	}
+	return &pb.Empty{}, s.impl.HandleEvent(ctx, ev)
+}
+
+type eventSinkClient struct {
+	client pb.EventSinkClient
+}
+
+func (c *eventSinkClient) Name(ctx context.Context) (string, error) {
+	resp, err := c.client.Name(ctx, &pb.Empty{})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetName(), nil
+}
+
+func (c *eventSinkClient) HandleEvent(ctx context.Context, ev *Event) error {
+	_, err := c.client.HandleEvent(ctx, &pb.Event{
+		Event:     ev.Event,
+		Peer:      ev.Peer,
+		Pin:       pinToPb(ev.Pin),
+		Error:     ev.Error,
+		Timestamp: ev.Timestamp.UnixNano(),
+	})
+	return err
+}
+
+func pinToPb(pin *api.Pin) *pb.Pin {
+	if pin == nil {
+		return nil
+	}
+	return &pb.Pin{
+		Cid:                  pin.Cid.String(),
+		Name:                 pin.Name,
+		ReplicationFactorMin: int32(pin.ReplicationFactorMin),
+		ReplicationFactorMax: int32(pin.ReplicationFactorMax),
+		Metadata:             pin.Metadata,
+		Allocations:          peersToPb(pin.Allocations),
+	}
+}
+
+func pinFromPb(in *pb.Pin) (*api.Pin, error) {
+	if in == nil {
+		return nil, errors.New("no pin provided")
+	}
+	c, err := cid.Decode(in.GetCid())
+	if err != nil {
+		return nil, err
+	}
+	allocs, err := peersFromPb(in.GetAllocations())
+	if err != nil {
+		return nil, err
+	}
+	pin := api.PinCid(c)
+	pin.Name = in.GetName()
+	pin.ReplicationFactorMin = int(in.GetReplicationFactorMin())
+	pin.ReplicationFactorMax = int(in.GetReplicationFactorMax())
+	pin.Metadata = in.GetMetadata()
+	pin.Allocations = allocs
+	return pin, nil
+}
+
+func metricsToPb(metrics []*api.Metric) []*pb.Metric {
+	out := make([]*pb.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		out = append(out, &pb.Metric{
+			Name:   m.Name,
+			Peer:   peer.IDB58Encode(m.Peer),
+			Value:  m.Value,
+			Valid:  m.Valid,
+			Expire: m.Expire,
+		})
+	}
+	return out
+}
+
+func metricsFromPb(metrics []*pb.Metric) ([]*api.Metric, error) {
+	out := make([]*api.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		p, err := peer.IDB58Decode(m.GetPeer())
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &api.Metric{
+			Name:   m.GetName(),
+			Peer:   p,
+			Value:  m.GetValue(),
+			Valid:  m.GetValid(),
+			Expire: m.GetExpire(),
+		})
+	}
+	return out, nil
+}
+
+func peersToPb(peers []peer.ID) []string {
+	out := make([]string, 0, len(peers))
+	for _, p := range peers {
+		out = append(out, peer.IDB58Encode(p))
+	}
+	return out
+}
+
+func peersFromPb(peers []string) ([]peer.ID, error) {
+	out := make([]peer.ID, 0, len(peers))
+	for _, s := range peers {
+		p, err := peer.IDB58Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
@@ -0,0 +1,159 @@
+package host
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/kelseyhightower/envconfig"
+)
+
+const configKey = "plugin"
+const envConfigKeyInformer = "cluster_plugin_informer"
+const envConfigKeyAllocator = "cluster_plugin_allocator"
+
+// These are the default values for a Config.
+const (
+	DefaultTimeout   = 10 * time.Second
+	DefaultMetricTTL = 30 * time.Second
+)
+
+// Config allows to initialize an Informer or an Allocator running in a
+// plugin process. The same type is used in the informer and the allocator
+// sections of the configuration.
+type Config struct {
+	config.Saver
+
+	// Command is the plugin executable. It must be set in order to
+	// use the plugin.
+	Command string
+	// Args are passed to the plugin executable.
+	Args []string
+	// Timeout limits how long the plugin can take to start and to
+	// answer a request. Plugins which time out are restarted.
+	Timeout time.Duration
+	// MetricTTL sets the validity of the metrics produced by informer
+	// plugins. Not used by allocators.
+	MetricTTL time.Duration
+
+	informer bool
+}
+
+type jsonConfig struct {
+	Command   string   `json:"command"`
+	Args      []string `json:"args,omitempty"`
+	Timeout   string   `json:"timeout"`
+	MetricTTL string   `json:"metric_ttl,omitempty"`
+}
+
+// NewInformerConfig returns an empty configuration for informer plugins.
+func NewInformerConfig() *Config {
+	return &Config{informer: true}
+}
+
+// NewAllocatorConfig returns an empty configuration for allocator plugins.
+func NewAllocatorConfig() *Config {
+	return &Config{}
+}
+
+// ConfigKey returns a human-friendly identifier for this
+// Config's type.
+func (cfg *Config) ConfigKey() string {
+	return configKey
+}
+
+// Default initializes this Config with sensible values.
+func (cfg *Config) Default() error {
+	cfg.Command = ""
+	cfg.Args = nil
+	cfg.Timeout = DefaultTimeout
+	cfg.MetricTTL = 0
+	if cfg.informer {
+		cfg.MetricTTL = DefaultMetricTTL
+	}
+	return nil
+}
+
+// ApplyEnvVars fills in any Config fields found
+// as environment variables.
+func (cfg *Config) ApplyEnvVars() error {
+	jcfg := cfg.toJSONConfig()
+
+	envKey := envConfigKeyAllocator
+	if cfg.informer {
+		envKey = envConfigKeyInformer
+	}
+
+	err := envconfig.Process(envKey, jcfg)
+	if err != nil {
+		return err
+	}
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+// Validate checks that the fields of this configuration have
+// sensible values. An empty command is valid, as the configuration is
+// present even when no plugin is used.
+func (cfg *Config) Validate() error {
+	if cfg.Timeout <= 0 {
+		return errors.New("plugin.timeout is invalid")
+	}
+
+	if cfg.informer && cfg.MetricTTL <= 0 {
+		return errors.New("plugin.metric_ttl is invalid")
+	}
+
+	return nil
+}
+
+// LoadJSON parses a raw JSON byte-slice as generated by ToJSON().
+func (cfg *Config) LoadJSON(raw []byte) error {
+	jcfg := &jsonConfig{}
+	err := json.Unmarshal(raw, jcfg)
+	if err != nil {
+		return err
+	}
+
+	cfg.Default()
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
+	config.SetIfNotDefault(jcfg.Command, &cfg.Command)
+	if len(jcfg.Args) > 0 {
+		cfg.Args = jcfg.Args
+	}
+
+	err := config.ParseDurations(
+		configKey,
+		&config.DurationOpt{Duration: jcfg.Timeout, Dst: &cfg.Timeout, Name: "timeout"},
+		&config.DurationOpt{Duration: jcfg.MetricTTL, Dst: &cfg.MetricTTL, Name: "metric_ttl"},
+	)
+	if err != nil {
+		return err
+	}
+
+	return cfg.Validate()
+}
+
+// ToJSON generates a human-friendly JSON representation of this Config.
+func (cfg *Config) ToJSON() ([]byte, error) {
+	jcfg := cfg.toJSONConfig()
+
+	return config.DefaultJSONMarshal(jcfg)
+}
+
+func (cfg *Config) toJSONConfig() *jsonConfig {
+	jcfg := &jsonConfig{
+		Command: cfg.Command,
+		Args:    cfg.Args,
+		Timeout: cfg.Timeout.String(),
+	}
+	if cfg.informer {
+		jcfg.MetricTTL = cfg.MetricTTL.String()
+	}
+	return jcfg
+}
@@ -0,0 +1,96 @@
+package host
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+var cfgJSON = []byte(`
+{
+      "command": "/usr/local/bin/my-plugin",
+      "args": ["-v"],
+      "timeout": "2s",
+      "metric_ttl": "1m"
+}
+`)
+
+func TestLoadJSON(t *testing.T) {
+	cfg := NewInformerConfig()
+	err := cfg.LoadJSON(cfgJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Command != "/usr/local/bin/my-plugin" ||
+		len(cfg.Args) != 1 ||
+		cfg.Timeout != 2*time.Second ||
+		cfg.MetricTTL != time.Minute {
+		t.Error("config not loaded correctly")
+	}
+
+	err = cfg.LoadJSON([]byte(`{"timeout": "abc"}`))
+	if err == nil {
+		t.Error("expected error decoding timeout")
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	cfg := NewInformerConfig()
+	cfg.LoadJSON(cfgJSON)
+	newjson, err := cfg.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = NewInformerConfig()
+	err = cfg.LoadJSON(newjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MetricTTL != time.Minute {
+		t.Error("metric_ttl not preserved")
+	}
+
+	acfg := NewAllocatorConfig()
+	acfg.Default()
+	newjson, err = acfg.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	acfg = NewAllocatorConfig()
+	err = acfg.LoadJSON(newjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cfg := NewInformerConfig()
+	cfg.Default()
+	if cfg.Validate() != nil {
+		t.Fatal("error validating")
+	}
+
+	cfg.MetricTTL = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	acfg := NewAllocatorConfig()
+	acfg.Default()
+	if acfg.Validate() != nil {
+		t.Fatal("allocators do not need metric_ttl")
+	}
+}
+
+func TestApplyEnvVars(t *testing.T) {
+	os.Setenv("CLUSTER_PLUGIN_ALLOCATOR_COMMAND", "/bin/alloc-plugin")
+	defer os.Unsetenv("CLUSTER_PLUGIN_ALLOCATOR_COMMAND")
+	cfg := NewAllocatorConfig()
+	cfg.Default()
+	cfg.ApplyEnvVars()
+
+	if cfg.Command != "/bin/alloc-plugin" {
+		t.Fatal("failed to override command with env var")
+	}
+}
@@ -0,0 +1,236 @@
+// Package host runs Informers and PinAllocators implemented as plugins
+// (see the plugins package) in separate processes. Importing it registers
+// them in the registry as the "plugin" informer and allocator, which are
+// configured in the "plugin" entries of the informer and allocator
+// configuration sections.
+package host
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/allocator/util"
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/config"
+	infutil "github.com/ipfs/ipfs-cluster/informer/util"
+	"github.com/ipfs/ipfs-cluster/plugins"
+	"github.com/ipfs/ipfs-cluster/registry"
+
+	cid "github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"go.opencensus.io/trace"
+)
+
+var logger = logging.Logger("plugins")
+
+// RegistryName is the name under which plugins are registered.
+const RegistryName = "plugin"
+
+func init() {
+	registry.RegisterInformer(
+		RegistryName,
+		func() config.ComponentConfig { return NewInformerConfig() },
+		func(cfg config.ComponentConfig) (ipfscluster.Informer, error) {
+			inf, err := NewInformer(cfg.(*Config))
+			if err != nil {
+				return nil, err
+			}
+			return inf, nil
+		},
+	)
+	registry.RegisterAllocator(
+		RegistryName,
+		func() config.ComponentConfig { return NewAllocatorConfig() },
+		func(cfg config.ComponentConfig) (ipfscluster.PinAllocator, error) {
+			alloc, err := NewAllocator(cfg.(*Config))
+			if err != nil {
+				return nil, err
+			}
+			return alloc, nil
+		},
+	)
+}
+
+// startPlugin launches the plugin and obtains its name.
+func startPlugin(cfg *Config, kind string) (*plugins.Client, string, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Command == "" {
+		return nil, "", errors.New("plugin.command is not set")
+	}
+
+	client, err := plugins.NewClient(kind, cfg.Command, cfg.Args, cfg.Timeout)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The first call starts the plugin, so that broken plugins are
+	// noticed on startup.
+	var name string
+	err = client.Call(context.Background(), func(ctx context.Context, raw interface{}) error {
+		var err error
+		switch kind {
+		case plugins.KindInformer:
+			name, err = raw.(plugins.Informer).Name(ctx)
+		case plugins.KindAllocator:
+			name, err = raw.(plugins.Allocator).Name(ctx)
+		}
+		return err
+	})
+	if err == nil && name == "" {
+		err = errors.New("plugin returned an empty name")
+	}
+	if err != nil {
+		client.Shutdown()
+		return nil, "", err
+	}
+	return client, name, nil
+}
+
+// Informer is an ipfscluster.Informer which obtains metrics from a plugin.
+type Informer struct {
+	config       *Config
+	client       *plugins.Client
+	name         string
+	pushInterval *infutil.PushInterval
+}
+
+// NewInformer launches the informer plugin and returns an Informer using
+// it.
+func NewInformer(cfg *Config) (*Informer, error) {
+	client, name, err := startPlugin(cfg, plugins.KindInformer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Informer{
+		config:       cfg,
+		client:       client,
+		name:         name,
+		pushInterval: &infutil.PushInterval{},
+	}, nil
+}
+
+// SetClient does nothing in this informer.
+func (inf *Informer) SetClient(c *rpc.Client) {}
+
+// Shutdown stops the plugin process.
+func (inf *Informer) Shutdown(ctx context.Context) error {
+	inf.client.Shutdown()
+	return nil
+}
+
+// Name returns the name of the metric, as provided by the plugin.
+func (inf *Informer) Name() string {
+	return inf.name
+}
+
+// PushInterval returns how long to wait before publishing a new metric.
+func (inf *Informer) PushInterval(m *api.Metric) time.Duration {
+	return inf.pushInterval.Next(m)
+}
+
+// GetMetric obtains the metric value from the plugin. Errors produce
+// invalid metrics.
+func (inf *Informer) GetMetric(ctx context.Context) *api.Metric {
+	ctx, span := trace.StartSpan(ctx, "plugins/host/GetMetric")
+	defer span.End()
+
+	var value string
+	err := inf.client.Call(ctx, func(ctx context.Context, raw interface{}) error {
+		var err error
+		value, err = raw.(plugins.Informer).GetMetric(ctx)
+		return err
+	})
+	if err != nil {
+		logger.Error(err)
+	}
+
+	m := &api.Metric{
+		Name:  inf.name,
+		Value: value,
+		Valid: err == nil,
+	}
+	m.SetTTL(inf.config.MetricTTL)
+	return m
+}
+
+// Allocator is an ipfscluster.PinAllocator which obtains allocations from
+// a plugin.
+type Allocator struct {
+	config *Config
+	client *plugins.Client
+	name   string
+}
+
+// NewAllocator launches the allocator plugin and returns an Allocator
+// using it.
+func NewAllocator(cfg *Config) (*Allocator, error) {
+	client, name, err := startPlugin(cfg, plugins.KindAllocator)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Allocator{
+		config: cfg,
+		client: client,
+		name:   name,
+	}, nil
+}
+
+// SetClient does nothing in this allocator.
+func (alloc *Allocator) SetClient(c *rpc.Client) {}
+
+// Shutdown stops the plugin process.
+func (alloc *Allocator) Shutdown(ctx context.Context) error {
+	alloc.client.Shutdown()
+	return nil
+}
+
+// Name returns the name provided by the plugin.
+func (alloc *Allocator) Name() string {
+	return alloc.name
+}
+
+// Allocate obtains the allocations for the given CID from the plugin.
+// Cluster uses AllocatePin instead, which sends the full pin request.
+func (alloc *Allocator) Allocate(ctx context.Context, c cid.Cid, current, candidates, priority map[peer.ID]*api.Metric) ([]peer.ID, error) {
+	return alloc.AllocatePin(ctx, api.PinCid(c), current, candidates, priority)
+}
+
+// AllocatePin sends the pin request along with the metrics of the current,
+// candidate and priority peers to the plugin and returns the peers in the
+// order provided by it.
+func (alloc *Allocator) AllocatePin(ctx context.Context, pin *api.Pin, current, candidates, priority map[peer.ID]*api.Metric) ([]peer.ID, error) {
+	ctx, span := trace.StartSpan(ctx, "plugins/host/AllocatePin")
+	defer span.End()
+
+	args := &plugins.AllocateArgs{
+		Pin:        pin,
+		Current:    util.SortByPeer(current),
+		Candidates: util.SortByPeer(candidates),
+		Priority:   util.SortByPeer(priority),
+	}
+	var reply []peer.ID
+	err := alloc.client.Call(ctx, func(ctx context.Context, raw interface{}) error {
+		var err error
+		reply, err = raw.(plugins.Allocator).Allocate(ctx, args)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	allocs, ignored := util.FilterCandidates(reply, candidates, priority)
+	for _, p := range ignored {
+		logger.Warningf("ignoring allocation to non-candidate peer %s", p.Pretty())
+	}
+	return allocs, nil
+}
@@ -0,0 +1,183 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/plugins"
+	"github.com/ipfs/ipfs-cluster/test"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// The test binary acts as plugin when launched with TEST_PLUGIN set.
+const testPluginEnv = "TEST_PLUGIN"
+
+type testInformer struct{}
+
+func (testInformer) Name(ctx context.Context) (string, error)      { return "testinformer", nil }
+func (testInformer) GetMetric(ctx context.Context) (string, error) { return "42", nil }
+
+type testAllocator struct {
+	hang bool
+}
+
+func (a testAllocator) Name(ctx context.Context) (string, error) { return "testallocator", nil }
+
+// Allocate returns the candidates in reverse order, along with a peer which
+// is not a candidate.
+func (a testAllocator) Allocate(ctx context.Context, args *plugins.AllocateArgs) ([]peer.ID, error) {
+	if a.hang {
+		time.Sleep(time.Minute)
+	}
+	if args.Pin.Name == "error" {
+		return nil, errors.New("allocation error")
+	}
+	allocs := []peer.ID{test.PeerID6}
+	for i := len(args.Candidates) - 1; i >= 0; i-- {
+		allocs = append(allocs, args.Candidates[i].Peer)
+	}
+	return allocs, nil
+}
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testPluginEnv) {
+	case "informer":
+		plugins.ServeInformer(testInformer{})
+		os.Exit(0)
+	case "allocator":
+		plugins.ServeAllocator(testAllocator{})
+		os.Exit(0)
+	case "hang":
+		plugins.ServeAllocator(testAllocator{hang: true})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func testConfig(t *testing.T, cfg *Config, kind string) *Config {
+	os.Setenv(testPluginEnv, kind)
+	cfg.Default()
+	cfg.Command = os.Args[0]
+	cfg.Timeout = 5 * time.Second
+	return cfg
+}
+
+func TestInformer(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig(t, NewInformerConfig(), "informer")
+	inf, err := NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inf.Shutdown(ctx)
+
+	if inf.Name() != "testinformer" {
+		t.Error("unexpected informer name:", inf.Name())
+	}
+
+	m := inf.GetMetric(ctx)
+	if !m.Valid || m.Value != "42" || m.Name != "testinformer" {
+		t.Errorf("unexpected metric: %+v", m)
+	}
+	if m.Discard() {
+		t.Error("metric should not be expired")
+	}
+}
+
+func TestInformerShutdown(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig(t, NewInformerConfig(), "informer")
+	inf, err := NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = inf.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := inf.GetMetric(ctx)
+	if m.Valid {
+		t.Error("metric should be invalid after shutdown")
+	}
+}
+
+func TestAllocator(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig(t, NewAllocatorConfig(), "allocator")
+	alloc, err := NewAllocator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alloc.Shutdown(ctx)
+
+	if alloc.Name() != "testallocator" {
+		t.Error("unexpected allocator name:", alloc.Name())
+	}
+
+	candidates := map[peer.ID]*api.Metric{
+		test.PeerID1: {Name: "m", Peer: test.PeerID1, Value: "1", Valid: true},
+		test.PeerID2: {Name: "m", Peer: test.PeerID2, Value: "2", Valid: true},
+	}
+	allocs, err := alloc.Allocate(ctx, test.Cid1, nil, candidates, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allocs) != 2 || allocs[0] < allocs[1] {
+		t.Errorf("unexpected allocations: %s", allocs)
+	}
+
+	pin := api.PinCid(test.Cid1)
+	pin.Name = "error"
+	_, err = alloc.AllocatePin(ctx, pin, nil, candidates, nil)
+	if err == nil {
+		t.Error("expected an error from the plugin")
+	}
+}
+
+func TestAllocatorTimeout(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig(t, NewAllocatorConfig(), "hang")
+	cfg.Timeout = 500 * time.Millisecond
+	alloc, err := NewAllocator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alloc.Shutdown(ctx)
+
+	candidates := map[peer.ID]*api.Metric{
+		test.PeerID1: {Name: "m", Peer: test.PeerID1, Value: "1", Valid: true},
+	}
+	_, err = alloc.Allocate(ctx, test.Cid1, nil, candidates, nil)
+	if err == nil {
+		t.Fatal("expected a timeout")
+	}
+}
+
+func TestBadPlugins(t *testing.T) {
+	cfg := NewInformerConfig()
+	cfg.Default()
+	_, err := NewInformer(cfg)
+	if err == nil {
+		t.Error("expected an error without command")
+	}
+
+	cfg.Command = "echo"
+	cfg.Args = []string{"hello"}
+	_, err = NewInformer(cfg)
+	if err == nil {
+		t.Error("expected a handshake error")
+	}
+
+	cfg.Command = "/nonexistent/plugin"
+	_, err = NewInformer(cfg)
+	if err == nil {
+		t.Error("expected an error starting the plugin")
+	}
+}
@@ -0,0 +1,814 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: plugins.proto
+
+package plugins_pb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Empty struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Empty) Reset()         { *m = Empty{} }
+func (m *Empty) String() string { return proto.CompactTextString(m) }
+func (*Empty) ProtoMessage()    {}
+func (*Empty) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a41a6b6d08976c8c, []int{0}
+}
+
+func (m *Empty) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Empty.Unmarshal(m, b)
+}
+func (m *Empty) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Empty.Marshal(b, m, deterministic)
+}
+func (m *Empty) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Empty.Merge(m, src)
+}
+func (m *Empty) XXX_Size() int {
+	return xxx_messageInfo_Empty.Size(m)
+}
+func (m *Empty) XXX_DiscardUnknown() {
+	xxx_messageInfo_Empty.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Empty proto.InternalMessageInfo
+
+type NameResponse struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NameResponse) Reset()         { *m = NameResponse{} }
+func (m *NameResponse) String() string { return proto.CompactTextString(m) }
+func (*NameResponse) ProtoMessage()    {}
+func (*NameResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a41a6b6d08976c8c, []int{1}
+}
+
+func (m *NameResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NameResponse.Unmarshal(m, b)
+}
+func (m *NameResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NameResponse.Marshal(b, m, deterministic)
+}
+func (m *NameResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NameResponse.Merge(m, src)
+}
+func (m *NameResponse) XXX_Size() int {
+	return xxx_messageInfo_NameResponse.Size(m)
+}
+func (m *NameResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_NameResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NameResponse proto.InternalMessageInfo
+
+func (m *NameResponse) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type Pin struct {
+	Cid                  string            `protobuf:"bytes,1,opt,name=Cid,proto3" json:"Cid,omitempty"`
+	Name                 string            `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	ReplicationFactorMin int32             `protobuf:"zigzag32,3,opt,name=ReplicationFactorMin,proto3" json:"ReplicationFactorMin,omitempty"`
+	ReplicationFactorMax int32             `protobuf:"zigzag32,4,opt,name=ReplicationFactorMax,proto3" json:"ReplicationFactorMax,omitempty"`
+	Metadata             map[string]string `protobuf:"bytes,5,rep,name=Metadata,proto3" json:"Metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Allocations          []string          `protobuf:"bytes,6,rep,name=Allocations,proto3" json:"Allocations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Pin) Reset()         { *m = Pin{} }
+func (m *Pin) String() string { return proto.CompactTextString(m) }
+func (*Pin) ProtoMessage()    {}
+func (*Pin) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a41a6b6d08976c8c, []int{2}
+}
+
+func (m *Pin) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Pin.Unmarshal(m, b)
+}
+func (m *Pin) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Pin.Marshal(b, m, deterministic)
+}
+func (m *Pin) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Pin.Merge(m, src)
+}
+func (m *Pin) XXX_Size() int {
+	return xxx_messageInfo_Pin.Size(m)
+}
+func (m *Pin) XXX_DiscardUnknown() {
+	xxx_messageInfo_Pin.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Pin proto.InternalMessageInfo
+
+func (m *Pin) GetCid() string {
+	if m != nil {
+		return m.Cid
+	}
+	return ""
+}
+
+func (m *Pin) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Pin) GetReplicationFactorMin() int32 {
+	if m != nil {
+		return m.ReplicationFactorMin
+	}
+	return 0
+}
+
+func (m *Pin) GetReplicationFactorMax() int32 {
+	if m != nil {
+		return m.ReplicationFactorMax
+	}
+	return 0
+}
+
+func (m *Pin) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *Pin) GetAllocations() []string {
+	if m != nil {
+		return m.Allocations
+	}
+	return nil
+}
+
+type Metric struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Peer                 string   `protobuf:"bytes,2,opt,name=Peer,proto3" json:"Peer,omitempty"`
+	Value                string   `protobuf:"bytes,3,opt,name=Value,proto3" json:"Value,omitempty"`
+	Valid                bool     `protobuf:"varint,4,opt,name=Valid,proto3" json:"Valid,omitempty"`
+	Expire               int64    `protobuf:"zigzag64,5,opt,name=Expire,proto3" json:"Expire,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Metric) Reset()         { *m = Metric{} }
+func (m *Metric) String() string { return proto.CompactTextString(m) }
+func (*Metric) ProtoMessage()    {}
+func (*Metric) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a41a6b6d08976c8c, []int{3}
+}
+
+func (m *Metric) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Metric.Unmarshal(m, b)
+}
+func (m *Metric) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Metric.Marshal(b, m, deterministic)
+}
+func (m *Metric) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Metric.Merge(m, src)
+}
+func (m *Metric) XXX_Size() int {
+	return xxx_messageInfo_Metric.Size(m)
+}
+func (m *Metric) XXX_DiscardUnknown() {
+	xxx_messageInfo_Metric.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Metric proto.InternalMessageInfo
+
+func (m *Metric) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Metric) GetPeer() string {
+	if m != nil {
+		return m.Peer
+	}
+	return ""
+}
+
+func (m *Metric) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Metric) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+func (m *Metric) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+type MetricResponse struct {
+	Value                string   `protobuf:"bytes,1,opt,name=Value,proto3" json:"Value,omitempty"`
+	Valid                bool     `protobuf:"varint,2,opt,name=Valid,proto3" json:"Valid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MetricResponse) Reset()         { *m = MetricResponse{} }
+func (m *MetricResponse) String() string { return proto.CompactTextString(m) }
+func (*MetricResponse) ProtoMessage()    {}
+func (*MetricResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a41a6b6d08976c8c, []int{4}
+}
+
+func (m *MetricResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MetricResponse.Unmarshal(m, b)
+}
+func (m *MetricResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MetricResponse.Marshal(b, m, deterministic)
+}
+func (m *MetricResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MetricResponse.Merge(m, src)
+}
+func (m *MetricResponse) XXX_Size() int {
+	return xxx_messageInfo_MetricResponse.Size(m)
+}
+func (m *MetricResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MetricResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MetricResponse proto.InternalMessageInfo
+
+func (m *MetricResponse) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *MetricResponse) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+type AllocateRequest struct {
+	Pin                  *Pin      `protobuf:"bytes,1,opt,name=Pin,proto3" json:"Pin,omitempty"`
+	Current              []*Metric `protobuf:"bytes,2,rep,name=Current,proto3" json:"Current,omitempty"`
+	Candidates           []*Metric `protobuf:"bytes,3,rep,name=Candidates,proto3" json:"Candidates,omitempty"`
+	Priority             []*Metric `protobuf:"bytes,4,rep,name=Priority,proto3" json:"Priority,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *AllocateRequest) Reset()         { *m = AllocateRequest{} }
+func (m *AllocateRequest) String() string { return proto.CompactTextString(m) }
+func (*AllocateRequest) ProtoMessage()    {}
+func (*AllocateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a41a6b6d08976c8c, []int{5}
+}
+
+func (m *AllocateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AllocateRequest.Unmarshal(m, b)
+}
+func (m *AllocateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AllocateRequest.Marshal(b, m, deterministic)
+}
+func (m *AllocateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AllocateRequest.Merge(m, src)
+}
+func (m *AllocateRequest) XXX_Size() int {
+	return xxx_messageInfo_AllocateRequest.Size(m)
+}
+func (m *AllocateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AllocateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AllocateRequest proto.InternalMessageInfo
+
+func (m *AllocateRequest) GetPin() *Pin {
+	if m != nil {
+		return m.Pin
+	}
+	return nil
+}
+
+func (m *AllocateRequest) GetCurrent() []*Metric {
+	if m != nil {
+		return m.Current
+	}
+	return nil
+}
+
+func (m *AllocateRequest) GetCandidates() []*Metric {
+	if m != nil {
+		return m.Candidates
+	}
+	return nil
+}
+
+func (m *AllocateRequest) GetPriority() []*Metric {
+	if m != nil {
+		return m.Priority
+	}
+	return nil
+}
+
+type AllocateResponse struct {
+	Allocations          []string `protobuf:"bytes,1,rep,name=Allocations,proto3" json:"Allocations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AllocateResponse) Reset()         { *m = AllocateResponse{} }
+func (m *AllocateResponse) String() string { return proto.CompactTextString(m) }
+func (*AllocateResponse) ProtoMessage()    {}
+func (*AllocateResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a41a6b6d08976c8c, []int{6}
+}
+
+func (m *AllocateResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AllocateResponse.Unmarshal(m, b)
+}
+func (m *AllocateResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AllocateResponse.Marshal(b, m, deterministic)
+}
+func (m *AllocateResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AllocateResponse.Merge(m, src)
+}
+func (m *AllocateResponse) XXX_Size() int {
+	return xxx_messageInfo_AllocateResponse.Size(m)
+}
+func (m *AllocateResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AllocateResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AllocateResponse proto.InternalMessageInfo
+
+func (m *AllocateResponse) GetAllocations() []string {
+	if m != nil {
+		return m.Allocations
+	}
+	return nil
+}
+
+type Event struct {
+	Event                string   `protobuf:"bytes,1,opt,name=Event,proto3" json:"Event,omitempty"`
+	Peer                 string   `protobuf:"bytes,2,opt,name=Peer,proto3" json:"Peer,omitempty"`
+	Pin                  *Pin     `protobuf:"bytes,3,opt,name=Pin,proto3" json:"Pin,omitempty"`
+	Error                string   `protobuf:"bytes,4,opt,name=Error,proto3" json:"Error,omitempty"`
+	Timestamp            int64    `protobuf:"zigzag64,5,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+func (*Event) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a41a6b6d08976c8c, []int{7}
+}
+
+func (m *Event) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Event.Unmarshal(m, b)
+}
+func (m *Event) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Event.Marshal(b, m, deterministic)
+}
+func (m *Event) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Event.Merge(m, src)
+}
+func (m *Event) XXX_Size() int {
+	return xxx_messageInfo_Event.Size(m)
+}
+func (m *Event) XXX_DiscardUnknown() {
+	xxx_messageInfo_Event.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Event proto.InternalMessageInfo
+
+func (m *Event) GetEvent() string {
+	if m != nil {
+		return m.Event
+	}
+	return ""
+}
+
+func (m *Event) GetPeer() string {
+	if m != nil {
+		return m.Peer
+	}
+	return ""
+}
+
+func (m *Event) GetPin() *Pin {
+	if m != nil {
+		return m.Pin
+	}
+	return nil
+}
+
+func (m *Event) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Event) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*Empty)(nil), "plugins.pb.Empty")
+	proto.RegisterType((*NameResponse)(nil), "plugins.pb.NameResponse")
+	proto.RegisterType((*Pin)(nil), "plugins.pb.Pin")
+	proto.RegisterMapType((map[string]string)(nil), "plugins.pb.Pin.MetadataEntry")
+	proto.RegisterType((*Metric)(nil), "plugins.pb.Metric")
+	proto.RegisterType((*MetricResponse)(nil), "plugins.pb.MetricResponse")
+	proto.RegisterType((*AllocateRequest)(nil), "plugins.pb.AllocateRequest")
+	proto.RegisterType((*AllocateResponse)(nil), "plugins.pb.AllocateResponse")
+	proto.RegisterType((*Event)(nil), "plugins.pb.Event")
+}
+
+func init() { proto.RegisterFile("plugins.proto", fileDescriptor_a41a6b6d08976c8c) }
+
+var fileDescriptor_a41a6b6d08976c8c = []byte{
+	// 541 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0xcd, 0x8e, 0xd3, 0x3c,
+	0x14, 0x55, 0x9a, 0xb6, 0xd3, 0xdc, 0x7e, 0xf3, 0xcd, 0x8c, 0x55, 0x21, 0xab, 0x0c, 0x52, 0xc8,
+	0xaa, 0x0b, 0xd4, 0x45, 0xca, 0x02, 0x06, 0x36, 0xa8, 0x2a, 0x3f, 0x8b, 0xa2, 0x2a, 0x20, 0xf6,
+	0x9e, 0xe6, 0x82, 0xac, 0xa6, 0x4e, 0x70, 0xdc, 0x51, 0x2b, 0x1e, 0x00, 0x5e, 0x86, 0xd7, 0xe0,
+	0xb9, 0x90, 0x1d, 0x27, 0x4d, 0x3b, 0x29, 0x8b, 0xd9, 0xf9, 0xfe, 0x9c, 0x93, 0x7b, 0xcf, 0xb1,
+	0x03, 0xe7, 0x59, 0xb2, 0xf9, 0xc6, 0x45, 0x3e, 0xce, 0x64, 0xaa, 0x52, 0x02, 0x55, 0x78, 0x1b,
+	0x9c, 0x41, 0x67, 0xb6, 0xce, 0xd4, 0x2e, 0x08, 0xe0, 0xbf, 0x8f, 0x6c, 0x8d, 0x11, 0xe6, 0x59,
+	0x2a, 0x72, 0x24, 0x04, 0xda, 0x3a, 0xa6, 0x8e, 0xef, 0x8c, 0xbc, 0xc8, 0x9c, 0x83, 0xdf, 0x2d,
+	0x70, 0x17, 0x5c, 0x90, 0x4b, 0x70, 0xa7, 0x3c, 0xb6, 0x25, 0x7d, 0xac, 0xba, 0x5b, 0xfb, 0x6e,
+	0x12, 0xc2, 0x20, 0xc2, 0x2c, 0xe1, 0x4b, 0xa6, 0x78, 0x2a, 0xde, 0xb2, 0xa5, 0x4a, 0xe5, 0x9c,
+	0x0b, 0xea, 0xfa, 0xce, 0xe8, 0x2a, 0x6a, 0xac, 0x35, 0x63, 0xd8, 0x96, 0xb6, 0x4f, 0x61, 0xd8,
+	0x96, 0xbc, 0x84, 0xde, 0x1c, 0x15, 0x8b, 0x99, 0x62, 0xb4, 0xe3, 0xbb, 0xa3, 0x7e, 0xf8, 0x64,
+	0xbc, 0xdf, 0x70, 0xbc, 0xe0, 0x62, 0x5c, 0xd6, 0x67, 0x42, 0xc9, 0x5d, 0x54, 0xb5, 0x13, 0x1f,
+	0xfa, 0x6f, 0x92, 0x24, 0x2d, 0x18, 0x73, 0xda, 0xf5, 0xdd, 0x91, 0x17, 0xd5, 0x53, 0xc3, 0x57,
+	0x70, 0x7e, 0x00, 0xd6, 0xbb, 0xaf, 0x70, 0x57, 0xee, 0xbe, 0xc2, 0x1d, 0x19, 0x40, 0xe7, 0x8e,
+	0x25, 0x9b, 0x72, 0xf9, 0x22, 0xb8, 0x69, 0xbd, 0x70, 0x02, 0x05, 0xdd, 0x39, 0x2a, 0xc9, 0x97,
+	0x4d, 0x6a, 0xea, 0xdc, 0x02, 0x51, 0x96, 0x9a, 0xe9, 0xb3, 0xe6, 0xfa, 0x62, 0xb8, 0xdc, 0x82,
+	0xcb, 0x04, 0x36, 0xcb, 0x63, 0x23, 0x43, 0x2f, 0x2a, 0x02, 0xf2, 0x08, 0xba, 0xb3, 0x6d, 0xc6,
+	0x25, 0xd2, 0x8e, 0xef, 0x8c, 0x48, 0x64, 0xa3, 0xe0, 0x35, 0xfc, 0x5f, 0x7c, 0xb5, 0xf2, 0xb2,
+	0x62, 0x75, 0x1a, 0x59, 0x5b, 0x35, 0xd6, 0xe0, 0x8f, 0x03, 0x17, 0x56, 0x00, 0x8c, 0xf0, 0xfb,
+	0x06, 0x73, 0x45, 0x9e, 0x1a, 0xdb, 0x0d, 0xba, 0x1f, 0x5e, 0x1c, 0x89, 0x1b, 0xe9, 0x1a, 0x79,
+	0x06, 0x67, 0xd3, 0x8d, 0x94, 0x28, 0x14, 0x6d, 0x19, 0x0f, 0x48, 0xbd, 0xcd, 0xce, 0x53, 0xb6,
+	0x90, 0x10, 0x60, 0xca, 0x44, 0xcc, 0x63, 0xa6, 0x30, 0xa7, 0xee, 0x49, 0x40, 0xad, 0x8b, 0x8c,
+	0xa1, 0xb7, 0x90, 0x3c, 0x95, 0x5c, 0xed, 0x68, 0xfb, 0x24, 0xa2, 0xea, 0x09, 0x9e, 0xc3, 0xe5,
+	0x7e, 0x0f, 0x2b, 0xc4, 0x91, 0xdf, 0xce, 0x3d, 0xbf, 0x83, 0x5f, 0x0e, 0x74, 0x66, 0x77, 0x7a,
+	0xc6, 0x81, 0x3d, 0x94, 0xa2, 0x15, 0xd9, 0x26, 0xd3, 0xac, 0x3c, 0xee, 0x3f, 0xe4, 0xd1, 0x64,
+	0x52, 0xa6, 0x92, 0xb6, 0x2d, 0x99, 0x0e, 0xc8, 0x35, 0x78, 0x9f, 0xf9, 0x1a, 0x73, 0xc5, 0xd6,
+	0x99, 0x35, 0x71, 0x9f, 0x08, 0x7f, 0x40, 0xef, 0x83, 0xf8, 0x9a, 0xca, 0x35, 0x4a, 0x32, 0x29,
+	0xee, 0x0f, 0xb9, 0xaa, 0xb3, 0x9b, 0x87, 0x3b, 0xa4, 0xf5, 0xd4, 0xc1, 0x13, 0xbe, 0x01, 0xef,
+	0x1d, 0x2a, 0x7b, 0x03, 0x1b, 0x90, 0xc3, 0x06, 0xfd, 0x2c, 0x36, 0xfc, 0xe9, 0x80, 0x67, 0x75,
+	0x49, 0x1f, 0xf8, 0xf9, 0x19, 0xf4, 0x4a, 0x03, 0xc8, 0xe3, 0x7a, 0xd7, 0xd1, 0xf5, 0x1a, 0x5e,
+	0x37, 0x17, 0xed, 0x24, 0x1b, 0xf0, 0x8c, 0xf4, 0x9f, 0xb8, 0x58, 0x3d, 0x6c, 0x90, 0x09, 0xf4,
+	0xdf, 0x33, 0x11, 0x27, 0x58, 0x58, 0x78, 0x88, 0xd5, 0xa9, 0xe1, 0x7d, 0xba, 0xdb, 0xae, 0xf9,
+	0x57, 0x4e, 0xfe, 0x0e, 0x00, 0x71, 0xa0, 0x6e, 0xa3, 0x3c, 0x05, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// InformerClient is the client API for Informer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type InformerClient interface {
+	Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error)
+	GetMetric(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*MetricResponse, error)
+}
+
+type informerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewInformerClient(cc *grpc.ClientConn) InformerClient {
+	return &informerClient{cc}
+}
+
+func (c *informerClient) Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error) {
+	out := new(NameResponse)
+	err := c.cc.Invoke(ctx, "/plugins.pb.Informer/Name", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *informerClient) GetMetric(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*MetricResponse, error) {
+	out := new(MetricResponse)
+	err := c.cc.Invoke(ctx, "/plugins.pb.Informer/GetMetric", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InformerServer is the server API for Informer service.
+type InformerServer interface {
+	Name(context.Context, *Empty) (*NameResponse, error)
+	GetMetric(context.Context, *Empty) (*MetricResponse, error)
+}
+
+func RegisterInformerServer(s *grpc.Server, srv InformerServer) {
+	s.RegisterService(&_Informer_serviceDesc, srv)
+}
+
+func _Informer_Name_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InformerServer).Name(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/plugins.pb.Informer/Name",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InformerServer).Name(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Informer_GetMetric_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InformerServer).GetMetric(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/plugins.pb.Informer/GetMetric",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InformerServer).GetMetric(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Informer_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "plugins.pb.Informer",
+	HandlerType: (*InformerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Name",
+			Handler:    _Informer_Name_Handler,
+		},
+		{
+			MethodName: "GetMetric",
+			Handler:    _Informer_GetMetric_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugins.proto",
+}
+
+// AllocatorClient is the client API for Allocator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AllocatorClient interface {
+	Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error)
+	Allocate(ctx context.Context, in *AllocateRequest, opts ...grpc.CallOption) (*AllocateResponse, error)
+}
+
+type allocatorClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAllocatorClient(cc *grpc.ClientConn) AllocatorClient {
+	return &allocatorClient{cc}
+}
+
+func (c *allocatorClient) Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error) {
+	out := new(NameResponse)
+	err := c.cc.Invoke(ctx, "/plugins.pb.Allocator/Name", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *allocatorClient) Allocate(ctx context.Context, in *AllocateRequest, opts ...grpc.CallOption) (*AllocateResponse, error) {
+	out := new(AllocateResponse)
+	err := c.cc.Invoke(ctx, "/plugins.pb.Allocator/Allocate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AllocatorServer is the server API for Allocator service.
+type AllocatorServer interface {
+	Name(context.Context, *Empty) (*NameResponse, error)
+	Allocate(context.Context, *AllocateRequest) (*AllocateResponse, error)
+}
+
+func RegisterAllocatorServer(s *grpc.Server, srv AllocatorServer) {
+	s.RegisterService(&_Allocator_serviceDesc, srv)
+}
+
+func _Allocator_Name_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AllocatorServer).Name(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/plugins.pb.Allocator/Name",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AllocatorServer).Name(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Allocator_Allocate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AllocatorServer).Allocate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/plugins.pb.Allocator/Allocate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AllocatorServer).Allocate(ctx, req.(*AllocateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Allocator_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "plugins.pb.Allocator",
+	HandlerType: (*AllocatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Name",
+			Handler:    _Allocator_Name_Handler,
+		},
+		{
+			MethodName: "Allocate",
+			Handler:    _Allocator_Allocate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugins.proto",
+}
+
+// EventSinkClient is the client API for EventSink service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type EventSinkClient interface {
+	Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error)
+	HandleEvent(ctx context.Context, in *Event, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type eventSinkClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewEventSinkClient(cc *grpc.ClientConn) EventSinkClient {
+	return &eventSinkClient{cc}
+}
+
+func (c *eventSinkClient) Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error) {
+	out := new(NameResponse)
+	err := c.cc.Invoke(ctx, "/plugins.pb.EventSink/Name", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventSinkClient) HandleEvent(ctx context.Context, in *Event, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/plugins.pb.EventSink/HandleEvent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EventSinkServer is the server API for EventSink service.
+type EventSinkServer interface {
+	Name(context.Context, *Empty) (*NameResponse, error)
+	HandleEvent(context.Context, *Event) (*Empty, error)
+}
+
+func RegisterEventSinkServer(s *grpc.Server, srv EventSinkServer) {
+	s.RegisterService(&_EventSink_serviceDesc, srv)
+}
+
+func _EventSink_Name_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventSinkServer).Name(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/plugins.pb.EventSink/Name",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventSinkServer).Name(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventSink_HandleEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Event)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventSinkServer).HandleEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/plugins.pb.EventSink/HandleEvent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventSinkServer).HandleEvent(ctx, req.(*Event))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _EventSink_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "plugins.pb.EventSink",
+	HandlerType: (*EventSinkServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Name",
+			Handler:    _EventSink_Name_Handler,
+		},
+		{
+			MethodName: "HandleEvent",
+			Handler:    _EventSink_HandleEvent_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugins.proto",
+}
@@ -0,0 +1,131 @@
+// Package plugins allows to write Informers, PinAllocators and event sinks
+// which run as separate processes (plugins), isolating third-party code from
+// the ipfs-cluster-service daemon. Plugins are served and launched with
+// hashicorp/go-plugin over gRPC. The daemon side is implemented in the
+// plugins/host package, which makes plugins available as the "plugin"
+// informer and allocator, and in the stateless pintracker, whose hooks can
+// send pin events to event sink plugins.
+//
+// Plugins written in Go can simply call ServeInformer, ServeAllocator or
+// ServeEventSink from their main() function. Plugins in other languages
+// implement the gRPC service matching their kind, as defined in
+// pb/plugins.proto, and follow the go-plugin handshake: they are launched
+// with the MagicCookieKey environment variable set to MagicCookieValue and
+// must print
+//
+//	1|<ProtocolVersion>|tcp|<address>|grpc
+//
+// on their standard output once they listen on the given address. The
+// plugin is dispensed with the name of its kind ("informer", "allocator"
+// or "eventsink").
+package plugins
+
+import (
+	"context"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	plugin "github.com/hashicorp/go-plugin"
+	logging "github.com/ipfs/go-log"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+var logger = logging.Logger("plugins")
+
+// Handshake and protocol constants.
+const (
+	// MagicCookieKey is the environment variable set by the daemon
+	// when launching a plugin.
+	MagicCookieKey = "IPFS_CLUSTER_PLUGIN_MAGIC_COOKIE"
+	// MagicCookieValue is the value of the MagicCookieKey variable.
+	// It allows plugins to tell that they were launched by the
+	// daemon.
+	MagicCookieValue = "b0b9c6a7e3f14e3c9d4a2a1f0b3d5e77"
+	// ProtocolVersion is the version of the plugin protocol.
+	ProtocolVersion = 1
+)
+
+// Plugin kinds, which are the names under which plugins are dispensed.
+const (
+	KindInformer  = "informer"
+	KindAllocator = "allocator"
+	KindEventSink = "eventsink"
+)
+
+// Handshake is the go-plugin handshake shared by the daemon and the plugins.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  ProtocolVersion,
+	MagicCookieKey:   MagicCookieKey,
+	MagicCookieValue: MagicCookieValue,
+}
+
+// pluginMap returns the go-plugin plugins served or dispensed for each
+// kind. impl is the implementation served by the plugin process, nil on the
+// daemon side.
+func pluginMap(kind string, impl interface{}) map[string]plugin.Plugin {
+	var p plugin.Plugin
+	switch kind {
+	case KindInformer:
+		inf, _ := impl.(Informer)
+		p = &InformerPlugin{Impl: inf}
+	case KindAllocator:
+		alloc, _ := impl.(Allocator)
+		p = &AllocatorPlugin{Impl: alloc}
+	case KindEventSink:
+		sink, _ := impl.(EventSink)
+		p = &EventSinkPlugin{Impl: sink}
+	default:
+		return nil
+	}
+	return map[string]plugin.Plugin{kind: p}
+}
+
+// AllocateArgs are the arguments of Allocate: the pin being allocated and
+// the metrics of the peers currently allocated, the candidates and the
+// priority candidates.
+type AllocateArgs struct {
+	Pin        *api.Pin
+	Current    []*api.Metric
+	Candidates []*api.Metric
+	Priority   []*api.Metric
+}
+
+// Event is sent to event sinks when a pin operation of a peer finishes (see
+// the hooks of the stateless pintracker).
+type Event struct {
+	// Event is "pinned", "unpinned" or "error".
+	Event string
+	// Peer is the peer which tracks the pin.
+	Peer string
+	Pin  *api.Pin
+	// Error is set for the error event.
+	Error     string
+	Timestamp time.Time
+}
+
+// Informer is implemented by informer plugins.
+type Informer interface {
+	// Name returns the name of the metrics produced.
+	Name(ctx context.Context) (string, error)
+	// GetMetric returns the current metric value. Errors produce
+	// invalid metrics.
+	GetMetric(ctx context.Context) (string, error)
+}
+
+// Allocator is implemented by allocator plugins.
+type Allocator interface {
+	// Name returns a short identifier for the allocation strategy.
+	Name(ctx context.Context) (string, error)
+	// Allocate returns the peers to allocate a pin to, in order of
+	// preference. Candidates left out are not allocated.
+	Allocate(ctx context.Context, args *AllocateArgs) ([]peer.ID, error)
+}
+
+// EventSink is implemented by event sink plugins.
+type EventSink interface {
+	// Name returns a short identifier for the event sink.
+	Name(ctx context.Context) (string, error)
+	// HandleEvent processes an event.
+	HandleEvent(ctx context.Context, ev *Event) error
+}
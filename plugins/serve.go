@@ -0,0 +1,31 @@
+package plugins
+
+import (
+	plugin "github.com/hashicorp/go-plugin"
+)
+
+// ServeInformer serves the given informer plugin until the daemon stops
+// it. It exits the program when it was not launched by the daemon.
+func ServeInformer(inf Informer) {
+	serve(KindInformer, inf)
+}
+
+// ServeAllocator serves the given allocator plugin until the daemon stops
+// it. It exits the program when it was not launched by the daemon.
+func ServeAllocator(alloc Allocator) {
+	serve(KindAllocator, alloc)
+}
+
+// ServeEventSink serves the given event sink plugin until the daemon stops
+// it. It exits the program when it was not launched by the daemon.
+func ServeEventSink(sink EventSink) {
+	serve(KindEventSink, sink)
+}
+
+func serve(kind string, impl interface{}) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         pluginMap(kind, impl),
+		GRPCServer:      plugin.DefaultGRPCServer,
+	})
+}
@@ -0,0 +1,214 @@
+// Package policy implements content policies for IPFS Cluster peers. A
+// Filter holds the CIDs that a peer refuses to pin, given directly or
+// through denylists fetched from URLs (such as the "bad bits" list), and
+// the CIDs which are allowed regardless of those lists.
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	cid "github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log"
+)
+
+var logger = logging.Logger("policy")
+
+// SourceConfig identifies the CIDs denied in the configuration.
+const SourceConfig = "config"
+
+// Anchor returns the hashed form of a CID used by double-hashed denylists:
+// the hex-encoded sha256 of its CIDv1 string followed by "/". These lists
+// can be shared without publishing the CIDs they block.
+func Anchor(c cid.Cid) string {
+	v1 := cid.NewCidV1(c.Type(), c.Hash())
+	sum := sha256.Sum256([]byte(v1.String() + "/"))
+	return hex.EncodeToString(sum[:])
+}
+
+// Denylist is a set of denied CIDs and anchors.
+type Denylist struct {
+	hashes  map[string]struct{}
+	anchors map[string]struct{}
+}
+
+// NewDenylist returns an empty Denylist.
+func NewDenylist() *Denylist {
+	return &Denylist{
+		hashes:  make(map[string]struct{}),
+		anchors: make(map[string]struct{}),
+	}
+}
+
+// Add denies the given CID, in any version.
+func (dl *Denylist) Add(c cid.Cid) {
+	dl.hashes[string(c.Hash())] = struct{}{}
+}
+
+// AddAnchor denies the CIDs with the given hashed form (see Anchor).
+func (dl *Denylist) AddAnchor(anchor string) error {
+	b, err := hex.DecodeString(anchor)
+	if err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("invalid denylist anchor: %q", anchor)
+	}
+	dl.anchors[strings.ToLower(anchor)] = struct{}{}
+	return nil
+}
+
+// Len returns the number of entries in the list.
+func (dl *Denylist) Len() int {
+	return len(dl.hashes) + len(dl.anchors)
+}
+
+func (dl *Denylist) contains(c cid.Cid, anchor string) bool {
+	if _, ok := dl.hashes[string(c.Hash())]; ok {
+		return true
+	}
+	_, ok := dl.anchors[anchor]
+	return ok
+}
+
+// Parse reads a denylist. Two formats are accepted:
+//
+//   - Text, with one entry per line: a CID (optionally prefixed with
+//     /ipfs/) or "//" followed by an anchor. Empty lines and lines
+//     starting with "#" are ignored.
+//   - A JSON array of objects with an "anchor" key.
+//
+// Invalid entries are skipped and counted.
+func Parse(r io.Reader) (*Denylist, int, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dl := NewDenylist()
+	skipped := 0
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var entries []struct {
+			Anchor string `json:"anchor"`
+		}
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, 0, fmt.Errorf("error parsing JSON denylist: %s", err)
+		}
+		for _, e := range entries {
+			if dl.AddAnchor(e.Anchor) != nil {
+				skipped++
+			}
+		}
+		return dl, skipped, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "//"):
+			if dl.AddAnchor(strings.TrimPrefix(line, "//")) != nil {
+				skipped++
+			}
+		default:
+			c, err := cid.Decode(strings.TrimPrefix(line, "/ipfs/"))
+			if err != nil {
+				skipped++
+				continue
+			}
+			dl.Add(c)
+		}
+	}
+	return dl, skipped, scanner.Err()
+}
+
+// Fetch downloads and parses the denylist at the given URL.
+func Fetch(ctx context.Context, client *http.Client, url string) (*Denylist, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response fetching %s: %s", url, resp.Status)
+	}
+
+	dl, skipped, err := Parse(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		logger.Warningf("%s: skipped %d invalid denylist entries", url, skipped)
+	}
+	return dl, nil
+}
+
+// Filter decides whether content is denied. It is safe for concurrent use.
+type Filter struct {
+	allowed map[string]struct{}
+	denied  *Denylist
+
+	mu    sync.RWMutex
+	lists map[string]*Denylist
+}
+
+// New returns a Filter which denies the given CIDs, unless they are also
+// allowed. Allowed CIDs are never denied by lists set later either.
+func New(allowed, denied []cid.Cid) *Filter {
+	f := &Filter{
+		allowed: make(map[string]struct{}),
+		denied:  NewDenylist(),
+		lists:   make(map[string]*Denylist),
+	}
+	for _, c := range allowed {
+		f.allowed[string(c.Hash())] = struct{}{}
+	}
+	for _, c := range denied {
+		f.denied.Add(c)
+	}
+	return f
+}
+
+// SetList replaces the denylist obtained from the given source (i.e. its
+// URL).
+func (f *Filter) SetList(source string, dl *Denylist) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lists[source] = dl
+}
+
+// Denied returns whether the given CID is denied and, if so, the source of
+// the matching entry (SourceConfig or the URL of a list).
+func (f *Filter) Denied(c cid.Cid) (bool, string) {
+	if _, ok := f.allowed[string(c.Hash())]; ok {
+		return false, ""
+	}
+
+	anchor := Anchor(c)
+	if f.denied.contains(c, anchor) {
+		return true, SourceConfig
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for source, dl := range f.lists {
+		if dl.contains(c, anchor) {
+			return true, source
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+var (
+	testCid1, _ = cid.Decode("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmmq")
+	testCid2, _ = cid.Decode("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmma")
+	testCid3, _ = cid.Decode("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmmb")
+)
+
+func TestParse(t *testing.T) {
+	list := fmt.Sprintf(`# a comment
+
+%s
+/ipfs/%s
+//%s
+not-a-cid
+//abcd
+`, testCid1, testCid2, Anchor(testCid3))
+
+	dl, skipped, err := Parse(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Len() != 3 {
+		t.Errorf("expected 3 entries, got %d", dl.Len())
+	}
+	if skipped != 2 {
+		t.Errorf("expected 2 skipped entries, got %d", skipped)
+	}
+
+	jsonList := fmt.Sprintf(`[{"anchor": "%s"}, {"anchor": "bad"}]`, Anchor(testCid1))
+	dl, skipped, err = Parse(strings.NewReader(jsonList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Len() != 1 || skipped != 1 {
+		t.Errorf("unexpected JSON denylist: %d entries, %d skipped", dl.Len(), skipped)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := New([]cid.Cid{testCid2}, []cid.Cid{testCid1, testCid2})
+
+	if denied, source := f.Denied(testCid1); !denied || source != SourceConfig {
+		t.Error("expected testCid1 to be denied by the configuration")
+	}
+	// CIDv1 of a denied CIDv0
+	v1 := cid.NewCidV1(testCid1.Type(), testCid1.Hash())
+	if denied, _ := f.Denied(v1); !denied {
+		t.Error("expected every version of a denied CID to be denied")
+	}
+	if denied, _ := f.Denied(testCid2); denied {
+		t.Error("allowed CIDs should not be denied")
+	}
+	if denied, _ := f.Denied(testCid3); denied {
+		t.Error("testCid3 should not be denied yet")
+	}
+
+	dl := NewDenylist()
+	if err := dl.AddAnchor(Anchor(testCid3)); err != nil {
+		t.Fatal(err)
+	}
+	f.SetList("https://example.org/denylist", dl)
+	if denied, source := f.Denied(testCid3); !denied || source != "https://example.org/denylist" {
+		t.Error("expected testCid3 to be denied by the list")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/denylist" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, testCid1)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	dl, err := Fetch(ctx, srv.Client(), srv.URL+"/denylist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Len() != 1 {
+		t.Errorf("expected 1 entry, got %d", dl.Len())
+	}
+
+	_, err = Fetch(ctx, srv.Client(), srv.URL+"/missing")
+	if err == nil {
+		t.Error("expected an error fetching a missing list")
+	}
+}
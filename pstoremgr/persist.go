@@ -0,0 +1,213 @@
+package pstoremgr
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	ds "github.com/ipfs/go-datastore"
+	namespace "github.com/ipfs/go-datastore/namespace"
+	query "github.com/ipfs/go-datastore/query"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// DatastoreNamespace is the datastore namespace under which the addresses
+// of known peers are persisted.
+var DatastoreNamespace = "/peerstore"
+
+// peerRecord is the persisted form of the addresses known for a peer.
+// LastSeen is the last time (in unix nanoseconds) that we were connected
+// to the peer.
+type peerRecord struct {
+	Addrs    []string `json:"addrs"`
+	LastSeen int64    `json:"last_seen"`
+}
+
+func peerKey(p peer.ID) ds.Key {
+	return ds.NewKey(peer.IDB58Encode(p))
+}
+
+// SetDatastore makes the Manager persist the addresses of known peers in
+// the given datastore (see PersistPeers). Peers which have not been seen
+// connected for longer than ttl are forgotten. A ttl of 0 means that
+// addresses never expire.
+func (pm *Manager) SetDatastore(store ds.Datastore, ttl time.Duration) {
+	if store == nil {
+		return
+	}
+	pm.store = namespace.Wrap(store, ds.NewKey(DatastoreNamespace))
+	pm.ttl = ttl
+}
+
+func (pm *Manager) expired(rec *peerRecord, now time.Time) bool {
+	return pm.ttl > 0 && now.Sub(time.Unix(0, rec.LastSeen)) > pm.ttl
+}
+
+func (pm *Manager) getRecord(p peer.ID) (*peerRecord, bool) {
+	if pm.store == nil {
+		return nil, false
+	}
+	b, err := pm.store.Get(peerKey(p))
+	if err != nil {
+		return nil, false
+	}
+	rec := &peerRecord{}
+	if err := json.Unmarshal(b, rec); err != nil {
+		logger.Warningf("discarding unreadable peerstore record for %s: %s", p, err)
+		return nil, false
+	}
+	return rec, true
+}
+
+func (pm *Manager) connected(p peer.ID) bool {
+	return pm.host.Network().Connectedness(p) == inet.Connected
+}
+
+// PersistPeers stores the addresses currently known for the given peers
+// in the datastore, updating the last time they were seen when we are
+// connected to them. Records for peers not seen for longer than the TTL
+// are removed.
+func (pm *Manager) PersistPeers(peers []peer.ID) error {
+	if pm.host == nil || pm.store == nil {
+		return nil
+	}
+
+	now := time.Now()
+	for _, p := range peers {
+		if p == pm.host.ID() {
+			continue
+		}
+
+		addrs := pm.host.Peerstore().Addrs(p)
+		if len(addrs) == 0 {
+			continue
+		}
+
+		rec := &peerRecord{LastSeen: now.UnixNano()}
+		if old, ok := pm.getRecord(p); ok && !pm.connected(p) {
+			rec.LastSeen = old.LastSeen
+		}
+
+		if pm.expired(rec, now) {
+			logger.Debugf("forgetting addresses for %s: not seen since %s", p, time.Unix(0, rec.LastSeen))
+			if err := pm.store.Delete(peerKey(p)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, a := range addrs {
+			rec.Addrs = append(rec.Addrs, a.String())
+		}
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if err := pm.store.Put(peerKey(p), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportPersistedPeers adds the addresses persisted in the datastore to
+// the host's peerstore, optionally dialing the peers. Addresses are kept
+// in the peerstore until they would expire according to the TTL. Expired
+// records are removed.
+func (pm *Manager) ImportPersistedPeers(connect bool) error {
+	if pm.host == nil || pm.store == nil {
+		return nil
+	}
+
+	results, err := pm.store.Query(query.Query{})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	now := time.Now()
+	n := 0
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		k := ds.NewKey(r.Key)
+		p, err := peer.IDB58Decode(k.BaseNamespace())
+		if err != nil {
+			logger.Warningf("discarding peerstore record with bad key %s", r.Key)
+			continue
+		}
+		rec := &peerRecord{}
+		if err := json.Unmarshal(r.Value, rec); err != nil {
+			logger.Warningf("discarding unreadable peerstore record for %s: %s", p, err)
+			continue
+		}
+		if pm.expired(rec, now) {
+			pm.store.Delete(k)
+			continue
+		}
+
+		ttl := peerstore.PermanentAddrTTL
+		if pm.ttl > 0 {
+			ttl = pm.ttl - now.Sub(time.Unix(0, rec.LastSeen))
+		}
+		for _, s := range rec.Addrs {
+			addr, err := ma.NewMultiaddr(s)
+			if err != nil {
+				logger.Warningf("discarding bad persisted address for %s: %s", p, err)
+				continue
+			}
+			pm.host.Peerstore().AddAddr(p, addr, ttl)
+		}
+		n++
+
+		if connect {
+			pm.connect(p)
+		}
+	}
+	logger.Debugf("imported addresses for %d persisted peers", n)
+	return nil
+}
+
+// KnownPeers lists the peers in the host's peerstore for which addresses
+// are known, along with whether we are connected to them and the last time
+// they were seen.
+func (pm *Manager) KnownPeers() []*api.KnownPeer {
+	if pm.host == nil {
+		return nil
+	}
+
+	var known []*api.KnownPeer
+	for _, p := range pm.host.Peerstore().PeersWithAddrs() {
+		if p == pm.host.ID() {
+			continue
+		}
+		kp := &api.KnownPeer{
+			ID:        p,
+			Addresses: pm.peerAddrs(p),
+			Connected: pm.connected(p),
+		}
+		if kp.Connected {
+			kp.LastSeen = time.Now()
+		} else if rec, ok := pm.getRecord(p); ok {
+			kp.LastSeen = time.Unix(0, rec.LastSeen)
+		}
+		known = append(known, kp)
+	}
+	return known
+}
+
+// peerAddrs returns all the addresses known for a peer, encapsulating the
+// peer ID.
+func (pm *Manager) peerAddrs(p peer.ID) []api.Multiaddr {
+	peerPart, _ := ma.NewMultiaddr("/ipfs/" + peer.IDB58Encode(p))
+	var addrs []api.Multiaddr
+	for _, a := range pm.host.Peerstore().Addrs(p) {
+		addrs = append(addrs, api.NewMultiaddrWithValue(a.Encapsulate(peerPart)))
+	}
+	return addrs
+}
@@ -2,7 +2,7 @@
 // addition, listing and removal of cluster peer multiaddresses from
 // the libp2p Host. This includes resolving DNS addresses, decapsulating
 // and encapsulating the /p2p/ (/ipfs/) protocol as needed, listing, saving
-// and loading addresses.
+// and loading addresses, and persisting them to a datastore.
 package pstoremgr
 
 import (
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ipfs/ipfs-cluster/api"
 
+	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log"
 	host "github.com/libp2p/go-libp2p-host"
 	peer "github.com/libp2p/go-libp2p-peer"
@@ -38,6 +39,10 @@ type Manager struct {
 	host          host.Host
 	peerstoreLock sync.Mutex
 	peerstorePath string
+
+	// set with SetDatastore
+	store ds.Datastore
+	ttl   time.Duration
 }
 
 // New creates a Manager with the given libp2p Host and peerstorePath.
@@ -79,15 +84,20 @@ func (pm *Manager) ImportPeer(addr ma.Multiaddr, connect bool) error {
 		pm.ImportPeers(resolvedAddrs, connect)
 	}
 	if connect {
-		go func() {
-			ctx, cancel := context.WithTimeout(pm.ctx, ConnectTimeout)
-			defer cancel()
-			pm.host.Network().DialPeer(ctx, pid)
-		}()
+		pm.connect(pid)
 	}
 	return nil
 }
 
+// connect dials the given peer in the background.
+func (pm *Manager) connect(pid peer.ID) {
+	go func() {
+		ctx, cancel := context.WithTimeout(pm.ctx, ConnectTimeout)
+		defer cancel()
+		pm.host.Network().DialPeer(ctx, pid)
+	}()
+}
+
 // RmPeer clear all addresses for a given peer ID from the host's peerstore.
 func (pm *Manager) RmPeer(pid peer.ID) error {
 	if pm.host == nil {
@@ -96,6 +106,9 @@ func (pm *Manager) RmPeer(pid peer.ID) error {
 
 	logger.Debugf("forgetting peer %s", pid.Pretty())
 	pm.host.Peerstore().ClearAddrs(pid)
+	if pm.store != nil {
+		return pm.store.Delete(peerKey(pid))
+	}
 	return nil
 }
 
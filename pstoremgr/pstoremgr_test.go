@@ -2,12 +2,16 @@ package pstoremgr
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/datastore/inmem"
 
 	libp2p "github.com/libp2p/go-libp2p"
+	peer "github.com/libp2p/go-libp2p-peer"
 	ma "github.com/multiformats/go-multiaddr"
 )
 
@@ -103,3 +107,80 @@ func TestPeerstore(t *testing.T) {
 		t.Error("expected 2 addresses from the peerstore")
 	}
 }
+
+func TestPersistPeers(t *testing.T) {
+	store := inmem.New()
+	pm := makeMgr(t)
+	defer clean(pm)
+	pm.SetDatastore(store, time.Hour)
+
+	testPeer, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/1234/ipfs/" + pid)
+	err := pm.ImportPeer(testPeer, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peers := api.StringsToPeers([]string{pid})
+	err = pm.PersistPeers(peers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	known := pm.KnownPeers()
+	if len(known) != 1 || known[0].ID != peers[0] || known[0].Connected {
+		t.Fatal("unexpected known peers:", known)
+	}
+	if known[0].LastSeen.IsZero() {
+		t.Error("a persisted peer should have a last seen time")
+	}
+
+	pm2 := makeMgr(t)
+	defer clean(pm2)
+	pm2.SetDatastore(store, time.Hour)
+	err = pm2.ImportPersistedPeers(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := pm2.PeersAddresses(peers)
+	if len(addrs) != 1 || !addrs[0].Equal(testPeer) {
+		t.Error("expected the persisted address:", addrs)
+	}
+
+	pm2.RmPeer(peers[0])
+	pm3 := makeMgr(t)
+	defer clean(pm3)
+	pm3.SetDatastore(store, time.Hour)
+	pm3.ImportPersistedPeers(false)
+	if len(pm3.PeersAddresses(peers)) != 0 {
+		t.Error("removed peers should not be persisted")
+	}
+}
+
+func TestPersistPeersTTL(t *testing.T) {
+	store := inmem.New()
+	pm := makeMgr(t)
+	defer clean(pm)
+	pm.SetDatastore(store, time.Hour)
+
+	p := api.StringsToPeers([]string{pid})[0]
+	rec := &peerRecord{
+		Addrs:    []string{"/ip4/127.0.0.1/tcp/1234"},
+		LastSeen: time.Now().Add(-2 * time.Hour).UnixNano(),
+	}
+	b, _ := json.Marshal(rec)
+	err := pm.store.Put(peerKey(p), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pm.ImportPersistedPeers(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pm.PeersAddresses([]peer.ID{p})) != 0 {
+		t.Error("expired addresses should not be imported")
+	}
+	if _, ok := pm.getRecord(p); ok {
+		t.Error("expired records should be removed")
+	}
+}
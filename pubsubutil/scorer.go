@@ -0,0 +1,73 @@
+// Package pubsubutil provides utilities for components which use libp2p
+// pubsub topics.
+package pubsubutil
+
+import (
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+var logger = logging.Logger("pubsubutil")
+
+// Scorer keeps track of the invalid messages sent by every peer on a pubsub
+// topic. Peers sending as many invalid messages as the threshold within the
+// window are blacklisted: pubsub drops their messages and stops talking to
+// them until the peer restarts.
+type Scorer struct {
+	psub      *pubsub.PubSub
+	topic     string
+	threshold int
+	window    time.Duration
+
+	mu     sync.Mutex
+	scores map[peer.ID]*score
+}
+
+type score struct {
+	invalid int
+	since   time.Time
+}
+
+// NewScorer returns a Scorer for the given topic. A threshold of 0 disables
+// blacklisting.
+func NewScorer(psub *pubsub.PubSub, topic string, threshold int, window time.Duration) *Scorer {
+	return &Scorer{
+		psub:      psub,
+		topic:     topic,
+		threshold: threshold,
+		window:    window,
+		scores:    make(map[peer.ID]*score),
+	}
+}
+
+// Penalize records an invalid message received from the given peer. It
+// returns true when the peer has been blacklisted as a result.
+func (s *Scorer) Penalize(p peer.ID) bool {
+	if s.threshold <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	sc, ok := s.scores[p]
+	if !ok || now.Sub(sc.since) > s.window {
+		sc = &score{since: now}
+		s.scores[p] = sc
+	}
+	sc.invalid++
+	blacklist := sc.invalid >= s.threshold
+	if blacklist {
+		delete(s.scores, p)
+	}
+	s.mu.Unlock()
+
+	if blacklist {
+		logger.Warningf("blacklisting %s: too many invalid messages on %s", p, s.topic)
+		s.psub.BlacklistPeer(p)
+	}
+	return blacklist
+}
@@ -0,0 +1,65 @@
+package pubsubutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/test"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+func testPubSub(t *testing.T) (*pubsub.PubSub, func()) {
+	ctx := context.Background()
+	h, err := libp2p.New(ctx, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	psub, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		h.Close()
+		t.Fatal(err)
+	}
+	return psub, func() { h.Close() }
+}
+
+func TestScorer(t *testing.T) {
+	psub, shutdown := testPubSub(t)
+	defer shutdown()
+
+	s := NewScorer(psub, "test", 3, time.Minute)
+	for i := 0; i < 2; i++ {
+		if s.Penalize(test.PeerID1) {
+			t.Fatal("peer should not be blacklisted yet")
+		}
+	}
+	if s.Penalize(test.PeerID2) {
+		t.Error("scores should be kept per peer")
+	}
+	if !s.Penalize(test.PeerID1) {
+		t.Error("peer should have been blacklisted")
+	}
+}
+
+func TestScorerWindow(t *testing.T) {
+	psub, shutdown := testPubSub(t)
+	defer shutdown()
+
+	s := NewScorer(psub, "test", 2, 100*time.Millisecond)
+	s.Penalize(test.PeerID1)
+	time.Sleep(200 * time.Millisecond)
+	if s.Penalize(test.PeerID1) {
+		t.Error("invalid messages outside the window should not count")
+	}
+}
+
+func TestScorerDisabled(t *testing.T) {
+	s := NewScorer(nil, "test", 0, time.Minute)
+	for i := 0; i < 100; i++ {
+		if s.Penalize(test.PeerID1) {
+			t.Fatal("blacklisting should be disabled")
+		}
+	}
+}
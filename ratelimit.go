@@ -0,0 +1,100 @@
+package ipfscluster
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// Pins and unpins are throttled before being committed to the shared state
+// when a PinRateLimit is configured, so that bulk importers cannot flood
+// the consensus layer (raft log or pubsub) faster than it can cope with.
+// The limit is cluster-wide: every peer allows its share of it (the limit
+// divided by the number of peers) for the operations submitted through it.
+// Operations over the limit are queued for up to PinRateMaxWait. Beyond
+// that, they fail with an api.RateLimitError telling when to retry, which
+// the REST API turns into a 429 response with a Retry-After header. The
+// limit is enforced with a ratelimit.Bucket.
+
+// pinRateRefreshInterval is how often the share of the pin rate limit of
+// this peer is adjusted to the number of cluster peers.
+var pinRateRefreshInterval = 30 * time.Second
+
+// pinRateWatcher adjusts the share of the pin rate limit of this peer.
+func (c *Cluster) pinRateWatcher() {
+	ticker := time.NewTicker(pinRateRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		c.refreshPinRate(c.ctx)
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cluster) refreshPinRate(ctx context.Context) {
+	peers, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Debugf("error listing peers to adjust the pin rate limit: %s", err)
+		return
+	}
+	n := len(peers)
+	if n == 0 {
+		n = 1
+	}
+	c.pinRate.SetRate(c.config.PinRateLimit / float64(n))
+}
+
+// waitPinRate blocks until this peer may commit a pin or unpin operation
+// under the configured rate limit.
+func (c *Cluster) waitPinRate(ctx context.Context) error {
+	if c.config.PinRateLimit == 0 {
+		return nil
+	}
+
+	ctx, span := trace.StartSpan(ctx, "cluster/waitPinRate")
+	defer span.End()
+
+	wait, ok := c.pinRate.Reserve(time.Now(), c.config.PinRateMaxWait)
+	if !ok {
+		err := &api.RateLimitError{RetryAfter: wait}
+		logger.Warningf("refusing pin operation: %s", err)
+		return err
+	}
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		c.pinRate.Release()
+		return ctx.Err()
+	}
+}
+
+// logPin commits a pin to the shared state, respecting the pin rate limit.
+func (c *Cluster) logPin(ctx context.Context, pin *api.Pin) error {
+	if err := c.waitPinRate(ctx); err != nil {
+		return err
+	}
+	return c.consensus.LogPin(ctx, pin)
+}
+
+// logUnpin removes a pin from the shared state, respecting the pin rate
+// limit.
+func (c *Cluster) logUnpin(ctx context.Context, pin *api.Pin) error {
+	if err := c.waitPinRate(ctx); err != nil {
+		return err
+	}
+	return c.consensus.LogUnpin(ctx, pin)
+}
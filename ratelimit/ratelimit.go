@@ -0,0 +1,86 @@
+// Package ratelimit provides the token bucket used to rate-limit requests
+// and operations in the cluster.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket which holds up to one second worth of
+// operations (and at least one). It is safe for concurrent use.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64 // operations per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full Bucket allowing the given number of operations
+// per second.
+func NewBucket(rate float64) *Bucket {
+	b := &Bucket{last: time.Now()}
+	b.SetRate(rate)
+	b.tokens = b.burst
+	return b
+}
+
+// SetRate changes the number of operations allowed per second.
+func (b *Bucket) SetRate(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.rate = rate
+	b.burst = rate
+	if b.burst < 1 {
+		b.burst = 1
+	}
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+func (b *Bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		b.last = now
+	}
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Reserve takes a token and returns how long to wait before the operation
+// can proceed. When that would be longer than maxWait, no token is taken
+// and it returns false along with how long to wait until the operation
+// could proceed.
+func (b *Bucket) Reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0, true
+	}
+
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	if wait > maxWait {
+		b.tokens++
+		return wait, false
+	}
+	return wait, true
+}
+
+// Release gives back a token taken by an operation which did not proceed.
+func (b *Bucket) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
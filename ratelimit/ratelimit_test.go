@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := NewBucket(2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		wait, ok := b.Reserve(now, 0)
+		if !ok || wait != 0 {
+			t.Fatalf("operation %d should proceed right away: %s", i, wait)
+		}
+	}
+
+	wait, ok := b.Reserve(now, time.Second)
+	if !ok {
+		t.Fatal("operation should be allowed to wait")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms, got %s", wait)
+	}
+
+	wait, ok = b.Reserve(now, 500*time.Millisecond)
+	if ok {
+		t.Fatal("operation should be refused")
+	}
+	if wait != time.Second {
+		t.Errorf("expected to retry after 1s, got %s", wait)
+	}
+
+	// The refused operation took no token.
+	wait, ok = b.Reserve(now.Add(time.Second), 0)
+	if !ok || wait != 0 {
+		t.Errorf("operation should proceed after refill: %s", wait)
+	}
+
+	b.Release()
+	wait, ok = b.Reserve(now.Add(time.Second), 0)
+	if !ok || wait != 0 {
+		t.Errorf("released token should be available: %s", wait)
+	}
+}
+
+func TestBucketSetRate(t *testing.T) {
+	b := NewBucket(10)
+	b.SetRate(0.5)
+	now := time.Now()
+
+	wait, ok := b.Reserve(now, 0)
+	if !ok || wait != 0 {
+		t.Fatal("the bucket should hold at least one token")
+	}
+	wait, ok = b.Reserve(now, 0)
+	if ok {
+		t.Fatal("the burst should have been reduced")
+	}
+	if wait != 2*time.Second {
+		t.Errorf("expected to retry after 2s, got %s", wait)
+	}
+}
@@ -0,0 +1,279 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/pstoremgr"
+
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// A peer which loses contact with all the other cluster peers (i.e. after a
+// long network partition) tries to rejoin them on its own, instead of
+// needing a restart. Every ReconnectInterval, it checks whether some known
+// cluster peer is connected. When none is, it dials the known cluster peers
+// and the bootstrap addresses (those given to Join and PeerAddresses),
+// looking up peers without addresses in the DHT, and runs a round of DHT
+// bootstrap. Failed attempts are retried with an exponential backoff, up to
+// ReconnectMaxInterval.
+
+// reconnectState keeps the peers that the reconnection manager tries to
+// contact and the status reported by Cluster.ReconnectStatus.
+type reconnectState struct {
+	mu         sync.Mutex
+	known      map[peer.ID]struct{}
+	bootstraps []ma.Multiaddr
+	status     api.ReconnectStatus
+}
+
+func newReconnectState(bootstraps []ma.Multiaddr) *reconnectState {
+	st := &reconnectState{
+		known: make(map[peer.ID]struct{}),
+	}
+	for _, addr := range bootstraps {
+		st.addBootstrap(addr)
+	}
+	return st
+}
+
+// addBootstrap remembers an address of a cluster peer to dial when
+// reconnecting.
+func (st *reconnectState) addBootstrap(addr ma.Multiaddr) {
+	pid, _, err := api.Libp2pMultiaddrSplit(addr)
+	if err != nil {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, b := range st.bootstraps {
+		if b.Equal(addr) {
+			return
+		}
+	}
+	st.bootstraps = append(st.bootstraps, addr)
+	st.known[pid] = struct{}{}
+}
+
+func (st *reconnectState) bootstrapAddrs() []ma.Multiaddr {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([]ma.Multiaddr{}, st.bootstraps...)
+}
+
+// update adds the given cluster peers to the known ones and returns them
+// all. With replace, peers no longer in the peerset are forgotten, unless
+// they are bootstrap peers.
+func (st *reconnectState) update(self peer.ID, peers []peer.ID, replace bool) []peer.ID {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if replace {
+		st.known = make(map[peer.ID]struct{})
+		for _, addr := range st.bootstraps {
+			pid, _, _ := api.Libp2pMultiaddrSplit(addr)
+			st.known[pid] = struct{}{}
+		}
+	}
+	for _, p := range peers {
+		st.known[p] = struct{}{}
+	}
+	delete(st.known, self)
+
+	known := make([]peer.ID, 0, len(st.known))
+	for p := range st.known {
+		known = append(known, p)
+	}
+	return known
+}
+
+// reconnectBackoff returns how long to wait after the given number of
+// consecutive failed attempts.
+func reconnectBackoff(interval, max time.Duration, attempts int) time.Duration {
+	wait := interval
+	for i := 0; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// reconnectWatcher is started once the peer is ready, so the first check
+// happens right away.
+func (c *Cluster) reconnectWatcher() {
+	timer := time.NewTimer(c.checkReconnect(c.ctx))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(c.checkReconnect(c.ctx))
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// connectedClusterPeers returns the known cluster peers and how many of
+// them are connected.
+func (c *Cluster) connectedClusterPeers(ctx context.Context) (int, []peer.ID) {
+	peers, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Debugf("reconnect: error listing peers: %s", err)
+		peers = nil
+	}
+
+	connected := 0
+	for _, p := range peers {
+		if p != c.id && c.host.Network().Connectedness(p) == inet.Connected {
+			connected++
+		}
+	}
+	// Once we are in touch with the cluster, the peerset is reliable
+	// and removed peers can be forgotten. Otherwise, remember everyone.
+	known := c.reconnect.update(c.id, peers, connected > 0)
+
+	connected = 0
+	for _, p := range known {
+		if c.host.Network().Connectedness(p) == inet.Connected {
+			connected++
+		}
+	}
+	return connected, known
+}
+
+// checkReconnect makes a reconnection attempt when no other cluster peer is
+// connected. It updates the reconnection status and returns how long to
+// wait until the next check.
+func (c *Cluster) checkReconnect(ctx context.Context) time.Duration {
+	ctx, span := trace.StartSpan(ctx, "cluster/checkReconnect")
+	defer span.End()
+
+	connected, known := c.connectedClusterPeers(ctx)
+
+	attempted := false
+	var err error
+	if connected == 0 && len(known) > 0 {
+		logger.Infof("no cluster peers connected. Trying to reconnect to %d known peers", len(known))
+		attempted = true
+		err = c.reconnectPeers(ctx, known)
+		connected, known = c.connectedClusterPeers(ctx)
+		if connected > 0 {
+			logger.Infof("reconnected to %d cluster peers", connected)
+		}
+	}
+
+	now := time.Now()
+	st := c.reconnect
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.status.ConnectedPeers = connected
+	st.status.KnownPeers = len(known)
+	if attempted {
+		st.status.LastAttempt = now
+	}
+	switch {
+	case connected > 0:
+		st.status.State = api.ReconnectStateConnected
+		st.status.Attempts = 0
+		st.status.LastConnected = now
+		st.status.LastError = ""
+	case len(known) == 0:
+		st.status.State = api.ReconnectStateAlone
+		st.status.Attempts = 0
+	default:
+		st.status.State = api.ReconnectStateReconnecting
+		st.status.Attempts++
+		st.status.LastError = ""
+		if err != nil {
+			st.status.LastError = err.Error()
+		}
+	}
+
+	wait := reconnectBackoff(
+		c.config.ReconnectInterval,
+		c.config.ReconnectMaxInterval,
+		st.status.Attempts,
+	)
+	st.status.NextAttempt = now.Add(wait)
+	return wait
+}
+
+// reconnectPeers dials the given cluster peers and the bootstrap addresses
+// in parallel, and then bootstraps the DHT so that we can find the peers
+// through others on the next attempt. It returns one of the dial errors.
+func (c *Cluster) reconnectPeers(ctx context.Context, peers []peer.ID) error {
+	// Bootstrap addresses may have expired from the peerstore.
+	for _, addr := range c.reconnect.bootstrapAddrs() {
+		c.peerManager.ImportPeer(addr, false)
+	}
+
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, p := range peers {
+		wg.Add(1)
+		go func(i int, p peer.ID) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, pstoremgr.ConnectTimeout)
+			defer cancel()
+
+			if len(c.host.Peerstore().Addrs(p)) == 0 {
+				pinfo, err := c.dht.FindPeer(ctx, p)
+				if err != nil {
+					errs[i] = fmt.Errorf("finding %s: %s", p.Pretty(), err)
+					return
+				}
+				c.host.Peerstore().AddAddrs(p, pinfo.Addrs, peerstore.TempAddrTTL)
+			}
+			_, err := c.host.Network().DialPeer(ctx, p)
+			if err != nil {
+				errs[i] = fmt.Errorf("dialing %s: %s", p.Pretty(), err)
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	c.dht.BootstrapOnce(ctx, dht.DefaultBootstrapConfig)
+
+	var firstErr error
+	for _, err := range errs {
+		if err != nil {
+			logger.Debugf("reconnect: %s", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// ReconnectStatus reports whether this peer is in contact with other
+// cluster peers and, when not, how the attempts to reconnect to them are
+// going.
+func (c *Cluster) ReconnectStatus(ctx context.Context) *api.ReconnectStatus {
+	_, span := trace.StartSpan(ctx, "cluster/ReconnectStatus")
+	defer span.End()
+
+	c.reconnect.mu.Lock()
+	status := c.reconnect.status
+	c.reconnect.mu.Unlock()
+
+	status.Peer = c.id
+	if c.config.ReconnectInterval == 0 {
+		status.State = api.ReconnectStateDisabled
+	}
+	return &status
+}
@@ -0,0 +1,254 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// After an outage, many items may be in error state at once. Recovering
+// them all at the same time floods the pin queues in arbitrary order, so a
+// recovery job recovers them in the background instead: at most
+// RecoveryConcurrency items at a time, starting with those pinned by the
+// fewest peers, and waiting until each item is done before taking the
+// next one.
+
+// recoveryPollInterval is how often the status of an item being recovered
+// is checked.
+var recoveryPollInterval = time.Second
+
+// recoveryItemTimeout limits how long to wait for an item to be recovered.
+var recoveryItemTimeout = 10 * time.Minute
+
+// maxRecoveryErrors is the number of failures kept in a RecoveryJob.
+const maxRecoveryErrors = 100
+
+var errRecoveryRunning = errors.New("a recovery job is running already")
+
+// recoveryItem is a CID in error state on some peers.
+type recoveryItem struct {
+	cid    cid.Cid
+	peers  []peer.ID
+	pinned int
+}
+
+// recoveryState holds the last recovery job started by this peer.
+type recoveryState struct {
+	mu  sync.Mutex
+	job *api.RecoveryJob
+}
+
+func (rs *recoveryState) start(pid peer.ID) (*api.RecoveryJob, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.job != nil && rs.job.State == api.RecoveryJobRunning {
+		return nil, errRecoveryRunning
+	}
+	rs.job = &api.RecoveryJob{
+		Peer:    pid,
+		State:   api.RecoveryJobRunning,
+		Started: time.Now(),
+	}
+	return rs.get(), nil
+}
+
+// get returns a copy of the job. It must be called with the lock held.
+func (rs *recoveryState) get() *api.RecoveryJob {
+	if rs.job == nil {
+		return nil
+	}
+	job := *rs.job
+	job.Errors = append([]string{}, rs.job.Errors...)
+	return &job
+}
+
+func (rs *recoveryState) current() *api.RecoveryJob {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.get()
+}
+
+func (rs *recoveryState) setTotal(total int) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.job.Total = total
+}
+
+func (rs *recoveryState) progress(c cid.Cid, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if err == nil {
+		rs.job.Recovered++
+		return
+	}
+	rs.job.Failed++
+	if len(rs.job.Errors) < maxRecoveryErrors {
+		rs.job.Errors = append(rs.job.Errors, fmt.Sprintf("%s: %s", c, err))
+	}
+}
+
+func (rs *recoveryState) finish(err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.job.Finished = time.Now()
+	if err != nil {
+		rs.job.State = api.RecoveryJobFailed
+		rs.job.Error = err.Error()
+		return
+	}
+	rs.job.State = api.RecoveryJobDone
+}
+
+// RecoveryStart starts a recovery job for every item in error state in the
+// cluster and returns it. Only one job can run at a time on each peer.
+func (c *Cluster) RecoveryStart(ctx context.Context) (*api.RecoveryJob, error) {
+	_, span := trace.StartSpan(ctx, "cluster/RecoveryStart")
+	defer span.End()
+
+	job, err := c.recovery.start(c.id)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("starting recovery job")
+	go c.runRecovery(c.ctx)
+	return job, nil
+}
+
+// RecoveryStatus returns the last recovery job started by this peer.
+func (c *Cluster) RecoveryStatus(ctx context.Context) (*api.RecoveryJob, error) {
+	_, span := trace.StartSpan(ctx, "cluster/RecoveryStatus")
+	defer span.End()
+
+	job := c.recovery.current()
+	if job == nil {
+		return nil, errors.New("no recovery job has been started by this peer")
+	}
+	return job, nil
+}
+
+func (c *Cluster) runRecovery(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "cluster/runRecovery")
+	defer span.End()
+
+	items, err := c.recoveryItems(ctx)
+	if err != nil {
+		logger.Errorf("recovery job failed: %s", err)
+		c.recovery.finish(err)
+		return
+	}
+	c.recovery.setTotal(len(items))
+
+	work := make(chan *recoveryItem)
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.RecoveryConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				c.recovery.progress(item.cid, c.recoverItem(ctx, item))
+			}
+		}()
+	}
+
+feed:
+	for _, item := range items {
+		select {
+		case work <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	c.recovery.finish(ctx.Err())
+	job := c.recovery.current()
+	logger.Infof("recovery job finished: %d recovered, %d failed", job.Recovered, job.Failed)
+}
+
+// recoveryItems returns the items in pin or unpin error state on some
+// peer, sorted by the number of peers which have them pinned.
+func (c *Cluster) recoveryItems(ctx context.Context) ([]*recoveryItem, error) {
+	errored, err := c.StatusAll(ctx, api.TrackerStatusPinError|api.TrackerStatusUnpinError)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := c.StatusAll(ctx, api.TrackerStatusPinned)
+	if err != nil {
+		return nil, err
+	}
+	pinnedCount := make(map[cid.Cid]int, len(pinned))
+	for _, gpi := range pinned {
+		for _, pinfo := range gpi.PeerMap {
+			if pinfo.Status == api.TrackerStatusPinned {
+				pinnedCount[gpi.Cid]++
+			}
+		}
+	}
+
+	items := make([]*recoveryItem, 0, len(errored))
+	for _, gpi := range errored {
+		item := &recoveryItem{
+			cid:    gpi.Cid,
+			pinned: pinnedCount[gpi.Cid],
+		}
+		for _, pinfo := range gpi.PeerMap {
+			if pinfo.Status == api.TrackerStatusPinError || pinfo.Status == api.TrackerStatusUnpinError {
+				item.peers = append(item.peers, pinfo.Peer)
+			}
+		}
+		if len(item.peers) > 0 {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].pinned < items[j].pinned
+	})
+	return items, nil
+}
+
+// recoverItem recovers an item on the peers where it is in error state and
+// waits until they are done with it.
+func (c *Cluster) recoverItem(ctx context.Context, item *recoveryItem) error {
+	ctx, cancel := context.WithTimeout(ctx, recoveryItemTimeout)
+	defer cancel()
+
+	for _, p := range item.peers {
+		err := c.recoverItemInPeer(ctx, item.cid, p)
+		if err != nil {
+			return fmt.Errorf("%s: %s", p.Pretty(), err)
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) recoverItemInPeer(ctx context.Context, h cid.Cid, p peer.ID) error {
+	var pinfo api.PinInfo
+	err := c.rpcClient.CallContext(ctx, p, "PinTracker", "Recover", h, &pinfo)
+	for err == nil {
+		switch {
+		case pinfo.Status.Match(api.TrackerStatusError):
+			return errors.New(pinfo.Error)
+		case !pinfo.Status.Match(api.TrackerStatusQueued | api.TrackerStatusPinning | api.TrackerStatusUnpinning):
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(recoveryPollInterval):
+		}
+		err = c.rpcClient.CallContext(ctx, p, "PinTracker", "Status", h, &pinfo)
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+// Package registry allows to compile custom Informer and PinAllocator
+// implementations into the ipfs-cluster-service daemon.
+//
+// Packages providing them register them by name, usually from an init()
+// function, and are compiled in by importing them (i.e. with a blank import
+// in a file added to cmd/ipfs-cluster-service). The registered components
+// are then selected by name in the cluster section of the configuration
+// ("allocation_informer" and "allocator").
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/config"
+)
+
+// ConfigFactory returns a new component configuration, which is registered
+// in the configuration manager so that it is loaded and saved with the
+// rest.
+type ConfigFactory func() config.ComponentConfig
+
+// InformerFactory creates an Informer from its configuration, which is nil
+// when it was registered without a ConfigFactory.
+type InformerFactory func(cfg config.ComponentConfig) (ipfscluster.Informer, error)
+
+// AllocatorFactory creates a PinAllocator from its configuration, which is
+// nil when it was registered without a ConfigFactory.
+type AllocatorFactory func(cfg config.ComponentConfig) (ipfscluster.PinAllocator, error)
+
+type informerEntry struct {
+	newConfig   ConfigFactory
+	newInformer InformerFactory
+}
+
+type allocatorEntry struct {
+	newConfig    ConfigFactory
+	newAllocator AllocatorFactory
+}
+
+var (
+	mux        sync.RWMutex
+	informers  = make(map[string]informerEntry)
+	allocators = make(map[string]allocatorEntry)
+)
+
+// RegisterInformer makes an Informer available under the given name.
+// newConfig can be nil for informers without configuration. It panics if
+// the name is empty or already registered.
+func RegisterInformer(name string, newConfig ConfigFactory, newInformer InformerFactory) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	if name == "" || newInformer == nil {
+		panic("registry: informer name and factory must be set")
+	}
+	if _, ok := informers[name]; ok {
+		panic("registry: informer already registered: " + name)
+	}
+	informers[name] = informerEntry{newConfig, newInformer}
+}
+
+// RegisterAllocator makes a PinAllocator available under the given name.
+// newConfig can be nil for allocators without configuration. It panics if
+// the name is empty or already registered.
+func RegisterAllocator(name string, newConfig ConfigFactory, newAllocator AllocatorFactory) {
+	mux.Lock()
+	defer mux.Unlock()
+
+	if name == "" || newAllocator == nil {
+		panic("registry: allocator name and factory must be set")
+	}
+	if _, ok := allocators[name]; ok {
+		panic("registry: allocator already registered: " + name)
+	}
+	allocators[name] = allocatorEntry{newConfig, newAllocator}
+}
+
+// Informers returns the names of the registered informers, sorted.
+func Informers() []string {
+	mux.RLock()
+	defer mux.RUnlock()
+
+	names := make([]string, 0, len(informers))
+	for name := range informers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Allocators returns the names of the registered allocators, sorted.
+func Allocators() []string {
+	mux.RLock()
+	defer mux.RUnlock()
+
+	names := make([]string, 0, len(allocators))
+	for name := range allocators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Configs holds the configurations of the registered components.
+type Configs struct {
+	informers  map[string]config.ComponentConfig
+	allocators map[string]config.ComponentConfig
+}
+
+// NewConfigs creates the configurations of all the registered components
+// and registers them in the given configuration manager, in the informer
+// and allocator sections. The manager can be nil.
+func NewConfigs(mgr *config.Manager) *Configs {
+	mux.RLock()
+	defer mux.RUnlock()
+
+	cfgs := &Configs{
+		informers:  make(map[string]config.ComponentConfig),
+		allocators: make(map[string]config.ComponentConfig),
+	}
+
+	for name, e := range informers {
+		if e.newConfig == nil {
+			continue
+		}
+		cfg := e.newConfig()
+		cfgs.informers[name] = cfg
+		if mgr != nil {
+			mgr.RegisterComponent(config.Informer, cfg)
+		}
+	}
+
+	for name, e := range allocators {
+		if e.newConfig == nil {
+			continue
+		}
+		cfg := e.newConfig()
+		cfgs.allocators[name] = cfg
+		if mgr != nil {
+			mgr.RegisterComponent(config.Allocator, cfg)
+		}
+	}
+	return cfgs
+}
+
+// NewInformer creates the Informer registered with the given name.
+func (cfgs *Configs) NewInformer(name string) (ipfscluster.Informer, error) {
+	mux.RLock()
+	e, ok := informers[name]
+	mux.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown informer: %s", name)
+	}
+	return e.newInformer(cfgs.informers[name])
+}
+
+// NewAllocator creates the PinAllocator registered with the given name.
+func (cfgs *Configs) NewAllocator(name string) (ipfscluster.PinAllocator, error) {
+	mux.RLock()
+	e, ok := allocators[name]
+	mux.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown allocator: %s", name)
+	}
+	return e.newAllocator(cfgs.allocators[name])
+}
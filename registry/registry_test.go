@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"testing"
+
+	ipfscluster "github.com/ipfs/ipfs-cluster"
+	"github.com/ipfs/ipfs-cluster/allocator/ascendalloc"
+	"github.com/ipfs/ipfs-cluster/config"
+	"github.com/ipfs/ipfs-cluster/informer/numpin"
+)
+
+func init() {
+	RegisterInformer(
+		"test-numpin",
+		func() config.ComponentConfig { return &numpin.Config{} },
+		func(cfg config.ComponentConfig) (ipfscluster.Informer, error) {
+			return numpin.NewInformer(cfg.(*numpin.Config))
+		},
+	)
+	RegisterAllocator(
+		"test-ascend",
+		nil,
+		func(cfg config.ComponentConfig) (ipfscluster.PinAllocator, error) {
+			return ascendalloc.NewAllocator(), nil
+		},
+	)
+}
+
+func TestRegistry(t *testing.T) {
+	if names := Informers(); len(names) != 1 || names[0] != "test-numpin" {
+		t.Error("unexpected informers:", names)
+	}
+	if names := Allocators(); len(names) != 1 || names[0] != "test-ascend" {
+		t.Error("unexpected allocators:", names)
+	}
+
+	mgr := config.NewManager()
+	defer mgr.Shutdown()
+	cfgs := NewConfigs(mgr)
+	err := mgr.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inf, err := cfgs.NewInformer("test-numpin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inf.Name() != numpin.MetricName {
+		t.Error("unexpected informer")
+	}
+
+	_, err = cfgs.NewAllocator("test-ascend")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cfgs.NewInformer("abc")
+	if err == nil {
+		t.Error("expected error with unknown informer")
+	}
+	_, err = cfgs.NewAllocator("abc")
+	if err == nil {
+		t.Error("expected error with unknown allocator")
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic registering a duplicate name")
+		}
+	}()
+	RegisterAllocator(
+		"test-ascend",
+		nil,
+		func(cfg config.ComponentConfig) (ipfscluster.PinAllocator, error) {
+			return ascendalloc.NewAllocator(), nil
+		},
+	)
+}
@@ -0,0 +1,174 @@
+package ipfscluster
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/informer/popularity"
+)
+
+// Replication scaling adjusts the replication factors of pins to the
+// demand for their content. Every ReplicationScalingInterval, the
+// popularity metrics of all peers are aggregated. Pins whose content is
+// popular above ReplicationScalingUpThreshold get their replication
+// factors raised by one (up to ReplicationScalingMax). Once the popularity
+// drops below ReplicationScalingDownThreshold, they are lowered by one
+// until they return to their original values.
+//
+// The original replication factors are kept in the pin metadata, so that
+// scaling can always be reverted. Re-pinning content with explicit options
+// discards them and the new factors become the original ones.
+
+// Metadata keys used to keep the original replication factors of pins
+// whose replication has been scaled.
+const (
+	scalingOrigMinMetaKey = "replication_scaling_orig_min"
+	scalingOrigMaxMetaKey = "replication_scaling_orig_max"
+)
+
+// replicationScalingWatcher adjusts the replication factors of pins
+// periodically.
+func (c *Cluster) replicationScalingWatcher() {
+	ticker := time.NewTicker(c.config.ReplicationScalingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			logger.Debug("auto-triggering replication scaling")
+			c.scaleReplication(c.ctx)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// scaleReplication adjusts the replication factors of all pins to the
+// current popularity of their content. Only the coordinator does it.
+func (c *Cluster) scaleReplication(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "cluster/scaleReplication")
+	defer span.End()
+
+	if !c.isCoordinator(ctx) {
+		return
+	}
+
+	// Scaled pins would not match their signatures anymore.
+	if c.pinVerifier != nil && c.pinVerifier.require {
+		return
+	}
+
+	scores := popularity.Aggregate(c.monitor.LatestMetrics(ctx, popularity.MetricName))
+
+	pins, err := c.Pins(ctx)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	for _, pin := range pins {
+		score := scores[pin.Cid.String()]
+		rplMin, rplMax, ok := c.scaledReplication(pin, score)
+		if !ok {
+			continue
+		}
+
+		logger.Infof(
+			"scaling replication of %s (popularity %.2f): min %d -> %d, max %d -> %d",
+			pin.Cid,
+			score,
+			pin.ReplicationFactorMin,
+			rplMin,
+			pin.ReplicationFactorMax,
+			rplMax,
+		)
+
+		if _, scaled := pin.Metadata[scalingOrigMinMetaKey]; !scaled {
+			pin.Metadata = copyMetadata(pin.Metadata)
+			pin.Metadata[scalingOrigMinMetaKey] = strconv.Itoa(pin.ReplicationFactorMin)
+			pin.Metadata[scalingOrigMaxMetaKey] = strconv.Itoa(pin.ReplicationFactorMax)
+		}
+
+		origMin, origMax, _ := scalingOrigin(pin)
+		if rplMin == origMin && rplMax == origMax {
+			pin.Metadata = copyMetadata(pin.Metadata)
+			delete(pin.Metadata, scalingOrigMinMetaKey)
+			delete(pin.Metadata, scalingOrigMaxMetaKey)
+		}
+
+		pin.ReplicationFactorMin = rplMin
+		pin.ReplicationFactorMax = rplMax
+		pin.Provenance = &api.PinProvenance{Source: api.ProvenanceReallocation}
+		_, _, err := c.pin(ctx, pin, []peer.ID{}, pin.UserAllocations)
+		if err != nil {
+			logger.Errorf("error scaling replication of %s: %s", pin.Cid, err)
+		}
+	}
+}
+
+// scaledReplication returns the replication factors that a pin should have
+// given the popularity of its content, and whether they differ from the
+// current ones.
+func (c *Cluster) scaledReplication(pin *api.Pin, score float64) (int, int, bool) {
+	rplMin := pin.ReplicationFactorMin
+	rplMax := pin.ReplicationFactorMax
+
+	// Only data pins with a replication factor can be scaled.
+	if pin.Type != api.DataType || pin.IsScheduledForRemoval() || pin.IsScheduled() ||
+		rplMin == -1 || rplMax == -1 {
+		return rplMin, rplMax, false
+	}
+
+	bound := c.config.ReplicationScalingMax
+	switch {
+	case score >= c.config.ReplicationScalingUpThreshold:
+		if rplMax >= bound {
+			return rplMin, rplMax, false
+		}
+		return rplMin + 1, rplMax + 1, true
+	case score < c.config.ReplicationScalingDownThreshold:
+		origMin, origMax, scaled := scalingOrigin(pin)
+		if !scaled {
+			return rplMin, rplMax, false
+		}
+		if rplMin > origMin {
+			rplMin--
+		}
+		if rplMax > origMax {
+			rplMax--
+		}
+		return rplMin, rplMax, rplMin != pin.ReplicationFactorMin || rplMax != pin.ReplicationFactorMax
+	default:
+		return rplMin, rplMax, false
+	}
+}
+
+// scalingOrigin returns the original replication factors of a pin whose
+// replication has been scaled, and whether it has been scaled.
+func scalingOrigin(pin *api.Pin) (int, int, bool) {
+	minStr, ok1 := pin.Metadata[scalingOrigMinMetaKey]
+	maxStr, ok2 := pin.Metadata[scalingOrigMaxMetaKey]
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	origMin, err1 := strconv.Atoi(minStr)
+	origMax, err2 := strconv.Atoi(maxStr)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return origMin, origMax, true
+}
+
+func copyMetadata(m map[string]string) map[string]string {
+	cp := make(map[string]string, len(m)+2)
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
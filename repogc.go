@@ -0,0 +1,247 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/rpcutil"
+
+	"go.opencensus.io/trace"
+)
+
+// Garbage collections of the IPFS repositories can be triggered through the
+// cluster (RepoGC). In GC-safe mode (see Config.RepoGCGracePeriod), they
+// avoid removing blocks which are about to be pinned: those sent to the
+// IPFS daemon by adds in progress, which are only pinned once the add
+// finishes, and those of pins allocated to the peer which are not pinned
+// locally yet, whether they are being fetched or have been committed to
+// the shared state and not queued by the pin tracker yet.
+//
+// Adds identify the blocks they put (api.NodeWithMeta.AddID) and tell the
+// peers which received them when they finish (BlockPutDone). Adds which
+// stop putting blocks for longer than the grace period without finishing
+// are considered aborted.
+
+// repoGCPollInterval is how often a postponed garbage collection checks
+// whether it can run.
+var repoGCPollInterval = time.Second
+
+// repoGCGuard coordinates garbage collections with the blocks added to the
+// IPFS daemon through the cluster.
+type repoGCGuard struct {
+	// mu is held for writing while a garbage collection runs and for
+	// reading while blocks are added.
+	mu sync.RWMutex
+
+	lastMux      sync.Mutex
+	lastBlockPut time.Time
+	// adds in progress and when they last put a block.
+	adds map[string]time.Time
+}
+
+func newRepoGCGuard() *repoGCGuard {
+	return &repoGCGuard{
+		adds: make(map[string]time.Time),
+	}
+}
+
+// blockPut runs the given function, which adds a block to the IPFS daemon
+// for the given add (if any), once no garbage collection is running. It is
+// safe to call on a nil repoGCGuard.
+func (g *repoGCGuard) blockPut(addID string, put func() error) error {
+	if g == nil {
+		return put()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	err := put()
+	now := time.Now()
+	g.lastMux.Lock()
+	g.lastBlockPut = now
+	if addID != "" {
+		g.adds[addID] = now
+	}
+	g.lastMux.Unlock()
+	return err
+}
+
+// addDone forgets about a finished add. It is safe to call on a nil
+// repoGCGuard.
+func (g *repoGCGuard) addDone(addID string) {
+	if g == nil {
+		return
+	}
+	g.lastMux.Lock()
+	delete(g.adds, addID)
+	g.lastMux.Unlock()
+}
+
+func (g *repoGCGuard) lastBlock() time.Time {
+	g.lastMux.Lock()
+	defer g.lastMux.Unlock()
+	return g.lastBlockPut
+}
+
+// addsInProgress returns how many adds have put blocks and not finished.
+// Adds which have not put blocks for longer than the grace period are
+// forgotten, as they were aborted.
+func (g *repoGCGuard) addsInProgress(grace time.Duration) int {
+	g.lastMux.Lock()
+	defer g.lastMux.Unlock()
+	for id, last := range g.adds {
+		if time.Since(last) >= grace {
+			logger.Warningf("add %s did not finish and is considered aborted", id)
+			delete(g.adds, id)
+		}
+	}
+	return len(g.adds)
+}
+
+// RepoGC runs the garbage collector of the IPFS daemons of every cluster
+// peer.
+func (c *Cluster) RepoGC(ctx context.Context) ([]*api.RepoGC, error) {
+	_, span := trace.StartSpan(ctx, "cluster/RepoGC")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	lenMembers := len(members)
+
+	replies := make([]*api.RepoGC, lenMembers, lenMembers)
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, lenMembers)
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		members,
+		"Cluster",
+		"RepoGCLocal",
+		struct{}{},
+		rpcutil.CopyRepoGCsToIfaces(replies),
+	)
+
+	for i, err := range errs {
+		if err != nil {
+			logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, members[i], err)
+			replies[i] = &api.RepoGC{
+				Peer:  members[i],
+				Error: err.Error(),
+			}
+		}
+	}
+	return replies, nil
+}
+
+// RepoGCLocal runs the garbage collector of the IPFS daemon of this peer.
+// In GC-safe mode, it waits until it cannot remove blocks which are about
+// to be pinned, for up to RepoGCMaxWait.
+func (c *Cluster) RepoGCLocal(ctx context.Context) (*api.RepoGC, error) {
+	_, span := trace.StartSpan(ctx, "cluster/RepoGCLocal")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	err := c.lockRepoGC(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.gcGuard.mu.Unlock()
+
+	logger.Info("running garbage collection of the IPFS repository")
+	res, err := c.ipfs.RepoGC(ctx)
+	if err != nil {
+		logger.Errorf("error running garbage collection: %s", err)
+		return nil, err
+	}
+	res.Peer = c.id
+	res.Peername = c.config.Peername
+	logger.Infof("garbage collection removed %d blocks", res.Removed)
+	return res, nil
+}
+
+// lockRepoGC locks the guard for a garbage collection. In GC-safe mode, it
+// waits until it is safe to run it, for up to RepoGCMaxWait, and returns an
+// error otherwise.
+func (c *Cluster) lockRepoGC(ctx context.Context) error {
+	maxWait := c.config.RepoGCMaxWait
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(repoGCPollInterval)
+	defer ticker.Stop()
+
+	for {
+		c.gcGuard.mu.Lock()
+		reason := c.repoGCUnsafe(ctx)
+		if reason == "" {
+			return nil
+		}
+		c.gcGuard.mu.Unlock()
+		logger.Debugf("garbage collection postponed: %s", reason)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("garbage collection refused after waiting %s: %s", maxWait, reason)
+		case <-ticker.C:
+		}
+	}
+}
+
+// repoGCUnsafe returns why a garbage collection could remove blocks which
+// are about to be pinned, or an empty string when it cannot.
+func (c *Cluster) repoGCUnsafe(ctx context.Context) string {
+	grace := c.config.RepoGCGracePeriod
+	if grace == 0 {
+		return ""
+	}
+
+	if n := c.gcGuard.addsInProgress(grace); n > 0 {
+		return fmt.Sprintf("%d adds are in progress", n)
+	}
+	if since := time.Since(c.gcGuard.lastBlock()); since < grace {
+		return fmt.Sprintf("blocks were added %s ago", since.Round(time.Second))
+	}
+
+	pending, err := c.pendingPins(ctx)
+	if err != nil {
+		return fmt.Sprintf("pins could not be checked: %s", err)
+	}
+	if pending > 0 {
+		return fmt.Sprintf("%d pins are not pinned locally yet", pending)
+	}
+	return ""
+}
+
+// pendingPins returns how many pins allocated to this peer are being
+// fetched, or are in the shared state and unknown to the pin tracker, which
+// happens until it queues them.
+func (c *Cluster) pendingPins(ctx context.Context) (int, error) {
+	pins, err := c.Pins(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tracked := make(map[string]api.TrackerStatus)
+	for _, pi := range c.tracker.StatusAll(ctx, api.TrackerStatusUndefined) {
+		tracked[pi.Cid.String()] = pi.Status
+	}
+
+	pending := 0
+	for _, pin := range pins {
+		if pin.Type == api.MetaType || pin.IsScheduledForRemoval() || pin.IsRemotePin(c.id) {
+			continue
+		}
+		status, ok := tracked[pin.Cid.String()]
+		if !ok || status.Match(api.TrackerStatusPinQueued|api.TrackerStatusPinning) {
+			pending++
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,253 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// Reservations allow several peers to allocate pins concurrently without
+// all of them picking the same peers (and overcommitting them) because they
+// are looking at the same informer metrics.
+//
+// When a peer allocates a pin, it reserves capacity in the allocated
+// peers. Reservations are optimistic: informers implementing Reserver
+// adjust the metrics of the reserved peers accordingly before they are
+// given to the allocator. A reservation lasts until the reserved peer
+// publishes a metric produced after it, at which point the informer metric
+// is assumed to account for it. Metrics are compared by the time at which
+// they were sent (Metric.SentAt), not when they were received, so that a
+// stale metric delivered late does not drop a reservation. The clocks of
+// the peers are assumed to be roughly in sync (pubsubmon warns otherwise).
+//
+// Every peer broadcasts the number of reservations it holds for every
+// other peer using the reservations metric, so that they are taken into
+// account by all peers when allocating, without any coordination.
+
+// reservationsMetricName is the name of the metric used to let other peers
+// know about the reservations made by this peer.
+var reservationsMetricName = "reservations"
+
+// reservationsPushInterval specifies how often the reservations metric is
+// published while reservations change.
+var reservationsPushInterval = time.Second
+
+type reservations struct {
+	mu sync.Mutex
+	// timestamps (UnixNano) of the reservations made for every peer
+	reserved map[peer.ID][]int64
+	dirty    bool
+}
+
+func newReservations() *reservations {
+	return &reservations{
+		reserved: make(map[peer.ID][]int64),
+	}
+}
+
+// reserve makes a reservation in every given peer.
+func (r *reservations) reserve(peers []peer.ID) {
+	if len(peers) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UnixNano()
+	for _, p := range peers {
+		r.reserved[p] = append(r.reserved[p], now)
+	}
+	r.dirty = true
+}
+
+// metricTimestamp returns when the given metric was produced by its peer.
+// Metrics from peers which do not set SentAt fall back to the time they
+// were received.
+func metricTimestamp(m *api.Metric) int64 {
+	if m.SentAt > 0 {
+		return m.SentAt
+	}
+	return m.ReceivedAt
+}
+
+// reconcile drops the reservations which are older than the given metrics
+// for the same peer.
+func (r *reservations) reconcile(metrics []*api.Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range metrics {
+		ts, ok := r.reserved[m.Peer]
+		if !ok {
+			continue
+		}
+
+		// timestamps are sorted
+		produced := metricTimestamp(m)
+		i := 0
+		for i < len(ts) && ts[i] <= produced {
+			i++
+		}
+		if i == 0 {
+			continue
+		}
+		if i == len(ts) {
+			delete(r.reserved, m.Peer)
+		} else {
+			r.reserved[m.Peer] = ts[i:]
+		}
+		r.dirty = true
+	}
+}
+
+// counts returns the number of reservations made for every peer.
+func (r *reservations) counts() map[peer.ID]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[peer.ID]int, len(r.reserved))
+	for p, ts := range r.reserved {
+		counts[p] = len(ts)
+	}
+	return counts
+}
+
+// metric returns the reservations metric for the given peer and resets
+// the dirty flag.
+func (r *reservations) metric(self peer.ID) (*api.Metric, error) {
+	counts := r.counts()
+	value := make(map[string]int, len(counts))
+	for p, n := range counts {
+		value[peer.IDB58Encode(p)] = n
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.dirty = false
+	r.mu.Unlock()
+
+	return &api.Metric{
+		Name:  reservationsMetricName,
+		Peer:  self,
+		Value: string(b),
+		Valid: true,
+	}, nil
+}
+
+func (r *reservations) isDirty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dirty
+}
+
+// parseReservationsMetric returns the reservation counts carried by a
+// reservations metric.
+func parseReservationsMetric(m *api.Metric) (map[peer.ID]int, error) {
+	value := make(map[string]int)
+	err := json.Unmarshal([]byte(m.Value), &value)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[peer.ID]int, len(value))
+	for k, n := range value {
+		p, err := peer.IDB58Decode(k)
+		if err != nil {
+			return nil, err
+		}
+		counts[p] = n
+	}
+	return counts, nil
+}
+
+// reserveMetrics adjusts the given informer metrics according to the
+// reservations made by this and other peers. It only does something when
+// the informer implements Reserver.
+func (c *Cluster) reserveMetrics(ctx context.Context, metrics []*api.Metric) []*api.Metric {
+	ctx, span := trace.StartSpan(ctx, "cluster/reserveMetrics")
+	defer span.End()
+
+	reserver, ok := c.informer.(Reserver)
+	if !ok {
+		return metrics
+	}
+
+	c.reservations.reconcile(metrics)
+	counts := c.reservations.counts()
+
+	for _, m := range c.monitor.LatestMetrics(ctx, reservationsMetricName) {
+		if m.Peer == c.id {
+			continue
+		}
+		remote, err := parseReservationsMetric(m)
+		if err != nil {
+			logger.Warningf("bad reservations metric from %s: %s", m.Peer, err)
+			continue
+		}
+		for p, n := range remote {
+			counts[p] += n
+		}
+	}
+
+	adjusted := make([]*api.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		if n := counts[m.Peer]; n > 0 {
+			m = reserver.Reserve(m, n)
+		}
+		adjusted = append(adjusted, m)
+	}
+	return adjusted
+}
+
+func (c *Cluster) sendReservationsMetric(ctx context.Context) (*api.Metric, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/sendReservationsMetric")
+	defer span.End()
+
+	metric, err := c.reservations.metric(c.id)
+	if err != nil {
+		return nil, err
+	}
+	metric.SetTTL(c.config.MonitorPingInterval * 2)
+	return metric, c.monitor.PublishMetric(ctx, metric)
+}
+
+// pushReservationsMetrics publishes the reservations metric as soon as
+// reservations change, at most once every reservationsPushInterval. It
+// is also published along with the ping metric.
+func (c *Cluster) pushReservationsMetrics(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "cluster/pushReservationsMetrics")
+	defer span.End()
+
+	ticker := time.NewTicker(reservationsPushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// drop reservations for which newer metrics have
+		// been received.
+		c.reservations.reconcile(c.monitor.LatestMetrics(ctx, c.informer.Name()))
+		if !c.reservations.isDirty() {
+			continue
+		}
+
+		_, err := c.sendReservationsMetric(ctx)
+		if err != nil {
+			logger.Errorf("error broadcasting reservations: %s", err)
+		}
+	}
+}
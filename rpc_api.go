@@ -2,13 +2,16 @@ package ipfscluster
 
 import (
 	"context"
+	"time"
 
 	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/faults"
 	"github.com/ipfs/ipfs-cluster/version"
 	ocgorpc "github.com/lanzafame/go-libp2p-ocgorpc"
 
 	cid "github.com/ipfs/go-cid"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
+	host "github.com/libp2p/go-libp2p-host"
 	peer "github.com/libp2p/go-libp2p-peer"
 	"go.opencensus.io/trace"
 )
@@ -35,8 +38,9 @@ type RPCEndpointType int
 // This does not cover globalPinInfo*(...) broadcasts nor redirects to leader
 // in Raft.
 
-// newRPCServer returns a new RPC Server for Cluster.
-func newRPCServer(c *Cluster) (*rpc.Server, error) {
+// newRPCServer returns a new RPC Server for Cluster, which handles the
+// requests from other peers through the given host.
+func newRPCServer(c *Cluster, h host.Host) (*rpc.Server, error) {
 	var s *rpc.Server
 
 	authF := func(pid peer.ID, svc, method string) bool {
@@ -47,23 +51,32 @@ func newRPCServer(c *Cluster) (*rpc.Server, error) {
 
 		switch endpointType {
 		case RPCTrusted:
-			return c.consensus.IsTrustedPeer(c.ctx, pid)
+			if !c.consensus.IsTrustedPeer(c.ctx, pid) {
+				return false
+			}
 		case RPCOpen:
-			return true
 		default:
 			return false
 		}
+
+		// Injected RPC faults only affect requests from other peers,
+		// as this function is not called for local ones. Failed
+		// requests are rejected as unauthorized.
+		if c.faults != nil {
+			return c.faults.Inject(c.ctx, faults.TargetRPC, svc+"."+method) == nil
+		}
+		return true
 	}
 
 	if c.config.Tracing {
 		s = rpc.NewServer(
-			c.host,
+			h,
 			version.RPCProtocol,
 			rpc.WithServerStatsHandler(&ocgorpc.ServerHandler{}),
 			rpc.WithAuthorizeFunc(authF),
 		)
 	} else {
-		s = rpc.NewServer(c.host, version.RPCProtocol, rpc.WithAuthorizeFunc(authF))
+		s = rpc.NewServer(h, version.RPCProtocol, rpc.WithAuthorizeFunc(authF))
 	}
 
 	cl := &ClusterRPCAPI{c}
@@ -71,12 +84,12 @@ func newRPCServer(c *Cluster) (*rpc.Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	pt := &PinTrackerRPCAPI{c.tracker}
+	pt := &PinTrackerRPCAPI{c.tracker, c.pinVerifier}
 	err = s.RegisterName(RPCServiceID(pt), pt)
 	if err != nil {
 		return nil, err
 	}
-	ic := &IPFSConnectorRPCAPI{c.ipfs}
+	ic := &IPFSConnectorRPCAPI{c.ipfs, c.gcGuard}
 	err = s.RegisterName(RPCServiceID(ic), ic)
 	if err != nil {
 		return nil, err
@@ -121,13 +134,15 @@ type ClusterRPCAPI struct {
 // PinTrackerRPCAPI is a go-libp2p-gorpc service which provides the internal
 // peer API for the PinTracker component.
 type PinTrackerRPCAPI struct {
-	tracker PinTracker
+	tracker  PinTracker
+	verifier *pinVerifier
 }
 
 // IPFSConnectorRPCAPI is a go-libp2p-gorpc service which provides the
 // internal peer API for the IPFSConnector component.
 type IPFSConnectorRPCAPI struct {
-	ipfs IPFSConnector
+	ipfs    IPFSConnector
+	gcGuard *repoGCGuard
 }
 
 // ConsensusRPCAPI is a go-libp2p-gorpc service which provides the
@@ -155,16 +170,55 @@ func (rpcapi *ClusterRPCAPI) ID(ctx context.Context, in struct{}, out *api.ID) e
 
 // Pin runs Cluster.Pin().
 func (rpcapi *ClusterRPCAPI) Pin(ctx context.Context, in *api.Pin, out *struct{}) error {
+	if err := rpcapi.c.verifySignedRequest(ctx, api.SignedPinOp, in); err != nil {
+		return err
+	}
 	return rpcapi.c.Pin(ctx, in)
 }
 
+// PinWithResponse runs Cluster.Pin() and returns the pin, along with
+// whether an identical one existed already.
+func (rpcapi *ClusterRPCAPI) PinWithResponse(ctx context.Context, in *api.Pin, out *api.PinResponse) error {
+	if err := rpcapi.c.verifySignedRequest(ctx, api.SignedPinOp, in); err != nil {
+		return err
+	}
+	pin, dup, err := rpcapi.c.pinDedup(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = api.PinResponse{
+		Pin:       *pin,
+		Duplicate: dup,
+	}
+	return nil
+}
+
 // Unpin runs Cluster.Unpin().
 func (rpcapi *ClusterRPCAPI) Unpin(ctx context.Context, in *api.Pin, out *struct{}) error {
+	if err := rpcapi.c.verifySignedRequest(ctx, api.SignedUnpinOp, in); err != nil {
+		return err
+	}
 	return rpcapi.c.Unpin(ctx, in.Cid)
 }
 
+// RestorePin runs Cluster.RestorePin().
+func (rpcapi *ClusterRPCAPI) RestorePin(ctx context.Context, in *api.Pin, out *api.Pin) error {
+	if err := rpcapi.c.verifySignedRequest(ctx, api.SignedRestoreOp, in); err != nil {
+		return err
+	}
+	pin, err := rpcapi.c.RestorePin(ctx, in.Cid)
+	if err != nil {
+		return err
+	}
+	*out = *pin
+	return nil
+}
+
 // PinPath resolves path into a cid and runs Cluster.Pin().
 func (rpcapi *ClusterRPCAPI) PinPath(ctx context.Context, in *api.PinPath, out *api.Pin) error {
+	if err := rpcapi.c.pinVerifier.verifyPaths(); err != nil {
+		return err
+	}
 	pin, err := rpcapi.c.PinPath(ctx, in)
 	if err != nil {
 		return err
@@ -175,6 +229,9 @@ func (rpcapi *ClusterRPCAPI) PinPath(ctx context.Context, in *api.PinPath, out *
 
 // UnpinPath resolves path into a cid and runs Cluster.Unpin().
 func (rpcapi *ClusterRPCAPI) UnpinPath(ctx context.Context, in *api.PinPath, out *api.Pin) error {
+	if err := rpcapi.c.pinVerifier.verifyPaths(); err != nil {
+		return err
+	}
 	pin, err := rpcapi.c.UnpinPath(ctx, in.Path)
 	if err != nil {
 		return err
@@ -217,6 +274,44 @@ func (rpcapi *ClusterRPCAPI) Peers(ctx context.Context, in struct{}, out *[]*api
 	return nil
 }
 
+// Peerstore runs Cluster.Peerstore().
+func (rpcapi *ClusterRPCAPI) Peerstore(ctx context.Context, in struct{}, out *[]*api.KnownPeer) error {
+	*out = rpcapi.c.Peerstore(ctx)
+	return nil
+}
+
+// PeerstoreAdd runs Cluster.PeerstoreAdd().
+func (rpcapi *ClusterRPCAPI) PeerstoreAdd(ctx context.Context, in []api.Multiaddr, out *[]*api.KnownPeer) error {
+	known, err := rpcapi.c.PeerstoreAdd(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = known
+	return nil
+}
+
+// ReconnectStatus runs Cluster.ReconnectStatus().
+func (rpcapi *ClusterRPCAPI) ReconnectStatus(ctx context.Context, in struct{}, out *api.ReconnectStatus) error {
+	*out = *rpcapi.c.ReconnectStatus(ctx)
+	return nil
+}
+
+// Versions runs Cluster.Versions().
+func (rpcapi *ClusterRPCAPI) Versions(ctx context.Context, in struct{}, out *[]*api.PeerVersion) error {
+	*out = rpcapi.c.Versions(ctx)
+	return nil
+}
+
+// ClusterInfo runs Cluster.ClusterInfo().
+func (rpcapi *ClusterRPCAPI) ClusterInfo(ctx context.Context, in struct{}, out *api.ClusterInfo) error {
+	info, err := rpcapi.c.ClusterInfo(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *info
+	return nil
+}
+
 // PeerAdd runs Cluster.PeerAdd().
 func (rpcapi *ClusterRPCAPI) PeerAdd(ctx context.Context, in peer.ID, out *api.ID) error {
 	id, err := rpcapi.c.PeerAdd(ctx, in)
@@ -242,14 +337,151 @@ func (rpcapi *ClusterRPCAPI) PeerRemove(ctx context.Context, in peer.ID, out *st
 	return rpcapi.c.PeerRemove(ctx, in)
 }
 
+// PeerMigrate runs Cluster.PeerMigrate().
+func (rpcapi *ClusterRPCAPI) PeerMigrate(ctx context.Context, in peer.ID, out *api.PeerMigration) error {
+	res, err := rpcapi.c.PeerMigrate(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *res
+	return nil
+}
+
+// SetAllocatable runs Cluster.SetAllocatable().
+func (rpcapi *ClusterRPCAPI) SetAllocatable(ctx context.Context, in *api.PeerAllocatable, out *struct{}) error {
+	return rpcapi.c.SetAllocatable(ctx, in.Peer, in.Allocatable)
+}
+
+// SetTrackerPaused runs Cluster.SetTrackerPaused().
+func (rpcapi *ClusterRPCAPI) SetTrackerPaused(ctx context.Context, in *api.TrackerPause, out *struct{}) error {
+	return rpcapi.c.SetTrackerPaused(ctx, in.Peer, in.Paused)
+}
+
+// SetTrackerPausedLocal pauses or resumes the pin tracker of this peer.
+func (rpcapi *ClusterRPCAPI) SetTrackerPausedLocal(ctx context.Context, in *api.TrackerPause, out *struct{}) error {
+	return rpcapi.c.setTrackerPausedLocal(ctx, in.Paused)
+}
+
+// SetAllocatableLocal sets whether this peer is a candidate for new
+// allocations.
+func (rpcapi *ClusterRPCAPI) SetAllocatableLocal(ctx context.Context, in *api.PeerAllocatable, out *struct{}) error {
+	return rpcapi.c.setAllocatableLocal(ctx, in.Allocatable)
+}
+
+// ConnectionDenyList runs Cluster.ConnectionDenyList().
+func (rpcapi *ClusterRPCAPI) ConnectionDenyList(ctx context.Context, in struct{}, out *api.ConnectionFilter) error {
+	*out = *rpcapi.c.ConnectionDenyList(ctx)
+	return nil
+}
+
+// ConnectionDeny runs Cluster.ConnectionDeny().
+func (rpcapi *ClusterRPCAPI) ConnectionDeny(ctx context.Context, in *api.ConnectionFilter, out *struct{}) error {
+	return rpcapi.c.ConnectionDeny(ctx, in)
+}
+
+// Provide runs Cluster.Provide().
+func (rpcapi *ClusterRPCAPI) Provide(ctx context.Context, in cid.Cid, out *[]*api.ProvideResult) error {
+	results, err := rpcapi.c.Provide(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = results
+	return nil
+}
+
+// Faults runs Cluster.Faults().
+func (rpcapi *ClusterRPCAPI) Faults(ctx context.Context, in struct{}, out *[]*api.FaultRule) error {
+	rules, err := rpcapi.c.Faults(ctx)
+	if err != nil {
+		return err
+	}
+	*out = rules
+	return nil
+}
+
+// Logs runs Cluster.Logs().
+func (rpcapi *ClusterRPCAPI) Logs(ctx context.Context, in api.LogQuery, out *[]*api.LogEntry) error {
+	entries, err := rpcapi.c.Logs(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = entries
+	return nil
+}
+
+// SetFaults runs Cluster.SetFaults().
+func (rpcapi *ClusterRPCAPI) SetFaults(ctx context.Context, in []*api.FaultRule, out *struct{}) error {
+	return rpcapi.c.SetFaults(ctx, in)
+}
+
+// ConnectionUndeny runs Cluster.ConnectionUndeny().
+func (rpcapi *ClusterRPCAPI) ConnectionUndeny(ctx context.Context, in *api.ConnectionFilter, out *struct{}) error {
+	return rpcapi.c.ConnectionUndeny(ctx, in)
+}
+
+// JoinToken runs Cluster.JoinToken().
+func (rpcapi *ClusterRPCAPI) JoinToken(ctx context.Context, in time.Duration, out *api.JoinToken) error {
+	token, err := rpcapi.c.JoinToken(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *token
+	return nil
+}
+
+// SnapshotCreate runs Cluster.SnapshotCreate().
+func (rpcapi *ClusterRPCAPI) SnapshotCreate(ctx context.Context, in string, out *api.Snapshot) error {
+	snap, err := rpcapi.c.SnapshotCreate(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *snap
+	return nil
+}
+
+// Snapshots runs Cluster.Snapshots().
+func (rpcapi *ClusterRPCAPI) Snapshots(ctx context.Context, in struct{}, out *[]*api.Snapshot) error {
+	snaps, err := rpcapi.c.Snapshots(ctx)
+	if err != nil {
+		return err
+	}
+	*out = snaps
+	return nil
+}
+
+// SnapshotDelete runs Cluster.SnapshotDelete().
+func (rpcapi *ClusterRPCAPI) SnapshotDelete(ctx context.Context, in string, out *struct{}) error {
+	return rpcapi.c.SnapshotDelete(ctx, in)
+}
+
+// SnapshotDiff runs Cluster.SnapshotDiff().
+func (rpcapi *ClusterRPCAPI) SnapshotDiff(ctx context.Context, in api.SnapshotDiffQuery, out *api.SnapshotDiff) error {
+	diff, err := rpcapi.c.SnapshotDiff(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *diff
+	return nil
+}
+
+// SnapshotRestore runs Cluster.SnapshotRestore().
+func (rpcapi *ClusterRPCAPI) SnapshotRestore(ctx context.Context, in api.SnapshotRestoreQuery, out *api.SnapshotRestore) error {
+	res, err := rpcapi.c.SnapshotRestore(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *res
+	return nil
+}
+
 // Join runs Cluster.Join().
 func (rpcapi *ClusterRPCAPI) Join(ctx context.Context, in api.Multiaddr, out *struct{}) error {
 	return rpcapi.c.Join(ctx, in.Value())
 }
 
 // StatusAll runs Cluster.StatusAll().
-func (rpcapi *ClusterRPCAPI) StatusAll(ctx context.Context, in struct{}, out *[]*api.GlobalPinInfo) error {
-	pinfos, err := rpcapi.c.StatusAll(ctx)
+func (rpcapi *ClusterRPCAPI) StatusAll(ctx context.Context, in api.TrackerStatus, out *[]*api.GlobalPinInfo) error {
+	pinfos, err := rpcapi.c.StatusAll(ctx, in)
 	if err != nil {
 		return err
 	}
@@ -257,9 +489,25 @@ func (rpcapi *ClusterRPCAPI) StatusAll(ctx context.Context, in struct{}, out *[]
 	return nil
 }
 
+// StatusSummary runs Cluster.StatusSummary().
+func (rpcapi *ClusterRPCAPI) StatusSummary(ctx context.Context, in struct{}, out *api.StatusSummary) error {
+	summary, err := rpcapi.c.StatusSummary(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *summary
+	return nil
+}
+
+// StatusSummaryLocal runs Cluster.StatusSummaryLocal().
+func (rpcapi *ClusterRPCAPI) StatusSummaryLocal(ctx context.Context, in struct{}, out *api.PeerStatusSummary) error {
+	*out = *rpcapi.c.StatusSummaryLocal(ctx)
+	return nil
+}
+
 // StatusAllLocal runs Cluster.StatusAllLocal().
-func (rpcapi *ClusterRPCAPI) StatusAllLocal(ctx context.Context, in struct{}, out *[]*api.PinInfo) error {
-	pinfos := rpcapi.c.StatusAllLocal(ctx)
+func (rpcapi *ClusterRPCAPI) StatusAllLocal(ctx context.Context, in api.TrackerStatus, out *[]*api.PinInfo) error {
+	pinfos := rpcapi.c.StatusAllLocal(ctx, in)
 	*out = pinfos
 	return nil
 }
@@ -281,6 +529,26 @@ func (rpcapi *ClusterRPCAPI) StatusLocal(ctx context.Context, in cid.Cid, out *a
 	return nil
 }
 
+// AllocationExplanation runs Cluster.AllocationExplanation().
+func (rpcapi *ClusterRPCAPI) AllocationExplanation(ctx context.Context, in cid.Cid, out *api.AllocationExplanation) error {
+	expl, err := rpcapi.c.AllocationExplanation(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *expl
+	return nil
+}
+
+// AllocationExplanationLocal runs Cluster.AllocationExplanationLocal().
+func (rpcapi *ClusterRPCAPI) AllocationExplanationLocal(ctx context.Context, in cid.Cid, out *api.AllocationExplanation) error {
+	expl, err := rpcapi.c.AllocationExplanationLocal(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *expl
+	return nil
+}
+
 // SyncAll runs Cluster.SyncAll().
 func (rpcapi *ClusterRPCAPI) SyncAll(ctx context.Context, in struct{}, out *[]*api.GlobalPinInfo) error {
 	pinfos, err := rpcapi.c.SyncAll(ctx)
@@ -321,6 +589,26 @@ func (rpcapi *ClusterRPCAPI) SyncLocal(ctx context.Context, in cid.Cid, out *api
 	return nil
 }
 
+// RecoveryStart runs Cluster.RecoveryStart().
+func (rpcapi *ClusterRPCAPI) RecoveryStart(ctx context.Context, in struct{}, out *api.RecoveryJob) error {
+	job, err := rpcapi.c.RecoveryStart(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *job
+	return nil
+}
+
+// RecoveryStatus runs Cluster.RecoveryStatus().
+func (rpcapi *ClusterRPCAPI) RecoveryStatus(ctx context.Context, in struct{}, out *api.RecoveryJob) error {
+	job, err := rpcapi.c.RecoveryStatus(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *job
+	return nil
+}
+
 // RecoverAllLocal runs Cluster.RecoverAllLocal().
 func (rpcapi *ClusterRPCAPI) RecoverAllLocal(ctx context.Context, in struct{}, out *[]*api.PinInfo) error {
 	pinfos, err := rpcapi.c.RecoverAllLocal(ctx)
@@ -331,6 +619,150 @@ func (rpcapi *ClusterRPCAPI) RecoverAllLocal(ctx context.Context, in struct{}, o
 	return nil
 }
 
+// StateSyncAll runs Cluster.StateSyncAll().
+func (rpcapi *ClusterRPCAPI) StateSyncAll(ctx context.Context, in struct{}, out *[]*api.StateSync) error {
+	results, err := rpcapi.c.StateSyncAll(ctx)
+	if err != nil {
+		return err
+	}
+	*out = results
+	return nil
+}
+
+// Ping runs Cluster.Ping().
+func (rpcapi *ClusterRPCAPI) Ping(ctx context.Context, in struct{}, out *time.Time) error {
+	*out = rpcapi.c.Ping(ctx)
+	return nil
+}
+
+// PingPeer runs Cluster.PingPeer().
+func (rpcapi *ClusterRPCAPI) PingPeer(ctx context.Context, in peer.ID, out *api.PingResult) error {
+	*out = *rpcapi.c.PingPeer(ctx, in)
+	return nil
+}
+
+// PingAll runs Cluster.PingAll().
+func (rpcapi *ClusterRPCAPI) PingAll(ctx context.Context, in struct{}, out *[]*api.PingResult) error {
+	results, err := rpcapi.c.PingAll(ctx)
+	if err != nil {
+		return err
+	}
+	*out = results
+	return nil
+}
+
+// StateSyncPeer runs Cluster.StateSyncPeer().
+func (rpcapi *ClusterRPCAPI) StateSyncPeer(ctx context.Context, in peer.ID, out *api.StateSync) error {
+	result, err := rpcapi.c.StateSyncPeer(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *result
+	return nil
+}
+
+// StateSyncLocal runs Cluster.StateSyncLocal().
+func (rpcapi *ClusterRPCAPI) StateSyncLocal(ctx context.Context, in struct{}, out *api.StateSync) error {
+	result, err := rpcapi.c.StateSyncLocal(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *result
+	return nil
+}
+
+// LastStateSyncAll runs Cluster.LastStateSyncAll().
+func (rpcapi *ClusterRPCAPI) LastStateSyncAll(ctx context.Context, in struct{}, out *[]*api.StateSync) error {
+	results, err := rpcapi.c.LastStateSyncAll(ctx)
+	if err != nil {
+		return err
+	}
+	*out = results
+	return nil
+}
+
+// LastStateSyncLocal runs Cluster.LastStateSyncLocal().
+func (rpcapi *ClusterRPCAPI) LastStateSyncLocal(ctx context.Context, in struct{}, out *api.StateSync) error {
+	result, err := rpcapi.c.LastStateSyncLocal(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *result
+	return nil
+}
+
+// AuditLog runs Cluster.AuditLog().
+func (rpcapi *ClusterRPCAPI) AuditLog(ctx context.Context, in struct{}, out *[]*api.AuditRecord) error {
+	recs, err := rpcapi.c.AuditLog(ctx)
+	if err != nil {
+		return err
+	}
+	*out = recs
+	return nil
+}
+
+// RepoGC runs Cluster.RepoGC().
+func (rpcapi *ClusterRPCAPI) RepoGC(ctx context.Context, in struct{}, out *[]*api.RepoGC) error {
+	res, err := rpcapi.c.RepoGC(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// RepoGCLocal runs Cluster.RepoGCLocal().
+func (rpcapi *ClusterRPCAPI) RepoGCLocal(ctx context.Context, in struct{}, out *api.RepoGC) error {
+	res, err := rpcapi.c.RepoGCLocal(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *res
+	return nil
+}
+
+// IPFSDrift runs Cluster.IPFSDrift().
+func (rpcapi *ClusterRPCAPI) IPFSDrift(ctx context.Context, in struct{}, out *api.IPFSDrift) error {
+	drift, err := rpcapi.c.IPFSDrift(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *drift
+	return nil
+}
+
+// IPFSDaemonInfoLocal runs Cluster.IPFSDaemonInfoLocal().
+func (rpcapi *ClusterRPCAPI) IPFSDaemonInfoLocal(ctx context.Context, in struct{}, out *api.IPFSDaemonInfo) error {
+	*out = *rpcapi.c.IPFSDaemonInfoLocal(ctx)
+	return nil
+}
+
+// RuntimeStatsAll runs Cluster.RuntimeStatsAll().
+func (rpcapi *ClusterRPCAPI) RuntimeStatsAll(ctx context.Context, in struct{}, out *[]*api.RuntimeStats) error {
+	results, err := rpcapi.c.RuntimeStatsAll(ctx)
+	if err != nil {
+		return err
+	}
+	*out = results
+	return nil
+}
+
+// RuntimeStatsLocal runs Cluster.RuntimeStatsLocal().
+func (rpcapi *ClusterRPCAPI) RuntimeStatsLocal(ctx context.Context, in struct{}, out *api.RuntimeStats) error {
+	*out = *rpcapi.c.RuntimeStatsLocal(ctx)
+	return nil
+}
+
+// RecoverPeer runs Cluster.RecoverPeer().
+func (rpcapi *ClusterRPCAPI) RecoverPeer(ctx context.Context, in peer.ID, out *[]*api.PinInfo) error {
+	pinfos, err := rpcapi.c.RecoverPeer(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = pinfos
+	return nil
+}
+
 // Recover runs Cluster.Recover().
 func (rpcapi *ClusterRPCAPI) Recover(ctx context.Context, in cid.Cid, out *api.GlobalPinInfo) error {
 	pinfo, err := rpcapi.c.Recover(ctx, in)
@@ -352,13 +784,20 @@ func (rpcapi *ClusterRPCAPI) RecoverLocal(ctx context.Context, in cid.Cid, out *
 }
 
 // BlockAllocate returns allocations for blocks. This is used in the adders.
-// It's different from pin allocations when ReplicationFactor < 0.
+// It's different from pin allocations when ReplicationFactor < 0 or with
+// the BlockPutLocal strategy.
 func (rpcapi *ClusterRPCAPI) BlockAllocate(ctx context.Context, in *api.Pin, out *[]peer.ID) error {
 	err := rpcapi.c.setupPin(ctx, in)
 	if err != nil {
 		return err
 	}
 
+	// Blocks of shards are always spread over the allocations.
+	if rpcapi.c.config.BlockPutStrategy == BlockPutLocal && in.Type == api.DataType {
+		*out = []peer.ID{rpcapi.c.id}
+		return nil
+	}
+
 	// Return the current peer list.
 	if in.ReplicationFactorMin < 0 {
 		// Returned metrics are Valid and belong to current
@@ -375,9 +814,7 @@ func (rpcapi *ClusterRPCAPI) BlockAllocate(ctx context.Context, in *api.Pin, out
 
 	allocs, err := rpcapi.c.allocate(
 		ctx,
-		in.Cid,
-		in.ReplicationFactorMin,
-		in.ReplicationFactorMax,
+		in,
 		[]peer.ID{}, // blacklist
 		[]peer.ID{}, // prio list
 	)
@@ -390,9 +827,26 @@ func (rpcapi *ClusterRPCAPI) BlockAllocate(ctx context.Context, in *api.Pin, out
 	return nil
 }
 
-// SendInformerMetric runs Cluster.sendInformerMetric().
+// BlockFetch runs Cluster.BlockFetch().
+func (rpcapi *ClusterRPCAPI) BlockFetch(ctx context.Context, in *api.BlockFetch, out *api.BlockTransfer) error {
+	tr, err := rpcapi.c.BlockFetch(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *tr
+	return nil
+}
+
+// PeerLatencies runs Cluster.peerLatencies().
+func (rpcapi *ClusterRPCAPI) PeerLatencies(ctx context.Context, in []peer.ID, out *map[string]time.Duration) error {
+	*out = rpcapi.c.peerLatencies(ctx, in)
+	return nil
+}
+
+// SendInformerMetric runs Cluster.sendInformerMetric() with the
+// allocation informer.
 func (rpcapi *ClusterRPCAPI) SendInformerMetric(ctx context.Context, in struct{}, out *api.Metric) error {
-	m, err := rpcapi.c.sendInformerMetric(ctx)
+	m, err := rpcapi.c.sendInformerMetric(ctx, rpcapi.c.informer)
 	if err != nil {
 		return err
 	}
@@ -400,6 +854,43 @@ func (rpcapi *ClusterRPCAPI) SendInformerMetric(ctx context.Context, in struct{}
 	return nil
 }
 
+// StorageUsage runs Cluster.StorageUsage() with the given sample size.
+func (rpcapi *ClusterRPCAPI) StorageUsage(ctx context.Context, in int, out *api.StorageUsage) error {
+	usage, err := rpcapi.c.StorageUsage(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *usage
+	return nil
+}
+
+// AddDelegate runs Cluster.AddDelegate() with the given number of adds in
+// progress.
+func (rpcapi *ClusterRPCAPI) AddDelegate(ctx context.Context, in int, out *peer.ID) error {
+	pid, err := rpcapi.c.AddDelegate(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = pid
+	return nil
+}
+
+// PinnedBytes runs Cluster.PinnedBytes().
+func (rpcapi *ClusterRPCAPI) PinnedBytes(ctx context.Context, in struct{}, out *api.PinnedBytes) error {
+	pb, err := rpcapi.c.PinnedBytes(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *pb
+	return nil
+}
+
+// RecordAccess runs Cluster.RecordAccess().
+func (rpcapi *ClusterRPCAPI) RecordAccess(ctx context.Context, in cid.Cid, out *struct{}) error {
+	rpcapi.c.RecordAccess(ctx, in)
+	return nil
+}
+
 /*
    Tracker component methods
 */
@@ -408,6 +899,9 @@ func (rpcapi *ClusterRPCAPI) SendInformerMetric(ctx context.Context, in struct{}
 func (rpcapi *PinTrackerRPCAPI) Track(ctx context.Context, in *api.Pin, out *struct{}) error {
 	ctx, span := trace.StartSpan(ctx, "rpc/tracker/Track")
 	defer span.End()
+	if err := rpcapi.verifier.verify(api.SignedPinOp, in); err != nil {
+		return err
+	}
 	return rpcapi.tracker.Track(ctx, in)
 }
 
@@ -419,10 +913,10 @@ func (rpcapi *PinTrackerRPCAPI) Untrack(ctx context.Context, in *api.Pin, out *s
 }
 
 // StatusAll runs PinTracker.StatusAll().
-func (rpcapi *PinTrackerRPCAPI) StatusAll(ctx context.Context, in struct{}, out *[]*api.PinInfo) error {
+func (rpcapi *PinTrackerRPCAPI) StatusAll(ctx context.Context, in api.TrackerStatus, out *[]*api.PinInfo) error {
 	ctx, span := trace.StartSpan(ctx, "rpc/tracker/StatusAll")
 	defer span.End()
-	*out = rpcapi.tracker.StatusAll(ctx)
+	*out = rpcapi.tracker.StatusAll(ctx, in)
 	return nil
 }
 
@@ -524,7 +1018,16 @@ func (rpcapi *IPFSConnectorRPCAPI) SwarmPeers(ctx context.Context, in struct{},
 
 // BlockPut runs IPFSConnector.BlockPut().
 func (rpcapi *IPFSConnectorRPCAPI) BlockPut(ctx context.Context, in *api.NodeWithMeta, out *struct{}) error {
-	return rpcapi.ipfs.BlockPut(ctx, in)
+	return rpcapi.gcGuard.blockPut(in.AddID, func() error {
+		return rpcapi.ipfs.BlockPut(ctx, in)
+	})
+}
+
+// BlockPutDone tells that the add with the given ID has finished putting
+// blocks, so that they no longer prevent garbage collections.
+func (rpcapi *IPFSConnectorRPCAPI) BlockPutDone(ctx context.Context, in string, out *struct{}) error {
+	rpcapi.gcGuard.addDone(in)
+	return nil
 }
 
 // BlockGet runs IPFSConnector.BlockGet().
@@ -547,6 +1050,42 @@ func (rpcapi *IPFSConnectorRPCAPI) Resolve(ctx context.Context, in string, out *
 	return nil
 }
 
+// Provide runs IPFSConnector.Provide().
+func (rpcapi *IPFSConnectorRPCAPI) Provide(ctx context.Context, in cid.Cid, out *struct{}) error {
+	return rpcapi.ipfs.Provide(ctx, in)
+}
+
+// DAGBlocks runs IPFSConnector.DAGBlocks() for the Cid and MaxDepth of the
+// given pin.
+func (rpcapi *IPFSConnectorRPCAPI) DAGBlocks(ctx context.Context, in *api.Pin, out *[]*api.IPFSBlockStat) error {
+	res, err := rpcapi.ipfs.DAGBlocks(ctx, in.Cid, in.MaxDepth)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// BitswapStat runs IPFSConnector.BitswapStat().
+func (rpcapi *IPFSConnectorRPCAPI) BitswapStat(ctx context.Context, in struct{}, out *api.IPFSBitswapStat) error {
+	res, err := rpcapi.ipfs.BitswapStat(ctx)
+	if err != nil {
+		return err
+	}
+	*out = *res
+	return nil
+}
+
+// PinProgress runs IPFSConnector.PinProgress().
+func (rpcapi *IPFSConnectorRPCAPI) PinProgress(ctx context.Context, in struct{}, out *[]*api.PinProgress) error {
+	res, err := rpcapi.ipfs.PinProgress(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
 /*
    Consensus component methods
 */
@@ -0,0 +1,143 @@
+package ipfscluster
+
+import (
+	"context"
+
+	snappy "github.com/golang/snappy"
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+)
+
+// RPC streams between peers can be compressed to save bandwidth in
+// geographically distributed clusters (see Config.RPCCompression). Every
+// peer accepts compressed RPC streams, under their own protocol ID, but
+// only peers with compression enabled offer them when calling others. The
+// protocol is negotiated when opening each stream, so peers which do not
+// support compression are still called with plain streams. Compressed
+// streams carry both the request and the response.
+//
+// Data is compressed in chunks, as it is written. Chunks which do not
+// compress well, i.e. small payloads or those made of CIDs and peer IDs
+// only, are sent uncompressed within the compressed stream.
+
+// RPC compression algorithms.
+const (
+	RPCCompressionNone   = ""
+	RPCCompressionSnappy = "snappy"
+)
+
+// compressedRPCProtocol returns the protocol ID of the RPC streams
+// compressed with the given algorithm.
+func compressedRPCProtocol(p protocol.ID, alg string) protocol.ID {
+	return protocol.ID(string(p) + "/" + alg)
+}
+
+// rpcHost wraps the libp2p host used by the RPC server and client, so that
+// the RPC streams are compressed when both ends agree, and scheduled by
+// traffic class (see rpc_traffic.go).
+type rpcHost struct {
+	host.Host
+	protocol   protocol.ID
+	compressed protocol.ID
+	offer      bool
+	sched      *rpcScheduler
+}
+
+func newRPCHost(h host.Host, p protocol.ID, compression string, sched *rpcScheduler) *rpcHost {
+	return &rpcHost{
+		Host:       h,
+		protocol:   p,
+		compressed: compressedRPCProtocol(p, RPCCompressionSnappy),
+		offer:      compression == RPCCompressionSnappy,
+		sched:      sched,
+	}
+}
+
+// SetStreamHandler sets the handler for the given protocol. The RPC
+// protocol handler also handles compressed streams.
+func (h *rpcHost) SetStreamHandler(pid protocol.ID, handler inet.StreamHandler) {
+	if pid != h.protocol {
+		h.Host.SetStreamHandler(pid, handler)
+		return
+	}
+	h.Host.SetStreamHandler(pid, func(s inet.Stream) {
+		h.serve(handler, s, false)
+	})
+	h.Host.SetStreamHandler(h.compressed, func(s inet.Stream) {
+		h.serve(handler, s, true)
+	})
+}
+
+func (h *rpcHost) serve(handler inet.StreamHandler, s inet.Stream, compressed bool) {
+	var cs *classStream
+	if h.sched != nil {
+		cs = newClassStream(s, h.sched)
+		s = cs
+	}
+	if compressed {
+		s = newSnappyStream(s)
+	}
+	if cs == nil {
+		handler(s)
+		return
+	}
+	h.sched.serveClassified(handler, s, cs)
+}
+
+// NewStream opens a new stream. RPC streams are compressed when this peer
+// offers compression and the other peer supports it.
+func (h *rpcHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (inet.Stream, error) {
+	if len(pids) != 1 || pids[0] != h.protocol {
+		return h.Host.NewStream(ctx, p, pids...)
+	}
+
+	offered := pids
+	if h.offer {
+		offered = []protocol.ID{h.compressed, h.protocol}
+	}
+	s, err := h.Host.NewStream(ctx, p, offered...)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs *classStream
+	if h.sched != nil {
+		cs = newClassStream(s, h.sched)
+		s = cs
+	}
+	if s.Protocol() == h.compressed {
+		s = newSnappyStream(s)
+	}
+	if cs != nil {
+		s = &requestStream{Stream: s, cs: cs}
+	}
+	return s, nil
+}
+
+// snappyStream compresses what is written to a stream and decompresses
+// what is read from it, using the snappy framing format.
+type snappyStream struct {
+	inet.Stream
+	r *snappy.Reader
+	w *snappy.Writer
+}
+
+func newSnappyStream(s inet.Stream) *snappyStream {
+	return &snappyStream{
+		Stream: s,
+		r:      snappy.NewReader(s),
+		// The RPC client and server buffer and flush their writes,
+		// so each write is sent right away.
+		w: snappy.NewWriter(s),
+	}
+}
+
+func (s *snappyStream) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+func (s *snappyStream) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
@@ -0,0 +1,52 @@
+package ipfscluster
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	inet "github.com/libp2p/go-libp2p-net"
+)
+
+// pipeStream is an inet.Stream which reads from and writes to buffers.
+type pipeStream struct {
+	inet.Stream
+	r io.Reader
+	w io.Writer
+}
+
+func (s *pipeStream) Read(p []byte) (int, error)  { return s.r.Read(p) }
+func (s *pipeStream) Write(p []byte) (int, error) { return s.w.Write(p) }
+
+func TestSnappyStream(t *testing.T) {
+	var wire bytes.Buffer
+	payload := bytes.Repeat([]byte("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmmq"), 1000)
+
+	w := newSnappyStream(&pipeStream{w: &wire})
+	// Written in two parts, as they would be by separate flushes.
+	if _, err := w.Write(payload[:100]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(payload[100:]); err != nil {
+		t.Fatal(err)
+	}
+	if wire.Len() >= len(payload) {
+		t.Errorf("expected the payload to be compressed: %d bytes", wire.Len())
+	}
+
+	r := newSnappyStream(&pipeStream{r: &wire})
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("the payload read does not match the one written")
+	}
+}
+
+func TestCompressedRPCProtocol(t *testing.T) {
+	if p := compressedRPCProtocol("/ipfscluster/0.10/rpc", RPCCompressionSnappy); p != "/ipfscluster/0.10/rpc/snappy" {
+		t.Errorf("unexpected protocol: %s", p)
+	}
+}
@@ -7,32 +7,85 @@ package ipfscluster
 // without missing any endpoint.
 var DefaultRPCPolicy = map[string]RPCEndpointType{
 	// Cluster methods
-	"Cluster.BlockAllocate":      RPCClosed,
-	"Cluster.ConnectGraph":       RPCClosed,
-	"Cluster.ID":                 RPCOpen,
-	"Cluster.Join":               RPCClosed,
-	"Cluster.PeerAdd":            RPCOpen, // Used by Join()
-	"Cluster.PeerRemove":         RPCTrusted,
-	"Cluster.Peers":              RPCTrusted, // Used by ConnectGraph()
-	"Cluster.Pin":                RPCClosed,
-	"Cluster.PinGet":             RPCClosed,
-	"Cluster.PinPath":            RPCClosed,
-	"Cluster.Pins":               RPCClosed, // Used in stateless tracker, ipfsproxy, restapi
-	"Cluster.Recover":            RPCClosed,
-	"Cluster.RecoverAllLocal":    RPCClosed,
-	"Cluster.RecoverLocal":       RPCClosed,
-	"Cluster.SendInformerMetric": RPCClosed,
-	"Cluster.Status":             RPCClosed,
-	"Cluster.StatusAll":          RPCClosed,
-	"Cluster.StatusAllLocal":     RPCClosed,
-	"Cluster.StatusLocal":        RPCClosed,
-	"Cluster.Sync":               RPCClosed,
-	"Cluster.SyncAll":            RPCClosed,
-	"Cluster.SyncAllLocal":       RPCTrusted, // Called in broadcast from SyncAll()
-	"Cluster.SyncLocal":          RPCTrusted, // Called in broadcast from Sync()
-	"Cluster.Unpin":              RPCClosed,
-	"Cluster.UnpinPath":          RPCClosed,
-	"Cluster.Version":            RPCOpen,
+	"Cluster.AddDelegate":                RPCClosed,
+	"Cluster.AllocationExplanation":      RPCClosed,
+	"Cluster.AllocationExplanationLocal": RPCTrusted, // Called in broadcast from AllocationExplanation()
+	"Cluster.AuditLog":                   RPCClosed,
+	"Cluster.BlockAllocate":              RPCClosed,
+	"Cluster.BlockFetch":                 RPCTrusted, // Called from reallocate()
+	"Cluster.ClusterInfo":                RPCClosed,
+	"Cluster.ConnectGraph":               RPCClosed,
+	"Cluster.ConnectionDeny":             RPCClosed,
+	"Cluster.ConnectionDenyList":         RPCClosed,
+	"Cluster.ConnectionUndeny":           RPCClosed,
+	"Cluster.Faults":                     RPCClosed,
+	"Cluster.ID":                         RPCOpen,
+	"Cluster.IPFSDaemonInfoLocal":        RPCTrusted, // Called in broadcast from IPFSDrift()
+	"Cluster.IPFSDrift":                  RPCClosed,
+	"Cluster.Join":                       RPCClosed,
+	"Cluster.JoinToken":                  RPCClosed,
+	"Cluster.LastStateSyncAll":           RPCClosed,
+	"Cluster.LastStateSyncLocal":         RPCTrusted, // Called in broadcast from LastStateSyncAll()
+	"Cluster.Logs":                       RPCClosed,
+	"Cluster.PeerAdd":                    RPCOpen,    // Used by Join()
+	"Cluster.PeerLatencies":              RPCTrusted, // Used by ConnectGraph()
+	"Cluster.PeerMigrate":                RPCClosed,
+	"Cluster.PeerRemove":                 RPCTrusted,
+	"Cluster.Peers":                      RPCTrusted, // Used by ConnectGraph()
+	"Cluster.Peerstore":                  RPCClosed,
+	"Cluster.PeerstoreAdd":               RPCClosed,
+	"Cluster.Pin":                        RPCClosed,
+	"Cluster.PinGet":                     RPCClosed,
+	"Cluster.PinPath":                    RPCClosed,
+	"Cluster.PinWithResponse":            RPCClosed,  // Used by restapi
+	"Cluster.Ping":                       RPCTrusted, // Called from PingPeer()
+	"Cluster.PingAll":                    RPCClosed,
+	"Cluster.PingPeer":                   RPCClosed,
+	"Cluster.PinnedBytes":                RPCClosed, // Used by the pinnedbytes informer
+	"Cluster.Pins":                       RPCClosed, // Used in stateless tracker, ipfsproxy, restapi
+	"Cluster.Provide":                    RPCClosed,
+	"Cluster.ReconnectStatus":            RPCClosed,
+	"Cluster.RecordAccess":               RPCClosed, // Used by ipfsproxy
+	"Cluster.Recover":                    RPCClosed,
+	"Cluster.RecoverAllLocal":            RPCClosed,
+	"Cluster.RecoverLocal":               RPCClosed,
+	"Cluster.RecoverPeer":                RPCClosed,
+	"Cluster.RecoveryStart":              RPCClosed,
+	"Cluster.RecoveryStatus":             RPCClosed,
+	"Cluster.RepoGC":                     RPCClosed,
+	"Cluster.RepoGCLocal":                RPCTrusted, // Called in broadcast from RepoGC()
+	"Cluster.RestorePin":                 RPCClosed,
+	"Cluster.RuntimeStatsAll":            RPCClosed,
+	"Cluster.RuntimeStatsLocal":          RPCTrusted, // Called in broadcast from RuntimeStatsAll()
+	"Cluster.SendInformerMetric":         RPCClosed,
+	"Cluster.SetAllocatable":             RPCClosed,
+	"Cluster.SetAllocatableLocal":        RPCTrusted, // Called from SetAllocatable()
+	"Cluster.SetFaults":                  RPCClosed,
+	"Cluster.SetTrackerPaused":           RPCClosed,
+	"Cluster.SetTrackerPausedLocal":      RPCTrusted, // Called from SetTrackerPaused()
+	"Cluster.SnapshotCreate":             RPCClosed,
+	"Cluster.SnapshotDelete":             RPCClosed,
+	"Cluster.SnapshotDiff":               RPCClosed,
+	"Cluster.SnapshotRestore":            RPCClosed,
+	"Cluster.Snapshots":                  RPCClosed,
+	"Cluster.StateSyncAll":               RPCClosed,
+	"Cluster.StateSyncLocal":             RPCTrusted, // Called in broadcast from StateSyncAll() and from StateSyncPeer()
+	"Cluster.StateSyncPeer":              RPCClosed,
+	"Cluster.Status":                     RPCClosed,
+	"Cluster.StatusAll":                  RPCClosed,
+	"Cluster.StatusAllLocal":             RPCClosed,
+	"Cluster.StatusLocal":                RPCClosed,
+	"Cluster.StatusSummary":              RPCClosed,
+	"Cluster.StatusSummaryLocal":         RPCTrusted, // Called in broadcast from StatusSummary()
+	"Cluster.StorageUsage":               RPCClosed,
+	"Cluster.Sync":                       RPCClosed,
+	"Cluster.SyncAll":                    RPCClosed,
+	"Cluster.SyncAllLocal":               RPCTrusted, // Called in broadcast from SyncAll()
+	"Cluster.SyncLocal":                  RPCTrusted, // Called in broadcast from Sync()
+	"Cluster.Unpin":                      RPCClosed,
+	"Cluster.UnpinPath":                  RPCClosed,
+	"Cluster.Version":                    RPCOpen,
+	"Cluster.Versions":                   RPCClosed,
 
 	// PinTracker methods
 	"PinTracker.Recover":    RPCTrusted, // Called in broadcast from Recover()
@@ -43,16 +96,21 @@ var DefaultRPCPolicy = map[string]RPCEndpointType{
 	"PinTracker.Untrack":    RPCClosed,
 
 	// IPFSConnector methods
-	"IPFSConnector.BlockGet":   RPCClosed,
-	"IPFSConnector.BlockPut":   RPCTrusted, // Called from Add()
-	"IPFSConnector.ConfigKey":  RPCClosed,
-	"IPFSConnector.Pin":        RPCClosed,
-	"IPFSConnector.PinLs":      RPCClosed,
-	"IPFSConnector.PinLsCid":   RPCClosed,
-	"IPFSConnector.RepoStat":   RPCTrusted, // Called in broadcast from proxy/repo/stat
-	"IPFSConnector.Resolve":    RPCClosed,
-	"IPFSConnector.SwarmPeers": RPCTrusted, // Called in ConnectGraph
-	"IPFSConnector.Unpin":      RPCClosed,
+	"IPFSConnector.BitswapStat":  RPCClosed,
+	"IPFSConnector.BlockGet":     RPCClosed,
+	"IPFSConnector.BlockPut":     RPCTrusted, // Called from Add()
+	"IPFSConnector.BlockPutDone": RPCTrusted, // Called from Add()
+	"IPFSConnector.ConfigKey":    RPCClosed,
+	"IPFSConnector.DAGBlocks":    RPCTrusted, // Called from StorageUsage()
+	"IPFSConnector.Pin":          RPCClosed,
+	"IPFSConnector.PinLs":        RPCClosed,
+	"IPFSConnector.PinLsCid":     RPCClosed,
+	"IPFSConnector.PinProgress":  RPCClosed,
+	"IPFSConnector.Provide":      RPCTrusted, // Called in broadcast from Provide()
+	"IPFSConnector.RepoStat":     RPCTrusted, // Called in broadcast from proxy/repo/stat
+	"IPFSConnector.Resolve":      RPCClosed,
+	"IPFSConnector.SwarmPeers":   RPCTrusted, // Called in ConnectGraph
+	"IPFSConnector.Unpin":        RPCClosed,
 
 	// Consensus methods
 	"Consensus.AddPeer":  RPCTrusted, // Called by Raft/redirect to leader
@@ -0,0 +1,229 @@
+package ipfscluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	inet "github.com/libp2p/go-libp2p-net"
+	codec "github.com/ugorji/go/codec"
+)
+
+// RPC traffic between peers is split in classes (see DefaultRPCClasses), so
+// that bulk transfers and large status queries on a saturated link do not
+// delay the control traffic, like pings and consensus requests, enough for
+// peers to be considered down. Each class can be given a budget in
+// Config.RPCClassBudgets: how many requests of the class from other peers
+// are served at once, and how many bytes per second its streams send and
+// receive. Classes without a budget, which by default are all of them, are
+// not limited. The class of a stream is found from the header of its
+// request, which names the RPC method.
+
+// RPCClass identifies a class of RPC traffic between peers.
+type RPCClass string
+
+// RPC traffic classes, by decreasing priority.
+const (
+	// RPCClassControl is peer and consensus management traffic.
+	RPCClassControl RPCClass = "control"
+	// RPCClassStatus is status queries and pinset operations. It is the
+	// class of the methods not in DefaultRPCClasses.
+	RPCClassStatus RPCClass = "status"
+	// RPCClassBulk is block and pinset transfers.
+	RPCClassBulk RPCClass = "bulk"
+)
+
+// DefaultRPCClasses associates the RPC methods used between peers which
+// are not in RPCClassStatus to their class.
+var DefaultRPCClasses = map[string]RPCClass{
+	"Cluster.BlockFetch":         RPCClassBulk,
+	"Cluster.ID":                 RPCClassControl,
+	"Cluster.Join":               RPCClassControl,
+	"Cluster.PeerAdd":            RPCClassControl,
+	"Cluster.PeerRemove":         RPCClassControl,
+	"Cluster.Peers":              RPCClassControl,
+	"Cluster.Ping":               RPCClassControl,
+	"Cluster.Pins":               RPCClassBulk,
+	"Cluster.SendInformerMetric": RPCClassControl,
+	"Cluster.Version":            RPCClassControl,
+
+	"IPFSConnector.BlockGet":  RPCClassBulk,
+	"IPFSConnector.BlockPut":  RPCClassBulk,
+	"IPFSConnector.DAGBlocks": RPCClassBulk,
+
+	"Consensus.AddPeer":  RPCClassControl,
+	"Consensus.LogPin":   RPCClassControl,
+	"Consensus.LogUnpin": RPCClassControl,
+	"Consensus.Peers":    RPCClassControl,
+	"Consensus.RmPeer":   RPCClassControl,
+
+	"PeerMonitor.LatestMetrics": RPCClassControl,
+}
+
+func isRPCClassValid(class RPCClass) bool {
+	switch class {
+	case RPCClassControl, RPCClassStatus, RPCClassBulk:
+		return true
+	default:
+		return false
+	}
+}
+
+// rpcClass returns the class of the given RPC method.
+func rpcClass(svc, method string) RPCClass {
+	if class, ok := DefaultRPCClasses[svc+"."+method]; ok {
+		return class
+	}
+	return RPCClassStatus
+}
+
+// readRPCClass reads the header of an RPC request and returns the class of
+// its method.
+func readRPCClass(r io.Reader) (RPCClass, error) {
+	var svcID rpc.ServiceID
+	err := codec.NewDecoder(r, &codec.MsgpackHandle{}).Decode(&svcID)
+	if err != nil {
+		return "", fmt.Errorf("error reading the RPC request header: %s", err)
+	}
+	return rpcClass(svcID.Name, svcID.Method), nil
+}
+
+// RPCClassBudget limits the RPC traffic of a class.
+type RPCClassBudget struct {
+	// MaxConcurrent is how many requests of the class from other
+	// peers are served at once. Others wait. 0 means no limit.
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// Bandwidth is the maximum number of bytes per second that the
+	// RPC streams of the class send, and receive. 0 means no limit.
+	Bandwidth uint64 `json:"bandwidth,omitempty"`
+}
+
+// rpcScheduler enforces the budgets of the RPC traffic classes. A nil
+// rpcScheduler does not limit anything.
+type rpcScheduler struct {
+	ctx   context.Context
+	slots map[RPCClass]chan struct{}
+	send  map[RPCClass]*bandwidthLimiter
+	recv  map[RPCClass]*bandwidthLimiter
+}
+
+func newRPCScheduler(ctx context.Context, budgets map[RPCClass]RPCClassBudget) *rpcScheduler {
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	rs := &rpcScheduler{
+		ctx:   ctx,
+		slots: make(map[RPCClass]chan struct{}),
+		send:  make(map[RPCClass]*bandwidthLimiter),
+		recv:  make(map[RPCClass]*bandwidthLimiter),
+	}
+	for class, b := range budgets {
+		if b.MaxConcurrent > 0 {
+			rs.slots[class] = make(chan struct{}, b.MaxConcurrent)
+		}
+		rs.send[class] = newBandwidthLimiter(b.Bandwidth)
+		rs.recv[class] = newBandwidthLimiter(b.Bandwidth)
+	}
+	return rs
+}
+
+// acquire waits until a request of the given class can be served, and
+// returns a function to call once it is.
+func (rs *rpcScheduler) acquire(class RPCClass) func() {
+	slots, ok := rs.slots[class]
+	if !ok {
+		return func() {}
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }
+	case <-rs.ctx.Done():
+		return func() {}
+	}
+}
+
+// classStream applies the bandwidth budget of its class to the data sent
+// and received on an RPC stream. Until the class is set, nothing is
+// limited. The class is set, and the stream used, by a single goroutine.
+type classStream struct {
+	inet.Stream
+	rs    *rpcScheduler
+	class RPCClass
+}
+
+func newClassStream(s inet.Stream, rs *rpcScheduler) *classStream {
+	return &classStream{
+		Stream: s,
+		rs:     rs,
+	}
+}
+
+func (s *classStream) Read(p []byte) (int, error) {
+	n, err := s.Stream.Read(p)
+	if n > 0 {
+		s.rs.recv[s.class].wait(s.rs.ctx, n)
+	}
+	return n, err
+}
+
+func (s *classStream) Write(p []byte) (int, error) {
+	err := s.rs.send[s.class].wait(s.rs.ctx, len(p))
+	if err != nil {
+		return 0, err
+	}
+	return s.Stream.Write(p)
+}
+
+// requestStream sets the class of an outgoing RPC stream from the header of
+// its request, which is sent with the first write.
+type requestStream struct {
+	inet.Stream
+	cs         *classStream
+	classified bool
+}
+
+func (s *requestStream) Write(p []byte) (int, error) {
+	if !s.classified {
+		s.classified = true
+		class, err := readRPCClass(bytes.NewReader(p))
+		if err == nil {
+			s.cs.class = class
+		}
+	}
+	return s.Stream.Write(p)
+}
+
+// peekedStream returns again the data read to find the class of an
+// incoming RPC stream, before the rest of the stream.
+type peekedStream struct {
+	inet.Stream
+	r io.Reader
+}
+
+func (s *peekedStream) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+// serveClassified finds the class of an incoming RPC stream and handles it
+// once the budget of the class allows it.
+func (rs *rpcScheduler) serveClassified(handler inet.StreamHandler, s inet.Stream, cs *classStream) {
+	var buf bytes.Buffer
+	class, err := readRPCClass(io.TeeReader(s, &buf))
+	ps := &peekedStream{
+		Stream: s,
+		r:      io.MultiReader(&buf, s),
+	}
+	if err != nil {
+		// the RPC server reports the error
+		handler(ps)
+		return
+	}
+
+	cs.class = class
+	release := rs.acquire(class)
+	defer release()
+	handler(ps)
+}
@@ -0,0 +1,92 @@
+package ipfscluster
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	inet "github.com/libp2p/go-libp2p-net"
+	codec "github.com/ugorji/go/codec"
+)
+
+func rpcRequestHeader(t *testing.T, svc, method string) []byte {
+	var buf bytes.Buffer
+	err := codec.NewEncoder(&buf, &codec.MsgpackHandle{}).Encode(rpc.ServiceID{Name: svc, Method: method})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadRPCClass(t *testing.T) {
+	testcases := []struct {
+		svc, method string
+		class       RPCClass
+	}{
+		{"Consensus", "LogPin", RPCClassControl},
+		{"Cluster", "StatusAllLocal", RPCClassStatus},
+		{"IPFSConnector", "BlockPut", RPCClassBulk},
+	}
+	for _, tc := range testcases {
+		class, err := readRPCClass(bytes.NewReader(rpcRequestHeader(t, tc.svc, tc.method)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if class != tc.class {
+			t.Errorf("%s.%s: expected class %s, got %s", tc.svc, tc.method, tc.class, class)
+		}
+	}
+
+	_, err := readRPCClass(bytes.NewReader([]byte("not msgpack")))
+	if err == nil {
+		t.Error("expected an error reading a bad header")
+	}
+}
+
+func TestServeClassified(t *testing.T) {
+	ctx := context.Background()
+	rs := newRPCScheduler(ctx, map[RPCClass]RPCClassBudget{
+		RPCClassBulk: {MaxConcurrent: 1},
+	})
+
+	request := append(rpcRequestHeader(t, "IPFSConnector", "BlockPut"), []byte("arguments")...)
+	cs := newClassStream(&pipeStream{r: bytes.NewReader(request)}, rs)
+
+	var got []byte
+	handler := func(s inet.Stream) {
+		got, _ = ioutil.ReadAll(s)
+	}
+	rs.serveClassified(handler, cs, cs)
+	if !bytes.Equal(got, request) {
+		t.Error("the handler should read the whole request")
+	}
+	if cs.class != RPCClassBulk {
+		t.Errorf("expected the stream to be in the bulk class: %s", cs.class)
+	}
+
+	// The only bulk slot is taken: requests wait.
+	release := rs.acquire(RPCClassBulk)
+	done := make(chan struct{})
+	go func() {
+		cs := newClassStream(&pipeStream{r: bytes.NewReader(request)}, rs)
+		rs.serveClassified(func(inet.Stream) {}, cs, cs)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("the request should wait for a bulk slot")
+	case <-time.After(100 * time.Millisecond):
+	}
+	// Other classes are not limited.
+	release2 := rs.acquire(RPCClassControl)
+	release2()
+	release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the request should be served once the slot is free")
+	}
+}
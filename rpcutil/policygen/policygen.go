@@ -24,22 +24,38 @@ func rpcTypeStr(t cluster.RPCEndpointType) string {
 }
 
 var comments = map[string]string{
-	"Cluster.PeerAdd":          "Used by Join()",
-	"Cluster.Peers":            "Used by ConnectGraph()",
-	"Cluster.Pins":             "Used in stateless tracker, ipfsproxy, restapi",
-	"Cluster.SyncAllLocal":     "Called in broadcast from SyncAll()",
-	"Cluster.SyncLocal":        "Called in broadcast from Sync()",
-	"PinTracker.Recover":       "Called in broadcast from Recover()",
-	"PinTracker.RecoverAll":    "Broadcast in RecoverAll unimplemented",
-	"Pintracker.Status":        "Called in broadcast from Status()",
-	"Pintracker.StatusAll":     "Called in broadcast from StatusAll()",
-	"IPFSConnector.BlockPut":   "Called from Add()",
-	"IPFSConnector.RepoStat":   "Called in broadcast from proxy/repo/stat",
-	"IPFSConnector.SwarmPeers": "Called in ConnectGraph",
-	"Consensus.AddPeer":        "Called by Raft/redirect to leader",
-	"Consensus.LogPin":         "Called by Raft/redirect to leader",
-	"Consensus.LogUnpin":       "Called by Raft/redirect to leader",
-	"Consensus.RmPeer":         "Called by Raft/redirect to leader",
+	"Cluster.AllocationExplanationLocal": "Called in broadcast from AllocationExplanation()",
+	"Cluster.BlockFetch":                 "Called from reallocate()",
+	"Cluster.IPFSDaemonInfoLocal":        "Called in broadcast from IPFSDrift()",
+	"Cluster.LastStateSyncLocal":         "Called in broadcast from LastStateSyncAll()",
+	"Cluster.PeerAdd":                    "Used by Join()",
+	"Cluster.PeerLatencies":              "Used by ConnectGraph()",
+	"Cluster.Peers":                      "Used by ConnectGraph()",
+	"Cluster.Ping":                       "Called from PingPeer()",
+	"Cluster.PinnedBytes":                "Used by the pinnedbytes informer",
+	"Cluster.Pins":                       "Used in stateless tracker, ipfsproxy, restapi",
+	"Cluster.RecordAccess":               "Used by ipfsproxy",
+	"Cluster.RepoGCLocal":                "Called in broadcast from RepoGC()",
+	"Cluster.RuntimeStatsLocal":          "Called in broadcast from RuntimeStatsAll()",
+	"Cluster.SetAllocatableLocal":        "Called from SetAllocatable()",
+	"Cluster.SetTrackerPausedLocal":      "Called from SetTrackerPaused()",
+	"Cluster.StateSyncLocal":             "Called in broadcast from StateSyncAll() and from StateSyncPeer()",
+	"Cluster.StatusSummaryLocal":         "Called in broadcast from StatusSummary()",
+	"Cluster.SyncAllLocal":               "Called in broadcast from SyncAll()",
+	"Cluster.SyncLocal":                  "Called in broadcast from Sync()",
+	"PinTracker.Recover":                 "Called in broadcast from Recover()",
+	"Pintracker.Status":                  "Called in broadcast from Status()",
+	"Pintracker.StatusAll":               "Called in broadcast from StatusAll()",
+	"IPFSConnector.BlockPut":             "Called from Add()",
+	"IPFSConnector.BlockPutDone":         "Called from Add()",
+	"IPFSConnector.DAGBlocks":            "Called from StorageUsage()",
+	"IPFSConnector.Provide":              "Called in broadcast from Provide()",
+	"IPFSConnector.RepoStat":             "Called in broadcast from proxy/repo/stat",
+	"IPFSConnector.SwarmPeers":           "Called in ConnectGraph",
+	"Consensus.AddPeer":                  "Called by Raft/redirect to leader",
+	"Consensus.LogPin":                   "Called by Raft/redirect to leader",
+	"Consensus.LogUnpin":                 "Called by Raft/redirect to leader",
+	"Consensus.RmPeer":                   "Called by Raft/redirect to leader",
 }
 
 func main() {
@@ -6,10 +6,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ipfs/ipfs-cluster/api"
 
+	rpc "github.com/libp2p/go-libp2p-gorpc"
 	peer "github.com/libp2p/go-libp2p-peer"
 )
 
@@ -55,6 +58,55 @@ func MultiCancel(cancels []context.CancelFunc) {
 	}
 }
 
+// LimitedMultiCall works like gorpc.Client.MultiCall(), but it only
+// waits on at most "limit" destinations at the same time, and every
+// call is given "timeout" to complete from the moment it is started.
+// A limit <= 0 means that all destinations are contacted at once, and a
+// timeout <= 0 that the calls are only limited by their context.
+func LimitedMultiCall(
+	ctxs []context.Context,
+	client *rpc.Client,
+	limit int,
+	timeout time.Duration,
+	dests []peer.ID,
+	svcName, svcMethod string,
+	args interface{},
+	replies []interface{},
+) []error {
+
+	if limit <= 0 {
+		limit = len(dests)
+	}
+
+	errs := make([]error, len(dests), len(dests))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := range dests {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ctx := ctxs[i]
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			errs[i] = client.CallContext(
+				ctx,
+				dests[i],
+				svcName,
+				svcMethod,
+				args,
+				replies[i],
+			)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
 // The copy functions below are used in calls to Cluste.multiRPC()
 
 // CopyPIDsToIfaces converts a peer.ID slice to an empty interface
@@ -68,6 +120,66 @@ func CopyPIDsToIfaces(in []peer.ID) []interface{} {
 	return ifaces
 }
 
+// CopyStateSyncsToIfaces converts an api.StateSync slice to an empty
+// interface slice using pointers to each elements of the original slice.
+// Useful to handle gorpc.MultiCall() replies.
+func CopyStateSyncsToIfaces(in []*api.StateSync) []interface{} {
+	ifaces := make([]interface{}, len(in), len(in))
+	for i := range in {
+		in[i] = &api.StateSync{}
+		ifaces[i] = in[i]
+	}
+	return ifaces
+}
+
+// CopyRepoGCsToIfaces converts an api.RepoGC slice to an empty interface
+// slice using pointers to each elements of the original slice. Useful to
+// handle gorpc.MultiCall() replies.
+func CopyRepoGCsToIfaces(in []*api.RepoGC) []interface{} {
+	ifaces := make([]interface{}, len(in), len(in))
+	for i := range in {
+		in[i] = &api.RepoGC{}
+		ifaces[i] = in[i]
+	}
+	return ifaces
+}
+
+// CopyIPFSDaemonInfosToIfaces converts an api.IPFSDaemonInfo slice to an
+// empty interface slice using pointers to each elements of the original
+// slice. Useful to handle gorpc.MultiCall() replies.
+func CopyIPFSDaemonInfosToIfaces(in []*api.IPFSDaemonInfo) []interface{} {
+	ifaces := make([]interface{}, len(in), len(in))
+	for i := range in {
+		in[i] = &api.IPFSDaemonInfo{}
+		ifaces[i] = in[i]
+	}
+	return ifaces
+}
+
+// CopyRuntimeStatsToIfaces converts an api.RuntimeStats slice to an empty
+// interface slice using pointers to each elements of the original slice.
+// Useful to handle gorpc.MultiCall() replies.
+func CopyRuntimeStatsToIfaces(in []*api.RuntimeStats) []interface{} {
+	ifaces := make([]interface{}, len(in), len(in))
+	for i := range in {
+		in[i] = &api.RuntimeStats{}
+		ifaces[i] = in[i]
+	}
+	return ifaces
+}
+
+// CopyPeerStatusSummariesToIfaces converts an api.PeerStatusSummary slice
+// to an empty interface slice using pointers to each elements of the
+// original slice. Useful to handle gorpc.MultiCall() replies.
+func CopyPeerStatusSummariesToIfaces(in []*api.PeerStatusSummary) []interface{} {
+	ifaces := make([]interface{}, len(in), len(in))
+	for i := range in {
+		in[i] = &api.PeerStatusSummary{}
+		ifaces[i] = in[i]
+	}
+	return ifaces
+}
+
 // CopyIDsToIfaces converts an api.ID slice to an empty interface
 // slice using pointers to each elements of the original slice.
 // Useful to handle gorpc.MultiCall() replies.
@@ -114,6 +226,18 @@ func CopyPinInfoSliceToIfaces(in [][]*api.PinInfo) []interface{} {
 	return ifaces
 }
 
+// CopyAllocationExplanationsToIfaces converts an api.AllocationExplanation
+// slice to an empty interface slice using pointers to each elements of
+// the original slice. Useful to handle gorpc.MultiCall() replies.
+func CopyAllocationExplanationsToIfaces(in []*api.AllocationExplanation) []interface{} {
+	ifaces := make([]interface{}, len(in), len(in))
+	for i := range in {
+		in[i] = &api.AllocationExplanation{}
+		ifaces[i] = in[i]
+	}
+	return ifaces
+}
+
 // CopyEmptyStructToIfaces converts an empty struct slice to an empty interface
 // slice using pointers to each elements of the original slice.
 // Useful to handle gorpc.MultiCall() replies.
@@ -133,6 +257,64 @@ func RPCDiscardReplies(n int) []interface{} {
 	return CopyEmptyStructToIfaces(replies)
 }
 
+// Outcome classifies the error returned by an RPC request.
+func Outcome(err error) api.RPCOutcome {
+	switch {
+	case err == nil:
+		return api.RPCSuccess
+	case err == context.DeadlineExceeded,
+		strings.Contains(err.Error(), context.DeadlineExceeded.Error()):
+		return api.RPCTimeout
+	default:
+		return api.RPCError
+	}
+}
+
+// PeerResults returns the result for every destination of a
+// gorpc.MultiCall() given the errors it returned.
+func PeerResults(dests []peer.ID, errs []error) []*api.PeerResult {
+	results := make([]*api.PeerResult, len(dests), len(dests))
+	for i, p := range dests {
+		results[i] = &api.PeerResult{
+			Peer:    p,
+			Outcome: Outcome(errs[i]),
+		}
+		if errs[i] != nil {
+			results[i].Error = errs[i].Error()
+		}
+	}
+	return results
+}
+
+// MultiError is returned when some of the destinations of a
+// gorpc.MultiCall() failed. It carries the result for every destination,
+// including those that succeeded.
+type MultiError struct {
+	Results []*api.PeerResult
+}
+
+// Error returns the errors of the failed destinations, one per line.
+func (merr *MultiError) Error() string {
+	var msgs []string
+	for _, r := range merr.Results {
+		if r.Outcome != api.RPCSuccess {
+			msgs = append(msgs, fmt.Sprintf("%s: %s: %s", r.Peer.Pretty(), r.Outcome, r.Error))
+		}
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// CheckPeerErrs returns nil if all the errors from a gorpc.MultiCall() to
+// the given destinations are nil, and a *MultiError otherwise.
+func CheckPeerErrs(dests []peer.ID, errs []error) error {
+	for _, e := range errs {
+		if e != nil {
+			return &MultiError{Results: PeerResults(dests, errs)}
+		}
+	}
+	return nil
+}
+
 // CheckErrs returns nil if all the errors in a slice are nil, otherwise
 // it returns a single error formed by joining the error messages existing
 // in the slice with a line-break.
@@ -0,0 +1,173 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/cron"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"go.opencensus.io/trace"
+)
+
+// Pins can be deferred until a given time (PinOptions.ScheduleAt), so that
+// heavy replication work happens off-peak, and pins of mutable paths can be
+// refreshed on a cron schedule (PinOptions.Recurrence). Deferred pins are
+// kept in the shared state without allocations, and are tracked as remote
+// by every peer, until the scheduler allocates them. Only the coordinator
+// (see isCoordinator) runs the scheduler.
+
+// pinSchedulerInterval specifies how often scheduled pins are checked.
+var pinSchedulerInterval = 30 * time.Second
+
+// setupSchedule checks the scheduling options of a pin. Pins scheduled in
+// the past are pinned right away.
+func (c *Cluster) setupSchedule(pin *api.Pin) error {
+	if !pin.IsScheduled() && pin.Recurrence == "" {
+		return nil
+	}
+
+	if pin.Type != api.DataType {
+		return errors.New("only regular pins can be scheduled")
+	}
+
+	// The scheduler modifies the pins (and resolves the CIDs of
+	// recurring ones) when they run, so they would not be signed.
+	if c.pinVerifier != nil && c.pinVerifier.require {
+		return errors.New("scheduled pins cannot be used when signed pins are required")
+	}
+
+	if pin.IsScheduled() && !pin.ScheduleAt.After(time.Now()) {
+		pin.ScheduleAt = time.Time{}
+	}
+
+	if pin.Recurrence == "" {
+		return nil
+	}
+	if pin.RecurrencePath == "" {
+		return errors.New("recurring pins must be pinned by path")
+	}
+	_, err := cron.Parse(pin.Recurrence)
+	return err
+}
+
+// pinScheduler runs the scheduled pins every pinSchedulerInterval.
+func (c *Cluster) pinScheduler() {
+	ticker := time.NewTicker(pinSchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.runScheduledPins(c.ctx, time.Now())
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// runScheduledPins runs the deferred pins which are due and the recurring
+// pins whose schedule fired since they last ran.
+func (c *Cluster) runScheduledPins(ctx context.Context, now time.Time) {
+	ctx, span := trace.StartSpan(ctx, "cluster/runScheduledPins")
+	defer span.End()
+
+	if !c.isCoordinator(ctx) {
+		return
+	}
+
+	pins, err := c.Pins(ctx)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	for _, pin := range pins {
+		if pin.Type != api.DataType || pin.IsScheduledForRemoval() {
+			continue
+		}
+		switch {
+		case pin.IsScheduled():
+			if pin.ScheduleAt.After(now) {
+				continue
+			}
+			c.runDeferredPin(ctx, pin, now)
+		case pin.Recurrence != "":
+			c.runRecurringPin(ctx, pin, now)
+		}
+	}
+}
+
+// runDeferredPin allocates a deferred pin.
+func (c *Cluster) runDeferredPin(ctx context.Context, pin *api.Pin, now time.Time) {
+	p := *pin
+	p.ScheduleAt = time.Time{}
+	p.LastRun = now
+	p.Provenance = &api.PinProvenance{Source: api.ProvenanceScheduler}
+
+	logger.Infof("running pin of %s scheduled at %s", p.Cid, pin.ScheduleAt)
+	_, _, err := c.pin(ctx, &p, []peer.ID{}, p.UserAllocations)
+	if err != nil {
+		logger.Errorf("error running scheduled pin of %s: %s", p.Cid, err)
+	}
+}
+
+// runRecurringPin resolves the path of a recurring pin when its schedule
+// has fired since it last ran (or was created). When the path points to a
+// new CID, the new CID is pinned with the same options and the old one is
+// unpinned. Occurrences which fail are skipped.
+func (c *Cluster) runRecurringPin(ctx context.Context, pin *api.Pin, now time.Time) {
+	sched, err := cron.Parse(pin.Recurrence)
+	if err != nil {
+		logger.Errorf("bad recurrence for %s: %s", pin.Cid, err)
+		return
+	}
+
+	last := pin.LastRun
+	if last.IsZero() {
+		last = pin.CreatedAt
+	}
+	next := sched.Next(last)
+	if next.IsZero() || next.After(now) {
+		return
+	}
+
+	p := *pin
+	p.LastRun = now
+	p.Provenance = &api.PinProvenance{Source: api.ProvenanceScheduler}
+
+	ci, err := c.ipfs.Resolve(ctx, pin.RecurrencePath)
+	if err != nil {
+		logger.Errorf("error resolving %s for recurring pin %s: %s", pin.RecurrencePath, pin.Cid, err)
+		// Record the run so that it is retried on the next occurrence.
+		if err := c.logPin(ctx, &p); err != nil {
+			logger.Error(err)
+		}
+		return
+	}
+
+	if ci.Equals(pin.Cid) {
+		logger.Debugf("recurring pin %s: %s unchanged", pin.Cid, pin.RecurrencePath)
+		if err := c.logPin(ctx, &p); err != nil {
+			logger.Error(err)
+		}
+		return
+	}
+
+	logger.Infof("recurring pin %s: %s now points to %s", pin.Cid, pin.RecurrencePath, ci)
+	p.Cid = ci
+	p.Allocations = nil
+	p.Size = 0
+	_, _, err = c.pin(ctx, &p, []peer.ID{}, p.UserAllocations)
+	if err != nil {
+		logger.Errorf("error pinning %s for recurring pin %s: %s", ci, pin.Cid, err)
+		return
+	}
+	_, err = c.unpin(ctx, pin.Cid)
+	if err != nil {
+		logger.Errorf("error unpinning %s after it was replaced by %s: %s", pin.Cid, ci, err)
+	}
+}
@@ -0,0 +1,80 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// Shards are usually allocated to a single peer. Repinning relies on the
+// remaining allocations of a pin, so when the only peer holding a shard is
+// lost its shard would stay allocated to it and the sharded pin would have
+// to be added again. Instead, the shards allocated only to the lost peer are
+// re-allocated to other peers, which fetch their blocks from any IPFS daemon
+// still providing them (the daemon of the lost peer when only its cluster
+// peer is down, or any daemon which fetched the content). The rest of the
+// shards and the sharded pin itself are left untouched. Shards which cannot
+// be fetched from anywhere appear as missing in the status of their sharded
+// pin (and stay allocated to the lost peer when ReallocationVerification is
+// enabled).
+
+// repairShardsFromPeer repairs the shards lost with the given peer. Only the
+// coordinator does it.
+func (c *Cluster) repairShardsFromPeer(ctx context.Context, lost peer.ID, pins []*api.Pin) {
+	ctx, span := trace.StartSpan(ctx, "cluster/repairShardsFromPeer")
+	defer span.End()
+
+	if !c.isCoordinator(ctx) {
+		return
+	}
+
+	if c.config.DisableRepinning {
+		logger.Warningf("repinning is disabled. Will not repair the shards held by %s", lost.Pretty())
+		return
+	}
+
+	rep := c.repairShards(ctx, lost, pins)
+	if len(rep.Pins) == 0 && len(rep.Errors) == 0 {
+		return
+	}
+	logger.Infof(
+		"repairing the shards held by %s: %d re-allocated, %d errors",
+		lost.Pretty(),
+		len(rep.Pins),
+		len(rep.Errors),
+	)
+}
+
+// repairShards re-allocates the shard pins which are only allocated to the
+// given peer. Shards with other allocations are repinned by those peers.
+func (c *Cluster) repairShards(ctx context.Context, lost peer.ID, pins []*api.Pin) *api.PeerMigration {
+	ctx, span := trace.StartSpan(ctx, "cluster/repairShards")
+	defer span.End()
+
+	rep := &api.PeerMigration{
+		Peer: lost,
+		Pins: []*api.Pin{},
+	}
+	for _, pin := range pins {
+		if pin.Type != api.ShardType || len(pin.Allocations) != 1 || pin.Allocations[0] != lost {
+			continue
+		}
+		pin.Provenance = &api.PinProvenance{Source: api.ProvenanceReallocation}
+		_, ok, err := c.reallocate(ctx, pin, lost)
+		if err != nil {
+			logger.Errorf("error repairing shard %s held by %s: %s", pin.Cid, lost.Pretty(), err)
+			rep.Errors = append(rep.Errors, fmt.Sprintf("%s: %s", pin.Cid, err))
+			continue
+		}
+		if ok {
+			logger.Infof("shard %s held by %s re-allocated to %s", pin.Cid, lost.Pretty(), pin.Allocations)
+			rep.Pins = append(rep.Pins, pin)
+		}
+	}
+	return rep
+}
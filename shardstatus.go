@@ -0,0 +1,89 @@
+package ipfscluster
+
+import (
+	"context"
+	"sort"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// Peers report the root of sharded pins as "sharded", which says nothing
+// about whether the content is available. The status of a sharded pin
+// therefore includes the status of its shards: how many are pinned, by
+// which peers, and which ones no peer has pinned.
+
+// attachShardsStatus sets the status of the shards when the given
+// GlobalPinInfo belongs to a sharded pin. Errors obtaining it are logged.
+func (c *Cluster) attachShardsStatus(ctx context.Context, gpi *api.GlobalPinInfo) {
+	pin, err := c.PinGet(ctx, gpi.Cid)
+	if err != nil || pin.Type != api.MetaType {
+		return
+	}
+	shards, err := c.shardsStatus(ctx, pin)
+	if err != nil {
+		logger.Errorf("error obtaining the status of the shards of %s: %s", gpi.Cid, err)
+		return
+	}
+	gpi.Shards = shards
+}
+
+// shardsStatus asks every peer for the status of each of the shards of the
+// given meta pin.
+func (c *Cluster) shardsStatus(ctx context.Context, metaPin *api.Pin) (*api.ShardsStatus, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/shardsStatus")
+	defer span.End()
+
+	shards, err := c.shardsFromMetaPin(ctx, metaPin)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &api.ShardsStatus{
+		Total:   len(shards),
+		Missing: []cid.Cid{},
+		Shards:  make([]*api.ShardStatus, 0, len(shards)),
+	}
+	for _, s := range shards {
+		shardStatus, err := c.shardStatus(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if len(shardStatus.Pinned) > 0 {
+			status.Pinned++
+		} else {
+			status.Missing = append(status.Missing, s)
+		}
+		status.Shards = append(status.Shards, shardStatus)
+	}
+	return status, nil
+}
+
+func (c *Cluster) shardStatus(ctx context.Context, shard cid.Cid) (*api.ShardStatus, error) {
+	shardStatus := &api.ShardStatus{
+		Cid:    shard,
+		Pinned: []peer.ID{},
+	}
+	pin, err := c.PinGet(ctx, shard)
+	if err == nil {
+		shardStatus.Allocations = pin.Allocations
+	}
+
+	gpi, err := c.globalPinInfoCid(ctx, "PinTracker", "Status", shard)
+	if err != nil {
+		return nil, err
+	}
+	for _, pinfo := range gpi.PeerMap {
+		if pinfo.Status == api.TrackerStatusPinned {
+			shardStatus.Pinned = append(shardStatus.Pinned, pinfo.Peer)
+		}
+	}
+	sort.Slice(shardStatus.Pinned, func(i, j int) bool {
+		return shardStatus.Pinned[i] < shardStatus.Pinned[j]
+	})
+	return shardStatus, nil
+}
@@ -33,6 +33,16 @@ test_expect_success IPFS,CLUSTER "export + cleanup + import == noop" '
     [ 1 -eq "$(ipfs-cluster-ctl --enc=json status | jq ". | length")" ]
 '
 
+test_expect_success IPFS,CLUSTER "state cleanup --stale keeps the pinset" '
+    cid=`docker exec ipfs sh -c "echo test_54_stale | ipfs add -q"` &&
+    ipfs-cluster-ctl pin add "$cid" && sleep 5 &&
+    cluster_kill && sleep 5 &&
+    ipfs-cluster-service --debug --config "test-config" state cleanup --stale -f &&
+    cluster_start && sleep 5 &&
+    ipfs-cluster-ctl pin ls "$cid" | grep -q "$cid" &&
+    ipfs-cluster-ctl status "$cid" | grep -q -i "PINNED"
+'
+
 test_clean_ipfs
 test_clean_cluster
 
@@ -0,0 +1,173 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/state"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// In collaborative clusters, followers may want to make sure that changes
+// to the shared state originate from the cluster operator. With
+// RequireSignedPins, pin, unpin and restore requests must carry a signature
+// (see api.SignPin) made by one of the AuthorizedKeys. Pin signatures cover
+// all the options kept in the shared state, so a pin is stored with its
+// signature and every peer verifies it again before tracking it.
+//
+// Signatures also cover the time they were made. Requests are only accepted
+// within SignedPinMaxAge of that time, once, and when they were signed
+// after the pin currently in the shared state, so that captured requests
+// cannot be replayed to revert later changes.
+
+var errPathSignedPins = errors.New("requests using IPFS paths cannot be verified: sign a request for the resolved CID instead")
+
+// pinVerifier checks the signatures of pin requests.
+type pinVerifier struct {
+	require    bool
+	authorized map[peer.ID]struct{}
+	maxAge     time.Duration
+
+	// The replication factors set in pins which leave them to 0.
+	rplMin int
+	rplMax int
+
+	seenMux sync.Mutex
+	// signatures of the accepted requests and their time.
+	seen map[string]time.Time
+}
+
+func newPinVerifier(cfg *Config) *pinVerifier {
+	v := &pinVerifier{
+		require:    cfg.RequireSignedPins,
+		authorized: make(map[peer.ID]struct{}),
+		maxAge:     cfg.SignedPinMaxAge,
+		rplMin:     cfg.ReplicationFactorMin,
+		rplMax:     cfg.ReplicationFactorMax,
+		seen:       make(map[string]time.Time),
+	}
+	for _, p := range cfg.AuthorizedKeys {
+		v.authorized[p] = struct{}{}
+	}
+	return v
+}
+
+// verify returns an error when signed pins are required and the given
+// operation on the pin is not signed by an authorized key. It only checks
+// the validity of the signature: requests must be checked with
+// verifyRequest. It is safe to call on a nil pinVerifier.
+func (v *pinVerifier) verify(op string, pin *api.Pin) error {
+	if v == nil || !v.require {
+		return nil
+	}
+
+	// Shards and cluster DAGs are covered by the signature of their
+	// meta pin.
+	if pin.Type != api.DataType && pin.Type != api.MetaType {
+		return nil
+	}
+
+	if pin.Signature == nil {
+		return fmt.Errorf("%s %s: request not signed", op, pin.Cid)
+	}
+	signer, err := v.signer(op, pin)
+	if err != nil {
+		return fmt.Errorf("%s %s: %s", op, pin.Cid, err)
+	}
+	if _, ok := v.authorized[signer]; !ok {
+		return fmt.Errorf("%s %s: %s is not an authorized key", op, pin.Cid, signer.Pretty())
+	}
+	return nil
+}
+
+// signer verifies the signature of the pin and returns the signing peer.
+// The replication factors left to 0 in pin requests are set to the default
+// ones before the pins are committed, so pins using the defaults are also
+// verified with 0 in their place.
+func (v *pinVerifier) signer(op string, pin *api.Pin) (peer.ID, error) {
+	signer, err := pin.Signature.Verify(op, pin)
+	if err == nil || op != api.SignedPinOp {
+		return signer, err
+	}
+
+	for _, zero := range []struct{ min, max bool }{{true, false}, {false, true}, {true, true}} {
+		if zero.min && pin.ReplicationFactorMin != v.rplMin ||
+			zero.max && pin.ReplicationFactorMax != v.rplMax {
+			continue
+		}
+		p := *pin
+		if zero.min {
+			p.ReplicationFactorMin = 0
+		}
+		if zero.max {
+			p.ReplicationFactorMax = 0
+		}
+		if signer, err := pin.Signature.Verify(op, &p); err == nil {
+			return signer, nil
+		}
+	}
+	return "", err
+}
+
+// verifyRequest verifies a pin, unpin or restore request like verify and
+// additionally refuses requests signed too long ago (or too far ahead),
+// signed before the given existing pin (nil if none) or seen already.
+func (v *pinVerifier) verifyRequest(op string, pin, existing *api.Pin) error {
+	if err := v.verify(op, pin); err != nil {
+		return err
+	}
+	if v == nil || !v.require || pin.Signature == nil {
+		return nil
+	}
+
+	now := time.Now()
+	ts := pin.Signature.Timestamp
+	if now.Sub(ts) > v.maxAge || ts.Sub(now) > v.maxAge {
+		return fmt.Errorf("%s %s: the signature time (%s) is too far from the current time", op, pin.Cid, ts)
+	}
+	if existing != nil && existing.Signature != nil && !ts.After(existing.Signature.Timestamp) {
+		return fmt.Errorf("%s %s: the request was signed before the current pin", op, pin.Cid)
+	}
+
+	v.seenMux.Lock()
+	defer v.seenMux.Unlock()
+	key := string(pin.Signature.Signature)
+	if _, ok := v.seen[key]; ok {
+		return fmt.Errorf("%s %s: the request has been submitted already", op, pin.Cid)
+	}
+	// Forget the requests which would be refused as too old anyways.
+	for k, t := range v.seen {
+		if now.Sub(t) > v.maxAge {
+			delete(v.seen, k)
+		}
+	}
+	v.seen[key] = ts
+	return nil
+}
+
+// verifyPaths returns an error when signed pins are required, as requests
+// using IPFS paths cannot be verified.
+func (v *pinVerifier) verifyPaths() error {
+	if v == nil || !v.require {
+		return nil
+	}
+	return errPathSignedPins
+}
+
+// verifySignedRequest verifies a request for the given operation on a pin
+// against the pin in the shared state when signed pins are required.
+func (c *Cluster) verifySignedRequest(ctx context.Context, op string, pin *api.Pin) error {
+	if c.pinVerifier == nil || !c.pinVerifier.require {
+		return nil
+	}
+	existing, err := c.PinGet(ctx, pin.Cid)
+	if err != nil && err != state.ErrNotFound {
+		return err
+	}
+	return c.pinVerifier.verifyRequest(op, pin, existing)
+}
@@ -0,0 +1,261 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// simulationMetricTTL is set on the snapshot metrics so that they do not
+// expire during the simulation.
+var simulationMetricTTL = time.Hour
+
+// SimulationOptions configures an allocation simulation (see
+// SimulateAllocations).
+type SimulationOptions struct {
+	// Informer selects the metrics given to the allocator. When it
+	// implements Reserver, the metrics are adjusted as pins are
+	// allocated during the simulation.
+	Informer Informer
+	// Allocator is the PinAllocator being evaluated.
+	Allocator PinAllocator
+	// ReplicationFactorMin and ReplicationFactorMax replace the
+	// replication factors of regular pins when they are not 0.
+	ReplicationFactorMin int
+	ReplicationFactorMax int
+	// Reallocate discards the current allocations of all pins, so that
+	// the whole pinset is allocated from scratch. Otherwise, the current
+	// allocations are kept whenever they are still valid, as a running
+	// cluster would do.
+	Reallocate bool
+}
+
+// SimulationPeer summarizes how many pins are allocated to a peer before
+// and after the simulation.
+type SimulationPeer struct {
+	Peer    peer.ID `json:"peer"`
+	Metric  string  `json:"metric,omitempty"`
+	Before  int     `json:"before"`
+	After   int     `json:"after"`
+	Added   int     `json:"added"`
+	Removed int     `json:"removed"`
+}
+
+// SimulationFailure is a pin which could not be allocated during the
+// simulation.
+type SimulationFailure struct {
+	Cid   cid.Cid `json:"cid"`
+	Error string  `json:"error"`
+}
+
+// SimulationReport is the result of an allocation simulation.
+type SimulationReport struct {
+	Informer  string               `json:"informer"`
+	Allocator string               `json:"allocator"`
+	Pins      int                  `json:"pins"`
+	Changed   int                  `json:"changed"`
+	Failed    []*SimulationFailure `json:"failed,omitempty"`
+	Peers     []*SimulationPeer    `json:"peers"`
+}
+
+// SimulateAllocations computes how the given pins would be allocated with
+// the given options, using a snapshot of the metrics of the cluster peers
+// (as returned by PeerMonitor.LatestMetrics), and reports the resulting
+// distribution of pins per peer. It works offline: nothing is pinned and
+// the allocator is the only component contacted.
+//
+// Pins are allocated in order with the same logic used by cluster peers.
+// Only the snapshot metrics named after the informer are used, along with
+// the metrics announcing peers which are not allocatable. Expired metrics
+// are used too, as snapshots are usually older than their TTL.
+func SimulateAllocations(ctx context.Context, pins []*api.Pin, metrics []*api.Metric, opts SimulationOptions) (*SimulationReport, error) {
+	if opts.Informer == nil || opts.Allocator == nil {
+		return nil, errors.New("an informer and an allocator are needed")
+	}
+
+	// Keep the last valid metric of every peer.
+	base := make(map[peer.ID]*api.Metric)
+	var notAllocatable []peer.ID
+	for _, m := range metrics {
+		if !m.Valid {
+			continue
+		}
+		switch m.Name {
+		case opts.Informer.Name():
+			// Allocators discard expired metrics.
+			fresh := *m
+			fresh.SetTTL(simulationMetricTTL)
+			base[m.Peer] = &fresh
+		case allocatableMetricName:
+			if allocatable, err := strconv.ParseBool(m.Value); err == nil && !allocatable {
+				notAllocatable = append(notAllocatable, m.Peer)
+			}
+		}
+	}
+	if len(base) == 0 {
+		return nil, errors.New("no valid " + opts.Informer.Name() + " metrics found")
+	}
+
+	var everywhere []peer.ID
+	for p := range base {
+		everywhere = append(everywhere, p)
+	}
+
+	report := &SimulationReport{
+		Informer:  opts.Informer.Name(),
+		Allocator: opts.Allocator.Name(),
+	}
+	peers := make(map[peer.ID]*SimulationPeer)
+	getPeer := func(p peer.ID) *SimulationPeer {
+		sp, ok := peers[p]
+		if !ok {
+			sp = &SimulationPeer{Peer: p}
+			if m, ok := base[p]; ok {
+				sp.Metric = m.Value
+			}
+			peers[p] = sp
+		}
+		return sp
+	}
+	for p := range base {
+		getPeer(p)
+	}
+
+	reserver, _ := opts.Informer.(Reserver)
+	reserved := make(map[peer.ID]int)
+
+	for _, pin := range pins {
+		if pin.Type == api.MetaType {
+			continue
+		}
+		report.Pins++
+
+		before := pin.Allocations
+		if allocatesEverywhere(pin) {
+			before = everywhere
+		}
+
+		simPin := *pin
+		if simPin.Type == api.DataType {
+			if opts.ReplicationFactorMin != 0 {
+				simPin.ReplicationFactorMin = opts.ReplicationFactorMin
+			}
+			if opts.ReplicationFactorMax != 0 {
+				simPin.ReplicationFactorMax = opts.ReplicationFactorMax
+			}
+		}
+		if opts.Reallocate {
+			simPin.Allocations = nil
+		}
+
+		after, err := simulateAllocation(ctx, opts.Allocator, &simPin, base, reserver, reserved, notAllocatable)
+		if err != nil {
+			report.Failed = append(report.Failed, &SimulationFailure{
+				Cid:   pin.Cid,
+				Error: err.Error(),
+			})
+			after = nil
+		}
+		if allocatesEverywhere(&simPin) {
+			after = everywhere
+		}
+
+		for _, p := range before {
+			sp := getPeer(p)
+			sp.Before++
+			if !containsPeer(after, p) {
+				sp.Removed++
+			}
+		}
+		changed := len(before) != len(after)
+		for _, p := range after {
+			sp := getPeer(p)
+			sp.After++
+			if !containsPeer(before, p) {
+				sp.Added++
+				changed = true
+				reserved[p]++
+			}
+		}
+		if changed {
+			report.Changed++
+		}
+	}
+
+	for _, sp := range peers {
+		report.Peers = append(report.Peers, sp)
+	}
+	sort.Slice(report.Peers, func(i, j int) bool {
+		return report.Peers[i].Peer < report.Peers[j].Peer
+	})
+	return report, nil
+}
+
+// allocatesEverywhere returns whether the pin is allocated to every peer.
+func allocatesEverywhere(pin *api.Pin) bool {
+	return pin.ReplicationFactorMin < 0 && pin.ReplicationFactorMax < 0
+}
+
+// simulateAllocation allocates a single pin like Cluster.allocate() does,
+// using the given metrics adjusted by the allocations simulated so far.
+func simulateAllocation(
+	ctx context.Context,
+	allocator PinAllocator,
+	pin *api.Pin,
+	base map[peer.ID]*api.Metric,
+	reserver Reserver,
+	reserved map[peer.ID]int,
+	notAllocatable []peer.ID,
+) ([]peer.ID, error) {
+	if allocatesEverywhere(pin) {
+		return nil, nil
+	}
+	if pin.ReplicationFactorMin+pin.ReplicationFactorMax == 0 {
+		return nil, errors.New("bad replication factors")
+	}
+
+	currentMetrics := make(map[peer.ID]*api.Metric)
+	candidatesMetrics := make(map[peer.ID]*api.Metric)
+	for p, m := range base {
+		if n := reserved[p]; n > 0 && reserver != nil {
+			m = reserver.Reserve(m, n)
+		}
+		switch {
+		case containsPeer(pin.Allocations, p):
+			currentMetrics[p] = m
+		case containsPeer(notAllocatable, p):
+			continue
+		default:
+			candidatesMetrics[p] = m
+		}
+	}
+
+	allocs, err := obtainAllocations(
+		ctx,
+		allocator,
+		pin,
+		currentMetrics,
+		candidatesMetrics,
+		make(map[peer.ID]*api.Metric),
+		&api.AllocationExplanation{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if allocs == nil {
+		// Keep the current allocations which are still valid.
+		for _, p := range pin.Allocations {
+			if _, ok := currentMetrics[p]; ok {
+				allocs = append(allocs, p)
+			}
+		}
+	}
+	return allocs, nil
+}
@@ -0,0 +1,145 @@
+package ipfscluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/ipfs-cluster/allocator/ascendalloc"
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/informer/numpin"
+	"github.com/ipfs/ipfs-cluster/test"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func simulationOptions(t *testing.T) SimulationOptions {
+	cfg := &numpin.Config{}
+	cfg.Default()
+	inf, err := numpin.NewInformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return SimulationOptions{
+		Informer:  inf,
+		Allocator: ascendalloc.NewAllocator(),
+	}
+}
+
+func simulationMetrics() []*api.Metric {
+	var metrics []*api.Metric
+	for _, p := range []peer.ID{test.PeerID1, test.PeerID2, test.PeerID3} {
+		metrics = append(metrics, &api.Metric{
+			Name:  numpin.MetricName,
+			Peer:  p,
+			Value: "0",
+			Valid: true,
+		})
+	}
+	return metrics
+}
+
+func simulationPins(n int, allocs ...peer.ID) []*api.Pin {
+	var pins []*api.Pin
+	for i := 0; i < n; i++ {
+		h, _ := mh.Sum([]byte{byte(i)}, mh.SHA2_256, -1)
+		pin := api.PinCid(cid.NewCidV1(cid.Raw, h))
+		pin.ReplicationFactorMin = 1
+		pin.ReplicationFactorMax = 1
+		pin.Allocations = allocs
+		pins = append(pins, pin)
+	}
+	return pins
+}
+
+func simulationPeer(t *testing.T, r *SimulationReport, p peer.ID) *SimulationPeer {
+	for _, sp := range r.Peers {
+		if sp.Peer == p {
+			return sp
+		}
+	}
+	t.Fatalf("peer %s not in report", p)
+	return nil
+}
+
+func TestSimulateAllocations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("balanced with reservations", func(t *testing.T) {
+		report, err := SimulateAllocations(ctx, simulationPins(6), simulationMetrics(), simulationOptions(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Pins != 6 || report.Changed != 6 || len(report.Failed) != 0 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		for _, sp := range report.Peers {
+			if sp.Before != 0 || sp.After != 2 || sp.Added != 2 {
+				t.Errorf("unexpected peer summary: %+v", sp)
+			}
+		}
+	})
+
+	t.Run("raise replication factor", func(t *testing.T) {
+		opts := simulationOptions(t)
+		opts.ReplicationFactorMin = 2
+		opts.ReplicationFactorMax = 2
+		report, err := SimulateAllocations(ctx, simulationPins(4, test.PeerID1), simulationMetrics(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Changed != 4 {
+			t.Errorf("expected all pins to change: %d", report.Changed)
+		}
+		sp := simulationPeer(t, report, test.PeerID1)
+		if sp.Before != 4 || sp.After != 4 || sp.Removed != 0 {
+			t.Errorf("current allocations should be kept: %+v", sp)
+		}
+		if a, b := simulationPeer(t, report, test.PeerID2), simulationPeer(t, report, test.PeerID3); a.After != 2 || b.After != 2 {
+			t.Errorf("new allocations should be balanced: %+v %+v", a, b)
+		}
+	})
+
+	t.Run("reallocate", func(t *testing.T) {
+		// The metrics account for the current allocations.
+		metrics := simulationMetrics()
+		metrics[0].Value = "3"
+		opts := simulationOptions(t)
+		opts.Reallocate = true
+		report, err := SimulateAllocations(ctx, simulationPins(3, test.PeerID1), metrics, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sp := simulationPeer(t, report, test.PeerID1)
+		if sp.Before != 3 || sp.After != 0 || sp.Removed != 3 {
+			t.Errorf("unexpected peer summary: %+v", sp)
+		}
+	})
+
+	t.Run("not allocatable and failures", func(t *testing.T) {
+		metrics := append(simulationMetrics(), &api.Metric{
+			Name:  allocatableMetricName,
+			Peer:  test.PeerID3,
+			Value: "false",
+			Valid: true,
+		})
+		opts := simulationOptions(t)
+		opts.ReplicationFactorMin = 3
+		opts.ReplicationFactorMax = 3
+		report, err := SimulateAllocations(ctx, simulationPins(2), metrics, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Failed) != 2 {
+			t.Errorf("expected all pins to fail: %+v", report.Failed)
+		}
+	})
+
+	t.Run("no metrics", func(t *testing.T) {
+		_, err := SimulateAllocations(ctx, simulationPins(1), nil, simulationOptions(t))
+		if err == nil {
+			t.Error("expected an error without metrics")
+		}
+	})
+}
@@ -0,0 +1,358 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ipfs/ipfs-cluster/api"
+
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	namespace "github.com/ipfs/go-datastore/namespace"
+	query "github.com/ipfs/go-datastore/query"
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"go.opencensus.io/trace"
+)
+
+// Snapshots are named copies of the pinset which act as restore points
+// before risky batch changes. They are stored in the datastore of the peer
+// which takes them. Restoring a snapshot pins and unpins only what differs
+// between the current pinset and the snapshot, so that untouched pins keep
+// their allocations.
+
+// snapshotsNamespace is the datastore namespace under which snapshots are
+// stored. Each snapshot has an info entry, used to list them, and a pins
+// entry.
+var snapshotsNamespace = "/snapshots"
+
+var snapshotNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,127}$`)
+
+var errSnapshotNotFound = errors.New("snapshot not found")
+
+func (c *Cluster) snapshotStore() ds.Datastore {
+	return namespace.Wrap(c.datastore, ds.NewKey(snapshotsNamespace))
+}
+
+func snapshotInfoKey(name string) ds.Key {
+	return ds.NewKey("info").ChildString(name)
+}
+
+func snapshotPinsKey(name string) ds.Key {
+	return ds.NewKey("pins").ChildString(name)
+}
+
+// currentPinset returns the pins in the shared state which are not
+// scheduled for removal.
+func (c *Cluster) currentPinset(ctx context.Context) ([]*api.Pin, error) {
+	pins, err := c.Pins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := make([]*api.Pin, 0, len(pins))
+	for _, pin := range pins {
+		if !pin.IsScheduledForRemoval() {
+			current = append(current, pin)
+		}
+	}
+	return current, nil
+}
+
+// SnapshotCreate stores a snapshot of the current pinset with the given
+// name, which must not be in use.
+func (c *Cluster) SnapshotCreate(ctx context.Context, name string) (*api.Snapshot, error) {
+	_, span := trace.StartSpan(ctx, "cluster/SnapshotCreate")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	if !snapshotNameRegexp.MatchString(name) || name == api.SnapshotCurrent {
+		return nil, fmt.Errorf("invalid snapshot name: %q", name)
+	}
+
+	c.snapshotsMux.Lock()
+	defer c.snapshotsMux.Unlock()
+
+	store := c.snapshotStore()
+	exists, err := store.Has(snapshotInfoKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("snapshot %s already exists", name)
+	}
+
+	pins, err := c.currentPinset(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pinsData, err := json.Marshal(pins)
+	if err != nil {
+		return nil, err
+	}
+	snap := &api.Snapshot{
+		Name:      name,
+		CreatedAt: time.Now(),
+		Pins:      len(pins),
+	}
+	infoData, err := json.Marshal(snap)
+	if err != nil {
+		return nil, err
+	}
+
+	// The info entry goes last: snapshots without it are not listed
+	// and are overwritten when their name is reused.
+	err = store.Put(snapshotPinsKey(name), pinsData)
+	if err != nil {
+		return nil, err
+	}
+	err = store.Put(snapshotInfoKey(name), infoData)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("pinset snapshot %s created with %d pins", name, len(pins))
+	return snap, nil
+}
+
+// Snapshots lists the snapshots stored by this peer, oldest first.
+func (c *Cluster) Snapshots(ctx context.Context) ([]*api.Snapshot, error) {
+	_, span := trace.StartSpan(ctx, "cluster/Snapshots")
+	defer span.End()
+
+	results, err := c.snapshotStore().Query(query.Query{Prefix: "/info"})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	snaps := []*api.Snapshot{}
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		snap := &api.Snapshot{}
+		if err := json.Unmarshal(r.Value, snap); err != nil {
+			logger.Warningf("skipping unreadable snapshot %s: %s", r.Key, err)
+			continue
+		}
+		snaps = append(snaps, snap)
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].CreatedAt.Before(snaps[j].CreatedAt)
+	})
+	return snaps, nil
+}
+
+// SnapshotDelete removes a snapshot.
+func (c *Cluster) SnapshotDelete(ctx context.Context, name string) error {
+	_, span := trace.StartSpan(ctx, "cluster/SnapshotDelete")
+	defer span.End()
+
+	c.snapshotsMux.Lock()
+	defer c.snapshotsMux.Unlock()
+
+	store := c.snapshotStore()
+	exists, err := store.Has(snapshotInfoKey(name))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errSnapshotNotFound
+	}
+	err = store.Delete(snapshotInfoKey(name))
+	if err != nil {
+		return err
+	}
+	return store.Delete(snapshotPinsKey(name))
+}
+
+// snapshotPins returns the pins in the given snapshot, or the current
+// pinset for api.SnapshotCurrent.
+func (c *Cluster) snapshotPins(ctx context.Context, name string) ([]*api.Pin, error) {
+	if name == api.SnapshotCurrent {
+		return c.currentPinset(ctx)
+	}
+
+	store := c.snapshotStore()
+	exists, err := store.Has(snapshotInfoKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s: %s", errSnapshotNotFound, name)
+	}
+	data, err := store.Get(snapshotPinsKey(name))
+	if err != nil {
+		return nil, err
+	}
+	var pins []*api.Pin
+	err = json.Unmarshal(data, &pins)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s: %s", name, err)
+	}
+	return pins, nil
+}
+
+// SnapshotDiff compares two snapshots (or a snapshot and the current
+// pinset).
+func (c *Cluster) SnapshotDiff(ctx context.Context, q api.SnapshotDiffQuery) (*api.SnapshotDiff, error) {
+	_, span := trace.StartSpan(ctx, "cluster/SnapshotDiff")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	from, err := c.snapshotPins(ctx, q.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.snapshotPins(ctx, q.To)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffPinsets(from, to)
+	diff.From = q.From
+	diff.To = q.To
+	return diff, nil
+}
+
+// diffPinsets returns the pins which need to be pinned and unpinned to go
+// from one pinset to the other.
+func diffPinsets(from, to []*api.Pin) *api.SnapshotDiff {
+	fromSet := make(map[string]*api.Pin, len(from))
+	for _, pin := range from {
+		fromSet[pin.Cid.KeyString()] = pin
+	}
+	toSet := make(map[string]struct{}, len(to))
+
+	diff := &api.SnapshotDiff{
+		Pin:   []*api.Pin{},
+		Unpin: []*api.Pin{},
+	}
+	for _, pin := range to {
+		toSet[pin.Cid.KeyString()] = struct{}{}
+		existing, ok := fromSet[pin.Cid.KeyString()]
+		if !ok || !samePinIntent(existing, pin) {
+			diff.Pin = append(diff.Pin, pin)
+		}
+	}
+	for _, pin := range from {
+		if _, ok := toSet[pin.Cid.KeyString()]; !ok {
+			diff.Unpin = append(diff.Unpin, pin)
+		}
+	}
+	return diff
+}
+
+// samePinIntent returns true when two pins only differ in their
+// allocations, which change as the cluster repins content.
+func samePinIntent(pin1, pin2 *api.Pin) bool {
+	p1 := *pin1
+	p2 := *pin2
+	p1.Allocations = nil
+	p2.Allocations = nil
+	return p1.Equals(&p2)
+}
+
+// snapshotPinOrder sorts pins so that the pins referenced by others are
+// pinned first.
+var snapshotPinOrder = map[api.PinType]int{
+	api.ShardType:      0,
+	api.ClusterDAGType: 1,
+	api.MetaType:       2,
+	api.DataType:       3,
+}
+
+// countDataPins returns the number of the given pins which are unpinned by
+// users: shards and cluster DAGs go away with their meta pin.
+func countDataPins(pins []*api.Pin) int {
+	n := 0
+	for _, pin := range pins {
+		if pin.Type == api.DataType || pin.Type == api.MetaType {
+			n++
+		}
+	}
+	return n
+}
+
+// SnapshotRestore brings the pinset back to the given snapshot: pins which
+// are not in the snapshot are unpinned and pins which are missing or have
+// different options are pinned. Operations which fail are reported and do
+// not stop the restore. Restores which would unpin more than the allowed
+// share of the pinset must be forced and, when configured, confirmed (see
+// checkBulkUnpinRequest).
+func (c *Cluster) SnapshotRestore(ctx context.Context, q api.SnapshotRestoreQuery) (*api.SnapshotRestore, error) {
+	_, span := trace.StartSpan(ctx, "cluster/SnapshotRestore")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	// Restored pins would not carry signatures for the operations.
+	if c.pinVerifier != nil && c.pinVerifier.require {
+		return nil, errors.New("snapshots cannot be restored when signed pins are required")
+	}
+
+	name := q.Name
+	current, err := c.currentPinset(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := c.snapshotPins(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffPinsets(current, pins)
+
+	op := "restore of snapshot " + name
+	err = c.checkBulkUnpinRequest(ctx, op, countDataPins(diff.Unpin), countDataPins(current), q.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &api.SnapshotRestore{
+		Name:     name,
+		Pinned:   []cid.Cid{},
+		Unpinned: []cid.Cid{},
+	}
+
+	for _, pin := range diff.Unpin {
+		// Shards and cluster DAGs go away with their meta pin.
+		if pin.Type != api.DataType && pin.Type != api.MetaType {
+			continue
+		}
+		err := c.Unpin(ctx, pin.Cid)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("unpinning %s: %s", pin.Cid, err))
+			continue
+		}
+		result.Unpinned = append(result.Unpinned, pin.Cid)
+	}
+
+	sort.SliceStable(diff.Pin, func(i, j int) bool {
+		return snapshotPinOrder[diff.Pin[i].Type] < snapshotPinOrder[diff.Pin[j].Type]
+	})
+	for _, pin := range diff.Pin {
+		pin.Provenance = &api.PinProvenance{Source: api.ProvenanceSnapshot}
+		// The allocations in the snapshot are preferred.
+		priority := append(append([]peer.ID{}, pin.UserAllocations...), pin.Allocations...)
+		_, _, err := c.pin(ctx, pin, []peer.ID{}, priority)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("pinning %s: %s", pin.Cid, err))
+			continue
+		}
+		result.Pinned = append(result.Pinned, pin.Cid)
+	}
+
+	outcome := api.AuditCompleted
+	if len(result.Errors) > 0 {
+		outcome = api.AuditFailed
+	}
+	c.audit(ctx, op, outcome, fmt.Sprintf(
+		"%d pinned, %d unpinned, %d errors",
+		len(result.Pinned),
+		len(result.Unpinned),
+		len(result.Errors),
+	))
+	return result, nil
+}
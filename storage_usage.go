@@ -0,0 +1,119 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+
+	cid "github.com/ipfs/go-cid"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+)
+
+// Storage usage reports answer how much disk the cluster is actually using.
+// The blocks of the DAG of every pin (or of a random sample of pins, for
+// large pinsets) are listed by one of the peers holding it. Blocks shared
+// between DAGs are counted once towards the unique usage, while the raw
+// usage counts the blocks of every DAG as many times as it is replicated.
+
+// StorageUsage examines the DAGs of the pins in the shared state and
+// compares the amount of unique data referenced by the pinset with the raw
+// amount of data stored by the peers holding replicas of it. When sample
+// is positive and lower than the number of pins, only that many pins,
+// chosen at random, are examined. Figures obtained from a part of the
+// pinset (because of sampling or of pins which could not be examined) are
+// extrapolated to the whole pinset.
+func (c *Cluster) StorageUsage(ctx context.Context, sample int) (*api.StorageUsage, error) {
+	_, span := trace.StartSpan(ctx, "cluster/StorageUsage")
+	defer span.End()
+	ctx = trace.NewContext(c.ctx, span)
+
+	pins, err := c.Pins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	peers, err := c.consensus.Peers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Meta pins are not pinned in IPFS. Their shards are.
+	examined := make([]*api.Pin, 0, len(pins))
+	for _, pin := range pins {
+		if pin.Type != api.MetaType {
+			examined = append(examined, pin)
+		}
+	}
+
+	usage := &api.StorageUsage{Pins: len(examined)}
+	if sample > 0 && sample < len(examined) {
+		rand.Shuffle(len(examined), func(i, j int) {
+			examined[i], examined[j] = examined[j], examined[i]
+		})
+		examined = examined[:sample]
+	}
+	usage.SampledPins = len(examined)
+
+	seen := make(map[cid.Cid]struct{})
+	for _, pin := range examined {
+		blocks, err := c.dagBlocks(ctx, pin)
+		if err != nil {
+			logger.Warningf("cannot list the blocks of %s: %s", pin.Cid, err)
+			usage.FailedPins++
+			continue
+		}
+
+		var pinBytes uint64
+		for _, b := range blocks {
+			pinBytes += b.Size
+			if _, ok := seen[b.Cid]; ok {
+				continue
+			}
+			seen[b.Cid] = struct{}{}
+			usage.UniqueBlocks++
+			usage.UniqueBytes += b.Size
+		}
+
+		replicas := len(pin.Allocations)
+		if allocatesEverywhere(pin) {
+			replicas = len(peers)
+		}
+		usage.ReplicatedBytes += pinBytes * uint64(replicas)
+	}
+
+	if n := usage.SampledPins - usage.FailedPins; n > 0 && n < usage.Pins {
+		scale := float64(usage.Pins) / float64(n)
+		usage.UniqueBlocks = uint64(float64(usage.UniqueBlocks) * scale)
+		usage.UniqueBytes = uint64(float64(usage.UniqueBytes) * scale)
+		usage.ReplicatedBytes = uint64(float64(usage.ReplicatedBytes) * scale)
+	}
+	return usage, nil
+}
+
+// dagBlocks lists the blocks of the DAG of a pin using the IPFS daemon of
+// this peer, when it holds the pin, or of one of the peers allocated to it.
+func (c *Cluster) dagBlocks(ctx context.Context, pin *api.Pin) ([]*api.IPFSBlockStat, error) {
+	if !pin.IsRemotePin(c.id) {
+		return c.ipfs.DAGBlocks(ctx, pin.Cid, pin.MaxDepth)
+	}
+
+	err := errors.New("no peers are allocated to this pin")
+	for _, p := range pin.Allocations {
+		var blocks []*api.IPFSBlockStat
+		err = c.rpcClient.CallContext(
+			ctx,
+			p,
+			"IPFSConnector",
+			"DAGBlocks",
+			pin,
+			&blocks,
+		)
+		if err == nil {
+			return blocks, nil
+		}
+		logger.Debugf("error listing blocks of %s in %s: %s", pin.Cid, p, err)
+	}
+	return nil, err
+}
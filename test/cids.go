@@ -24,6 +24,10 @@ var (
 	PeerID5, _  = peer.IDB58Decode("QmZVAo3wd8s5eTTy2kPYs34J9PvfxpKPuYsePPYGjgRRjg")
 	PeerID6, _  = peer.IDB58Decode("QmR8Vu6kZk7JvAN2rWVWgiduHatgBq2bb15Yyq8RRhYSbx")
 
+	// RateLimitedCid is refused by the mock cluster as if the pin rate
+	// limit had been exceeded.
+	RateLimitedCid, _ = cid.Decode("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmme")
+
 	PeerName1 = "TestPeer1"
 	PeerName2 = "TestPeer2"
 	PeerName3 = "TestPeer3"
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -38,10 +39,18 @@ type IpfsMock struct {
 	Port       int
 	pinMap     state.State
 	BlockStore map[string][]byte
+
+	configMux          sync.Mutex
+	reproviderStrategy string
+
+	bitswapMux          sync.Mutex
+	bitswapDataReceived uint64
+	bitswapStalled      bool
 }
 
 type mockPinResp struct {
-	Pins []string
+	Pins     []string `json:",omitempty"`
+	Progress int      `json:",omitempty"`
 }
 
 type mockPinType struct {
@@ -68,10 +77,29 @@ type mockRepoStatResp struct {
 	StorageMax uint64
 }
 
+type mockObjectStatResp struct {
+	Hash           string
+	NumLinks       int
+	CumulativeSize uint64
+}
+
 type mockConfigResp struct {
 	Datastore struct {
 		StorageMax string
 	}
+	Routing struct {
+		Type string
+	}
+	Reprovider struct {
+		Strategy string
+	}
+}
+
+type mockBitswapStatResp struct {
+	Wantlist       []map[string]string
+	Peers          []string
+	BlocksReceived uint64
+	DataReceived   uint64
 }
 
 type mockAddResp struct {
@@ -85,6 +113,16 @@ type mockRefsResp struct {
 	Err string
 }
 
+type mockRepoGCResp struct {
+	Key   map[string]string `json:",omitempty"`
+	Error string            `json:",omitempty"`
+}
+
+type mockBlockStatResp struct {
+	Key  string
+	Size int
+}
+
 type mockSwarmPeersResp struct {
 	Peers []mockIpfsPeer
 }
@@ -98,7 +136,7 @@ type mockBlockPutResp struct {
 }
 
 // NewIpfsMock returns a new mock.
-func NewIpfsMock(t *testing.T) *IpfsMock {
+func NewIpfsMock(t testing.TB) *IpfsMock {
 	store := inmem.New()
 	st, err := dsstate.New(store, "", dsstate.DefaultHandle())
 	if err != nil {
@@ -160,11 +198,25 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 		if arg == ErrorCid.String() {
 			goto ERROR
 		}
+		if arg == SlowCid1.String() {
+			// never finishes pinning
+			<-r.Context().Done()
+			goto ERROR
+		}
 		c, err := cid.Decode(arg)
 		if err != nil {
 			goto ERROR
 		}
-		m.pinMap.Add(ctx, api.PinCid(c))
+		pin := api.PinCid(c)
+		if r.URL.Query().Get("recursive") == "false" {
+			pin.MaxDepth = 0
+		}
+		if r.URL.Query().Get("progress") == "true" {
+			// every DAG has two blocks
+			j, _ := json.Marshal(mockPinResp{Progress: 2})
+			w.Write(j)
+		}
+		m.pinMap.Add(ctx, pin)
 		resp := mockPinResp{
 			Pins: []string{arg},
 		}
@@ -186,6 +238,11 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 		j, _ := json.Marshal(resp)
 		w.Write(j)
 	case "pin/ls":
+		typeFilter := r.URL.Query().Get("type")
+		matchesType := func(pinType string) bool {
+			return typeFilter == "" || typeFilter == "all" || typeFilter == pinType
+		}
+
 		arg, ok := extractCid(r.URL)
 		if !ok {
 			rMap := make(map[string]mockPinType)
@@ -194,7 +251,9 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 				goto ERROR
 			}
 			for _, p := range pins {
-				rMap[p.Cid.String()] = mockPinType{"recursive"}
+				if pinType := mockPinTypeOf(p); matchesType(pinType) {
+					rMap[p.Cid.String()] = mockPinType{pinType}
+				}
 			}
 			j, _ := json.Marshal(mockPinLsResp{rMap})
 			w.Write(j)
@@ -206,13 +265,13 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			goto ERROR
 		}
-		ok, err = m.pinMap.Has(ctx, c)
-		if err != nil {
+		pin, err := m.pinMap.Get(ctx, c)
+		if err != nil && err != state.ErrNotFound {
 			goto ERROR
 		}
-		if ok {
+		if pin != nil && matchesType(mockPinTypeOf(pin)) {
 			rMap := make(map[string]mockPinType)
-			rMap[cidStr] = mockPinType{"recursive"}
+			rMap[cidStr] = mockPinType{mockPinTypeOf(pin)}
 			j, _ := json.Marshal(mockPinLsResp{rMap})
 			w.Write(j)
 		} else {
@@ -318,15 +377,66 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 		}
 		j, _ := json.Marshal(resp)
 		w.Write(j)
+	case "repo/gc":
+		// Reports two removed blocks and one which could not be
+		// removed.
+		for _, c := range []cid.Cid{Cid4, CidResolved} {
+			j, _ := json.Marshal(mockRepoGCResp{Key: map[string]string{"/": c.String()}})
+			w.Write(j)
+		}
+		j, _ := json.Marshal(mockRepoGCResp{Error: "could not remove " + ErrorCid.String()})
+		w.Write(j)
+	case "object/stat":
+		arg, ok := extractCid(r.URL)
+		if !ok {
+			goto ERROR
+		}
+		resp := mockObjectStatResp{
+			Hash:           arg,
+			CumulativeSize: 1024,
+		}
+		j, _ := json.Marshal(resp)
+		w.Write(j)
 	case "resolve":
 		w.Write([]byte("{\"Path\":\"" + "/ipfs/" + CidResolved.String() + "\"}"))
 	case "config/show":
-		resp := mockConfigResp{
-			Datastore: struct {
-				StorageMax string
-			}{
-				StorageMax: "10G",
+		resp := mockConfigResp{}
+		resp.Datastore.StorageMax = "10G"
+		resp.Routing.Type = "dht"
+		resp.Reprovider.Strategy = m.ReproviderStrategy()
+		j, _ := json.Marshal(resp)
+		w.Write(j)
+	case "config":
+		args := r.URL.Query()["arg"]
+		if len(args) != 2 || args[0] != "Reprovider.Strategy" {
+			goto ERROR
+		}
+		m.configMux.Lock()
+		m.reproviderStrategy = args[1]
+		m.configMux.Unlock()
+		w.Write([]byte("{\"Key\":\"Reprovider.Strategy\",\"Value\":\"" + args[1] + "\"}"))
+	case "dht/provide":
+		arg, ok := extractCid(r.URL)
+		if !ok || arg == ErrorCid.String() {
+			goto ERROR
+		}
+		w.Write([]byte("{\"ID\":\"\",\"Type\":4}"))
+	case "bitswap/stat":
+		// every call reports 1KB more of received data, unless
+		// stalled.
+		m.bitswapMux.Lock()
+		if !m.bitswapStalled {
+			m.bitswapDataReceived += 1024
+		}
+		dataReceived := m.bitswapDataReceived
+		m.bitswapMux.Unlock()
+		resp := mockBitswapStatResp{
+			Wantlist: []map[string]string{
+				{"/": Cid4.String()},
 			},
+			Peers:          []string{PeerID4.Pretty(), PeerID5.Pretty()},
+			BlocksReceived: dataReceived / 256,
+			DataReceived:   dataReceived,
 		}
 		j, _ := json.Marshal(resp)
 		w.Write(j)
@@ -335,11 +445,40 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 		if !ok {
 			goto ERROR
 		}
+		if arg == SlowCid1.String() {
+			// fetches the root and nothing else.
+			j, _ := json.Marshal(mockRefsResp{Ref: arg})
+			w.Write(j)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			<-r.Context().Done()
+			return
+		}
+		// Every DAG links to Cid4.
 		resp := mockRefsResp{
 			Ref: arg,
 		}
 		j, _ := json.Marshal(resp)
 		w.Write(j)
+		resp.Ref = Cid4.String()
+		j, _ = json.Marshal(resp)
+		w.Write(j)
+	case "block/stat":
+		arg, ok := extractCid(r.URL)
+		if !ok {
+			goto ERROR
+		}
+		size := 256
+		if data, ok := m.BlockStore[arg]; ok {
+			size = len(data)
+		}
+		resp := mockBlockStatResp{
+			Key:  arg,
+			Size: size,
+		}
+		j, _ := json.Marshal(resp)
+		w.Write(j)
 	case "version":
 		w.Write([]byte("{\"Version\":\"m.o.c.k\"}"))
 	default:
@@ -356,6 +495,30 @@ func (m *IpfsMock) Close() {
 	m.server.Close()
 }
 
+// ReproviderStrategy returns the reprovider strategy set in the mock
+// configuration.
+func (m *IpfsMock) ReproviderStrategy() string {
+	m.configMux.Lock()
+	defer m.configMux.Unlock()
+	return m.reproviderStrategy
+}
+
+// SetBitswapStalled makes the bitswap statistics stop (or resume)
+// reporting received data.
+func (m *IpfsMock) SetBitswapStalled(stalled bool) {
+	m.bitswapMux.Lock()
+	defer m.bitswapMux.Unlock()
+	m.bitswapStalled = stalled
+}
+
+// mockPinTypeOf returns the type with which ipfs reports the given pin.
+func mockPinTypeOf(pin *api.Pin) string {
+	if pin.MaxDepth == 0 {
+		return "direct"
+	}
+	return "recursive"
+}
+
 // extractCid extracts the cid argument from a url.URL, either via
 // the query string parameters or from the url path itself.
 func extractCid(u *url.URL) (string, bool) {
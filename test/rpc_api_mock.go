@@ -65,6 +65,18 @@ func (mock *mockCluster) Pin(ctx context.Context, in *api.Pin, out *struct{}) er
 	if in.Cid.Equals(ErrorCid) {
 		return ErrBadCid
 	}
+	if in.Cid.Equals(RateLimitedCid) {
+		return &api.RateLimitError{RetryAfter: 1500 * time.Millisecond}
+	}
+	return nil
+}
+
+func (mock *mockCluster) PinWithResponse(ctx context.Context, in *api.Pin, out *api.PinResponse) error {
+	err := mock.Pin(ctx, in, &struct{}{})
+	if err != nil {
+		return err
+	}
+	*out = api.PinResponse{Pin: *in}
 	return nil
 }
 
@@ -75,6 +87,85 @@ func (mock *mockCluster) Unpin(ctx context.Context, in *api.Pin, out *struct{})
 	return nil
 }
 
+func (mock *mockCluster) JoinToken(ctx context.Context, in time.Duration, out *api.JoinToken) error {
+	if in <= 0 {
+		return errors.New("the join token TTL must be positive")
+	}
+	*out = api.JoinToken{
+		Token:   "token",
+		Expires: time.Now().Add(in),
+	}
+	return nil
+}
+
+func (mock *mockCluster) SnapshotCreate(ctx context.Context, in string, out *api.Snapshot) error {
+	if in == "" {
+		return errors.New("invalid snapshot name")
+	}
+	*out = api.Snapshot{
+		Name:      in,
+		CreatedAt: time.Now(),
+		Pins:      2,
+	}
+	return nil
+}
+
+func (mock *mockCluster) Snapshots(ctx context.Context, in struct{}, out *[]*api.Snapshot) error {
+	*out = []*api.Snapshot{
+		{
+			Name:      "snap1",
+			CreatedAt: time.Now(),
+			Pins:      2,
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) SnapshotDelete(ctx context.Context, in string, out *struct{}) error {
+	if in != "snap1" {
+		return errors.New("snapshot not found")
+	}
+	return nil
+}
+
+func (mock *mockCluster) SnapshotDiff(ctx context.Context, in api.SnapshotDiffQuery, out *api.SnapshotDiff) error {
+	*out = api.SnapshotDiff{
+		From:  in.From,
+		To:    in.To,
+		Pin:   []*api.Pin{api.PinCid(Cid1)},
+		Unpin: []*api.Pin{api.PinCid(Cid2)},
+	}
+	return nil
+}
+
+func (mock *mockCluster) SnapshotRestore(ctx context.Context, in api.SnapshotRestoreQuery, out *api.SnapshotRestore) error {
+	if in.Name != "snap1" {
+		return errors.New("snapshot not found")
+	}
+	// Restoring snap1 unpins half of the pinset.
+	if !in.Options.Force {
+		return &api.BulkUnpinError{
+			Operation: "restore of snapshot snap1",
+			Unpins:    1,
+			Total:     2,
+			Limit:     0,
+		}
+	}
+	*out = api.SnapshotRestore{
+		Name:     in.Name,
+		Pinned:   []cid.Cid{Cid1},
+		Unpinned: []cid.Cid{Cid2},
+	}
+	return nil
+}
+
+func (mock *mockCluster) RestorePin(ctx context.Context, in *api.Pin, out *api.Pin) error {
+	if in.Cid.Equals(ErrorCid) {
+		return ErrBadCid
+	}
+	return mock.PinGet(ctx, in.Cid, out)
+}
+
 func (mock *mockCluster) PinPath(ctx context.Context, in *api.PinPath, out *api.Pin) error {
 	p, err := gopath.ParsePath(in.Path)
 	if err != nil {
@@ -110,11 +201,18 @@ func (mock *mockCluster) Pins(ctx context.Context, in struct{}, out *[]*api.Pin)
 		ReplicationFactorMax: -1,
 	}
 
-	*out = []*api.Pin{
-		api.PinWithOpts(Cid1, opts),
-		api.PinCid(Cid2),
-		api.PinWithOpts(Cid3, opts),
-	}
+	now := time.Now()
+	pin1 := api.PinWithOpts(Cid1, opts)
+	pin1.CreatedAt = now.Add(-48 * time.Hour)
+	pin1.UpdatedAt = now.Add(-30 * time.Minute)
+	pin2 := api.PinCid(Cid2)
+	pin2.CreatedAt = now.Add(-time.Hour)
+	pin2.UpdatedAt = now.Add(-time.Hour)
+	pin3 := api.PinWithOpts(Cid3, opts)
+	pin3.CreatedAt = now.Add(-240 * time.Hour)
+	pin3.UpdatedAt = now.Add(-240 * time.Hour)
+
+	*out = []*api.Pin{pin1, pin2, pin3}
 	return nil
 }
 
@@ -148,10 +246,32 @@ func (mock *mockCluster) ID(ctx context.Context, in struct{}, out *api.ID) error
 	*out = api.ID{
 		ID: PeerID1,
 		//PublicKey: pubkey,
-		Version: "0.0.mock",
+		Version:     "0.0.mock",
+		Allocatable: true,
 		IPFS: &api.IPFSID{
-			ID:        PeerID1,
-			Addresses: []api.Multiaddr{addr},
+			ID:         PeerID1,
+			Addresses:  []api.Multiaddr{addr},
+			GatewayURL: "http://127.0.0.1:8080",
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) Versions(ctx context.Context, in struct{}, out *[]*api.PeerVersion) error {
+	*out = []*api.PeerVersion{
+		{
+			Peer:         PeerID1,
+			Peername:     PeerName1,
+			Version:      "0.0.mock",
+			StateVersion: 1,
+			Compatible:   true,
+		},
+		{
+			Peer:         PeerID2,
+			Peername:     PeerName2,
+			Version:      "1.0.mock",
+			StateVersion: 2,
+			Error:        "version 1.0.0-mock is incompatible with 0.0.0-mock",
 		},
 	}
 	return nil
@@ -172,6 +292,65 @@ func (mock *mockCluster) Peers(ctx context.Context, in struct{}, out *[]*api.ID)
 	return nil
 }
 
+func (mock *mockCluster) ClusterInfo(ctx context.Context, in struct{}, out *api.ClusterInfo) error {
+	*out = api.ClusterInfo{
+		Peers:         1,
+		Pins:          3,
+		PinErrors:     1,
+		FreeSpace:     1000,
+		Consensus:     "raft",
+		Leader:        PeerID1,
+		OldestVersion: "0.0.mock",
+		UpdatedAt:     time.Now(),
+	}
+	return nil
+}
+
+func (mock *mockCluster) Peerstore(ctx context.Context, in struct{}, out *[]*api.KnownPeer) error {
+	addr, _ := api.NewMultiaddr("/ip4/1.2.3.4/tcp/9096/ipfs/" + PeerID1.Pretty())
+	*out = []*api.KnownPeer{
+		{
+			ID:        PeerID1,
+			Addresses: []api.Multiaddr{addr},
+			Connected: true,
+			LastSeen:  time.Now(),
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) ReconnectStatus(ctx context.Context, in struct{}, out *api.ReconnectStatus) error {
+	now := time.Now()
+	*out = api.ReconnectStatus{
+		Peer:           PeerID1,
+		State:          api.ReconnectStateConnected,
+		ConnectedPeers: 2,
+		KnownPeers:     2,
+		LastConnected:  now,
+		NextAttempt:    now.Add(10 * time.Second),
+	}
+	return nil
+}
+
+func (mock *mockCluster) PeerstoreAdd(ctx context.Context, in []api.Multiaddr, out *[]*api.KnownPeer) error {
+	if len(in) == 0 {
+		return errors.New("no addresses given")
+	}
+	var known []*api.KnownPeer
+	for _, addr := range in {
+		pid, _, err := api.Libp2pMultiaddrSplit(addr.Value())
+		if err != nil {
+			return err
+		}
+		known = append(known, &api.KnownPeer{
+			ID:        pid,
+			Addresses: []api.Multiaddr{addr},
+		})
+	}
+	*out = known
+	return nil
+}
+
 func (mock *mockCluster) PeerAdd(ctx context.Context, in peer.ID, out *api.ID) error {
 	id := api.ID{}
 	mock.ID(ctx, struct{}{}, &id)
@@ -183,6 +362,105 @@ func (mock *mockCluster) PeerRemove(ctx context.Context, in peer.ID, out *struct
 	return nil
 }
 
+func (mock *mockCluster) PeerMigrate(ctx context.Context, in peer.ID, out *api.PeerMigration) error {
+	pin := api.PinCid(Cid1)
+	pin.Allocations = []peer.ID{PeerID2}
+	*out = api.PeerMigration{
+		Peer: in,
+		Pins: []*api.Pin{pin},
+	}
+	return nil
+}
+
+func (mock *mockCluster) SetAllocatable(ctx context.Context, in *api.PeerAllocatable, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) SetAllocatableLocal(ctx context.Context, in *api.PeerAllocatable, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) SetTrackerPaused(ctx context.Context, in *api.TrackerPause, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) SetTrackerPausedLocal(ctx context.Context, in *api.TrackerPause, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) ConnectionDenyList(ctx context.Context, in struct{}, out *api.ConnectionFilter) error {
+	*out = api.ConnectionFilter{
+		Peers: []peer.ID{PeerID2},
+		CIDRs: []string{"10.0.0.0/8"},
+	}
+	return nil
+}
+
+func (mock *mockCluster) ConnectionDeny(ctx context.Context, in *api.ConnectionFilter, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) ConnectionUndeny(ctx context.Context, in *api.ConnectionFilter, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) Provide(ctx context.Context, in cid.Cid, out *[]*api.ProvideResult) error {
+	if in.Equals(ErrorCid) {
+		return ErrBadCid
+	}
+	*out = []*api.ProvideResult{
+		{Peer: PeerID1},
+		{Peer: PeerID2, Error: "provide failed"},
+	}
+	return nil
+}
+
+func (mock *mockCluster) Faults(ctx context.Context, in struct{}, out *[]*api.FaultRule) error {
+	*out = []*api.FaultRule{
+		{
+			Target:  "ipfs",
+			Method:  "Pin",
+			Percent: 50,
+			Error:   "boom",
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) Logs(ctx context.Context, in api.LogQuery, out *[]*api.LogEntry) error {
+	severity, err := api.LogLevelSeverity(in.Level)
+	if err != nil {
+		return err
+	}
+	entries := []*api.LogEntry{
+		{
+			Time:      time.Now(),
+			Component: "pintracker",
+			Level:     "warning",
+			Message:   "error pinning",
+		},
+		{
+			Time:      time.Now(),
+			Component: "cluster",
+			Level:     "info",
+			Message:   "peer ready",
+		},
+	}
+	var matching []*api.LogEntry
+	for _, e := range entries {
+		sev, _ := api.LogLevelSeverity(e.Level)
+		if (in.Component == "" || in.Component == e.Component) && sev <= severity {
+			matching = append(matching, e)
+		}
+	}
+	*out = matching
+	return nil
+}
+
+func (mock *mockCluster) SetFaults(ctx context.Context, in []*api.FaultRule, out *struct{}) error {
+	return nil
+}
+
 func (mock *mockCluster) ConnectGraph(ctx context.Context, in struct{}, out *api.ConnectGraph) error {
 	*out = api.ConnectGraph{
 		ClusterID: PeerID1,
@@ -201,11 +479,16 @@ func (mock *mockCluster) ConnectGraph(ctx context.Context, in struct{}, out *api
 			peer.IDB58Encode(PeerID2): PeerID5,
 			peer.IDB58Encode(PeerID3): PeerID6,
 		},
+		ClusterLatencies: map[string]map[string]time.Duration{
+			peer.IDB58Encode(PeerID1): {
+				peer.IDB58Encode(PeerID2): 10 * time.Millisecond,
+			},
+		},
 	}
 	return nil
 }
 
-func (mock *mockCluster) StatusAll(ctx context.Context, in struct{}, out *[]*api.GlobalPinInfo) error {
+func (mock *mockCluster) StatusAll(ctx context.Context, in api.TrackerStatus, out *[]*api.GlobalPinInfo) error {
 	pid := peer.IDB58Encode(PeerID1)
 	*out = []*api.GlobalPinInfo{
 		{
@@ -245,10 +528,37 @@ func (mock *mockCluster) StatusAll(ctx context.Context, in struct{}, out *[]*api
 	return nil
 }
 
-func (mock *mockCluster) StatusAllLocal(ctx context.Context, in struct{}, out *[]*api.PinInfo) error {
+func (mock *mockCluster) StatusAllLocal(ctx context.Context, in api.TrackerStatus, out *[]*api.PinInfo) error {
 	return (&mockPinTracker{}).StatusAll(ctx, in, out)
 }
 
+func (mock *mockCluster) StatusSummary(ctx context.Context, in struct{}, out *api.StatusSummary) error {
+	var local api.PeerStatusSummary
+	mock.StatusSummaryLocal(ctx, in, &local)
+	remote := &api.PeerStatusSummary{
+		Peer:  PeerID2,
+		Error: "this is an error",
+	}
+	*out = api.StatusSummary{
+		Totals: local.Counts,
+		Peers:  []*api.PeerStatusSummary{&local, remote},
+	}
+	return nil
+}
+
+func (mock *mockCluster) StatusSummaryLocal(ctx context.Context, in struct{}, out *api.PeerStatusSummary) error {
+	var pinfos []*api.PinInfo
+	mock.StatusAllLocal(ctx, api.TrackerStatusUndefined, &pinfos)
+	*out = api.PeerStatusSummary{
+		Peer:     PeerID1,
+		Peername: PeerName1,
+	}
+	for _, pinfo := range pinfos {
+		out.Counts.Count(pinfo.Status)
+	}
+	return nil
+}
+
 func (mock *mockCluster) Status(ctx context.Context, in cid.Cid, out *api.GlobalPinInfo) error {
 	if in.Equals(ErrorCid) {
 		return ErrBadCid
@@ -271,12 +581,176 @@ func (mock *mockCluster) StatusLocal(ctx context.Context, in cid.Cid, out *api.P
 	return (&mockPinTracker{}).Status(ctx, in, out)
 }
 
+func (mock *mockCluster) AllocationExplanation(ctx context.Context, in cid.Cid, out *api.AllocationExplanation) error {
+	if in.Equals(ErrorCid) {
+		return ErrBadCid
+	}
+	*out = api.AllocationExplanation{
+		Cid:         in,
+		Peer:        PeerID1,
+		Allocator:   "ascendalloc",
+		Informer:    "numpin",
+		Allocations: []peer.ID{PeerID1},
+		Rejected: []*api.RejectedPeer{
+			{
+				Peer:   PeerID2,
+				Reason: "discarded by allocator",
+			},
+		},
+		TS: time.Now(),
+	}
+	return nil
+}
+
+func (mock *mockCluster) AllocationExplanationLocal(ctx context.Context, in cid.Cid, out *api.AllocationExplanation) error {
+	return mock.AllocationExplanation(ctx, in, out)
+}
+
 func (mock *mockCluster) SyncAll(ctx context.Context, in struct{}, out *[]*api.GlobalPinInfo) error {
-	return mock.StatusAll(ctx, in, out)
+	return mock.StatusAll(ctx, api.TrackerStatusUndefined, out)
 }
 
 func (mock *mockCluster) SyncAllLocal(ctx context.Context, in struct{}, out *[]*api.PinInfo) error {
-	return mock.StatusAllLocal(ctx, in, out)
+	return mock.StatusAllLocal(ctx, api.TrackerStatusUndefined, out)
+}
+
+func (mock *mockCluster) StateSyncAll(ctx context.Context, in struct{}, out *[]*api.StateSync) error {
+	var result api.StateSync
+	mock.StateSyncLocal(ctx, in, &result)
+	*out = []*api.StateSync{&result}
+	return nil
+}
+
+func (mock *mockCluster) StateSyncPeer(ctx context.Context, in peer.ID, out *api.StateSync) error {
+	err := mock.StateSyncLocal(ctx, struct{}{}, out)
+	out.Peer = in
+	return err
+}
+
+func (mock *mockCluster) StateSyncLocal(ctx context.Context, in struct{}, out *api.StateSync) error {
+	*out = api.StateSync{
+		Peer:     PeerID1,
+		PeerName: PeerName1,
+		Start:    time.Now(),
+		End:      time.Now(),
+		Changed:  1,
+	}
+	return nil
+}
+
+func (mock *mockCluster) LastStateSyncAll(ctx context.Context, in struct{}, out *[]*api.StateSync) error {
+	return mock.StateSyncAll(ctx, in, out)
+}
+
+func (mock *mockCluster) LastStateSyncLocal(ctx context.Context, in struct{}, out *api.StateSync) error {
+	return mock.StateSyncLocal(ctx, in, out)
+}
+
+func (mock *mockCluster) AuditLog(ctx context.Context, in struct{}, out *[]*api.AuditRecord) error {
+	*out = []*api.AuditRecord{
+		{
+			Time:      time.Now(),
+			Peer:      PeerID1,
+			Operation: "restore of snapshot snap1",
+			Outcome:   api.AuditRefused,
+			Details:   "unpins 3 of 4 pins (limit 2)",
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) RepoGC(ctx context.Context, in struct{}, out *[]*api.RepoGC) error {
+	var res api.RepoGC
+	mock.RepoGCLocal(ctx, in, &res)
+	*out = []*api.RepoGC{&res}
+	return nil
+}
+
+func (mock *mockCluster) RepoGCLocal(ctx context.Context, in struct{}, out *api.RepoGC) error {
+	*out = api.RepoGC{
+		Peer:     PeerID1,
+		Peername: PeerName1,
+		Removed:  2,
+	}
+	return nil
+}
+
+func (mock *mockCluster) IPFSDrift(ctx context.Context, in struct{}, out *api.IPFSDrift) error {
+	*out = api.IPFSDrift{
+		Daemons: []*api.IPFSDaemonInfo{
+			{
+				Peer:       PeerID1,
+				Peername:   PeerName1,
+				Version:    "0.5.0",
+				Routing:    "dht",
+				StorageMax: "10G",
+			},
+			{
+				Peer:       PeerID2,
+				Peername:   PeerName2,
+				Version:    "0.4.23",
+				Routing:    "dht",
+				StorageMax: "10G",
+			},
+		},
+		Warnings:  []string{"peers run different IPFS versions: 0.4.23 (" + PeerName2 + "), 0.5.0 (" + PeerName1 + ")"},
+		Dangerous: true,
+	}
+	return nil
+}
+
+func (mock *mockCluster) IPFSDaemonInfoLocal(ctx context.Context, in struct{}, out *api.IPFSDaemonInfo) error {
+	*out = api.IPFSDaemonInfo{
+		Peer:       PeerID1,
+		Peername:   PeerName1,
+		Version:    "0.5.0",
+		Routing:    "dht",
+		StorageMax: "10G",
+	}
+	return nil
+}
+
+func (mock *mockCluster) RuntimeStatsAll(ctx context.Context, in struct{}, out *[]*api.RuntimeStats) error {
+	var rs api.RuntimeStats
+	mock.RuntimeStatsLocal(ctx, in, &rs)
+	*out = []*api.RuntimeStats{&rs}
+	return nil
+}
+
+func (mock *mockCluster) RuntimeStatsLocal(ctx context.Context, in struct{}, out *api.RuntimeStats) error {
+	*out = api.RuntimeStats{
+		Peer:        PeerID1,
+		Peername:    PeerName1,
+		Goroutines:  100,
+		HeapAlloc:   10 << 20,
+		HeapSys:     20 << 20,
+		NumGC:       5,
+		LastGCPause: time.Millisecond,
+		OpenFDs:     30,
+	}
+	return nil
+}
+
+func (mock *mockCluster) Ping(ctx context.Context, in struct{}, out *time.Time) error {
+	*out = time.Now()
+	return nil
+}
+
+func (mock *mockCluster) PingPeer(ctx context.Context, in peer.ID, out *api.PingResult) error {
+	*out = api.PingResult{
+		Peer:      in,
+		RTT:       10 * time.Millisecond,
+		ClockSkew: -2 * time.Millisecond,
+	}
+	return nil
+}
+
+func (mock *mockCluster) PingAll(ctx context.Context, in struct{}, out *[]*api.PingResult) error {
+	var result1, result2 api.PingResult
+	mock.PingPeer(ctx, PeerID1, &result1)
+	mock.PingPeer(ctx, PeerID2, &result2)
+	*out = []*api.PingResult{&result1, &result2}
+	return nil
 }
 
 func (mock *mockCluster) Sync(ctx context.Context, in cid.Cid, out *api.GlobalPinInfo) error {
@@ -291,10 +765,29 @@ func (mock *mockCluster) RecoverAllLocal(ctx context.Context, in struct{}, out *
 	return (&mockPinTracker{}).RecoverAll(ctx, in, out)
 }
 
+func (mock *mockCluster) RecoveryStart(ctx context.Context, in struct{}, out *api.RecoveryJob) error {
+	return mock.RecoveryStatus(ctx, in, out)
+}
+
+func (mock *mockCluster) RecoveryStatus(ctx context.Context, in struct{}, out *api.RecoveryJob) error {
+	*out = api.RecoveryJob{
+		Peer:      PeerID1,
+		State:     api.RecoveryJobRunning,
+		Total:     3,
+		Recovered: 1,
+		Started:   time.Now(),
+	}
+	return nil
+}
+
 func (mock *mockCluster) Recover(ctx context.Context, in cid.Cid, out *api.GlobalPinInfo) error {
 	return mock.Status(ctx, in, out)
 }
 
+func (mock *mockCluster) RecoverPeer(ctx context.Context, in peer.ID, out *[]*api.PinInfo) error {
+	return (&mockPinTracker{}).RecoverAll(ctx, struct{}{}, out)
+}
+
 func (mock *mockCluster) RecoverLocal(ctx context.Context, in cid.Cid, out *api.PinInfo) error {
 	return (&mockPinTracker{}).Recover(ctx, in, out)
 }
@@ -307,10 +800,61 @@ func (mock *mockCluster) BlockAllocate(ctx context.Context, in *api.Pin, out *[]
 	return nil
 }
 
+func (mock *mockCluster) BlockFetch(ctx context.Context, in *api.BlockFetch, out *api.BlockTransfer) error {
+	*out = api.BlockTransfer{
+		Cid:    in.Cid,
+		Source: PeerID1,
+		Blocks: 1,
+		Bytes:  256,
+	}
+	return nil
+}
+
 func (mock *mockCluster) SendInformerMetric(ctx context.Context, in struct{}, out *api.Metric) error {
 	return nil
 }
 
+func (mock *mockCluster) PeerLatencies(ctx context.Context, in []peer.ID, out *map[string]time.Duration) error {
+	*out = map[string]time.Duration{
+		peer.IDB58Encode(PeerID2): 10 * time.Millisecond,
+	}
+	return nil
+}
+
+func (mock *mockCluster) RecordAccess(ctx context.Context, in cid.Cid, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) StorageUsage(ctx context.Context, in int, out *api.StorageUsage) error {
+	*out = api.StorageUsage{
+		Pins:            4,
+		SampledPins:     4,
+		UniqueBlocks:    5,
+		UniqueBytes:     1280,
+		ReplicatedBytes: 4096,
+	}
+	if in > 0 && in < 4 {
+		out.SampledPins = in
+	}
+	return nil
+}
+
+// AddDelegate never delegates adds.
+func (mock *mockCluster) AddDelegate(ctx context.Context, in int, out *peer.ID) error {
+	*out = ""
+	return nil
+}
+
+func (mock *mockCluster) PinnedBytes(ctx context.Context, in struct{}, out *api.PinnedBytes) error {
+	*out = api.PinnedBytes{
+		Bytes:       2048,
+		Pins:        2,
+		UnknownSize: 0,
+		RepoSize:    4096,
+	}
+	return nil
+}
+
 /* Tracker methods */
 
 func (mock *mockPinTracker) Track(ctx context.Context, in *api.Pin, out *struct{}) error {
@@ -321,7 +865,7 @@ func (mock *mockPinTracker) Untrack(ctx context.Context, in *api.Pin, out *struc
 	return nil
 }
 
-func (mock *mockPinTracker) StatusAll(ctx context.Context, in struct{}, out *[]*api.PinInfo) error {
+func (mock *mockPinTracker) StatusAll(ctx context.Context, in api.TrackerStatus, out *[]*api.PinInfo) error {
 	*out = []*api.PinInfo{
 		{
 			Cid:    Cid1,
@@ -441,6 +985,43 @@ func (mock *mockIPFSConnector) BlockPut(ctx context.Context, in *api.NodeWithMet
 	return nil
 }
 
+func (mock *mockIPFSConnector) BlockPutDone(ctx context.Context, in string, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockIPFSConnector) Provide(ctx context.Context, in cid.Cid, out *struct{}) error {
+	if in.Equals(ErrorCid) {
+		return ErrBadCid
+	}
+	return nil
+}
+
+func (mock *mockIPFSConnector) BitswapStat(ctx context.Context, in struct{}, out *api.IPFSBitswapStat) error {
+	*out = api.IPFSBitswapStat{
+		WantlistSize:     10,
+		Peers:            2,
+		BlocksReceived:   100,
+		DataReceived:     25600,
+		DataReceivedRate: 1024,
+	}
+	return nil
+}
+
+func (mock *mockIPFSConnector) PinProgress(ctx context.Context, in struct{}, out *[]*api.PinProgress) error {
+	*out = []*api.PinProgress{
+		{Cid: SlowCid1, Blocks: 10, Bytes: 2560},
+	}
+	return nil
+}
+
+func (mock *mockIPFSConnector) DAGBlocks(ctx context.Context, in *api.Pin, out *[]*api.IPFSBlockStat) error {
+	*out = []*api.IPFSBlockStat{
+		{Cid: in.Cid, Size: 256},
+		{Cid: Cid4, Size: 256},
+	}
+	return nil
+}
+
 func (mock *mockIPFSConnector) Resolve(ctx context.Context, in string, out *cid.Cid) error {
 	switch in {
 	case ErrorCid.String(), "/ipfs/" + ErrorCid.String():
@@ -0,0 +1,189 @@
+package ipfscluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+
+	"go.opencensus.io/trace"
+
+	"github.com/ipfs/ipfs-cluster/api"
+	"github.com/ipfs/ipfs-cluster/rpcutil"
+)
+
+// Pins re-allocated away from a peer can be transferred in two steps (see
+// Config.ReallocationVerification). First, the new allocations are added
+// while the old peer stays allocated. Then, once enough of the new
+// allocations report the pin as pinned, the old peer is removed from the
+// allocations, which makes it unpin the content. This way the number of
+// replicas never drops while the content is being copied.
+
+// reallocationPollInterval is how often the new allocations of a pin being
+// transferred are asked for its status.
+var reallocationPollInterval = 5 * time.Second
+
+// pinTransfers keeps the CIDs with transfers in progress.
+type pinTransfers struct {
+	mu       sync.Mutex
+	inflight map[cid.Cid]struct{}
+}
+
+func newPinTransfers() *pinTransfers {
+	return &pinTransfers{
+		inflight: make(map[cid.Cid]struct{}),
+	}
+}
+
+// start registers a transfer of the given CID. It returns false when there
+// is one in progress already.
+func (pt *pinTransfers) start(c cid.Cid) bool {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if _, ok := pt.inflight[c]; ok {
+		return false
+	}
+	pt.inflight[c] = struct{}{}
+	return true
+}
+
+func (pt *pinTransfers) finish(c cid.Cid) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	delete(pt.inflight, c)
+}
+
+// reallocate re-allocates a pin away from the given peer, following the
+// ReallocationVerification policy. It returns the pin with its final
+// allocations and whether it was submitted to the consensus layer.
+func (c *Cluster) reallocate(ctx context.Context, pin *api.Pin, from peer.ID) (*api.Pin, bool, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/reallocate")
+	defer span.End()
+
+	// The previous allocations are asked for the blocks, the peer
+	// being left last as it may be gone.
+	sources := []peer.ID{}
+	for _, p := range pin.Allocations {
+		if p != from {
+			sources = append(sources, p)
+		}
+	}
+	sources = append(sources, from)
+
+	if c.config.ReallocationVerification == ReallocationVerifyNone {
+		pin, ok, err := c.pin(ctx, pin, []peer.ID{from}, []peer.ID{})
+		if err == nil && ok {
+			c.requestBlockTransfers(pin, sources)
+		}
+		return pin, ok, err
+	}
+
+	submit, err := c.preparePin(ctx, pin, []peer.ID{from}, []peer.ID{})
+	if err != nil || !submit {
+		return pin, false, err
+	}
+
+	// Meta pins, deferred pins and pins allocated everywhere are not
+	// transferred between peers.
+	if pin.Type == api.MetaType || pin.IsScheduled() || len(pin.Allocations) == 0 {
+		return pin, true, c.logPin(ctx, pin)
+	}
+
+	if !c.transfers.start(pin.Cid) {
+		logger.Debugf("%s is being transferred already", pin.Cid)
+		return pin, false, nil
+	}
+
+	transition := *pin
+	transition.Allocations = append(append([]peer.ID{}, pin.Allocations...), from)
+	err = c.logPin(ctx, &transition)
+	if err != nil {
+		c.transfers.finish(pin.Cid)
+		return pin, false, err
+	}
+	logger.Infof("transferring %s from %s to %s", pin.Cid, from, pin.Allocations)
+	go c.completeTransfer(pin, &transition, from)
+	c.requestBlockTransfers(pin, sources)
+	return pin, true, nil
+}
+
+// completeTransfer unallocates the old peer of a transferred pin once the
+// transfer is verified. When it cannot be verified in time, or the pin
+// changes meanwhile, the old peer is left allocated.
+func (c *Cluster) completeTransfer(pin, transition *api.Pin, from peer.ID) {
+	defer c.transfers.finish(pin.Cid)
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.config.ReallocationVerificationTimeout)
+	defer cancel()
+	ctx, span := trace.StartSpan(ctx, "cluster/completeTransfer")
+	defer span.End()
+
+	err := c.waitTransferred(ctx, pin)
+	if err != nil {
+		logger.Errorf("cannot verify the transfer of %s, %s stays allocated: %s", pin.Cid, from, err)
+		return
+	}
+
+	curr, err := c.PinGet(ctx, pin.Cid)
+	if err != nil || !curr.Equals(transition) {
+		logger.Infof("%s changed during its transfer from %s", pin.Cid, from)
+		return
+	}
+	err = c.logPin(ctx, pin)
+	if err != nil {
+		logger.Errorf("error unallocating %s from %s: %s", pin.Cid, from, err)
+		return
+	}
+	logger.Infof("%s transferred from %s", pin.Cid, from)
+}
+
+// waitTransferred waits until enough of the allocations of the given pin
+// report it as pinned: all of them, or, with ReallocationVerifyMin, as
+// many as its minimum replication factor.
+func (c *Cluster) waitTransferred(ctx context.Context, pin *api.Pin) error {
+	required := len(pin.Allocations)
+	if c.config.ReallocationVerification == ReallocationVerifyMin && pin.ReplicationFactorMin < required {
+		required = pin.ReplicationFactorMin
+	}
+
+	ticker := time.NewTicker(reallocationPollInterval)
+	defer ticker.Stop()
+	for {
+		if c.countPinned(ctx, pin) >= required {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// countPinned returns how many of the allocations of a pin report it as
+// pinned.
+func (c *Cluster) countPinned(ctx context.Context, pin *api.Pin) int {
+	allocs := pin.Allocations
+	replies := make([]*api.PinInfo, len(allocs), len(allocs))
+	ctxs, cancels := rpcutil.CtxsWithCancel(ctx, len(allocs))
+	defer rpcutil.MultiCancel(cancels)
+
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		allocs,
+		"PinTracker",
+		"Status",
+		pin.Cid,
+		rpcutil.CopyPinInfoToIfaces(replies),
+	)
+
+	pinned := 0
+	for i, err := range errs {
+		if err == nil && replies[i].Status == api.TrackerStatusPinned {
+			pinned++
+		}
+	}
+	return pinned
+}
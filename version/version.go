@@ -15,3 +15,29 @@ var Version = semver.MustParse("0.10.1")
 var RPCProtocol = protocol.ID(
 	fmt.Sprintf("/ipfscluster/%d.%d/rpc", Version.Major, Version.Minor),
 )
+
+// StateVersion is the version of the format of the shared state. It must
+// be increased with every change which makes peers unable to read the
+// state written by others.
+var StateVersion = 1
+
+// Compatible returns an error when a peer running the given cluster
+// version, RPC protocol and state version cannot safely share a cluster
+// with the current one. Peers with different patch versions are always
+// compatible.
+func Compatible(v string, rpcProtocol protocol.ID, stateVersion int) error {
+	ver, err := semver.Parse(v)
+	if err != nil {
+		return fmt.Errorf("cannot parse version %q: %s", v, err)
+	}
+	if ver.Major != Version.Major {
+		return fmt.Errorf("version %s is incompatible with %s", ver, Version)
+	}
+	if rpcProtocol != RPCProtocol {
+		return fmt.Errorf("rpc protocol %s is incompatible with %s", rpcProtocol, RPCProtocol)
+	}
+	if stateVersion != StateVersion {
+		return fmt.Errorf("state version %d is incompatible with %d", stateVersion, StateVersion)
+	}
+	return nil
+}
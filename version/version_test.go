@@ -0,0 +1,41 @@
+package version
+
+import (
+	"fmt"
+	"testing"
+
+	protocol "github.com/libp2p/go-libp2p-protocol"
+)
+
+func TestCompatible(t *testing.T) {
+	err := Compatible(Version.String(), RPCProtocol, StateVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := Version
+	patch.Patch++
+	err = Compatible(patch.String(), RPCProtocol, StateVersion)
+	if err != nil {
+		t.Error("different patch versions should be compatible:", err)
+	}
+
+	major := Version
+	major.Major++
+	if Compatible(major.String(), RPCProtocol, StateVersion) == nil {
+		t.Error("different major versions should be incompatible")
+	}
+
+	otherRPC := protocol.ID(fmt.Sprintf("/ipfscluster/%d.%d/rpc", Version.Major, Version.Minor+1))
+	if Compatible(Version.String(), otherRPC, StateVersion) == nil {
+		t.Error("different rpc protocols should be incompatible")
+	}
+
+	if Compatible(Version.String(), RPCProtocol, StateVersion+1) == nil {
+		t.Error("different state versions should be incompatible")
+	}
+
+	if Compatible("abc", RPCProtocol, StateVersion) == nil {
+		t.Error("unparseable versions should be incompatible")
+	}
+}